@@ -0,0 +1,113 @@
+// Package app contains the window setup shared by the demos.
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+
+	"flag"
+)
+
+// Window size and title, as set on the command line.
+var (
+	Width  int
+	Height int
+	Title  string
+)
+
+var configFile = flag.String("config", "config.json", "config file")
+
+// Flags defines the flags -width, -height and -title, with the values
+// given as defaults, parses the command line, loads the config file, and
+// starts serving profiles and frame statistics, with -pprof and -metrics.
+//
+// Demos that need more flags should define them before calling Flags.
+func Flags(width, height int, title string) {
+	flag.IntVar(&Width, "width", width, "window width")
+	flag.IntVar(&Height, "height", height, "window height")
+	flag.StringVar(&Title, "title", title, "window title")
+	flag.Parse()
+
+	if err := loadConfig(*configFile); err != nil {
+		Fatal(err)
+	}
+	servePprof()
+	serveMetrics()
+}
+
+// CreateWindow creates a window with the size and title from the command line.
+// Window hints must be set before calling CreateWindow. The number of
+// samples for multisample anti-aliasing in the config file, if set there,
+// overrides the hint. With -snapshot, the window is hidden.
+//
+// If a robust context was asked for with Robust, and the driver can't make
+// one, it makes a window with an ordinary context instead.
+func CreateWindow() (*glfw.Window, error) {
+	if s, _ := CurrentSettings(); s.Samples > 0 {
+		glfw.WindowHint(glfw.Samples, s.Samples)
+	}
+	if Snapshot() {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	}
+	w, err := glfw.CreateWindow(Width, Height, Title, nil, nil)
+	if err != nil && robust {
+		Warn("no robust context:", err)
+		robust = false
+		glfw.WindowHint(glfw.ContextRobustness, glfw.NoRobustness)
+		w, err = glfw.CreateWindow(Width, Height, Title, nil, nil)
+	}
+	return w, err
+}
+
+// CoreProfile sets the window hints for a forward-compatible core profile
+// context of at least the given version. Call it before CreateWindow.
+func CoreProfile(major, minor int) {
+	glfw.WindowHint(glfw.ContextVersionMajor, major)
+	glfw.WindowHint(glfw.ContextVersionMinor, minor)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+}
+
+// Whether Robust was called, and the hint is still set.
+var robust bool
+
+// Robust sets the window hint for a context that is lost when the GPU is
+// reset, after a driver crash or a shader that runs too long, rather than
+// one that may go on drawing garbage. The context can't be used anymore
+// after that: see RecoverContext. Call it before CreateWindow.
+//
+// Not all drivers support this, and with those making the window fails.
+// CreateWindow then falls back to an ordinary context, so a demo that asks
+// for a robust context still runs, only without surviving a reset.
+func Robust() {
+	robust = true
+	glfw.WindowHint(glfw.ContextRobustness, glfw.LoseContextOnReset)
+}
+
+// RecreateWindow destroys the window with its context, and creates a new
+// one in the same place, with the same size and window hints, and makes its
+// context current. This is how to go on after the context was lost. The
+// callbacks of the window and the swap interval must be set again, and the
+// OpenGL objects made again. RecoverContext does all of this.
+func RecreateWindow(old *glfw.Window) (*glfw.Window, error) {
+	x, y := old.GetPos()
+	width, height := old.GetSize()
+	if r, ok := windowed[old]; ok {
+		// the new window isn't fullscreen
+		delete(windowed, old)
+		x, y, width, height = r[0], r[1], r[2], r[3]
+	}
+	delete(screenshots, old)
+	old.Destroy()
+
+	if s, _ := CurrentSettings(); s.Samples > 0 {
+		glfw.WindowHint(glfw.Samples, s.Samples)
+	}
+	w, err := glfw.CreateWindow(width, height, Title, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.SetPos(x, y)
+	w.MakeContextCurrent()
+	replaceSwapWindow(old, w)
+	return w, nil
+}
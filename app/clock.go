@@ -0,0 +1,106 @@
+package app
+
+import (
+	"math"
+	"time"
+)
+
+// Duration of a single step while the clock is paused.
+const step = time.Second / 60
+
+// Limits and factor for changing the speed of the clock.
+const (
+	minSpeed    = 0.1
+	maxSpeed    = 10
+	speedFactor = 1.25
+)
+
+// Clock keeps the time used for animations. It can be paused, and while
+// paused it can be advanced one step at a time.
+type Clock struct {
+	t      time.Duration
+	dt     time.Duration
+	last   time.Time
+	speed  float64
+	paused bool
+	step   bool
+
+	settings int // generation of the config settings last applied
+}
+
+// NewClock returns a running clock, starting at zero.
+func NewClock() *Clock {
+	return &Clock{last: time.Now(), speed: 1}
+}
+
+// Tick advances the clock. Call it once per frame. After a change of the
+// speed in the config file, the clock gets that speed.
+func (c *Clock) Tick() {
+	if s, gen := CurrentSettings(); gen != c.settings {
+		c.settings = gen
+		if s.Speed != 0 {
+			c.speed = s.Speed
+		}
+	}
+
+	now := time.Now()
+	dt := now.Sub(c.last)
+	c.last = now
+
+	c.dt = 0
+	if c.paused {
+		if c.step {
+			c.dt = time.Duration(float64(step) * c.speed)
+			c.step = false
+		}
+	} else {
+		c.dt = time.Duration(float64(dt) * c.speed)
+	}
+	c.t += c.dt
+}
+
+// Seconds returns the animation time in seconds.
+func (c *Clock) Seconds() float64 {
+	return c.t.Seconds()
+}
+
+// Delta returns the time in seconds that the clock advanced on the last Tick.
+func (c *Clock) Delta() float64 {
+	return c.dt.Seconds()
+}
+
+// Pause toggles between paused and running.
+func (c *Clock) Pause() {
+	c.paused = !c.paused
+	c.step = false
+}
+
+// Paused reports whether the clock is paused.
+func (c *Clock) Paused() bool {
+	return c.paused
+}
+
+// Faster increases the speed of the clock, up to 10 times normal speed.
+func (c *Clock) Faster() {
+	c.speed = math.Min(c.speed*speedFactor, maxSpeed)
+}
+
+// Slower decreases the speed of the clock, down to 0.1 times normal speed.
+func (c *Clock) Slower() {
+	c.speed = math.Max(c.speed/speedFactor, minSpeed)
+}
+
+// Speed returns the speed of the clock relative to real time.
+func (c *Clock) Speed() float64 {
+	return c.speed
+}
+
+// Step pauses the clock if it is running, otherwise it advances the clock
+// by a single step on the next Tick.
+func (c *Clock) Step() {
+	if !c.paused {
+		c.paused = true
+		return
+	}
+	c.step = true
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// The layout of the config file.
+type config struct {
+	Keys map[string][]string `json:"keys"`
+	Settings
+}
+
+// Settings are the values of the config file that are applied while a demo
+// runs: the config file is checked for changes twice a second, and each
+// setting is picked up where it is used. Fields that are not set in the
+// file leave the demo as it is.
+type Settings struct {
+	// Background color, RGBA, for demos that use Background.
+	Background *[4]float32 `json:"background"`
+
+	// Speed of all clocks, relative to real time.
+	Speed float64 `json:"speed"`
+
+	// Post-processing effects, enabled or not, by name.
+	Effects map[string]bool `json:"effects"`
+
+	// Number of samples per pixel for multisample anti-aliasing. This is
+	// only used when a window is made, so a change needs a restart.
+	Samples int `json:"samples"`
+
+	// Whether multisample anti-aliasing is enabled, in windows that have
+	// samples, applied by glutil.State.
+	Multisample *bool `json:"multisample"`
+}
+
+var (
+	settingsMu sync.Mutex
+	settings   Settings
+	generation int // incremented on each load
+)
+
+// CurrentSettings returns the settings of the config file, and the number
+// of times they were loaded, so a change can be applied once, rather than
+// each frame, leaving the demo free to change the same value in between.
+// The number is 0 if there is no config file.
+func CurrentSettings() (s Settings, gen int) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return settings, generation
+}
+
+// Background returns the background color of the config file, or the given
+// color if it has none. Use it as:
+//
+//	gl.ClearColor(app.Background(.1, .1, .1, 0))
+func Background(r, g, b, a float32) (float32, float32, float32, float32) {
+	s, _ := CurrentSettings()
+	if c := s.Background; c != nil {
+		return c[0], c[1], c[2], c[3]
+	}
+	return r, g, b, a
+}
+
+// loadConfig reads the config file, and watches it for changes of the
+// settings. A missing file is not an error.
+func loadConfig(filename string) error {
+	c, modified, err := readConfig(filename)
+	if os.IsNotExist(err) {
+		go watchConfig(filename, time.Time{})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for action, keys := range c.Keys {
+		Keys[action] = keys
+	}
+	setSettings(c.Settings)
+	go watchConfig(filename, modified)
+	return nil
+}
+
+// readConfig reads and parses the config file.
+func readConfig(filename string) (c config, modified time.Time, err error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return c, modified, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return c, modified, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, modified, fmt.Errorf("%s: %v", filename, err)
+	}
+	return c, fi.ModTime(), nil
+}
+
+func setSettings(s Settings) {
+	if s.Speed != 0 {
+		s.Speed = math.Max(minSpeed, math.Min(s.Speed, maxSpeed))
+	}
+	settingsMu.Lock()
+	settings = s
+	generation++
+	settingsMu.Unlock()
+}
+
+// watchConfig loads the settings of the config file again when it changes.
+// Key bindings are not changed, as they are only used when a window is set
+// up. An error is reported, and the old settings are kept.
+func watchConfig(filename string, modified time.Time) {
+	for range time.Tick(500 * time.Millisecond) {
+		fi, err := os.Stat(filename)
+		if err != nil || fi.ModTime().Equal(modified) {
+			continue
+		}
+		c, m, err := readConfig(filename)
+		if err != nil {
+			Warn(err)
+			// don't report the same error again
+			modified = fi.ModTime()
+			continue
+		}
+		modified = m
+		setSettings(c.Settings)
+		Info("Reloaded", filename)
+	}
+}
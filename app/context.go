@@ -0,0 +1,60 @@
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Functions set with HandleContextLoss.
+var (
+	contextLost  func() bool
+	contextReset func() error
+)
+
+// The function set with HandleRebuild.
+var rebuild func(w *glfw.Window)
+
+// HandleContextLoss sets the functions that RecoverContext uses to check
+// whether the context of the current window was lost, and to get the
+// OpenGL bindings and the packages that use them ready for a new context.
+// Package glutil sets them for the demos that use it: glutil.ContextLost,
+// and gl.Init followed by glutil.ForgetContext.
+func HandleContextLoss(lost func() bool, reset func() error) {
+	contextLost = lost
+	contextReset = reset
+}
+
+// HandleRebuild sets the function that RecoverContext calls with the new
+// window, after the old context was lost. It should set the callbacks of
+// the window and the swap interval, and make the OpenGL objects of the demo
+// again. Only the demos that set it survive a reset of the GPU.
+func HandleRebuild(f func(w *glfw.Window)) {
+	rebuild = f
+}
+
+// RecoverContext checks whether the context of the window was lost, after
+// the GPU was reset. If so, it makes a new window and context with
+// RecreateWindow, gets the OpenGL bindings ready for it, calls the function
+// set with HandleRebuild, and returns the new window. Otherwise, or if no
+// function was set with HandleRebuild, it returns w. Call it in the main
+// loop, after SwapBuffers:
+//
+//	w = app.RecoverContext(w)
+//
+// The context only gets lost if it was made after Robust.
+func RecoverContext(w *glfw.Window) *glfw.Window {
+	if rebuild == nil || contextLost == nil || !contextLost() {
+		return w
+	}
+	Info("The OpenGL context was lost, making a new one")
+	w, err := RecreateWindow(w)
+	if err != nil {
+		Fatal(err)
+	}
+	if contextReset != nil {
+		if err := contextReset(); err != nil {
+			Fatal(err)
+		}
+	}
+	rebuild(w)
+	return w
+}
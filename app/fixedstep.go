@@ -0,0 +1,44 @@
+package app
+
+// FixedStep runs updates at a fixed rate, independent of the frame rate,
+// as physics simulations need. Use it with the time from a Clock:
+//
+//	alpha := steps.Advance(clock.Delta(), update)
+//
+// and draw the state interpolated between the last two updates, a fraction
+// alpha of the way from the previous to the current.
+type FixedStep struct {
+	Step float64 // seconds per update
+
+	// Maximum number of updates per call to Advance. If the updates
+	// can't keep up, the simulation slows down instead of taking ever
+	// longer frames.
+	MaxSteps int
+
+	acc float64
+}
+
+// NewFixedStep returns a FixedStep with rate updates per second, and at
+// most 10 updates per frame.
+func NewFixedStep(rate float64) *FixedStep {
+	return &FixedStep{
+		Step:     1 / rate,
+		MaxSteps: 10,
+	}
+}
+
+// Advance adds dt seconds, and calls update once for each whole step that
+// has passed, with the length of a step. It returns the time that is left,
+// as a fraction of a step, from 0 to 1.
+func (f *FixedStep) Advance(dt float64, update func(dt float64)) (alpha float64) {
+	f.acc += dt
+	for n := 0; f.acc >= f.Step; n++ {
+		if n == f.MaxSteps {
+			f.acc = 0
+			break
+		}
+		update(f.Step)
+		f.acc -= f.Step
+	}
+	return f.acc / f.Step
+}
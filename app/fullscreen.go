@@ -0,0 +1,31 @@
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Position and size of the windows that were made fullscreen with the
+// action Fullscreen, to go back to.
+var windowed = make(map[*glfw.Window][4]int)
+
+// toggleFullscreen makes the window fullscreen on the primary monitor, in
+// its current video mode, or, if it is fullscreen, puts it back where it
+// was, with the size it had.
+func toggleFullscreen(w *glfw.Window) {
+	if r, ok := windowed[w]; ok {
+		delete(windowed, w)
+		w.SetMonitor(nil, r[0], r[1], r[2], r[3], 0)
+		return
+	}
+
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		Warn("fullscreen: no monitor")
+		return
+	}
+	x, y := w.GetPos()
+	width, height := w.GetSize()
+	windowed[w] = [4]int{x, y, width, height}
+	mode := monitor.GetVideoMode()
+	w.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
+}
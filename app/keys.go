@@ -0,0 +1,150 @@
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Actions that have a default key binding.
+const (
+	Quit       = "quit"
+	Pause      = "pause"
+	Step       = "step"
+	Faster     = "faster"
+	Slower     = "slower"
+	Screenshot = "screenshot"
+	Fullscreen = "fullscreen"
+	DumpState  = "dumpstate"
+)
+
+// Keys maps actions to keys. A key is either a single character, as typed,
+// or the name of a special key, such as "space", "escape" or "F11".
+//
+// Demos can add bindings for their own actions before calling Flags. Bindings
+// in the config file replace the bindings for the same action.
+var Keys = map[string][]string{
+	Quit:       {"q", "escape"},
+	Pause:      {"space"},
+	Step:       {"."},
+	Faster:     {"+"},
+	Slower:     {"-"},
+	Screenshot: {"F12"},
+	Fullscreen: {"F11"},
+	DumpState:  {"F9"},
+}
+
+// Functions set with Handle.
+var handlers = make(map[string]func())
+
+// Handle makes OnAction call f when a key for action is pressed, before the
+// handler of the demo. This is for packages that handle an action the same
+// way in all demos that use them, such as glutil for DumpState.
+func Handle(action string, f func()) {
+	handlers[action] = f
+}
+
+// Functions set with HandleClose.
+var closeHandlers []func()
+
+// HandleClose makes OnAction call f when the window is about to close, by
+// the action Quit or by its close button, while its context is still
+// current. This is for packages that check the state at the end, such as
+// glutil for objects that were never deleted.
+func HandleClose(f func()) {
+	closeHandlers = append(closeHandlers, f)
+}
+
+// Special keys that can be used in a binding.
+var keyNames = map[string]glfw.Key{
+	"escape":    glfw.KeyEscape,
+	"enter":     glfw.KeyEnter,
+	"tab":       glfw.KeyTab,
+	"backspace": glfw.KeyBackspace,
+	"insert":    glfw.KeyInsert,
+	"delete":    glfw.KeyDelete,
+	"left":      glfw.KeyLeft,
+	"right":     glfw.KeyRight,
+	"up":        glfw.KeyUp,
+	"down":      glfw.KeyDown,
+	"pageup":    glfw.KeyPageUp,
+	"pagedown":  glfw.KeyPageDown,
+	"home":      glfw.KeyHome,
+	"end":       glfw.KeyEnd,
+	"F1":        glfw.KeyF1,
+	"F2":        glfw.KeyF2,
+	"F3":        glfw.KeyF3,
+	"F4":        glfw.KeyF4,
+	"F5":        glfw.KeyF5,
+	"F6":        glfw.KeyF6,
+	"F7":        glfw.KeyF7,
+	"F8":        glfw.KeyF8,
+	"F9":        glfw.KeyF9,
+	"F10":       glfw.KeyF10,
+	"F11":       glfw.KeyF11,
+	"F12":       glfw.KeyF12,
+}
+
+// OnAction sets the callbacks of the window so that handler is called with
+// the action bound to each key that is pressed. The close callback of the
+// window is set for HandleClose, and to write the profiles of -cpuprofile
+// and -memprofile. The action Screenshot makes the next call of SwapBuffers
+// write the frame to a PNG file, and the action Fullscreen switches the
+// window between fullscreen and the position and size it had.
+func OnAction(w *glfw.Window, handler func(w *glfw.Window, action string)) {
+	closing := false
+	onClose := func() {
+		if closing {
+			return
+		}
+		closing = true
+		for _, f := range closeHandlers {
+			f()
+		}
+		stopProfiles()
+	}
+	call := func(w *glfw.Window, action string) {
+		if f, ok := handlers[action]; ok {
+			f()
+		}
+		switch action {
+		case Screenshot:
+			screenshots[w] = true
+		case Fullscreen:
+			toggleFullscreen(w)
+		}
+		handler(w, action)
+		if action == Quit {
+			onClose()
+		}
+	}
+
+	chars := make(map[rune]string)
+	keys := make(map[glfw.Key]string)
+	for action, names := range Keys {
+		for _, name := range names {
+			if key, ok := keyNames[name]; ok {
+				keys[key] = action
+			} else if name == "space" {
+				chars[' '] = action
+			} else if r := []rune(name); len(r) == 1 {
+				chars[r[0]] = action
+			}
+		}
+	}
+
+	w.SetCharCallback(func(w *glfw.Window, char rune) {
+		if action, ok := chars[char]; ok {
+			call(w, action)
+		}
+	})
+	w.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, act glfw.Action, mods glfw.ModifierKey) {
+		if act == glfw.Release {
+			return
+		}
+		if action, ok := keys[key]; ok {
+			call(w, action)
+		}
+	})
+	w.SetCloseCallback(func(w *glfw.Window) {
+		onClose()
+	})
+}
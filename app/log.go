@@ -0,0 +1,181 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	verbose = flag.Bool("v", false, "also log debug messages")
+	quiet   = flag.Bool("quiet", false, "only log warnings and errors")
+)
+
+// Level is the level of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level %d", int(l))
+}
+
+// LogEntry is a message in the log.
+type LogEntry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+}
+
+// Number of messages kept for RecentLog.
+const logSize = 100
+
+var (
+	logMu   sync.Mutex
+	logRing [logSize]LogEntry
+	logNext int // index in logRing of the next message
+	logLen  int // number of messages in logRing
+)
+
+// Logger is a logger of package log whose messages go into this log, as
+// warnings. It is for packages that log without a level, such as asset,
+// and for code that wants a *log.Logger. The standard logger of package log
+// is left as it is.
+var Logger = log.New(warnWriter{}, "", 0)
+
+// warnWriter logs each write as a warning.
+type warnWriter struct{}
+
+func (warnWriter) Write(p []byte) (int, error) {
+	logMessage(LevelWarn, string(p))
+	return len(p), nil
+}
+
+// LogLevel returns the lowest level of the messages that are written, as
+// set with -v and -quiet.
+func LogLevel() Level {
+	switch {
+	case *verbose:
+		return LevelDebug
+	case *quiet:
+		return LevelWarn
+	}
+	return LevelInfo
+}
+
+// logMessage keeps a message for RecentLog, and writes it if its level is
+// high enough: debug and info messages as they are to the standard output,
+// warnings and errors with their level to the standard error.
+func logMessage(level Level, msg string) {
+	msg = strings.TrimSuffix(msg, "\n")
+
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	logRing[logNext] = LogEntry{Time: time.Now(), Level: level, Message: msg}
+	logNext = (logNext + 1) % logSize
+	if logLen < logSize {
+		logLen++
+	}
+
+	if level < LogLevel() {
+		return
+	}
+	if level < LevelWarn {
+		fmt.Fprintln(os.Stdout, msg)
+	} else {
+		fmt.Fprintf(os.Stderr, "%v: %s\n", level, msg)
+	}
+}
+
+// RecentLog returns the last n messages of the log, at most 100, the oldest
+// first. Messages are kept whatever their level, also those not written
+// because of -quiet, or without -v.
+func RecentLog(n int) []LogEntry {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if n > logLen {
+		n = logLen
+	}
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = logRing[(logNext-n+i+logSize)%logSize]
+	}
+	return entries
+}
+
+// Debug logs a message for -v, with its arguments formatted as by
+// fmt.Println.
+func Debug(args ...interface{}) {
+	logMessage(LevelDebug, fmt.Sprintln(args...))
+}
+
+// Debugf logs a message for -v, formatted as by fmt.Printf.
+func Debugf(format string, args ...interface{}) {
+	logMessage(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Info logs a message, with its arguments formatted as by fmt.Println.
+func Info(args ...interface{}) {
+	logMessage(LevelInfo, fmt.Sprintln(args...))
+}
+
+// Infof logs a message, formatted as by fmt.Printf.
+func Infof(format string, args ...interface{}) {
+	logMessage(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning, with its arguments formatted as by fmt.Println.
+func Warn(args ...interface{}) {
+	logMessage(LevelWarn, fmt.Sprintln(args...))
+}
+
+// Warnf logs a warning, formatted as by fmt.Printf.
+func Warnf(format string, args ...interface{}) {
+	logMessage(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error, with its arguments formatted as by fmt.Println.
+func Error(args ...interface{}) {
+	logMessage(LevelError, fmt.Sprintln(args...))
+}
+
+// Errorf logs an error, formatted as by fmt.Printf.
+func Errorf(format string, args ...interface{}) {
+	logMessage(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs an error, as Error, and exits with status 1, after writing
+// the profiles, if asked for.
+func Fatal(args ...interface{}) {
+	Error(args...)
+	stopProfiles()
+	os.Exit(1)
+}
+
+// Fatalf logs an error, as Errorf, and exits with status 1, after writing
+// the profiles, if asked for.
+func Fatalf(format string, args ...interface{}) {
+	Errorf(format, args...)
+	stopProfiles()
+	os.Exit(1)
+}
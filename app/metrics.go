@@ -0,0 +1,214 @@
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+
+	"expvar"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var metricsAddr = flag.String("metrics", "", "serve frame statistics over HTTP at this address, as expvar at /debug/vars and for Prometheus at /metrics")
+
+// FrameStats are statistics of the frames of a demo, as served with
+// -metrics, and returned by LastFrame.
+//
+// Draw calls and uploaded bytes are counted by the helpers of this
+// repository, such as sprite.Batch, mesh.VAO and glutil.MakeTexture. A demo
+// that calls OpenGL directly can add its own with CountDrawCalls and
+// CountUpload.
+type FrameStats struct {
+	Frames        int64         // frames swapped
+	FrameTime     time.Duration // from the swap before the last frame to its swap
+	DrawCalls     int           // in the last frame
+	UploadedBytes int           // in the last frame
+	GPUTime       time.Duration // of the last frame that was measured, 0 if unknown
+
+	TotalDrawCalls     int64
+	TotalUploadedBytes int64
+}
+
+// Counts of the frame being drawn, only used on the goroutine with the
+// OpenGL context.
+var (
+	frameDrawCalls int
+	frameUploaded  int
+	lastSwap       time.Time
+	swapWindow     *glfw.Window
+)
+
+// Statistics of the last frame, also read by the HTTP server, and the
+// times of the last frames, for RecentFrameTimes.
+var (
+	statsMu    sync.Mutex
+	frameStats FrameStats
+	frameTimes [frameTimesSize]time.Duration
+	frameNext  int // index in frameTimes of the next frame
+	frameLen   int // number of frames in frameTimes
+)
+
+// Number of frame times kept for RecentFrameTimes.
+const frameTimesSize = 240
+
+// The function set with HandleSwap.
+var swapHandler func()
+
+// CountDrawCalls adds n draw calls to the statistics of the frame. Call it
+// on the goroutine with the OpenGL context.
+func CountDrawCalls(n int) {
+	frameDrawCalls += n
+}
+
+// CountUpload adds n bytes uploaded to buffers or textures to the
+// statistics of the frame. Call it on the goroutine with the OpenGL
+// context.
+func CountUpload(n int) {
+	frameUploaded += n
+}
+
+// SetGPUTime sets the time the GPU took for a frame, for the statistics.
+// Results of GPU timer queries come a few frames late, so this is the time
+// of the last frame that was measured.
+func SetGPUTime(d time.Duration) {
+	statsMu.Lock()
+	frameStats.GPUTime = d
+	statsMu.Unlock()
+}
+
+// LastFrame returns the statistics of the last frame that was swapped.
+func LastFrame() FrameStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	return frameStats
+}
+
+// RecentFrameTimes returns the times of the last n frames, at most 240,
+// the oldest first, as in FrameStats.
+func RecentFrameTimes(n int) []time.Duration {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if n > frameLen {
+		n = frameLen
+	}
+	times := make([]time.Duration, n)
+	for i := range times {
+		times[i] = frameTimes[(frameNext-n+i+frameTimesSize)%frameTimesSize]
+	}
+	return times
+}
+
+// Metrics reports whether the demo was started with -metrics.
+func Metrics() bool {
+	return *metricsAddr != ""
+}
+
+// replaceSwapWindow makes the statistics go on with the new window, if the
+// old one was the window they are of. The frame time of the first frame of
+// the new window is not counted, as it includes making the window.
+func replaceSwapWindow(old, w *glfw.Window) {
+	if swapWindow == old {
+		swapWindow = w
+		lastSwap = time.Time{}
+	}
+}
+
+// HandleSwap sets a function that SwapBuffers calls before it swaps the
+// buffers of the first window it was called with, with -metrics. Package
+// glutil sets it to measure the time the GPU takes for each frame.
+func HandleSwap(f func()) {
+	swapHandler = f
+}
+
+// endFrame is called by SwapBuffers. The statistics are of the first
+// window only, in a demo with several, or of the window that replaced it
+// with RecreateWindow.
+func endFrame(w *glfw.Window) {
+	if swapWindow == nil {
+		swapWindow = w
+	}
+	if w != swapWindow {
+		return
+	}
+	if Metrics() && swapHandler != nil {
+		swapHandler()
+	}
+
+	now := time.Now()
+	statsMu.Lock()
+	frameStats.Frames++
+	if !lastSwap.IsZero() {
+		frameStats.FrameTime = now.Sub(lastSwap)
+		frameTimes[frameNext] = frameStats.FrameTime
+		frameNext = (frameNext + 1) % frameTimesSize
+		if frameLen < frameTimesSize {
+			frameLen++
+		}
+	}
+	frameStats.DrawCalls = frameDrawCalls
+	frameStats.UploadedBytes = frameUploaded
+	frameStats.TotalDrawCalls += int64(frameDrawCalls)
+	frameStats.TotalUploadedBytes += int64(frameUploaded)
+	statsMu.Unlock()
+
+	lastSwap = now
+	frameDrawCalls = 0
+	frameUploaded = 0
+}
+
+func init() {
+	expvar.Publish("frame", expvar.Func(func() interface{} {
+		return LastFrame()
+	}))
+}
+
+// serveMetrics serves the frame statistics, if asked for with -metrics, on
+// a goroutine: as expvar at /debug/vars, with the memory statistics of
+// the Go runtime, and in the text format of Prometheus at /metrics. If the
+// address is the same as that of -pprof, they are served together.
+func serveMetrics() {
+	if !Metrics() {
+		return
+	}
+	host := *metricsAddr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	Infof("Serving frame statistics at http://%s/metrics", host)
+
+	if *metricsAddr == *pprofAddr {
+		// added to the server of -pprof, which has expvar too
+		http.HandleFunc("/metrics", writeMetrics)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/metrics", writeMetrics)
+	go func() {
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			Error(err)
+		}
+	}()
+}
+
+// writeMetrics writes the frame statistics in the text format of
+// Prometheus.
+func writeMetrics(w http.ResponseWriter, req *http.Request) {
+	s := LastFrame()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("gl_frames_total", "counter", "Frames swapped.", s.Frames)
+	metric("gl_frame_seconds", "gauge", "Time of the last frame, from swap to swap.", s.FrameTime.Seconds())
+	metric("gl_draw_calls", "gauge", "Draw calls in the last frame.", s.DrawCalls)
+	metric("gl_draw_calls_total", "counter", "Draw calls.", s.TotalDrawCalls)
+	metric("gl_uploaded_bytes", "gauge", "Bytes uploaded to buffers and textures in the last frame.", s.UploadedBytes)
+	metric("gl_uploaded_bytes_total", "counter", "Bytes uploaded to buffers and textures.", s.TotalUploadedBytes)
+	if s.GPUTime > 0 {
+		metric("gl_gpu_frame_seconds", "gauge", "Time the GPU took for the last frame that was measured.", s.GPUTime.Seconds())
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"flag"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+var (
+	cpuProfile = flag.String("cpuprofile", "", "write a CPU profile of the render loop to this file")
+	memProfile = flag.String("memprofile", "", "write a memory profile to this file, when the window closes")
+	pprofAddr  = flag.String("pprof", "", "serve live profiles over HTTP at this address, such as :6060")
+)
+
+// servePprof serves the profiles of net/http/pprof, if asked for with
+// -pprof, on a goroutine, for as long as the demo runs, such as for:
+//
+//	go tool pprof http://localhost:6060/debug/pprof/profile?seconds=10
+//	curl http://localhost:6060/debug/pprof/goroutine?debug=2
+//
+// Without a host, as in :6060, it listens on all network interfaces, so
+// use localhost:6060 to keep the profiles to the machine itself. If the
+// address can't be used, that is logged, and the demo goes on.
+func servePprof() {
+	if *pprofAddr == "" {
+		return
+	}
+	host := *pprofAddr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	Infof("Serving profiles at http://%s/debug/pprof/", host)
+	go func() {
+		if err := http.ListenAndServe(*pprofAddr, nil); err != nil {
+			Error(err)
+		}
+	}()
+}
+
+// Whether the profiles were started, and stopped, and the file of the CPU
+// profile.
+var (
+	profiling       bool
+	profilesStopped bool
+	cpuProfileFile  *os.File
+)
+
+// startProfiles starts the CPU profile, if asked for with -cpuprofile. It
+// is called by SwapBuffers, so the profile starts with the render loop, and
+// doesn't include setting up the window and the resources. Errors are
+// logged, the demo goes on without the profile.
+func startProfiles() {
+	if profiling {
+		return
+	}
+	profiling = true
+	if *cpuProfile == "" {
+		return
+	}
+	fp, err := os.Create(*cpuProfile)
+	if err != nil {
+		Error(err)
+		*cpuProfile = ""
+		return
+	}
+	if err := pprof.StartCPUProfile(fp); err != nil {
+		Error(err)
+		fp.Close()
+		*cpuProfile = ""
+		return
+	}
+	cpuProfileFile = fp
+}
+
+// stopProfiles stops the CPU profile, and writes the memory profile, if
+// asked for with -memprofile. It is called when the window is about to
+// close, when the snapshot was written, and by Fatal. Only the first call
+// does anything.
+func stopProfiles() {
+	if !profiling || profilesStopped {
+		return
+	}
+	profilesStopped = true
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+		if err := cpuProfileFile.Close(); err != nil {
+			Error(err)
+		} else {
+			Info("Wrote", *cpuProfile)
+		}
+	}
+	if *memProfile != "" {
+		if err := writeMemProfile(*memProfile); err != nil {
+			Error(err)
+			return
+		}
+		Info("Wrote", *memProfile)
+	}
+}
+
+func writeMemProfile(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	// up-to-date statistics
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(fp); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
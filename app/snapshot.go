@@ -0,0 +1,134 @@
+package app
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+)
+
+var (
+	snapshotFile   = flag.String("snapshot", "", "render in a hidden window, write a frame to this PNG file, and quit")
+	snapshotFrames = flag.Int("frames", 1, "number of the frame written with -snapshot")
+)
+
+// The function set with HandleSnapshot.
+var readPixels func(width, height int) (*image.RGBA, error)
+
+// Number of frames swapped for each window, with -snapshot, and whether
+// the snapshot was written.
+var (
+	swapped      = make(map[*glfw.Window]int)
+	snapshotDone bool
+)
+
+// Windows for which a screenshot was asked for with the action Screenshot.
+var screenshots = make(map[*glfw.Window]bool)
+
+// HandleSnapshot sets the function that SwapBuffers uses to read the pixels
+// of the current window for -snapshot. Package glutil sets it for the
+// demos that use it, a demo with other bindings must set it itself.
+func HandleSnapshot(f func(width, height int) (*image.RGBA, error)) {
+	readPixels = f
+}
+
+// Snapshot reports whether the demo was started with -snapshot, and runs in
+// a hidden window.
+func Snapshot() bool {
+	return *snapshotFile != ""
+}
+
+// SwapBuffers swaps the buffers of the window. With -snapshot, it first
+// writes the frame to the file, if it is the frame asked for, and makes the
+// window close. Its context must be current. In a demo with several
+// windows, the first one that gets to that frame is written.
+//
+// After the action Screenshot, it writes the frame to the first file
+// screenshot1.png, screenshot2.png, ... that doesn't exist yet.
+//
+// The first call starts the CPU profile, with -cpuprofile. Each call ends
+// a frame, for the statistics of LastFrame and -metrics.
+func SwapBuffers(w *glfw.Window) {
+	startProfiles()
+	if Snapshot() && !snapshotDone {
+		swapped[w]++
+		if swapped[w] >= *snapshotFrames {
+			if err := writeSnapshot(w, *snapshotFile); err != nil {
+				Fatal(err)
+			}
+			snapshotDone = true
+			stopProfiles()
+			w.SetShouldClose(true)
+		}
+	}
+	if screenshots[w] {
+		delete(screenshots, w)
+		if err := writeScreenshot(w); err != nil {
+			Warn(err)
+		}
+	}
+	endFrame(w)
+	w.SwapBuffers()
+}
+
+// Most files tried by writeScreenshot.
+const maxScreenshots = 10000
+
+// writeScreenshot writes the frame to the first file screenshot1.png,
+// screenshot2.png, ... that doesn't exist yet. The file is made with
+// O_EXCL, so an existing file is never overwritten.
+func writeScreenshot(w *glfw.Window) error {
+	img, err := readFrame(w)
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= maxScreenshots; i++ {
+		filename := fmt.Sprintf("screenshot%d.png", i)
+		fp, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := writePNG(fp, img); err != nil {
+			return err
+		}
+		Infof("Screenshot: %s", filename)
+		return nil
+	}
+	return fmt.Errorf("screenshot: screenshot1.png to screenshot%d.png all exist", maxScreenshots)
+}
+
+func writeSnapshot(w *glfw.Window, filename string) error {
+	img, err := readFrame(w)
+	if err != nil {
+		return err
+	}
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	return writePNG(fp, img)
+}
+
+// readFrame reads the pixels of the window of the current context.
+func readFrame(w *glfw.Window) (*image.RGBA, error) {
+	if readPixels == nil {
+		return nil, fmt.Errorf("no function to read pixels, see app.HandleSnapshot")
+	}
+	width, height := w.GetFramebufferSize()
+	return readPixels(width, height)
+}
+
+// writePNG writes the image to the file, and closes it.
+func writePNG(fp *os.File, img image.Image) error {
+	if err := png.Encode(fp, img); err != nil {
+		fp.Close()
+		return fmt.Errorf("%s: %v", fp.Name(), err)
+	}
+	return fp.Close()
+}
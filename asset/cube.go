@@ -0,0 +1,146 @@
+package asset
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LUT is a 3D color lookup table: for each of Size x Size x Size input
+// colors, evenly spread from DomainMin to DomainMax, the output color.
+type LUT struct {
+	Title     string
+	Size      int
+	DomainMin [3]float32
+	DomainMax [3]float32
+
+	// Red, green and blue of each entry, with red changing fastest, then
+	// green, then blue.
+	Data []float32
+}
+
+// IdentityLUT returns a table of size entries in each direction that maps
+// each color to itself.
+func IdentityLUT(size int) *LUT {
+	l := &LUT{
+		Size:      size,
+		DomainMax: [3]float32{1, 1, 1},
+		Data:      make([]float32, 0, 3*size*size*size),
+	}
+	step := 1 / float32(size-1)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				l.Data = append(l.Data, float32(r)*step, float32(g)*step, float32(b)*step)
+			}
+		}
+	}
+	return l
+}
+
+// LoadCube reads a 3D lookup table in the .cube format of Adobe and
+// Resolve.
+func LoadCube(filename string) (*LUT, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	l, err := DecodeCube(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return l, nil
+}
+
+// DecodeCube reads a 3D lookup table in the .cube format. 1D tables are
+// not supported.
+func DecodeCube(r io.Reader) (*LUT, error) {
+	l := &LUT{
+		DomainMax: [3]float32{1, 1, 1},
+	}
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "TITLE":
+			l.Title = strings.Trim(strings.TrimSpace(line[len("TITLE"):]), `"`)
+		case "LUT_3D_SIZE":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: invalid size", lineno)
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil || size < 2 || size > 256 {
+				return nil, fmt.Errorf("line %d: invalid size", lineno)
+			}
+			l.Size = size
+			l.Data = make([]float32, 0, 3*size*size*size)
+		case "LUT_1D_SIZE":
+			return nil, fmt.Errorf("line %d: 1D tables are not supported", lineno)
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			v, err := parseTriple(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			if fields[0] == "DOMAIN_MIN" {
+				l.DomainMin = v
+			} else {
+				l.DomainMax = v
+			}
+		default:
+			// keywords that don't matter here, such as
+			// LUT_3D_INPUT_RANGE, start with a letter
+			if c := fields[0][0]; c >= 'A' && c <= 'Z' {
+				continue
+			}
+			if l.Size == 0 {
+				return nil, fmt.Errorf("line %d: data before LUT_3D_SIZE", lineno)
+			}
+			v, err := parseTriple(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			if len(l.Data) == cap(l.Data) {
+				return nil, fmt.Errorf("line %d: too many entries", lineno)
+			}
+			l.Data = append(l.Data, v[0], v[1], v[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if l.Size == 0 {
+		return nil, fmt.Errorf("missing LUT_3D_SIZE")
+	}
+	if n := len(l.Data) / 3; n != l.Size*l.Size*l.Size {
+		return nil, fmt.Errorf("%d entries, should be %d", n, l.Size*l.Size*l.Size)
+	}
+	for i := 0; i < 3; i++ {
+		if l.DomainMax[i] <= l.DomainMin[i] {
+			return nil, fmt.Errorf("invalid domain")
+		}
+	}
+	return l, nil
+}
+
+func parseTriple(fields []string) ([3]float32, error) {
+	var v [3]float32
+	if len(fields) != 3 {
+		return v, fmt.Errorf("need three values")
+	}
+	for i, f := range fields {
+		value, err := strconv.ParseFloat(f, 32)
+		if err != nil {
+			return v, err
+		}
+		v[i] = float32(value)
+	}
+	return v, nil
+}
@@ -0,0 +1,49 @@
+// Package asset loads images and other data used by the demos. It doesn't
+// depend on OpenGL, so it can be used with any version of the bindings.
+package asset
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+)
+
+// LoadImage reads an image file in any of the registered formats, such as
+// PNG or JPEG, and converts it to RGBA.
+func LoadImage(filename string) (*image.RGBA, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	rgba, err := DecodeImage(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return rgba, nil
+}
+
+// DecodeImage decodes an image and converts it to RGBA.
+func DecodeImage(r io.Reader) (*image.RGBA, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return ToRGBA(img), nil
+}
+
+// ToRGBA returns the image as RGBA, with its origin at 0, 0 and the
+// rows stored without padding, ready for uploading to a texture.
+func ToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) && rgba.Stride == rgba.Rect.Dx()*4 {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
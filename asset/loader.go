@@ -0,0 +1,171 @@
+package asset
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+// Loader loads assets in the background. Files are read and decoded on
+// worker goroutines. The results are handed one at a time to the goroutine
+// that calls Update, which has the OpenGL context and can upload them.
+// Meanwhile the main loop goes on drawing, such as a progress bar:
+//
+//	l := asset.NewLoader(4)
+//	l.Image("hello1.png", func(img *image.RGBA) error {
+//		texture = glutil.MakeTextureFromImage(img)
+//		return nil
+//	})
+//	...
+//	// each frame
+//	if !l.Done() {
+//		l.Update(5 * time.Millisecond)
+//		done, total, _ := l.Progress()
+//		drawProgress(done, total)
+//	}
+type Loader struct {
+	workers int
+
+	mu      sync.Mutex
+	queue   []*loadJob // waiting for a worker
+	ready   []*loadJob // loaded, waiting for Update
+	running int        // workers
+	total   int
+	done    int
+	last    string
+	errs    []error
+}
+
+type loadJob struct {
+	name   string
+	load   func() (any, error)
+	upload func(any) error
+	data   any
+	err    error
+}
+
+// NewLoader returns a loader that loads at most the given number of assets
+// at the same time.
+func NewLoader(workers int) *Loader {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Loader{workers: workers}
+}
+
+// Load queues an asset with a name, for Progress. The function load is
+// called on a worker goroutine, to read and decode it, and must not use
+// OpenGL. Then upload is called with the result by Update. Assets are
+// loaded in the order they were queued, but several at the same time, so
+// they may be uploaded in another order.
+//
+// Load can be called at any time, also from upload, for assets that a
+// loaded asset refers to.
+func Load[T any](l *Loader, name string, load func() (T, error), upload func(T) error) {
+	j := &loadJob{
+		name: name,
+		load: func() (any, error) {
+			return load()
+		},
+		upload: func(data any) error {
+			return upload(data.(T))
+		},
+	}
+
+	l.mu.Lock()
+	l.queue = append(l.queue, j)
+	l.total++
+	start := l.running < l.workers
+	if start {
+		l.running++
+	}
+	l.mu.Unlock()
+
+	if start {
+		go l.work()
+	}
+}
+
+// Image queues an image file, as by LoadImageOrPlaceholder.
+func (l *Loader) Image(filename string, upload func(img *image.RGBA) error) {
+	Load(l, filename, func() (*image.RGBA, error) {
+		return LoadImageOrPlaceholder(filename)
+	}, upload)
+}
+
+// work loads assets until the queue is empty.
+func (l *Loader) work() {
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.running--
+			l.mu.Unlock()
+			return
+		}
+		j := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mu.Unlock()
+
+		j.data, j.err = j.load()
+
+		l.mu.Lock()
+		l.ready = append(l.ready, j)
+		l.mu.Unlock()
+	}
+}
+
+// Update uploads loaded assets, until there are none left, or the budget
+// of time is used up, but at least one, so the frame rate stays up while
+// loading. Call it on the goroutine that has the OpenGL context, once per
+// frame.
+func (l *Loader) Update(budget time.Duration) {
+	start := time.Now()
+	for {
+		l.mu.Lock()
+		if len(l.ready) == 0 {
+			l.mu.Unlock()
+			return
+		}
+		j := l.ready[0]
+		l.ready = l.ready[1:]
+		l.mu.Unlock()
+
+		err := j.err
+		if err == nil {
+			err = j.upload(j.data)
+		}
+
+		l.mu.Lock()
+		l.done++
+		l.last = j.name
+		if err != nil {
+			l.errs = append(l.errs, err)
+		}
+		l.mu.Unlock()
+
+		if time.Since(start) >= budget {
+			return
+		}
+	}
+}
+
+// Progress returns the number of assets that are done, uploaded or failed,
+// the number that were queued, and the name of the last one that was done.
+func (l *Loader) Progress() (done, total int, last string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done, l.total, l.last
+}
+
+// Done reports whether all assets that were queued are done.
+func (l *Loader) Done() bool {
+	done, total, _ := l.Progress()
+	return done == total
+}
+
+// Errors returns the errors of the assets that failed so far.
+func (l *Loader) Errors() []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.errs...)
+}
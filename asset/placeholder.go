@@ -0,0 +1,84 @@
+package asset
+
+import (
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/font"
+
+	"image"
+	imagecolor "image/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Size of a placeholder image for a missing file.
+const placeholderSize = 256
+
+// LoadImageOrPlaceholder is LoadImage, but if the file doesn't exist, it
+// logs that, and returns a placeholder with the name of the file, so a
+// demo can still start, and shows what is missing. Other errors, such as
+// a file that can't be decoded, are returned as they are.
+func LoadImageOrPlaceholder(filename string) (*image.RGBA, error) {
+	rgba, err := LoadImage(filename)
+	if os.IsNotExist(err) {
+		app.Logger.Printf("%v, using a placeholder", err)
+		return Placeholder(filepath.Base(filename), placeholderSize, placeholderSize), nil
+	}
+	return rgba, err
+}
+
+// Placeholder returns an image of the given size that is obviously not the
+// real thing: a checkerboard with a rainbow gradient from left to right, so
+// the orientation of a texture can be seen, and the label in the middle,
+// on a black band.
+func Placeholder(label string, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	cell := (width + height) / 16
+	if cell < 1 {
+		cell = 1
+	}
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			c := color.Rainbow(float32(col) / float32(width) * 5 / 6)
+			if (row/cell+col/cell)%2 == 1 {
+				c = c.Mul(.4)
+			}
+			img.SetRGBA(col, row, imagecolor.RGBA{
+				R: uint8(255 * c[0]),
+				G: uint8(255 * c[1]),
+				B: uint8(255 * c[2]),
+				A: 255,
+			})
+		}
+	}
+
+	// as many characters per line as fit
+	scale := 1 + width/256
+	perLine := (width - 16) / (font.CellWidth * scale)
+	if perLine < 1 {
+		return img
+	}
+	lines := []string{"MISSING"}
+	for label != "" {
+		n := len(label)
+		if n > perLine {
+			n = perLine
+		}
+		lines = append(lines, label[:n])
+		label = label[n:]
+	}
+	text := strings.Join(lines, "\n")
+
+	textWidth, textHeight := font.Size(text, scale)
+	top := (height - textHeight) / 2
+	band := image.Rect(0, top-4*scale, width, top+textHeight+3*scale).Intersect(img.Rect)
+	for row := band.Min.Y; row < band.Max.Y; row++ {
+		for col := band.Min.X; col < band.Max.X; col++ {
+			img.SetRGBA(col, row, imagecolor.RGBA{A: 255})
+		}
+	}
+	font.Draw(img, text, (width-textWidth)/2, top, scale, imagecolor.White)
+
+	return img
+}
@@ -0,0 +1,80 @@
+package asset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Volume is a 3D grid of values from 0 to 1, such as the densities of a
+// CT scan.
+type Volume struct {
+	Width, Height, Depth int
+
+	// The values, with x changing fastest, then y, then z.
+	Data []float32
+}
+
+// LoadRawVolume reads a volume stored as raw unsigned values without a
+// header, with 8 or 16 bits (little-endian) per value. As the file doesn't
+// say, the size must be given.
+func LoadRawVolume(filename string, width, height, depth, bits int) (*Volume, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	v, err := DecodeRawVolume(bufio.NewReader(fp), width, height, depth, bits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return v, nil
+}
+
+// DecodeRawVolume reads a volume of raw values. 8-bit values are divided by
+// 255. 16-bit values are divided by the largest value in the volume, as
+// they often use fewer bits, such as 12 for most CT scans.
+func DecodeRawVolume(r io.Reader, width, height, depth, bits int) (*Volume, error) {
+	if width < 1 || height < 1 || depth < 1 {
+		return nil, fmt.Errorf("invalid size %dx%dx%d", width, height, depth)
+	}
+	if bits != 8 && bits != 16 {
+		return nil, fmt.Errorf("unsupported number of bits %d", bits)
+	}
+	n := width * height * depth
+	data := make([]byte, n*bits/8)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return nil, fmt.Errorf("file too short for %dx%dx%d values of %d bits", width, height, depth, bits)
+		}
+		return nil, err
+	}
+
+	v := &Volume{
+		Width:  width,
+		Height: height,
+		Depth:  depth,
+		Data:   make([]float32, n),
+	}
+	if bits == 8 {
+		for i, b := range data {
+			v.Data[i] = float32(b) / 255
+		}
+		return v, nil
+	}
+	var max uint16
+	for i := range v.Data {
+		if u := binary.LittleEndian.Uint16(data[2*i:]); u > max {
+			max = u
+		}
+	}
+	if max == 0 {
+		return v, nil
+	}
+	for i := range v.Data {
+		v.Data[i] = float32(binary.LittleEndian.Uint16(data[2*i:])) / float32(max)
+	}
+	return v, nil
+}
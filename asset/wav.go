@@ -0,0 +1,164 @@
+package asset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Sound is audio, with the samples of each channel from -1 to 1.
+type Sound struct {
+	Rate     int // samples per second
+	Channels [][]float32
+}
+
+// Mono returns the average of the channels.
+func (s *Sound) Mono() []float32 {
+	if len(s.Channels) == 1 {
+		return s.Channels[0]
+	}
+	mono := make([]float32, len(s.Channels[0]))
+	for _, c := range s.Channels {
+		for i, v := range c {
+			mono[i] += v / float32(len(s.Channels))
+		}
+	}
+	return mono
+}
+
+// LoadWAV reads a WAV file, with 8, 16, 24 or 32 bit integer samples, or
+// 32 bit floating point samples.
+func LoadWAV(filename string) (*Sound, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	s, err := DecodeWAV(bufio.NewReader(fp))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// DecodeWAV reads audio in the WAV format.
+func DecodeWAV(r io.Reader) (*Sound, error) {
+	var header struct {
+		Riff [4]byte
+		Size uint32
+		Wave [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Riff[:]) != "RIFF" || string(header.Wave[:]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	var format struct {
+		AudioFormat   uint16
+		Channels      uint16
+		Rate          uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+	}
+	haveFormat := false
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("no data")
+			}
+			return nil, err
+		}
+		// chunks are padded to an even size
+		size := int64(chunk.Size) + int64(chunk.Size&1)
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			if chunk.Size < 16 {
+				return nil, fmt.Errorf("invalid format")
+			}
+			if err := binary.Read(r, binary.LittleEndian, &format); err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(io.Discard, r, size-16); err != nil {
+				return nil, err
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("data before format")
+			}
+			data := make([]byte, chunk.Size)
+			// a truncated file still has sound
+			n, err := io.ReadFull(r, data)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			return decodeSamples(data[:n], int(format.AudioFormat), int(format.Channels), int(format.BitsPerSample), int(format.Rate))
+		default:
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Audio formats in the WAV header.
+const (
+	wavPCM        = 1
+	wavFloat      = 3
+	wavExtensible = 0xFFFE // the real format is further on, assume PCM or float by size
+)
+
+func decodeSamples(data []byte, audioFormat, channels, bits, rate int) (*Sound, error) {
+	if channels < 1 {
+		return nil, fmt.Errorf("no channels")
+	}
+	isFloat := audioFormat == wavFloat
+	switch {
+	case audioFormat == wavPCM && (bits == 8 || bits == 16 || bits == 24 || bits == 32):
+	case isFloat && bits == 32:
+	case audioFormat == wavExtensible && (bits == 16 || bits == 24):
+	default:
+		return nil, fmt.Errorf("unsupported format %d with %d bits", audioFormat, bits)
+	}
+
+	size := bits / 8
+	n := len(data) / (size * channels)
+	s := &Sound{
+		Rate:     rate,
+		Channels: make([][]float32, channels),
+	}
+	for c := range s.Channels {
+		s.Channels[c] = make([]float32, n)
+	}
+	for i := 0; i < n; i++ {
+		for c := 0; c < channels; c++ {
+			b := data[(i*channels+c)*size:]
+			var v float32
+			switch {
+			case isFloat:
+				v = math.Float32frombits(binary.LittleEndian.Uint32(b))
+			case bits == 8:
+				// unsigned
+				v = (float32(b[0]) - 128) / 128
+			case bits == 16:
+				v = float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+			case bits == 24:
+				v = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+			case bits == 32:
+				v = float32(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+			}
+			s.Channels[c][i] = v
+		}
+	}
+	return s, nil
+}
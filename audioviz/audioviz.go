@@ -0,0 +1,584 @@
+// An audio visualizer: the spectrum of a sound, computed with an FFT, shown
+// as bars, with a line at the recent peak of each.
+//
+// With -mic, audio is recorded from the default microphone, with PortAudio.
+// This needs the PortAudio library, and a build with the tag portaudio:
+//
+//	go build -tags portaudio
+//
+// Audio is also taken from a WAV file, or read as raw 16-bit samples from
+// standard input, which can be the output of a program that records from
+// a microphone, for example on Linux:
+//
+//	arecord -f S16_LE -c 1 -r 44100 | audioviz -file -
+//
+// The file isn't played, only shown, at the speed it would be played at.
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"io"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+var (
+	// The waveform, a vertex for each sample, with x from gl_VertexID.
+	wave_vertex_glsl = `
+#version 330 core
+
+uniform int count;
+
+layout(location = 0) in float sample;
+
+void main()
+{
+    float x = -1.0 + 2.0 * float(gl_VertexID) / float(count - 1);
+    gl_Position = vec4(x, 0.65 + 0.3 * sample, 0.0, 1.0);
+}
+` + "\x00"
+
+	wave_fragment_glsl = `
+#version 330 core
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(0.4, 0.9, 1.0, 1.0);
+}
+` + "\x00"
+
+	// The spectrum, an instance for each bar with its level and its peak,
+	// both from 0 to 1, and the corners from gl_VertexID.
+	bars_vertex_glsl = `
+#version 330 core
+
+uniform int count;
+uniform bool peaks;
+
+layout(location = 0) in vec2 bar; // level, peak
+
+out float level;
+
+void main()
+{
+    // a triangle strip: (0, 0), (1, 0), (0, 1), (1, 1)
+    vec2 corner = vec2(gl_VertexID & 1, gl_VertexID >> 1);
+    float w = 2.0 / float(count);
+    float x = -1.0 + w * (float(gl_InstanceID) + 0.1 + 0.8 * corner.x);
+
+    // a bar up to the level, or a thin line at the peak
+    float y0 = peaks ? bar.y - 0.01 : 0.0;
+    float y1 = peaks ? bar.y : bar.x;
+    level = mix(y0, y1, corner.y);
+    gl_Position = vec4(x, -0.95 + 1.2 * level, 0.0, 1.0);
+}
+` + "\x00"
+
+	bars_fragment_glsl = `
+#version 330 core
+
+in float level;
+
+out vec4 fragColor;
+
+void main()
+{
+    // from green through yellow to red
+    vec3 c = mix(vec3(0.1, 0.8, 0.2), vec3(1.0, 0.9, 0.1), smoothstep(0.3, 0.7, level));
+    c = mix(c, vec3(1.0, 0.2, 0.1), smoothstep(0.75, 0.95, level));
+    fragColor = vec4(c, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	file     = flag.String("file", "", "WAV file, or - for raw signed 16-bit little-endian samples on standard input; without it, a generated sound")
+	mic      = flag.Bool("mic", false, "record from the default microphone, needs a build with -tags portaudio")
+	rateFlag = flag.Int("rate", 44100, "sample rate of the input on standard input or from the microphone")
+	channels = flag.Int("channels", 1, "number of channels of the input on standard input")
+	fftSize  = flag.Int("fft", 2048, "number of samples for the FFT, a power of 2")
+	barCount = flag.Int("bars", 64, "number of bars of the spectrum")
+)
+
+// Extra action for this demo.
+const actionLog = "log"
+
+//
+// Sources of sound
+//
+
+// source gives the most recent samples of a sound, in mono.
+type source interface {
+	Rate() int
+	// Latest fills dst with the samples up to now.
+	Latest(dst []float32)
+}
+
+// soundSource follows a sound, as if it were played, over and over.
+type soundSource struct {
+	samples []float32
+	rate    int
+}
+
+func (s *soundSource) Rate() int {
+	return s.rate
+}
+
+func (s *soundSource) Latest(dst []float32) {
+	n := len(s.samples)
+	end := int(clock.Seconds() * float64(s.rate))
+	for i := range dst {
+		j := (end - len(dst) + i) % n
+		if j < 0 {
+			j += n
+		}
+		dst[i] = s.samples[j]
+	}
+}
+
+// streamSource reads samples as they arrive, and keeps the most recent
+// ones in a ring buffer.
+type streamSource struct {
+	rate int
+
+	mu    sync.Mutex
+	ring  []float32
+	write int // where the next sample goes
+}
+
+func newStreamSource(rate int) *streamSource {
+	return &streamSource{
+		rate: rate,
+		ring: make([]float32, rate), // a second
+	}
+}
+
+// newReaderSource reads raw signed 16-bit little-endian samples from r.
+func newReaderSource(r io.Reader, rate, channels int) *streamSource {
+	s := newStreamSource(rate)
+	go s.read(bufio.NewReader(r), channels)
+	return s
+}
+
+func (s *streamSource) read(r io.Reader, channels int) {
+	frame := make([]int16, channels)
+	for {
+		if err := binary.Read(r, binary.LittleEndian, frame); err != nil {
+			if err != io.EOF {
+				app.Error(err)
+			}
+			return
+		}
+		var v float32
+		for _, f := range frame {
+			v += float32(f) / (1 << 15) / float32(channels)
+		}
+		s.add(v)
+	}
+}
+
+// add puts samples that arrived in the ring buffer.
+func (s *streamSource) add(samples ...float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range samples {
+		s.ring[s.write] = v
+		s.write = (s.write + 1) % len(s.ring)
+	}
+}
+
+func (s *streamSource) Rate() int {
+	return s.rate
+}
+
+func (s *streamSource) Latest(dst []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.ring)
+	for i := range dst {
+		dst[i] = s.ring[((s.write-len(dst)+i)%n+n)%n]
+	}
+}
+
+// synthSource makes up a sound: a bass note on every beat, a chord that
+// slowly moves up and down, and a bit of noise.
+type synthSource struct {
+	rate int
+}
+
+func (s *synthSource) Rate() int {
+	return s.rate
+}
+
+func (s *synthSource) Latest(dst []float32) {
+	end := clock.Seconds()
+	for i := range dst {
+		t := end - float64(len(dst)-i)/float64(s.rate)
+		beat := math.Mod(t*2, 1)
+		v := .4 * math.Exp(-6*beat) * math.Sin(2*math.Pi*55*t)
+		f := 220 * math.Pow(2, math.Sin(t*.3))
+		for _, m := range []float64{1, 1.25, 1.5, 3} {
+			v += .1 * math.Sin(2*math.Pi*f*m*t)
+		}
+		v += .02 * rand.NormFloat64()
+		dst[i] = float32(v)
+	}
+}
+
+//
+// The spectrum
+//
+
+// fft is an in-place radix-2 fast Fourier transform. The length of a must
+// be a power of 2.
+func fft(a []complex128) {
+	n := len(a)
+	// bit-reversed order
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := a[start+k], w*a[start+k+size/2]
+				a[start+k] = even + odd
+				a[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// analyzer turns samples into levels of frequency bands.
+type analyzer struct {
+	window  []float64 // Hann
+	gain    float64   // to make a full-scale sine 0 dB
+	buf     []complex128
+	bands   [][2]int // first and last bin of each bar
+	logFreq bool
+}
+
+func newAnalyzer(size, bars, rate int, logFreq bool) *analyzer {
+	a := &analyzer{
+		window:  make([]float64, size),
+		buf:     make([]complex128, size),
+		bands:   make([][2]int, bars),
+		logFreq: logFreq,
+	}
+	var sum float64
+	for i := range a.window {
+		a.window[i] = .5 - .5*math.Cos(2*math.Pi*float64(i)/float64(size-1))
+		sum += a.window[i]
+	}
+	a.gain = 2 / sum
+
+	// bands from 30 Hz, or the first bin, to half the sample rate, evenly
+	// spread on a logarithmic or a linear scale
+	binWidth := float64(rate) / float64(size)
+	lo, hi := math.Max(30, binWidth), float64(rate)/2
+	prev := 0
+	for i := range a.bands {
+		t := float64(i+1) / float64(bars)
+		f := lo + (hi-lo)*t
+		if logFreq {
+			f = lo * math.Pow(hi/lo, t)
+		}
+		last := int(f / binWidth)
+		if last >= size/2 {
+			last = size/2 - 1
+		}
+		first := prev + 1
+		if first > last {
+			first = last
+		}
+		a.bands[i] = [2]int{first, last}
+		prev = last
+	}
+	return a
+}
+
+// levels sets the level of each band, from 0 for -80 dB to 1 for 0 dB.
+func (a *analyzer) levels(samples []float32, levels []float32) {
+	for i, v := range samples {
+		a.buf[i] = complex(float64(v)*a.window[i], 0)
+	}
+	fft(a.buf)
+	for i, b := range a.bands {
+		var m float64
+		for k := b[0]; k <= b[1]; k++ {
+			m = math.Max(m, cmplx.Abs(a.buf[k]))
+		}
+		db := 20 * math.Log10(m*a.gain+1e-12)
+		levels[i] = float32(math.Max(0, math.Min(1, (db+80)/80)))
+	}
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	waveProgram *glutil.Program
+	barsProgram *glutil.Program
+
+	// streamed every frame: the samples, and the level and peak of each bar
+	waveVAO    uint32
+	waveStream *glutil.StreamBuffer
+	barsVAO    uint32
+	barsStream *glutil.StreamBuffer
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.waveProgram, err = glutil.NewProgram(wave_vertex_glsl, wave_fragment_glsl)
+	x(err)
+	r.barsProgram, err = glutil.NewProgram(bars_vertex_glsl, bars_fragment_glsl)
+	x(err)
+
+	gl.GenVertexArrays(1, &r.waveVAO)
+	gl.BindVertexArray(r.waveVAO)
+	r.waveStream = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, 4**fftSize)
+	gl.EnableVertexAttribArray(0)
+
+	gl.GenVertexArrays(1, &r.barsVAO)
+	gl.BindVertexArray(r.barsVAO)
+	r.barsStream = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, 8**barCount)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribDivisor(0, 1)
+	gl.BindVertexArray(0)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+
+	sound    source
+	analyze  *analyzer
+	samples  []float32
+	bars     []float32 // level and peak of each bar
+	current  []float32
+	peakHold []float64 // seconds left before the peak falls
+)
+
+func update() {
+	sound.Latest(samples)
+	analyze.levels(samples, current)
+
+	// bars fall slowly, peaks stay for a while first
+	dt := float32(clock.Delta())
+	for i, c := range current {
+		level, peak := &bars[2*i], &bars[2*i+1]
+		*level -= 1.5 * dt
+		if c > *level {
+			*level = c
+		}
+		peakHold[i] -= float64(dt)
+		if peakHold[i] < 0 {
+			*peak -= .5 * dt
+		}
+		if c > *peak {
+			*peak = c
+			peakHold[i] = .5
+		}
+		if *level < 0 {
+			*level = 0
+		}
+		if *peak < 0 {
+			*peak = 0
+		}
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.05, .05, .08, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	glutil.State{}.Apply()
+
+	// the waveform
+	p := r.waveProgram
+	p.Use()
+	p.SetInt("count", int32(len(samples)))
+	gl.BindVertexArray(r.waveVAO)
+	offset := r.waveStream.Write(unsafe.Pointer(&samples[0]), 4*len(samples))
+	// the offset changes with each write to a persistent buffer
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.waveStream.Buffer)
+	gl.VertexAttribPointer(0, 1, gl.FLOAT, false, 4, gl.PtrOffset(offset))
+	gl.DrawArrays(gl.LINE_STRIP, 0, int32(len(samples)))
+	r.waveStream.Done()
+
+	// the spectrum, bars and peaks from the same data
+	p = r.barsProgram
+	p.Use()
+	p.SetInt("count", int32(*barCount))
+	gl.BindVertexArray(r.barsVAO)
+	offset = r.barsStream.Write(unsafe.Pointer(&bars[0]), 4*len(bars))
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.barsStream.Buffer)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 8, gl.PtrOffset(offset))
+	p.SetBool("peaks", false)
+	gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(*barCount))
+	p.SetBool("peaks", true)
+	gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(*barCount))
+	r.barsStream.Done()
+
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Keys[actionLog] = []string{"l"}
+	app.Flags(800, 600, "Audio visualizer")
+	if n := *fftSize; n < 64 || n&(n-1) != 0 {
+		app.Fatal("-fft must be a power of 2, at least 64")
+	}
+	if *barCount < 1 || *barCount > *fftSize/4 {
+		app.Fatalf("-bars must be from 1 to %d", *fftSize/4)
+	}
+
+	switch {
+	case *mic:
+		if *rateFlag < 1 {
+			app.Fatal("-rate must be positive")
+		}
+		s, stop, err := newMicSource(*rateFlag)
+		x(err)
+		defer stop()
+		sound = s
+		app.Infof("Recording from the microphone, %d Hz", *rateFlag)
+	case *file == "":
+		sound = &synthSource{rate: 44100}
+	case *file == "-":
+		if *rateFlag < 1 || *channels < 1 {
+			app.Fatal("-rate and -channels must be positive")
+		}
+		sound = newReaderSource(os.Stdin, *rateFlag, *channels)
+	default:
+		s, err := asset.LoadWAV(*file)
+		x(err)
+		mono := s.Mono()
+		if len(mono) == 0 {
+			app.Fatal(*file, "has no samples")
+		}
+		sound = &soundSource{samples: mono, rate: s.Rate}
+		app.Infof("%s: %d channels, %d Hz, %.1f seconds", *file, len(s.Channels), s.Rate, float64(len(mono))/float64(s.Rate))
+	}
+	samples = make([]float32, *fftSize)
+	current = make([]float32, *barCount)
+	bars = make([]float32, 2**barCount)
+	peakHold = make([]float64, *barCount)
+	analyze = newAnalyzer(*fftSize, *barCount, sound.Rate(), true)
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+
+	if r.waveStream.Persistent() {
+		app.Info("Streaming with persistently mapped buffers")
+	} else {
+		app.Info("Streaming by orphaning buffers, persistent mapping needs OpenGL 4.4")
+	}
+	app.Infof("FFT of %d samples, %.1f Hz per bin", *fftSize, float64(sound.Rate())/float64(*fftSize))
+	app.Info("Press 'l' to switch between a logarithmic and a linear frequency scale")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		if !clock.Paused() {
+			update()
+		}
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionLog:
+		analyze = newAnalyzer(*fftSize, *barCount, sound.Rate(), !analyze.logFreq)
+		if analyze.logFreq {
+			app.Info("Logarithmic frequency scale")
+		} else {
+			app.Info("Linear frequency scale")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
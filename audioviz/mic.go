@@ -0,0 +1,35 @@
+//go:build portaudio
+
+package main
+
+import (
+	"github.com/gordonklaus/portaudio"
+)
+
+// newMicSource records from the default input device, in mono, with
+// PortAudio. The samples arrive on a thread of PortAudio, and go into the
+// ring buffer of a streamSource. stop ends the recording.
+func newMicSource(rate int) (src source, stop func(), err error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, nil, err
+	}
+	s := newStreamSource(rate)
+	stream, err := portaudio.OpenDefaultStream(1, 0, float64(rate), 0, func(in []float32) {
+		s.add(in...)
+	})
+	if err != nil {
+		portaudio.Terminate()
+		return nil, nil, err
+	}
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		portaudio.Terminate()
+		return nil, nil, err
+	}
+	stop = func() {
+		stream.Stop()
+		stream.Close()
+		portaudio.Terminate()
+	}
+	return s, stop, nil
+}
@@ -0,0 +1,12 @@
+//go:build !portaudio
+
+package main
+
+import (
+	"errors"
+)
+
+// newMicSource fails without PortAudio: see mic.go.
+func newMicSource(rate int) (src source, stop func(), err error) {
+	return nil, nil, errors.New("-mic: built without PortAudio, build with -tags portaudio")
+}
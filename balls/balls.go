@@ -0,0 +1,384 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/postfx"
+
+	"flag"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+var (
+	// A square around each ball, from gl_VertexID, with the circle cut out
+	// in the fragment shader.
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 projection;
+
+layout(location = 0) in vec3 ball; // center, radius
+layout(location = 1) in vec3 color;
+
+out vec2 local;
+out vec3 baseColor;
+flat out float radius;
+
+void main()
+{
+    // a triangle strip: (-1, -1), (1, -1), (-1, 1), (1, 1)
+    local = vec2(gl_VertexID & 1, gl_VertexID >> 1) * 2.0 - 1.0;
+    baseColor = color;
+    radius = ball.z;
+    gl_Position = projection * vec4(ball.xy + ball.z * local, 0.0, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+in vec2 local;
+in vec3 baseColor;
+flat in float radius;
+
+out vec4 fragColor;
+
+void main()
+{
+    float r = length(local);
+    // one pixel of anti-aliasing at the edge
+    float alpha = clamp((1.0 - r) * radius, 0.0, 1.0);
+    if (alpha == 0.0) {
+        discard;
+    }
+    // lit like a sphere, from the top left
+    vec3 n = vec3(local, sqrt(max(1.0 - r * r, 0.0)));
+    float diffuse = max(dot(n, normalize(vec3(-0.5, 0.5, 1.0))), 0.0);
+    fragColor = vec4((0.3 + 0.7 * diffuse) * baseColor, alpha);
+}
+` + "\x00"
+)
+
+var (
+	ballCount = flag.Int("balls", 200, "number of balls")
+	rate      = flag.Float64("rate", 60, "physics updates per second")
+)
+
+// Extra actions for this demo.
+const (
+	actionInterpolate = "interpolate"
+	actionRate        = "rate"
+)
+
+//
+// The simulation, in pixels, with y up
+//
+
+const (
+	gravity     = 1500 // pixels per second squared
+	restitution = .85  // fraction of the speed kept in a collision
+)
+
+type tBall struct {
+	position glm.Vec2
+	previous glm.Vec2 // position before the last update, for interpolation
+	velocity glm.Vec2
+	radius   float32
+	mass     float32
+	color    glm.Vec3
+}
+
+var balls []*tBall
+
+func addBall(x, y float32) {
+	r := 6 + 18*rand.Float32()*rand.Float32()
+	p := glm.Vec2{x, y}
+	balls = append(balls, &tBall{
+		position: p,
+		previous: p,
+		velocity: glm.Vec2{rand.Float32()*400 - 200, rand.Float32() * 200},
+		radius:   r,
+		mass:     r * r,
+		color:    color.HSV(rand.Float32(), .7, .95),
+	})
+}
+
+// step advances the simulation by dt seconds, in a window of width by
+// height pixels.
+func step(dt float32, width, height float32) {
+	for _, b := range balls {
+		b.previous = b.position
+		b.velocity[1] -= gravity * dt
+		b.position = b.position.Add(b.velocity.Mul(dt))
+	}
+
+	// each pair once, fine for a few hundred balls
+	for i, a := range balls {
+		for _, b := range balls[i+1:] {
+			collide(a, b)
+		}
+	}
+
+	for _, b := range balls {
+		for axis, limit := range [2]float32{width, height} {
+			if b.position[axis] < b.radius {
+				b.position[axis] = b.radius
+				if b.velocity[axis] < 0 {
+					b.velocity[axis] *= -restitution
+				}
+			} else if b.position[axis] > limit-b.radius {
+				b.position[axis] = limit - b.radius
+				if b.velocity[axis] > 0 {
+					b.velocity[axis] *= -restitution
+				}
+			}
+		}
+	}
+}
+
+// collide separates two balls that overlap, and if they move towards each
+// other, exchanges momentum along the line between their centers.
+func collide(a, b *tBall) {
+	d := b.position.Sub(a.position)
+	dist := float32(math.Hypot(float64(d[0]), float64(d[1])))
+	overlap := a.radius + b.radius - dist
+	if overlap <= 0 || dist == 0 {
+		return
+	}
+	n := d.Mul(1 / dist)
+	wa, wb := 1/a.mass, 1/b.mass
+
+	// the lighter ball moves the most
+	a.position = a.position.Sub(n.Mul(overlap * wa / (wa + wb)))
+	b.position = b.position.Add(n.Mul(overlap * wb / (wa + wb)))
+
+	rv := b.velocity.Sub(a.velocity)
+	vn := rv[0]*n[0] + rv[1]*n[1]
+	if vn >= 0 {
+		return
+	}
+	j := -(1 + restitution) * vn / (wa + wb)
+	a.velocity = a.velocity.Sub(n.Mul(j * wa))
+	b.velocity = b.velocity.Add(n.Mul(j * wb))
+}
+
+//
+// Global data used by render
+//
+
+// Data of a ball for the shader: center, radius, and color.
+type tInstance struct {
+	Ball  glm.Vec3
+	Color glm.Vec3
+}
+
+const instanceSize = int(unsafe.Sizeof(tInstance{}))
+
+// Room in the instance buffer, for the balls added with the mouse.
+const maxBalls = 10000
+
+type gResources struct {
+	program   *glutil.Program
+	vao       uint32
+	instances *glutil.StreamBuffer
+
+	effects *postfx.Chain // nil without -crt
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.BindVertexArray(r.vao)
+	r.instances = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, maxBalls*instanceSize)
+	for i := uint32(0); i < 2; i++ {
+		gl.EnableVertexAttribArray(i)
+		gl.VertexAttribDivisor(i, 1)
+	}
+	gl.BindVertexArray(0)
+
+	r.effects, err = postfx.OptionalCRT()
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock       = app.NewClock()
+	steps       *app.FixedStep
+	interpolate = true
+	slow        = false
+
+	instances []tInstance
+)
+
+var stateBalls = glutil.State{
+	Blend: true,
+}
+
+func render(w *glfw.Window, r *gResources, alpha float32) {
+	width, height := w.GetFramebufferSize()
+	x(r.effects.Begin(int32(width), int32(height)))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.12, .12, .15, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	drawBalls(w, r, alpha)
+
+	x(r.effects.End(nil))
+}
+
+// drawBalls draws the balls, a fraction alpha of the way from their
+// previous to their current position.
+func drawBalls(w *glfw.Window, r *gResources, alpha float32) {
+	if !interpolate {
+		alpha = 1
+	}
+	instances = instances[:0]
+	for _, b := range balls {
+		p := b.previous.Add(b.position.Sub(b.previous).Mul(alpha))
+		instances = append(instances, tInstance{glm.Vec3{p[0], p[1], b.radius}, b.color})
+	}
+	if len(instances) == 0 {
+		return
+	}
+
+	sw, sh := w.GetSize()
+	stateBalls.Apply()
+	p := r.program
+	p.Use()
+	p.SetMat4("projection", glm.Ortho(0, float32(sw), 0, float32(sh), -1, 1))
+
+	gl.BindVertexArray(r.vao)
+	offset := r.instances.Write(unsafe.Pointer(&instances[0]), len(instances)*instanceSize)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.instances.Buffer)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, int32(instanceSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, int32(instanceSize), gl.PtrOffset(offset+12))
+	gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(len(instances)))
+	r.instances.Done()
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Keys[actionInterpolate] = []string{"i"}
+	app.Keys[actionRate] = []string{"r"}
+	app.Flags(800, 600, "Bouncing balls")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	steps = app.NewFixedStep(*rate)
+	for i := 0; i < *ballCount && i < maxBalls; i++ {
+		addBall(rand.Float32()*float32(app.Width), float32(app.Height)*(.5+rand.Float32()/2))
+	}
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button != glfw.MouseButtonLeft || action != glfw.Press {
+			return
+		}
+		cx, cy := w.GetCursorPos()
+		_, sh := w.GetSize()
+		for i := 0; i < 10 && len(balls) < maxBalls; i++ {
+			addBall(float32(cx)+rand.Float32()*20-10, float32(sh)-float32(cy)+rand.Float32()*20-10)
+		}
+	})
+
+	app.Info("Click to add balls")
+	app.Info("Press 'i' to toggle interpolation, 'r' to switch between the normal and a low update rate")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		sw, sh := w.GetSize()
+		alpha := steps.Advance(clock.Delta(), func(dt float64) {
+			step(float32(dt), float32(sw), float32(sh))
+		})
+		render(w, r, float32(alpha))
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionInterpolate:
+		interpolate = !interpolate
+		if interpolate {
+			app.Info("Drawing positions interpolated between updates")
+		} else {
+			app.Info("Drawing positions of the last update")
+		}
+	case actionRate:
+		// a low rate shows what interpolation does
+		slow = !slow
+		if slow {
+			steps.Step = 1 / 10.
+		} else {
+			steps.Step = 1 / *rate
+		}
+		app.Infof("%.0f updates per second", 1/steps.Step)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/postfx"
+
+	"flag"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = normalMatrix * normal;
+}
+`
+
+	// Lit objects, and emissive objects with colors brighter than 1.
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+uniform bool emissive;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    if (emissive) {
+        fragColor = vec4(color, 1.0);
+        return;
+    }
+    vec3 l = normalize(vec3(0.3, 1.0, 0.5));
+    float diffuse = max(dot(normalize(worldNormal), l), 0.0);
+    fragColor = vec4((0.1 + 0.6 * diffuse) * color, 1.0);
+}
+`
+
+	copy_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = texture(source, uv);
+}
+`
+)
+
+var (
+	threshold = flag.Float64("threshold", 1, "brightness above which pixels glow")
+	intensity = flag.Float64("intensity", 1, "strength of the glow")
+	levels    = flag.Int("levels", 5, "number of blur levels")
+)
+
+// Extra action for this demo.
+const actionBloom = "bloom"
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	copy    *glutil.Program
+	scene   *glutil.Framebuffer
+	bloom   *postfx.Bloom
+
+	sphere *mesh.VAO
+	cube   *mesh.VAO
+	ground *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		sphere: mesh.Sphere(32, 16).Upload(),
+		cube:   mesh.Cube().Upload(),
+		ground: mesh.Quad().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.copy, err = glutil.NewProgram(glutil.FullscreenVertexShader, copy_glsl)
+	x(err)
+	r.bloom, err = postfx.NewBloom(*levels)
+	x(err)
+	r.bloom.Threshold = float32(*threshold)
+	r.bloom.Intensity = float32(*intensity)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock    = app.NewClock()
+	zoom     = input.NewZoom(.5, 3)
+	useBloom = true
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func drawScene(r *gResources, width, height int) {
+	t := clock.Seconds()
+
+	eye := glm.Vec3{0, 3, 8 / float32(zoom.Scale())}
+	p := r.program
+	p.Use()
+	p.SetMat4("view", glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0}))
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100))
+
+	draw := func(vao *mesh.VAO, model glm.Mat4, color glm.Vec3, emissive bool) {
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetVec3("color", color)
+		p.SetBool("emissive", emissive)
+		vao.Draw()
+	}
+
+	draw(r.ground,
+		glm.Translate(0, -1, 0).Mul(glm.Scale(6, 6, 6)).Mul(glm.Rotate(-math.Pi/2, glm.Vec3{1, 0, 0})),
+		glm.Vec3{.3, .3, .35}, false)
+	for i := 0; i < 3; i++ {
+		a := float32(i) * 2 * math.Pi / 3
+		draw(r.cube,
+			glm.Rotate(a, glm.Vec3{0, 1, 0}).Mul(glm.Translate(0, -.5, 2.5)).Mul(glm.Scale(.5, .5, .5)),
+			glm.Vec3{.8, .8, .8}, false)
+	}
+
+	// glowing spheres, pulsating, with colors up to 4 times too bright
+	for i := 0; i < 5; i++ {
+		a := float64(i)*2*math.Pi/5 + t/3
+		red, green, blue := hsb2rgb(float32(i)/5, .8, 1)
+		glow := float32(2.5 + 1.5*math.Sin(2*t+float64(i)))
+		draw(r.sphere,
+			glm.Translate(1.5*float32(math.Cos(a)), .3*float32(math.Sin(3*a)), 1.5*float32(math.Sin(a))).Mul(glm.Scale(.25, .25, .25)),
+			glm.Vec3{red, green, blue}.Mul(glow), true)
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	if r.scene == nil || r.scene.Width != int32(width) || r.scene.Height != int32(height) {
+		if r.scene != nil {
+			r.scene.Delete()
+		}
+		var err error
+		r.scene, err = glutil.NewMultiFramebuffer(int32(width), int32(height), glutil.FormatRGBA16F)
+		x(err)
+	}
+
+	// the scene, in high dynamic range
+	r.scene.Bind()
+	state3D.Apply()
+	gl.ClearColor(.02, .02, .04, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawScene(r, width, height)
+	r.scene.Unbind()
+
+	if useBloom {
+		x(r.bloom.Apply(r.scene.Texture, int32(width), int32(height), nil))
+		return
+	}
+
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+	r.copy.Use()
+	r.copy.SetInt("source", 0)
+	gl.BindTexture(gl.TEXTURE_2D, r.scene.Texture)
+	glutil.DrawFullscreen()
+}
+
+func main() {
+	app.Keys[actionBloom] = []string{"b"}
+	app.Flags(800, 600, "Bloom")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Press 'b' to toggle bloom")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionBloom:
+		useBloom = !useBloom
+		if useBloom {
+			app.Info("Bloom: on")
+		} else {
+			app.Info("Bloom: off")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	m := b - c
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c + m, x + m, m
+	}
+	if h < 2 {
+		return x + m, c + m, m
+	}
+	if h < 3 {
+		return m, c + m, x + m
+	}
+	if h < 4 {
+		return m, x + m, c + m
+	}
+	if h < 5 {
+		return x + m, m, c + m
+	}
+	return c + m, m, x + m
+}
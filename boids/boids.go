@@ -0,0 +1,467 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/postfx"
+
+	"flag"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+var (
+	// A triangle pointing along the heading of each boid, from gl_VertexID.
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 projection;
+
+layout(location = 0) in vec4 boid; // position, heading
+layout(location = 1) in vec3 color;
+
+out vec3 boidColor;
+
+const vec2 shape[3] = vec2[](vec2(1.0, 0.0), vec2(-0.6, 0.5), vec2(-0.6, -0.5));
+
+void main()
+{
+    vec2 h = boid.zw;
+    vec2 p = 6.0 * shape[gl_VertexID];
+    p = vec2(p.x * h.x - p.y * h.y, p.x * h.y + p.y * h.x);
+    boidColor = color;
+    gl_Position = projection * vec4(boid.xy + p, 0.0, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+in vec3 boidColor;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(boidColor, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	boidCount = flag.Int("boids", 3000, "number of boids")
+)
+
+// Extra action for this demo.
+const (
+	actionGrid = "grid"
+)
+
+//
+// The simulation, in pixels, with y up, on a window that wraps around
+//
+
+const (
+	radius        = 40  // boids see their neighbours up to this distance
+	personalSpace = 15  // and keep away from those that are closer
+	minSpeed      = 60  // pixels per second
+	maxSpeed      = 160 // pixels per second
+
+	separation = 40 // weights of the three rules
+	alignment  = 1
+	cohesion   = .8
+
+	fleeRadius = 120 // around the cursor, with the mouse button down
+	flee       = 600
+)
+
+type tBoid struct {
+	position glm.Vec2
+	velocity glm.Vec2
+	color    glm.Vec3
+}
+
+var (
+	boids []tBoid
+
+	// new velocities, so all boids see the same state of their neighbours
+	velocities []glm.Vec2
+
+	// The spatial hash: a grid of cells of radius by radius pixels. The
+	// boids in cell i are in order[start[i]:start[i+1]].
+	cols, rows int
+	start      []int
+	order      []int
+)
+
+func makeBoids(n int, width, height float32) {
+	boids = make([]tBoid, n)
+	velocities = make([]glm.Vec2, n)
+	order = make([]int, n)
+	for i := range boids {
+		a := 2 * math.Pi * rand.Float64()
+		s := minSpeed + (maxSpeed-minSpeed)*rand.Float32()
+		boids[i] = tBoid{
+			position: glm.Vec2{width * rand.Float32(), height * rand.Float32()},
+			velocity: glm.Vec2{s * float32(math.Cos(a)), s * float32(math.Sin(a))},
+			color:    color.HSV(.45+.2*rand.Float32(), .6, .95),
+		}
+	}
+}
+
+// cell returns the column and row of the cell with position p.
+func cell(p glm.Vec2) (int, int) {
+	c, r := int(p[0]/radius), int(p[1]/radius)
+	if c >= cols {
+		c = cols - 1
+	}
+	if r >= rows {
+		r = rows - 1
+	}
+	return c, r
+}
+
+// makeGrid sorts the boids into the cells of the spatial hash, with a
+// counting sort.
+func makeGrid(width, height float32) {
+	cols, rows = int(width/radius), int(height/radius)
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	if len(start) != cols*rows+1 {
+		start = make([]int, cols*rows+1)
+	}
+	for i := range start {
+		start[i] = 0
+	}
+	for _, b := range boids {
+		c, r := cell(b.position)
+		start[r*cols+c+1]++
+	}
+	for i := 1; i < len(start); i++ {
+		start[i] += start[i-1]
+	}
+	next := make([]int, cols*rows)
+	copy(next, start)
+	for i, b := range boids {
+		c, r := cell(b.position)
+		k := r*cols + c
+		order[next[k]] = i
+		next[k]++
+	}
+}
+
+// step advances the simulation by dt seconds, in a window of width by
+// height pixels. With useGrid, only the boids in the cells around a boid
+// are considered as neighbours, else all of them. If cursor isn't nil,
+// boids flee from it.
+func step(dt float32, width, height float32, useGrid bool, cursor *glm.Vec2) {
+	// the grid needs three cells in each direction, or neighbouring cells
+	// wrap around to the same cell twice
+	if useGrid {
+		makeGrid(width, height)
+		useGrid = cols >= 3 && rows >= 3
+	}
+
+	for i := range boids {
+		b := &boids[i]
+		var sep, align, center glm.Vec2
+		n := 0
+		visit := func(j int) {
+			if j == i {
+				return
+			}
+			d := wrap(boids[j].position.Sub(b.position), width, height)
+			dist2 := d[0]*d[0] + d[1]*d[1]
+			if dist2 > radius*radius || dist2 == 0 {
+				return
+			}
+			n++
+			align = align.Add(boids[j].velocity)
+			center = center.Add(d)
+			if dist2 < personalSpace*personalSpace {
+				// stronger when closer
+				sep = sep.Sub(d.Mul(1 / dist2))
+			}
+		}
+		if useGrid {
+			c, r := cell(b.position)
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					k := (r+dr+rows)%rows*cols + (c+dc+cols)%cols
+					for _, j := range order[start[k]:start[k+1]] {
+						visit(j)
+					}
+				}
+			}
+		} else {
+			for j := range boids {
+				visit(j)
+			}
+		}
+
+		v := b.velocity
+		if n > 0 {
+			inv := 1 / float32(n)
+			v = v.Add(sep.Mul(separation * radius * dt))
+			v = v.Add(align.Mul(inv).Sub(b.velocity).Mul(alignment * dt))
+			v = v.Add(center.Mul(inv * cohesion * dt))
+		}
+		if cursor != nil {
+			d := wrap(b.position.Sub(*cursor), width, height)
+			if l := length(d); l < fleeRadius && l > 0 {
+				v = v.Add(d.Mul(flee * dt / l))
+			}
+		}
+		velocities[i] = limit(v)
+	}
+
+	for i := range boids {
+		b := &boids[i]
+		b.velocity = velocities[i]
+		b.position = b.position.Add(b.velocity.Mul(dt))
+		b.position[0] = float32(math.Mod(float64(b.position[0]+width), float64(width)))
+		b.position[1] = float32(math.Mod(float64(b.position[1]+height), float64(height)))
+	}
+}
+
+// wrap returns the shortest vector for d, with the window wrapping around.
+func wrap(d glm.Vec2, width, height float32) glm.Vec2 {
+	if d[0] > width/2 {
+		d[0] -= width
+	} else if d[0] < -width/2 {
+		d[0] += width
+	}
+	if d[1] > height/2 {
+		d[1] -= height
+	} else if d[1] < -height/2 {
+		d[1] += height
+	}
+	return d
+}
+
+// limit keeps the speed between minSpeed and maxSpeed.
+func limit(v glm.Vec2) glm.Vec2 {
+	s := length(v)
+	switch {
+	case s == 0:
+		return glm.Vec2{minSpeed, 0}
+	case s < minSpeed:
+		return v.Mul(minSpeed / s)
+	case s > maxSpeed:
+		return v.Mul(maxSpeed / s)
+	}
+	return v
+}
+
+func length(v glm.Vec2) float32 {
+	return float32(math.Hypot(float64(v[0]), float64(v[1])))
+}
+
+//
+// Global data used by render
+//
+
+// Data of a boid for the shader: position, heading as a unit vector, and
+// color.
+type tInstance struct {
+	Boid  glm.Vec4
+	Color glm.Vec3
+}
+
+const instanceSize = int(unsafe.Sizeof(tInstance{}))
+
+type gResources struct {
+	program   *glutil.Program
+	vao       uint32
+	instances *glutil.StreamBuffer
+
+	effects *postfx.Chain // nil without -crt
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.BindVertexArray(r.vao)
+	r.instances = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, len(boids)*instanceSize)
+	for i := uint32(0); i < 2; i++ {
+		gl.EnableVertexAttribArray(i)
+		gl.VertexAttribDivisor(i, 1)
+	}
+	gl.BindVertexArray(0)
+
+	r.effects, err = postfx.OptionalCRT()
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock   = app.NewClock()
+	useGrid = true
+
+	instances []tInstance
+	lastPrint = time.Now()
+	stepTime  time.Duration
+)
+
+func update(w *glfw.Window) {
+	// no big jumps after a pause or a slow frame
+	dt := clock.Delta()
+	if dt > .05 {
+		dt = .05
+	}
+	if dt == 0 {
+		return
+	}
+
+	sw, sh := w.GetSize()
+	var cursor *glm.Vec2
+	if w.GetMouseButton(glfw.MouseButtonLeft) == glfw.Press {
+		cx, cy := w.GetCursorPos()
+		cursor = &glm.Vec2{float32(cx), float32(sh) - float32(cy)}
+	}
+
+	t := time.Now()
+	step(float32(dt), float32(sw), float32(sh), useGrid, cursor)
+	stepTime = time.Since(t)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	x(r.effects.Begin(int32(width), int32(height)))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.08, .1, .15, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	instances = instances[:0]
+	for _, b := range boids {
+		h := b.velocity.Mul(1 / length(b.velocity))
+		instances = append(instances, tInstance{glm.Vec4{b.position[0], b.position[1], h[0], h[1]}, b.color})
+	}
+
+	sw, sh := w.GetSize()
+	p := r.program
+	p.Use()
+	p.SetMat4("projection", glm.Ortho(0, float32(sw), 0, float32(sh), -1, 1))
+
+	gl.BindVertexArray(r.vao)
+	offset := r.instances.Write(unsafe.Pointer(&instances[0]), len(instances)*instanceSize)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.instances.Buffer)
+	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, int32(instanceSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, int32(instanceSize), gl.PtrOffset(offset+16))
+	gl.DrawArraysInstanced(gl.TRIANGLES, 0, 3, int32(len(instances)))
+	r.instances.Done()
+	gl.BindVertexArray(0)
+
+	x(r.effects.End(nil))
+
+	if time.Since(lastPrint) >= time.Second {
+		app.Debugf("Update: %.1f ms", float64(stepTime.Microseconds())/1000)
+		lastPrint = time.Now()
+	}
+}
+
+func main() {
+	app.Keys[actionGrid] = []string{"g"}
+	app.Flags(1000, 700, "Boids")
+
+	if *boidCount < 1 {
+		app.Fatal("need at least one boid")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	makeBoids(*boidCount, float32(app.Width), float32(app.Height))
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+
+	app.Info("Hold the mouse button down to scare the boids")
+	app.Info("Press 'g' to toggle the spatial hash")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionGrid:
+		useGrid = !useGrid
+		if useGrid {
+			app.Info("Neighbours from the spatial hash")
+		} else {
+			app.Info("Neighbours from all boids")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
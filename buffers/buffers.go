@@ -0,0 +1,310 @@
+package main
+
+// A benchmark of the ways to update a buffer every frame: many points are
+// moved on the CPU, and written to a glutil.DynamicBuffer with each of its
+// strategies in turn. Every second, the time it took on the CPU to write
+// and draw the points is printed, and the number of frames.
+//
+// Without vsync and without a pause between frames, waits for the GPU
+// show up in the times, which is the point.
+//
+// With -bench, each strategy is run for that many frames in a hidden
+// window, and a table comparing them is printed. This isn't a Benchmark
+// function for go test, because it needs a window with an OpenGL context,
+// made on the main thread.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+layout(location = 0) in vec2 position;
+layout(location = 1) in float hue;
+
+out vec3 color;
+
+void main()
+{
+    color = clamp(abs(mod(hue * 6.0 + vec3(0.0, 4.0, 2.0), 6.0) - 3.0) - 1.0, 0.0, 1.0);
+    gl_Position = vec4(position, 0.0, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+in vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	pointCount = flag.Int("points", 200000, "number of points")
+	strategy   = flag.String("strategy", "persistent", "update strategy to start with: subdata, orphan or persistent")
+	benchmark  = flag.Int("bench", 0, "run each strategy for this many frames in a hidden window, print a comparison, and quit")
+)
+
+// Extra action for this demo.
+const actionStrategy = "strategy"
+
+// Data of a point for the shader.
+type tPoint struct {
+	Position glm.Vec2
+	Hue      float32
+}
+
+const pointSize = int(unsafe.Sizeof(tPoint{}))
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	vao     uint32
+	points  *glutil.DynamicBuffer
+}
+
+func makeResources(s glutil.Strategy) *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.BindVertexArray(r.vao)
+	gl.EnableVertexAttribArray(0)
+	gl.EnableVertexAttribArray(1)
+	gl.BindVertexArray(0)
+
+	setStrategy(r, s)
+
+	return r
+}
+
+// setStrategy replaces the buffer by one with the given strategy.
+func setStrategy(r *gResources, s glutil.Strategy) {
+	if r.points != nil {
+		r.points.Delete()
+	}
+	r.points = glutil.NewDynamicBuffer(gl.ARRAY_BUFFER, *pointCount*pointSize, s)
+	if r.points.Strategy != s {
+		app.Infof("%v is not available, using %v", s, r.points.Strategy)
+	}
+	resetTimes()
+}
+
+//
+// Update and render
+//
+
+var (
+	clock  = app.NewClock()
+	points []tPoint
+
+	// for the times printed every second
+	frames     int
+	updateTime time.Duration
+	started    time.Time
+)
+
+func resetTimes() {
+	frames = 0
+	updateTime = 0
+	started = time.Now()
+}
+
+// update moves the points along curves that slowly change, so all of the
+// data is new every frame.
+func update() {
+	if points == nil {
+		points = make([]tPoint, *pointCount)
+	}
+	t := clock.Seconds()
+	n := float64(len(points))
+	for i := range points {
+		f := float64(i) / n
+		a := 2 * math.Pi * f
+		rr := .2 + .7*math.Abs(math.Sin(3*a+.3*t))
+		points[i] = tPoint{
+			Position: glm.Vec2{
+				float32(rr * math.Cos(a*17+.2*t)),
+				float32(rr * math.Sin(a*13-.1*t)),
+			},
+			Hue: float32(f),
+		}
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, 0, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	glutil.State{}.Apply()
+	r.program.Use()
+
+	start := time.Now()
+	gl.BindVertexArray(r.vao)
+	offset := r.points.Write(unsafe.Pointer(&points[0]), len(points)*pointSize)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.points.Buffer)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, int32(pointSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 1, gl.FLOAT, false, int32(pointSize), gl.PtrOffset(offset+8))
+	gl.DrawArrays(gl.POINTS, 0, int32(len(points)))
+	r.points.Done()
+	gl.BindVertexArray(0)
+	updateTime += time.Since(start)
+
+	frames++
+	if d := time.Since(started); d >= time.Second && *benchmark == 0 {
+		app.Infof("%-10v %7.3f ms per update, %4.0f frames per second",
+			r.points.Strategy,
+			float64(updateTime)/float64(frames)/float64(time.Millisecond),
+			float64(frames)/d.Seconds())
+		resetTimes()
+	}
+}
+
+func main() {
+	app.Keys[actionStrategy] = []string{"s"}
+	app.Flags(800, 800, "Buffer update strategies")
+
+	var s glutil.Strategy
+	switch strings.ToLower(*strategy) {
+	case "subdata":
+		s = glutil.SubData
+	case "orphan":
+		s = glutil.Orphan
+	case "persistent":
+		s = glutil.Persistent
+	default:
+		app.Fatal("unknown strategy:", *strategy)
+	}
+	if *pointCount < 1 {
+		app.Fatal("-points must be at least 1")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	if *benchmark > 0 {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	}
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	// no vsync, to measure how fast the updates can go
+	glfw.SwapInterval(0)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources(s)
+	if *benchmark > 0 {
+		runBenchmark(w, r)
+		return
+	}
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+
+	app.Info("Press 's' to switch to the next update strategy")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		clock.Tick()
+		update()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+// runBenchmark draws the given number of frames with each strategy, and
+// prints the CPU time per update and the frames per second. The total time
+// includes the wait for the GPU to finish the last frame.
+func runBenchmark(w *glfw.Window, r *gResources) {
+	fmt.Printf("%d points, %d frames per strategy\n\n", *pointCount, *benchmark)
+	fmt.Printf("%-10s  %-10s  %14s  %10s  %10s\n", "strategy", "used", "ms per update", "fps", "total s")
+	for _, s := range []glutil.Strategy{glutil.SubData, glutil.Orphan, glutil.Persistent} {
+		setStrategy(r, s)
+		for i := 0; i < *benchmark; i++ {
+			clock.Tick()
+			update()
+			render(w, r)
+			app.SwapBuffers(w)
+			glfw.PollEvents()
+		}
+		gl.Finish()
+		d := time.Since(started)
+		fmt.Printf("%-10v  %-10v  %14.3f  %10.1f  %10.2f\n",
+			s, r.points.Strategy,
+			float64(updateTime)/float64(frames)/float64(time.Millisecond),
+			float64(frames)/d.Seconds(),
+			d.Seconds())
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionStrategy:
+		setStrategy(r, (r.points.Strategy+1)%3)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package camera contains cameras that are controlled with the keyboard
+// and the mouse, and that provide a view matrix.
+package camera
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/input"
+
+	"math"
+	"time"
+)
+
+// FreeFly is a camera that flies through the scene. Drag with the mouse to
+// look around, and use the keys W, A, S and D to move forward, left,
+// backward and right, and page up and page down to move up and down. Hold
+// shift to move faster. The keys can be changed with Keys.
+//
+// Install it with:
+//
+//	w.SetMouseButtonCallback(cam.MouseButton)
+//	w.SetCursorPosCallback(cam.CursorPos)
+//
+// and call Update once per frame.
+type FreeFly struct {
+	Position glm.Vec3
+
+	// Direction of view, in radians. With Yaw and Pitch 0 the camera looks
+	// along the negative z axis. Positive Yaw turns to the right, positive
+	// Pitch looks up.
+	Yaw, Pitch float32
+
+	Speed       float32 // units per second
+	Sensitivity float32 // radians per pixel of mouse movement
+
+	Keys MoveKeys
+
+	drag input.Drag
+	last time.Time
+}
+
+// MoveKeys are the keys that move a FreeFly camera.
+type MoveKeys struct {
+	Forward, Backward, Left, Right, Up, Down glfw.Key
+}
+
+// Sets of keys for moving a FreeFly camera.
+var (
+	// The default.
+	WASD = MoveKeys{glfw.KeyW, glfw.KeyS, glfw.KeyA, glfw.KeyD, glfw.KeyPageUp, glfw.KeyPageDown}
+
+	// For a second camera in the same window.
+	Arrows = MoveKeys{glfw.KeyUp, glfw.KeyDown, glfw.KeyLeft, glfw.KeyRight, glfw.KeyHome, glfw.KeyEnd}
+)
+
+// NewFreeFly returns a camera at the given position, looking along the
+// negative z axis, moving 5 units per second.
+func NewFreeFly(position glm.Vec3) *FreeFly {
+	c := &FreeFly{
+		Position:    position,
+		Speed:       5,
+		Sensitivity: .005,
+		Keys:        WASD,
+		last:        time.Now(),
+	}
+	c.drag.OnMove = func(x, y, dx, dy float64) {
+		c.Yaw += float32(dx) * c.Sensitivity
+		c.Pitch -= float32(dy) * c.Sensitivity
+		// don't look straight up or down, where the up vector is undefined
+		limit := float32(math.Pi/2 - .01)
+		if c.Pitch > limit {
+			c.Pitch = limit
+		} else if c.Pitch < -limit {
+			c.Pitch = -limit
+		}
+	}
+	return c
+}
+
+// Forward returns the unit vector in the direction of view.
+func (c *FreeFly) Forward() glm.Vec3 {
+	yaw, pitch := float64(c.Yaw), float64(c.Pitch)
+	return glm.Vec3{
+		float32(math.Sin(yaw) * math.Cos(pitch)),
+		float32(math.Sin(pitch)),
+		float32(-math.Cos(yaw) * math.Cos(pitch)),
+	}
+}
+
+// Right returns the horizontal unit vector to the right of the camera.
+func (c *FreeFly) Right() glm.Vec3 {
+	yaw := float64(c.Yaw)
+	return glm.Vec3{float32(math.Cos(yaw)), 0, float32(math.Sin(yaw))}
+}
+
+// View returns the view matrix.
+func (c *FreeFly) View() glm.Mat4 {
+	return glm.LookAt(c.Position, c.Position.Add(c.Forward()), glm.Vec3{0, 1, 0})
+}
+
+// MouseButton is a glfw.MouseButtonCallback.
+func (c *FreeFly) MouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+	c.drag.MouseButton(w, button, action, mod)
+}
+
+// CursorPos is a glfw.CursorPosCallback.
+func (c *FreeFly) CursorPos(w *glfw.Window, x, y float64) {
+	c.drag.CursorPos(w, x, y)
+}
+
+// Update moves the camera according to the keys that are held down. Call
+// it once per frame.
+func (c *FreeFly) Update(w *glfw.Window) {
+	now := time.Now()
+	dt := float32(now.Sub(c.last).Seconds())
+	c.last = now
+
+	var move glm.Vec3
+	for _, k := range []struct {
+		key glfw.Key
+		dir glm.Vec3
+	}{
+		{c.Keys.Forward, c.Forward()},
+		{c.Keys.Backward, c.Forward().Mul(-1)},
+		{c.Keys.Right, c.Right()},
+		{c.Keys.Left, c.Right().Mul(-1)},
+		{c.Keys.Up, glm.Vec3{0, 1, 0}},
+		{c.Keys.Down, glm.Vec3{0, -1, 0}},
+	} {
+		if w.GetKey(k.key) == glfw.Press {
+			move = move.Add(k.dir)
+		}
+	}
+	if move.Len() == 0 {
+		return
+	}
+	speed := c.Speed
+	if w.GetKey(glfw.KeyLeftShift) == glfw.Press || w.GetKey(glfw.KeyRightShift) == glfw.Press {
+		speed *= 4
+	}
+	c.Position = c.Position.Add(move.Normalize().Mul(speed * dt))
+}
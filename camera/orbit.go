@@ -0,0 +1,91 @@
+package camera
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/input"
+
+	"math"
+)
+
+// Orbit is a camera that circles around a target. Drag with the mouse to
+// rotate around it, and use the scroll wheel to move closer or further
+// away.
+//
+// Install it with:
+//
+//	w.SetMouseButtonCallback(cam.MouseButton)
+//	w.SetCursorPosCallback(cam.CursorPos)
+//	w.SetScrollCallback(cam.Scroll)
+type Orbit struct {
+	Target   glm.Vec3
+	Distance float32
+
+	// Position of the camera around the target, in radians. With Yaw and
+	// Pitch 0 the camera is on the positive z axis of the target.
+	Yaw, Pitch float32
+
+	Sensitivity float32 // radians per pixel of mouse movement
+
+	// Limits for Distance.
+	MinDistance, MaxDistance float32
+
+	drag input.Drag
+}
+
+// NewOrbit returns a camera looking at target from the given distance.
+func NewOrbit(target glm.Vec3, distance float32) *Orbit {
+	c := &Orbit{
+		Target:      target,
+		Distance:    distance,
+		Sensitivity: .01,
+		MinDistance: distance / 100,
+		MaxDistance: distance * 100,
+	}
+	c.drag.OnMove = func(x, y, dx, dy float64) {
+		c.Yaw -= float32(dx) * c.Sensitivity
+		c.Pitch += float32(dy) * c.Sensitivity
+		limit := float32(math.Pi/2 - .01)
+		if c.Pitch > limit {
+			c.Pitch = limit
+		} else if c.Pitch < -limit {
+			c.Pitch = -limit
+		}
+	}
+	return c
+}
+
+// Eye returns the position of the camera.
+func (c *Orbit) Eye() glm.Vec3 {
+	yaw, pitch := float64(c.Yaw), float64(c.Pitch)
+	return c.Target.Add(glm.Vec3{
+		float32(math.Sin(yaw) * math.Cos(pitch)),
+		float32(math.Sin(pitch)),
+		float32(math.Cos(yaw) * math.Cos(pitch)),
+	}.Mul(c.Distance))
+}
+
+// View returns the view matrix.
+func (c *Orbit) View() glm.Mat4 {
+	return glm.LookAt(c.Eye(), c.Target, glm.Vec3{0, 1, 0})
+}
+
+// MouseButton is a glfw.MouseButtonCallback.
+func (c *Orbit) MouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+	c.drag.MouseButton(w, button, action, mod)
+}
+
+// CursorPos is a glfw.CursorPosCallback.
+func (c *Orbit) CursorPos(w *glfw.Window, x, y float64) {
+	c.drag.CursorPos(w, x, y)
+}
+
+// Scroll is a glfw.ScrollCallback.
+func (c *Orbit) Scroll(w *glfw.Window, xoff, yoff float64) {
+	c.Distance *= float32(math.Pow(.9, yoff))
+	if c.Distance < c.MinDistance {
+		c.Distance = c.MinDistance
+	} else if c.Distance > c.MaxDistance {
+		c.Distance = c.MaxDistance
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	// The crossfade of the hello demo, with a fade factor for each pixel:
+	// where the foreground has the key color, the background shows through.
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D textures[2]; // background, foreground
+uniform vec3 key;
+uniform float tolerance;
+uniform float softness;
+uniform float spill;
+uniform int view;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+// the color difference components of YCbCr, independent of brightness
+vec2 chroma(vec3 c)
+{
+    return vec2(
+        -0.169 * c.r - 0.331 * c.g + 0.5 * c.b,
+        0.5 * c.r - 0.419 * c.g - 0.081 * c.b
+    );
+}
+
+void main()
+{
+    // the images have row 0 at the top
+    vec2 p = vec2(uv.x, 1.0 - uv.y);
+    vec3 bg = texture(textures[0], p).rgb;
+    vec3 fg = texture(textures[1], p).rgb;
+
+    vec2 k = chroma(key);
+    vec2 c = chroma(fg);
+    float fade_factor = smoothstep(tolerance, tolerance + softness, distance(c, k));
+
+    // spill suppression: remove the part of the color in the direction
+    // of the key, keeping the brightness
+    vec2 dir = length(k) > 0.0 ? normalize(k) : vec2(0.0);
+    float s = max(dot(c, dir), 0.0) * spill;
+    vec2 cs = c - s * dir;
+    float y = dot(fg, vec3(0.299, 0.587, 0.114));
+    vec3 clean = vec3(
+        y + 1.402 * cs.y,
+        y - 0.344 * cs.x - 0.714 * cs.y,
+        y + 1.772 * cs.x
+    );
+
+    if (view == 1) {
+        fragColor = vec4(vec3(fade_factor), 1.0);
+    } else if (view == 2) {
+        fragColor = vec4(fg, 1.0);
+    } else {
+        fragColor = vec4(mix(bg, clamp(clean, 0.0, 1.0), fade_factor), 1.0);
+    }
+}
+`
+)
+
+var (
+	foregroundFile = flag.String("foreground", "", "image shot in front of a green screen, instead of a generated one")
+	backgroundFile = flag.String("background", "", "background image, instead of a generated one")
+)
+
+// Extra actions for this demo.
+const (
+	actionView     = "view"
+	actionSpill    = "spill"
+	actionTolerant = "tolerant"
+	actionStrict   = "strict"
+	actionSofter   = "softer"
+	actionHarder   = "harder"
+	actionResetKey = "resetkey"
+)
+
+var viewNames = []string{"composite", "matte", "foreground"}
+
+const imageWidth, imageHeight = 800, 600
+
+// makeForeground returns a figure in front of an unevenly lit green
+// screen, with green light spilling onto its edges.
+func makeForeground() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			fx := float64(x)/imageWidth - .5
+			fy := float64(y)/imageHeight - .5
+
+			// brighter in the middle of the screen
+			light := 1 - .8*(fx*fx+fy*fy)
+			r, g, b := 40*light, 190*light, 60*light
+
+			// distance inside the figure: a head and a body
+			head := .12 - math.Hypot(fx, (fy+.22)*.75)
+			body := .2 - math.Max(math.Abs(fx)*1.1, math.Abs(fy-.28)*.8)
+			if fy > .08 {
+				body = math.Max(body, .25-math.Hypot(fx*.9, fy-.48))
+			}
+			if d := math.Max(head, body); d > 0 {
+				var fr, fg, fb float64
+				if head > body {
+					fr, fg, fb = 225, 180, 150
+				} else {
+					// a red jacket with a blue stripe
+					fr, fg, fb = 180, 40, 50
+					if math.Abs(fx) < .02 {
+						fr, fg, fb = 40, 60, 170
+					}
+				}
+				// shading, lit from the left
+				shade := .8 - .6*fx
+				fr, fg, fb = fr*shade, fg*shade, fb*shade
+				// spill near the edge
+				if d < .02 {
+					t := .5 * (1 - d/.02)
+					fr, fg, fb = fr*(1-t)+r*t, fg*(1-t)+g*t, fb*(1-t)+b*t
+				}
+				r, g, b = fr, fg, fb
+			}
+			img.SetRGBA(x, y, color.RGBA{clip(r), clip(g), clip(b), 255})
+		}
+	}
+	return img
+}
+
+// makeBackground returns a sunset over the sea.
+func makeBackground() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			fx, fy := float64(x)/imageWidth, float64(y)/imageHeight
+			r, g, b := 250-80*(1-fy), 120+60*fy, 90+100*(1-fy)
+			if d := math.Hypot(fx-.3, fy-.55); d < .08 {
+				r, g, b = 255, 230, 150
+			}
+			if fy > .6 {
+				// the sea, with the sun reflected in waves
+				r, g, b = 40, 60, 110
+				if math.Abs(fx-.3) < .1*(1.4-fy) && math.Sin(fy*300) > .3 {
+					r, g, b = 240, 170, 100
+				}
+			}
+			img.SetRGBA(x, y, color.RGBA{clip(r), clip(g), clip(b), 255})
+		}
+	}
+	return img
+}
+
+func clip(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, v)))
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program    *glutil.Program
+	foreground *image.RGBA // for picking the key color
+	textures   [2]uint32
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	if *backgroundFile != "" {
+		r.textures[0], err = glutil.MakeTexture(*backgroundFile)
+		x(err)
+	} else {
+		r.textures[0] = glutil.MakeTextureFromImage(makeBackground())
+	}
+	if *foregroundFile != "" {
+		r.foreground, err = asset.LoadImageOrPlaceholder(*foregroundFile)
+		x(err)
+	} else {
+		r.foreground = makeForeground()
+	}
+	r.textures[1] = glutil.MakeTextureFromImage(r.foreground)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	defaultKey = glm.Vec3{0, 1, 0}
+	key        = defaultKey
+	tolerance  = float32(.08)
+	softness   = float32(.06)
+	spill      = true
+	view       = 0
+)
+
+// viewport returns the part of the window the images are drawn in, as
+// large as fits, centered, in pixels of the framebuffer.
+func viewport(w *glfw.Window, r *gResources) (x0, y0, width, height int32) {
+	fw, fh := w.GetFramebufferSize()
+	b := r.foreground.Rect
+	s := math.Min(float64(fw)/float64(b.Dx()), float64(fh)/float64(b.Dy()))
+	width, height = int32(s*float64(b.Dx())), int32(s*float64(b.Dy()))
+	return (int32(fw) - width) / 2, (int32(fh) - height) / 2, width, height
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.ClearColor(app.Background(.1, .1, .1, 0))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	gl.Viewport(viewport(w, r))
+	glutil.State{}.Apply()
+
+	p := r.program
+	p.Use()
+	p.SetInt("textures[0]", 0)
+	p.SetInt("textures[1]", 1)
+	p.SetVec3("key", key)
+	p.SetFloat("tolerance", tolerance)
+	p.SetFloat("softness", softness)
+	if spill {
+		p.SetFloat("spill", 1)
+	} else {
+		p.SetFloat("spill", 0)
+	}
+	p.SetInt("view", int32(view))
+	for i, t := range r.textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, t)
+	}
+	glutil.DrawFullscreen()
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// pickKey sets the key color to that of the foreground at the cursor.
+func pickKey(w *glfw.Window, r *gResources) {
+	cx, cy := w.GetCursorPos()
+	// from window to framebuffer pixels, and y up
+	ww, wh := w.GetSize()
+	fw, fh := w.GetFramebufferSize()
+	px := cx * float64(fw) / float64(ww)
+	py := float64(fh) - cy*float64(fh)/float64(wh)
+
+	x0, y0, vw, vh := viewport(w, r)
+	u := (px - float64(x0)) / float64(vw)
+	v := (py - float64(y0)) / float64(vh)
+	if u < 0 || u >= 1 || v < 0 || v >= 1 {
+		return
+	}
+	b := r.foreground.Rect
+	c := r.foreground.RGBAAt(b.Min.X+int(u*float64(b.Dx())), b.Min.Y+int((1-v)*float64(b.Dy())))
+	key = glm.Vec3{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255}
+	app.Infof("Key color: %d, %d, %d", c.R, c.G, c.B)
+}
+
+func main() {
+	app.Keys[actionView] = []string{"v"}
+	app.Keys[actionSpill] = []string{"s"}
+	app.Keys[actionTolerant] = []string{"up"}
+	app.Keys[actionStrict] = []string{"down"}
+	app.Keys[actionSofter] = []string{"right"}
+	app.Keys[actionHarder] = []string{"left"}
+	app.Keys[actionResetKey] = []string{"k"}
+	app.Flags(800, 600, "Chroma key")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button == glfw.MouseButtonLeft && action == glfw.Press {
+			pickKey(w, r)
+		}
+	})
+
+	app.Info("Click on the foreground to pick the key color")
+	app.Info("Press up and down to change the tolerance, left and right to change the softness of the edge")
+	app.Info("Press 's' to toggle spill suppression, 'v' to show the composite, the matte or the foreground, 'k' to key out pure green again")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionView:
+		view = (view + 1) % len(viewNames)
+		app.Info("Showing the", viewNames[view])
+	case actionSpill:
+		spill = !spill
+		if spill {
+			app.Info("Spill suppression on")
+		} else {
+			app.Info("Spill suppression off")
+		}
+	case actionTolerant, actionStrict, actionSofter, actionHarder:
+		switch action {
+		case actionTolerant:
+			tolerance += .01
+		case actionStrict:
+			tolerance -= .01
+		case actionSofter:
+			softness += .01
+		case actionHarder:
+			softness -= .01
+		}
+		if tolerance < 0 {
+			tolerance = 0
+		}
+		if softness < .001 {
+			softness = .001
+		}
+		app.Infof("Tolerance %.2f, softness %.2f", tolerance, softness)
+	case actionResetKey:
+		key = defaultKey
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
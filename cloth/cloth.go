@@ -0,0 +1,456 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"math"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 uv;
+
+out vec3 worldNormal;
+out vec2 texcoord;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = mat3(model) * normal;
+    texcoord = uv;
+}
+` + "\x00"
+
+	// The cloth isn't culled, so both sides are drawn: the front with a
+	// checkerboard pattern, the back plain, each lit with the normal of
+	// its own side.
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 frontColor;
+uniform vec3 backColor;
+uniform vec3 lightDirection;
+uniform bool checkers;
+
+in vec3 worldNormal;
+in vec2 texcoord;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    vec3 c = frontColor;
+    if (gl_FrontFacing) {
+        if (checkers) {
+            ivec2 cell = ivec2(floor(texcoord * 10.0));
+            c *= ((cell.x + cell.y) & 1) == 0 ? 1.0 : 0.7;
+        }
+    } else {
+        n = -n;
+        c = backColor;
+    }
+    float diffuse = max(dot(n, lightDirection), 0.0);
+    fragColor = vec4((0.25 + 0.75 * diffuse) * c, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	gridSize   = flag.Int("grid", 40, "number of particles along each side of the cloth")
+	iterations = flag.Int("iterations", 8, "constraint iterations per update")
+)
+
+// Extra actions for this demo.
+const (
+	actionRelease = "release"
+	actionWind    = "wind"
+	actionReset   = "reset"
+)
+
+//
+// The simulation
+//
+
+const (
+	clothSize = 4   // width and height
+	damping   = .99 // fraction of the velocity kept per update
+)
+
+var (
+	gravity = glm.Vec3{0, -9.8, 0}
+
+	// the ball the cloth falls on
+	ballCenter = glm.Vec3{0, -.5, .5}
+	ballRadius = float32(1)
+)
+
+type tParticle struct {
+	position glm.Vec3
+	previous glm.Vec3 // the velocity is implied by the last move
+	pinned   bool
+}
+
+// A distance constraint keeps two particles at their rest length.
+type tConstraint struct {
+	a, b   int
+	length float32
+}
+
+var (
+	particles   []tParticle
+	constraints []tConstraint
+	wind        = true
+	windTime    float64
+)
+
+// makeCloth creates a horizontal cloth above the ball, pinned at the
+// corners and the middle of its back edge. Structural constraints connect
+// neighbours, shear constraints connect diagonal neighbours, and bend
+// constraints connect particles two apart, to resist folding.
+func makeCloth(n int) {
+	particles = make([]tParticle, n*n)
+	constraints = constraints[:0]
+	spacing := float32(clothSize) / float32(n-1)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			p := glm.Vec3{-clothSize/2 + float32(i)*spacing, 1.5, -clothSize/2 + float32(j)*spacing}
+			particles[j*n+i] = tParticle{
+				position: p,
+				previous: p,
+				pinned:   j == 0 && (i == 0 || i == n-1 || i == n/2),
+			}
+		}
+	}
+	link := func(i0, j0, i1, j1 int) {
+		if i1 < 0 || i1 >= n || j1 >= n {
+			return
+		}
+		a, b := j0*n+i0, j1*n+i1
+		constraints = append(constraints, tConstraint{a, b, particles[b].position.Sub(particles[a].position).Len()})
+	}
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			link(i, j, i+1, j)
+			link(i, j, i, j+1)
+			link(i, j, i+1, j+1)
+			link(i, j, i-1, j+1)
+			link(i, j, i+2, j)
+			link(i, j, i, j+2)
+		}
+	}
+}
+
+// update advances the simulation by dt seconds: Verlet integration, then
+// the constraints, and collisions with the ball, relaxed a few times.
+func update(dt float32) {
+	windTime += float64(dt)
+	force := gravity
+	if wind {
+		gust := float32(1 + math.Sin(windTime*.7)*math.Sin(windTime*2.3))
+		force = force.Add(glm.Vec3{1, 0, 4}.Mul(gust))
+	}
+
+	for i := range particles {
+		p := &particles[i]
+		if p.pinned {
+			continue
+		}
+		v := p.position.Sub(p.previous).Mul(damping)
+		p.previous = p.position
+		p.position = p.position.Add(v).Add(force.Mul(dt * dt))
+	}
+
+	for it := 0; it < *iterations; it++ {
+		for _, c := range constraints {
+			a, b := &particles[c.a], &particles[c.b]
+			d := b.position.Sub(a.position)
+			l := d.Len()
+			if l == 0 {
+				continue
+			}
+			// move both ends half of the way, or a free end all of it
+			corr := d.Mul((l - c.length) / l)
+			switch {
+			case a.pinned && b.pinned:
+			case a.pinned:
+				b.position = b.position.Sub(corr)
+			case b.pinned:
+				a.position = a.position.Add(corr)
+			default:
+				a.position = a.position.Add(corr.Mul(.5))
+				b.position = b.position.Sub(corr.Mul(.5))
+			}
+		}
+		for i := range particles {
+			p := &particles[i]
+			// a small margin, so the cloth doesn't intersect the
+			// triangles of the ball
+			d := p.position.Sub(ballCenter)
+			if l := d.Len(); l < ballRadius*1.03 {
+				p.position = ballCenter.Add(d.Mul(ballRadius * 1.03 / l))
+			}
+		}
+	}
+}
+
+// release unpins all particles, so the cloth falls.
+func release() {
+	for i := range particles {
+		particles[i].pinned = false
+	}
+}
+
+//
+// Global data used by render
+//
+
+// Data of a vertex in the stream buffer: position, normal, and texture
+// coordinates.
+type tVertex struct {
+	Position glm.Vec3
+	Normal   glm.Vec3
+	UV       glm.Vec2
+}
+
+const vertexSize = int(unsafe.Sizeof(tVertex{}))
+
+type gResources struct {
+	program *glutil.Program
+	ball    *mesh.VAO
+
+	vao      uint32
+	vertices *glutil.StreamBuffer
+	elements uint32
+	count    int32
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		ball: mesh.Sphere(48, 24).Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	n := *gridSize
+	var indices []uint32
+	for j := 0; j < n-1; j++ {
+		for i := 0; i < n-1; i++ {
+			a := uint32(j*n + i)
+			b, c, d := a+1, a+uint32(n), a+uint32(n)+1
+			// counter-clockwise seen from above, the front
+			indices = append(indices, a, c, b, b, c, d)
+		}
+	}
+	r.count = int32(len(indices))
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.BindVertexArray(r.vao)
+	r.elements = glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, indices, gl.STATIC_DRAW)
+	r.vertices = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, n*n*vertexSize)
+	for i := uint32(0); i < 3; i++ {
+		gl.EnableVertexAttribArray(i)
+	}
+	gl.BindVertexArray(0)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock    = app.NewClock()
+	steps    = app.NewFixedStep(120)
+	cam      *camera.Orbit
+	vertices []tVertex
+)
+
+var stateScene = glutil.State{
+	DepthTest: true,
+}
+
+// makeVertices computes the vertices of the cloth, with normals averaged
+// from the faces around each particle.
+func makeVertices() {
+	n := *gridSize
+	if len(vertices) != n*n {
+		vertices = make([]tVertex, n*n)
+	}
+	pos := func(i, j int) glm.Vec3 {
+		if i < 0 {
+			i = 0
+		} else if i >= n {
+			i = n - 1
+		}
+		if j < 0 {
+			j = 0
+		} else if j >= n {
+			j = n - 1
+		}
+		return particles[j*n+i].position
+	}
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			// central differences, along +x and -z
+			du := pos(i+1, j).Sub(pos(i-1, j))
+			dv := pos(i, j-1).Sub(pos(i, j+1))
+			vertices[j*n+i] = tVertex{
+				Position: pos(i, j),
+				Normal:   du.Cross(dv).Normalize(),
+				UV:       glm.Vec2{float32(i) / float32(n-1), float32(j) / float32(n-1)},
+			}
+		}
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	stateScene.Apply()
+	gl.ClearColor(app.Background(.55, .65, .75, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100))
+	p.SetVec3("lightDirection", glm.Vec3{.5, 1, .8}.Normalize())
+
+	// the ball
+	p.SetMat4("model", glm.Translate(ballCenter[0], ballCenter[1], ballCenter[2]).Mul(glm.Scale(ballRadius, ballRadius, ballRadius)))
+	p.SetVec3("frontColor", glm.Vec3{.8, .3, .2})
+	p.SetBool("checkers", false)
+	r.ball.Draw()
+
+	// the cloth
+	makeVertices()
+	p.SetMat4("model", glm.Identity())
+	p.SetVec3("frontColor", glm.Vec3{.9, .85, .3})
+	p.SetVec3("backColor", glm.Vec3{.25, .4, .8})
+	p.SetBool("checkers", true)
+	gl.BindVertexArray(r.vao)
+	offset := r.vertices.Write(unsafe.Pointer(&vertices[0]), len(vertices)*vertexSize)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertices.Buffer)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, int32(vertexSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, int32(vertexSize), gl.PtrOffset(offset+12))
+	gl.VertexAttribPointer(2, 2, gl.FLOAT, false, int32(vertexSize), gl.PtrOffset(offset+24))
+	gl.DrawElements(gl.TRIANGLES, r.count, gl.UNSIGNED_INT, nil)
+	r.vertices.Done()
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Keys[actionRelease] = []string{"p"}
+	app.Keys[actionWind] = []string{"w"}
+	app.Keys[actionReset] = []string{"r"}
+	app.Flags(800, 600, "Cloth")
+
+	if *gridSize < 3 {
+		app.Fatal("grid must be at least 3")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewOrbit(glm.Vec3{0, 0, 0}, 9)
+	cam.Pitch = .4
+	cam.Yaw = .5
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	makeCloth(*gridSize)
+
+	app.Infof("%d particles, %d constraints", len(particles), len(constraints))
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 'p' to release the pins, 'w' to toggle the wind, 'r' to reset")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		steps.Advance(clock.Delta(), func(dt float64) { update(float32(dt)) })
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionRelease:
+		release()
+	case actionWind:
+		wind = !wind
+	case actionReset:
+		makeCloth(*gridSize)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
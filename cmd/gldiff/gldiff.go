@@ -0,0 +1,170 @@
+// Command gldiff compares two images, such as a capture of a renderer and a
+// golden image that is known to be right, and tells how different they are:
+//
+//	gldiff [flags] golden.png capture.png
+//
+// It prints three metrics:
+//
+//	pixels  the fraction of pixels that differ more than -tolerance in
+//	        any channel, with the largest difference, the RMSE and the PSNR
+//	ssim    the structural similarity of the luminance, 1 for equal images
+//	flip    the mean perceptual error, 0 for equal images, in the style of
+//	        NVIDIA's FLIP: differences in CIELAB after blurring both images
+//	        a little, as the eye does, so dithering and noise count less
+//	        than shifted edges and wrong colors
+//
+// With -heatmap, it writes an image of where the images differ, by the
+// metric of -metric, from black for no difference through red and yellow
+// to white.
+//
+// The exit status is 0 if the error by the metric of -metric is at most
+// -threshold, 1 if it is larger, and 2 on failure, such as images of
+// different sizes. The error is the fraction of pixels for "pixels", 1 - ssim
+// for "ssim", and the mean error for "flip". Transparent pixels are compared
+// as if drawn on black.
+package main
+
+import (
+	"github.com/pebbe/gl/asset"
+
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+)
+
+var (
+	heatmapFile = flag.String("heatmap", "", "write a heatmap of the differences to this PNG file")
+	metric      = flag.String("metric", "flip", "metric for the heatmap and the threshold: pixels, ssim or flip")
+	threshold   = flag.Float64("threshold", 0, "largest error for which the images count as equal")
+	tolerance   = flag.Int("tolerance", 0, "largest difference in a channel, 0 to 255, for which pixels count as equal")
+	blur        = flag.Float64("blur", 1, "sigma in pixels of the blur before computing the perceptual error")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] golden.png capture.png\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *metric != "pixels" && *metric != "ssim" && *metric != "flip" {
+		fail(fmt.Errorf("unknown metric: %s", *metric))
+	}
+
+	a, err := load(flag.Arg(0))
+	x(err)
+	b, err := load(flag.Arg(1))
+	x(err)
+	if a.width != b.width || a.height != b.height {
+		fail(fmt.Errorf("sizes differ: %dx%d and %dx%d", a.width, a.height, b.width, b.height))
+	}
+
+	p := comparePixels(a, b, uint8(*tolerance))
+	ssim, ssimMap := compareSSIM(a, b)
+	flip, flipMap := compareFLIP(a, b, *blur)
+
+	psnr := "inf"
+	if p.rmse > 0 {
+		psnr = fmt.Sprintf("%.2f dB", 20*math.Log10(1/p.rmse))
+	}
+	fmt.Printf("pixels  %.6f  (%d of %d, max %d, rmse %.6f, psnr %s)\n",
+		p.fraction, p.count, a.width*a.height, p.max, p.rmse, psnr)
+	fmt.Printf("ssim    %.6f\n", ssim)
+	fmt.Printf("flip    %.6f\n", flip)
+
+	var e float64
+	var errors []float64
+	switch *metric {
+	case "pixels":
+		e, errors = p.fraction, p.errors
+	case "ssim":
+		e, errors = 1-ssim, ssimMap
+	case "flip":
+		e, errors = flip, flipMap
+	}
+
+	if *heatmapFile != "" {
+		x(saveHeatmap(*heatmapFile, errors, a.width, a.height))
+	}
+
+	if e > *threshold {
+		fmt.Printf("%s error %.6f is above the threshold %g\n", *metric, e, *threshold)
+		os.Exit(1)
+	}
+}
+
+// tImage holds the red, green and blue of each pixel, from 0 to 1,
+// row by row.
+type tImage struct {
+	width, height int
+	pixels        [][3]float32
+}
+
+func load(filename string) (*tImage, error) {
+	rgba, err := asset.LoadImage(filename)
+	if err != nil {
+		return nil, err
+	}
+	b := rgba.Bounds()
+	img := &tImage{
+		width:  b.Dx(),
+		height: b.Dy(),
+		pixels: make([][3]float32, b.Dx()*b.Dy()),
+	}
+	// RGBA is premultiplied, which is the same as drawn on black
+	for i := range img.pixels {
+		for c := 0; c < 3; c++ {
+			img.pixels[i][c] = float32(rgba.Pix[4*i+c]) / 255
+		}
+	}
+	return img, nil
+}
+
+// saveHeatmap writes errors from 0 to 1 as an image.
+func saveHeatmap(filename string, errors []float64, width, height int) error {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, e := range errors {
+		r, g, b := heat(e)
+		img.Pix[4*i] = r
+		img.Pix[4*i+1] = g
+		img.Pix[4*i+2] = b
+		img.Pix[4*i+3] = 255
+	}
+
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(fp, img); err != nil {
+		fp.Close()
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return fp.Close()
+}
+
+// heat returns the color for an error from 0 to 1: black, red, yellow,
+// white. Small errors are made brighter, so they show.
+func heat(e float64) (r, g, b uint8) {
+	e = math.Sqrt(math.Max(0, math.Min(1, e)))
+	c := func(f float64) uint8 {
+		return uint8(255*math.Max(0, math.Min(1, f)) + .5)
+	}
+	return c(3 * e), c(3*e - 1), c(3*e - 2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
+
+func x(err error) {
+	if err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glm"
+
+	"math"
+)
+
+type tPixelDiff struct {
+	count    int     // pixels that differ more than the tolerance
+	fraction float64 // count relative to all pixels
+	max      int     // largest difference in a channel, 0 to 255
+	rmse     float64 // root mean square error over all channels, 0 to 1
+	errors   []float64
+}
+
+// comparePixels compares the images pixel by pixel. The error of a pixel
+// is 0 or 1, for the heatmap.
+func comparePixels(a, b *tImage, tolerance uint8) tPixelDiff {
+	d := tPixelDiff{errors: make([]float64, len(a.pixels))}
+	sum := 0.0
+	for i := range a.pixels {
+		differs := false
+		for c := 0; c < 3; c++ {
+			f := float64(a.pixels[i][c] - b.pixels[i][c])
+			sum += f * f
+			n := int(math.Abs(f)*255 + .5)
+			if n > d.max {
+				d.max = n
+			}
+			if n > int(tolerance) {
+				differs = true
+			}
+		}
+		if differs {
+			d.count++
+			d.errors[i] = 1
+		}
+	}
+	n := len(a.pixels)
+	if n > 0 {
+		d.fraction = float64(d.count) / float64(n)
+		d.rmse = math.Sqrt(sum / float64(3*n))
+	}
+	return d
+}
+
+// compareSSIM returns the mean structural similarity of the luma of the
+// images, computed in a gaussian window with a sigma of 1.5 pixels around
+// each pixel, as by Wang et al., and the dissimilarity 1 - ssim of each
+// pixel, from 0 to 1.
+func compareSSIM(a, b *tImage) (float64, []float64) {
+	const (
+		c1 = .01 * .01
+		c2 = .03 * .03
+	)
+
+	n := len(a.pixels)
+	ya := make([]float64, n)
+	yb := make([]float64, n)
+	yaa := make([]float64, n)
+	ybb := make([]float64, n)
+	yab := make([]float64, n)
+	for i := range a.pixels {
+		ya[i] = luma(a.pixels[i])
+		yb[i] = luma(b.pixels[i])
+		yaa[i] = ya[i] * ya[i]
+		ybb[i] = yb[i] * yb[i]
+		yab[i] = ya[i] * yb[i]
+	}
+	for _, ch := range [][]float64{ya, yb, yaa, ybb, yab} {
+		gaussian(ch, a.width, a.height, 1.5)
+	}
+
+	errors := make([]float64, n)
+	sum := 0.0
+	for i := range errors {
+		ma, mb := ya[i], yb[i]
+		va := yaa[i] - ma*ma
+		vb := ybb[i] - mb*mb
+		cov := yab[i] - ma*mb
+		s := (2*ma*mb + c1) * (2*cov + c2) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+		sum += s
+		errors[i] = math.Max(0, math.Min(1, 1-s))
+	}
+	if n == 0 {
+		return 1, errors
+	}
+	return sum / float64(n), errors
+}
+
+// luma returns the Rec. 709 luma of an sRGB color.
+func luma(c [3]float32) float64 {
+	return .2126*float64(c[0]) + .7152*float64(c[1]) + .0722*float64(c[2])
+}
+
+// compareFLIP returns the mean perceptual error of the images, and the
+// error of each pixel, from 0 to 1. This is a simplified take on FLIP:
+// both images are converted to CIELAB and blurred, which stands in for the
+// contrast sensitivity of the eye, and the error of a pixel is the HyAB
+// distance, the difference in lightness plus the distance in color, scaled
+// so that black against white is 1. Unlike FLIP, small errors are not
+// boosted, so noise of one level doesn't weigh nearly as much as a wrong
+// shape.
+func compareFLIP(a, b *tImage, sigma float64) (float64, []float64) {
+	la := lab(a, sigma)
+	lb := lab(b, sigma)
+
+	n := len(a.pixels)
+	errors := make([]float64, n)
+	sum := 0.0
+	for i := range errors {
+		dl := math.Abs(la[0][i] - lb[0][i])
+		da := la[1][i] - lb[1][i]
+		db := la[2][i] - lb[2][i]
+		e := math.Min(1, (dl+math.Sqrt(da*da+db*db))/100)
+		errors[i] = e
+		sum += e
+	}
+	if n == 0 {
+		return 0, errors
+	}
+	return sum / float64(n), errors
+}
+
+// lab returns the L, a and b channels of the image, blurred.
+func lab(img *tImage, sigma float64) [3][]float64 {
+	var ch [3][]float64
+	for c := range ch {
+		ch[c] = make([]float64, len(img.pixels))
+	}
+	for i, p := range img.pixels {
+		v := color.Lab(glm.Vec3{p[0], p[1], p[2]})
+		for c := range ch {
+			ch[c][i] = float64(v[c])
+		}
+	}
+	for c := range ch {
+		gaussian(ch[c], img.width, img.height, sigma)
+	}
+	return ch
+}
+
+// gaussian blurs a channel in place, with edges clamped. A sigma of 0 or
+// less leaves it as it is.
+func gaussian(ch []float64, width, height int, sigma float64) {
+	if sigma <= 0 {
+		return
+	}
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	clamp := func(i, n int) int {
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	}
+
+	tmp := make([]float64, len(ch))
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			v := 0.0
+			for k, w := range kernel {
+				v += w * ch[row*width+clamp(col+k-radius, width)]
+			}
+			tmp[row*width+col] = v
+		}
+	}
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			v := 0.0
+			for k, w := range kernel {
+				v += w * tmp[clamp(row+k-radius, height)*width+col]
+			}
+			ch[row*width+col] = v
+		}
+	}
+}
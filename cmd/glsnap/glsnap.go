@@ -0,0 +1,187 @@
+// Command glsnap makes a thumbnail of each demo: it builds the demos, runs
+// each of them in a hidden window at the given size with -snapshot, and
+// collects the PNG files in a directory, one per demo:
+//
+//	glsnap [flags] [demo ...]
+//
+// Without arguments, it does all demos, which are the directories of the
+// repository with a main package that uses package app. It reports the
+// demos that failed, timed out, or drew nothing but a single color, and
+// then the exit status is 1. This makes it a quick check that every demo
+// still draws something.
+//
+// Demos are run in their own directory, as they would be by hand, so they
+// find their files and their config.json. A display is needed, as for any
+// window, but with Xvfb on Linux any machine will do:
+//
+//	xvfb-run glsnap -out gallery
+package main
+
+import (
+	"github.com/pebbe/gl/asset"
+
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	root    = flag.String("root", ".", "root directory of the repository")
+	out     = flag.String("out", "snapshots", "directory for the PNG files")
+	width   = flag.Int("width", 320, "width of the images")
+	height  = flag.Int("height", 240, "height of the images")
+	frames  = flag.Int("frames", 10, "number of the frame to write, as some demos need a few to get going")
+	timeout = flag.Duration("timeout", time.Minute, "time limit for each demo, after building it")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [demo ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	demos := flag.Args()
+	if len(demos) == 0 {
+		var err error
+		demos, err = findDemos(*root)
+		x(err)
+	}
+
+	x(os.MkdirAll(*out, 0755))
+	outDir, err := filepath.Abs(*out)
+	x(err)
+	binDir, err := os.MkdirTemp("", "glsnap")
+	x(err)
+	defer os.RemoveAll(binDir)
+
+	failed := 0
+	for _, demo := range demos {
+		filename := filepath.Join(outDir, demo+".png")
+		start := time.Now()
+		if err := snap(demo, binDir, filename); err != nil {
+			fmt.Printf("%-16s FAIL  %v\n", demo, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%-16s ok    %v\n", demo, time.Since(start).Round(time.Millisecond))
+	}
+
+	fmt.Printf("%d of %d demos failed\n", failed, len(demos))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// findDemos returns the names of the directories of the repository with a
+// main package that imports package app.
+func findDemos(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var demos []string
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "cmd" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		ok, err := isDemo(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			demos = append(demos, e.Name())
+		}
+	}
+	sort.Strings(demos)
+	return demos, nil
+}
+
+func isDemo(dir string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return false, err
+	}
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			return false, err
+		}
+		if f.Name.Name != "main" {
+			return false, nil
+		}
+		for _, imp := range f.Imports {
+			if path, _ := strconv.Unquote(imp.Path.Value); path == "github.com/pebbe/gl/app" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// snap builds the demo, and runs it to write its snapshot.
+func snap(demo, binDir, filename string) error {
+	bin := filepath.Join(binDir, demo)
+	build := exec.Command("go", "build", "-o", bin, "./"+demo)
+	build.Dir = *root
+	if output, err := build.CombinedOutput(); err != nil {
+		return fmt.Errorf("build: %v\n%s", err, indent(output))
+	}
+
+	os.Remove(filename)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	run := exec.CommandContext(ctx, bin,
+		"-snapshot", filename,
+		"-frames", strconv.Itoa(*frames),
+		"-width", strconv.Itoa(*width),
+		"-height", strconv.Itoa(*height))
+	run.Dir = filepath.Join(*root, demo)
+	output, err := run.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %v", *timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%v\n%s", err, indent(output))
+	}
+
+	img, err := asset.LoadImage(filename)
+	if err != nil {
+		return err
+	}
+	if len(img.Pix) == 0 {
+		return fmt.Errorf("empty image")
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		if !bytes.Equal(img.Pix[i:i+4], img.Pix[:4]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("nothing drawn, the image is a single color")
+}
+
+// indent returns the last lines of the output of a command, indented.
+func indent(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) > 10 {
+		lines = lines[len(lines)-10:]
+	}
+	return "    " + strings.Join(lines, "\n    ")
+}
+
+func x(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
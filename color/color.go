@@ -0,0 +1,71 @@
+// Package color contains conversions between color models, computed on
+// the CPU. Colors are glm.Vec3 with red, green and blue from 0 to 1.
+package color
+
+import (
+	"github.com/pebbe/gl/glm"
+
+	"math"
+)
+
+// HSV returns the color with hue h, saturation s and value v, all from 0
+// to 1. The hue wraps around: 0 and 1 are both red.
+func HSV(h, s, v float32) glm.Vec3 {
+	h = 6 * (h - float32(math.Floor(float64(h))))
+	i := int(h)
+	f := h - float32(i)
+	p := v * (1 - s)
+	q := v * (1 - s*f)
+	t := v * (1 - s*(1-f))
+	switch i {
+	case 0:
+		return glm.Vec3{v, t, p}
+	case 1:
+		return glm.Vec3{q, v, p}
+	case 2:
+		return glm.Vec3{p, v, t}
+	case 3:
+		return glm.Vec3{p, q, v}
+	case 4:
+		return glm.Vec3{t, p, v}
+	}
+	return glm.Vec3{v, p, q}
+}
+
+// Rainbow returns the fully saturated color with hue t, so t from 0 to 1
+// goes through red, yellow, green, cyan, blue, magenta and back to red.
+func Rainbow(t float32) glm.Vec3 {
+	return HSV(t, 1, 1)
+}
+
+// Linear returns the sRGB color c in linear light.
+func Linear(c glm.Vec3) glm.Vec3 {
+	var v glm.Vec3
+	for i, f := range c {
+		if f <= .04045 {
+			v[i] = f / 12.92
+		} else {
+			v[i] = float32(math.Pow((float64(f)+.055)/1.055, 2.4))
+		}
+	}
+	return v
+}
+
+// Lab returns the sRGB color c in CIELAB, with a D65 white point: L from 0
+// to 100 for lightness, a from green to red and b from blue to yellow, in
+// which equal distances look about equally different.
+func Lab(c glm.Vec3) glm.Vec3 {
+	l := Linear(c)
+	x := (.4124*l[0] + .3576*l[1] + .1805*l[2]) / .95047
+	y := .2126*l[0] + .7152*l[1] + .0722*l[2]
+	z := (.0193*l[0] + .1192*l[1] + .9505*l[2]) / 1.08883
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return glm.Vec3{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func labF(t float32) float32 {
+	if t > 216./24389 {
+		return float32(math.Cbrt(float64(t)))
+	}
+	return (24389./27*t + 16) / 116
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Applies a kernel of up to 5 x 5 to the image. Row 0 of the kernel is
+	// at the top, as the kernels are usually written.
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D image;
+uniform float kernel[25];
+uniform int size;
+uniform float bias;
+uniform float split; // left of this, in texture coordinates, the original
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    // the image has row 0 at the top
+    vec2 p = vec2(uv.x, 1.0 - uv.y);
+    if (uv.x < split) {
+        fragColor = texture(image, p);
+        return;
+    }
+    vec2 texel = 1.0 / vec2(textureSize(image, 0));
+    int h = size / 2;
+    vec3 sum = vec3(0.0);
+    for (int j = 0; j < size; j++) {
+        for (int i = 0; i < size; i++) {
+            sum += kernel[j * size + i] * texture(image, p + texel * vec2(i - h, j - h)).rgb;
+        }
+    }
+    fragColor = vec4(clamp(sum + bias, 0.0, 1.0), 1.0);
+}
+`
+)
+
+var (
+	imageFile = flag.String("image", "", "image to filter, instead of a generated one")
+	custom    = flag.String("kernel", "", "custom kernel, 9 or 25 comma separated values, row by row")
+)
+
+// Extra actions for this demo.
+const (
+	actionFilter = "filter"
+	actionSplit  = "split"
+)
+
+// A tFilter is a kernel of size by size values, normalized by dividing by
+// their sum if that isn't zero, with bias added to the result.
+type tFilter struct {
+	name   string
+	size   int
+	kernel []float32
+	bias   float32
+}
+
+var filters = []*tFilter{
+	{"identity", 3, []float32{
+		0, 0, 0,
+		0, 1, 0,
+		0, 0, 0,
+	}, 0},
+	{"sharpen", 3, []float32{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	}, 0},
+	{"edge detect", 3, []float32{
+		-1, -1, -1,
+		-1, 8, -1,
+		-1, -1, -1,
+	}, 0},
+	{"emboss", 3, []float32{
+		-2, -1, 0,
+		-1, 0, 1,
+		0, 1, 2,
+	}, .5},
+	{"box blur 3 x 3", 3, []float32{
+		1, 1, 1,
+		1, 1, 1,
+		1, 1, 1,
+	}, 0},
+	{"box blur 5 x 5", 5, []float32{
+		1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1,
+	}, 0},
+	{"gaussian blur 5 x 5", 5, []float32{
+		1, 4, 6, 4, 1,
+		4, 16, 24, 16, 4,
+		6, 24, 36, 24, 6,
+		4, 16, 24, 16, 4,
+		1, 4, 6, 4, 1,
+	}, 0},
+	{"unsharp mask 5 x 5", 5, []float32{
+		-1, -4, -6, -4, -1,
+		-4, -16, -24, -16, -4,
+		-6, -24, 476, -24, -6,
+		-4, -16, -24, -16, -4,
+		-1, -4, -6, -4, -1,
+	}, 0},
+}
+
+// parseKernel reads a kernel from comma separated values.
+func parseKernel(s string) (*tFilter, error) {
+	f := &tFilter{name: "custom"}
+	for _, field := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+		if err != nil {
+			return nil, fmt.Errorf("kernel: %v", err)
+		}
+		f.kernel = append(f.kernel, float32(v))
+	}
+	switch len(f.kernel) {
+	case 9:
+		f.size = 3
+	case 25:
+		f.size = 5
+	default:
+		return nil, fmt.Errorf("kernel: need 9 or 25 values, got %d", len(f.kernel))
+	}
+	return f, nil
+}
+
+// normalized returns the kernel divided by its sum, or the kernel itself
+// if the sum is zero, padded to 25 values.
+func (f *tFilter) normalized() [25]float32 {
+	var sum float32
+	for _, v := range f.kernel {
+		sum += v
+	}
+	if sum == 0 {
+		sum = 1
+	}
+	var k [25]float32
+	for i, v := range f.kernel {
+		k[i] = v / sum
+	}
+	return k
+}
+
+// makeImage returns a test image with smooth gradients, hard edges and
+// fine detail, to show what each filter does.
+func makeImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			// a sky fading to the horizon
+			r, g, b := 80+100*fy, 130+80*fy, 220-20*fy
+
+			// a sun
+			if d := math.Hypot(fx-.75, (fy-.3)*float64(height)/float64(width)); d < .1 {
+				r, g, b = 255, 220, 120
+			}
+			// hills with stripes of fields
+			if fy > .6+.08*math.Sin(fx*9) {
+				r, g, b = 60, 140, 60
+				if int(fx*40+fy*20)%2 == 0 {
+					r, g, b = 90, 170, 70
+				}
+			}
+			// a checkerboard sign, with fine lines under it
+			if fx > .1 && fx < .35 && fy > .2 && fy < .45 {
+				if (int(fx*80)+int(fy*80))%2 == 0 {
+					r, g, b = 30, 30, 30
+				} else {
+					r, g, b = 240, 240, 240
+				}
+			}
+			if fx > .1 && fx < .35 && fy > .47 && fy < .55 && y%3 == 0 {
+				r, g, b = 200, 40, 40
+			}
+			img.SetRGBA(x, y, color.RGBA{uint8(r), uint8(g), uint8(b), 255})
+		}
+	}
+	return img
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	texture uint32
+
+	width, height int32 // of the image
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	if *imageFile != "" {
+		r.texture, err = glutil.MakeTexture(*imageFile)
+		x(err)
+	} else {
+		r.texture = glutil.MakeTextureFromImage(makeImage(800, 600))
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.texture)
+	gl.GetTexLevelParameteriv(gl.TEXTURE_2D, 0, gl.TEXTURE_WIDTH, &r.width)
+	gl.GetTexLevelParameteriv(gl.TEXTURE_2D, 0, gl.TEXTURE_HEIGHT, &r.height)
+	// the kernel is applied to texels, not to a blend of neighbours
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	current = 1
+	split   = false
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.ClearColor(app.Background(.1, .1, .1, 0))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// the image as large as fits, centered
+	s := math.Min(float64(width)/float64(r.width), float64(height)/float64(r.height))
+	vw, vh := int32(s*float64(r.width)), int32(s*float64(r.height))
+	gl.Viewport((int32(width)-vw)/2, (int32(height)-vh)/2, vw, vh)
+	glutil.State{}.Apply()
+
+	f := filters[current]
+	k := f.normalized()
+	p := r.program
+	p.Use()
+	p.SetInt("image", 0)
+	gl.Uniform1fv(p.Uniform("kernel"), 25, &k[0])
+	p.SetInt("size", int32(f.size))
+	p.SetFloat("bias", f.bias)
+	if split {
+		p.SetFloat("split", .5)
+	} else {
+		p.SetFloat("split", 0)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.texture)
+	glutil.DrawFullscreen()
+}
+
+func main() {
+	app.Keys[actionFilter] = []string{"f"}
+	app.Keys[actionSplit] = []string{"s"}
+	app.Flags(800, 600, "Convolution")
+
+	if *custom != "" {
+		f, err := parseKernel(*custom)
+		x(err)
+		filters = append(filters, f)
+		current = len(filters) - 1
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+
+	app.Infof("Image of %d x %d pixels", r.width, r.height)
+	app.Info("Filter:", filters[current].name)
+	app.Info("Press 'f' for the next filter, 's' to compare with the original")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionFilter:
+		current = (current + 1) % len(filters)
+		app.Info("Filter:", filters[current].name)
+	case actionSplit:
+		split = !split
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
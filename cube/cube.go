@@ -0,0 +1,329 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 mvp;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec2 texcoord;
+
+out vec2 uv;
+
+void main()
+{
+    gl_Position = mvp * vec4(position, 1.0);
+    uv = texcoord;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D tex;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = texture(tex, uv);
+}
+` + "\x00"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	vertexArray   uint32
+	vertexBuffer  uint32
+	elementBuffer uint32
+	program       uint32
+	mvp           int32
+	tex           int32
+	textures      [6]uint32
+}
+
+//
+// The cube: four vertices per face, so each face can have its own texture
+// coordinates. Each face is given by its normal, and two vectors along its
+// sides, chosen so that the corners are in counter-clockwise order when
+// seen from outside.
+//
+
+var faces = [6]struct {
+	normal, right, up glm.Vec3
+	pips              int
+}{
+	{glm.Vec3{1, 0, 0}, glm.Vec3{0, 0, -1}, glm.Vec3{0, 1, 0}, 1},
+	{glm.Vec3{-1, 0, 0}, glm.Vec3{0, 0, 1}, glm.Vec3{0, 1, 0}, 6},
+	{glm.Vec3{0, 1, 0}, glm.Vec3{1, 0, 0}, glm.Vec3{0, 0, -1}, 2},
+	{glm.Vec3{0, -1, 0}, glm.Vec3{1, 0, 0}, glm.Vec3{0, 0, 1}, 5},
+	{glm.Vec3{0, 0, 1}, glm.Vec3{1, 0, 0}, glm.Vec3{0, 1, 0}, 3},
+	{glm.Vec3{0, 0, -1}, glm.Vec3{-1, 0, 0}, glm.Vec3{0, 1, 0}, 4},
+}
+
+func makeCube() (vertices []float32, indices []uint32) {
+	// corners as multiples of right and up, with texture coordinates;
+	// the first row of an image is at t = 0
+	corners := [4][4]float32{
+		{-1, -1, 0, 1},
+		{1, -1, 1, 1},
+		{1, 1, 1, 0},
+		{-1, 1, 0, 0},
+	}
+	for i, f := range faces {
+		for _, c := range corners {
+			p := f.normal.Add(f.right.Mul(c[0])).Add(f.up.Mul(c[1]))
+			vertices = append(vertices, p[0], p[1], p[2], c[2], c[3])
+		}
+		n := uint32(4 * i)
+		indices = append(indices, n, n+1, n+2, n, n+2, n+3)
+	}
+	return
+}
+
+// makeDieFace draws a face of a die.
+func makeDieFace(pips int, bg color.RGBA) *image.RGBA {
+	const size = 128
+	// pip positions on a 3x3 grid
+	positions := map[int][][2]int{
+		1: {{1, 1}},
+		2: {{0, 0}, {2, 2}},
+		3: {{0, 0}, {1, 1}, {2, 2}},
+		4: {{0, 0}, {2, 0}, {0, 2}, {2, 2}},
+		5: {{0, 0}, {2, 0}, {1, 1}, {0, 2}, {2, 2}},
+		6: {{0, 0}, {2, 0}, {0, 1}, {2, 1}, {0, 2}, {2, 2}},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := bg
+			if x < 4 || y < 4 || x >= size-4 || y >= size-4 {
+				c = color.RGBA{40, 40, 40, 255}
+			}
+			for _, p := range positions[pips] {
+				dx := float64(x - 28 - 36*p[0])
+				dy := float64(y - 28 - 36*p[1])
+				if math.Hypot(dx, dy) < 12 {
+					c = color.RGBA{255, 255, 255, 255}
+				}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.MakeProgramFromSource(vertex_glsl, fragment_glsl)
+	x(err)
+	r.mvp = gl.GetUniformLocation(r.program, gl.Str("mvp\x00"))
+	r.tex = gl.GetUniformLocation(r.program, gl.Str("tex\x00"))
+
+	vertices, indices := makeCube()
+	gl.GenVertexArrays(1, &r.vertexArray)
+	gl.BindVertexArray(r.vertexArray)
+	r.vertexBuffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, vertices, gl.STATIC_DRAW)
+	r.elementBuffer = glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, indices, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 20, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(1, 2, gl.FLOAT, false, 20, gl.PtrOffset(12))
+	gl.EnableVertexAttribArray(1)
+	gl.BindVertexArray(0)
+
+	for i, f := range faces {
+		rd, g, b := hsb2rgb(float32(i)/6, .6, .8)
+		bg := color.RGBA{uint8(255 * rd), uint8(255 * g), uint8(255 * b), 255}
+		r.textures[i] = glutil.MakeTextureFromImage(makeDieFace(f.pips, bg))
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	zoom  = input.NewZoom(.3, 3)
+	drag  input.Drag
+
+	yaw, pitch float32 = 0, .5
+)
+
+// Three-dimensional state: depth test and back-face culling.
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func update() {
+	if !drag.Dragging() {
+		yaw += float32(.5 * clock.Delta())
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.5, .5, .5, 0))
+	state3D.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	aspect := float32(width) / float32(height)
+	projection := glm.Perspective(glm.Radians(45), aspect, .1, 100)
+	view := glm.LookAt(glm.Vec3{0, 0, 6 / float32(zoom.Scale())}, glm.Vec3{}, glm.Vec3{0, 1, 0})
+	model := glm.Rotate(pitch, glm.Vec3{1, 0, 0}).Mul(glm.Rotate(yaw, glm.Vec3{0, 1, 0}))
+	mvp := projection.Mul(view).Mul(model)
+
+	gl.UseProgram(r.program)
+	gl.UniformMatrix4fv(r.mvp, 1, false, &mvp[0])
+	gl.Uniform1i(r.tex, 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	gl.BindVertexArray(r.vertexArray)
+	for i := range faces {
+		gl.BindTexture(gl.TEXTURE_2D, r.textures[i])
+		gl.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, gl.PtrOffset(6*4*i))
+	}
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Flags(640, 480, "Cube")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	app.Robust()
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	setupWindow(w)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	// after a GPU reset, start again with a new context
+	app.HandleRebuild(func(w *glfw.Window) {
+		setupWindow(w)
+		r = makeResources()
+	})
+
+	app.Info("Drag with the mouse to rotate the cube, scroll to zoom")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+		w = app.RecoverContext(w)
+	}
+}
+
+// setupWindow sets the callbacks and the swap interval of a new window.
+func setupWindow(w *glfw.Window) {
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+	drag.OnMove = func(x, y, dx, dy float64) {
+		yaw += float32(dx) * .01
+		pitch += float32(dy) * .01
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	m := b - c
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c + m, x + m, m
+	}
+	if h < 2 {
+		return x + m, c + m, m
+	}
+	if h < 3 {
+		return m, c + m, x + m
+	}
+	if h < 4 {
+		return m, x + m, c + m
+	}
+	if h < 5 {
+		return x + m, m, c + m
+	}
+	return c + m, m, x + m
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+uniform vec3 lightDirection;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    float diffuse = max(dot(normalize(worldNormal), lightDirection), 0.0);
+    fragColor = vec4((0.3 + 0.7 * diffuse) * color, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	objectCount = flag.Int("objects", 20000, "number of objects")
+	objFile     = flag.String("obj", "", "OBJ file to use as one of the shapes")
+)
+
+// Extra actions for this demo.
+const (
+	actionCulling = "culling"
+	actionBounds  = "bounds"
+)
+
+// Size of the field the objects are spread over.
+const fieldSize = 200
+
+//
+// The objects, each with its bounding volumes in world coordinates
+//
+
+type tShape struct {
+	vao    *mesh.VAO
+	box    glm.AABB
+	sphere glm.Sphere
+}
+
+type tObject struct {
+	shape  *tShape
+	model  glm.Mat4
+	color  glm.Vec3
+	box    glm.AABB
+	sphere glm.Sphere
+}
+
+var objects []tObject
+
+func makeObjects(shapes []*tShape) {
+	objects = make([]tObject, *objectCount)
+	for i := range objects {
+		s := shapes[rand.Intn(len(shapes))]
+		scale := .5 + rand.Float32()
+		model := glm.Translate(fieldSize*(rand.Float32()-.5), scale, fieldSize*(rand.Float32()-.5)).
+			Mul(glm.Rotate(6.3*rand.Float32(), glm.Vec3{0, 1, 0})).
+			Mul(glm.Scale(scale, scale, scale))
+		objects[i] = tObject{
+			shape:  s,
+			model:  model,
+			color:  glm.Vec3{.3 + .7*rand.Float32(), .3 + .7*rand.Float32(), .3 + .7*rand.Float32()},
+			box:    s.box.Transform(model),
+			sphere: s.sphere.Transform(model),
+		}
+	}
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	shapes  []*tShape
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	meshes := []*mesh.Mesh{mesh.Cube(), mesh.Sphere(24, 12)}
+	if *objFile != "" {
+		model, err := mesh.LoadOBJ(*objFile)
+		x(err)
+		// scaled to fit in the same space as the other shapes
+		m := model.Mesh
+		s := m.BoundingSphere()
+		for i, v := range m.Vertices {
+			m.Vertices[i].Position = v.Position.Sub(s.Center).Mul(1 / s.Radius)
+		}
+		meshes = append(meshes, m)
+	}
+	for _, m := range meshes {
+		r.shapes = append(r.shapes, &tShape{
+			vao:    m.Upload(),
+			box:    m.Bounds(),
+			sphere: m.BoundingSphere(),
+		})
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam        *camera.FreeFly
+	culling    = true
+	useSpheres = true
+
+	// for the statistics printed once per second
+	frames    int
+	drawn     int
+	lastPrint = time.Now()
+)
+
+var stateScene = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	stateScene.Apply()
+	gl.ClearColor(app.Background(.6, .7, .85, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	view := cam.View()
+	projection := glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 150)
+	frustum := glm.NewFrustum(projection.Mul(view))
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("lightDirection", glm.Vec3{.4, 1, .6}.Normalize())
+
+	count := 0
+	for _, o := range objects {
+		if culling {
+			if useSpheres && !frustum.ContainsSphere(o.sphere) || !useSpheres && !frustum.ContainsAABB(o.box) {
+				continue
+			}
+		}
+		p.SetMat4("model", o.model)
+		p.SetMat3("normalMatrix", o.model.NormalMatrix())
+		p.SetVec3("color", o.color)
+		o.shape.vao.Draw()
+		count++
+	}
+
+	frames++
+	drawn += count
+	if d := time.Since(lastPrint); d >= time.Second {
+		app.Infof("Drawn: %d of %d objects, %.1f fps", drawn/frames, len(objects), float64(frames)/d.Seconds())
+		frames, drawn = 0, 0
+		lastPrint = time.Now()
+	}
+}
+
+func main() {
+	app.Keys[actionCulling] = []string{"c"}
+	app.Keys[actionBounds] = []string{"b"}
+	app.Flags(800, 600, "Frustum culling")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	// no waiting for vertical sync, to see the difference in frame rate
+	glfw.SwapInterval(0)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewFreeFly(glm.Vec3{0, 2, 0})
+	cam.Speed = 15
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	makeObjects(r.shapes)
+
+	app.Info("Drag with the mouse to look around, W, A, S and D to move")
+	app.Info("Press 'c' to toggle culling, 'b' to switch between bounding spheres and boxes")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		cam.Update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionCulling:
+		culling = !culling
+		if culling {
+			app.Info("Culling on")
+		} else {
+			app.Info("Culling off")
+		}
+	case actionBounds:
+		useSpheres = !useSpheres
+		if useSpheres {
+			app.Info("Testing bounding spheres")
+		} else {
+			app.Info("Testing bounding boxes")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
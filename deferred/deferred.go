@@ -0,0 +1,444 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+const maxLights = 64
+
+var (
+	//
+	// geometry pass: fill the G-buffer
+	//
+	geometry_vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+}
+`
+	geometry_fragment_glsl = `
+#version 330 core
+
+uniform vec3 albedo;
+uniform float specular;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+layout(location = 0) out vec4 gPosition;
+layout(location = 1) out vec4 gNormal;
+layout(location = 2) out vec4 gAlbedo;
+
+void main()
+{
+    gPosition = vec4(worldPosition, 1.0);
+    gNormal = vec4(normalize(worldNormal), 0.0);
+    gAlbedo = vec4(albedo, specular);
+}
+`
+
+	//
+	// lighting pass: shade every pixel of the screen
+	//
+	lighting_fragment_glsl = fmt.Sprintf(`
+#version 330 core
+
+struct Light {
+    vec3 position;
+    vec3 color;
+    float radius;
+};
+
+uniform sampler2D gPosition;
+uniform sampler2D gNormal;
+uniform sampler2D gAlbedo;
+
+uniform Light lights[%d];
+uniform int numLights;
+uniform vec3 viewPosition;
+uniform int mode;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 p = texture(gPosition, uv);
+    vec3 n = texture(gNormal, uv).xyz;
+    vec4 albedo = texture(gAlbedo, uv);
+
+    if (mode == 1) {
+        fragColor = vec4(fract(p.xyz), 1.0);
+        return;
+    }
+    if (mode == 2) {
+        fragColor = vec4(n * 0.5 + 0.5, 1.0);
+        return;
+    }
+    if (mode == 3) {
+        fragColor = vec4(albedo.rgb, 1.0);
+        return;
+    }
+    if (p.w == 0.0) {
+        // background
+        fragColor = vec4(0.0);
+        return;
+    }
+
+    vec3 v = normalize(viewPosition - p.xyz);
+    vec3 result = 0.05 * albedo.rgb;
+    for (int i = 0; i < numLights; i++) {
+        vec3 d = lights[i].position - p.xyz;
+        float dist = length(d);
+        if (dist > lights[i].radius) {
+            continue;
+        }
+        vec3 l = d / dist;
+        vec3 h = normalize(l + v);
+        float attenuation = pow(1.0 - dist / lights[i].radius, 2.0);
+        float diffuse = max(dot(n, l), 0.0);
+        float spec = albedo.a * pow(max(dot(n, h), 0.0), 32.0);
+        result += attenuation * lights[i].color * (diffuse * albedo.rgb + spec);
+    }
+    fragColor = vec4(result, 1.0);
+}
+`, maxLights)
+
+	//
+	// forward pass: the lights themselves
+	//
+	lamp_fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color, 1.0);
+}
+`
+)
+
+var numLights = flag.Int("lights", 48, fmt.Sprintf("number of lights, at most %d", maxLights))
+
+// Extra action for this demo: cycle through the final image and the
+// contents of the G-buffer.
+const actionView = "view"
+
+var viewNames = []string{"lit", "position", "normal", "albedo"}
+
+//
+// Global data used by render
+//
+
+type tObject struct {
+	vao      *mesh.VAO
+	model    glm.Mat4
+	albedo   glm.Vec3
+	specular float32
+}
+
+type tLight struct {
+	center glm.Vec3 // center of the circle the light moves on
+	orbit  float32  // radius of the circle
+	speed  float32
+	phase  float32
+	color  glm.Vec3
+}
+
+type gResources struct {
+	geometry *glutil.Program
+	lighting *glutil.Program
+	lamp     *glutil.Program
+	gbuffer  *glutil.Framebuffer
+
+	sphere  *mesh.VAO
+	objects []tObject
+	lights  []tLight
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.geometry, err = glutil.NewProgram(geometry_vertex_glsl, geometry_fragment_glsl)
+	x(err)
+	r.lighting, err = glutil.NewProgram(glutil.FullscreenVertexShader, lighting_fragment_glsl)
+	x(err)
+	r.lamp, err = glutil.NewProgram(geometry_vertex_glsl, lamp_fragment_glsl)
+	x(err)
+
+	cube := mesh.Cube().Upload()
+	r.sphere = mesh.Sphere(24, 12).Upload()
+
+	// the ground, and a grid of cubes and spheres
+	r.objects = append(r.objects, tObject{
+		vao:    mesh.Quad().Upload(),
+		model:  glm.Translate(0, -.5, 0).Mul(glm.Scale(8, 8, 8)).Mul(glm.Rotate(-math.Pi/2, glm.Vec3{1, 0, 0})),
+		albedo: glm.Vec3{.7, .7, .7},
+	})
+	for i := -3; i <= 3; i++ {
+		for j := -3; j <= 3; j++ {
+			obj := tObject{
+				vao:      cube,
+				model:    glm.Translate(float32(i)*1.5, 0, float32(j)*1.5).Mul(glm.Scale(.4, .4, .4)),
+				albedo:   glm.Vec3{.5 + .5*rand.Float32(), .5 + .5*rand.Float32(), .5 + .5*rand.Float32()},
+				specular: .5,
+			}
+			if (i+j)%2 != 0 {
+				obj.vao = r.sphere
+				obj.specular = 1
+			}
+			r.objects = append(r.objects, obj)
+		}
+	}
+
+	n := *numLights
+	if n > maxLights {
+		n = maxLights
+	}
+	for i := 0; i < n; i++ {
+		red, green, blue := hsb2rgb(rand.Float32(), .7, 1)
+		r.lights = append(r.lights, tLight{
+			center: glm.Vec3{8*rand.Float32() - 4, .2 + .6*rand.Float32(), 8*rand.Float32() - 4},
+			orbit:  .5 + 1.5*rand.Float32(),
+			speed:  .2 + rand.Float32(),
+			phase:  2 * math.Pi * rand.Float32(),
+			color:  glm.Vec3{red, green, blue},
+		})
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	zoom  = input.NewZoom(.5, 3)
+	view  = 0
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func lightPosition(l tLight, t float32) glm.Vec3 {
+	a := float64(l.phase + l.speed*t)
+	return l.center.Add(glm.Vec3{l.orbit * float32(math.Cos(a)), 0, l.orbit * float32(math.Sin(a))})
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	if r.gbuffer == nil || r.gbuffer.Width != int32(width) || r.gbuffer.Height != int32(height) {
+		if r.gbuffer != nil {
+			r.gbuffer.Delete()
+		}
+		var err error
+		r.gbuffer, err = glutil.NewMultiFramebuffer(int32(width), int32(height),
+			glutil.FormatRGBA16F, // position
+			glutil.FormatRGBA16F, // normal
+			glutil.FormatRGBA8)   // albedo, and specular intensity in alpha
+		x(err)
+	}
+
+	t := float32(clock.Seconds())
+
+	a := float64(t / 10)
+	d := 12 / float32(zoom.Scale())
+	eye := glm.Vec3{d * float32(math.Sin(a)), d / 2, d * float32(math.Cos(a))}
+	viewMatrix := glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0})
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100)
+
+	// geometry pass
+	r.gbuffer.Bind()
+	state3D.Apply()
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	p := r.geometry
+	p.Use()
+	p.SetMat4("view", viewMatrix)
+	p.SetMat4("projection", projection)
+	for _, obj := range r.objects {
+		p.SetMat4("model", obj.model)
+		p.SetMat3("normalMatrix", obj.model.NormalMatrix())
+		p.SetVec3("albedo", obj.albedo)
+		p.SetFloat("specular", obj.specular)
+		obj.vao.Draw()
+	}
+	r.gbuffer.Unbind()
+
+	// lighting pass
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	p = r.lighting
+	p.Use()
+	for i, name := range []string{"gPosition", "gNormal", "gAlbedo"} {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, r.gbuffer.Textures[i])
+		p.SetInt(name, int32(i))
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	for i, l := range r.lights {
+		prefix := fmt.Sprintf("lights[%d].", i)
+		p.SetVec3(prefix+"position", lightPosition(l, t))
+		p.SetVec3(prefix+"color", l.color)
+		p.SetFloat(prefix+"radius", 3)
+	}
+	p.SetInt("numLights", int32(len(r.lights)))
+	p.SetVec3("viewPosition", eye)
+	p.SetInt("mode", int32(view))
+	glutil.DrawFullscreen()
+
+	// forward pass, with the depth from the geometry pass
+	if view != 0 {
+		return
+	}
+	r.gbuffer.BlitDepth()
+	state3D.Apply()
+	p = r.lamp
+	p.Use()
+	p.SetMat4("view", viewMatrix)
+	p.SetMat4("projection", projection)
+	for _, l := range r.lights {
+		pos := lightPosition(l, t)
+		p.SetMat4("model", glm.Translate(pos[0], pos[1], pos[2]).Mul(glm.Scale(.05, .05, .05)))
+		p.SetVec3("color", l.color)
+		r.sphere.Draw()
+	}
+}
+
+func main() {
+	app.Keys[actionView] = []string{"g"}
+	app.Flags(800, 600, "Deferred shading")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Press 'g' to show the contents of the G-buffer")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionView:
+		view = (view + 1) % len(viewNames)
+		app.Info("View:", viewNames[view])
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	m := b - c
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c + m, x + m, m
+	}
+	if h < 2 {
+		return x + m, c + m, m
+	}
+	if h < 3 {
+		return m, c + m, x + m
+	}
+	if h < 4 {
+		return m, x + m, c + m
+	}
+	if h < 5 {
+		return x + m, m, c + m
+	}
+	return c + m, m, x + m
+}
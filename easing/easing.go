@@ -0,0 +1,151 @@
+// Package easing contains easing functions for animations.
+//
+// An easing function maps the progress of an animation, from 0 to 1, to
+// the value to use at that point, which starts at 0 and ends at 1 but may
+// overshoot in between.
+package easing
+
+import (
+	"math"
+)
+
+// Func is an easing function.
+type Func func(t float64) float64
+
+// Easing functions by name, for use with command line flags.
+var ByName = map[string]Func{
+	"linear":       Linear,
+	"inquad":       InQuad,
+	"outquad":      OutQuad,
+	"inoutquad":    InOutQuad,
+	"incubic":      InCubic,
+	"outcubic":     OutCubic,
+	"inoutcubic":   InOutCubic,
+	"inelastic":    InElastic,
+	"outelastic":   OutElastic,
+	"inoutelastic": InOutElastic,
+	"inbounce":     InBounce,
+	"outbounce":    OutBounce,
+	"inoutbounce":  InOutBounce,
+	"ease":         Bezier(.25, .1, .25, 1),
+	"easeinout":    Bezier(.42, 0, .58, 1),
+}
+
+func clamp(t float64) float64 {
+	return math.Max(0, math.Min(1, t))
+}
+
+func Linear(t float64) float64 {
+	return clamp(t)
+}
+
+func InQuad(t float64) float64 {
+	t = clamp(t)
+	return t * t
+}
+
+func OutQuad(t float64) float64 {
+	t = clamp(t)
+	return t * (2 - t)
+}
+
+func InOutQuad(t float64) float64 {
+	t = clamp(t)
+	if t < .5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+func InCubic(t float64) float64 {
+	t = clamp(t)
+	return t * t * t
+}
+
+func OutCubic(t float64) float64 {
+	t = clamp(t) - 1
+	return t*t*t + 1
+}
+
+func InOutCubic(t float64) float64 {
+	t = clamp(t)
+	if t < .5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return t*t*t/2 + 1
+}
+
+func InElastic(t float64) float64 {
+	return 1 - OutElastic(1-t)
+}
+
+func OutElastic(t float64) float64 {
+	t = clamp(t)
+	if t == 0 || t == 1 {
+		return t
+	}
+	return math.Pow(2, -10*t)*math.Sin((t*10-.75)*2*math.Pi/3) + 1
+}
+
+func InOutElastic(t float64) float64 {
+	t = clamp(t)
+	if t < .5 {
+		return InElastic(2*t) / 2
+	}
+	return OutElastic(2*t-1)/2 + .5
+}
+
+func InBounce(t float64) float64 {
+	return 1 - OutBounce(1-t)
+}
+
+func OutBounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	t = clamp(t)
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + .75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + .9375
+	default:
+		t -= 2.625 / d
+		return n*t*t + .984375
+	}
+}
+
+func InOutBounce(t float64) float64 {
+	t = clamp(t)
+	if t < .5 {
+		return InBounce(2*t) / 2
+	}
+	return OutBounce(2*t-1)/2 + .5
+}
+
+// Bezier returns an easing function for a cubic Bézier curve from (0, 0) to
+// (1, 1) with control points (x1, y1) and (x2, y2), like cubic-bezier() in CSS.
+// The values of x1 and x2 must be in the range 0 to 1.
+func Bezier(x1, y1, x2, y2 float64) Func {
+	// coordinate on the curve for parameter s
+	bezier := func(s, p1, p2 float64) float64 {
+		return 3*(1-s)*(1-s)*s*p1 + 3*(1-s)*s*s*p2 + s*s*s
+	}
+	return func(t float64) float64 {
+		t = clamp(t)
+		// find s where x(s) = t, by bisection, since x is monotonic
+		lo, hi := 0.0, 1.0
+		for i := 0; i < 30; i++ {
+			s := (lo + hi) / 2
+			if bezier(s, x1, x2) < t {
+				lo = s
+			} else {
+				hi = s
+			}
+		}
+		return bezier((lo+hi)/2, y1, y2)
+	}
+}
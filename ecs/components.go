@@ -0,0 +1,51 @@
+package ecs
+
+import (
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+)
+
+// The components used by the systems of this package.
+
+// Transform is the position, rotation and scale of an entity.
+type Transform struct {
+	Position glm.Vec3
+	Rotation glm.Quat
+	Scale    glm.Vec3
+}
+
+// NewTransform returns a transform at a position, without rotation, and
+// with the same scale along all axes.
+func NewTransform(position glm.Vec3, scale float32) Transform {
+	return Transform{
+		Position: position,
+		Rotation: glm.Quat{0, 0, 0, 1},
+		Scale:    glm.Vec3{scale, scale, scale},
+	}
+}
+
+// Mat4 returns the transformation as a matrix: first scale, then rotate,
+// then translate.
+func (t Transform) Mat4() glm.Mat4 {
+	return glm.Translate(t.Position[0], t.Position[1], t.Position[2]).
+		Mul(t.Rotation.Mat4()).
+		Mul(glm.Scale(t.Scale[0], t.Scale[1], t.Scale[2]))
+}
+
+// Velocity is how fast an entity moves, in units per second.
+type Velocity struct {
+	Linear glm.Vec3
+}
+
+// Mesh is what is drawn for an entity. Many entities can share a VAO.
+type Mesh struct {
+	VAO *mesh.VAO
+}
+
+// Material is how the mesh of an entity is drawn: with a program, and a
+// color that the program gets per instance.
+type Material struct {
+	Program *glutil.Program
+	Color   glm.Vec4
+}
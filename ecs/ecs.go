@@ -0,0 +1,132 @@
+// Package ecs is a small entity-component structure, for demos with many
+// objects of a few kinds, such as games.
+//
+// An entity is only a number. Its data is in components, each kind of
+// component in a Store of its own, packed in a slice so that a system, a
+// function that updates all entities with some components, goes through
+// memory in order. The World has stores for the components that the
+// systems of this package use, and a demo can add stores for its own.
+package ecs
+
+// Entity identifies an object. Entities are never reused, so a stale
+// entity has no components, rather than those of another object.
+type Entity uint32
+
+// World holds the entities and their components.
+type World struct {
+	Transforms *Store[Transform]
+	Velocities *Store[Velocity]
+	Meshes     *Store[Mesh]
+	Materials  *Store[Material]
+
+	last   Entity
+	stores []remover
+}
+
+// A store, for World.Destroy.
+type remover interface {
+	Remove(e Entity)
+}
+
+// NewWorld returns a world without entities.
+func NewWorld() *World {
+	w := &World{}
+	w.Transforms = NewStore[Transform](w)
+	w.Velocities = NewStore[Velocity](w)
+	w.Meshes = NewStore[Mesh](w)
+	w.Materials = NewStore[Material](w)
+	return w
+}
+
+// Spawn returns a new entity, without components.
+func (w *World) Spawn() Entity {
+	w.last++
+	return w.last
+}
+
+// Destroy removes all components of an entity, from all stores of the
+// world.
+func (w *World) Destroy(e Entity) {
+	for _, s := range w.stores {
+		s.Remove(e)
+	}
+}
+
+// Store holds the components of one kind, with the entities they belong
+// to. An entity has at most one component in a store.
+type Store[T any] struct {
+	components []T
+	entities   []Entity       // the entity of each component
+	index      map[Entity]int // the index of the component of each entity
+}
+
+// NewStore returns a store for components of type T, that is emptied for
+// an entity by w.Destroy.
+func NewStore[T any](w *World) *Store[T] {
+	s := &Store[T]{index: make(map[Entity]int)}
+	w.stores = append(w.stores, s)
+	return s
+}
+
+// Add sets the component of an entity, and returns a pointer to it, which
+// is valid until a component is added to or removed from the store.
+func (s *Store[T]) Add(e Entity, c T) *T {
+	if i, ok := s.index[e]; ok {
+		s.components[i] = c
+		return &s.components[i]
+	}
+	s.index[e] = len(s.components)
+	s.components = append(s.components, c)
+	s.entities = append(s.entities, e)
+	return &s.components[len(s.components)-1]
+}
+
+// Get returns a pointer to the component of an entity, or nil if it has
+// none. The pointer is valid until a component is added to or removed from
+// the store.
+func (s *Store[T]) Get(e Entity) *T {
+	if i, ok := s.index[e]; ok {
+		return &s.components[i]
+	}
+	return nil
+}
+
+// Has reports whether an entity has a component in the store.
+func (s *Store[T]) Has(e Entity) bool {
+	_, ok := s.index[e]
+	return ok
+}
+
+// Remove removes the component of an entity, if it has one. The last
+// component takes its place.
+func (s *Store[T]) Remove(e Entity) {
+	i, ok := s.index[e]
+	if !ok {
+		return
+	}
+	last := len(s.components) - 1
+	if i != last {
+		s.components[i] = s.components[last]
+		s.entities[i] = s.entities[last]
+		s.index[s.entities[i]] = i
+	}
+	var zero T
+	s.components[last] = zero // for the garbage collector
+	s.components = s.components[:last]
+	s.entities = s.entities[:last]
+	delete(s.index, e)
+}
+
+// Len returns the number of components in the store.
+func (s *Store[T]) Len() int {
+	return len(s.components)
+}
+
+// Each calls f for each component in the store, with its entity. The
+// components are visited from last to first, so f may remove the current
+// component, or destroy its entity, but no others.
+func (s *Store[T]) Each(f func(e Entity, c *T)) {
+	for i := len(s.components) - 1; i >= 0; i-- {
+		f(s.entities[i], &s.components[i])
+	}
+}
@@ -0,0 +1,79 @@
+package ecs
+
+import (
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+)
+
+// Physics moves entities with a Transform and a Velocity by dt seconds,
+// with a constant acceleration such as gravity, and a drag that slows them
+// down by a fraction of their speed per second.
+func Physics(w *World, dt float32, gravity glm.Vec3, drag float32) {
+	w.Velocities.Each(func(e Entity, v *Velocity) {
+		t := w.Transforms.Get(e)
+		if t == nil {
+			return
+		}
+		v.Linear = v.Linear.Add(gravity.Mul(dt))
+		v.Linear = v.Linear.Sub(v.Linear.Mul(drag * dt))
+		t.Position = t.Position.Add(v.Linear.Mul(dt))
+	})
+}
+
+// Renderer draws entities with a Transform, a Mesh and a Material, with a
+// draw call for each combination of program and VAO, using instancing.
+//
+// The programs get the uniforms view and projection, and must declare the
+// inputs of mesh.VAO, with the model matrix and the color per instance:
+//
+//	layout(location = 6) in mat4 model;  // mesh.InstanceModelLocation
+//	layout(location = 10) in vec4 color; // mesh.InstanceDataLocation
+//
+// The pipeline state is left as it is, so set it before Draw.
+type Renderer struct {
+	batches map[batchKey][]mesh.Instance
+	order   []batchKey // batches in order of first use, for a stable order
+}
+
+type batchKey struct {
+	program *glutil.Program
+	vao     *mesh.VAO
+}
+
+// Draw draws the entities of the world.
+func (r *Renderer) Draw(w *World, view, projection glm.Mat4) {
+	if r.batches == nil {
+		r.batches = make(map[batchKey][]mesh.Instance)
+	}
+	// keep the slices, to reuse them in the next frame
+	for key, instances := range r.batches {
+		r.batches[key] = instances[:0]
+	}
+
+	w.Meshes.Each(func(e Entity, m *Mesh) {
+		t, mat := w.Transforms.Get(e), w.Materials.Get(e)
+		if t == nil || mat == nil {
+			return
+		}
+		key := batchKey{mat.Program, m.VAO}
+		instances, ok := r.batches[key]
+		if !ok {
+			r.order = append(r.order, key)
+		}
+		r.batches[key] = append(instances, mesh.Instance{Model: t.Mat4(), Data: mat.Color})
+	})
+
+	for _, key := range r.order {
+		instances := r.batches[key]
+		if len(instances) == 0 {
+			continue
+		}
+		p := key.program
+		p.Use()
+		p.SetMat4("view", view)
+		p.SetMat4("projection", projection)
+		key.vao.SetInstances(instances)
+		key.vao.DrawInstanced()
+	}
+}
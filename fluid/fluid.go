@@ -0,0 +1,554 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// Stable fluids, after Jos Stam, with each step a fragment shader drawn
+// over a grid stored in a texture. Every field has two framebuffers: one
+// is read while the other is written, and then they swap roles.
+//
+// Velocities are in cells of the simulation grid per second. The dye is
+// carried along by the velocity, on a grid of its own, finer than that of
+// the simulation.
+var (
+	// Moves the quantity in source along the velocity: the value at a
+	// point is the value that was dt seconds upstream.
+	advect_glsl = `
+#version 330 core
+
+uniform sampler2D velocity;
+uniform sampler2D source;
+uniform vec2 cell; // size of a cell of the simulation grid in texture coordinates
+uniform float dt;
+uniform float dissipation;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec2 from = uv - dt * texture(velocity, uv).xy * cell;
+    fragColor = texture(source, from) / (1.0 + dissipation * dt);
+}
+`
+
+	// One Jacobi iteration for the diffusion of the velocity, or for the
+	// pressure: x = (sum of the neighbours + alpha * b) / beta.
+	jacobi_glsl = `
+#version 330 core
+
+uniform sampler2D x;
+uniform sampler2D b;
+uniform vec2 cell;
+uniform float alpha;
+uniform float beta;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 l = texture(x, uv - vec2(cell.x, 0.0));
+    vec4 r = texture(x, uv + vec2(cell.x, 0.0));
+    vec4 d = texture(x, uv - vec2(0.0, cell.y));
+    vec4 u = texture(x, uv + vec2(0.0, cell.y));
+    fragColor = (l + r + d + u + alpha * texture(b, uv)) / beta;
+}
+`
+
+	// The divergence of the velocity. At the walls the velocity is
+	// reflected, so no fluid flows out.
+	divergence_glsl = `
+#version 330 core
+
+uniform sampler2D velocity;
+uniform vec2 cell;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec2 c = texture(velocity, uv).xy;
+    float l = texture(velocity, uv - vec2(cell.x, 0.0)).x;
+    float r = texture(velocity, uv + vec2(cell.x, 0.0)).x;
+    float d = texture(velocity, uv - vec2(0.0, cell.y)).y;
+    float u = texture(velocity, uv + vec2(0.0, cell.y)).y;
+    if (uv.x - cell.x < 0.0) { l = -c.x; }
+    if (uv.x + cell.x > 1.0) { r = -c.x; }
+    if (uv.y - cell.y < 0.0) { d = -c.y; }
+    if (uv.y + cell.y > 1.0) { u = -c.y; }
+    fragColor = vec4(0.5 * (r - l + u - d), 0.0, 0.0, 1.0);
+}
+`
+
+	// Subtracts the gradient of the pressure, which leaves a velocity
+	// without divergence.
+	gradient_glsl = `
+#version 330 core
+
+uniform sampler2D velocity;
+uniform sampler2D pressure;
+uniform vec2 cell;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    float l = texture(pressure, uv - vec2(cell.x, 0.0)).x;
+    float r = texture(pressure, uv + vec2(cell.x, 0.0)).x;
+    float d = texture(pressure, uv - vec2(0.0, cell.y)).x;
+    float u = texture(pressure, uv + vec2(0.0, cell.y)).x;
+    vec2 v = texture(velocity, uv).xy - 0.5 * vec2(r - l, u - d);
+    fragColor = vec4(v, 0.0, 1.0);
+}
+`
+
+	// Adds a Gaussian blob of value around point.
+	splat_glsl = `
+#version 330 core
+
+uniform sampler2D target;
+uniform vec2 point;
+uniform vec3 value;
+uniform float radius;
+uniform float aspect;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec2 d = uv - point;
+    d.x *= aspect;
+    fragColor = vec4(texture(target, uv).xyz + value * exp(-dot(d, d) / radius), 1.0);
+}
+`
+
+	// Shows the dye, the velocity, or the pressure.
+	show_glsl = `
+#version 330 core
+
+uniform sampler2D dye;
+uniform sampler2D velocity;
+uniform sampler2D pressure;
+uniform int mode;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 c;
+    if (mode == 0) {
+        // no hard clipping where dye piles up
+        c = 1.0 - exp(-texture(dye, uv).rgb);
+    } else if (mode == 1) {
+        vec2 v = texture(velocity, uv).xy;
+        c = vec3(0.5 + 0.5 * v / (1.0 + length(v)), 0.5);
+    } else {
+        float p = texture(pressure, uv).x;
+        c = p > 0.0 ? vec3(p, 0.2 * p, 0.0) : vec3(0.0, -0.2 * p, -p);
+        c = 1.0 - exp(-0.1 * c);
+    }
+    fragColor = vec4(c, 1.0);
+}
+`
+)
+
+var (
+	simSize    = flag.Int("sim", 256, "number of rows of the simulation grid")
+	dyeSize    = flag.Int("dye", 768, "number of rows of the dye grid")
+	iterations = flag.Int("iterations", 30, "Jacobi iterations for the pressure and for diffusion")
+	viscosity  = flag.Float64("viscosity", .5, "viscosity, in cells squared per second, 0 for none")
+)
+
+// Extra actions for this demo.
+const (
+	actionShow   = "show"
+	actionSplats = "splats"
+	actionClear  = "clear"
+)
+
+const (
+	velocityDissipation = .2 // fraction lost per second, roughly
+	dyeDissipation      = .3
+	splatRadius         = .0004 // in texture coordinates squared
+)
+
+var showNames = []string{"dye", "velocity", "pressure"}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	advect     *glutil.Program
+	jacobi     *glutil.Program
+	divergence *glutil.Program
+	gradient   *glutil.Program
+	splat      *glutil.Program
+	show       *glutil.Program
+
+	// Each field twice, the current values first.
+	velocity [2]*glutil.Framebuffer
+	pressure [2]*glutil.Framebuffer
+	dye      [2]*glutil.Framebuffer
+	div      *glutil.Framebuffer
+	spare    *glutil.Framebuffer // for diffusion, the size of the velocity
+
+	aspect float32 // width / height of the grids
+	cell   glm.Vec2
+}
+
+func makeResources(aspect float32) *gResources {
+	r := &gResources{
+		aspect: aspect,
+	}
+
+	for _, p := range []struct {
+		program **glutil.Program
+		source  string
+	}{
+		{&r.advect, advect_glsl},
+		{&r.jacobi, jacobi_glsl},
+		{&r.divergence, divergence_glsl},
+		{&r.gradient, gradient_glsl},
+		{&r.splat, splat_glsl},
+		{&r.show, show_glsl},
+	} {
+		var err error
+		*p.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, p.source)
+		x(err)
+	}
+
+	rows := int32(*simSize)
+	cols := int32(float32(rows) * aspect)
+	r.cell = glm.Vec2{1 / float32(cols), 1 / float32(rows)}
+	dyeRows := int32(*dyeSize)
+	dyeCols := int32(float32(dyeRows) * aspect)
+
+	var err error
+	for i := 0; i < 2; i++ {
+		r.velocity[i], err = glutil.NewMultiFramebuffer(cols, rows, glutil.FormatRG16F)
+		x(err)
+		r.pressure[i], err = glutil.NewMultiFramebuffer(cols, rows, glutil.FormatR16F)
+		x(err)
+		r.dye[i], err = glutil.NewMultiFramebuffer(dyeCols, dyeRows, glutil.FormatRGBA16F)
+		x(err)
+
+		// the velocity and the dye are sampled between cells when advected
+		r.velocity[i].SetFilter(gl.LINEAR)
+		r.dye[i].SetFilter(gl.LINEAR)
+	}
+	r.div, err = glutil.NewMultiFramebuffer(cols, rows, glutil.FormatR16F)
+	x(err)
+	r.spare, err = glutil.NewMultiFramebuffer(cols, rows, glutil.FormatRG16F)
+	x(err)
+	r.spare.SetFilter(gl.LINEAR)
+
+	clearAll(r)
+
+	return r
+}
+
+// clearAll sets all fields to zero.
+func clearAll(r *gResources) {
+	gl.ClearColor(0, 0, 0, 0)
+	for _, f := range []*glutil.Framebuffer{r.velocity[0], r.velocity[1], r.pressure[0], r.pressure[1], r.dye[0], r.dye[1], r.div, r.spare} {
+		f.Bind()
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		f.Unbind()
+	}
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	show  = 0
+
+	// the last position of the cursor while dragging, in texture
+	// coordinates
+	dragging bool
+	last     glm.Vec2
+)
+
+// pass draws a fullscreen pass with program p into field[1], with the
+// textures bound to units 0 and up, and swaps the framebuffers of field.
+func pass(p *glutil.Program, field *[2]*glutil.Framebuffer, textures ...uint32) {
+	field[1].Bind()
+	for i, t := range textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, t)
+	}
+	glutil.DrawFullscreen()
+	field[1].Unbind()
+	field[0], field[1] = field[1], field[0]
+}
+
+// splat adds value to field, around point.
+func splat(r *gResources, field *[2]*glutil.Framebuffer, point glm.Vec2, value glm.Vec3) {
+	p := r.splat
+	p.Use()
+	p.SetInt("target", 0)
+	p.SetVec2("point", point)
+	p.SetVec3("value", value)
+	p.SetFloat("radius", splatRadius)
+	p.SetFloat("aspect", r.aspect)
+	pass(p, field, field[0].Texture)
+}
+
+// addSplat pushes the fluid at point with velocity v, in cells per
+// second, and adds dye of a color that changes over time.
+func addSplat(r *gResources, point, v glm.Vec2) {
+	splat(r, &r.velocity, point, glm.Vec3{v[0], v[1], 0})
+	c := color.HSV(float32(math.Mod(clock.Seconds()/10, 1)), .9, 1).Mul(.5)
+	splat(r, &r.dye, point, c)
+}
+
+// randomSplats adds a few splats at random points, in random directions.
+func randomSplats(r *gResources) {
+	for i := 0; i < 6; i++ {
+		a := 2 * math.Pi * rand.Float64()
+		s := float32(200 + 300*rand.Float64())
+		point := glm.Vec2{.1 + .8*rand.Float32(), .1 + .8*rand.Float32()}
+		splat(r, &r.velocity, point, glm.Vec3{s * float32(math.Cos(a)), s * float32(math.Sin(a)), 0})
+		splat(r, &r.dye, point, color.HSV(rand.Float32(), .9, 1))
+	}
+}
+
+// step advances the simulation by dt seconds: advection, diffusion,
+// forces from the mouse, and projection, which makes the velocity
+// divergence free.
+func step(w *glfw.Window, r *gResources, dt float32) {
+	glutil.State{}.Apply()
+
+	// advection of the velocity by itself, and of the dye
+	p := r.advect
+	p.Use()
+	p.SetInt("velocity", 0)
+	p.SetInt("source", 1)
+	p.SetVec2("cell", r.cell)
+	p.SetFloat("dt", dt)
+	p.SetFloat("dissipation", velocityDissipation)
+	pass(p, &r.velocity, r.velocity[0].Texture, r.velocity[0].Texture)
+	p.SetFloat("dissipation", dyeDissipation)
+	pass(p, &r.dye, r.velocity[0].Texture, r.dye[0].Texture)
+
+	// diffusion: solve (1 - viscosity * dt * laplacian) v' = v
+	if *viscosity > 0 {
+		alpha := 1 / (float32(*viscosity) * dt)
+		p = r.jacobi
+		p.Use()
+		p.SetInt("x", 0)
+		p.SetInt("b", 1)
+		p.SetVec2("cell", r.cell)
+		p.SetFloat("alpha", alpha)
+		p.SetFloat("beta", 4+alpha)
+		// the velocity before diffusion moves to the spare buffer, to
+		// stay the right hand side b, and is the first guess for x
+		r.spare, r.velocity[0] = r.velocity[0], r.spare
+		guess := r.spare.Texture
+		for i := 0; i < *iterations; i++ {
+			pass(p, &r.velocity, guess, r.spare.Texture)
+			guess = r.velocity[0].Texture
+		}
+	}
+
+	// forces
+	if w.GetMouseButton(glfw.MouseButtonLeft) == glfw.Press {
+		cx, cy := w.GetCursorPos()
+		sw, sh := w.GetSize()
+		pos := glm.Vec2{float32(cx) / float32(sw), 1 - float32(cy)/float32(sh)}
+		if dragging {
+			// the fluid follows the mouse
+			d := pos.Sub(last)
+			v := glm.Vec2{d[0] / r.cell[0] / dt, d[1] / r.cell[1] / dt}
+			if v[0] != 0 || v[1] != 0 {
+				addSplat(r, pos, v)
+			}
+		}
+		dragging = true
+		last = pos
+	} else {
+		dragging = false
+	}
+
+	// projection: the pressure that makes the divergence zero, by solving
+	// laplacian p = div, starting from the pressure of the last step
+	p = r.divergence
+	p.Use()
+	p.SetInt("velocity", 0)
+	p.SetVec2("cell", r.cell)
+	r.div.Bind()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, r.velocity[0].Texture)
+	glutil.DrawFullscreen()
+	r.div.Unbind()
+
+	p = r.jacobi
+	p.Use()
+	p.SetInt("x", 0)
+	p.SetInt("b", 1)
+	p.SetVec2("cell", r.cell)
+	p.SetFloat("alpha", -1)
+	p.SetFloat("beta", 4)
+	for i := 0; i < *iterations; i++ {
+		pass(p, &r.pressure, r.pressure[0].Texture, r.div.Texture)
+	}
+
+	p = r.gradient
+	p.Use()
+	p.SetInt("velocity", 0)
+	p.SetInt("pressure", 1)
+	p.SetVec2("cell", r.cell)
+	pass(p, &r.velocity, r.velocity[0].Texture, r.pressure[0].Texture)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func update(w *glfw.Window, r *gResources) {
+	// no big jumps after a slow frame
+	dt := clock.Delta()
+	if dt > 1./30 {
+		dt = 1. / 30
+	}
+	if dt == 0 {
+		dragging = false
+		return
+	}
+	step(w, r, float32(dt))
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	p := r.show
+	p.Use()
+	p.SetInt("dye", 0)
+	p.SetInt("velocity", 1)
+	p.SetInt("pressure", 2)
+	p.SetInt("mode", int32(show))
+	for i, t := range []uint32{r.dye[0].Texture, r.velocity[0].Texture, r.pressure[0].Texture} {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, t)
+	}
+	glutil.DrawFullscreen()
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func main() {
+	app.Keys[actionShow] = []string{"d"}
+	app.Keys[actionSplats] = []string{"r"}
+	app.Keys[actionClear] = []string{"c"}
+	app.Flags(1024, 640, "Fluid")
+
+	if *iterations < 1 {
+		app.Fatal("iterations must be at least 1")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	// the grids have the shape of the window as it is at the start
+	width, height := w.GetFramebufferSize()
+	r := makeResources(float32(width) / float32(height))
+	randomSplats(r)
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+
+	app.Infof("Simulation grid %d x %d, dye grid %d x %d", r.velocity[0].Width, r.velocity[0].Height, r.dye[0].Width, r.dye[0].Height)
+	app.Info("Drag with the mouse to stir the fluid")
+	app.Info("Press 'd' to show the dye, velocity or pressure, 'r' for random splats, 'c' to clear")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(w, r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionShow:
+		show = (show + 1) % len(showNames)
+		app.Info("Showing the", showNames[show])
+	case actionSplats:
+		randomSplats(r)
+	case actionClear:
+		clearAll(r)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
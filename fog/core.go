@@ -0,0 +1,152 @@
+package main
+
+// The core profile version: there is no fixed-function fog, so the
+// fragment shader mixes in the fog color itself, with the same formulas
+// that OpenGL 2.1 uses.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 faceNormal;
+out float eyeDistance;
+
+void main()
+{
+    vec4 p = view * model * vec4(position, 1.0);
+    gl_Position = projection * p;
+    faceNormal = normal;
+    // like glFog: the distance along the view direction
+    eyeDistance = -p.z;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+const int fogOff = -1;
+const int fogLinear = 0;
+const int fogExp = 1;
+const int fogExp2 = 2;
+
+uniform vec3 color;
+uniform int fogMode;
+uniform float fogStart;
+uniform float fogEnd;
+uniform float fogDensity;
+uniform vec3 fogColor;
+
+in vec3 faceNormal;
+in float eyeDistance;
+
+out vec4 fragColor;
+
+// the same as faceShade in fog.go
+float shade(vec3 n)
+{
+    if (n.y > 0.5) {
+        return 1.0;
+    }
+    if (n.y < -0.5) {
+        return 0.4;
+    }
+    if (abs(n.x) > 0.5) {
+        return 0.8;
+    }
+    return 0.6;
+}
+
+void main()
+{
+    // f is the fraction of the original color that remains
+    float f = 1.0;
+    if (fogMode == fogLinear) {
+        f = (fogEnd - eyeDistance) / (fogEnd - fogStart);
+    } else if (fogMode == fogExp) {
+        f = exp(-fogDensity * eyeDistance);
+    } else if (fogMode == fogExp2) {
+        float d = fogDensity * eyeDistance;
+        f = exp(-d * d);
+    }
+    f = clamp(f, 0.0, 1.0);
+    fragColor = vec4(mix(fogColor, shade(faceNormal) * color, f), 1.0);
+}
+` + "\x00"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	cube    *mesh.VAO
+}
+
+func initCore() (*gResources, error) {
+	if err := gl.Init(); err != nil {
+		return nil, err
+	}
+	glinfo.Report()
+
+	r := &gResources{
+		cube: mesh.Cube().Upload(),
+	}
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+var stateBlocks = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func renderCore(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(fog.color[0], fog.color[1], fog.color[2], 0)
+	stateBlocks.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	view, projection := camera(width, height)
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+
+	mode := int32(fog.mode)
+	if !fog.enabled {
+		mode = -1
+	}
+	p.SetInt("fogMode", mode)
+	p.SetFloat("fogStart", fog.start)
+	p.SetFloat("fogEnd", fog.end)
+	p.SetFloat("fogDensity", fog.density)
+	p.SetVec3("fogColor", fog.color)
+
+	for _, b := range blocks {
+		p.SetMat4("model", glm.Translate(b.position[0], b.position[1], b.position[2]).Mul(glm.Scale(b.size[0], b.size[1], b.size[2])))
+		p.SetVec3("color", b.color)
+		r.cube.Draw()
+	}
+}
@@ -0,0 +1,235 @@
+// Fog, twice: on the left with the fixed-function fog of OpenGL 2.1, on the
+// right computed in the fragment shader of a core profile context. Both
+// windows show the same scene with the same fog settings, so they should
+// look the same.
+//
+// See legacy.go and core.go for the two versions.
+package main
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+// Extra actions for this demo.
+const (
+	actionMode    = "mode"
+	actionFog     = "fog"
+	actionDenser  = "denser"
+	actionThinner = "thinner"
+)
+
+// Fog modes, in the order they are cycled through.
+const (
+	fogLinear = iota
+	fogExp
+	fogExp2
+)
+
+var fogNames = []string{"linear", "exp", "exp2"}
+
+// The fog settings shared by both windows.
+var fog = struct {
+	enabled    bool
+	mode       int
+	start, end float32 // for linear fog, distances from the eye
+	density    float32 // for exp and exp2 fog
+	color      glm.Vec3
+}{
+	enabled: true,
+	mode:    fogLinear,
+	start:   5,
+	end:     40,
+	density: .05,
+	color:   glm.Vec3{.6, .65, .7},
+}
+
+//
+// The scene: rows of blocks on both sides of a road, seen from a camera
+// that moves slowly along it
+//
+
+type tBlock struct {
+	position glm.Vec3
+	size     glm.Vec3 // half the size on each axis
+	color    glm.Vec3
+}
+
+var blocks []tBlock
+
+func makeBlocks() {
+	// the ground
+	blocks = append(blocks, tBlock{glm.Vec3{0, -.05, -50}, glm.Vec3{20, .05, 60}, glm.Vec3{.35, .5, .3}})
+	for i := 0; i < 30; i++ {
+		z := -4 * float32(i)
+		for _, side := range []float32{-1, 1} {
+			h := 1 + .8*float32(math.Sin(float64(i)*1.7+float64(side)))
+			blocks = append(blocks, tBlock{
+				position: glm.Vec3{side * 3, h, z},
+				size:     glm.Vec3{.8, h, .8},
+				color:    glm.Vec3{.8, .4 + .1*side, .3 + .02*float32(i%5)},
+			})
+		}
+	}
+}
+
+// The shading of each face of a block, so the blocks look solid without
+// lighting: top, bottom, and sides.
+func faceShade(normal glm.Vec3) float32 {
+	switch {
+	case normal[1] > 0:
+		return 1
+	case normal[1] < 0:
+		return .4
+	case normal[0] != 0:
+		return .8
+	}
+	return .6
+}
+
+func camera(width, height int) (view, projection glm.Mat4) {
+	t := clock.Seconds()
+	eye := glm.Vec3{float32(math.Sin(t / 3)), 1.7, 6 - float32(math.Mod(t*2, 40))}
+	view = glm.LookAt(eye, eye.Add(glm.Vec3{0, -.1, -1}), glm.Vec3{0, 1, 0})
+	projection = glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 200)
+	return
+}
+
+//
+// Update and render
+//
+
+var clock = app.NewClock()
+
+func main() {
+	app.Keys[actionMode] = []string{"m"}
+	app.Keys[actionFog] = []string{"f"}
+	app.Keys[actionDenser] = []string{"]"}
+	app.Keys[actionThinner] = []string{"["}
+	app.Flags(480, 360, "Fog")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	makeBlocks()
+
+	// on the left: OpenGL 2.1, which has fixed-function fog
+	glfw.WindowHint(glfw.ContextVersionMajor, 2)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	if app.Snapshot() {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	}
+	legacy, err := glfw.CreateWindow(app.Width, app.Height, app.Title+": OpenGL 2.1, glFog", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	legacy.MakeContextCurrent()
+	glfw.SwapInterval(1)
+	x(initLegacy())
+
+	// on the right: core profile, fog in the fragment shader
+	glfw.DefaultWindowHints()
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	if app.Snapshot() {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	}
+	core, err := glfw.CreateWindow(app.Width, app.Height, app.Title+": core profile, shader", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	core.MakeContextCurrent()
+	glfw.SwapInterval(0) // one wait for vertical sync per frame is enough
+	r, err := initCore()
+	x(err)
+
+	left, top := legacy.GetPos()
+	core.SetPos(left+app.Width+10, top)
+
+	app.OnAction(legacy, onAction)
+	app.OnAction(core, onAction)
+
+	printFog()
+	app.Info("Press 'f' to toggle fog, 'm' to change the fog mode, '[' and ']' to change the density")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !legacy.ShouldClose() && !core.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+
+		legacy.MakeContextCurrent()
+		renderLegacy(legacy)
+		app.SwapBuffers(legacy)
+
+		core.MakeContextCurrent()
+		renderCore(core, r)
+		app.SwapBuffers(core)
+
+		glfw.PollEvents()
+	}
+}
+
+func printFog() {
+	switch {
+	case !fog.enabled:
+		app.Info("Fog: off")
+	case fog.mode == fogLinear:
+		app.Infof("Fog: linear, from %g to %g", fog.start, fog.end)
+	default:
+		app.Infof("Fog: %s, density %.3f", fogNames[fog.mode], fog.density)
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionFog:
+		fog.enabled = !fog.enabled
+		printFog()
+	case actionMode:
+		fog.mode = (fog.mode + 1) % len(fogNames)
+		printFog()
+	case actionDenser:
+		fog.density *= 1.25
+		if fog.end/1.25 > fog.start {
+			fog.end /= 1.25
+		}
+		printFog()
+	case actionThinner:
+		fog.density /= 1.25
+		fog.end *= 1.25
+		printFog()
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
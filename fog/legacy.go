@@ -0,0 +1,85 @@
+package main
+
+// The OpenGL 2.1 version: the fog is part of the fixed-function pipeline,
+// and is set up with gl.Fog.
+
+import (
+	"github.com/go-gl/gl/v2.1/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/glm"
+)
+
+// The corners of each face of a cube from -1 to 1, counter-clockwise seen
+// from outside, with the normal of the face.
+var cubeFaces = [6]struct {
+	normal  glm.Vec3
+	corners [4]glm.Vec3
+}{
+	{glm.Vec3{1, 0, 0}, [4]glm.Vec3{{1, -1, 1}, {1, -1, -1}, {1, 1, -1}, {1, 1, 1}}},
+	{glm.Vec3{-1, 0, 0}, [4]glm.Vec3{{-1, -1, -1}, {-1, -1, 1}, {-1, 1, 1}, {-1, 1, -1}}},
+	{glm.Vec3{0, 1, 0}, [4]glm.Vec3{{-1, 1, 1}, {1, 1, 1}, {1, 1, -1}, {-1, 1, -1}}},
+	{glm.Vec3{0, -1, 0}, [4]glm.Vec3{{-1, -1, -1}, {1, -1, -1}, {1, -1, 1}, {-1, -1, 1}}},
+	{glm.Vec3{0, 0, 1}, [4]glm.Vec3{{-1, -1, 1}, {1, -1, 1}, {1, 1, 1}, {-1, 1, 1}}},
+	{glm.Vec3{0, 0, -1}, [4]glm.Vec3{{1, -1, -1}, {-1, -1, -1}, {-1, 1, -1}, {1, 1, -1}}},
+}
+
+func initLegacy() error {
+	if err := gl.Init(); err != nil {
+		return err
+	}
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.CULL_FACE)
+
+	// fog distance per fragment rather than per vertex, like the shader
+	gl.Hint(gl.FOG_HINT, gl.NICEST)
+	return nil
+}
+
+func renderLegacy(w *glfw.Window) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(fog.color[0], fog.color[1], fog.color[2], 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// The fog is computed from the distance to the eye, in eye coordinates.
+	// That is what the modelview matrix gives, so the camera must be in
+	// the modelview matrix, not in the projection matrix.
+	view, projection := camera(width, height)
+	gl.MatrixMode(gl.PROJECTION)
+	gl.LoadMatrixf(&projection[0])
+	gl.MatrixMode(gl.MODELVIEW)
+	gl.LoadMatrixf(&view[0])
+
+	if fog.enabled {
+		gl.Enable(gl.FOG)
+		color := fog.color.Vec4(1)
+		gl.Fogfv(gl.FOG_COLOR, &color[0])
+		switch fog.mode {
+		case fogLinear:
+			gl.Fogi(gl.FOG_MODE, gl.LINEAR)
+			gl.Fogf(gl.FOG_START, fog.start)
+			gl.Fogf(gl.FOG_END, fog.end)
+		case fogExp:
+			gl.Fogi(gl.FOG_MODE, gl.EXP)
+			gl.Fogf(gl.FOG_DENSITY, fog.density)
+		case fogExp2:
+			gl.Fogi(gl.FOG_MODE, gl.EXP2)
+			gl.Fogf(gl.FOG_DENSITY, fog.density)
+		}
+	} else {
+		gl.Disable(gl.FOG)
+	}
+
+	gl.Begin(gl.QUADS)
+	for _, b := range blocks {
+		for _, f := range cubeFaces {
+			c := b.color.Mul(faceShade(f.normal))
+			gl.Color3f(c[0], c[1], c[2])
+			for _, p := range f.corners {
+				p = b.position.Add(p.MulVec(b.size))
+				gl.Vertex3f(p[0], p[1], p[2])
+			}
+		}
+	}
+	gl.End()
+}
@@ -0,0 +1,63 @@
+// Package font contains the small built-in bitmap font of the demos, and
+// draws text with it in images, on the CPU. Package text draws it with
+// OpenGL.
+//
+// Only printable ASCII is supported, other characters are drawn as '?'.
+package font
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// Size of a character cell in pixels of the font: 5 by 7 pixels for the
+// glyph, and a column and a row of space.
+const (
+	CellWidth  = 6
+	CellHeight = 8
+)
+
+// Glyph returns the glyph of r, or of '?' if there is none.
+func Glyph(r rune) [5]byte {
+	i := int(r) - ' '
+	if i < 0 || i >= len(Glyphs) {
+		i = '?' - ' '
+	}
+	return Glyphs[i]
+}
+
+// Draw draws s in the image, with the top left corner at x, y, in color c,
+// with each pixel of the font a square of scale by scale pixels. Lines are
+// separated by '\n'.
+func Draw(img draw.Image, s string, x, y, scale int, c color.Color) {
+	src := image.NewUniform(c)
+	for _, line := range strings.Split(s, "\n") {
+		px := x
+		for _, r := range line {
+			for col, bits := range Glyph(r) {
+				for row := 0; row < 7; row++ {
+					if bits&(1<<uint(row)) != 0 {
+						rect := image.Rect(0, 0, scale, scale).Add(image.Pt(px+col*scale, y+row*scale))
+						draw.Draw(img, rect, src, image.Point{}, draw.Over)
+					}
+				}
+			}
+			px += CellWidth * scale
+		}
+		y += CellHeight * scale
+	}
+}
+
+// Size returns the width and height of s when drawn at the scale.
+func Size(s string, scale int) (width, height int) {
+	lines := strings.Split(s, "\n")
+	n := 0
+	for _, line := range lines {
+		if l := len([]rune(line)); l > n {
+			n = l
+		}
+	}
+	return n * CellWidth * scale, len(lines) * CellHeight * scale
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/palette"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	// Without emulation, a float has too few bits for deep zooms: at a
+	// scale below about 1e-6 per pixel, neighbouring pixels get the same
+	// coordinates. With emulation, each number is a vec2 with the sum of
+	// two floats, a large part and a small correction, for about twice the
+	// precision. See Dekker (1971), and the DSFUN90 library.
+	fragment_glsl = `
+#version 330 core
+` + palette.GLSL + `
+uniform Palette colors;
+uniform vec2 centerX;    // as a sum of two floats
+uniform vec2 centerY;
+uniform vec2 scale;      // units per pixel
+uniform vec2 resolution; // in pixels
+uniform bool julia;
+uniform vec2 juliaC;
+uniform int maxIterations;
+uniform bool emulate;
+
+out vec4 fragColor;
+
+const float bailout = 256.0;
+
+vec2 dsAdd(vec2 a, vec2 b)
+{
+    float t1 = a.x + b.x;
+    float e = t1 - a.x;
+    float t2 = ((b.x - e) + (a.x - (t1 - e))) + a.y + b.y;
+    float hi = t1 + t2;
+    return vec2(hi, t2 - (hi - t1));
+}
+
+vec2 dsMul(vec2 a, vec2 b)
+{
+    const float split = 8193.0;
+    float ca = split * a.x;
+    float cb = split * b.x;
+    float a1 = ca - (ca - a.x);
+    float b1 = cb - (cb - b.x);
+    float a2 = a.x - a1;
+    float b2 = b.x - b1;
+    float c11 = a.x * b.x;
+    float c21 = a2 * b2 + (a2 * b1 + (a1 * b2 + (a1 * b1 - c11)));
+    float c2 = a.x * b.y + a.y * b.x;
+    float t1 = c11 + c21;
+    float e = t1 - c11;
+    float t2 = a.y * b.y + ((c2 - e) + (c11 - (t1 - e))) + c21;
+    float hi = t1 + t2;
+    return vec2(hi, t2 - (hi - t1));
+}
+
+// iterate returns the number of iterations before z escapes, and the
+// squared length of z at that point.
+vec2 iterate()
+{
+    vec2 offset = gl_FragCoord.xy - 0.5 * resolution;
+    int i;
+    float r2;
+    if (emulate) {
+        vec2 px = dsAdd(centerX, dsMul(vec2(offset.x, 0.0), scale));
+        vec2 py = dsAdd(centerY, dsMul(vec2(offset.y, 0.0), scale));
+        vec2 zx = julia ? px : vec2(0.0);
+        vec2 zy = julia ? py : vec2(0.0);
+        vec2 cx = julia ? vec2(juliaC.x, 0.0) : px;
+        vec2 cy = julia ? vec2(juliaC.y, 0.0) : py;
+        for (i = 0; i < maxIterations; i++) {
+            vec2 x2 = dsMul(zx, zx);
+            vec2 y2 = dsMul(zy, zy);
+            r2 = x2.x + y2.x;
+            if (r2 > bailout) {
+                break;
+            }
+            zy = dsAdd(2.0 * dsMul(zx, zy), cy);
+            zx = dsAdd(dsAdd(x2, -y2), cx);
+        }
+    } else {
+        vec2 p = vec2(centerX.x, centerY.x) + offset * scale.x;
+        vec2 z = julia ? p : vec2(0.0);
+        vec2 c = julia ? juliaC : p;
+        for (i = 0; i < maxIterations; i++) {
+            r2 = dot(z, z);
+            if (r2 > bailout) {
+                break;
+            }
+            z = vec2(z.x * z.x - z.y * z.y, 2.0 * z.x * z.y) + c;
+        }
+    }
+    return vec2(float(i), r2);
+}
+
+void main()
+{
+    vec2 result = iterate();
+    if (int(result.x) >= maxIterations) {
+        fragColor = vec4(0.0, 0.0, 0.0, 1.0);
+        return;
+    }
+    // a continuous count, without bands between whole iterations
+    float mu = result.x + 1.0 - log2(log2(result.y) / 2.0);
+    fragColor = vec4(palette(colors, 0.02 * mu), 1.0);
+}
+`
+)
+
+// Extra actions for this demo.
+const (
+	actionJulia   = "julia"
+	actionPalette = "palette"
+	actionMore    = "more"
+	actionLess    = "less"
+	actionReset   = "reset"
+)
+
+// Below this scale, in units per pixel, float precision is not enough.
+const emulateBelow = 1e-6
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+// The view, in double precision.
+var (
+	centerX, centerY float64
+	scale            float64 // units per screen pixel
+
+	julia          bool
+	juliaX, juliaY float64
+
+	colors        int   // index into palette.All
+	maxIterations int32 = 256
+
+	drag input.Drag
+)
+
+// split returns a float64 as the sum of two float32s.
+func split(v float64) glm.Vec2 {
+	hi := float32(v)
+	return glm.Vec2{hi, float32(v - float64(hi))}
+}
+
+// home shows the whole set in the window.
+func home(w *glfw.Window) {
+	centerX, centerY = 0, 0
+	if !julia {
+		centerX = -.5
+	}
+	_, height := w.GetSize()
+	scale = 3 / float64(height)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	// scale is per screen pixel, the framebuffer may have more pixels
+	ww, _ := w.GetSize()
+	s := scale * float64(ww) / float64(width)
+
+	p := r.program
+	p.Use()
+	palette.All[colors].Set(p, "colors")
+	p.SetVec2("centerX", split(centerX))
+	p.SetVec2("centerY", split(centerY))
+	p.SetVec2("scale", split(s))
+	p.SetVec2("resolution", glm.Vec2{float32(width), float32(height)})
+	p.SetBool("julia", julia)
+	p.SetVec2("juliaC", glm.Vec2{float32(juliaX), float32(juliaY)})
+	p.SetInt("maxIterations", maxIterations)
+	p.SetBool("emulate", scale < emulateBelow)
+	glutil.DrawFullscreen()
+}
+
+// complexAt returns the point in the complex plane under screen position
+// x, y.
+func complexAt(w *glfw.Window, x, y float64) (re, im float64) {
+	width, height := w.GetSize()
+	return centerX + (x-float64(width)/2)*scale, centerY - (y-float64(height)/2)*scale
+}
+
+// scroll zooms in or out around the cursor.
+func scroll(w *glfw.Window, xoff, yoff float64) {
+	x, y := w.GetCursorPos()
+	re, im := complexAt(w, x, y)
+	wasEmulated := scale < emulateBelow
+	f := math.Pow(.8, yoff)
+	scale *= f
+	// keep the point under the cursor in place
+	centerX = re + (centerX-re)*f
+	centerY = im + (centerY-im)*f
+	if emulated := scale < emulateBelow; emulated != wasEmulated {
+		if emulated {
+			app.Info("Emulating double precision")
+		} else {
+			app.Info("Single precision")
+		}
+	}
+	if scale < 1e-13 {
+		app.Info("At the limit of emulated precision")
+	}
+}
+
+func main() {
+	app.Keys[actionJulia] = []string{"j"}
+	app.Keys[actionPalette] = []string{"p"}
+	app.Keys[actionMore] = []string{"]"}
+	app.Keys[actionLess] = []string{"["}
+	app.Keys[actionReset] = []string{"r"}
+	app.Flags(800, 600, "Fractal")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	drag.OnMove = func(x, y, dx, dy float64) {
+		centerX -= dx * scale
+		centerY += dy * scale
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+	w.SetScrollCallback(scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	home(w)
+
+	app.Info("Drag with the mouse to move, scroll to zoom")
+	app.Info("Press 'j' to switch between Mandelbrot and the Julia set for the point under the cursor")
+	app.Info("Press 'p' to change the palette, '[' and ']' to change the number of iterations, 'r' to reset")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionJulia:
+		julia = !julia
+		if julia {
+			cx, cy := w.GetCursorPos()
+			juliaX, juliaY = complexAt(w, cx, cy)
+			app.Infof("Julia set for %g%+gi", juliaX, juliaY)
+		} else {
+			app.Info("Mandelbrot set")
+		}
+		home(w)
+	case actionPalette:
+		colors = (colors + 1) % len(palette.All)
+		app.Info("Palette:", palette.All[colors].Name)
+	case actionMore:
+		if maxIterations < 1<<16 {
+			maxIterations *= 2
+		}
+		app.Info("Iterations:", maxIterations)
+	case actionLess:
+		if maxIterations > 32 {
+			maxIterations /= 2
+		}
+		app.Info("Iterations:", maxIterations)
+	case actionReset:
+		home(w)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
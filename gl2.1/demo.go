@@ -2,14 +2,31 @@ package main
 
 import (
 	"github.com/go-gl/gl/v2.1/gl"
-	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/input"
 
+	"flag"
 	"fmt"
+	"image"
 	"math"
 	"runtime"
 	"time"
 )
 
+var textureFile = flag.String("texture", "../hello/hello1.png", "image for the textured quad")
+
+var (
+	clock = app.NewClock()
+	zoom  = input.NewZoom(.1, 10)
+)
+
+// Toggles between immediate mode and client-side vertex arrays.
+const actionArrays = "vertexarrays"
+
+var useArrays bool
+
 func init() {
 	// This is needed to arrange that main() runs on main thread.
 	// See documentation for functions that are only allowed to be called from the main thread.
@@ -17,13 +34,16 @@ func init() {
 }
 
 func main() {
+	app.Keys[actionArrays] = []string{"v"}
+	app.Flags(640, 480, "Testing")
+
 	err := glfw.Init()
 	if err != nil {
 		panic(err)
 	}
 	defer glfw.Terminate()
 
-	w, err := glfw.CreateWindow(640, 480, "Testing", nil, nil)
+	w, err := app.CreateWindow()
 	if err != nil {
 		panic(err)
 	}
@@ -31,32 +51,105 @@ func main() {
 	w.MakeContextCurrent()
 	glfw.SwapInterval(1)
 
-	w.SetCharCallback(charCallBack)
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
 
 	if err := gl.Init(); err != nil {
 		panic(err)
 	}
+	app.HandleSnapshot(readPixels)
 
 	setupScene(w)
-	fmt.Println("Press 'q' to quit")
+	app.Info("Press 'v' to switch between immediate mode and vertex arrays")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
 	for !w.ShouldClose() {
 		// Do OpenGL stuff.
 		time.Sleep(10 * time.Millisecond)
+		clock.Tick()
 		drawScene(w)
 
-		w.SwapBuffers()
+		app.SwapBuffers(w)
 		glfw.PollEvents()
 	}
 }
 
-func charCallBack(w *glfw.Window, char rune) {
-	if char == 'q' {
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
 		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionArrays:
+		useArrays = !useArrays
+		if useArrays {
+			app.Info("Drawing with vertex arrays")
+		} else {
+			app.Info("Drawing in immediate mode")
+		}
 	}
 }
 
+// Data for drawing with client-side vertex arrays. The driver reads the
+// arrays when gl.DrawArrays is called, not when the pointer is set, so they
+// must stay in memory.
+var (
+	triangleVertices []float32
+	triangleColors   []float32
+	circleVertices   []float32
+	circleColors     []float32
+)
+
+var texture uint32
+
 func setupScene(w *glfw.Window) {
 	gl.ClearColor(.5, .5, .5, 0)
+
+	rgba, err := asset.LoadImageOrPlaceholder(*textureFile)
+	if err != nil {
+		app.Fatal(err)
+	}
+	gl.GenTextures(1, &texture)            // generate texture names
+	gl.BindTexture(gl.TEXTURE_2D, texture) // bind a named texture to a texturing target
+
+	// set texture parameters
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	// specify a two-dimensional texture image
+	gl.TexImage2D(
+		gl.TEXTURE_2D, 0, gl.RGBA8,
+		int32(rgba.Rect.Dx()), int32(rgba.Rect.Dy()), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	gl.TexEnvi(gl.TEXTURE_ENV, gl.TEXTURE_ENV_MODE, gl.MODULATE) // multiply the texture with the current color
+
+	s := float32(.95)
+
+	triangleVertices = []float32{
+		0, s,
+		s * .866, s * -0.5,
+		s * -.866, s * -0.5,
+	}
+	triangleColors = []float32{
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+	}
+
+	for i := float64(0); i < 2*math.Pi; i += .05 {
+		r, g, b := hsb2rgb(float32(i/(2*math.Pi)), 1, 1)
+		circleColors = append(circleColors, r, g, b)
+		circleVertices = append(circleVertices, s*float32(math.Sin(i)), s*float32(math.Cos(i)))
+	}
 }
 
 func drawScene(w *glfw.Window) {
@@ -92,7 +185,33 @@ func drawScene(w *glfw.Window) {
 	gl.Vertex3f(x2, 0, 0)
 	gl.End()
 
-	gl.Rotatef(float32(glfw.GetTime()*50), 0, 0, 1) // multiply the current matrix by a rotation matrix
+	z := float32(zoom.Scale())
+	gl.Scalef(z, z, 1) // multiply the current matrix by a scaling matrix
+
+	// A textured quad, inside the circle. The first row of the image is
+	// the top, but it is stored as the bottom row of the texture.
+	q := float32(.6)
+	gl.Enable(gl.TEXTURE_2D) // enable texturing for subsequent primitives
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.Color3f(1, 1, 1)
+	gl.Begin(gl.QUADS)
+	gl.TexCoord2f(0, 1) // set the current texture coordinates
+	gl.Vertex3f(-q, -q, 0)
+	gl.TexCoord2f(1, 1)
+	gl.Vertex3f(q, -q, 0)
+	gl.TexCoord2f(1, 0)
+	gl.Vertex3f(q, q, 0)
+	gl.TexCoord2f(0, 0)
+	gl.Vertex3f(-q, q, 0)
+	gl.End()
+	gl.Disable(gl.TEXTURE_2D)
+
+	gl.Rotatef(float32(clock.Seconds()*50), 0, 0, 1) // multiply the current matrix by a rotation matrix
+
+	if useArrays {
+		drawArrays()
+		return
+	}
 
 	s := float32(.95)
 
@@ -116,6 +235,25 @@ func drawScene(w *glfw.Window) {
 
 }
 
+// drawArrays draws the triangle and the circle with client-side vertex
+// arrays, using the same data as the immediate mode code in drawScene.
+func drawArrays() {
+	gl.EnableClientState(gl.VERTEX_ARRAY) // enable the vertex array for gl.DrawArrays
+	gl.EnableClientState(gl.COLOR_ARRAY)  // enable the color array for gl.DrawArrays
+
+	gl.VertexPointer(2, gl.FLOAT, 0, gl.Ptr(triangleVertices)) // define an array of vertex data
+	gl.ColorPointer(3, gl.FLOAT, 0, gl.Ptr(triangleColors))    // define an array of colors
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)                          // render primitives from array data
+
+	gl.LineWidth(5)
+	gl.VertexPointer(2, gl.FLOAT, 0, gl.Ptr(circleVertices))
+	gl.ColorPointer(3, gl.FLOAT, 0, gl.Ptr(circleColors))
+	gl.DrawArrays(gl.LINE_LOOP, 0, int32(len(circleVertices)/2))
+
+	gl.DisableClientState(gl.COLOR_ARRAY)
+	gl.DisableClientState(gl.VERTEX_ARRAY)
+}
+
 func hsb2rgb(h, s, b float32) (float32, float32, float32) {
 	c := b * s
 	h *= 6
@@ -137,3 +275,28 @@ func hsb2rgb(h, s, b float32) (float32, float32, float32) {
 	}
 	return c, 0, x
 }
+
+// readPixels reads the back buffer for -snapshot, as glutil.ReadPixels does
+// in the other demos.
+func readPixels(width, height int) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == 0 || height == 0 {
+		return img, nil
+	}
+	gl.ReadBuffer(gl.BACK)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	if e := gl.GetError(); e != gl.NO_ERROR {
+		return nil, fmt.Errorf("glReadPixels: error 0x%x", e)
+	}
+
+	// flip, and ignore alpha
+	flipped := image.NewRGBA(img.Rect)
+	for row := 0; row < height; row++ {
+		copy(flipped.Pix[row*img.Stride:(row+1)*img.Stride], img.Pix[(height-1-row)*img.Stride:])
+	}
+	for i := 3; i < len(flipped.Pix); i += 4 {
+		flipped.Pix[i] = 255
+	}
+	return flipped, nil
+}
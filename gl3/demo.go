@@ -2,20 +2,26 @@ package main
 
 import (
 	"github.com/go-gl/gl/all-core/gl"
-	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/easing"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/tween"
 
 	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/draw"
 	_ "image/png"
-	"log"
 	"math"
 	"os"
 	"runtime"
 	"strings"
 	"time"
-	"unsafe"
 )
 
 var (
@@ -115,20 +121,17 @@ type gResources struct {
 	elementBuffer3 uint32
 	colorBuffer3   uint32
 	len3           int32
+
+	// filled color wheel, uses same buffers as circle, with the
+	// triangles of the disc as elements
+	elementBuffer4 uint32
+	len4           int32
 }
 
 //
 // Functions for creating OpenGL objects:
 //
 
-func makeBuffer(target uint32, bufferData unsafe.Pointer, bufferSize int) uint32 {
-	var buffer uint32
-	gl.GenBuffers(1, &buffer)
-	gl.BindBuffer(target, buffer)
-	gl.BufferData(target, bufferSize, bufferData, gl.STATIC_DRAW)
-	return buffer
-}
-
 func makeTexture(filename string) uint32 {
 	fp, err := os.Open(filename)
 	x(err)
@@ -243,11 +246,11 @@ var (
 
 func makeResources() *gResources {
 	r := gResources{
-		vertexBuffer1:  makeBuffer(gl.ARRAY_BUFFER, gl.Ptr(gVertexBufferData1), 4*len(gVertexBufferData1)),
-		elementBuffer1: makeBuffer(gl.ELEMENT_ARRAY_BUFFER, gl.Ptr(gElementBufferData1), 4*len(gElementBufferData1)),
-		vertexBuffer2:  makeBuffer(gl.ARRAY_BUFFER, gl.Ptr(gVertexBufferData2), 4*len(gVertexBufferData2)),
-		elementBuffer2: makeBuffer(gl.ELEMENT_ARRAY_BUFFER, gl.Ptr(gElementBufferData2), 4*len(gElementBufferData2)),
-		colorBuffer2:   makeBuffer(gl.ARRAY_BUFFER, gl.Ptr(gColorBufferData2), 4*len(gColorBufferData2)),
+		vertexBuffer1:  glutil.MakeBuffer(gl.ARRAY_BUFFER, gVertexBufferData1, gl.STATIC_DRAW),
+		elementBuffer1: glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, gElementBufferData1, gl.STATIC_DRAW),
+		vertexBuffer2:  glutil.MakeBuffer(gl.ARRAY_BUFFER, gVertexBufferData2, gl.STATIC_DRAW),
+		elementBuffer2: glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, gElementBufferData2, gl.STATIC_DRAW),
+		colorBuffer2:   glutil.MakeBuffer(gl.ARRAY_BUFFER, gColorBufferData2, gl.STATIC_DRAW),
 	}
 
 	r.vertexShader1 = makeShader(gl.VERTEX_SHADER, vertex_glsl1)
@@ -267,28 +270,106 @@ func makeResources() *gResources {
 	r.attributes2.position = gl.GetAttribLocation(r.program2, gl.Str("position\x00"))
 	r.attributes2.color = gl.GetAttribLocation(r.program2, gl.Str("vertexColor\x00"))
 
-	// circle
-	gColorBufferData3 := make([]float32, 0, 126*3)
-	gVertexBufferData3 := make([]float32, 0, 126*2)
-	gElementBufferData3 := make([]uint32, 0, 126)
-	r.len3 = 0
-	for i := float64(0); i < 2*math.Pi; i += .05 {
-		rd, g, b := hsb2rgb(float32(i/(2*math.Pi)), 1, 1)
+	// circle and color wheel: the hue goes round clockwise from the top,
+	// the center is white
+	disc := mesh.Disc(*segments, 1)
+	gColorBufferData3 := make([]float32, 0, 3*len(disc.Vertices))
+	gVertexBufferData3 := make([]float32, 0, 2*len(disc.Vertices))
+	for i, v := range disc.Vertices {
+		px, py := v.Position[0], v.Position[1]
+		hue := math.Atan2(float64(px), float64(py)) / (2 * math.Pi)
+		if hue < 0 {
+			hue++
+		}
+		sat := float32(1)
+		if i == 0 {
+			sat = 0
+		}
+		rd, g, b := hsb2rgb(float32(hue), sat, 1)
 		gColorBufferData3 = append(gColorBufferData3, rd, g, b)
-		gVertexBufferData3 = append(gVertexBufferData3, float32(math.Sin(i)), float32(math.Cos(i)))
-		gElementBufferData3 = append(gElementBufferData3, uint32(r.len3))
-		r.len3++
+		gVertexBufferData3 = append(gVertexBufferData3, px, py)
+	}
+	gElementBufferData3 := make([]uint32, 0, *segments)
+	for i := 1; i < len(disc.Vertices); i++ {
+		gElementBufferData3 = append(gElementBufferData3, uint32(i))
 	}
-	r.vertexBuffer3 = makeBuffer(gl.ARRAY_BUFFER, gl.Ptr(gVertexBufferData3), 4*len(gVertexBufferData3))
-	r.elementBuffer3 = makeBuffer(gl.ELEMENT_ARRAY_BUFFER, gl.Ptr(gElementBufferData3), 4*len(gElementBufferData3))
-	r.colorBuffer3 = makeBuffer(gl.ARRAY_BUFFER, gl.Ptr(gColorBufferData3), 4*len(gColorBufferData3))
+	r.len3 = int32(len(gElementBufferData3))
+	r.len4 = int32(len(disc.Indices))
+	r.vertexBuffer3 = glutil.MakeBuffer(gl.ARRAY_BUFFER, gVertexBufferData3, gl.STATIC_DRAW)
+	r.elementBuffer3 = glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, gElementBufferData3, gl.STATIC_DRAW)
+	r.elementBuffer4 = glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, disc.Indices, gl.STATIC_DRAW)
+	r.colorBuffer3 = glutil.MakeBuffer(gl.ARRAY_BUFFER, gColorBufferData3, gl.STATIC_DRAW)
 
 	return &r
 }
 
-var start = time.Now()
+var clock = app.NewClock()
+var zoom = input.NewZoom(.1, 10)
 var ra = float32(.95)
 
+var (
+	segments = flag.Int("segments", 126, "number of segments of the circle")
+	wheel    = flag.Bool("wheel", false, "draw a filled color wheel instead of a circle")
+)
+
+// Extra action for this demo.
+const actionWheel = "wheel"
+
+// The rotation of the triangle: a full turn every 2π seconds, on a time of
+// its own. The first axis of a joystick sets the speed and direction of
+// that time.
+var (
+	angle        float64
+	turn         float64 // set by rotation
+	rotation     = tween.Loop(tween.Float(&turn, 0, 2*math.Pi, 2*math.Pi, easing.Linear), 0)
+	rotationTime float64
+	joysticks    = input.Joysticks{OnChange: joystickChanged}
+)
+
+// Dragging with the mouse rotates the triangle around the center of the window.
+var (
+	drag        input.Drag
+	dragAngle   float64 // angle of the triangle minus angle of the cursor
+	angleOffset float64 // angle of the triangle minus turn, set by dragging
+)
+
+func setupDrag(w *glfw.Window) {
+	drag.OnStart = func(x, y float64) {
+		dragAngle = angle - cursorAngle(w, x, y)
+	}
+	drag.OnMove = func(x, y, dx, dy float64) {
+		angleOffset = dragAngle + cursorAngle(w, x, y) - turn
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+}
+
+func cursorAngle(w *glfw.Window, x, y float64) float64 {
+	width, height := w.GetSize()
+	return math.Atan2(float64(height)/2-y, x-float64(width)/2)
+}
+
+func update() {
+	joysticks.Poll()
+	if !drag.Dragging() {
+		speed := 1.0
+		if v := joysticks.Axis(0); v != 0 {
+			speed = 3 * float64(v)
+		}
+		rotationTime += speed * clock.Delta()
+	}
+	rotation.Seek(rotationTime)
+	angle = turn + angleOffset
+}
+
+func joystickChanged(joy glfw.Joystick, name string, connected bool) {
+	if connected {
+		app.Infof("Joystick %d connected: %s", joy+1, name)
+	} else {
+		app.Infof("Joystick %d disconnected: %s", joy+1, name)
+	}
+}
+
 func render(w *glfw.Window, r *gResources) {
 
 	width, height := w.GetFramebufferSize()
@@ -300,10 +381,11 @@ func render(w *glfw.Window, r *gResources) {
 	} else {
 		xmul, ymul = ra, ra*ratio
 	}
+	z := float32(zoom.Scale())
+	xmul, ymul = xmul*z, ymul*z
 
-	d := time.Since(start).Seconds()
-	sin := float32(math.Sin(d))
-	cos := float32(math.Cos(d))
+	sin := float32(math.Sin(angle))
+	cos := float32(math.Cos(angle))
 
 	gl.Viewport(0, 0, int32(width), int32(height))
 	gl.Clear(gl.COLOR_BUFFER_BIT)
@@ -312,6 +394,8 @@ func render(w *glfw.Window, r *gResources) {
 
 	// axes
 
+	endGroup := glutil.DebugGroup("axes")
+
 	gl.UseProgram(r.program1)
 
 	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer1)
@@ -336,9 +420,11 @@ func render(w *glfw.Window, r *gResources) {
 
 	gl.DisableVertexAttribArray(uint32(r.attributes1.position))
 
+	endGroup()
+
 	////////////////
 
-	// triangle
+	// triangle and circle
 
 	gl.UseProgram(r.program2)
 
@@ -347,7 +433,14 @@ func render(w *glfw.Window, r *gResources) {
 	gl.Uniform1f(r.uniforms2.sin, sin)
 	gl.Uniform1f(r.uniforms2.cos, cos)
 
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer2)
+	gl.EnableVertexAttribArray(uint32(r.attributes2.position))
+	gl.EnableVertexAttribArray(uint32(r.attributes2.color))
+
+	// circle, before the triangle, so a filled wheel doesn't hide it
+
+	endGroup = glutil.DebugGroup("circle")
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer3)
 
 	gl.VertexAttribPointer(
 		uint32(r.attributes2.position), // attribute
@@ -356,12 +449,9 @@ func render(w *glfw.Window, r *gResources) {
 		false,           // normalized?
 		8,               // stride
 		gl.PtrOffset(0)) // array buffer offset
-	gl.EnableVertexAttribArray(uint32(r.attributes2.position))
 
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.elementBuffer2)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.colorBuffer3)
 
-	gl.EnableVertexAttribArray(uint32(r.attributes2.color))
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.colorBuffer2)
 	gl.VertexAttribPointer(
 		uint32(r.attributes2.color), // attribute
 		3,               // size
@@ -370,17 +460,32 @@ func render(w *glfw.Window, r *gResources) {
 		0,               // stride
 		gl.PtrOffset(0)) // array buffer offset
 
-	gl.DrawElements(
-		gl.TRIANGLES,    // mode
-		3,               // count
-		gl.UNSIGNED_INT, // type
-		gl.PtrOffset(0)) // element array buffer offset
+	if *wheel {
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.elementBuffer4)
+		gl.DrawElements(
+			gl.TRIANGLES,    // mode
+			r.len4,          // count
+			gl.UNSIGNED_INT, // type
+			gl.PtrOffset(0)) // element array buffer offset
+	} else {
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.elementBuffer3)
+		gl.LineWidth(5)
+		gl.DrawElements(
+			gl.LINE_LOOP,    // mode
+			r.len3,          // count
+			gl.UNSIGNED_INT, // type
+			gl.PtrOffset(0)) // element array buffer offset
+	}
+
+	endGroup()
 
 	////////////////
 
-	// circle
+	// triangle
+
+	endGroup = glutil.DebugGroup("triangle")
 
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer3)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer2)
 
 	gl.VertexAttribPointer(
 		uint32(r.attributes2.position), // attribute
@@ -390,10 +495,9 @@ func render(w *glfw.Window, r *gResources) {
 		8,               // stride
 		gl.PtrOffset(0)) // array buffer offset
 
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.elementBuffer3)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, r.colorBuffer3)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.elementBuffer2)
 
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.colorBuffer2)
 	gl.VertexAttribPointer(
 		uint32(r.attributes2.color), // attribute
 		3,               // size
@@ -402,26 +506,32 @@ func render(w *glfw.Window, r *gResources) {
 		0,               // stride
 		gl.PtrOffset(0)) // array buffer offset
 
-	gl.LineWidth(5)
 	gl.DrawElements(
-		gl.LINE_LOOP,    // mode
-		r.len3,          // count
+		gl.TRIANGLES,    // mode
+		3,               // count
 		gl.UNSIGNED_INT, // type
 		gl.PtrOffset(0)) // element array buffer offset
 
 	gl.DisableVertexAttribArray(uint32(r.attributes2.color))
 	gl.DisableVertexAttribArray(uint32(r.attributes2.position))
 
+	endGroup()
 }
 
 func main() {
+	app.Keys[actionWheel] = []string{"w"}
+	app.Flags(640, 480, "Testing 3+")
+	if *segments < 3 {
+		app.Fatal("-segments must be at least 3")
+	}
+
 	err := glfw.Init()
 	if err != nil {
 		panic(err)
 	}
 	defer glfw.Terminate()
 
-	w, err := glfw.CreateWindow(640, 480, "Testing 3+", nil, nil)
+	w, err := app.CreateWindow()
 	if err != nil {
 		panic(err)
 	}
@@ -429,29 +539,48 @@ func main() {
 	w.MakeContextCurrent()
 	glfw.SwapInterval(1)
 
-	w.SetCharCallback(charCallBack)
+	app.OnAction(w, onAction)
+	setupDrag(w)
+	w.SetScrollCallback(zoom.Scroll)
 
 	if err := gl.Init(); err != nil {
 		panic(err)
 	}
+	glinfo.Report()
 
 	r := makeResources()
 
 	gl.ClearColor(.5, .5, .5, 0)
-	fmt.Println("Press 'q' to quit")
+	app.Info("Press 'w' to switch between the circle and a filled color wheel")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
 	for !w.ShouldClose() {
 		time.Sleep(10 * time.Millisecond)
 
+		clock.Tick()
+		update()
 		render(w, r)
 
-		w.SwapBuffers()
+		app.SwapBuffers(w)
 		glfw.PollEvents()
 	}
 }
 
-func charCallBack(w *glfw.Window, char rune) {
-	if char == 'q' {
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
 		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionWheel:
+		*wheel = !*wheel
 	}
 }
 
@@ -463,7 +592,7 @@ func init() {
 
 func x(err error) {
 	if err != nil {
-		log.Fatalln(err)
+		app.Fatal(err)
 	}
 }
 
@@ -0,0 +1,448 @@
+package main
+
+// This is the gl3 demo, ported to a strict OpenGL 3.3 core profile context.
+// Compared to gl3 it uses GLSL 3.30 with in/out variables, explicit
+// attribute locations, and vertex array objects, which are required in the
+// core profile.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/polyline"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+// Attribute locations, as set in the shaders.
+const (
+	positionLocation = 0
+	colorLocation    = 1
+)
+
+var (
+	//
+	// axes
+	//
+	vertex_glsl1 = `
+#version 330 core
+
+layout(location = 0) in vec2 position;
+
+void main()
+{
+    gl_Position = vec4(position, 0.0, 1.0);
+}
+` + "\x00"
+	fragment_glsl1 = `
+#version 330 core
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(0, 0, 0, 0);
+}
+` + "\x00"
+
+	//
+	// triangle and circle
+	//
+	vertex_glsl2 = `
+#version 330 core
+
+uniform float xmul;
+uniform float ymul;
+uniform float sin;
+uniform float cos;
+
+layout(location = 0) in vec2 position;
+layout(location = 1) in vec3 vertexColor;
+
+out vec3 color;
+
+void main()
+{
+    gl_Position = vec4(xmul * (cos*position[0] + sin*position[1]), ymul * (sin*position[0] - cos*position[1]), 0.0, 1.0);
+    color = vertexColor;
+}
+` + "\x00"
+	fragment_glsl2 = `
+#version 330 core
+
+in vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color, 0);
+}
+` + "\x00"
+)
+
+//
+// Global data used by render
+//
+
+type tUniforms struct {
+	xmul int32
+	ymul int32
+	sin  int32
+	cos  int32
+}
+
+type gResources struct {
+	// axes
+	vertexArray1 uint32
+	program1     uint32
+
+	// triangle
+	vertexArray2 uint32
+	program2     uint32
+	uniforms2    tUniforms
+
+	// circle, a thick line
+	circle *polyline.Line
+	lines  *polyline.Renderer
+}
+
+//
+// Data used to seed our vertex arrays:
+//
+
+// axes
+var (
+	gVertexBufferData1 = []float32{
+		-1.0, 0.0,
+		1.0, 0.0,
+		0.0, -1.0,
+		0.0, 1.0,
+	}
+)
+
+// triangle
+var (
+	gVertexBufferData2 = []float32{
+		0.0, 1.0,
+		0.866, -0.5,
+		-0.866, -0.5,
+	}
+	gColorBufferData2 = []float32{
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+	}
+)
+
+// makeVertexArray creates a vertex array object with positions, and
+// colors if not nil.
+func makeVertexArray(positions, colors []float32) uint32 {
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+
+	glutil.MakeBuffer(gl.ARRAY_BUFFER, positions, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(
+		positionLocation, // attribute
+		2,                // size
+		gl.FLOAT,         // type
+		false,            // normalized?
+		0,                // stride
+		gl.PtrOffset(0))  // array buffer offset
+	gl.EnableVertexAttribArray(positionLocation)
+
+	if colors != nil {
+		glutil.MakeBuffer(gl.ARRAY_BUFFER, colors, gl.STATIC_DRAW)
+		gl.VertexAttribPointer(
+			colorLocation,   // attribute
+			3,               // size
+			gl.FLOAT,        // type
+			false,           // normalized?
+			0,               // stride
+			gl.PtrOffset(0)) // array buffer offset
+		gl.EnableVertexAttribArray(colorLocation)
+	}
+
+	gl.BindVertexArray(0)
+	return vao
+}
+
+//
+// Load and create all of our resources
+//
+
+func makeResources() *gResources {
+	r := gResources{
+		vertexArray1: makeVertexArray(gVertexBufferData1, nil),
+		vertexArray2: makeVertexArray(gVertexBufferData2, gColorBufferData2),
+	}
+
+	var err error
+	r.program1, err = glutil.MakeProgramFromSource(vertex_glsl1, fragment_glsl1)
+	x(err)
+	r.program2, err = glutil.MakeProgramFromSource(vertex_glsl2, fragment_glsl2)
+	x(err)
+
+	r.uniforms2.xmul = gl.GetUniformLocation(r.program2, gl.Str("xmul\x00"))
+	r.uniforms2.ymul = gl.GetUniformLocation(r.program2, gl.Str("ymul\x00"))
+	r.uniforms2.sin = gl.GetUniformLocation(r.program2, gl.Str("sin\x00"))
+	r.uniforms2.cos = gl.GetUniformLocation(r.program2, gl.Str("cos\x00"))
+
+	// circle
+	points := make([]polyline.Point, 0, 126)
+	for i := float64(0); i < 2*math.Pi; i += .05 {
+		rd, g, b := hsb2rgb(float32(i/(2*math.Pi)), 1, 1)
+		points = append(points, polyline.Point{
+			Position: glm.Vec3{float32(math.Sin(i)), float32(math.Cos(i)), 0},
+			Color:    glm.Vec4{rd, g, b, 1},
+		})
+	}
+	r.circle = polyline.NewLine(points, true)
+	r.lines, err = polyline.NewRenderer()
+	x(err)
+	r.lines.Width = 5
+
+	return &r
+}
+
+var clock = app.NewClock()
+var zoom = input.NewZoom(.1, 10)
+var ra = float32(.95)
+
+// Extra action for this demo.
+const actionDashes = "dashes"
+
+// Dash patterns for the circle, lengths of dashes and gaps for a radius of
+// 1. The dashes move along at a fifth of the radius per second.
+var (
+	dashPatterns = []struct {
+		name   string
+		dashes []float32
+	}{
+		{"solid", nil},
+		{"dashed", []float32{.2, .1}},
+		{"dotted", []float32{.02, .06}},
+		{"dash-dot", []float32{.2, .08, .02, .08}},
+	}
+	dashPattern = 0
+)
+
+// The rotation of the triangle. The first axis of a joystick sets the speed
+// and direction of the rotation.
+var (
+	angle     float64
+	joysticks = input.Joysticks{OnChange: joystickChanged}
+)
+
+// Dragging with the mouse rotates the triangle around the center of the window.
+var (
+	drag      input.Drag
+	dragAngle float64 // angle of the triangle minus angle of the cursor
+)
+
+func setupDrag(w *glfw.Window) {
+	drag.OnStart = func(x, y float64) {
+		dragAngle = angle - cursorAngle(w, x, y)
+	}
+	drag.OnMove = func(x, y, dx, dy float64) {
+		angle = dragAngle + cursorAngle(w, x, y)
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+}
+
+func cursorAngle(w *glfw.Window, x, y float64) float64 {
+	width, height := w.GetSize()
+	return math.Atan2(float64(height)/2-y, x-float64(width)/2)
+}
+
+func update() {
+	joysticks.Poll()
+	if drag.Dragging() {
+		return
+	}
+	speed := 1.0
+	if v := joysticks.Axis(0); v != 0 {
+		speed = 3 * float64(v)
+	}
+	angle += speed * clock.Delta()
+}
+
+func joystickChanged(joy glfw.Joystick, name string, connected bool) {
+	if connected {
+		app.Infof("Joystick %d connected: %s", joy+1, name)
+	} else {
+		app.Infof("Joystick %d disconnected: %s", joy+1, name)
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+
+	width, height := w.GetFramebufferSize()
+	ratio := float32(width) / float32(height)
+
+	var xmul, ymul float32
+	if ratio > 1 {
+		xmul, ymul = ra/ratio, ra
+	} else {
+		xmul, ymul = ra, ra*ratio
+	}
+	z := float32(zoom.Scale())
+	xmul, ymul = xmul*z, ymul*z
+
+	sin := float32(math.Sin(angle))
+	cos := float32(math.Cos(angle))
+
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// no blending, which the polyline turned on, as the colors of the
+	// axes and the triangle have alpha 0
+	glutil.State{}.Apply()
+
+	////////////////
+
+	// axes
+
+	gl.UseProgram(r.program1)
+	gl.BindVertexArray(r.vertexArray1)
+	gl.DrawArrays(gl.LINES, 0, 4)
+
+	////////////////
+
+	// triangle
+
+	gl.UseProgram(r.program2)
+
+	gl.Uniform1f(r.uniforms2.xmul, xmul)
+	gl.Uniform1f(r.uniforms2.ymul, ymul)
+	gl.Uniform1f(r.uniforms2.sin, sin)
+	gl.Uniform1f(r.uniforms2.cos, cos)
+
+	gl.BindVertexArray(r.vertexArray2)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+
+	////////////////
+
+	// circle
+
+	// Line widths other than 1 are not supported in a forward-compatible
+	// context, so the circle is drawn as a polyline, with the same
+	// transform as the triangle.
+	gl.BindVertexArray(0)
+	transform := glm.Mat4{
+		xmul * cos, ymul * sin, 0, 0,
+		xmul * sin, -ymul * cos, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+	r.lines.Dashes = dashPatterns[dashPattern].dashes
+	r.lines.DashOffset = float32(clock.Seconds() * .2)
+	r.lines.Draw(r.circle, transform, int32(width), int32(height))
+}
+
+func main() {
+	app.Keys[actionDashes] = []string{"d"}
+	app.Flags(640, 480, "Testing 3.3 core")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	setupDrag(w)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	gl.ClearColor(.5, .5, .5, 0)
+	app.Info("Press 'd' to change the dash pattern of the circle")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionDashes:
+		dashPattern = (dashPattern + 1) % len(dashPatterns)
+		app.Info("Circle:", dashPatterns[dashPattern].name)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c, x, 0
+	}
+	if h < 2 {
+		return x, c, 0
+	}
+	if h < 3 {
+		return 0, c, x
+	}
+	if h < 4 {
+		return 0, x, c
+	}
+	if h < 5 {
+		return x, 0, c
+	}
+	return c, 0, x
+}
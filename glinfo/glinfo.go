@@ -0,0 +1,110 @@
+// Package glinfo reports the OpenGL version, renderer and limits of the
+// current context, as needed for reports of driver problems.
+//
+// Importing the package adds the flag -info to the command line. With -info,
+// Report prints the information and exits. With -info=run, the program
+// continues after printing.
+package glinfo
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Info is what the context says about itself.
+type Info struct {
+	Version     string
+	GLSLVersion string
+	Vendor      string
+	Renderer    string
+
+	MaxTextureSize int32
+	MaxSamples     int32
+
+	Extensions []string
+}
+
+// Get returns the information of the current context. It must be called
+// after gl.Init.
+func Get() *Info {
+	info := &Info{
+		Version:     gl.GoStr(gl.GetString(gl.VERSION)),
+		GLSLVersion: gl.GoStr(gl.GetString(gl.SHADING_LANGUAGE_VERSION)),
+		Vendor:      gl.GoStr(gl.GetString(gl.VENDOR)),
+		Renderer:    gl.GoStr(gl.GetString(gl.RENDERER)),
+	}
+	gl.GetIntegerv(gl.MAX_TEXTURE_SIZE, &info.MaxTextureSize)
+	gl.GetIntegerv(gl.MAX_SAMPLES, &info.MaxSamples)
+
+	// The core profile has no single string with all extensions.
+	var n int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &n)
+	for i := int32(0); i < n; i++ {
+		info.Extensions = append(info.Extensions, gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))))
+	}
+	return info
+}
+
+// Print writes the information in a form that can be pasted in a report.
+func (info *Info) Print(w io.Writer) {
+	fmt.Fprintln(w, "GL_VERSION:      ", info.Version)
+	fmt.Fprintln(w, "GLSL version:    ", info.GLSLVersion)
+	fmt.Fprintln(w, "Vendor:          ", info.Vendor)
+	fmt.Fprintln(w, "Renderer:        ", info.Renderer)
+	fmt.Fprintln(w, "Max texture size:", info.MaxTextureSize)
+	fmt.Fprintln(w, "Max samples:     ", info.MaxSamples)
+	fmt.Fprintf(w, "Extensions (%d):\n", len(info.Extensions))
+	for _, e := range info.Extensions {
+		fmt.Fprintln(w, "   ", e)
+	}
+}
+
+// The value of -info: "" when not given, "exit" or "run".
+type mode string
+
+func (m *mode) String() string {
+	return string(*m)
+}
+
+func (m *mode) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "true", "exit":
+		*m = "exit"
+	case "false":
+		*m = ""
+	case "run":
+		*m = "run"
+	default:
+		return fmt.Errorf("want exit or run")
+	}
+	return nil
+}
+
+// IsBoolFlag allows -info without a value.
+func (m *mode) IsBoolFlag() bool {
+	return true
+}
+
+var info mode
+
+func init() {
+	flag.Var(&info, "info", "print OpenGL version, renderer, limits and extensions, then exit, or with -info=run, continue")
+}
+
+// Report prints the information to standard output if -info was given on
+// the command line, and exits unless it was -info=run. Call it after
+// gl.Init.
+func Report() {
+	if info == "" {
+		return
+	}
+	Get().Print(os.Stdout)
+	if info == "exit" {
+		os.Exit(0)
+	}
+}
@@ -0,0 +1,113 @@
+package glm
+
+import (
+	"math"
+)
+
+// AABB is an axis-aligned bounding box.
+type AABB struct {
+	Min, Max Vec3
+}
+
+func (b AABB) Center() Vec3 {
+	return b.Min.Add(b.Max).Mul(.5)
+}
+
+// Transform returns the axis-aligned box around b transformed by m.
+func (b AABB) Transform(m Mat4) AABB {
+	// Arvo's method: the extent on each axis is the sum of the extents of
+	// the transformed axes of b.
+	center := m.Transform(b.Center())
+	half := b.Max.Sub(b.Min).Mul(.5)
+	var extent Vec3
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			extent[row] += float32(math.Abs(float64(m.At(row, col)))) * half[col]
+		}
+	}
+	return AABB{center.Sub(extent), center.Add(extent)}
+}
+
+// Sphere is a bounding sphere.
+type Sphere struct {
+	Center Vec3
+	Radius float32
+}
+
+// Transform returns a sphere around s transformed by m. The radius is
+// scaled by the largest scale of m.
+func (s Sphere) Transform(m Mat4) Sphere {
+	var scale float32
+	for col := 0; col < 3; col++ {
+		axis := Vec3{m.At(0, col), m.At(1, col), m.At(2, col)}
+		if l := axis.Len(); l > scale {
+			scale = l
+		}
+	}
+	return Sphere{m.Transform(s.Center), s.Radius * scale}
+}
+
+// Frustum is the space seen through a projection, as six planes: left,
+// right, bottom, top, near and far. For each plane (a, b, c, d), the
+// points p inside have a*p.x + b*p.y + c*p.z + d >= 0.
+type Frustum [6]Vec4
+
+// NewFrustum extracts the planes from a projection matrix. With a
+// projection × view matrix, the planes are in world coordinates, and with a
+// projection × view × model matrix in model coordinates.
+func NewFrustum(m Mat4) Frustum {
+	row := func(i int) Vec4 {
+		return Vec4{m.At(i, 0), m.At(i, 1), m.At(i, 2), m.At(i, 3)}
+	}
+	add := func(a, b Vec4, s float32) Vec4 {
+		return Vec4{a[0] + s*b[0], a[1] + s*b[1], a[2] + s*b[2], a[3] + s*b[3]}
+	}
+	w := row(3)
+	f := Frustum{
+		add(w, row(0), 1),
+		add(w, row(0), -1),
+		add(w, row(1), 1),
+		add(w, row(1), -1),
+		add(w, row(2), 1),
+		add(w, row(2), -1),
+	}
+	// normalize, so the planes give distances
+	for i, p := range f {
+		l := p.Vec3().Len()
+		if l > 0 {
+			f[i] = Vec4{p[0] / l, p[1] / l, p[2] / l, p[3] / l}
+		}
+	}
+	return f
+}
+
+// ContainsSphere returns false if s is entirely outside f. It may return
+// true for some spheres just outside the corners of f.
+func (f Frustum) ContainsSphere(s Sphere) bool {
+	for _, p := range f {
+		if p.Dot(s.Center.Vec4(1)) < -s.Radius {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsAABB returns false if b is entirely outside f. It may return true
+// for some boxes just outside the corners of f.
+func (f Frustum) ContainsAABB(b AABB) bool {
+	for _, p := range f {
+		// the corner of b furthest along the normal of the plane
+		var v Vec3
+		for i := 0; i < 3; i++ {
+			if p[i] >= 0 {
+				v[i] = b.Max[i]
+			} else {
+				v[i] = b.Min[i]
+			}
+		}
+		if p.Dot(v.Vec4(1)) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package glm
+
+import (
+	"math"
+)
+
+// Quat is a rotation as a unit quaternion, stored as x, y, z, w, the order
+// used by glTF.
+type Quat [4]float32
+
+// Mat4 returns the rotation matrix.
+func (q Quat) Mat4() Mat4 {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Dot returns the dot product of q and r.
+func (q Quat) Dot(r Quat) float32 {
+	return q[0]*r[0] + q[1]*r[1] + q[2]*r[2] + q[3]*r[3]
+}
+
+// Normalize returns q scaled to unit length.
+func (q Quat) Normalize() Quat {
+	l := float32(math.Sqrt(float64(q.Dot(q))))
+	if l == 0 {
+		return Quat{0, 0, 0, 1}
+	}
+	return Quat{q[0] / l, q[1] / l, q[2] / l, q[3] / l}
+}
+
+// Slerp interpolates along the shortest arc from q, for t = 0, to r, for
+// t = 1.
+func (q Quat) Slerp(r Quat, t float32) Quat {
+	d := q.Dot(r)
+	if d < 0 {
+		// q and -q are the same rotation, take the short way
+		r = Quat{-r[0], -r[1], -r[2], -r[3]}
+		d = -d
+	}
+	var a, b float32
+	if d > .9995 {
+		// almost the same, linear interpolation is accurate enough
+		a, b = 1-t, t
+	} else {
+		theta := math.Acos(float64(d))
+		s := math.Sin(theta)
+		a = float32(math.Sin((1-float64(t))*theta) / s)
+		b = float32(math.Sin(float64(t)*theta) / s)
+	}
+	return Quat{
+		a*q[0] + b*r[0],
+		a*q[1] + b*r[1],
+		a*q[2] + b*r[2],
+		a*q[3] + b*r[3],
+	}.Normalize()
+}
+
+// AxisAngle returns the rotation by angle radians around axis.
+func AxisAngle(axis Vec3, angle float32) Quat {
+	a := axis.Normalize()
+	s := float32(math.Sin(float64(angle) / 2))
+	return Quat{a[0] * s, a[1] * s, a[2] * s, float32(math.Cos(float64(angle) / 2))}
+}
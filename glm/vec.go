@@ -0,0 +1,88 @@
+// Package glm contains the vector and matrix math used by the demos.
+//
+// Matrices are stored in column-major order, as OpenGL expects them.
+package glm
+
+import (
+	"math"
+)
+
+type Vec2 [2]float32
+type Vec3 [3]float32
+type Vec4 [4]float32
+
+func (a Vec2) Add(b Vec2) Vec2 {
+	return Vec2{a[0] + b[0], a[1] + b[1]}
+}
+
+func (a Vec2) Sub(b Vec2) Vec2 {
+	return Vec2{a[0] - b[0], a[1] - b[1]}
+}
+
+func (a Vec2) Mul(s float32) Vec2 {
+	return Vec2{a[0] * s, a[1] * s}
+}
+
+func (a Vec3) Add(b Vec3) Vec3 {
+	return Vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+func (a Vec3) Sub(b Vec3) Vec3 {
+	return Vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func (a Vec3) Mul(s float32) Vec3 {
+	return Vec3{a[0] * s, a[1] * s, a[2] * s}
+}
+
+// MulVec multiplies component-wise.
+func (a Vec3) MulVec(b Vec3) Vec3 {
+	return Vec3{a[0] * b[0], a[1] * b[1], a[2] * b[2]}
+}
+
+func (a Vec3) Dot(b Vec3) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func (a Vec3) Cross(b Vec3) Vec3 {
+	return Vec3{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+func (a Vec3) Len() float32 {
+	return float32(math.Sqrt(float64(a.Dot(a))))
+}
+
+// Normalize returns a vector of length 1, or the zero vector unchanged.
+func (a Vec3) Normalize() Vec3 {
+	l := a.Len()
+	if l == 0 {
+		return a
+	}
+	return a.Mul(1 / l)
+}
+
+// Lerp interpolates linearly between a and b.
+func (a Vec3) Lerp(b Vec3, t float32) Vec3 {
+	return a.Add(b.Sub(a).Mul(t))
+}
+
+func (a Vec3) Vec4(w float32) Vec4 {
+	return Vec4{a[0], a[1], a[2], w}
+}
+
+func (a Vec4) Vec3() Vec3 {
+	return Vec3{a[0], a[1], a[2]}
+}
+
+func (a Vec4) Dot(b Vec4) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] + a[3]*b[3]
+}
+
+// Radians converts degrees to radians.
+func Radians(degrees float32) float32 {
+	return degrees * math.Pi / 180
+}
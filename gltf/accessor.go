@@ -0,0 +1,304 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The JSON structure of a glTF file, as far as it is used.
+
+type jDocument struct {
+	Scene       *int
+	Scenes      []jScene
+	Nodes       []jNode
+	Meshes      []jMesh
+	Materials   []jMaterial
+	Textures    []jTexture
+	Images      []jImage
+	Accessors   []jAccessor
+	BufferViews []jBufferView
+	Buffers     []jBuffer
+	Skins       []jSkin
+	Animations  []jAnimation
+}
+
+type jScene struct {
+	Nodes []int
+}
+
+type jNode struct {
+	Name        string
+	Children    []int
+	Mesh        *int
+	Skin        *int
+	Matrix      []float32
+	Translation []float32
+	Rotation    []float32
+	Scale       []float32
+}
+
+type jSkin struct {
+	Name                string
+	InverseBindMatrices *int
+	Joints              []int
+}
+
+type jAnimation struct {
+	Name     string
+	Channels []struct {
+		Sampler int
+		Target  struct {
+			Node *int
+			Path string
+		}
+	}
+	Samplers []struct {
+		Input         int
+		Output        int
+		Interpolation string
+	}
+}
+
+type jMesh struct {
+	Name       string
+	Primitives []jPrimitive
+}
+
+type jPrimitive struct {
+	Attributes map[string]int
+	Indices    *int
+	Material   *int
+	Mode       *int
+}
+
+type jMaterial struct {
+	Name                 string
+	PbrMetallicRoughness struct {
+		BaseColorFactor  []float32
+		BaseColorTexture *struct {
+			Index int
+		}
+		MetallicFactor  *float32
+		RoughnessFactor *float32
+	}
+	EmissiveFactor []float32
+	DoubleSided    bool
+}
+
+type jTexture struct {
+	Source *int
+}
+
+type jImage struct {
+	URI        string
+	BufferView *int
+	MimeType   string
+}
+
+type jAccessor struct {
+	BufferView    *int
+	ByteOffset    int
+	ComponentType int
+	Normalized    bool
+	Count         int
+	Type          string
+	Sparse        interface{}
+}
+
+type jBufferView struct {
+	Buffer     int
+	ByteOffset int
+	ByteLength int
+	ByteStride int
+}
+
+type jBuffer struct {
+	URI        string
+	ByteLength int
+}
+
+// Component types of accessors.
+const (
+	typeByte          = 5120
+	typeUnsignedByte  = 5121
+	typeShort         = 5122
+	typeUnsignedShort = 5123
+	typeUnsignedInt   = 5125
+	typeFloat         = 5126
+)
+
+var componentSize = map[int]int{
+	typeByte:          1,
+	typeUnsignedByte:  1,
+	typeShort:         2,
+	typeUnsignedShort: 2,
+	typeUnsignedInt:   4,
+	typeFloat:         4,
+}
+
+var componentCount = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT2":   4,
+	"MAT3":   9,
+	"MAT4":   16,
+}
+
+// Most elements of an accessor without a buffer view, which are all zeros.
+// Such an accessor has no data in the file to check its count against.
+const maxZeroElements = 1 << 24
+
+// bufferView returns the bytes of a buffer view, and its stride.
+func (d *decoder) bufferView(i int) ([]byte, int, error) {
+	if i < 0 || i >= len(d.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("buffer view %d does not exist", i)
+	}
+	v := d.doc.BufferViews[i]
+	if v.Buffer < 0 || v.Buffer >= len(d.buffers) {
+		return nil, 0, fmt.Errorf("buffer %d does not exist", v.Buffer)
+	}
+	b := d.buffers[v.Buffer]
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset > len(b) || v.ByteLength > len(b)-v.ByteOffset {
+		return nil, 0, fmt.Errorf("buffer view %d is outside its buffer", i)
+	}
+	if v.ByteStride < 0 {
+		return nil, 0, fmt.Errorf("buffer view %d: negative stride %d", i, v.ByteStride)
+	}
+	return b[v.ByteOffset : v.ByteOffset+v.ByteLength], v.ByteStride, nil
+}
+
+// elements checks accessor i, and returns it with its data, starting at
+// the first element, the stride between elements, and the number and size
+// of their components. data is nil if the accessor has no buffer view.
+func (d *decoder) elements(i int) (a jAccessor, data []byte, stride, n, size int, err error) {
+	if i < 0 || i >= len(d.doc.Accessors) {
+		err = fmt.Errorf("accessor %d does not exist", i)
+		return
+	}
+	a = d.doc.Accessors[i]
+	if a.Sparse != nil {
+		err = fmt.Errorf("accessor %d: sparse accessors are not supported", i)
+		return
+	}
+	var ok bool
+	if n, ok = componentCount[a.Type]; !ok {
+		err = fmt.Errorf("accessor %d: unknown type %q", i, a.Type)
+		return
+	}
+	if size, ok = componentSize[a.ComponentType]; !ok {
+		err = fmt.Errorf("accessor %d: unknown component type %d", i, a.ComponentType)
+		return
+	}
+	if a.Count < 0 {
+		err = fmt.Errorf("accessor %d: negative count %d", i, a.Count)
+		return
+	}
+	if a.BufferView == nil {
+		if a.Count > maxZeroElements {
+			err = fmt.Errorf("accessor %d: count %d is too large for an accessor without a buffer view", i, a.Count)
+		}
+		return
+	}
+	if data, stride, err = d.bufferView(*a.BufferView); err != nil {
+		return
+	}
+	if stride == 0 {
+		stride = n * size
+	}
+	if a.ByteOffset < 0 || a.ByteOffset > len(data) {
+		err = fmt.Errorf("accessor %d is outside its buffer view", i)
+		return
+	}
+	// the last element must end within the buffer view, checked without
+	// multiplying the count, which may overflow
+	if avail := len(data) - a.ByteOffset - n*size; a.Count > 0 && (avail < 0 || a.Count-1 > avail/stride) {
+		err = fmt.Errorf("accessor %d: %d elements don't fit in its buffer view", i, a.Count)
+		return
+	}
+	data = data[a.ByteOffset:]
+	return
+}
+
+// accessor returns the values of an accessor as float32, with the number of
+// components per element. Integer values are converted, and normalized if
+// the accessor says so.
+func (d *decoder) accessor(i int) ([]float32, int, error) {
+	a, data, stride, n, size, err := d.elements(i)
+	if err != nil {
+		return nil, 0, err
+	}
+	values := make([]float32, a.Count*n)
+	if data == nil {
+		// all zeros
+		return values, n, nil
+	}
+
+	le := binary.LittleEndian
+	for e := 0; e < a.Count; e++ {
+		for c := 0; c < n; c++ {
+			p := data[e*stride+c*size:]
+			var f float32
+			switch a.ComponentType {
+			case typeFloat:
+				f = math.Float32frombits(le.Uint32(p))
+			case typeUnsignedInt:
+				f = float32(le.Uint32(p))
+			case typeUnsignedShort:
+				f = float32(le.Uint16(p))
+				if a.Normalized {
+					f /= 65535
+				}
+			case typeShort:
+				f = float32(int16(le.Uint16(p)))
+				if a.Normalized {
+					f = float32(math.Max(float64(f)/32767, -1))
+				}
+			case typeUnsignedByte:
+				f = float32(p[0])
+				if a.Normalized {
+					f /= 255
+				}
+			case typeByte:
+				f = float32(int8(p[0]))
+				if a.Normalized {
+					f = float32(math.Max(float64(f)/127, -1))
+				}
+			}
+			values[e*n+c] = f
+		}
+	}
+	return values, n, nil
+}
+
+// indices returns the values of an accessor of unsigned integers.
+func (d *decoder) indices(i int) ([]uint32, error) {
+	a, data, stride, n, _, err := d.elements(i)
+	if err != nil {
+		return nil, err
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("accessor %d: indices must be scalars", i)
+	}
+	idx := make([]uint32, a.Count)
+	if data == nil {
+		return idx, nil
+	}
+	le := binary.LittleEndian
+	for e := range idx {
+		p := data[e*stride:]
+		switch a.ComponentType {
+		case typeUnsignedInt:
+			idx[e] = le.Uint32(p)
+		case typeUnsignedShort:
+			idx[e] = uint32(le.Uint16(p))
+		case typeUnsignedByte:
+			idx[e] = uint32(p[0])
+		default:
+			return nil, fmt.Errorf("accessor %d: indices must be unsigned integers", i)
+		}
+	}
+	return idx, nil
+}
@@ -0,0 +1,174 @@
+package gltf
+
+import (
+	"github.com/pebbe/gl/glm"
+
+	"fmt"
+	"sort"
+)
+
+// Animation changes the transformations of nodes over time.
+type Animation struct {
+	Name     string
+	Channels []Channel
+	Duration float32 // seconds
+}
+
+// Channel animates one part of the transformation of one node.
+type Channel struct {
+	Node          int
+	Path          string    // "translation", "rotation" or "scale"
+	Interpolation string    // "LINEAR", "STEP" or "CUBICSPLINE"
+	Times         []float32 // seconds, ascending
+	// Values has 3 (translation, scale) or 4 (rotation) components for each
+	// time, or three times as many for CUBICSPLINE: in-tangent, value and
+	// out-tangent.
+	Values []float32
+}
+
+// Apply sets the nodes in pose to their animated transformation at time t,
+// in seconds. Nodes that are not animated are left unchanged. t is not
+// wrapped: before the first and after the last key frame the values of
+// those key frames are used.
+func (a *Animation) Apply(pose Pose, t float32) {
+	for _, c := range a.Channels {
+		if c.Node < 0 || c.Node >= len(pose) || len(c.Times) == 0 {
+			continue
+		}
+		v := c.value(t)
+		tr := &pose[c.Node]
+		switch c.Path {
+		case "translation":
+			copy(tr.Translation[:], v)
+		case "rotation":
+			copy(tr.Rotation[:], v)
+		case "scale":
+			copy(tr.Scale[:], v)
+		}
+	}
+}
+
+// value returns the value of the channel at time t.
+func (c *Channel) value(t float32) []float32 {
+	n := 3
+	if c.Path == "rotation" {
+		n = 4
+	}
+	cubic := c.Interpolation == "CUBICSPLINE"
+	key := func(k int) []float32 {
+		if cubic {
+			return c.Values[(3*k+1)*n : (3*k+2)*n]
+		}
+		return c.Values[k*n : (k+1)*n]
+	}
+
+	last := len(c.Times) - 1
+	if t <= c.Times[0] {
+		return key(0)
+	}
+	if t >= c.Times[last] {
+		return key(last)
+	}
+	// k is the last key frame at or before t
+	k := sort.Search(len(c.Times), func(i int) bool { return c.Times[i] > t }) - 1
+	dt := c.Times[k+1] - c.Times[k]
+	f := (t - c.Times[k]) / dt
+
+	v := make([]float32, n)
+	switch {
+	case c.Interpolation == "STEP":
+		copy(v, key(k))
+	case cubic:
+		// Hermite spline, with the tangents scaled by the duration
+		f2, f3 := f*f, f*f*f
+		p0, m0 := key(k), c.Values[(3*k+2)*n:(3*k+3)*n]
+		p1, m1 := key(k+1), c.Values[(3*k+3)*n:(3*k+4)*n]
+		for i := range v {
+			v[i] = (2*f3-3*f2+1)*p0[i] + (f3-2*f2+f)*dt*m0[i] + (-2*f3+3*f2)*p1[i] + (f3-f2)*dt*m1[i]
+		}
+		if n == 4 {
+			q := glm.Quat{v[0], v[1], v[2], v[3]}.Normalize()
+			copy(v, q[:])
+		}
+	case n == 4:
+		p0, p1 := key(k), key(k+1)
+		q := glm.Quat{p0[0], p0[1], p0[2], p0[3]}.Slerp(glm.Quat{p1[0], p1[1], p1[2], p1[3]}, f)
+		copy(v, q[:])
+	default:
+		p0, p1 := key(k), key(k+1)
+		for i := range v {
+			v[i] = p0[i] + f*(p1[i]-p0[i])
+		}
+	}
+	return v
+}
+
+// Blend returns the interpolation of two poses of the same model, a for
+// f = 0, b for f = 1. Rotations are interpolated with slerp.
+func Blend(a, b Pose, f float32) Pose {
+	p := make(Pose, len(a))
+	for i := range p {
+		p[i] = Transform{
+			Translation: a[i].Translation.Add(b[i].Translation.Sub(a[i].Translation).Mul(f)),
+			Rotation:    a[i].Rotation.Slerp(b[i].Rotation, f),
+			Scale:       a[i].Scale.Add(b[i].Scale.Sub(a[i].Scale).Mul(f)),
+		}
+	}
+	return p
+}
+
+// animation returns animation i from the file.
+func (d *decoder) animation(i int) (Animation, error) {
+	ja := d.doc.Animations[i]
+	a := Animation{Name: ja.Name}
+	for _, jc := range ja.Channels {
+		if jc.Target.Node == nil {
+			// targets an extension
+			continue
+		}
+		var n int
+		switch jc.Target.Path {
+		case "translation", "scale":
+			n = 3
+		case "rotation":
+			n = 4
+		default:
+			// morph target weights are not supported
+			continue
+		}
+		if jc.Sampler < 0 || jc.Sampler >= len(ja.Samplers) {
+			return a, fmt.Errorf("animation %d: sampler %d does not exist", i, jc.Sampler)
+		}
+		s := ja.Samplers[jc.Sampler]
+		c := Channel{
+			Node:          *jc.Target.Node,
+			Path:          jc.Target.Path,
+			Interpolation: s.Interpolation,
+		}
+		if c.Interpolation == "" {
+			c.Interpolation = "LINEAR"
+		}
+		if c.Node < 0 || c.Node >= len(d.doc.Nodes) {
+			return a, fmt.Errorf("animation %d: node %d does not exist", i, c.Node)
+		}
+		var err error
+		if c.Times, _, err = d.accessor(s.Input); err != nil {
+			return a, fmt.Errorf("animation %d: %v", i, err)
+		}
+		if c.Values, _, err = d.accessor(s.Output); err != nil {
+			return a, fmt.Errorf("animation %d: %v", i, err)
+		}
+		want := n * len(c.Times)
+		if c.Interpolation == "CUBICSPLINE" {
+			want *= 3
+		}
+		if len(c.Values) != want {
+			return a, fmt.Errorf("animation %d: wrong number of values for %d key frames", i, len(c.Times))
+		}
+		if len(c.Times) > 0 && c.Times[len(c.Times)-1] > a.Duration {
+			a.Duration = c.Times[len(c.Times)-1]
+		}
+		a.Channels = append(a.Channels, c)
+	}
+	return a, nil
+}
@@ -0,0 +1,458 @@
+// Package gltf loads models in glTF 2.0 format, both as .gltf files, with
+// external or embedded buffers and images, and as binary .glb files.
+//
+// Meshes are converted to mesh.Mesh, so they can be drawn like the other
+// meshes in the demos. Only triangle primitives are read. Materials are
+// limited to the metallic-roughness base color, metallic and roughness
+// factors, and the base color texture. Skins and animations of
+// translation, rotation and scale are supported, morph targets are not.
+package gltf
+
+import (
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/mesh"
+
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Model is the default scene of a glTF file.
+type Model struct {
+	Nodes      []Node
+	Roots      []int // the nodes in the scene, without a parent
+	Meshes     []Mesh
+	Materials  []Material
+	Images     []*image.RGBA
+	Skins      []Skin
+	Animations []Animation
+}
+
+// Node is a node in the scene hierarchy.
+type Node struct {
+	Name     string
+	Children []int
+	Mesh     int      // index into Meshes, or -1
+	Skin     int      // index into Skins, or -1
+	Local    glm.Mat4 // transformation relative to the parent
+
+	// The transformation as translation, rotation and scale, which can be
+	// animated. Not used if the file gave the transformation as a matrix.
+	Transform Transform
+	matrix    bool
+}
+
+// Mesh is a glTF mesh, drawn as one or more primitives.
+type Mesh struct {
+	Name       string
+	Primitives []Primitive
+}
+
+// Primitive is part of a mesh with a single material.
+type Primitive struct {
+	Mesh     *mesh.Mesh
+	Material int // index into Materials, or -1 for the default material
+
+	// For skinned meshes, for each vertex the indices into Skin.Joints of
+	// the four joints that move it, and their weights. Nil otherwise.
+	Joints  [][4]uint16
+	Weights []glm.Vec4
+}
+
+// Material is a physically based material.
+type Material struct {
+	Name             string
+	BaseColor        glm.Vec4
+	BaseColorTexture int // index into Images, or -1
+	Metallic         float32
+	Roughness        float32
+	Emissive         glm.Vec3
+	DoubleSided      bool
+}
+
+// DefaultMaterial is used for primitives without a material, as the glTF
+// specification prescribes.
+var DefaultMaterial = Material{
+	BaseColor:        glm.Vec4{1, 1, 1, 1},
+	BaseColorTexture: -1,
+	Metallic:         1,
+	Roughness:        1,
+}
+
+// Walk calls f for each node in the scene that has a mesh, with its
+// transformation relative to the root of the scene.
+func (m *Model) Walk(f func(node *Node, world glm.Mat4)) {
+	var walk func(i int, parent glm.Mat4)
+	walk = func(i int, parent glm.Mat4) {
+		n := &m.Nodes[i]
+		world := parent.Mul(n.Local)
+		if n.Mesh >= 0 {
+			f(n, world)
+		}
+		for _, c := range n.Children {
+			walk(c, world)
+		}
+	}
+	for _, r := range m.Roots {
+		walk(r, glm.Identity())
+	}
+}
+
+// Load reads a .gltf or .glb file. External files are looked for relative
+// to the directory of the file.
+func Load(filename string) (*Model, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data, filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return m, nil
+}
+
+const (
+	glbMagic     = 0x46546c67 // "glTF"
+	glbChunkJSON = 0x4e4f534a // "JSON"
+	glbChunkBIN  = 0x004e4942 // "BIN\0"
+)
+
+// decoder holds the state while reading a file.
+type decoder struct {
+	doc     jDocument
+	buffers [][]byte
+	dir     string
+}
+
+// Parse reads a model from the contents of a .gltf or .glb file. External
+// files are looked for relative to dir.
+func Parse(data []byte, dir string) (*Model, error) {
+	d := &decoder{dir: dir}
+
+	var bin []byte
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		var err error
+		data, bin, err = splitGLB(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, &d.doc); err != nil {
+		return nil, err
+	}
+
+	for i, b := range d.doc.Buffers {
+		var buf []byte
+		var err error
+		if b.URI == "" {
+			// the binary chunk of a .glb file
+			if i != 0 || bin == nil {
+				return nil, fmt.Errorf("buffer %d has no data", i)
+			}
+			buf = bin
+		} else if buf, err = d.load(b.URI); err != nil {
+			return nil, err
+		}
+		if len(buf) < b.ByteLength {
+			return nil, fmt.Errorf("buffer %d is too short", i)
+		}
+		d.buffers = append(d.buffers, buf)
+	}
+
+	return d.model()
+}
+
+// splitGLB returns the JSON and binary chunks of a .glb file.
+func splitGLB(data []byte) (jsonChunk, binChunk []byte, err error) {
+	le := binary.LittleEndian
+	if v := le.Uint32(data[4:]); v != 2 {
+		return nil, nil, fmt.Errorf("unsupported GLB version %d", v)
+	}
+	length := int(le.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, fmt.Errorf("GLB file is truncated")
+	}
+	for p := 12; p+8 <= length; {
+		size := int(le.Uint32(data[p:]))
+		typ := le.Uint32(data[p+4:])
+		p += 8
+		if size < 0 || p+size > length {
+			return nil, nil, fmt.Errorf("GLB chunk is truncated")
+		}
+		switch typ {
+		case glbChunkJSON:
+			jsonChunk = data[p : p+size]
+		case glbChunkBIN:
+			binChunk = data[p : p+size]
+		}
+		p += size
+	}
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("GLB file has no JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+// load returns the contents of a data URI, or of a file relative to the
+// directory of the model.
+func (d *decoder) load(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		i := strings.Index(uri, ";base64,")
+		if i < 0 {
+			return nil, fmt.Errorf("unsupported data URI")
+		}
+		return base64.StdEncoding.DecodeString(uri[i+8:])
+	}
+	return os.ReadFile(filepath.Join(d.dir, filepath.FromSlash(uri)))
+}
+
+func (d *decoder) model() (*Model, error) {
+	m := &Model{}
+
+	for i, img := range d.doc.Images {
+		var data []byte
+		var err error
+		if img.BufferView != nil {
+			data, _, err = d.bufferView(*img.BufferView)
+		} else {
+			data, err = d.load(img.URI)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %v", i, err)
+		}
+		rgba, err := asset.DecodeImage(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %v", i, err)
+		}
+		m.Images = append(m.Images, rgba)
+	}
+
+	for _, jm := range d.doc.Materials {
+		mat := DefaultMaterial
+		mat.Name = jm.Name
+		pbr := jm.PbrMetallicRoughness
+		copy(mat.BaseColor[:], pbr.BaseColorFactor)
+		copy(mat.Emissive[:], jm.EmissiveFactor)
+		if pbr.MetallicFactor != nil {
+			mat.Metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			mat.Roughness = *pbr.RoughnessFactor
+		}
+		if t := pbr.BaseColorTexture; t != nil && t.Index >= 0 && t.Index < len(d.doc.Textures) {
+			if src := d.doc.Textures[t.Index].Source; src != nil && *src >= 0 && *src < len(m.Images) {
+				mat.BaseColorTexture = *src
+			}
+		}
+		mat.DoubleSided = jm.DoubleSided
+		m.Materials = append(m.Materials, mat)
+	}
+
+	for i, jm := range d.doc.Meshes {
+		ms := Mesh{Name: jm.Name}
+		for j, jp := range jm.Primitives {
+			if jp.Mode != nil && *jp.Mode != 4 {
+				continue // not triangles
+			}
+			p, err := d.primitive(jp)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d, primitive %d: %v", i, j, err)
+			}
+			if p.Material >= len(m.Materials) {
+				p.Material = -1
+			}
+			ms.Primitives = append(ms.Primitives, p)
+		}
+		m.Meshes = append(m.Meshes, ms)
+	}
+
+	for i, jn := range d.doc.Nodes {
+		n := Node{
+			Name:     jn.Name,
+			Children: jn.Children,
+			Mesh:     -1,
+			Skin:     -1,
+		}
+		n.Transform, n.Local, n.matrix = nodeTransform(jn)
+		if jn.Mesh != nil && *jn.Mesh >= 0 && *jn.Mesh < len(m.Meshes) {
+			n.Mesh = *jn.Mesh
+		}
+		if jn.Skin != nil && *jn.Skin >= 0 && *jn.Skin < len(d.doc.Skins) {
+			n.Skin = *jn.Skin
+		}
+		for _, c := range n.Children {
+			if c < 0 || c >= len(d.doc.Nodes) {
+				return nil, fmt.Errorf("node %d: child %d does not exist", i, c)
+			}
+		}
+		m.Nodes = append(m.Nodes, n)
+	}
+
+	scene := 0
+	if d.doc.Scene != nil {
+		scene = *d.doc.Scene
+	}
+	if scene >= 0 && scene < len(d.doc.Scenes) {
+		m.Roots = d.doc.Scenes[scene].Nodes
+	} else {
+		// no scenes: all nodes that are not a child are roots
+		child := make([]bool, len(m.Nodes))
+		for _, n := range m.Nodes {
+			for _, c := range n.Children {
+				child[c] = true
+			}
+		}
+		for i := range m.Nodes {
+			if !child[i] {
+				m.Roots = append(m.Roots, i)
+			}
+		}
+	}
+	for _, r := range m.Roots {
+		if r < 0 || r >= len(m.Nodes) {
+			return nil, fmt.Errorf("scene %d: node %d does not exist", scene, r)
+		}
+	}
+
+	for i := range d.doc.Skins {
+		s, err := d.skin(i)
+		if err != nil {
+			return nil, err
+		}
+		m.Skins = append(m.Skins, s)
+	}
+	for i := range d.doc.Animations {
+		a, err := d.animation(i)
+		if err != nil {
+			return nil, err
+		}
+		m.Animations = append(m.Animations, a)
+	}
+
+	return m, nil
+}
+
+func (d *decoder) primitive(jp jPrimitive) (Primitive, error) {
+	p := Primitive{
+		Mesh:     &mesh.Mesh{},
+		Material: -1,
+	}
+	if jp.Material != nil {
+		p.Material = *jp.Material
+	}
+
+	pos, ok := jp.Attributes["POSITION"]
+	if !ok {
+		return p, fmt.Errorf("no positions")
+	}
+	positions, n, err := d.accessor(pos)
+	if err != nil {
+		return p, err
+	}
+	if n != 3 {
+		return p, fmt.Errorf("positions are not VEC3")
+	}
+	count := len(positions) / 3
+	p.Mesh.Vertices = make([]mesh.Vertex, count)
+	for i := range p.Mesh.Vertices {
+		copy(p.Mesh.Vertices[i].Position[:], positions[3*i:])
+	}
+
+	// the other attributes are copied as far as they fit
+	for _, attr := range []struct {
+		name string
+		dst  func(v *mesh.Vertex) []float32
+	}{
+		{"NORMAL", func(v *mesh.Vertex) []float32 { return v.Normal[:] }},
+		{"TEXCOORD_0", func(v *mesh.Vertex) []float32 { return v.UV[:] }},
+		{"TANGENT", func(v *mesh.Vertex) []float32 { return v.Tangent[:] }},
+	} {
+		a, ok := jp.Attributes[attr.name]
+		if !ok {
+			continue
+		}
+		values, n, err := d.accessor(a)
+		if err != nil {
+			return p, err
+		}
+		if len(values)/n != count {
+			return p, fmt.Errorf("%s has a different count than POSITION", attr.name)
+		}
+		for i := range p.Mesh.Vertices {
+			copy(attr.dst(&p.Mesh.Vertices[i]), values[n*i:n*i+n])
+		}
+	}
+
+	if j, ok := jp.Attributes["JOINTS_0"]; ok {
+		w, ok := jp.Attributes["WEIGHTS_0"]
+		if !ok {
+			return p, fmt.Errorf("JOINTS_0 without WEIGHTS_0")
+		}
+		joints, n1, err := d.accessor(j)
+		if err != nil {
+			return p, err
+		}
+		weights, n2, err := d.accessor(w)
+		if err != nil {
+			return p, err
+		}
+		if n1 != 4 || n2 != 4 || len(joints) != 4*count || len(weights) != 4*count {
+			return p, fmt.Errorf("JOINTS_0 and WEIGHTS_0 must be VEC4 with the same count as POSITION")
+		}
+		p.Joints = make([][4]uint16, count)
+		p.Weights = make([]glm.Vec4, count)
+		for i := 0; i < count; i++ {
+			for k := 0; k < 4; k++ {
+				p.Joints[i][k] = uint16(joints[4*i+k])
+				p.Weights[i][k] = weights[4*i+k]
+			}
+		}
+	}
+
+	if jp.Indices != nil {
+		if p.Mesh.Indices, err = d.indices(*jp.Indices); err != nil {
+			return p, err
+		}
+		for _, i := range p.Mesh.Indices {
+			if int(i) >= count {
+				return p, fmt.Errorf("index %d out of range", i)
+			}
+		}
+	} else {
+		p.Mesh.Indices = make([]uint32, count)
+		for i := range p.Mesh.Indices {
+			p.Mesh.Indices[i] = uint32(i)
+		}
+	}
+
+	if _, ok := jp.Attributes["NORMAL"]; !ok {
+		p.Mesh.ComputeNormals()
+	}
+	return p, nil
+}
+
+// nodeTransform returns the transformation of a node, as translation,
+// rotation and scale, and as a matrix. matrix is true if the file gave a
+// matrix, and the translation, rotation and scale were not set.
+func nodeTransform(n jNode) (t Transform, local glm.Mat4, matrix bool) {
+	t = Transform{
+		Rotation: glm.Quat{0, 0, 0, 1},
+		Scale:    glm.Vec3{1, 1, 1},
+	}
+	if len(n.Matrix) == 16 {
+		copy(local[:], n.Matrix)
+		return t, local, true
+	}
+	copy(t.Translation[:], n.Translation)
+	copy(t.Rotation[:], n.Rotation)
+	copy(t.Scale[:], n.Scale)
+	return t, t.Mat4(), false
+}
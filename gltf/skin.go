@@ -0,0 +1,109 @@
+package gltf
+
+import (
+	"github.com/pebbe/gl/glm"
+
+	"fmt"
+)
+
+// Transform is the transformation of a node as translation, rotation and
+// scale, the parts that can be animated.
+type Transform struct {
+	Translation glm.Vec3
+	Rotation    glm.Quat
+	Scale       glm.Vec3
+}
+
+// Mat4 returns the transformation as a matrix: first scale, then rotate,
+// then translate.
+func (t Transform) Mat4() glm.Mat4 {
+	return glm.Translate(t.Translation[0], t.Translation[1], t.Translation[2]).
+		Mul(t.Rotation.Mat4()).
+		Mul(glm.Scale(t.Scale[0], t.Scale[1], t.Scale[2]))
+}
+
+// Pose has a transformation for each node of a model.
+type Pose []Transform
+
+// Skin is a set of joints, nodes that deform the vertices of a skinned mesh.
+type Skin struct {
+	Name        string
+	Joints      []int      // indices into Model.Nodes
+	InverseBind []glm.Mat4 // for each joint, from model space to the space of the joint
+}
+
+// RestPose returns the transformations of the nodes as given in the file.
+func (m *Model) RestPose() Pose {
+	p := make(Pose, len(m.Nodes))
+	for i, n := range m.Nodes {
+		p[i] = n.Transform
+	}
+	return p
+}
+
+// WorldMatrices returns for each node its transformation relative to the
+// root of the scene, with the nodes in pose p. If p is nil, the
+// transformations of the file are used. Nodes that were given as a matrix
+// are not animated.
+func (m *Model) WorldMatrices(p Pose) []glm.Mat4 {
+	world := make([]glm.Mat4, len(m.Nodes))
+	var walk func(i int, parent glm.Mat4)
+	walk = func(i int, parent glm.Mat4) {
+		n := &m.Nodes[i]
+		local := n.Local
+		if p != nil && !n.matrix {
+			local = p[i].Mat4()
+		}
+		world[i] = parent.Mul(local)
+		for _, c := range n.Children {
+			walk(c, world[i])
+		}
+	}
+	for _, r := range m.Roots {
+		walk(r, glm.Identity())
+	}
+	return world
+}
+
+// JointMatrices returns the matrices for the joints of skin s, for the
+// skinned mesh of node. world is the result of WorldMatrices. A vertex is
+// moved by the weighted sum of the matrices of its joints. The result
+// includes the inverse of the node transformation, so the mesh should be
+// drawn with the model matrix of the node.
+func (m *Model) JointMatrices(s *Skin, world []glm.Mat4, node int) []glm.Mat4 {
+	inv := world[node].Inverse()
+	joints := make([]glm.Mat4, len(s.Joints))
+	for i, j := range s.Joints {
+		joints[i] = inv.Mul(world[j]).Mul(s.InverseBind[i])
+	}
+	return joints
+}
+
+// skin returns skin i from the file.
+func (d *decoder) skin(i int) (Skin, error) {
+	js := d.doc.Skins[i]
+	s := Skin{
+		Name:        js.Name,
+		Joints:      js.Joints,
+		InverseBind: make([]glm.Mat4, len(js.Joints)),
+	}
+	for j, n := range js.Joints {
+		if n < 0 || n >= len(d.doc.Nodes) {
+			return s, fmt.Errorf("skin %d: node %d does not exist", i, n)
+		}
+		s.InverseBind[j] = glm.Identity()
+	}
+	if js.InverseBindMatrices != nil {
+		values, n, err := d.accessor(*js.InverseBindMatrices)
+		if err != nil {
+			return s, fmt.Errorf("skin %d: %v", i, err)
+		}
+		if n != 16 || len(values) < 16*len(js.Joints) {
+			return s, fmt.Errorf("skin %d: inverse bind matrices must be MAT4, one for each joint", i)
+		}
+		for j := range s.InverseBind {
+			copy(s.InverseBind[j][:], values[16*j:])
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/gltf"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 uv;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+out vec2 texcoord;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+    texcoord = uv;
+}
+`
+
+	// An approximation of the metallic-roughness model with Blinn-Phong:
+	// metals have no diffuse light and colored reflections, rough surfaces
+	// have wide highlights.
+	fragment_glsl = `
+#version 330 core
+
+struct Material {
+    vec4 baseColor;
+    float metallic;
+    float roughness;
+    vec3 emissive;
+    bool hasTexture;
+};
+
+uniform Material material;
+uniform sampler2D baseColorMap;
+uniform vec3 eye;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+in vec2 texcoord;
+
+out vec4 fragColor;
+
+const vec3 lightDirection = normalize(vec3(0.4, 1.0, 0.6));
+
+void main()
+{
+    vec3 base = material.baseColor.rgb;
+    if (material.hasTexture) {
+        base *= texture(baseColorMap, texcoord).rgb;
+    }
+    vec3 n = normalize(worldNormal);
+    if (!gl_FrontFacing) {
+        n = -n;
+    }
+    vec3 v = normalize(eye - worldPosition);
+    vec3 h = normalize(lightDirection + v);
+
+    vec3 diffuseColor = base * (1.0 - material.metallic);
+    vec3 specularColor = mix(vec3(0.04), base, material.metallic);
+    float r = max(material.roughness, 0.05);
+    float shininess = 2.0 / (r * r * r * r) - 2.0;
+
+    float diffuse = max(dot(n, lightDirection), 0.0);
+    float specular = diffuse > 0.0 ? pow(max(dot(n, h), 0.0), shininess) * (shininess + 8.0) / 8.0 : 0.0;
+
+    vec3 c = 0.2 * base + diffuse * diffuseColor + specular * specularColor + material.emissive;
+    fragColor = vec4(c, 1.0);
+}
+`
+)
+
+var model = flag.String("model", "", "glTF or GLB file (default: a built-in model)")
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program  *glutil.Program
+	model    *gltf.Model
+	vaos     [][]*mesh.VAO // for each mesh, for each primitive
+	textures []uint32      // for each image
+}
+
+// defaultModel returns a .gltf file with two pyramids, one on top of the
+// other, with the vertices in a data URI.
+func defaultModel() []byte {
+	apex := [3]float32{0, 1, 0}
+	corners := [][3]float32{{-1, 0, 1}, {1, 0, 1}, {1, 0, -1}, {-1, 0, -1}}
+	var positions [][3]float32
+	for i := range corners {
+		positions = append(positions, corners[i], corners[(i+1)%4], apex)
+	}
+	positions = append(positions, corners[3], corners[2], corners[1], corners[3], corners[1], corners[0])
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, positions)
+
+	return []byte(fmt.Sprintf(`{
+    "asset": {"version": "2.0"},
+    "scene": 0,
+    "scenes": [{"nodes": [0]}],
+    "nodes": [
+        {"name": "bottom", "mesh": 0, "children": [1]},
+        {"name": "top", "mesh": 1, "translation": [0, 1.2, 0], "rotation": [0, 0.3827, 0, 0.9239], "scale": [0.5, 0.5, 0.5]}
+    ],
+    "meshes": [
+        {"primitives": [{"attributes": {"POSITION": 0}, "material": 0}]},
+        {"primitives": [{"attributes": {"POSITION": 0}, "material": 1}]}
+    ],
+    "materials": [
+        {"name": "gold", "pbrMetallicRoughness": {"baseColorFactor": [1, 0.8, 0.3, 1], "metallicFactor": 1, "roughnessFactor": 0.3}},
+        {"name": "plastic", "pbrMetallicRoughness": {"baseColorFactor": [0.2, 0.4, 0.9, 1], "metallicFactor": 0, "roughnessFactor": 0.5}}
+    ],
+    "accessors": [{"bufferView": 0, "componentType": 5126, "count": %d, "type": "VEC3"}],
+    "bufferViews": [{"buffer": 0, "byteLength": %d}],
+    "buffers": [{"byteLength": %d, "uri": "data:application/octet-stream;base64,%s"}]
+}`, len(positions), buf.Len(), buf.Len(), base64.StdEncoding.EncodeToString(buf.Bytes())))
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	if *model != "" {
+		r.model, err = gltf.Load(*model)
+	} else {
+		r.model, err = gltf.Parse(defaultModel(), "")
+	}
+	x(err)
+
+	for _, m := range r.model.Meshes {
+		vaos := make([]*mesh.VAO, len(m.Primitives))
+		for i, p := range m.Primitives {
+			vaos[i] = p.Mesh.Upload()
+		}
+		r.vaos = append(r.vaos, vaos)
+	}
+	for _, img := range r.model.Images {
+		texture := glutil.MakeTextureFromImage(img)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+		r.textures = append(r.textures, texture)
+	}
+
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+// bounds returns the center of the scene, and the radius of a sphere
+// around it that contains all vertices.
+func bounds(m *gltf.Model) (center glm.Vec3, radius float32) {
+	inf := float32(math.Inf(1))
+	lo, hi := glm.Vec3{inf, inf, inf}, glm.Vec3{-inf, -inf, -inf}
+	m.Walk(func(n *gltf.Node, world glm.Mat4) {
+		for _, p := range m.Meshes[n.Mesh].Primitives {
+			for _, v := range p.Mesh.Vertices {
+				q := world.Transform(v.Position)
+				for i := 0; i < 3; i++ {
+					lo[i] = float32(math.Min(float64(lo[i]), float64(q[i])))
+					hi[i] = float32(math.Max(float64(hi[i]), float64(q[i])))
+				}
+			}
+		}
+	})
+	if lo[0] > hi[0] {
+		return glm.Vec3{}, 1
+	}
+	center = lo.Add(hi).Mul(.5)
+	radius = hi.Sub(center).Len()
+	if radius == 0 {
+		radius = 1
+	}
+	return
+}
+
+//
+// Update and render
+//
+
+var cam *camera.Orbit
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+
+	glutil.State{DepthTest: true}.Apply()
+	gl.ClearColor(app.Background(.2, .2, .25, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	near := cam.Distance / 100
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), near, near*10000))
+	p.SetVec3("eye", cam.Eye())
+	p.SetInt("baseColorMap", 0)
+
+	r.model.Walk(func(n *gltf.Node, world glm.Mat4) {
+		p.SetMat4("model", world)
+		p.SetMat3("normalMatrix", world.NormalMatrix())
+		for i, prim := range r.model.Meshes[n.Mesh].Primitives {
+			m := gltf.DefaultMaterial
+			if prim.Material >= 0 {
+				m = r.model.Materials[prim.Material]
+			}
+			p.SetVec4("material.baseColor", m.BaseColor)
+			p.SetFloat("material.metallic", m.Metallic)
+			p.SetFloat("material.roughness", m.Roughness)
+			p.SetVec3("material.emissive", m.Emissive)
+			p.SetBool("material.hasTexture", m.BaseColorTexture >= 0)
+			if m.BaseColorTexture >= 0 {
+				gl.BindTexture(gl.TEXTURE_2D, r.textures[m.BaseColorTexture])
+			}
+			glutil.State{DepthTest: true, CullFace: !m.DoubleSided}.Apply()
+			r.vaos[n.Mesh][i].Draw()
+		}
+	})
+}
+
+func main() {
+	app.Flags(800, 600, "glTF viewer")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	center, radius := bounds(r.model)
+	cam = camera.NewOrbit(center, 2.5*radius)
+	cam.Pitch = .3
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	app.Infof("%d nodes, %d meshes, %d materials, %d images", len(r.model.Nodes), len(r.model.Meshes), len(r.model.Materials), len(r.model.Images))
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glm"
+
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// MakeCubemap creates a cube map texture from six square images of the
+// same size, in the order +x, -x, +y, -y, +z, -z.
+func MakeCubemap(faces [6]image.Image) (texture uint32, err error) {
+	if tracing {
+		defer trace("MakeCubemap", faces[0].Bounds()).done(&texture, &err)
+	}
+	size := faces[0].Bounds().Size()
+	for i, face := range faces {
+		s := face.Bounds().Size()
+		if s.X != s.Y || s != size {
+			return 0, fmt.Errorf("cube map face %d is %dx%d, should be %dx%d", i, s.X, s.Y, size.X, size.X)
+		}
+	}
+
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, texture)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	for i, face := range faces {
+		rgba := asset.ToRGBA(face)
+		gl.TexImage2D(
+			gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(i), 0, // target, level
+			gl.RGBA8,                  // internal format
+			int32(size.X),             // width
+			int32(size.Y),             // height
+			0,                         // border
+			gl.RGBA, gl.UNSIGNED_BYTE, // external format, type
+			gl.Ptr(rgba.Pix)) // pixels
+		app.CountUpload(len(rgba.Pix))
+	}
+	labelCaller(gl.TEXTURE, texture, "cube map")
+
+	// filter across the edges between faces
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+
+	return texture, nil
+}
+
+// MakeCubemapFromFiles creates a cube map texture from six image files, in
+// the order +x, -x, +y, -y, +z, -z. Files that don't exist are replaced by
+// placeholders, as in MakeTexture.
+func MakeCubemapFromFiles(filenames [6]string) (texture uint32, err error) {
+	if tracing {
+		defer trace("MakeCubemapFromFiles", filenames).done(&texture, &err)
+	}
+	var faces [6]image.Image
+	var missing []int
+	size := 256
+	for i, filename := range filenames {
+		img, err := asset.LoadImage(filename)
+		if os.IsNotExist(err) {
+			app.Warnf("%v, using a placeholder", err)
+			missing = append(missing, i)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		faces[i] = img
+		size = img.Bounds().Dx()
+	}
+	// placeholders of the size of the faces that do exist
+	for _, i := range missing {
+		faces[i] = asset.Placeholder(filepath.Base(filenames[i]), size, size)
+	}
+	return MakeCubemap(faces)
+}
+
+// MakeEmptyCubemap creates a cube map texture of size x size pixels per
+// face, without data, to render into with RenderToCubemap. With mipmaps,
+// storage is allocated for all mipmap levels, and the minification filter
+// uses them.
+func MakeEmptyCubemap(size int32, format Format, mipmaps bool) (texture uint32) {
+	if tracing {
+		defer trace("MakeEmptyCubemap", size, enum(format.Internal), mipmaps).done(&texture)
+	}
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, texture)
+	if mipmaps {
+		gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	} else {
+		gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	for level, s := int32(0), size; s > 0; level, s = level+1, s/2 {
+		for i := uint32(0); i < 6; i++ {
+			gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+i, level, format.Internal, s, s, 0, format.Format, format.Type, nil)
+		}
+		if !mipmaps {
+			break
+		}
+	}
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+	labelCaller(gl.TEXTURE, texture, "cube map")
+	return texture
+}
+
+// CubemapViews are the view matrices for rendering the faces of a cube map,
+// in the order +x, -x, +y, -y, +z, -z, from the origin, to be used with
+// CubemapProjection.
+var CubemapViews = [6]glm.Mat4{
+	glm.LookAt(glm.Vec3{}, glm.Vec3{1, 0, 0}, glm.Vec3{0, -1, 0}),
+	glm.LookAt(glm.Vec3{}, glm.Vec3{-1, 0, 0}, glm.Vec3{0, -1, 0}),
+	glm.LookAt(glm.Vec3{}, glm.Vec3{0, 1, 0}, glm.Vec3{0, 0, 1}),
+	glm.LookAt(glm.Vec3{}, glm.Vec3{0, -1, 0}, glm.Vec3{0, 0, -1}),
+	glm.LookAt(glm.Vec3{}, glm.Vec3{0, 0, 1}, glm.Vec3{0, -1, 0}),
+	glm.LookAt(glm.Vec3{}, glm.Vec3{0, 0, -1}, glm.Vec3{0, -1, 0}),
+}
+
+// CubemapProjection is the projection for rendering a face of a cube map: a
+// 90 degree field of view with a square aspect ratio.
+var CubemapProjection = glm.Perspective(glm.Radians(90), 1, .1, 10)
+
+// RenderToCubemap calls draw for each face of a cube map texture, with the
+// face attached to a temporary framebuffer as the render target at mipmap
+// level, and the viewport set to the size of that level. size is the size
+// of level 0. draw gets the index of the face into CubemapViews. There is
+// no depth buffer.
+func RenderToCubemap(texture uint32, size, level int32, draw func(face int)) (err error) {
+	if tracing {
+		defer trace("RenderToCubemap", texture, size, level).done(&err)
+	}
+	var fbo uint32
+	gl.GenFramebuffers(1, &fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	labelCaller(gl.FRAMEBUFFER, fbo, "cube map framebuffer")
+	defer func() {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		gl.DeleteFramebuffers(1, &fbo)
+	}()
+
+	s := size >> uint(level)
+	if s < 1 {
+		s = 1
+	}
+	gl.Viewport(0, 0, s, s)
+	for face := 0; face < 6; face++ {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(face), texture, level)
+		if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+			return fmt.Errorf("framebuffer incomplete: 0x%x", status)
+		}
+		draw(face)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Whether the functions of GL_KHR_debug can be called: 0 not known yet,
+// 1 yes, -1 no. This is checked once, for the first context, as the demos
+// that use more than one context use the same driver for all of them.
+var canDebug int
+
+// hasDebug reports whether labels and debug groups are available.
+func hasDebug() bool {
+	if canDebug == 0 {
+		canDebug = -1
+		if VersionAtLeast(4, 3) || Has("GL_KHR_debug") {
+			canDebug = 1
+		}
+	}
+	return canDebug > 0
+}
+
+// Label gives an object a name that shows up in frame debuggers such as
+// RenderDoc and apitrace. The identifier is the kind of object, such as
+// gl.BUFFER, gl.TEXTURE or gl.PROGRAM. It does nothing without OpenGL 4.3
+// or the extension GL_KHR_debug.
+//
+// The objects made by this package are given a name with the kind of
+// object and where in the program it was made, such as
+// "texture, terrain/terrain.go:264".
+func Label(identifier, name uint32, label string) {
+	if tracing {
+		defer trace("Label", enum(identifier), name, label).done()
+	}
+	if !hasDebug() {
+		return
+	}
+	gl.ObjectLabel(identifier, name, -1, gl.Str(label+"\x00"))
+}
+
+// labelCaller labels an object made by this package with its kind, and
+// the file and line of the first caller outside this package, and
+// registers it for ReportLeaks.
+func labelCaller(identifier, name uint32, kind string) {
+	track(identifier, name, kind)
+	if !hasDebug() {
+		return
+	}
+	pc := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/pebbe/gl/glutil.") {
+			// the directory is the name of the demo or package
+			file := filepath.Join(filepath.Base(filepath.Dir(frame.File)), filepath.Base(frame.File))
+			kind = fmt.Sprintf("%s, %s:%d", kind, filepath.ToSlash(file), frame.Line)
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	Label(identifier, name, kind)
+}
+
+// DebugGroup starts a group of commands, such as a render pass, that frame
+// debuggers show under the name, and returns the function that ends it:
+//
+//	defer glutil.DebugGroup("shadow pass")()
+//
+// Groups can be nested. It does nothing without OpenGL 4.3 or the
+// extension GL_KHR_debug.
+func DebugGroup(name string) (end func()) {
+	if tracing {
+		defer trace("DebugGroup", name).done()
+	}
+	if !hasDebug() {
+		return func() {}
+	}
+	gl.PushDebugGroup(gl.DEBUG_SOURCE_APPLICATION, 0, -1, gl.Str(name+"\x00"))
+	return func() {
+		if tracing {
+			defer trace("DebugGroup end", name).done()
+		}
+		gl.PopDebugGroup()
+	}
+}
+
+// shaderKind is a name for a type of shader, for its label.
+func shaderKind(shaderType uint32) string {
+	switch shaderType {
+	case gl.VERTEX_SHADER:
+		return "vertex shader"
+	case gl.FRAGMENT_SHADER:
+		return "fragment shader"
+	case gl.GEOMETRY_SHADER:
+		return "geometry shader"
+	case gl.TESS_CONTROL_SHADER:
+		return "tessellation control shader"
+	case gl.TESS_EVALUATION_SHADER:
+		return "tessellation evaluation shader"
+	case gl.COMPUTE_SHADER:
+		return "compute shader"
+	}
+	return "shader"
+}
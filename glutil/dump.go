@@ -0,0 +1,240 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// Snapshot is the part of the OpenGL state that most often explains why
+// nothing is drawn, as written by DumpState. Objects are given by their
+// names, 0 for none.
+type Snapshot struct {
+	Program     uint32 `json:"program"`
+	VertexArray uint32 `json:"vertexArray"`
+
+	ArrayBuffer   uint32 `json:"arrayBuffer"`
+	ElementBuffer uint32 `json:"elementBuffer"` // of the vertex array
+	UniformBuffer uint32 `json:"uniformBuffer"`
+
+	DrawFramebuffer uint32 `json:"drawFramebuffer"`
+	ReadFramebuffer uint32 `json:"readFramebuffer"`
+
+	ActiveTexture int               `json:"activeTexture"` // unit
+	Textures      []SnapshotTexture `json:"textures"`      // bound to any unit
+
+	Viewport [4]int32  `json:"viewport"`
+	Scissor  *[4]int32 `json:"scissor"` // nil without scissor test
+
+	DepthTest  bool    `json:"depthTest"`
+	DepthFunc  string  `json:"depthFunc"`
+	DepthWrite bool    `json:"depthWrite"`
+	ColorWrite [4]bool `json:"colorWrite"`
+
+	Blend    bool      `json:"blend"`
+	BlendRGB [2]string `json:"blendRGB"` // source and destination factors
+	BlendA   [2]string `json:"blendAlpha"`
+
+	CullFace  bool   `json:"cullFace"`
+	CullMode  string `json:"cullMode"`
+	FrontFace string `json:"frontFace"`
+
+	StencilTest bool `json:"stencilTest"`
+
+	// Enabled attributes of the vertex array.
+	Attributes []SnapshotAttribute `json:"attributes"`
+
+	// The first error, if any. Reading it clears it.
+	Error string `json:"error,omitempty"`
+}
+
+// SnapshotTexture is a texture bound to a texture unit.
+type SnapshotTexture struct {
+	Unit    int    `json:"unit"`
+	Target  string `json:"target"`
+	Texture uint32 `json:"texture"`
+}
+
+// SnapshotAttribute is an enabled vertex attribute.
+type SnapshotAttribute struct {
+	Location   uint32  `json:"location"`
+	Buffer     uint32  `json:"buffer"`
+	Size       int32   `json:"size"`
+	Type       string  `json:"type"`
+	Normalized bool    `json:"normalized"`
+	Stride     int32   `json:"stride"`
+	Offset     uintptr `json:"offset"`
+	Divisor    int32   `json:"divisor"`
+}
+
+// Names of the constants that show up in a snapshot.
+var enumNames = map[int32]string{
+	gl.NEVER:    "NEVER",
+	gl.LESS:     "LESS",
+	gl.EQUAL:    "EQUAL",
+	gl.LEQUAL:   "LEQUAL",
+	gl.GREATER:  "GREATER",
+	gl.NOTEQUAL: "NOTEQUAL",
+	gl.GEQUAL:   "GEQUAL",
+	gl.ALWAYS:   "ALWAYS",
+
+	gl.ZERO:                     "ZERO",
+	gl.ONE:                      "ONE",
+	gl.SRC_COLOR:                "SRC_COLOR",
+	gl.ONE_MINUS_SRC_COLOR:      "ONE_MINUS_SRC_COLOR",
+	gl.DST_COLOR:                "DST_COLOR",
+	gl.ONE_MINUS_DST_COLOR:      "ONE_MINUS_DST_COLOR",
+	gl.SRC_ALPHA:                "SRC_ALPHA",
+	gl.ONE_MINUS_SRC_ALPHA:      "ONE_MINUS_SRC_ALPHA",
+	gl.DST_ALPHA:                "DST_ALPHA",
+	gl.ONE_MINUS_DST_ALPHA:      "ONE_MINUS_DST_ALPHA",
+	gl.CONSTANT_COLOR:           "CONSTANT_COLOR",
+	gl.ONE_MINUS_CONSTANT_COLOR: "ONE_MINUS_CONSTANT_COLOR",
+
+	gl.FRONT:          "FRONT",
+	gl.BACK:           "BACK",
+	gl.FRONT_AND_BACK: "FRONT_AND_BACK",
+	gl.CW:             "CW",
+	gl.CCW:            "CCW",
+
+	gl.BYTE:           "BYTE",
+	gl.UNSIGNED_BYTE:  "UNSIGNED_BYTE",
+	gl.SHORT:          "SHORT",
+	gl.UNSIGNED_SHORT: "UNSIGNED_SHORT",
+	gl.INT:            "INT",
+	gl.UNSIGNED_INT:   "UNSIGNED_INT",
+	gl.HALF_FLOAT:     "HALF_FLOAT",
+	gl.FLOAT:          "FLOAT",
+
+	gl.INVALID_ENUM:                  "INVALID_ENUM",
+	gl.INVALID_VALUE:                 "INVALID_VALUE",
+	gl.INVALID_OPERATION:             "INVALID_OPERATION",
+	gl.INVALID_FRAMEBUFFER_OPERATION: "INVALID_FRAMEBUFFER_OPERATION",
+	gl.OUT_OF_MEMORY:                 "OUT_OF_MEMORY",
+}
+
+// enumName is the name of a constant, or its value in hexadecimal.
+func enumName(value int32) string {
+	if name, ok := enumNames[value]; ok {
+		return name
+	}
+	return enum(value).String()
+}
+
+// TakeSnapshot queries the OpenGL state.
+func TakeSnapshot() *Snapshot {
+	if tracing {
+		defer trace("TakeSnapshot").done()
+	}
+
+	integer := func(pname uint32) int32 {
+		var v int32
+		gl.GetIntegerv(pname, &v)
+		return v
+	}
+
+	s := &Snapshot{
+		Program:         uint32(integer(gl.CURRENT_PROGRAM)),
+		VertexArray:     uint32(integer(gl.VERTEX_ARRAY_BINDING)),
+		ArrayBuffer:     uint32(integer(gl.ARRAY_BUFFER_BINDING)),
+		ElementBuffer:   uint32(integer(gl.ELEMENT_ARRAY_BUFFER_BINDING)),
+		UniformBuffer:   uint32(integer(gl.UNIFORM_BUFFER_BINDING)),
+		DrawFramebuffer: uint32(integer(gl.DRAW_FRAMEBUFFER_BINDING)),
+		ReadFramebuffer: uint32(integer(gl.READ_FRAMEBUFFER_BINDING)),
+		ActiveTexture:   int(integer(gl.ACTIVE_TEXTURE) - gl.TEXTURE0),
+
+		DepthTest:   gl.IsEnabled(gl.DEPTH_TEST),
+		DepthFunc:   enumName(integer(gl.DEPTH_FUNC)),
+		DepthWrite:  integer(gl.DEPTH_WRITEMASK) != 0,
+		Blend:       gl.IsEnabled(gl.BLEND),
+		BlendRGB:    [2]string{enumName(integer(gl.BLEND_SRC_RGB)), enumName(integer(gl.BLEND_DST_RGB))},
+		BlendA:      [2]string{enumName(integer(gl.BLEND_SRC_ALPHA)), enumName(integer(gl.BLEND_DST_ALPHA))},
+		CullFace:    gl.IsEnabled(gl.CULL_FACE),
+		CullMode:    enumName(integer(gl.CULL_FACE_MODE)),
+		FrontFace:   enumName(integer(gl.FRONT_FACE)),
+		StencilTest: gl.IsEnabled(gl.STENCIL_TEST),
+	}
+
+	gl.GetIntegerv(gl.VIEWPORT, &s.Viewport[0])
+	if gl.IsEnabled(gl.SCISSOR_TEST) {
+		s.Scissor = new([4]int32)
+		gl.GetIntegerv(gl.SCISSOR_BOX, &s.Scissor[0])
+	}
+	gl.GetBooleanv(gl.COLOR_WRITEMASK, &s.ColorWrite[0])
+
+	// the bindings of each unit, which need it to be the active one
+	targets := []struct {
+		name    string
+		binding uint32
+	}{
+		{"TEXTURE_2D", gl.TEXTURE_BINDING_2D},
+		{"TEXTURE_3D", gl.TEXTURE_BINDING_3D},
+		{"TEXTURE_CUBE_MAP", gl.TEXTURE_BINDING_CUBE_MAP},
+	}
+	units := integer(gl.MAX_COMBINED_TEXTURE_IMAGE_UNITS)
+	for unit := int32(0); unit < units; unit++ {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+		for _, t := range targets {
+			if texture := integer(t.binding); texture != 0 {
+				s.Textures = append(s.Textures, SnapshotTexture{Unit: int(unit), Target: t.name, Texture: uint32(texture)})
+			}
+		}
+	}
+	gl.ActiveTexture(gl.TEXTURE0 + uint32(s.ActiveTexture))
+
+	// without a vertex array, the core profile has no attributes to query
+	if s.VertexArray != 0 {
+		attrib := func(location, pname uint32) int32 {
+			var v int32
+			gl.GetVertexAttribiv(location, pname, &v)
+			return v
+		}
+		for location := uint32(0); location < uint32(integer(gl.MAX_VERTEX_ATTRIBS)); location++ {
+			if attrib(location, gl.VERTEX_ATTRIB_ARRAY_ENABLED) == 0 {
+				continue
+			}
+			var offset unsafe.Pointer
+			gl.GetVertexAttribPointerv(location, gl.VERTEX_ATTRIB_ARRAY_POINTER, &offset)
+			s.Attributes = append(s.Attributes, SnapshotAttribute{
+				Location:   location,
+				Buffer:     uint32(attrib(location, gl.VERTEX_ATTRIB_ARRAY_BUFFER_BINDING)),
+				Size:       attrib(location, gl.VERTEX_ATTRIB_ARRAY_SIZE),
+				Type:       enumName(attrib(location, gl.VERTEX_ATTRIB_ARRAY_TYPE)),
+				Normalized: attrib(location, gl.VERTEX_ATTRIB_ARRAY_NORMALIZED) != 0,
+				Stride:     attrib(location, gl.VERTEX_ATTRIB_ARRAY_STRIDE),
+				Offset:     uintptr(offset),
+				Divisor:    attrib(location, gl.VERTEX_ATTRIB_ARRAY_DIVISOR),
+			})
+		}
+	}
+
+	if e := gl.GetError(); e != gl.NO_ERROR {
+		s.Error = enumName(int32(e))
+	}
+	return s
+}
+
+// DumpState writes a snapshot of the OpenGL state as JSON.
+func DumpState(w io.Writer) error {
+	data, err := json.MarshalIndent(TakeSnapshot(), "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// Demos that use this package dump the state to standard output when the
+// key for app.DumpState is pressed.
+func init() {
+	app.Handle(app.DumpState, func() {
+		if err := DumpState(os.Stdout); err != nil {
+			app.Error(err)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"fmt"
+	"unsafe"
+)
+
+// Strategy is the way a DynamicBuffer is updated.
+type Strategy int
+
+const (
+	// The data is copied into the buffer with gl.BufferSubData. If the GPU
+	// may still be drawing from the buffer, the driver has to wait for it,
+	// or make a copy.
+	SubData Strategy = iota
+
+	// The buffer is orphaned before each write, by gl.BufferData without
+	// data, so the driver can give it new storage while the GPU is still
+	// drawing from the old.
+	Orphan
+
+	// The buffer is mapped persistently, and divided into regions that are
+	// written in turn, with a fence for each region so it isn't overwritten
+	// while the GPU may still be reading from it. This needs OpenGL 4.4 or
+	// GL_ARB_buffer_storage, without them Orphan is used.
+	Persistent
+)
+
+var strategyNames = [...]string{"SubData", "Orphan", "Persistent"}
+
+func (s Strategy) String() string {
+	if s < 0 || int(s) >= len(strategyNames) {
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+	return strategyNames[s]
+}
+
+// Number of regions in a persistent buffer: while the GPU draws from one
+// region, the CPU writes into the next.
+const streamRegions = 3
+
+// DynamicBuffer is a buffer object for data that changes often, such as
+// every frame.
+type DynamicBuffer struct {
+	Buffer   uint32
+	Target   uint32
+	Size     int // size of one write, in bytes
+	Strategy Strategy
+
+	mapped unsafe.Pointer
+	region int
+	fences [streamRegions]uintptr
+}
+
+// StreamBuffer is a DynamicBuffer that is rewritten every frame, by the
+// fastest strategy available.
+type StreamBuffer = DynamicBuffer
+
+// NewDynamicBuffer creates a buffer for writes of at most size bytes, that
+// is updated with the given strategy. The buffer is left bound to target.
+func NewDynamicBuffer(target uint32, size int, strategy Strategy) *DynamicBuffer {
+	if tracing {
+		defer trace("NewDynamicBuffer", enum(target), size, strategy).done()
+	}
+	if strategy == Persistent && !VersionAtLeast(4, 4) && !Has("GL_ARB_buffer_storage") {
+		strategy = Orphan
+	}
+	b := &DynamicBuffer{
+		Target:   target,
+		Size:     size,
+		Strategy: strategy,
+	}
+	gl.GenBuffers(1, &b.Buffer)
+	gl.BindBuffer(target, b.Buffer)
+	labelCaller(gl.BUFFER, b.Buffer, "dynamic buffer")
+	switch strategy {
+	case SubData:
+		gl.BufferData(target, size, nil, gl.DYNAMIC_DRAW)
+	case Orphan:
+		gl.BufferData(target, size, nil, gl.STREAM_DRAW)
+	case Persistent:
+		flags := uint32(gl.MAP_WRITE_BIT | gl.MAP_PERSISTENT_BIT | gl.MAP_COHERENT_BIT)
+		gl.BufferStorage(target, streamRegions*size, nil, flags)
+		b.mapped = gl.MapBufferRange(target, 0, streamRegions*size, flags)
+	}
+	return b
+}
+
+// NewStreamBuffer creates a stream buffer for writes of at most size bytes.
+// It is mapped persistently, if possible. The buffer is left bound to
+// target.
+func NewStreamBuffer(target uint32, size int) *StreamBuffer {
+	return NewDynamicBuffer(target, size, Persistent)
+}
+
+// Persistent reports whether the buffer is mapped persistently.
+func (b *DynamicBuffer) Persistent() bool {
+	return b.Strategy == Persistent
+}
+
+// Write copies size bytes of data into the buffer, and returns the offset
+// in the buffer where the data starts, for use with gl.VertexAttribPointer
+// and the draw calls. Call Done after the draw calls that use the data.
+func (b *DynamicBuffer) Write(data unsafe.Pointer, size int) (offset int) {
+	if tracing {
+		defer trace("DynamicBuffer.Write", b.Buffer, size).done(&offset)
+	}
+	if size > b.Size {
+		panic("glutil: DynamicBuffer.Write: data too large")
+	}
+	app.CountUpload(size)
+	switch b.Strategy {
+	case SubData:
+		gl.BindBuffer(b.Target, b.Buffer)
+		gl.BufferSubData(b.Target, 0, size, data)
+		return 0
+	case Orphan:
+		gl.BindBuffer(b.Target, b.Buffer)
+		gl.BufferData(b.Target, b.Size, nil, gl.STREAM_DRAW)
+		gl.BufferSubData(b.Target, 0, size, data)
+		return 0
+	}
+
+	// wait until the GPU is done with this region
+	if fence := b.fences[b.region]; fence != 0 {
+		gl.ClientWaitSync(fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1e9)
+		gl.DeleteSync(fence)
+		b.fences[b.region] = 0
+	}
+	offset = b.region * b.Size
+	dst := (*[1 << 30]byte)(unsafe.Pointer(uintptr(b.mapped) + uintptr(offset)))[:size:size]
+	src := (*[1 << 30]byte)(data)[:size:size]
+	copy(dst, src)
+	return offset
+}
+
+// Done marks the end of the draw calls that use the data of the last Write.
+func (b *DynamicBuffer) Done() {
+	if tracing {
+		defer trace("DynamicBuffer.Done", b.Buffer).done()
+	}
+	if b.Strategy != Persistent {
+		return
+	}
+	b.fences[b.region] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	b.region = (b.region + 1) % streamRegions
+}
+
+// Delete deletes the buffer.
+func (b *DynamicBuffer) Delete() {
+	if tracing {
+		defer trace("DynamicBuffer.Delete", b.Buffer).done()
+	}
+	for i, fence := range b.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+			b.fences[i] = 0
+		}
+	}
+	if b.Strategy == Persistent {
+		gl.BindBuffer(b.Target, b.Buffer)
+		gl.UnmapBuffer(b.Target)
+	}
+	gl.DeleteBuffers(1, &b.Buffer)
+}
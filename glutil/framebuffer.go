@@ -0,0 +1,211 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+
+	"fmt"
+)
+
+// Framebuffer is a framebuffer object with one or more color textures and
+// a depth buffer, or with only a depth texture.
+type Framebuffer struct {
+	FBO          uint32
+	Texture      uint32   // first color attachment, if any
+	Textures     []uint32 // all color attachments, in order
+	Depth        uint32   // depth renderbuffer, if any
+	DepthTexture uint32   // depth texture, if any
+	Width        int32
+	Height       int32
+}
+
+// Format is the format of a color attachment.
+type Format struct {
+	Internal int32  // internal format, e.g. gl.RGBA8
+	Format   uint32 // format of pixel data, e.g. gl.RGBA
+	Type     uint32 // type of pixel data, e.g. gl.UNSIGNED_BYTE
+}
+
+var (
+	FormatRGBA8   = Format{gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE}
+	FormatRGBA16F = Format{gl.RGBA16F, gl.RGBA, gl.HALF_FLOAT}
+	FormatRGBA32F = Format{gl.RGBA32F, gl.RGBA, gl.FLOAT}
+
+	// single channel, read as the red component in a shader
+	FormatR8   = Format{gl.R8, gl.RED, gl.UNSIGNED_BYTE}
+	FormatR16F = Format{gl.R16F, gl.RED, gl.HALF_FLOAT}
+
+	FormatRG16F  = Format{gl.RG16F, gl.RG, gl.HALF_FLOAT}
+	FormatRG32F  = Format{gl.RG32F, gl.RG, gl.FLOAT}
+	FormatRGB16F = Format{gl.RGB16F, gl.RGB, gl.HALF_FLOAT}
+)
+
+// NewFramebuffer creates a framebuffer with an RGBA8 color texture and a
+// 24 bit depth buffer.
+func NewFramebuffer(width, height int32) (*Framebuffer, error) {
+	if tracing {
+		defer trace("NewFramebuffer", width, height).done()
+	}
+	return NewMultiFramebuffer(width, height, FormatRGBA8)
+}
+
+// NewMultiFramebuffer creates a framebuffer with a color texture for each
+// format, and a 24 bit depth buffer. Fragment shader output i goes to
+// attachment i, so outputs should be declared with
+// layout(location = i) out.
+func NewMultiFramebuffer(width, height int32, formats ...Format) (f *Framebuffer, err error) {
+	if tracing {
+		defer trace("NewMultiFramebuffer", width, height, formatsArg(formats)).done(&err)
+	}
+	f = &Framebuffer{
+		Width:    width,
+		Height:   height,
+		Textures: make([]uint32, len(formats)),
+	}
+
+	gl.GenFramebuffers(1, &f.FBO)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.FBO)
+	labelCaller(gl.FRAMEBUFFER, f.FBO, "framebuffer")
+
+	buffers := make([]uint32, len(formats))
+	for i, format := range formats {
+		gl.GenTextures(1, &f.Textures[i])
+		gl.BindTexture(gl.TEXTURE_2D, f.Textures[i])
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, format.Internal, width, height, 0, format.Format, format.Type, nil)
+		labelCaller(gl.TEXTURE, f.Textures[i], fmt.Sprintf("framebuffer color %d", i))
+
+		buffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, buffers[i], gl.TEXTURE_2D, f.Textures[i], 0)
+	}
+	if len(buffers) > 0 {
+		f.Texture = f.Textures[0]
+		gl.DrawBuffers(int32(len(buffers)), &buffers[0])
+	}
+
+	gl.GenRenderbuffers(1, &f.Depth)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, f.Depth)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, width, height)
+	labelCaller(gl.RENDERBUFFER, f.Depth, "framebuffer depth")
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, f.Depth)
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		f.Delete()
+		return nil, fmt.Errorf("framebuffer incomplete: 0x%x", status)
+	}
+
+	return f, nil
+}
+
+// NewDepthFramebuffer creates a framebuffer with only a 24 bit depth
+// texture, as used for shadow maps.
+//
+// The texture is set up for depth comparison: in a shader it is a
+// sampler2DShadow, and a lookup returns 1 where the reference value is
+// less than or equal to the stored depth, and 0 elsewhere, with linear
+// filtering between neighbouring texels. Outside the texture the depth
+// is 1, the far plane.
+func NewDepthFramebuffer(width, height int32) (f *Framebuffer, err error) {
+	if tracing {
+		defer trace("NewDepthFramebuffer", width, height).done(&err)
+	}
+	f = &Framebuffer{
+		Width:  width,
+		Height: height,
+	}
+
+	border := [4]float32{1, 1, 1, 1}
+	gl.GenTextures(1, &f.DepthTexture)
+	gl.BindTexture(gl.TEXTURE_2D, f.DepthTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+	gl.TexParameterfv(gl.TEXTURE_2D, gl.TEXTURE_BORDER_COLOR, &border[0])
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT24, width, height, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	labelCaller(gl.TEXTURE, f.DepthTexture, "framebuffer depth")
+
+	gl.GenFramebuffers(1, &f.FBO)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.FBO)
+	labelCaller(gl.FRAMEBUFFER, f.FBO, "depth framebuffer")
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, f.DepthTexture, 0)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		f.Delete()
+		return nil, fmt.Errorf("framebuffer incomplete: 0x%x", status)
+	}
+
+	return f, nil
+}
+
+// Bind makes the framebuffer the target for rendering, and sets the viewport
+// to its size.
+func (f *Framebuffer) Bind() {
+	if tracing {
+		defer trace("Framebuffer.Bind", f.FBO).done()
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.FBO)
+	gl.Viewport(0, 0, f.Width, f.Height)
+}
+
+// SetFilter sets the minification and magnification filter of the color
+// textures, e.g. gl.LINEAR for sampling a framebuffer of a different size.
+func (f *Framebuffer) SetFilter(filter int32) {
+	if tracing {
+		defer trace("Framebuffer.SetFilter", f.FBO, enum(filter)).done()
+	}
+	for _, texture := range f.Textures {
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+	}
+}
+
+// BlitDepth copies the depth buffer to the window, so that objects drawn
+// afterwards are hidden behind those drawn into the framebuffer. The window
+// must be the same size, with a depth buffer of the same format.
+func (f *Framebuffer) BlitDepth() {
+	if tracing {
+		defer trace("Framebuffer.BlitDepth", f.FBO).done()
+	}
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, f.FBO)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, 0)
+	gl.BlitFramebuffer(0, 0, f.Width, f.Height, 0, 0, f.Width, f.Height, gl.DEPTH_BUFFER_BIT, gl.NEAREST)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+}
+
+// Unbind makes the window the target for rendering again. The viewport is
+// not restored.
+func (f *Framebuffer) Unbind() {
+	if tracing {
+		defer trace("Framebuffer.Unbind", f.FBO).done()
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+}
+
+// Delete frees the framebuffer and its attachments.
+func (f *Framebuffer) Delete() {
+	if tracing {
+		defer trace("Framebuffer.Delete", f.FBO).done()
+	}
+	gl.DeleteFramebuffers(1, &f.FBO)
+	if len(f.Textures) > 0 {
+		gl.DeleteTextures(int32(len(f.Textures)), &f.Textures[0])
+	}
+	if f.Depth != 0 {
+		gl.DeleteRenderbuffers(1, &f.Depth)
+	}
+	if f.DepthTexture != 0 {
+		gl.DeleteTextures(1, &f.DepthTexture)
+	}
+}
@@ -0,0 +1,43 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+)
+
+// FullscreenVertexShader is a vertex shader for DrawFullscreen. It passes
+// texture coordinates uv, from 0, 0 in the lower left corner of the
+// viewport to 1, 1 in the upper right corner.
+const FullscreenVertexShader = `
+#version 330 core
+
+out vec2 uv;
+
+void main()
+{
+    // a triangle that covers the viewport: (-1, -1), (3, -1), (-1, 3)
+    vec2 p = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+    uv = p;
+    gl_Position = vec4(2.0 * p - 1.0, 0.0, 1.0);
+}
+`
+
+// An empty vertex array object, the vertices are generated in the shader.
+var fullscreenVAO uint32
+
+// DrawFullscreen draws a single triangle that covers the viewport, for use
+// with FullscreenVertexShader, with the current program.
+func DrawFullscreen() {
+	if tracing {
+		defer trace("DrawFullscreen").done()
+	}
+	if fullscreenVAO == 0 {
+		gl.GenVertexArrays(1, &fullscreenVAO)
+		gl.BindVertexArray(fullscreenVAO)
+		Label(gl.VERTEX_ARRAY, fullscreenVAO, "fullscreen triangle")
+	}
+	gl.BindVertexArray(fullscreenVAO)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	app.CountDrawCalls(1)
+	gl.BindVertexArray(0)
+}
@@ -0,0 +1,291 @@
+// Package glutil contains helpers for creating OpenGL objects, for use with
+// the core profile.
+//
+// All functions must be called from the thread that owns the GL context.
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// MakeBuffer creates a buffer object and fills it with data, with a usage
+// such as gl.STATIC_DRAW. The size in bytes is that of all elements of
+// data, which must be plain values, such as float32, glm.Vec3, or a struct
+// of those, without pointers. It panics if data is empty.
+func MakeBuffer[T any](target uint32, data []T, usage uint32) (buffer uint32) {
+	if len(data) == 0 {
+		panic("glutil: MakeBuffer: no data")
+	}
+	size := len(data) * int(unsafe.Sizeof(data[0]))
+	if tracing {
+		defer trace("MakeBuffer", enum(target), size, enum(usage)).done(&buffer)
+	}
+	gl.GenBuffers(1, &buffer)
+	gl.BindBuffer(target, buffer)
+	gl.BufferData(target, size, unsafe.Pointer(&data[0]), usage)
+	app.CountUpload(size)
+	labelCaller(gl.BUFFER, buffer, "buffer")
+	return buffer
+}
+
+// MakeEmptyBuffer creates a buffer object of size bytes, without data, for
+// data that is written later, such as by a shader.
+func MakeEmptyBuffer(target uint32, size int, usage uint32) (buffer uint32) {
+	if tracing {
+		defer trace("MakeEmptyBuffer", enum(target), size, enum(usage)).done(&buffer)
+	}
+	gl.GenBuffers(1, &buffer)
+	gl.BindBuffer(target, buffer)
+	gl.BufferData(target, size, nil, usage)
+	labelCaller(gl.BUFFER, buffer, "buffer")
+	return buffer
+}
+
+// MakeTexture creates a 2D texture from an image file. If the file doesn't
+// exist, the texture is a placeholder with its name: see
+// asset.LoadImageOrPlaceholder.
+func MakeTexture(filename string) (texture uint32, err error) {
+	if tracing {
+		defer trace("MakeTexture", filename).done(&texture, &err)
+	}
+	rgba, err := asset.LoadImageOrPlaceholder(filename)
+	if err != nil {
+		return 0, err
+	}
+	return MakeTextureFromImage(rgba), nil
+}
+
+// MakeTextureFromReader creates a 2D texture from an image in any of the
+// registered formats, such as PNG or JPEG.
+func MakeTextureFromReader(r io.Reader) (texture uint32, err error) {
+	if tracing {
+		defer trace("MakeTextureFromReader").done(&texture, &err)
+	}
+	rgba, err := asset.DecodeImage(r)
+	if err != nil {
+		return 0, err
+	}
+	return MakeTextureFromImage(rgba), nil
+}
+
+// MakeTextureFromImage creates a 2D texture from an image.
+func MakeTextureFromImage(img image.Image) (texture uint32) {
+	if tracing {
+		defer trace("MakeTextureFromImage", img.Bounds()).done(&texture)
+	}
+	rgba := asset.ToRGBA(img)
+
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexImage2D(
+		gl.TEXTURE_2D, 0, // target, level
+		gl.RGBA8,                  // internal format
+		int32(rgba.Rect.Size().X), // width
+		int32(rgba.Rect.Size().Y), // height
+		0,                         // border
+		gl.RGBA, gl.UNSIGNED_BYTE, // external format, type
+		gl.Ptr(rgba.Pix)) // pixels
+	app.CountUpload(len(rgba.Pix))
+	labelCaller(gl.TEXTURE, texture, "texture")
+
+	return texture
+}
+
+// MakeShader compiles a shader. The source doesn't need to be null-terminated.
+func MakeShader(shaderType uint32, source string) (shader uint32, err error) {
+	if tracing {
+		defer trace("MakeShader", enum(shaderType), shaderSource(source)).done(&shader, &err)
+	}
+	shader = gl.CreateShader(shaderType)
+
+	csource, free := gl.Strs(source)
+	gl.ShaderSource(shader, 1, csource, nil)
+	free()
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gl.DeleteShader(shader)
+
+		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
+	}
+	labelCaller(gl.SHADER, shader, shaderKind(shaderType))
+
+	return shader, nil
+}
+
+// MakeProgram links shaders into a program.
+func MakeProgram(shaders ...uint32) (program uint32, err error) {
+	if tracing {
+		defer trace("MakeProgram", shaders).done(&program, &err)
+	}
+
+	program = gl.CreateProgram()
+
+	for _, shader := range shaders {
+		gl.AttachShader(program, shader)
+	}
+	return linkProgram(program)
+}
+
+// MakeFeedbackProgram compiles a vertex shader, and links it into a program
+// for transform feedback, capturing the given outputs interleaved into a
+// single buffer. The program has no fragment shader, so it should be run
+// with gl.RASTERIZER_DISCARD enabled.
+func MakeFeedbackProgram(vertexSource string, varyings ...string) (program uint32, err error) {
+	if tracing {
+		defer trace("MakeFeedbackProgram", shaderSource(vertexSource), varyings).done(&program, &err)
+	}
+	vertexShader, err := MakeShader(gl.VERTEX_SHADER, vertexSource)
+	if err != nil {
+		return 0, err
+	}
+	defer gl.DeleteShader(vertexShader)
+
+	program = gl.CreateProgram()
+	gl.AttachShader(program, vertexShader)
+	cvaryings, free := gl.Strs(varyings...)
+	gl.TransformFeedbackVaryings(program, int32(len(varyings)), cvaryings, gl.INTERLEAVED_ATTRIBS)
+	free()
+	return linkProgram(program)
+}
+
+// linkProgram links a program with its shaders attached, and deletes it if
+// linking fails.
+func linkProgram(program uint32) (uint32, error) {
+	gl.LinkProgram(program)
+
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+		gl.DeleteProgram(program)
+
+		return 0, fmt.Errorf("failed to link program: %v", log)
+	}
+	labelCaller(gl.PROGRAM, program, "program")
+
+	return program, nil
+}
+
+// MakeProgramFromSource compiles a vertex and a fragment shader, and links
+// them into a program.
+func MakeProgramFromSource(vertexSource, fragmentSource string) (program uint32, err error) {
+	if tracing {
+		defer trace("MakeProgramFromSource", shaderSource(vertexSource), shaderSource(fragmentSource)).done(&program, &err)
+	}
+	vertexShader, err := MakeShader(gl.VERTEX_SHADER, vertexSource)
+	if err != nil {
+		return 0, err
+	}
+	defer gl.DeleteShader(vertexShader)
+	fragmentShader, err := MakeShader(gl.FRAGMENT_SHADER, fragmentSource)
+	if err != nil {
+		return 0, err
+	}
+	defer gl.DeleteShader(fragmentShader)
+	return MakeProgram(vertexShader, fragmentShader)
+}
+
+// MakeProgramWithGeometry compiles a vertex, a geometry and a fragment
+// shader, and links them into a program.
+func MakeProgramWithGeometry(vertexSource, geometrySource, fragmentSource string) (program uint32, err error) {
+	if tracing {
+		defer trace("MakeProgramWithGeometry", shaderSource(vertexSource), shaderSource(geometrySource), shaderSource(fragmentSource)).done(&program, &err)
+	}
+	return makeProgramFromSources(
+		[]uint32{gl.VERTEX_SHADER, gl.GEOMETRY_SHADER, gl.FRAGMENT_SHADER},
+		[]string{vertexSource, geometrySource, fragmentSource})
+}
+
+// MakeProgramWithTessellation compiles a vertex, a tessellation control, a
+// tessellation evaluation and a fragment shader, and links them into a
+// program. This needs OpenGL 4.0.
+func MakeProgramWithTessellation(vertexSource, controlSource, evaluationSource, fragmentSource string) (program uint32, err error) {
+	if tracing {
+		defer trace("MakeProgramWithTessellation", shaderSource(vertexSource), shaderSource(controlSource),
+			shaderSource(evaluationSource), shaderSource(fragmentSource)).done(&program, &err)
+	}
+	return makeProgramFromSources(
+		[]uint32{gl.VERTEX_SHADER, gl.TESS_CONTROL_SHADER, gl.TESS_EVALUATION_SHADER, gl.FRAGMENT_SHADER},
+		[]string{vertexSource, controlSource, evaluationSource, fragmentSource})
+}
+
+// MakeComputeProgram compiles a compute shader, and links it into a
+// program. This needs OpenGL 4.3.
+func MakeComputeProgram(source string) (program uint32, err error) {
+	if tracing {
+		defer trace("MakeComputeProgram", shaderSource(source)).done(&program, &err)
+	}
+	return makeProgramFromSources([]uint32{gl.COMPUTE_SHADER}, []string{source})
+}
+
+// makeProgramFromSources compiles a shader of shaderTypes[i] for each of
+// sources[i], and links them into a program.
+func makeProgramFromSources(shaderTypes []uint32, sources []string) (uint32, error) {
+	var shaders []uint32
+	defer func() {
+		for _, shader := range shaders {
+			gl.DeleteShader(shader)
+		}
+	}()
+	for i, source := range sources {
+		shader, err := MakeShader(shaderTypes[i], source)
+		if err != nil {
+			return 0, err
+		}
+		shaders = append(shaders, shader)
+	}
+	return MakeProgram(shaders...)
+}
+
+// VersionAtLeast reports whether the OpenGL version of the current context
+// is at least major.minor.
+func VersionAtLeast(major, minor int32) (ok bool) {
+	if tracing {
+		defer trace("VersionAtLeast", major, minor).done(&ok)
+	}
+	var ma, mi int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &ma)
+	gl.GetIntegerv(gl.MINOR_VERSION, &mi)
+	return ma > major || ma == major && mi >= minor
+}
+
+// Has reports whether the current context supports the extension, such as
+// "GL_ARB_debug_output". Many features of newer versions of OpenGL are also
+// available as an extension on drivers of older versions.
+func Has(extension string) (ok bool) {
+	if tracing {
+		defer trace("Has", extension).done(&ok)
+	}
+	var n int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &n)
+	for i := int32(0); i < n; i++ {
+		if gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))) == extension {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"time"
+)
+
+// The time the GPU takes for a frame is measured with timer queries, from
+// swap to swap. The result of a query is only available when the GPU is
+// done with the frame, a few frames later, so there are several, used in
+// turn. A query whose result isn't available yet is not waited for, its
+// frame is not measured.
+const gpuQueries = 4
+
+type gpuTimerState struct {
+	checked   bool
+	supported bool
+	queries   [gpuQueries]uint32
+	pending   [gpuQueries]bool
+	current   int // the query that measures the frame being drawn, or -1
+}
+
+var gpuTimer gpuTimerState
+
+// measureGPUTime ends the query of the frame, if there is one, reports the
+// results that are available, and starts a query for the next frame.
+func measureGPUTime() {
+	t := &gpuTimer
+	if !t.checked {
+		t.checked = true
+		t.supported = VersionAtLeast(3, 3) || Has("GL_ARB_timer_query")
+		if t.supported {
+			gl.GenQueries(gpuQueries, &t.queries[0])
+		}
+		t.current = -1
+	}
+	if !t.supported {
+		return
+	}
+
+	if t.current >= 0 {
+		gl.EndQuery(gl.TIME_ELAPSED)
+		t.pending[t.current] = true
+	}
+
+	// the oldest results first
+	next := (t.current + 1) % gpuQueries
+	for i := 0; i < gpuQueries; i++ {
+		q := (next + i) % gpuQueries
+		if !t.pending[q] {
+			continue
+		}
+		var available int32
+		gl.GetQueryObjectiv(t.queries[q], gl.QUERY_RESULT_AVAILABLE, &available)
+		if available == 0 {
+			continue
+		}
+		var ns uint64
+		gl.GetQueryObjectui64v(t.queries[q], gl.QUERY_RESULT, &ns)
+		t.pending[q] = false
+		app.SetGPUTime(time.Duration(ns))
+	}
+
+	t.current = -1
+	if !t.pending[next] {
+		gl.BeginQuery(gl.TIME_ELAPSED, t.queries[next])
+		t.current = next
+	}
+}
+
+// Demos that use this package report the time the GPU takes for each
+// frame with -metrics.
+func init() {
+	app.HandleSwap(measureGPUTime)
+}
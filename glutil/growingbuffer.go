@@ -0,0 +1,82 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"unsafe"
+)
+
+// GrowingBuffer is a buffer object that data is appended to, such as the
+// vertices of a line that grows over time. When it is full, it is replaced
+// by a buffer of twice the size, with the old data copied on the GPU.
+type GrowingBuffer struct {
+	Buffer uint32
+	Target uint32
+	Len    int // bytes in use
+	Cap    int // size of the buffer in bytes
+}
+
+// NewGrowingBuffer creates an empty buffer with room for capacity bytes.
+// The buffer is left bound to target.
+func NewGrowingBuffer(target uint32, capacity int) *GrowingBuffer {
+	if tracing {
+		defer trace("NewGrowingBuffer", enum(target), capacity).done()
+	}
+	b := &GrowingBuffer{
+		Target: target,
+		Cap:    capacity,
+	}
+	gl.GenBuffers(1, &b.Buffer)
+	gl.BindBuffer(target, b.Buffer)
+	gl.BufferData(target, capacity, nil, gl.DYNAMIC_DRAW)
+	labelCaller(gl.BUFFER, b.Buffer, "growing buffer")
+	return b
+}
+
+// Append copies size bytes of data to the end of the buffer. It returns
+// true if the buffer was replaced by a larger one, in which case vertex
+// array objects that use the buffer must be set up again. The buffer is
+// left bound to its target.
+func (b *GrowingBuffer) Append(data unsafe.Pointer, size int) (grown bool) {
+	if tracing {
+		defer trace("GrowingBuffer.Append", b.Buffer, size).done(&grown)
+	}
+	if b.Len+size > b.Cap {
+		capacity := 2 * b.Cap
+		for b.Len+size > capacity {
+			capacity *= 2
+		}
+		var buffer uint32
+		gl.GenBuffers(1, &buffer)
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, buffer)
+		gl.BufferData(gl.COPY_WRITE_BUFFER, capacity, nil, gl.DYNAMIC_DRAW)
+		labelCaller(gl.BUFFER, buffer, "growing buffer")
+		gl.BindBuffer(gl.COPY_READ_BUFFER, b.Buffer)
+		gl.CopyBufferSubData(gl.COPY_READ_BUFFER, gl.COPY_WRITE_BUFFER, 0, 0, b.Len)
+		gl.BindBuffer(gl.COPY_READ_BUFFER, 0)
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, 0)
+		gl.DeleteBuffers(1, &b.Buffer)
+		b.Buffer = buffer
+		b.Cap = capacity
+		grown = true
+	}
+	gl.BindBuffer(b.Target, b.Buffer)
+	gl.BufferSubData(b.Target, b.Len, size, data)
+	app.CountUpload(size)
+	b.Len += size
+	return
+}
+
+// Reset empties the buffer, keeping its size.
+func (b *GrowingBuffer) Reset() {
+	b.Len = 0
+}
+
+// Delete deletes the buffer.
+func (b *GrowingBuffer) Delete() {
+	if tracing {
+		defer trace("GrowingBuffer.Delete", b.Buffer).done()
+	}
+	gl.DeleteBuffers(1, &b.Buffer)
+}
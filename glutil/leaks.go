@@ -0,0 +1,167 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"flag"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// With -leaks, each object made by this package is registered with the
+// stack of the call that made it, and when the window closes, the objects
+// that still exist are listed, as by ReportLeaks.
+var leaks = flag.Bool("leaks", false, "list the OpenGL objects made by the helpers that were never deleted, on quit")
+
+// An object made by this package.
+type objectKey struct {
+	identifier uint32 // kind, such as gl.BUFFER
+	name       uint32
+}
+
+type madeObject struct {
+	kind  string
+	stack []uintptr
+	seq   int // order of making
+}
+
+var (
+	madeObjects = make(map[objectKey]madeObject)
+	madeCount   int
+)
+
+// track registers an object, if -leaks is set. A name that was used before
+// replaces the old object, which must have been deleted for OpenGL to give
+// out its name again.
+func track(identifier, name uint32, kind string) {
+	if !*leaks {
+		return
+	}
+	pc := make([]uintptr, 32)
+	// skip runtime.Callers, track, and labelCaller
+	n := runtime.Callers(3, pc)
+	madeCount++
+	madeObjects[objectKey{identifier, name}] = madeObject{kind: kind, stack: pc[:n], seq: madeCount}
+}
+
+// exists reports whether an object still exists, and was not marked for
+// deletion.
+func exists(key objectKey) bool {
+	var deleted int32
+	switch key.identifier {
+	case gl.BUFFER:
+		return gl.IsBuffer(key.name)
+	case gl.TEXTURE:
+		return gl.IsTexture(key.name)
+	case gl.FRAMEBUFFER:
+		return gl.IsFramebuffer(key.name)
+	case gl.RENDERBUFFER:
+		return gl.IsRenderbuffer(key.name)
+	case gl.VERTEX_ARRAY:
+		return gl.IsVertexArray(key.name)
+	case gl.PROGRAM:
+		// a program that is in use when it is deleted is only marked
+		if !gl.IsProgram(key.name) {
+			return false
+		}
+		gl.GetProgramiv(key.name, gl.DELETE_STATUS, &deleted)
+	case gl.SHADER:
+		// a shader that is attached to a program when it is deleted is
+		// only marked
+		if !gl.IsShader(key.name) {
+			return false
+		}
+		gl.GetShaderiv(key.name, gl.DELETE_STATUS, &deleted)
+	}
+	return deleted == gl.FALSE
+}
+
+// ReportLeaks logs the objects made by this package that were never
+// deleted as a warning, with the stack of the call that made each of
+// them, and returns their number. Objects made by the same call are listed
+// once, with their count. Objects are only registered with -leaks.
+//
+// An object is found to be deleted by asking OpenGL, so it doesn't matter
+// whether it was deleted with a method of this package, or directly with
+// OpenGL. Call it while the context is current.
+func ReportLeaks() (count int) {
+	if tracing {
+		defer trace("ReportLeaks").done(&count)
+	}
+
+	type group struct {
+		text  string
+		count int
+		seq   int
+	}
+	groups := make(map[string]*group)
+	for key, obj := range madeObjects {
+		if !exists(key) {
+			delete(madeObjects, key)
+			continue
+		}
+		count++
+		text := stackText(obj.kind, obj.stack)
+		if g, ok := groups[text]; ok {
+			g.count++
+			if obj.seq < g.seq {
+				g.seq = obj.seq
+			}
+		} else {
+			groups[text] = &group{text: text, count: 1, seq: obj.seq}
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+
+	list := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].seq < list[j].seq })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "glutil: %d objects were never deleted\n", count)
+	for _, g := range list {
+		if g.count > 1 {
+			fmt.Fprintf(&b, "\n%d times %s", g.count, g.text)
+		} else {
+			fmt.Fprintf(&b, "\n%s", g.text)
+		}
+	}
+	app.Warn(b.String())
+	return count
+}
+
+// stackText is the kind of an object and the stack of the call that made
+// it, in the format of a panic.
+func stackText(kind string, stack []uintptr) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, made by:\n", kind)
+	frames := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.main" || frame.Function == "runtime.goexit" {
+			break
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// Demos that use this package list their leaked objects when the window
+// closes, with -leaks.
+func init() {
+	app.HandleClose(func() {
+		if *leaks {
+			ReportLeaks()
+		}
+	})
+}
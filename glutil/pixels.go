@@ -0,0 +1,54 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"fmt"
+	"image"
+)
+
+// ReadPixels reads the back buffer of the window of the current context,
+// with the origin at the top left, as images have it. Call it before the
+// buffers are swapped.
+func ReadPixels(width, height int) (img *image.RGBA, err error) {
+	if tracing {
+		defer trace("ReadPixels", width, height).done(&err)
+	}
+
+	if VersionAtLeast(3, 0) {
+		gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+	}
+	gl.ReadBuffer(gl.BACK)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+
+	img = image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == 0 || height == 0 {
+		return img, nil
+	}
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	if e := gl.GetError(); e != gl.NO_ERROR {
+		return nil, fmt.Errorf("ReadPixels: %s", enumName(int32(e)))
+	}
+
+	// OpenGL has the origin at the bottom left
+	row := make([]byte, img.Stride)
+	for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+		t := img.Pix[top*img.Stride : (top+1)*img.Stride]
+		b := img.Pix[bottom*img.Stride : (bottom+1)*img.Stride]
+		copy(row, t)
+		copy(t, b)
+		copy(b, row)
+	}
+
+	// the window has no transparency, whatever is in the alpha channel
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img, nil
+}
+
+// Demos that use this package can write a frame to a file with -snapshot.
+func init() {
+	app.HandleSnapshot(ReadPixels)
+}
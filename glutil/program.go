@@ -0,0 +1,156 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/glm"
+)
+
+// Program is a linked shader program, with its uniform locations looked up
+// by name on first use.
+type Program struct {
+	ID        uint32
+	locations map[string]int32
+}
+
+// NewProgram compiles a vertex and a fragment shader, and links them into
+// a program.
+func NewProgram(vertexSource, fragmentSource string) (*Program, error) {
+	id, err := MakeProgramFromSource(vertexSource, fragmentSource)
+	if err != nil {
+		return nil, err
+	}
+	return WrapProgram(id), nil
+}
+
+// NewGeometryProgram compiles a vertex, a geometry and a fragment shader,
+// and links them into a program.
+func NewGeometryProgram(vertexSource, geometrySource, fragmentSource string) (*Program, error) {
+	id, err := MakeProgramWithGeometry(vertexSource, geometrySource, fragmentSource)
+	if err != nil {
+		return nil, err
+	}
+	return WrapProgram(id), nil
+}
+
+// NewTessellationProgram compiles a vertex, a tessellation control, a
+// tessellation evaluation and a fragment shader, and links them into a
+// program. This needs OpenGL 4.0.
+func NewTessellationProgram(vertexSource, controlSource, evaluationSource, fragmentSource string) (*Program, error) {
+	id, err := MakeProgramWithTessellation(vertexSource, controlSource, evaluationSource, fragmentSource)
+	if err != nil {
+		return nil, err
+	}
+	return WrapProgram(id), nil
+}
+
+// NewComputeProgram compiles a compute shader, and links it into a
+// program. This needs OpenGL 4.3.
+func NewComputeProgram(source string) (*Program, error) {
+	id, err := MakeComputeProgram(source)
+	if err != nil {
+		return nil, err
+	}
+	return WrapProgram(id), nil
+}
+
+// WrapProgram returns a Program for a program that is already linked, such
+// as one made with MakeProgram or MakeFeedbackProgram.
+func WrapProgram(id uint32) *Program {
+	return &Program{
+		ID:        id,
+		locations: make(map[string]int32),
+	}
+}
+
+// Use makes this the current program. The setters below apply to the
+// current program.
+func (p *Program) Use() {
+	if tracing {
+		defer trace("Program.Use", p.ID).done()
+	}
+	gl.UseProgram(p.ID)
+}
+
+// Delete deletes the program.
+func (p *Program) Delete() {
+	if tracing {
+		defer trace("Program.Delete", p.ID).done()
+	}
+	gl.DeleteProgram(p.ID)
+}
+
+// Uniform returns the location of a uniform, or -1 if the program has no
+// active uniform with that name.
+func (p *Program) Uniform(name string) int32 {
+	loc, ok := p.locations[name]
+	if !ok {
+		var t *traceCall
+		if tracing {
+			t = trace("Program.Uniform", p.ID, name)
+		}
+		loc = gl.GetUniformLocation(p.ID, gl.Str(name+"\x00"))
+		p.locations[name] = loc
+		t.done(&loc)
+	}
+	return loc
+}
+
+func (p *Program) SetInt(name string, v int32) {
+	if tracing {
+		defer trace("Program.SetInt", p.ID, name, v).done()
+	}
+	gl.Uniform1i(p.Uniform(name), v)
+}
+
+func (p *Program) SetBool(name string, v bool) {
+	if tracing {
+		defer trace("Program.SetBool", p.ID, name, v).done()
+	}
+	var i int32
+	if v {
+		i = 1
+	}
+	gl.Uniform1i(p.Uniform(name), i)
+}
+
+func (p *Program) SetFloat(name string, v float32) {
+	if tracing {
+		defer trace("Program.SetFloat", p.ID, name, v).done()
+	}
+	gl.Uniform1f(p.Uniform(name), v)
+}
+
+func (p *Program) SetVec2(name string, v glm.Vec2) {
+	if tracing {
+		defer trace("Program.SetVec2", p.ID, name, v).done()
+	}
+	gl.Uniform2f(p.Uniform(name), v[0], v[1])
+}
+
+func (p *Program) SetVec3(name string, v glm.Vec3) {
+	if tracing {
+		defer trace("Program.SetVec3", p.ID, name, v).done()
+	}
+	gl.Uniform3f(p.Uniform(name), v[0], v[1], v[2])
+}
+
+func (p *Program) SetVec4(name string, v glm.Vec4) {
+	if tracing {
+		defer trace("Program.SetVec4", p.ID, name, v).done()
+	}
+	gl.Uniform4f(p.Uniform(name), v[0], v[1], v[2], v[3])
+}
+
+func (p *Program) SetMat3(name string, m glm.Mat3) {
+	if tracing {
+		defer trace("Program.SetMat3", p.ID, name, m).done()
+	}
+	gl.UniformMatrix3fv(p.Uniform(name), 1, false, &m[0])
+}
+
+func (p *Program) SetMat4(name string, m glm.Mat4) {
+	if tracing {
+		defer trace("Program.SetMat4", p.ID, name, m).done()
+	}
+	gl.UniformMatrix4fv(p.Uniform(name), 1, false, &m[0])
+}
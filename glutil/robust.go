@@ -0,0 +1,70 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"time"
+)
+
+// Whether gl.GetGraphicsResetStatus can be called: 0 not known yet, 1 yes,
+// -1 no.
+var canReset int
+
+// ContextLost reports whether the context was lost because the GPU was
+// reset. This only happens to a context made after app.Robust, with
+// OpenGL 4.5 or the extension GL_KHR_robustness. After a reset, it waits
+// until the reset is complete, as a new context can't be made before that.
+//
+// To go on after a reset, make a new context with app.RecreateWindow, call
+// gl.Init and ForgetContext, and make all OpenGL objects again.
+// app.RecoverContext does this, with the function set with
+// app.HandleRebuild to make the objects.
+func ContextLost() bool {
+	if canReset == 0 {
+		canReset = -1
+		if VersionAtLeast(4, 5) || Has("GL_KHR_robustness") {
+			canReset = 1
+		}
+	}
+	if canReset < 0 || gl.GetGraphicsResetStatus() == gl.NO_ERROR {
+		return false
+	}
+	if tracing {
+		defer trace("ContextLost").done()
+	}
+
+	for i := 0; i < 100 && gl.GetGraphicsResetStatus() != gl.NO_ERROR; i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
+
+// ForgetContext forgets the objects that this package keeps in the current
+// context, and what it knows about the context, so they are made again in
+// a new context, after the old one was lost: the vertex array of
+// DrawFullscreen, the queries that measure the GPU time, which features
+// the context has, and the objects listed with -leaks.
+func ForgetContext() {
+	if tracing {
+		defer trace("ForgetContext").done()
+	}
+
+	fullscreenVAO = 0
+	canDebug = 0
+	canReset = 0
+	gpuTimer = gpuTimerState{}
+	madeObjects = make(map[objectKey]madeObject)
+}
+
+// Demos that use this package can go on after a reset of the GPU with
+// app.RecoverContext.
+func init() {
+	app.HandleContextLoss(ContextLost, func() error {
+		if err := gl.Init(); err != nil {
+			return err
+		}
+		ForgetContext()
+		return nil
+	})
+}
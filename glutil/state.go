@@ -0,0 +1,118 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+)
+
+// State is a set of pipeline settings. The zero value is the default
+// OpenGL state: no depth test, no blending, no culling, no stencil or
+// scissor test, and all buffers writable.
+//
+// A zero value for a function or mode selects its default, so gl.ZERO can't
+// be used as a blend factor or stencil action.
+type State struct {
+	DepthTest    bool
+	DepthFunc    uint32 // default gl.LESS
+	NoDepthWrite bool
+	NoColorWrite bool
+
+	Blend    bool
+	BlendSrc uint32 // default gl.SRC_ALPHA
+	BlendDst uint32 // default gl.ONE_MINUS_SRC_ALPHA
+
+	// Use the alpha of the fragment as the fraction of samples it covers.
+	// This needs a multisampled framebuffer.
+	AlphaToCoverage bool
+
+	CullFace bool
+	CullMode uint32 // default gl.BACK
+
+	// Stencil test, disabled if nil.
+	Stencil *Stencil
+
+	// Scissor test, disabled if nil.
+	Scissor *Scissor
+}
+
+// Stencil holds the settings for the stencil test.
+type Stencil struct {
+	Func      uint32 // e.g. gl.ALWAYS, gl.EQUAL, gl.NOTEQUAL
+	Ref       int32
+	Mask      uint32 // mask for the test
+	WriteMask uint32 // mask for writing, 0 to leave the buffer unchanged
+
+	// Actions when the stencil test fails, when the depth test fails,
+	// and when both pass, e.g. gl.KEEP, gl.REPLACE, gl.INCR.
+	Fail, DepthFail, Pass uint32
+}
+
+// Scissor is the rectangle that drawing and clearing are limited to, in
+// framebuffer pixels from the bottom left corner.
+type Scissor struct {
+	X, Y, Width, Height int32
+}
+
+// Apply sets the OpenGL state. Multisample anti-aliasing is enabled or
+// disabled as set in the config file, if it is set there.
+func (s State) Apply() {
+	if tracing {
+		defer trace("State.Apply", stateArg(s)).done()
+	}
+	enable(gl.DEPTH_TEST, s.DepthTest)
+	if s.DepthTest {
+		gl.DepthFunc(or(s.DepthFunc, gl.LESS))
+	}
+	gl.DepthMask(!s.NoDepthWrite)
+	gl.ColorMask(!s.NoColorWrite, !s.NoColorWrite, !s.NoColorWrite, !s.NoColorWrite)
+
+	enable(gl.BLEND, s.Blend)
+	if s.Blend {
+		gl.BlendFunc(or(s.BlendSrc, gl.SRC_ALPHA), or(s.BlendDst, gl.ONE_MINUS_SRC_ALPHA))
+	}
+	enable(gl.SAMPLE_ALPHA_TO_COVERAGE, s.AlphaToCoverage)
+
+	enable(gl.CULL_FACE, s.CullFace)
+	if s.CullFace {
+		gl.CullFace(or(s.CullMode, gl.BACK))
+	}
+
+	if st := s.Stencil; st != nil {
+		gl.Enable(gl.STENCIL_TEST)
+		gl.StencilFunc(or(st.Func, gl.ALWAYS), st.Ref, st.Mask)
+		gl.StencilOp(or(st.Fail, gl.KEEP), or(st.DepthFail, gl.KEEP), or(st.Pass, gl.KEEP))
+		gl.StencilMask(st.WriteMask)
+	} else {
+		gl.Disable(gl.STENCIL_TEST)
+		gl.StencilMask(0xff)
+	}
+
+	if sc := s.Scissor; sc != nil {
+		gl.Enable(gl.SCISSOR_TEST)
+		gl.Scissor(sc.X, sc.Y, sc.Width, sc.Height)
+	} else {
+		gl.Disable(gl.SCISSOR_TEST)
+	}
+
+	// not part of the state of a demo, but of the config file
+	settings, _ := app.CurrentSettings()
+	if ms := settings.Multisample; ms != nil {
+		enable(gl.MULTISAMPLE, *ms)
+	}
+}
+
+func enable(capability uint32, on bool) {
+	if on {
+		gl.Enable(capability)
+	} else {
+		gl.Disable(capability)
+	}
+}
+
+// or returns value, or def if value is zero.
+func or(value, def uint32) uint32 {
+	if value == 0 {
+		return def
+	}
+	return value
+}
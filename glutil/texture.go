@@ -0,0 +1,102 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+
+	"fmt"
+	"unsafe"
+)
+
+// Swizzles for SetSwizzle.
+var (
+	// The default: each component from itself.
+	SwizzleRGBA = [4]int32{gl.RED, gl.GREEN, gl.BLUE, gl.ALPHA}
+
+	// A single channel texture as gray, instead of red.
+	SwizzleGray = [4]int32{gl.RED, gl.RED, gl.RED, gl.ONE}
+
+	// A single channel texture as the alpha of white.
+	SwizzleAlpha = [4]int32{gl.ONE, gl.ONE, gl.ONE, gl.RED}
+)
+
+// SetSwizzle sets where a shader reading the 2D texture gets its red,
+// green, blue and alpha components from: gl.RED, gl.GREEN, gl.BLUE,
+// gl.ALPHA, gl.ZERO or gl.ONE for each.
+func SetSwizzle(texture uint32, swizzle [4]int32) {
+	if tracing {
+		defer trace("SetSwizzle", texture, [4]enum{enum(swizzle[0]), enum(swizzle[1]), enum(swizzle[2]), enum(swizzle[3])}).done()
+	}
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteriv(gl.TEXTURE_2D, gl.TEXTURE_SWIZZLE_RGBA, &swizzle[0])
+}
+
+// MakeTextureStorage creates a 2D texture with immutable storage for the
+// given number of mipmap levels, without data. Immutable storage is needed
+// for texture views. This needs OpenGL 4.2.
+func MakeTextureStorage(width, height, levels int32, format Format) (texture uint32) {
+	if tracing {
+		defer trace("MakeTextureStorage", width, height, levels, enum(format.Internal)).done(&texture)
+	}
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexStorage2D(gl.TEXTURE_2D, levels, uint32(format.Internal), width, height)
+	labelCaller(gl.TEXTURE, texture, "texture")
+	if levels > 1 {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	} else {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	return texture
+}
+
+// MakeTextureView creates a 2D texture that shares the data of numLevels
+// mipmap levels of texture, starting at minLevel, which is level 0 of the
+// view. The texture must have been made with MakeTextureStorage, and the
+// format must be of the same size per pixel, e.g. gl.R8 and gl.R8UI. The
+// view has its own parameters, such as filters and swizzle. This needs
+// OpenGL 4.3, or the extension GL_ARB_texture_view.
+func MakeTextureView(texture uint32, format Format, minLevel, numLevels uint32) (view uint32, err error) {
+	if tracing {
+		defer trace("MakeTextureView", texture, enum(format.Internal), minLevel, numLevels).done(&view, &err)
+	}
+	if !VersionAtLeast(4, 3) && !Has("GL_ARB_texture_view") {
+		return 0, fmt.Errorf("texture views need OpenGL 4.3 or GL_ARB_texture_view")
+	}
+	// the name of a view must not have been bound before
+	gl.GenTextures(1, &view)
+	gl.TextureView(view, gl.TEXTURE_2D, texture, uint32(format.Internal), minLevel, numLevels, 0, 1)
+	gl.BindTexture(gl.TEXTURE_2D, view)
+	labelCaller(gl.TEXTURE, view, fmt.Sprintf("texture view, level %d", minLevel))
+	if numLevels > 1 {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	} else {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	return view, nil
+}
+
+// MakeTexture3D creates a 3D texture of width x height x depth pixels,
+// with linear filtering, clamped at the edges. data holds the pixels, row
+// by row and slice by slice, in the external format and type of format,
+// or is nil for a texture without data.
+func MakeTexture3D(width, height, depth int32, format Format, data unsafe.Pointer) (texture uint32) {
+	if tracing {
+		defer trace("MakeTexture3D", width, height, depth, enum(format.Internal)).done(&texture)
+	}
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_3D, texture)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.TexImage3D(gl.TEXTURE_3D, 0, format.Internal, width, height, depth, 0, format.Format, format.Type, data)
+	labelCaller(gl.TEXTURE, texture, "3D texture")
+	return texture
+}
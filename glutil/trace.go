@@ -0,0 +1,165 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// With -trace, each call of a function or method of this package is logged
+// to a file: when it starts, with its arguments, and when it ends, with its
+// results, the time it took, and the OpenGL error, if there is one. The
+// calls made by other calls of this package are indented. A line is written
+// at the start of each call, so if a driver crashes, the last lines of the
+// file show where.
+//
+// The time is the time on the CPU. Most OpenGL calls only queue commands
+// for the GPU, so a slow draw call usually shows up in a later call that
+// has to wait for it.
+//
+// Each function checks tracing before it calls trace, so without -trace
+// the arguments aren't boxed, and nothing is deferred:
+//
+//	if tracing {
+//		defer trace("Program.SetInt", p.ID, name, v).done()
+//	}
+var (
+	traceFile string
+	tracing   bool
+)
+
+func init() {
+	flag.Func("trace", "log the calls of the OpenGL helpers to this `file`", func(s string) error {
+		traceFile = s
+		tracing = s != ""
+		return nil
+	})
+}
+
+var (
+	traceOut   *os.File
+	traceStart time.Time
+	traceDepth int
+)
+
+// A call being traced.
+type traceCall struct {
+	name  string
+	start time.Time
+}
+
+// trace logs the start of a call, if tracing is on. The returned call must
+// be ended with done, usually in a defer statement. If tracing is off, it
+// returns nil.
+func trace(name string, args ...interface{}) *traceCall {
+	if !tracing {
+		return nil
+	}
+	if traceOut == nil {
+		fp, err := os.Create(traceFile)
+		if err != nil {
+			app.Warn(err)
+			tracing = false
+			return nil
+		}
+		traceOut = fp
+		traceStart = time.Now()
+	}
+
+	s := make([]string, len(args))
+	for i, arg := range args {
+		s[i] = fmt.Sprint(arg)
+	}
+	t := &traceCall{name: name, start: time.Now()}
+	traceLine(t.start, "%s(%s)", name, strings.Join(s, ", "))
+	traceDepth++
+	return t
+}
+
+// done logs the end of a call. The results are pointers, so a deferred done
+// logs the values of named results when the function returns.
+func (t *traceCall) done(results ...interface{}) {
+	if t == nil {
+		return
+	}
+	traceDepth--
+	now := time.Now()
+
+	line := fmt.Sprintf("%s done in %v", t.name, now.Sub(t.start))
+	if len(results) > 0 {
+		s := make([]string, len(results))
+		for i, result := range results {
+			s[i] = fmt.Sprint(reflect.Indirect(reflect.ValueOf(result)))
+		}
+		line += ": " + strings.Join(s, ", ")
+	}
+	if e := gl.GetError(); e != gl.NO_ERROR {
+		line += fmt.Sprintf(", OpenGL error %v", enum(e))
+	}
+	traceLine(now, "%s", line)
+}
+
+// traceLine writes a line with the time since tracing started, indented
+// for the depth of the call.
+func traceLine(at time.Time, format string, args ...interface{}) {
+	fmt.Fprintf(traceOut, "%12.6f %s%s\n", at.Sub(traceStart).Seconds(), strings.Repeat("  ", traceDepth), fmt.Sprintf(format, args...))
+}
+
+// enum is an OpenGL constant, shown in hexadecimal as in the headers and
+// the specification.
+type enum uint32
+
+func (e enum) String() string {
+	return fmt.Sprintf("0x%04X", uint32(e))
+}
+
+// shaderSource is the source code of a shader, shown by its size, and its
+// first line after the version.
+type shaderSource string
+
+func (s shaderSource) String() string {
+	lines := strings.Split(strings.TrimSpace(string(s)), "\n")
+	first := ""
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#version") {
+			first = line
+			break
+		}
+	}
+	return fmt.Sprintf("%d bytes: %q", len(s), first)
+}
+
+// formatsArg shows formats by their internal formats.
+type formatsArg []Format
+
+func (f formatsArg) String() string {
+	s := make([]string, len(f))
+	for i, format := range f {
+		s[i] = enum(format.Internal).String()
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// stateArg shows a State with the settings of the stencil and scissor
+// tests, rather than their addresses.
+type stateArg State
+
+func (s stateArg) String() string {
+	stencil, scissor := s.Stencil, s.Scissor
+	s.Stencil, s.Scissor = nil, nil
+	str := fmt.Sprintf("%+v", State(s))
+	if stencil != nil {
+		str += fmt.Sprintf(" stencil %+v", *stencil)
+	}
+	if scissor != nil {
+		str += fmt.Sprintf(" scissor %+v", *scissor)
+	}
+	return str
+}
@@ -0,0 +1,70 @@
+package glutil
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+
+	"unsafe"
+)
+
+// UniformBuffer is a buffer object for a uniform block, bound to a binding
+// point. Programs that use the block should bind it to the same point with
+// Program.BindUniformBlock. The data must have the std140 layout.
+type UniformBuffer struct {
+	Buffer  uint32
+	Binding uint32
+	Size    int // in bytes
+}
+
+// NewUniformBuffer creates a uniform buffer of size bytes, and binds it to
+// binding point binding.
+func NewUniformBuffer(binding uint32, size int) *UniformBuffer {
+	if tracing {
+		defer trace("NewUniformBuffer", binding, size).done()
+	}
+	b := &UniformBuffer{
+		Binding: binding,
+		Size:    size,
+	}
+	gl.GenBuffers(1, &b.Buffer)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, b.Buffer)
+	gl.BufferData(gl.UNIFORM_BUFFER, size, nil, gl.DYNAMIC_DRAW)
+	labelCaller(gl.BUFFER, b.Buffer, "uniform buffer")
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, binding, b.Buffer)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	return b
+}
+
+// Update copies size bytes of data to the start of the buffer.
+func (b *UniformBuffer) Update(data unsafe.Pointer, size int) {
+	if tracing {
+		defer trace("UniformBuffer.Update", b.Buffer, size).done()
+	}
+	if size > b.Size {
+		panic("glutil: UniformBuffer.Update: data too large")
+	}
+	gl.BindBuffer(gl.UNIFORM_BUFFER, b.Buffer)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, size, data)
+	app.CountUpload(size)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+}
+
+// Delete deletes the buffer.
+func (b *UniformBuffer) Delete() {
+	if tracing {
+		defer trace("UniformBuffer.Delete", b.Buffer).done()
+	}
+	gl.DeleteBuffers(1, &b.Buffer)
+}
+
+// BindUniformBlock binds the uniform block with the given name to binding
+// point binding. It does nothing if the program has no such block.
+func (p *Program) BindUniformBlock(name string, binding uint32) {
+	if tracing {
+		defer trace("Program.BindUniformBlock", p.ID, name, binding).done()
+	}
+	index := gl.GetUniformBlockIndex(p.ID, gl.Str(name+"\x00"))
+	if index != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(p.ID, index, binding)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+// The particles are simulated entirely on the GPU. Each particle is two
+// vec4s: position and velocity, and age, lifetime, a random seed, and an
+// unused value. The particles are kept in two buffers: each frame, one is
+// read and the other written, and then they swap roles.
+//
+// With OpenGL 4.3 or later the simulation is done by a compute shader,
+// otherwise by a vertex shader with transform feedback.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// The simulation, shared by the compute shader and the transform feedback
+// shader. The particles orbit an attractor, and when they grow old they
+// are replaced at a random position.
+var simulate_glsl = `
+uniform float dt;
+uniform float time;
+uniform vec2 attractor;
+
+float hash(float n)
+{
+    return fract(sin(n) * 43758.5453);
+}
+
+void simulate(inout vec4 s0, inout vec4 s1)
+{
+    s1.x += dt;
+    if (s1.x > s1.y) {
+        // respawn, with the speed for a circular orbit
+        float seed = s1.z + time;
+        vec2 p = vec2(hash(seed), hash(seed + 17.0)) * 2.0 - 1.0;
+        vec2 d = p - attractor;
+        float r = max(length(d), 0.05);
+        vec2 v = vec2(-d.y, d.x) / r * sqrt(0.2 / r);
+        s0 = vec4(p, v);
+        s1.x = 0.0;
+        s1.z = hash(seed + 31.0) * 1000.0;
+    }
+
+    vec2 d = attractor - s0.xy;
+    float r2 = dot(d, d) + 0.01;
+    vec2 v = s0.zw + dt * 0.2 * d / (r2 * sqrt(r2));
+    v *= 1.0 - 0.1 * dt;
+    s0 = vec4(s0.xy + dt * v, v);
+}
+`
+
+var (
+	feedback_glsl = `
+#version 330 core
+` + simulate_glsl + `
+layout(location = 0) in vec4 state0;
+layout(location = 1) in vec4 state1;
+
+out vec4 outState0;
+out vec4 outState1;
+
+void main()
+{
+    vec4 s0 = state0;
+    vec4 s1 = state1;
+    simulate(s0, s1);
+    outState0 = s0;
+    outState1 = s1;
+}
+`
+
+	compute_glsl = `
+#version 430 core
+
+layout(local_size_x = 256) in;
+
+struct Particle {
+    vec4 s0;
+    vec4 s1;
+};
+
+layout(std430, binding = 0) readonly buffer Source {
+    Particle source[];
+};
+layout(std430, binding = 1) writeonly buffer Destination {
+    Particle destination[];
+};
+
+uniform uint count;
+` + simulate_glsl + `
+void main()
+{
+    uint i = gl_GlobalInvocationID.x;
+    if (i >= count) {
+        return;
+    }
+    vec4 s0 = source[i].s0;
+    vec4 s1 = source[i].s1;
+    simulate(s0, s1);
+    destination[i].s0 = s0;
+    destination[i].s1 = s1;
+}
+`
+
+	vertex_glsl = `
+#version 330 core
+
+uniform vec2 aspect;
+
+layout(location = 0) in vec4 state0;
+layout(location = 1) in vec4 state1;
+
+out vec4 color;
+
+void main()
+{
+    gl_Position = vec4(aspect * state0.xy, 0.0, 1.0);
+    float speed = length(state0.zw);
+    float fade = min(1.0, 4.0 * state1.x) * min(1.0, 2.0 * (state1.y - state1.x));
+    color = vec4(mix(vec3(0.2, 0.3, 1.0), vec3(1.0, 0.6, 0.2), clamp(speed / 2.0, 0.0, 1.0)), 0.3 * fade);
+}
+`
+
+	fragment_glsl = `
+#version 330 core
+
+in vec4 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = color;
+}
+`
+)
+
+var (
+	numParticles = flag.Int("particles", 1000000, "number of particles")
+	useCompute   = flag.Bool("compute", true, "use a compute shader if available")
+)
+
+// Size of a particle in bytes.
+const particleSize = 4 * 8
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	buffers      [2]uint32
+	vertexArrays [2]uint32 // for reading from each buffer
+	current      int       // the buffer with the current state
+
+	compute  *glutil.Program // nil if not used
+	feedback *glutil.Program
+	program  *glutil.Program
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	// the initial state is the only data that comes from the CPU
+	data := make([]float32, 0, 8**numParticles)
+	for i := 0; i < *numParticles; i++ {
+		life := 2 + 6*rand.Float32()
+		data = append(data,
+			2*rand.Float32()-1, 2*rand.Float32()-1, 0, 0,
+			life*rand.Float32(), life, 1000*rand.Float32(), 0)
+	}
+
+	for i := range r.buffers {
+		r.buffers[i] = glutil.MakeBuffer(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
+		gl.GenVertexArrays(1, &r.vertexArrays[i])
+		gl.BindVertexArray(r.vertexArrays[i])
+		gl.BindBuffer(gl.ARRAY_BUFFER, r.buffers[i])
+		gl.VertexAttribPointer(0, 4, gl.FLOAT, false, particleSize, gl.PtrOffset(0))
+		gl.EnableVertexAttribArray(0)
+		gl.VertexAttribPointer(1, 4, gl.FLOAT, false, particleSize, gl.PtrOffset(16))
+		gl.EnableVertexAttribArray(1)
+	}
+	gl.BindVertexArray(0)
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	if *useCompute && glutil.VersionAtLeast(4, 3) {
+		shader, err := glutil.MakeShader(gl.COMPUTE_SHADER, compute_glsl)
+		x(err)
+		id, err := glutil.MakeProgram(shader)
+		x(err)
+		gl.DeleteShader(shader)
+		r.compute = glutil.WrapProgram(id)
+	} else {
+		id, err := glutil.MakeFeedbackProgram(feedback_glsl, "outState0", "outState1")
+		x(err)
+		r.feedback = glutil.WrapProgram(id)
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock    = app.NewClock()
+	mouse    glm.Vec2 // cursor position in simulation coordinates
+	mouseOn  bool     // true while a mouse button is pressed
+	ax, ay   float32  // aspect correction
+	elapsed  float64
+	additive = glutil.State{
+		Blend:    true,
+		BlendSrc: gl.SRC_ALPHA,
+		BlendDst: gl.ONE,
+	}
+)
+
+// update runs one step of the simulation, from the current buffer into the
+// other one.
+func update(r *gResources) {
+	dt := clock.Delta()
+	if dt == 0 {
+		return
+	}
+	elapsed += dt
+
+	attractor := glm.Vec2{.3 * float32(math.Cos(elapsed/3)), .3 * float32(math.Sin(elapsed/3))}
+	if mouseOn {
+		attractor = mouse
+	}
+
+	src, dst := r.current, 1-r.current
+	p := r.feedback
+	if r.compute != nil {
+		p = r.compute
+	}
+	p.Use()
+	p.SetFloat("dt", float32(dt))
+	p.SetFloat("time", float32(elapsed))
+	p.SetVec2("attractor", attractor)
+
+	if r.compute != nil {
+		gl.Uniform1ui(p.Uniform("count"), uint32(*numParticles))
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 0, r.buffers[src])
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 1, r.buffers[dst])
+		gl.DispatchCompute(uint32(*numParticles+255)/256, 1, 1)
+		gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT | gl.VERTEX_ATTRIB_ARRAY_BARRIER_BIT)
+	} else {
+		gl.Enable(gl.RASTERIZER_DISCARD)
+		gl.BindVertexArray(r.vertexArrays[src])
+		gl.BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0, r.buffers[dst])
+		gl.BeginTransformFeedback(gl.POINTS)
+		gl.DrawArrays(gl.POINTS, 0, int32(*numParticles))
+		gl.EndTransformFeedback()
+		gl.BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0, 0)
+		gl.BindVertexArray(0)
+		gl.Disable(gl.RASTERIZER_DISCARD)
+	}
+
+	r.current = dst
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, 0, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	ax, ay = 1, 1
+	if width > height {
+		ax = float32(height) / float32(width)
+	} else {
+		ay = float32(width) / float32(height)
+	}
+
+	additive.Apply()
+	r.program.Use()
+	r.program.SetVec2("aspect", glm.Vec2{ax, ay})
+	gl.BindVertexArray(r.vertexArrays[r.current])
+	gl.DrawArrays(gl.POINTS, 0, int32(*numParticles))
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Flags(800, 800, "GPU particles")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		mouseOn = action == glfw.Press
+	})
+	w.SetCursorPosCallback(func(w *glfw.Window, x, y float64) {
+		width, height := w.GetSize()
+		mouse = glm.Vec2{
+			float32(2*x/float64(width)-1) / ax,
+			float32(1-2*y/float64(height)) / ay,
+		}
+	})
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	if r.compute != nil {
+		app.Info("Simulating with a compute shader")
+	} else {
+		app.Info("Simulating with transform feedback")
+	}
+
+	app.Info("Hold a mouse button to move the attractor")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
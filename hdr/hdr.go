@@ -0,0 +1,251 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/postfx"
+
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    // the tunnel is seen from the inside
+    worldNormal = -(normalMatrix * normal);
+}
+`
+
+	fragment_glsl = `
+#version 330 core
+
+struct Light {
+    vec3 position;
+    vec3 color;
+};
+
+uniform Light lights[4];
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    // a checkered wall
+    vec3 cell = floor(worldPosition * 2.0);
+    vec3 albedo = mod(cell.x + cell.y + cell.z, 2.0) == 0.0 ? vec3(0.8) : vec3(0.5);
+
+    vec3 n = normalize(worldNormal);
+    vec3 result = vec3(0.0);
+    for (int i = 0; i < 4; i++) {
+        vec3 d = lights[i].position - worldPosition;
+        float diffuse = max(dot(n, normalize(d)), 0.0);
+        result += diffuse * lights[i].color * albedo / dot(d, d);
+    }
+    fragColor = vec4(result, 1.0);
+}
+`
+)
+
+// Extra actions for this demo.
+const (
+	actionBrighter = "brighter"
+	actionDarker   = "darker"
+	actionOperator = "operator"
+)
+
+// The lights in the tunnel, with intensities from 0.1 to 200.
+var lights = []struct {
+	position, color glm.Vec3
+}{
+	{glm.Vec3{0, 0, -24}, glm.Vec3{200, 200, 200}},
+	{glm.Vec3{-1.2, -1, -4}, glm.Vec3{.1, 0, 0}},
+	{glm.Vec3{0, -1.2, -9}, glm.Vec3{0, 0, .2}},
+	{glm.Vec3{1.2, -1, -14}, glm.Vec3{0, .1, 0}},
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	tunnel  *mesh.VAO
+	scene   *glutil.Framebuffer
+	tonemap *postfx.ToneMap
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		tunnel: mesh.Cube().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.tonemap, err = postfx.NewToneMap()
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var clock = app.NewClock()
+
+// The inside of the tunnel: front faces are culled.
+var stateInside = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+	CullMode:  gl.FRONT,
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	if r.scene == nil || r.scene.Width != int32(width) || r.scene.Height != int32(height) {
+		if r.scene != nil {
+			r.scene.Delete()
+		}
+		var err error
+		r.scene, err = glutil.NewMultiFramebuffer(int32(width), int32(height), glutil.FormatRGBA16F)
+		x(err)
+	}
+
+	t := clock.Seconds()
+
+	// the camera looks around a little, near the entrance of the tunnel
+	eye := glm.Vec3{0, 0, 1}
+	center := glm.Vec3{.5 * float32(math.Sin(t/2)), .3 * float32(math.Sin(t/3)), -5}
+
+	r.scene.Bind()
+	stateInside.Apply()
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	model := glm.Translate(0, 0, -11).Mul(glm.Scale(2, 2, 14))
+	p := r.program
+	p.Use()
+	p.SetMat4("model", model)
+	p.SetMat4("view", glm.LookAt(eye, center, glm.Vec3{0, 1, 0}))
+	p.SetMat4("projection", glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 100))
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	for i, l := range lights {
+		p.SetVec3(fmt.Sprintf("lights[%d].position", i), l.position)
+		p.SetVec3(fmt.Sprintf("lights[%d].color", i), l.color)
+	}
+	r.tunnel.Draw()
+	r.scene.Unbind()
+
+	x(r.tonemap.Apply(r.scene.Texture, int32(width), int32(height), nil))
+}
+
+func main() {
+	app.Keys[actionBrighter] = []string{"up"}
+	app.Keys[actionDarker] = []string{"down"}
+	app.Keys[actionOperator] = []string{"t"}
+	app.Flags(800, 600, "HDR")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+
+	app.Info("Press up and down to change the exposure, 't' to change the tone mapping")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionBrighter:
+		r.tonemap.Exposure *= 1.25
+		app.Infof("Exposure: %.2f", r.tonemap.Exposure)
+	case actionDarker:
+		r.tonemap.Exposure /= 1.25
+		app.Infof("Exposure: %.2f", r.tonemap.Exposure)
+	case actionOperator:
+		r.tonemap.Operator = (r.tonemap.Operator + 1) % 3
+		app.Info("Tone mapping:", r.tonemap.Operator)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -2,50 +2,70 @@ package main
 
 import (
 	"github.com/go-gl/gl/all-core/gl"
-	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/easing"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/postfx"
+	"github.com/pebbe/gl/text"
+	"github.com/pebbe/gl/tween"
 
 	"errors"
+	"flag"
 	"fmt"
-	"image"
-	"image/draw"
-	_ "image/png"
-	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
-	"unsafe"
+)
+
+// Extra action for this demo. The effects are toggled with actions that
+// have the name of the effect.
+const actionReorder = "reorder"
+
+var (
+	hold = flag.Duration("hold", 2*time.Second, "time each image is shown")
+	fade = flag.Duration("fade", time.Second, "duration of the crossfade")
+	ease = flag.String("easing", "inoutquad", "easing function for the crossfade")
+	lut  = flag.String("lut", "", "color lookup table in .cube format for the grade effect, instead of a film look")
 )
 
 var (
 	vertex_glsl = `
-#version 120
+#version 330 core
 
-attribute vec2 position;
+uniform float scale;
 
-varying vec2 texcoord;
+in vec2 position;
+
+out vec2 texcoord;
 
 void main()
 {
-    gl_Position = vec4(position, 0.0, 1.0);
+    gl_Position = vec4(scale * position, 0.0, 1.0);
     texcoord = position * vec2(0.5, -0.5) + vec2(0.5);
 }
 ` + "\x00"
 
 	fragment_glsl = `
-#version 120
+#version 330 core
 
 uniform float fade_factor;
 uniform sampler2D textures[2];
 
-varying vec2 texcoord;
+in vec2 texcoord;
+
+out vec4 fragColor;
 
 void main()
 {
-    gl_FragColor = mix(
-        texture2D(textures[0], texcoord),
-        texture2D(textures[1], texcoord),
+    fragColor = mix(
+        texture(textures[0], texcoord),
+        texture(textures[1], texcoord),
         fade_factor
     );
 }
@@ -58,6 +78,7 @@ void main()
 
 type tUniforms struct {
 	fadeFactor int32
+	scale      int32
 	textures   [2]int32
 }
 
@@ -66,10 +87,14 @@ type tAttributes struct {
 }
 
 type gResources struct {
+	vertexArray   uint32
 	vertexBuffer  uint32
 	elementBuffer uint32
 
-	textures [2]uint32
+	// The slideshow, and the two images currently shown.
+	textures []uint32
+	current  int
+	next     int
 
 	vertexShader   uint32
 	fragmentShader uint32
@@ -80,53 +105,18 @@ type gResources struct {
 	attributes tAttributes
 
 	fadeFactor float32
+
+	// The slideshow is drawn through a chain of effects.
+	effects *postfx.Chain
+
+	// Errors of images dropped on the window.
+	banner *text.Banner
 }
 
 //
 // Functions for creating OpenGL objects:
 //
 
-func makeBuffer(target uint32, bufferData unsafe.Pointer, bufferSize int) uint32 {
-	var buffer uint32
-	gl.GenBuffers(1, &buffer)
-	gl.BindBuffer(target, buffer)
-	gl.BufferData(target, bufferSize, bufferData, gl.STATIC_DRAW)
-	return buffer
-}
-
-func makeTexture(filename string) uint32 {
-	fp, err := os.Open(filename)
-	x(err)
-	img, _, err := image.Decode(fp)
-	fp.Close()
-	x(err)
-
-	rgba := image.NewRGBA(img.Bounds())
-	if rgba.Stride != rgba.Rect.Size().X*4 {
-		x(errors.New("unsupported stride"))
-	}
-
-	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
-
-	var texture uint32
-	gl.GenTextures(1, &texture)
-	gl.BindTexture(gl.TEXTURE_2D, texture)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-	gl.TexImage2D(
-		gl.TEXTURE_2D, 0, // target, level
-		gl.RGB8,                   // internal format
-		int32(rgba.Rect.Size().X), // width
-		int32(rgba.Rect.Size().Y), // height
-		0,                         // border
-		gl.RGBA, gl.UNSIGNED_BYTE, // external format, type
-		gl.Ptr(rgba.Pix)) // pixels
-
-	return texture
-}
-
 func makeShader(shaderType uint32, source string) uint32 {
 	shader := gl.CreateShader(shaderType)
 
@@ -191,24 +181,66 @@ var (
 //
 
 func makeResources() *gResources {
+	// a core profile needs a vertex array object
+	var vertexArray uint32
+	gl.GenVertexArrays(1, &vertexArray)
+	gl.BindVertexArray(vertexArray)
+
 	r := gResources{
-		vertexBuffer:  makeBuffer(gl.ARRAY_BUFFER, gl.Ptr(gVertexBufferData), 4*len(gVertexBufferData)),
-		elementBuffer: makeBuffer(gl.ELEMENT_ARRAY_BUFFER, gl.Ptr(gElementBufferData), 4*len(gElementBufferData)),
+		vertexArray:   vertexArray,
+		vertexBuffer:  glutil.MakeBuffer(gl.ARRAY_BUFFER, gVertexBufferData, gl.STATIC_DRAW),
+		elementBuffer: glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, gElementBufferData, gl.STATIC_DRAW),
 	}
 
-	r.textures[0] = makeTexture("hello1.png")
-	r.textures[1] = makeTexture("hello2.png")
+	names := imageFiles(flag.Args())
+	if len(names) == 0 {
+		names = []string{"hello1.png", "hello2.png"}
+	}
+	for _, name := range names {
+		texture, err := glutil.MakeTexture(name)
+		x(err)
+		r.textures = append(r.textures, texture)
+	}
 
 	r.vertexShader = makeShader(gl.VERTEX_SHADER, vertex_glsl)
 	r.fragmentShader = makeShader(gl.FRAGMENT_SHADER, fragment_glsl)
 	r.program = makeProgram(r.vertexShader, r.fragmentShader)
 
 	r.uniforms.fadeFactor = gl.GetUniformLocation(r.program, gl.Str("fade_factor\x00"))
+	r.uniforms.scale = gl.GetUniformLocation(r.program, gl.Str("scale\x00"))
 	r.uniforms.textures[0] = gl.GetUniformLocation(r.program, gl.Str("textures[0]\x00"))
 	r.uniforms.textures[1] = gl.GetUniformLocation(r.program, gl.Str("textures[1]\x00"))
 
 	r.attributes.position = gl.GetAttribLocation(r.program, gl.Str("position\x00"))
 
+	var err error
+	r.effects, err = postfx.NewChain()
+	x(err)
+	blur, err := postfx.NewBlur()
+	x(err)
+	vignette, err := postfx.NewVignette()
+	x(err)
+	grayscale, err := postfx.NewGrayscale()
+	x(err)
+	chromatic, err := postfx.NewChromaticAberration()
+	x(err)
+	r.effects.Add("blur", blur).Enabled = false
+	r.effects.Add("vignette", vignette)
+	r.effects.Add("grayscale", grayscale).Enabled = false
+	r.effects.Add("chromatic", chromatic).Enabled = false
+
+	var grade *postfx.ColorGrade
+	if *lut != "" {
+		grade, err = postfx.LoadColorGrade(*lut)
+	} else {
+		grade, err = postfx.NewColorGrade(postfx.FilmLook(33))
+	}
+	x(err)
+	r.effects.Add("grade", grade).Enabled = false
+
+	r.banner, err = text.NewBanner()
+	x(err)
+
 	return &r
 }
 
@@ -216,10 +248,81 @@ func makeResources() *gResources {
 // Update:
 //
 
-var start = time.Now()
+var (
+	clock     = app.NewClock()
+	zoom      = input.NewZoom(.1, 10)
+	slideshow *tween.Looping
+)
+
+// makeSlideshow makes the animation of the slideshow: each image is shown
+// for a while, and then faded into the next, endlessly.
+func makeSlideshow(r *gResources, ease easing.Func) {
+	slideshow = tween.Loop(tween.Sequence(
+		tween.Wait(hold.Seconds()),
+		tween.Float32(&r.fadeFactor, 0, 1, math.Max(fade.Seconds(), .001), ease),
+	), 0)
+}
+
+// updateSlideshow selects the two images to show, and how far the fade
+// from the first to the second has progressed.
+func updateSlideshow(r *gResources) {
+	slideshow.Seek(clock.Seconds())
+	r.current = slideshow.Iteration % len(r.textures)
+	r.next = (slideshow.Iteration + 1) % len(r.textures)
+}
+
+// imageFiles expands the arguments into a list of files. An argument can be
+// a file, a pattern, or a directory from which all images are used.
+func imageFiles(args []string) []string {
+	names := make([]string, 0)
+	for _, arg := range args {
+		if fi, err := os.Stat(arg); err == nil && fi.IsDir() {
+			files, _ := filepath.Glob(filepath.Join(arg, "*"))
+			for _, file := range files {
+				switch strings.ToLower(filepath.Ext(file)) {
+				case ".png", ".jpg", ".jpeg":
+					names = append(names, file)
+				}
+			}
+		} else if files, _ := filepath.Glob(arg); len(files) > 0 {
+			names = append(names, files...)
+		} else {
+			names = append(names, arg)
+		}
+	}
+	return names
+}
 
-func updateFadeFactor(r *gResources) {
-	r.fadeFactor = float32(math.Sin(time.Since(start).Seconds())*.5 + 0.5)
+// dropFiles replaces the slideshow with the images dropped on the window.
+// Files that can't be loaded are reported, also in the window.
+func dropFiles(r *gResources, names []string) {
+	textures := make([]uint32, 0)
+	failed := make([]string, 0)
+	for _, name := range imageFiles(names) {
+		fp, err := os.Open(name)
+		if err != nil {
+			app.Error(err)
+			failed = append(failed, err.Error())
+			continue
+		}
+		texture, err := glutil.MakeTextureFromReader(fp)
+		fp.Close()
+		if err != nil {
+			app.Errorf("%s: %v", name, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		textures = append(textures, texture)
+	}
+	r.banner.Set(nil)
+	if len(failed) > 0 {
+		r.banner.Set(errors.New(strings.Join(failed, "\n")))
+	}
+	if len(textures) == 0 {
+		return
+	}
+	gl.DeleteTextures(int32(len(r.textures)), &r.textures[0])
+	r.textures = textures
 }
 
 func render(w *glfw.Window, r *gResources) {
@@ -238,18 +341,24 @@ func render(w *glfw.Window, r *gResources) {
 
 	////////////////
 
+	width, height := w.GetFramebufferSize()
+	x(r.effects.Begin(int32(width), int32(height)))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
 	gl.UseProgram(r.program)
 
 	gl.Uniform1f(r.uniforms.fadeFactor, r.fadeFactor)
+	gl.Uniform1f(r.uniforms.scale, float32(zoom.Scale()))
 
 	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, r.textures[0])
+	gl.BindTexture(gl.TEXTURE_2D, r.textures[r.current])
 	gl.Uniform1i(r.uniforms.textures[0], 0)
 
 	gl.ActiveTexture(gl.TEXTURE1)
-	gl.BindTexture(gl.TEXTURE_2D, r.textures[1])
+	gl.BindTexture(gl.TEXTURE_2D, r.textures[r.next])
 	gl.Uniform1i(r.uniforms.textures[1], 1)
 
+	gl.BindVertexArray(r.vertexArray)
 	gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer)
 	gl.VertexAttribPointer(
 		uint32(r.attributes.position), /* attribute */
@@ -270,17 +379,32 @@ func render(w *glfw.Window, r *gResources) {
 
 	gl.DisableVertexAttribArray(uint32(r.attributes.position))
 
+	x(r.effects.End(nil))
 }
 
 func main() {
+	app.Keys["blur"] = []string{"1"}
+	app.Keys["vignette"] = []string{"2"}
+	app.Keys["grayscale"] = []string{"3"}
+	app.Keys["chromatic"] = []string{"4"}
+	app.Keys["grade"] = []string{"5"}
+	app.Keys[actionReorder] = []string{"o"}
+	app.Flags(400, 300, "Hello World")
+
+	easeFunc := easing.ByName[*ease]
+	if easeFunc == nil {
+		app.Fatal("unknown easing function:", *ease)
+	}
+
 	err := glfw.Init()
 	if err != nil {
 		panic(err)
 	}
 	defer glfw.Terminate()
 
+	app.CoreProfile(3, 3)
 	glfw.WindowHint(glfw.Resizable, glfw.False)
-	w, err := glfw.CreateWindow(400, 300, "Hello World", nil, nil)
+	w, err := app.CreateWindow()
 	if err != nil {
 		panic(err)
 	}
@@ -288,31 +412,76 @@ func main() {
 	w.MakeContextCurrent()
 	glfw.SwapInterval(1)
 
-	w.SetCharCallback(charCallBack)
+	w.SetScrollCallback(zoom.Scroll)
 
 	if err := gl.Init(); err != nil {
 		panic(err)
 	}
+	glinfo.Report()
 
 	r := makeResources()
+	makeSlideshow(r, easeFunc)
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+
+	w.SetDropCallback(func(w *glfw.Window, names []string) {
+		dropFiles(r, names)
+	})
 
 	gl.ClearColor(1, 1, 1, 0)
-	fmt.Println("Press 'q' to quit")
+	app.Info("Drop image files on the window to replace the slideshow")
+	app.Info("Press '1' to '5' to toggle effects, 'o' to change their order")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
 	for !w.ShouldClose() {
 		time.Sleep(10 * time.Millisecond)
 
-		updateFadeFactor(r)
+		clock.Tick()
+		updateSlideshow(r)
 		render(w, r)
+		r.banner.Draw(w.GetFramebufferSize())
 
-		w.SwapBuffers()
+		app.SwapBuffers(w)
 		glfw.PollEvents()
 	}
 }
 
-func charCallBack(w *glfw.Window, char rune) {
-	if char == 'q' {
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
 		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionReorder:
+		r.effects.Move(r.effects.Nodes[0].Name, len(r.effects.Nodes))
+		printEffects(r.effects)
+	default:
+		if r.effects.Node(action) != nil {
+			r.effects.Toggle(action)
+			printEffects(r.effects)
+		}
+	}
+}
+
+// printEffects shows the order of the effects, and which are enabled.
+func printEffects(c *postfx.Chain) {
+	names := make([]string, 0, len(c.Nodes))
+	for _, n := range c.Nodes {
+		if n.Enabled {
+			names = append(names, n.Name)
+		} else {
+			names = append(names, "("+n.Name+")")
+		}
 	}
+	app.Info("Effects:", strings.Join(names, " -> "))
 }
 
 func init() {
@@ -323,6 +492,6 @@ func init() {
 
 func x(err error) {
 	if err != nil {
-		log.Fatalln(err)
+		app.Fatal(err)
 	}
 }
@@ -0,0 +1,69 @@
+package input
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Drag follows the cursor while a mouse button is held down. Positions are
+// in screen coordinates, relative to the top left corner of the window.
+//
+// Install it with:
+//
+//	w.SetMouseButtonCallback(drag.MouseButton)
+//	w.SetCursorPosCallback(drag.CursorPos)
+//
+// or call these methods from the window's own callbacks.
+type Drag struct {
+	// The button used for dragging. The zero value is the left button.
+	Button glfw.MouseButton
+
+	// Called when the button is pressed, when the cursor moves while the
+	// button is down, and when the button is released. Any can be nil.
+	OnStart func(x, y float64)
+	OnMove  func(x, y, dx, dy float64)
+	OnEnd   func(x, y float64)
+
+	dragging bool
+	x, y     float64
+}
+
+// Dragging reports whether the button is held down.
+func (d *Drag) Dragging() bool {
+	return d.dragging
+}
+
+// MouseButton is a glfw.MouseButtonCallback.
+func (d *Drag) MouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+	if button != d.Button {
+		return
+	}
+	x, y := w.GetCursorPos()
+	d.x, d.y = x, y
+	switch action {
+	case glfw.Press:
+		d.dragging = true
+		if d.OnStart != nil {
+			d.OnStart(x, y)
+		}
+	case glfw.Release:
+		if !d.dragging {
+			return
+		}
+		d.dragging = false
+		if d.OnEnd != nil {
+			d.OnEnd(x, y)
+		}
+	}
+}
+
+// CursorPos is a glfw.CursorPosCallback.
+func (d *Drag) CursorPos(w *glfw.Window, x, y float64) {
+	if !d.dragging {
+		return
+	}
+	dx, dy := x-d.x, y-d.y
+	d.x, d.y = x, y
+	if d.OnMove != nil {
+		d.OnMove(x, y, dx, dy)
+	}
+}
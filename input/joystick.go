@@ -0,0 +1,73 @@
+// Package input contains input handling shared by the demos.
+package input
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Axis values closer to zero than this are reported as zero.
+const Deadzone = 0.15
+
+// Joysticks keeps track of the connected joysticks and gamepads.
+// GLFW has no callback for joystick events, so the state must be polled.
+type Joysticks struct {
+	// OnChange, if set, is called by Poll when a joystick is connected
+	// or disconnected.
+	OnChange func(joy glfw.Joystick, name string, connected bool)
+
+	names   map[glfw.Joystick]string
+	axes    []float32
+	buttons []byte
+}
+
+// Poll updates the state of all joysticks. Call it once per frame.
+func (js *Joysticks) Poll() {
+	if js.names == nil {
+		js.names = make(map[glfw.Joystick]string)
+	}
+	js.axes, js.buttons = nil, nil
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		present := glfw.JoystickPresent(joy)
+		name, known := js.names[joy]
+		if present && !known {
+			name = glfw.GetJoystickName(joy)
+			js.names[joy] = name
+			if js.OnChange != nil {
+				js.OnChange(joy, name, true)
+			}
+		} else if !present && known {
+			delete(js.names, joy)
+			if js.OnChange != nil {
+				js.OnChange(joy, name, false)
+			}
+		}
+		if present && js.axes == nil && js.buttons == nil {
+			js.axes = glfw.GetJoystickAxes(joy)
+			js.buttons = glfw.GetJoystickButtons(joy)
+		}
+	}
+}
+
+// Connected reports whether any joystick is connected.
+func (js *Joysticks) Connected() bool {
+	return len(js.names) > 0
+}
+
+// Axis returns the value of an axis of the first connected joystick, in the
+// range -1 to 1. It returns zero if there is no such axis, or if the value
+// is within the deadzone.
+func (js *Joysticks) Axis(axis int) float32 {
+	if axis < 0 || axis >= len(js.axes) {
+		return 0
+	}
+	v := js.axes[axis]
+	if v > -Deadzone && v < Deadzone {
+		return 0
+	}
+	return v
+}
+
+// Button reports whether a button of the first connected joystick is pressed.
+func (js *Joysticks) Button(button int) bool {
+	return button >= 0 && button < len(js.buttons) && js.buttons[button] == byte(glfw.Press)
+}
@@ -0,0 +1,60 @@
+package input
+
+import (
+	"github.com/go-gl/glfw/v3.2/glfw"
+
+	"math"
+	"time"
+)
+
+// Zoom turns scroll wheel movement into a scale factor. The scale moves
+// smoothly towards its target, and is kept between Min and Max.
+//
+// Install it with:
+//
+//	w.SetScrollCallback(zoom.Scroll)
+type Zoom struct {
+	Min, Max float64
+
+	target  float64
+	current float64
+	last    time.Time
+}
+
+const (
+	zoomStep   = 1.1 // scale factor for one scroll step
+	zoomSmooth = 12  // higher is faster
+)
+
+// NewZoom returns a Zoom with scale 1, limited to the range min to max.
+func NewZoom(min, max float64) *Zoom {
+	return &Zoom{
+		Min:     min,
+		Max:     max,
+		target:  1,
+		current: 1,
+		last:    time.Now(),
+	}
+}
+
+// Scroll is a glfw.ScrollCallback.
+func (z *Zoom) Scroll(w *glfw.Window, xoff, yoff float64) {
+	z.target = math.Max(z.Min, math.Min(z.Max, z.target*math.Pow(zoomStep, yoff)))
+}
+
+// Scale returns the current scale factor. Call it once per frame.
+func (z *Zoom) Scale() float64 {
+	now := time.Now()
+	dt := now.Sub(z.last).Seconds()
+	z.last = now
+
+	// Interpolate in log space, so zooming in and out look the same.
+	f := math.Exp(-zoomSmooth * dt)
+	z.current = math.Exp(math.Log(z.target) + (math.Log(z.current)-math.Log(z.target))*f)
+	return z.current
+}
+
+// Reset sets the scale back to 1.
+func (z *Zoom) Reset() {
+	z.target = 1
+}
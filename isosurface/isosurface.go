@@ -0,0 +1,493 @@
+package main
+
+// The surface is made by marching cubes, on the CPU with mesh.Isosurface,
+// or with -compute, on the GPU by a compute shader, which needs OpenGL 4.3.
+// The compute shader adds the triangles of each cube to a buffer, counting
+// them in the command for an indirect draw call, so the number of triangles
+// doesn't have to be known on the CPU. It is only read back here to be
+// printed.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    worldPosition = position;
+    worldNormal = normal;
+    gl_Position = projection * view * vec4(position, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 eye;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+const vec3 lightDirection = normalize(vec3(0.5, 1.0, 0.7));
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    // the inside, seen through the sides of the box where the surface is cut
+    vec3 albedo = vec3(0.3, 0.7, 0.9);
+    if (!gl_FrontFacing) {
+        n = -n;
+        albedo = vec3(0.9, 0.4, 0.3);
+    }
+    vec3 v = normalize(eye - worldPosition);
+    float diffuse = max(dot(n, lightDirection), 0.0);
+    float specular = pow(max(dot(reflect(-lightDirection, n), v), 0.0), 40.0);
+    vec3 c = albedo * (0.15 + 0.85 * diffuse) + 0.3 * specular;
+    fragColor = vec4(c, 1.0);
+}
+` + "\x00"
+
+	// One invocation for each cube of the grid. The edges in the table of
+	// the cube's case give the vertices of its triangles, between the
+	// corners of the edges, where the value is equal to level.
+	compute_glsl = `
+#version 430 core
+
+layout(local_size_x = 4, local_size_y = 4, local_size_z = 4) in;
+
+struct Vertex {
+    vec4 position;
+    vec4 normal;
+};
+
+layout(std430, binding = 0) readonly buffer Values {
+    float values[];
+};
+layout(std430, binding = 1) readonly buffer Table {
+    int triangles[]; // 16 for each case
+};
+layout(std430, binding = 2) writeonly buffer Vertices {
+    Vertex vertices[];
+};
+// the arguments of glDrawArraysIndirect
+layout(std430, binding = 3) buffer Command {
+    uint count;
+    uint instanceCount;
+    uint first;
+    uint baseInstance;
+};
+
+uniform ivec3 size; // number of values along each axis
+uniform float level;
+uniform vec3 boxMin;
+uniform vec3 step; // between values
+uniform int maxVertices;
+uniform bool finish;
+
+const ivec3 corners[8] = ivec3[8](
+    ivec3(0, 0, 0), ivec3(1, 0, 0), ivec3(1, 1, 0), ivec3(0, 1, 0),
+    ivec3(0, 0, 1), ivec3(1, 0, 1), ivec3(1, 1, 1), ivec3(0, 1, 1));
+const ivec2 edges[12] = ivec2[12](
+    ivec2(0, 1), ivec2(1, 2), ivec2(2, 3), ivec2(3, 0),
+    ivec2(4, 5), ivec2(5, 6), ivec2(6, 7), ivec2(7, 4),
+    ivec2(0, 4), ivec2(1, 5), ivec2(2, 6), ivec2(3, 7));
+
+float value(ivec3 p)
+{
+    return values[p.x + size.x * (p.y + size.y * p.z)];
+}
+
+// by central differences, one-sided at the sides of the box, as in
+// mesh.Isosurface
+vec3 gradient(ivec3 p)
+{
+    vec3 g;
+    for (int axis = 0; axis < 3; axis++) {
+        ivec3 lo = p;
+        ivec3 hi = p;
+        lo[axis] = max(lo[axis] - 1, 0);
+        hi[axis] = min(hi[axis] + 1, size[axis] - 1);
+        g[axis] = (value(hi) - value(lo)) / (float(hi[axis] - lo[axis]) * step[axis]);
+    }
+    return g;
+}
+
+void main()
+{
+    if (finish) {
+        // vertices that didn't fit aren't drawn
+        count = min(count, uint(maxVertices));
+        return;
+    }
+
+    ivec3 cell = ivec3(gl_GlobalInvocationID);
+    if (any(greaterThanEqual(cell, size - 1))) {
+        return;
+    }
+
+    float v[8];
+    int c = 0;
+    for (int i = 0; i < 8; i++) {
+        v[i] = value(cell + corners[i]);
+        if (v[i] > level) {
+            c |= 1 << i;
+        }
+    }
+    if (c == 0 || c == 255) {
+        return;
+    }
+
+    for (int i = 0; i < 16 && triangles[16 * c + i] >= 0; i += 3) {
+        uint first = atomicAdd(count, 3u);
+        if (first + 3u > uint(maxVertices)) {
+            return;
+        }
+        for (int j = 0; j < 3; j++) {
+            ivec2 e = edges[triangles[16 * c + i + j]];
+            ivec3 a = cell + corners[e.x];
+            ivec3 b = cell + corners[e.y];
+            float t = (level - v[e.x]) / (v[e.y] - v[e.x]);
+            vec3 p = boxMin + step * mix(vec3(a), vec3(b), t);
+            vec3 g = mix(gradient(a), gradient(b), t);
+            vec3 n = length(g) > 0.0 ? -normalize(g) : vec3(0.0);
+            vertices[first + uint(j)] = Vertex(vec4(p, 1.0), vec4(n, 0.0));
+        }
+    }
+}
+`
+)
+
+var (
+	resolution = flag.Int("resolution", 64, "number of values of the grid along each axis")
+	useCompute = flag.Bool("compute", false, "make the surface with a compute shader, if available")
+)
+
+// Extra actions for this demo.
+const (
+	actionHigher    = "higher"
+	actionLower     = "lower"
+	actionWireframe = "wireframe"
+)
+
+// Room for the vertices made by the compute shader, 32 bytes each.
+const maxGPUVertices = 1 << 21
+
+//
+// The density field
+//
+
+// The surface is around metaballs, rippled by a gyroid.
+var metaballs = []struct {
+	center glm.Vec3
+	radius float32
+}{
+	{glm.Vec3{-.3, -.2, 0}, .35},
+	{glm.Vec3{.35, .1, .1}, .3},
+	{glm.Vec3{0, .35, -.3}, .25},
+	{glm.Vec3{.1, -.3, .4}, .2},
+}
+
+// density is about 1 at the surface of the metaballs, more inside.
+func density(p glm.Vec3) float32 {
+	var d float32
+	for _, b := range metaballs {
+		q := p.Sub(b.center)
+		d += b.radius * b.radius / (q.Dot(q) + 1e-6)
+	}
+	px, py, pz := 9*float64(p[0]), 9*float64(p[1]), 9*float64(p[2])
+	gyroid := math.Sin(px)*math.Cos(py) + math.Sin(py)*math.Cos(pz) + math.Sin(pz)*math.Cos(px)
+	return d + .12*float32(gyroid)
+}
+
+// The grid fills this box.
+var box = glm.AABB{Min: glm.Vec3{-1, -1, -1}, Max: glm.Vec3{1, 1, 1}}
+
+// sample returns the density at the points of an n by n by n grid. The
+// values on the sides are 0, to close the surface there.
+func sample(n int) []float32 {
+	values := make([]float32, n*n*n)
+	step := box.Max.Sub(box.Min).Mul(1 / float32(n-1))
+	i := 0
+	for z := 0; z < n; z++ {
+		for y := 0; y < n; y++ {
+			for xx := 0; xx < n; xx++ {
+				if xx > 0 && y > 0 && z > 0 && xx < n-1 && y < n-1 && z < n-1 {
+					values[i] = density(box.Min.Add(glm.Vec3{
+						float32(xx) * step[0],
+						float32(y) * step[1],
+						float32(z) * step[2],
+					}))
+				}
+				i++
+			}
+		}
+	}
+	return values
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	values  []float32
+
+	// on the CPU
+	surface *mesh.VAO
+
+	// on the GPU, with the compute shader
+	compute        *glutil.Program // nil if not used
+	valueBuffer    uint32
+	tableBuffer    uint32
+	vertexBuffer   uint32
+	commandBuffer  uint32
+	gpuVertexArray uint32
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		values: sample(*resolution),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	if *useCompute && glutil.VersionAtLeast(4, 3) {
+		shader, err := glutil.MakeShader(gl.COMPUTE_SHADER, compute_glsl)
+		x(err)
+		id, err := glutil.MakeProgram(shader)
+		x(err)
+		gl.DeleteShader(shader)
+		r.compute = glutil.WrapProgram(id)
+
+		table := make([]int32, 0, 256*16)
+		for _, c := range mesh.IsoTriangles {
+			for _, e := range c {
+				table = append(table, int32(e))
+			}
+		}
+		r.valueBuffer = glutil.MakeBuffer(gl.SHADER_STORAGE_BUFFER, r.values, gl.STATIC_DRAW)
+		r.tableBuffer = glutil.MakeBuffer(gl.SHADER_STORAGE_BUFFER, table, gl.STATIC_DRAW)
+		r.vertexBuffer = glutil.MakeEmptyBuffer(gl.SHADER_STORAGE_BUFFER, 32*maxGPUVertices, gl.STATIC_DRAW)
+		r.commandBuffer = glutil.MakeEmptyBuffer(gl.SHADER_STORAGE_BUFFER, 16, gl.STATIC_DRAW)
+
+		// the vertices as written by the compute shader: position and
+		// normal, each padded to a vec4
+		gl.GenVertexArrays(1, &r.gpuVertexArray)
+		gl.BindVertexArray(r.gpuVertexArray)
+		gl.BindBuffer(gl.ARRAY_BUFFER, r.vertexBuffer)
+		gl.VertexAttribPointer(mesh.PositionLocation, 3, gl.FLOAT, false, 32, gl.PtrOffset(0))
+		gl.EnableVertexAttribArray(mesh.PositionLocation)
+		gl.VertexAttribPointer(mesh.NormalLocation, 3, gl.FLOAT, false, 32, gl.PtrOffset(16))
+		gl.EnableVertexAttribArray(mesh.NormalLocation)
+		gl.BindVertexArray(0)
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam = camera.NewOrbit(glm.Vec3{0, 0, 0}, 3.5)
+
+	level     float32 = 1
+	changed           = true
+	wireframe         = false
+)
+
+// rebuild makes the surface for the current level.
+func rebuild(r *gResources) {
+	start := time.Now()
+	n := *resolution
+	var triangles int
+	if r.compute != nil {
+		command := [4]uint32{0, 1, 0, 0}
+		gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, r.commandBuffer)
+		gl.BufferSubData(gl.SHADER_STORAGE_BUFFER, 0, 16, gl.Ptr(&command[0]))
+
+		p := r.compute
+		p.Use()
+		p.SetInt("maxVertices", maxGPUVertices)
+		p.SetFloat("level", level)
+		p.SetVec3("boxMin", box.Min)
+		p.SetVec3("step", box.Max.Sub(box.Min).Mul(1/float32(n-1)))
+		gl.Uniform3i(p.Uniform("size"), int32(n), int32(n), int32(n))
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 0, r.valueBuffer)
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 1, r.tableBuffer)
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 2, r.vertexBuffer)
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 3, r.commandBuffer)
+		p.SetBool("finish", false)
+		groups := uint32(n-1+3) / 4
+		gl.DispatchCompute(groups, groups, groups)
+		gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT)
+		p.SetBool("finish", true)
+		gl.DispatchCompute(1, 1, 1)
+		gl.MemoryBarrier(gl.COMMAND_BARRIER_BIT | gl.VERTEX_ATTRIB_ARRAY_BARRIER_BIT | gl.BUFFER_UPDATE_BARRIER_BIT)
+
+		// only to print it, this waits for the GPU
+		gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, r.commandBuffer)
+		gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, 0, 16, gl.Ptr(&command[0]))
+		triangles = int(command[0] / 3)
+		if command[0] == maxGPUVertices {
+			app.Info("Not all triangles fit in the buffer")
+		}
+	} else {
+		m := mesh.Isosurface(r.values, n, n, n, level, box)
+		if r.surface != nil {
+			r.surface.Delete()
+		}
+		r.surface = m.Upload()
+		triangles = len(m.Indices) / 3
+	}
+	app.Infof("Level %.2f: %d triangles in %v", level, triangles, time.Since(start).Round(time.Microsecond))
+}
+
+func render(w *glfw.Window, r *gResources) {
+	if changed {
+		rebuild(r)
+		changed = false
+	}
+
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{DepthTest: true}.Apply()
+	gl.ClearColor(app.Background(.1, .1, .12, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	if wireframe {
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+	}
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100))
+	p.SetVec3("eye", cam.Eye())
+	if r.compute != nil {
+		gl.BindVertexArray(r.gpuVertexArray)
+		gl.BindBuffer(gl.DRAW_INDIRECT_BUFFER, r.commandBuffer)
+		gl.DrawArraysIndirect(gl.TRIANGLES, gl.PtrOffset(0))
+		gl.BindBuffer(gl.DRAW_INDIRECT_BUFFER, 0)
+		gl.BindVertexArray(0)
+	} else {
+		r.surface.Draw()
+	}
+
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+}
+
+func main() {
+	app.Keys[actionHigher] = []string{"up"}
+	app.Keys[actionLower] = []string{"down"}
+	app.Keys[actionWireframe] = []string{"w"}
+	app.Flags(800, 600, "Marching cubes")
+	if *resolution < 2 {
+		app.Fatal("-resolution must be at least 2")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+	cam.Pitch = .4
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	if r.compute != nil {
+		app.Info("Marching cubes with a compute shader")
+	} else {
+		if *useCompute {
+			app.Info("Compute shaders need OpenGL 4.3")
+		}
+		app.Info("Marching cubes on the CPU")
+	}
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press up and down to change the level of the surface, 'w' to toggle wireframe")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionHigher:
+		level *= 1.1
+		changed = true
+	case actionLower:
+		level /= 1.1
+		changed = true
+	case actionWireframe:
+		wireframe = !wireframe
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
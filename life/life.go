@@ -0,0 +1,378 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	// One generation with a compute shader, reading one image and writing
+	// the other. The grid wraps around at the edges.
+	compute_glsl = `
+#version 430 core
+
+layout(local_size_x = 16, local_size_y = 16) in;
+
+layout(r8, binding = 0) uniform readonly image2D current;
+layout(r8, binding = 1) uniform writeonly image2D next;
+
+void main()
+{
+    ivec2 size = imageSize(current);
+    ivec2 p = ivec2(gl_GlobalInvocationID.xy);
+    if (p.x >= size.x || p.y >= size.y) {
+        return;
+    }
+    int n = 0;
+    for (int dy = -1; dy <= 1; dy++) {
+        for (int dx = -1; dx <= 1; dx++) {
+            if (dx != 0 || dy != 0) {
+                n += int(imageLoad(current, (p + ivec2(dx, dy) + size) % size).r > 0.5);
+            }
+        }
+    }
+    bool alive = imageLoad(current, p).r > 0.5;
+    imageStore(next, p, vec4(n == 3 || alive && n == 2 ? 1.0 : 0.0));
+}
+`
+
+	// The same, for older versions of OpenGL, with a fragment shader
+	// rendering into a framebuffer with the next generation.
+	step_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D current;
+
+out vec4 fragColor;
+
+void main()
+{
+    ivec2 size = textureSize(current, 0);
+    ivec2 p = ivec2(gl_FragCoord.xy);
+    int n = 0;
+    for (int dy = -1; dy <= 1; dy++) {
+        for (int dx = -1; dx <= 1; dx++) {
+            if (dx != 0 || dy != 0) {
+                n += int(texelFetch(current, (p + ivec2(dx, dy) + size) % size, 0).r > 0.5);
+            }
+        }
+    }
+    bool alive = texelFetch(current, p, 0).r > 0.5;
+    fragColor = vec4(n == 3 || alive && n == 2 ? 1.0 : 0.0);
+}
+`
+
+	// Shows the cells in the window.
+	show_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D cells;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    float alive = texture(cells, uv).r;
+    fragColor = vec4(mix(vec3(0.05, 0.05, 0.1), vec3(0.4, 1.0, 0.4), alive), 1.0);
+}
+`
+)
+
+var (
+	cellSize = flag.Int("cell", 2, "size of a cell in pixels")
+	fallback = flag.Bool("fallback", false, "use a fragment shader instead of a compute shader")
+	density  = flag.Float64("density", .25, "fraction of cells alive at the start")
+)
+
+// Extra actions for this demo.
+const (
+	actionRandom = "random"
+	actionClear  = "clear"
+)
+
+// Generations per second at normal speed, one per step of the clock.
+const generationsPerSecond = 60
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	compute *glutil.Program // nil for the fallback
+	step    *glutil.Program
+	show    *glutil.Program
+
+	// The current generation, and the next.
+	cells      [2]*glutil.Framebuffer
+	cols, rows int32
+}
+
+func makeResources(cols, rows int32) *gResources {
+	r := &gResources{
+		cols: cols,
+		rows: rows,
+	}
+
+	var err error
+	if *fallback {
+		r.step, err = glutil.NewProgram(glutil.FullscreenVertexShader, step_fragment_glsl)
+	} else {
+		r.compute, err = glutil.NewComputeProgram(compute_glsl)
+	}
+	x(err)
+	r.show, err = glutil.NewProgram(glutil.FullscreenVertexShader, show_fragment_glsl)
+	x(err)
+
+	for i := range r.cells {
+		r.cells[i], err = glutil.NewMultiFramebuffer(cols, rows, glutil.FormatR8)
+		x(err)
+	}
+
+	// rows of single bytes aren't aligned to four bytes
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+
+	randomize(r, *density)
+
+	return r
+}
+
+// randomize makes each cell alive with probability p.
+func randomize(r *gResources, p float64) {
+	data := make([]uint8, r.cols*r.rows)
+	for i := range data {
+		if rand.Float64() < p {
+			data[i] = 255
+		}
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, r.cols, r.rows, gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(data))
+}
+
+// paint sets the cells in a square of 3 x 3 around col, row.
+func paint(r *gResources, col, row int32, alive bool) {
+	x0, y0, x1, y1 := col-1, row-1, col+2, row+2
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > r.cols {
+		x1 = r.cols
+	}
+	if y1 > r.rows {
+		y1 = r.rows
+	}
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+	var v uint8
+	if alive {
+		v = 255
+	}
+	data := make([]uint8, (x1-x0)*(y1-y0))
+	for i := range data {
+		data[i] = v
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, x0, y0, x1-x0, y1-y0, gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(data))
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+
+	generation int
+	pending    float64 // generations to run, with a fraction left from the last frame
+
+	painting, erasing bool
+)
+
+// nextGeneration computes the next generation into cells[1], and swaps
+// the buffers.
+func nextGeneration(r *gResources) {
+	if r.compute != nil {
+		r.compute.Use()
+		gl.BindImageTexture(0, r.cells[0].Texture, 0, false, 0, gl.READ_ONLY, gl.R8)
+		gl.BindImageTexture(1, r.cells[1].Texture, 0, false, 0, gl.WRITE_ONLY, gl.R8)
+		gl.DispatchCompute(uint32(r.cols+15)/16, uint32(r.rows+15)/16, 1)
+		// the result is read by the next dispatch, by the display, and
+		// written by painting
+		gl.MemoryBarrier(gl.ALL_BARRIER_BITS)
+	} else {
+		r.cells[1].Bind()
+		r.step.Use()
+		r.step.SetInt("current", 0)
+		gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+		glutil.DrawFullscreen()
+		r.cells[1].Unbind()
+	}
+	r.cells[0], r.cells[1] = r.cells[1], r.cells[0]
+	generation++
+}
+
+func update(r *gResources) {
+	pending += clock.Delta() * generationsPerSecond
+	// don't fall behind ever further if the GPU can't keep up
+	for n := 0; pending >= 1 && n < 20; n++ {
+		nextGeneration(r)
+		pending--
+	}
+	if pending > 1 {
+		pending = 0
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	r.show.Use()
+	r.show.SetInt("cells", 0)
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	glutil.DrawFullscreen()
+}
+
+// mouseButton starts painting live cells with the left button, and dead
+// cells with the right button.
+func mouseButton(w *glfw.Window, r *gResources, button glfw.MouseButton, action glfw.Action) {
+	switch button {
+	case glfw.MouseButtonLeft:
+		painting = action == glfw.Press
+	case glfw.MouseButtonRight:
+		erasing = action == glfw.Press
+	}
+	x, y := w.GetCursorPos()
+	cursorPos(w, r, x, y)
+}
+
+func cursorPos(w *glfw.Window, r *gResources, x, y float64) {
+	if !painting && !erasing {
+		return
+	}
+	// window coordinates have y down, the grid has row 0 at the bottom
+	width, height := w.GetSize()
+	col := int32(x / float64(width) * float64(r.cols))
+	row := int32((1 - y/float64(height)) * float64(r.rows))
+	paint(r, col, row, painting)
+}
+
+func main() {
+	app.Keys[actionRandom] = []string{"r"}
+	app.Keys[actionClear] = []string{"c"}
+	app.Flags(1024, 768, "Game of Life")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	// compute shaders need OpenGL 4.3
+	if *fallback {
+		app.CoreProfile(3, 3)
+	} else {
+		app.CoreProfile(4, 3)
+	}
+	w, err := app.CreateWindow()
+	if err != nil && !*fallback {
+		app.Info("No OpenGL 4.3, using a fragment shader instead of a compute shader")
+		*fallback = true
+		app.CoreProfile(3, 3)
+		w, err = app.CreateWindow()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	// the grid fills the window as it is at the start
+	width, height := w.GetFramebufferSize()
+	r := makeResources(int32(width / *cellSize), int32(height / *cellSize))
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		mouseButton(w, r, button, action)
+	})
+	w.SetCursorPosCallback(func(w *glfw.Window, x, y float64) {
+		cursorPos(w, r, x, y)
+	})
+
+	if *fallback {
+		app.Infof("%d x %d cells, with a fragment shader", r.cols, r.rows)
+	} else {
+		app.Infof("%d x %d cells, with a compute shader", r.cols, r.rows)
+	}
+	app.Info("Drag with the left mouse button to add cells, with the right button to remove them")
+	app.Info("Press 'r' for random cells, 'c' to clear")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+		app.Info("Generation", generation)
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionRandom:
+		randomize(r, *density)
+		generation = 0
+	case actionClear:
+		randomize(r, 0)
+		generation = 0
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
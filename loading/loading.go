@@ -0,0 +1,440 @@
+package main
+
+// Loading assets in the background: images and models are read and decoded
+// by an asset.Loader on worker goroutines, while the main loop draws a
+// progress bar, and uploads what has been loaded, a few milliseconds per
+// frame, so the window stays responsive, and shows each asset as soon as it
+// is there.
+//
+// Without arguments, the demo loads the images of hello, and makes a set of
+// fractal images and isosurface models, which take a while to compute,
+// standing in for large files. Arguments are image files and OBJ files.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/shapes"
+	"github.com/pebbe/gl/sprite"
+	"github.com/pebbe/gl/text"
+
+	"flag"
+	"fmt"
+	"image"
+	imagecolor "image/color"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = mat3(model) * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    float light = 0.2 + 0.8 * max(dot(n, normalize(vec3(1.0, 1.0, 2.0))), 0.0);
+    fragColor = vec4(light * color, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	workers = flag.Int("workers", runtime.NumCPU(), "number of assets loaded at the same time")
+	count   = flag.Int("count", 24, "number of images and models to make, without arguments")
+)
+
+// Extra action for this demo.
+const actionReload = "reload"
+
+// Time per frame for uploading.
+const uploadBudget = 5 * time.Millisecond
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	shapes  *shapes.Batch
+	sprites *sprite.Batch
+	font    *text.Font
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.shapes, err = shapes.NewBatch(64)
+	x(err)
+	r.sprites, err = sprite.NewBatch(1024)
+	x(err)
+	r.font = text.NewFont(2)
+
+	return r
+}
+
+//
+// The assets
+//
+
+// The loaded assets, in the order they were uploaded.
+type tAssets struct {
+	loader   *asset.Loader
+	started  time.Time
+	reported bool
+
+	textures []uint32
+	models   []tModel
+}
+
+type tModel struct {
+	vao *mesh.VAO
+	fit glm.Mat4 // to the unit sphere
+}
+
+// load starts loading all assets.
+func load() *tAssets {
+	a := &tAssets{
+		loader:  asset.NewLoader(*workers),
+		started: time.Now(),
+	}
+	l := a.loader
+
+	addTexture := func(img *image.RGBA) error {
+		a.textures = append(a.textures, glutil.MakeTextureFromImage(img))
+		return nil
+	}
+	addModel := func(m *mesh.Mesh) error {
+		bs := m.BoundingSphere()
+		if bs.Radius <= 0 {
+			bs.Radius = 1
+		}
+		a.models = append(a.models, tModel{
+			vao: m.Upload(),
+			fit: glm.Scale(1/bs.Radius, 1/bs.Radius, 1/bs.Radius).Mul(glm.Translate(-bs.Center[0], -bs.Center[1], -bs.Center[2])),
+		})
+		return nil
+	}
+
+	if flag.NArg() == 0 {
+		l.Image("../hello/hello1.png", addTexture)
+		l.Image("../hello/hello2.png", addTexture)
+		for i := 0; i < *count; i++ {
+			i := i
+			asset.Load(l, fmt.Sprintf("julia %d", i+1), func() (*image.RGBA, error) {
+				return julia(float64(i)/float64(*count), 512), nil
+			}, addTexture)
+		}
+		for i := 0; i < *count/4; i++ {
+			i := i
+			asset.Load(l, fmt.Sprintf("blobs %d", i+1), func() (*mesh.Mesh, error) {
+				return blobs(int64(i), 64), nil
+			}, addModel)
+		}
+		return a
+	}
+
+	for _, name := range flag.Args() {
+		name := name
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".obj":
+			asset.Load(l, name, func() (*mesh.Mesh, error) {
+				m, err := mesh.LoadOBJ(name)
+				if err != nil {
+					return nil, err
+				}
+				return m.Mesh, nil
+			}, addModel)
+		default:
+			l.Image(name, addTexture)
+		}
+	}
+	return a
+}
+
+// delete frees the assets. Assets that are still being loaded are dropped
+// with the loader, which is never updated again.
+func (a *tAssets) delete() {
+	if len(a.textures) > 0 {
+		gl.DeleteTextures(int32(len(a.textures)), &a.textures[0])
+	}
+	for _, m := range a.models {
+		m.vao.Delete()
+	}
+}
+
+// julia makes an image of a Julia set, for t from 0 to 1 along a circle of
+// values of c.
+func julia(t float64, size int) *image.RGBA {
+	const maxIterations = 256
+	a := 2 * math.Pi * t
+	cr, ci := .7885*math.Cos(a), .7885*math.Sin(a)
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			zr := 3 * (float64(col)/float64(size) - .5)
+			zi := 3 * (float64(row)/float64(size) - .5)
+			n := 0
+			for ; n < maxIterations && zr*zr+zi*zi < 4; n++ {
+				zr, zi = zr*zr-zi*zi+cr, 2*zr*zi+ci
+			}
+			c := glm.Vec3{}
+			if n < maxIterations {
+				f := float32(n) / maxIterations
+				c = color.HSV(float32(t)+f, .8, float32(math.Sqrt(float64(f))))
+			}
+			img.SetRGBA(col, row, imagecolor.RGBA{
+				R: uint8(255 * c[0]),
+				G: uint8(255 * c[1]),
+				B: uint8(255 * c[2]),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+// blobs makes an isosurface of a few metaballs, at random places for the
+// seed, on a grid of n by n by n values.
+func blobs(seed int64, n int) *mesh.Mesh {
+	rnd := rand.New(rand.NewSource(seed))
+	balls := make([]glm.Vec3, 3+rnd.Intn(4))
+	for i := range balls {
+		balls[i] = glm.Vec3{rnd.Float32() - .5, rnd.Float32() - .5, rnd.Float32() - .5}
+	}
+
+	values := make([]float32, n*n*n)
+	for k := 0; k < n; k++ {
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				p := glm.Vec3{float32(i), float32(j), float32(k)}.Mul(2 / float32(n-1)).Sub(glm.Vec3{1, 1, 1})
+				v := float32(0)
+				for _, b := range balls {
+					d := p.Sub(b)
+					v += .06 / (d.Dot(d) + .001)
+				}
+				values[i+n*(j+n*k)] = v
+			}
+		}
+	}
+	return mesh.Isosurface(values, n, n, n, 1, glm.AABB{Min: glm.Vec3{-1, -1, -1}, Max: glm.Vec3{1, 1, 1}})
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+
+	// size of the images in the gallery, and of the models
+	cellSize = float32(96)
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func render(w *glfw.Window, r *gResources, a *tAssets) {
+	if !a.loader.Done() {
+		a.loader.Update(uploadBudget)
+	} else if !a.reported {
+		a.reported = true
+		_, total, _ := a.loader.Progress()
+		app.Infof("Loaded %d assets in %v", total, time.Since(a.started).Round(time.Millisecond))
+		for _, err := range a.loader.Errors() {
+			app.Error(err)
+		}
+	}
+
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.15, .15, .18, 0))
+	state3D.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// the models, in a row at the bottom, each in a viewport of its own
+	p := r.program
+	p.Use()
+	p.SetMat4("view", glm.LookAt(glm.Vec3{0, 0, 3}, glm.Vec3{}, glm.Vec3{0, 1, 0}))
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), 1, .1, 10))
+	size := int32(2 * cellSize)
+	for i, m := range a.models {
+		gl.Viewport(int32(i)*size, 0, size, size)
+		t := float32(clock.Seconds())
+		p.SetMat4("model", glm.AxisAngle(glm.Vec3{0, 1, 0}, t+float32(i)).Mat4().Mul(m.fit))
+		p.SetVec3("color", color.Rainbow(float32(i)/float32(len(a.models))))
+		m.vao.Draw()
+	}
+	gl.Viewport(0, 0, int32(width), int32(height))
+
+	// the images, in a grid from the top
+	projection := glm.Ortho(0, float32(width), float32(height), 0, -1, 1)
+	columns := int(float32(width) / cellSize)
+	if columns < 1 {
+		columns = 1
+	}
+	b := r.sprites
+	b.Begin(projection)
+	for i, texture := range a.textures {
+		b.Draw(texture, sprite.Sprite{
+			Position: glm.Vec2{(float32(i%columns) + .5) * cellSize, (float32(i/columns) + .5) * cellSize},
+			Size:     glm.Vec2{cellSize - 4, cellSize - 4},
+			Region:   glm.Vec4{0, 0, 1, 1},
+			Color:    glm.Vec4{1, 1, 1, 1},
+		})
+	}
+	b.End()
+
+	if !a.loader.Done() {
+		drawProgress(r, a.loader, float32(width), float32(height), projection)
+	}
+}
+
+// drawProgress draws a progress bar in the middle of the window, with the
+// number of assets, and the name of the last one.
+func drawProgress(r *gResources, l *asset.Loader, width, height float32, projection glm.Mat4) {
+	done, total, last := l.Progress()
+	f := float32(done) / float32(total)
+
+	left, right := width*.2, width*.8
+	top, bottom := height/2-12, height/2+12
+
+	s := r.shapes
+	s.Begin(projection)
+	s.Rect(glm.Vec2{left - 16, top - 40}, glm.Vec2{right + 16, bottom + 40}, 8, shapes.Style{Fill: glm.Vec4{0, 0, 0, .8}})
+	s.Rect(glm.Vec2{left, top}, glm.Vec2{right, bottom}, 4, shapes.Style{
+		Border:      glm.Vec4{1, 1, 1, 1},
+		BorderWidth: 2,
+	})
+	if done > 0 {
+		s.Rect(glm.Vec2{left + 4, top + 4}, glm.Vec2{left + 4 + f*(right-left-8), bottom - 4}, 2, shapes.Style{Fill: glm.Vec4{.3, .7, 1, 1}})
+	}
+	s.End()
+
+	white := glm.Vec4{1, 1, 1, 1}
+	b := r.sprites
+	b.Begin(projection)
+	msg := fmt.Sprintf("Loading %d of %d", done, total)
+	r.font.Draw(b, msg, glm.Vec2{(width - r.font.Size(msg)[0]) / 2, top - 28}, white)
+	r.font.Draw(b, last, glm.Vec2{(width - r.font.Size(last)[0]) / 2, bottom + 12}, white)
+	b.End()
+}
+
+func main() {
+	app.Keys[actionReload] = []string{"r"}
+	app.Flags(800, 600, "Loading in the background")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	a := load()
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		if action == actionReload {
+			a.delete()
+			a = load()
+			return
+		}
+		onAction(w, action)
+	})
+
+	app.Info("Press 'r' to load everything again")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r, a)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
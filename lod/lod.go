@@ -0,0 +1,326 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 6) in mat4 instanceModel;
+layout(location = 10) in vec4 instanceData;
+
+out vec3 worldNormal;
+out vec3 baseColor;
+flat out float fade;
+
+void main()
+{
+    gl_Position = projection * view * instanceModel * vec4(position, 1.0);
+    // uniform scaling only, so no normal matrix is needed
+    worldNormal = mat3(instanceModel) * normal;
+    // a color that depends on the position of the instance
+    vec2 p = instanceModel[3].xz;
+    baseColor = 0.55 + 0.45 * cos(vec3(0.0, 2.0, 4.0) + 0.05 * (p.x + 1.7 * p.y));
+    fade = instanceData.x;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 lightDirection;
+uniform vec3 tint;
+
+in vec3 worldNormal;
+in vec3 baseColor;
+flat in float fade;
+
+out vec4 fragColor;
+
+// a 4x4 ordered dither pattern, with thresholds from 0 to 1
+float dither(vec2 p)
+{
+    int x = int(p.x) & 3;
+    int y = int(p.y) & 3;
+    int bayer[16] = int[16](0, 8, 2, 10, 12, 4, 14, 6, 3, 11, 1, 9, 15, 7, 13, 5);
+    return (float(bayer[y * 4 + x]) + 0.5) / 16.0;
+}
+
+void main()
+{
+    // fade > 0: draw a fraction fade of the pixels, fade < 0: draw the
+    // other pixels, so two levels in transition add up to a whole
+    float d = dither(gl_FragCoord.xy);
+    if (fade > 0.0 && d >= fade || fade < 0.0 && d < -fade) {
+        discard;
+    }
+    float diffuse = max(dot(normalize(worldNormal), lightDirection), 0.0);
+    fragColor = vec4((0.3 + 0.7 * diffuse) * baseColor * tint, 1.0);
+}
+` + "\x00"
+)
+
+var objectCount = flag.Int("objects", 10000, "number of objects")
+
+// Extra actions for this demo.
+const (
+	actionFade = "fade"
+	actionTint = "tint"
+)
+
+// Size of the field the objects are spread over.
+const fieldSize = 300
+
+// The levels of detail: the resolution of the mesh, and the distance up to
+// which it is used. Near the end of each range, a level fades into the
+// next over fadeRange units.
+var levels = []struct {
+	slices, stacks int
+	distance       float32
+	tint           glm.Vec3
+}{
+	{64, 32, 20, glm.Vec3{1, .6, .6}},
+	{24, 12, 45, glm.Vec3{.6, 1, .6}},
+	{12, 6, 90, glm.Vec3{.6, .6, 1}},
+	{6, 4, float32(math.Inf(1)), glm.Vec3{1, 1, .6}},
+}
+
+const fadeRange = 4
+
+// bumpySphere returns a sphere with bumps, so that the levels of detail
+// differ visibly.
+func bumpySphere(slices, stacks int) *mesh.Mesh {
+	m := mesh.Sphere(slices, stacks)
+	for i, v := range m.Vertices {
+		p := v.Position
+		bump := 1 + .12*float32(math.Sin(6*float64(p[0]))*math.Sin(6*float64(p[1]))*math.Sin(6*float64(p[2])))
+		m.Vertices[i].Position = p.Mul(bump)
+	}
+	m.ComputeNormals()
+	return m
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	levels  []*mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	for _, l := range levels {
+		r.levels = append(r.levels, bumpySphere(l.slices, l.stacks).Upload())
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam     *camera.FreeFly
+	fading  = true
+	tinted  = false
+	objects []glm.Mat4
+
+	// instances of each level for the current frame
+	instances = make([][]mesh.Instance, len(levels))
+
+	// for the statistics printed once per second
+	frames    int
+	lastPrint = time.Now()
+)
+
+var stateScene = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func makeObjects() {
+	objects = make([]glm.Mat4, *objectCount)
+	for i := range objects {
+		s := .5 + rand.Float32()
+		objects[i] = glm.Translate(fieldSize*(rand.Float32()-.5), s, fieldSize*(rand.Float32()-.5)).
+			Mul(glm.Scale(s, s, s))
+	}
+}
+
+// selectLevels sorts the visible objects into the levels of detail by their
+// distance to the camera.
+func selectLevels(frustum glm.Frustum) {
+	for i := range instances {
+		instances[i] = instances[i][:0]
+	}
+	for _, model := range objects {
+		center := glm.Vec3{model[12], model[13], model[14]}
+		if !frustum.ContainsSphere(glm.Sphere{Center: center, Radius: 1.2 * model[0]}) {
+			continue
+		}
+		d := center.Sub(cam.Position).Len()
+		i := 0
+		for d > levels[i].distance {
+			i++
+		}
+		// fraction of the way into the fade to the next level
+		f := float32(0)
+		if fading && i+1 < len(levels) {
+			f = 1 - (levels[i].distance-d)/fadeRange
+		}
+		if f <= 0 {
+			instances[i] = append(instances[i], mesh.Instance{Model: model, Data: glm.Vec4{1}})
+			continue
+		}
+		instances[i] = append(instances[i], mesh.Instance{Model: model, Data: glm.Vec4{1 - f}})
+		instances[i+1] = append(instances[i+1], mesh.Instance{Model: model, Data: glm.Vec4{f - 1}})
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	stateScene.Apply()
+	gl.ClearColor(app.Background(.6, .7, .85, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	view := cam.View()
+	projection := glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 300)
+	selectLevels(glm.NewFrustum(projection.Mul(view)))
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("lightDirection", glm.Vec3{.4, 1, .6}.Normalize())
+
+	triangles := 0
+	for i, vao := range r.levels {
+		if tinted {
+			p.SetVec3("tint", levels[i].tint)
+		} else {
+			p.SetVec3("tint", glm.Vec3{1, 1, 1})
+		}
+		vao.SetInstances(instances[i])
+		vao.DrawInstanced()
+		triangles += len(instances[i]) * int(vao.Count) / 3
+	}
+
+	frames++
+	if d := time.Since(lastPrint); d >= time.Second {
+		counts := make([]string, len(levels))
+		for i := range levels {
+			counts[i] = fmt.Sprint(len(instances[i]))
+		}
+		app.Infof("Instances per level: %s, %d triangles, %.1f fps", strings.Join(counts, " "), triangles, float64(frames)/d.Seconds())
+		frames = 0
+		lastPrint = time.Now()
+	}
+}
+
+func main() {
+	app.Keys[actionFade] = []string{"f"}
+	app.Keys[actionTint] = []string{"t"}
+	app.Flags(800, 600, "Level of detail")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewFreeFly(glm.Vec3{0, 3, 0})
+	cam.Speed = 10
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	makeObjects()
+
+	app.Info("Drag with the mouse to look around, W, A, S and D to move")
+	app.Info("Press 'f' to toggle fading between levels, 't' to tint each level")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		cam.Update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionFade:
+		fading = !fading
+		if fading {
+			app.Info("Dithered fade between levels")
+		} else {
+			app.Info("Sudden switch between levels")
+		}
+	case actionTint:
+		tinted = !tinted
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
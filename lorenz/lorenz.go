@@ -0,0 +1,279 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 vertexColor;
+
+out vec3 color;
+
+void main()
+{
+    gl_Position = projection * view * vec4(position, 1.0);
+    color = vertexColor;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+in vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	sigma     = flag.Float64("sigma", 10, "parameter sigma of the Lorenz system")
+	rho       = flag.Float64("rho", 28, "parameter rho of the Lorenz system")
+	beta      = flag.Float64("beta", 8.0/3.0, "parameter beta of the Lorenz system")
+	maxPoints = flag.Int("points", 200000, "maximum number of points on the curve")
+)
+
+// Extra action for this demo.
+const actionRestart = "restart"
+
+const (
+	// Floats per point in the vertex buffer: position, color.
+	vertexFloats = 3 + 3
+
+	// Time step of the integration, and steps per second of clock time.
+	dt             = .005
+	stepsPerSecond = 400
+
+	// Points for a full cycle through the colors of the rainbow.
+	colorCycle = 5000
+)
+
+//
+// The Lorenz system, integrated with Runge-Kutta
+//
+
+type tState [3]float64
+
+func derivative(s tState) tState {
+	return tState{
+		*sigma * (s[1] - s[0]),
+		s[0]*(*rho-s[2]) - s[1],
+		s[0]*s[1] - *beta*s[2],
+	}
+}
+
+func (s tState) add(d tState, f float64) tState {
+	return tState{s[0] + f*d[0], s[1] + f*d[1], s[2] + f*d[2]}
+}
+
+func (s tState) step(h float64) tState {
+	k1 := derivative(s)
+	k2 := derivative(s.add(k1, h/2))
+	k3 := derivative(s.add(k2, h/2))
+	k4 := derivative(s.add(k3, h))
+	return tState{
+		s[0] + h/6*(k1[0]+2*k2[0]+2*k3[0]+k4[0]),
+		s[1] + h/6*(k1[1]+2*k2[1]+2*k3[1]+k4[1]),
+		s[2] + h/6*(k1[2]+2*k2[2]+2*k3[2]+k4[2]),
+	}
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program     *glutil.Program
+	vertexArray uint32
+	points      *glutil.GrowingBuffer
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	gl.GenVertexArrays(1, &r.vertexArray)
+	r.points = glutil.NewGrowingBuffer(gl.ARRAY_BUFFER, 4*vertexFloats*1024)
+	r.setAttributes()
+
+	return r
+}
+
+// setAttributes points the vertex array at the buffer. This is needed again
+// each time the buffer has grown.
+func (r *gResources) setAttributes() {
+	gl.BindVertexArray(r.vertexArray)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.points.Buffer)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 4*vertexFloats, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, 4*vertexFloats, gl.PtrOffset(12))
+	gl.EnableVertexAttribArray(1)
+	gl.BindVertexArray(0)
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   *camera.Orbit
+
+	state    tState
+	count    int     // points on the curve
+	pending  float64 // steps waiting to be taken
+	vertices []float32
+)
+
+func restart(r *gResources) {
+	state = tState{1, 1, 1}
+	count = 0
+	pending = 0
+	r.points.Reset()
+}
+
+func update(r *gResources) {
+	pending += clock.Delta() * stepsPerSecond
+	vertices = vertices[:0]
+	for ; pending >= 1 && count < *maxPoints; pending-- {
+		state = state.step(dt)
+		c := color.Rainbow(float32(count%colorCycle) / colorCycle)
+		vertices = append(vertices,
+			float32(state[0]), float32(state[1]), float32(state[2]),
+			c[0], c[1], c[2])
+		count++
+	}
+	if count >= *maxPoints {
+		pending = 0
+	}
+	if len(vertices) == 0 {
+		return
+	}
+	if r.points.Append(gl.Ptr(vertices), 4*len(vertices)) {
+		r.setAttributes()
+		app.Infof("Buffer grown to %d points", r.points.Cap/(4*vertexFloats))
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, 0, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	if count < 2 {
+		return
+	}
+
+	r.program.Use()
+	r.program.SetMat4("view", cam.View())
+	r.program.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), 1, 1000))
+	gl.BindVertexArray(r.vertexArray)
+	gl.DrawArrays(gl.LINE_STRIP, 0, int32(count))
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Keys[actionRestart] = []string{"r"}
+	app.Flags(640, 480, "Lorenz attractor")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	restart(r)
+
+	app.OnAction(w, func(w *glfw.Window, action string) { onAction(w, r, action) })
+	cam = camera.NewOrbit(glm.Vec3{0, 0, 25}, 80)
+	cam.Pitch = .2
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 'r' to restart")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionRestart:
+		restart(r)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
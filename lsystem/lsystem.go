@@ -0,0 +1,609 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/text"
+
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	//
+	// branches as lines
+	//
+	line_vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in float depth;
+
+out float branchDepth;
+
+void main()
+{
+    gl_Position = projection * view * vec4(position, 1.0);
+    branchDepth = depth;
+}
+` + "\x00"
+	line_fragment_glsl = `
+#version 330 core
+
+in float branchDepth;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(mix(vec3(0.45, 0.3, 0.15), vec3(0.3, 0.8, 0.2), branchDepth), 1.0);
+}
+` + "\x00"
+
+	//
+	// branches as cylinders
+	//
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 uv;
+
+out vec3 worldNormal;
+out float branchDepth;
+
+void main()
+{
+    gl_Position = projection * view * vec4(position, 1.0);
+    worldNormal = normal;
+    branchDepth = uv.x;
+}
+` + "\x00"
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 lightDirection;
+
+in vec3 worldNormal;
+in float branchDepth;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 color = mix(vec3(0.45, 0.3, 0.15), vec3(0.3, 0.8, 0.2), branchDepth);
+    float diffuse = max(dot(normalize(worldNormal), lightDirection), 0.0);
+    fragColor = vec4((0.25 + 0.75 * diffuse) * color, 1.0);
+}
+` + "\x00"
+)
+
+// The rules used when no file is given.
+const defaultRules = `# A three-dimensional tree.
+#
+# Settings are "name = value", rules are "symbol -> replacement".
+#
+# F draws a branch, f moves without drawing
+# + and - turn left and right, & and ^ pitch down and up,
+# \ and / roll left and right, | turns around
+# [ and ] start and end a side branch, which is thinner than its parent
+
+axiom = FA
+angle = 25
+iterations = 6
+length = 1
+width = 0.08
+taper = 0.7
+
+A -> [&FA]/////[&FA]///////[&FA]
+F -> S/////F
+S -> F
+`
+
+var (
+	rulesFile   = flag.String("rules", "", "file with the rules of the L-system, reloaded when it changes")
+	maxSegments = flag.Int("segments", 200000, "maximum number of branch segments")
+)
+
+// Extra actions for this demo.
+const (
+	actionMode = "mode"
+	actionLess = "less"
+	actionMore = "more"
+)
+
+// Sides of the cylinders.
+const sides = 8
+
+//
+// The L-system
+//
+
+type tLSystem struct {
+	axiom      string
+	rules      map[byte]string
+	angle      float32 // degrees
+	iterations int
+	length     float32
+	width      float32
+	taper      float32 // width of a side branch relative to its parent
+}
+
+func readLSystem(r io.Reader) (*tLSystem, error) {
+	ls := &tLSystem{
+		rules:      make(map[byte]string),
+		angle:      25,
+		iterations: 4,
+		length:     1,
+		width:      .1,
+		taper:      .7,
+	}
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if i := strings.Index(line, "->"); i >= 0 {
+			symbol := strings.TrimSpace(line[:i])
+			if len(symbol) != 1 {
+				return nil, fmt.Errorf("line %d: rule for %q, should be a single symbol", lineno, symbol)
+			}
+			ls.rules[symbol[0]] = strings.Join(strings.Fields(line[i+2:]), "")
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected a setting or a rule", lineno)
+		}
+		name := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if name == "axiom" {
+			ls.axiom = strings.Join(strings.Fields(value), "")
+			continue
+		}
+		if name == "iterations" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			ls.iterations = n
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		switch name {
+		case "angle":
+			ls.angle = float32(f)
+		case "length":
+			ls.length = float32(f)
+		case "width":
+			ls.width = float32(f)
+		case "taper":
+			ls.taper = float32(f)
+		default:
+			return nil, fmt.Errorf("line %d: unknown setting %q", lineno, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if ls.axiom == "" {
+		return nil, fmt.Errorf("no axiom")
+	}
+	return ls, nil
+}
+
+// expand applies the rules n times to the axiom. It stops early if the
+// result gets longer than limit.
+func (ls *tLSystem) expand(n, limit int) string {
+	s := ls.axiom
+	for i := 0; i < n && len(s) <= limit; i++ {
+		var b strings.Builder
+		for j := 0; j < len(s); j++ {
+			if r, ok := ls.rules[s[j]]; ok {
+				b.WriteString(r)
+			} else {
+				b.WriteByte(s[j])
+			}
+		}
+		s = b.String()
+	}
+	return s
+}
+
+type tSegment struct {
+	from, to glm.Vec3
+	width    float32
+	depth    int // nesting of side branches
+}
+
+type tTurtle struct {
+	position          glm.Vec3
+	heading, left, up glm.Vec3
+	width             float32
+	depth             int
+}
+
+func (t *tTurtle) turn(angle float32, axis glm.Vec3) {
+	rot := glm.Rotate(angle, axis)
+	t.heading = rot.Transform(t.heading).Normalize()
+	t.left = rot.Transform(t.left).Normalize()
+	t.up = rot.Transform(t.up).Normalize()
+}
+
+// interpret walks a turtle along the expanded string, starting upward
+// from the origin, and returns the branches it draws.
+func (ls *tLSystem) interpret(s string, limit int) (segments []tSegment, maxDepth int) {
+	t := tTurtle{
+		heading: glm.Vec3{0, 1, 0},
+		left:    glm.Vec3{-1, 0, 0},
+		up:      glm.Vec3{0, 0, 1},
+		width:   ls.width,
+	}
+	var stack []tTurtle
+	angle := glm.Radians(ls.angle)
+	for i := 0; i < len(s) && len(segments) < limit; i++ {
+		switch s[i] {
+		case 'F':
+			next := t.position.Add(t.heading.Mul(ls.length))
+			segments = append(segments, tSegment{t.position, next, t.width, t.depth})
+			t.position = next
+		case 'f':
+			t.position = t.position.Add(t.heading.Mul(ls.length))
+		case '+':
+			t.turn(angle, t.up)
+		case '-':
+			t.turn(-angle, t.up)
+		case '&':
+			t.turn(angle, t.left)
+		case '^':
+			t.turn(-angle, t.left)
+		case '\\':
+			t.turn(angle, t.heading)
+		case '/':
+			t.turn(-angle, t.heading)
+		case '|':
+			t.turn(math.Pi, t.up)
+		case '[':
+			stack = append(stack, t)
+			t.width *= ls.taper
+			t.depth++
+			if t.depth > maxDepth {
+				maxDepth = t.depth
+			}
+		case ']':
+			if len(stack) > 0 {
+				t = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return
+}
+
+// lines returns vertex data for drawing the segments as lines: position
+// and relative depth.
+func lines(segments []tSegment, maxDepth int) []float32 {
+	data := make([]float32, 0, 8*len(segments))
+	for _, s := range segments {
+		d := float32(s.depth) / float32(maxDepth+1)
+		data = append(data, s.from[0], s.from[1], s.from[2], d, s.to[0], s.to[1], s.to[2], d)
+	}
+	return data
+}
+
+// cylinders returns a mesh with an open cylinder for each segment. The
+// relative depth is stored in the first texture coordinate.
+func cylinders(segments []tSegment, maxDepth int) *mesh.Mesh {
+	m := &mesh.Mesh{}
+	for _, s := range segments {
+		axis := s.to.Sub(s.from).Normalize()
+		// any vector not parallel to the axis will do
+		side := glm.Vec3{1, 0, 0}
+		if math.Abs(float64(axis[0])) > .9 {
+			side = glm.Vec3{0, 0, 1}
+		}
+		u := axis.Cross(side).Normalize()
+		v := axis.Cross(u)
+		d := float32(s.depth) / float32(maxDepth+1)
+
+		n := uint32(len(m.Vertices))
+		for i := 0; i < sides; i++ {
+			a := 2 * math.Pi * float64(i) / sides
+			normal := u.Mul(float32(math.Cos(a))).Add(v.Mul(float32(math.Sin(a))))
+			offset := normal.Mul(s.width)
+			m.Vertices = append(m.Vertices,
+				mesh.Vertex{Position: s.from.Add(offset), Normal: normal, UV: glm.Vec2{d, 0}},
+				mesh.Vertex{Position: s.to.Add(offset), Normal: normal, UV: glm.Vec2{d, 1}})
+		}
+		for i := uint32(0); i < sides; i++ {
+			j := (i + 1) % sides
+			m.Indices = append(m.Indices, n+2*i, n+2*j, n+2*j+1, n+2*i, n+2*j+1, n+2*i+1)
+		}
+	}
+	return m
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	lineProgram *glutil.Program
+	program     *glutil.Program
+
+	lineArray  uint32
+	lineBuffer uint32
+	lineCount  int32
+	tree       *mesh.VAO
+
+	banner *text.Banner // errors in the rules file
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.lineProgram, err = glutil.NewProgram(line_vertex_glsl, line_fragment_glsl)
+	x(err)
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.banner, err = text.NewBanner()
+	x(err)
+
+	return r
+}
+
+// generate replaces the geometry by the tree of the L-system.
+func (r *gResources) generate(ls *tLSystem, iterations int) {
+	s := ls.expand(iterations, 10**maxSegments)
+	segments, maxDepth := ls.interpret(s, *maxSegments)
+	if len(segments) == *maxSegments {
+		app.Warnf("Stopped at %d segments", *maxSegments)
+	}
+	if len(segments) == 0 {
+		app.Info("Nothing to draw, no F in the result")
+		return
+	}
+
+	if r.lineArray != 0 {
+		gl.DeleteVertexArrays(1, &r.lineArray)
+		gl.DeleteBuffers(1, &r.lineBuffer)
+		r.tree.Delete()
+	}
+	data := lines(segments, maxDepth)
+	r.lineCount = int32(2 * len(segments))
+	gl.GenVertexArrays(1, &r.lineArray)
+	gl.BindVertexArray(r.lineArray)
+	r.lineBuffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 16, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(1, 1, gl.FLOAT, false, 16, gl.PtrOffset(12))
+	gl.EnableVertexAttribArray(1)
+	gl.BindVertexArray(0)
+
+	r.tree = cylinders(segments, maxDepth).Upload()
+
+	// aim the camera at the middle of the tree
+	lo := glm.Vec3{}
+	hi := glm.Vec3{}
+	for _, seg := range segments {
+		for i := 0; i < 3; i++ {
+			lo[i] = float32(math.Min(float64(lo[i]), float64(seg.to[i])))
+			hi[i] = float32(math.Max(float64(hi[i]), float64(seg.to[i])))
+		}
+	}
+	cam.Target = lo.Add(hi).Mul(.5)
+	cam.Distance = float32(math.Max(1, float64(hi.Sub(lo).Len())))
+	cam.MinDistance = cam.Distance / 100
+	cam.MaxDistance = cam.Distance * 100
+
+	app.Infof("Iterations: %d, segments: %d", iterations, len(segments))
+}
+
+//
+// Update and render
+//
+
+var (
+	cam       *camera.Orbit
+	lsystem   *tLSystem
+	iters     int
+	modTime   time.Time
+	cylinder  = true
+	lastCheck time.Time
+)
+
+var stateTree = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func load() (*tLSystem, error) {
+	if *rulesFile == "" {
+		return readLSystem(strings.NewReader(defaultRules))
+	}
+	fp, err := os.Open(*rulesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return readLSystem(fp)
+}
+
+// reload regenerates the tree when the rules file has changed. A file
+// with errors is reported, also in the window, and the old tree is kept.
+func reload(r *gResources) {
+	if *rulesFile == "" || time.Since(lastCheck) < time.Second/2 {
+		return
+	}
+	lastCheck = time.Now()
+	fi, err := os.Stat(*rulesFile)
+	if err != nil || !fi.ModTime().After(modTime) {
+		return
+	}
+	modTime = fi.ModTime()
+	ls, err := load()
+	r.banner.Set(err)
+	if err != nil {
+		app.Error(err)
+		return
+	}
+	lsystem = ls
+	iters = ls.iterations
+	r.generate(lsystem, iters)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.7, .8, .9, 0))
+	stateTree.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	near := cam.Distance / 100
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), near, 1000*near)
+
+	if cylinder {
+		p := r.program
+		p.Use()
+		p.SetMat4("view", cam.View())
+		p.SetMat4("projection", projection)
+		p.SetVec3("lightDirection", glm.Vec3{.5, 1, .7}.Normalize())
+		r.tree.Draw()
+		return
+	}
+
+	p := r.lineProgram
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", projection)
+	gl.BindVertexArray(r.lineArray)
+	gl.DrawArrays(gl.LINES, 0, r.lineCount)
+	gl.BindVertexArray(0)
+}
+
+func main() {
+	app.Keys[actionMode] = []string{"m"}
+	app.Keys[actionLess] = []string{"["}
+	app.Keys[actionMore] = []string{"]"}
+	app.Flags(640, 480, "L-system")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	lsystem, err = load()
+	if err != nil {
+		// the built-in rules, until the file is fixed
+		app.Error(err)
+		r.banner.Set(err)
+		lsystem, err = readLSystem(strings.NewReader(defaultRules))
+		x(err)
+	}
+	if *rulesFile != "" {
+		if fi, err := os.Stat(*rulesFile); err == nil {
+			modTime = fi.ModTime()
+		}
+	}
+	iters = lsystem.iterations
+
+	cam = camera.NewOrbit(glm.Vec3{}, 1)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	r.generate(lsystem, iters)
+
+	app.OnAction(w, func(w *glfw.Window, action string) { onAction(w, r, action) })
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	if *rulesFile != "" {
+		app.Info("Edit the rules file to see the changes")
+	}
+	app.Info("Press 'm' to switch between lines and cylinders")
+	app.Info("Press '[' and ']' to change the number of iterations")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		reload(r)
+		render(w, r)
+		r.banner.Draw(w.GetFramebufferSize())
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionMode:
+		cylinder = !cylinder
+	case actionLess:
+		if iters > 0 {
+			iters--
+			r.generate(lsystem, iters)
+		}
+	case actionMore:
+		iters++
+		r.generate(lsystem, iters)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+package mesh
+
+import (
+	"github.com/pebbe/gl/glm"
+)
+
+// Corners and edges of a cube, numbered as in IsoTriangles.
+var (
+	isoCorners = [8][3]int{
+		{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0},
+		{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1},
+	}
+	isoEdges = [12][2]int{
+		{0, 1}, {1, 2}, {2, 3}, {3, 0},
+		{4, 5}, {5, 6}, {6, 7}, {7, 4},
+		{0, 4}, {1, 5}, {2, 6}, {3, 7},
+	}
+)
+
+// Isosurface returns the surface where the values of a grid are equal to
+// level, by marching cubes. The grid has nx by ny by nz values, with x
+// changing fastest, then y, then z, evenly spread over the box. Values
+// above the level are inside, and the surface faces outwards, towards lower
+// values. The normals are from the gradient of the values, for smooth
+// shading.
+//
+// Where the surface reaches the sides of the box, it is open.
+func Isosurface(values []float32, nx, ny, nz int, level float32, box glm.AABB) *Mesh {
+	m := &Mesh{}
+	if nx < 2 || ny < 2 || nz < 2 || len(values) < nx*ny*nz {
+		return m
+	}
+
+	index := func(x, y, z int) int {
+		return x + nx*(y+ny*z)
+	}
+	size := box.Max.Sub(box.Min)
+	step := glm.Vec3{size[0] / float32(nx-1), size[1] / float32(ny-1), size[2] / float32(nz-1)}
+
+	// by central differences, one-sided at the sides of the box
+	gradient := func(p [3]int) glm.Vec3 {
+		n := [3]int{nx, ny, nz}
+		var g glm.Vec3
+		for axis := 0; axis < 3; axis++ {
+			lo, hi := p, p
+			if lo[axis] > 0 {
+				lo[axis]--
+			}
+			if hi[axis] < n[axis]-1 {
+				hi[axis]++
+			}
+			d := values[index(hi[0], hi[1], hi[2])] - values[index(lo[0], lo[1], lo[2])]
+			g[axis] = d / (float32(hi[axis]-lo[axis]) * step[axis])
+		}
+		return g
+	}
+
+	// Vertices on an edge of the grid are shared by the cubes around it.
+	// An edge is known by the corner it starts at, and its direction.
+	shared := make(map[int]uint32)
+	vertex := func(cell [3]int, edge int, v *[8]float32) uint32 {
+		a, b := isoEdges[edge][0], isoEdges[edge][1]
+		pa, pb := cell, cell
+		for i := 0; i < 3; i++ {
+			pa[i] += isoCorners[a][i]
+			pb[i] += isoCorners[b][i]
+		}
+		start, axis := pa, 0
+		for i := 0; i < 3; i++ {
+			if pa[i] != pb[i] {
+				axis = i
+				if pb[i] < pa[i] {
+					start = pb
+				}
+			}
+		}
+		key := 3*index(start[0], start[1], start[2]) + axis
+		if i, ok := shared[key]; ok {
+			return i
+		}
+
+		t := (level - v[a]) / (v[b] - v[a])
+		var position glm.Vec3
+		for i := 0; i < 3; i++ {
+			position[i] = box.Min[i] + step[i]*(float32(pa[i])+t*float32(pb[i]-pa[i]))
+		}
+		ga, gb := gradient(pa), gradient(pb)
+		normal := ga.Add(gb.Sub(ga).Mul(t))
+		if normal.Len() > 0 {
+			normal = normal.Normalize().Mul(-1)
+		}
+
+		i := uint32(len(m.Vertices))
+		m.Vertices = append(m.Vertices, Vertex{Position: position, Normal: normal})
+		shared[key] = i
+		return i
+	}
+
+	var v [8]float32
+	for z := 0; z < nz-1; z++ {
+		for y := 0; y < ny-1; y++ {
+			for x := 0; x < nx-1; x++ {
+				c := 0
+				for i, corner := range isoCorners {
+					v[i] = values[index(x+corner[0], y+corner[1], z+corner[2])]
+					if v[i] > level {
+						c |= 1 << uint(i)
+					}
+				}
+				if c == 0 || c == 255 {
+					continue
+				}
+				cell := [3]int{x, y, z}
+				for _, edge := range IsoTriangles[c] {
+					if edge < 0 {
+						break
+					}
+					m.Indices = append(m.Indices, vertex(cell, int(edge), &v))
+				}
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,192 @@
+// Package mesh contains indexed triangle meshes, with generators for some
+// basic shapes, a vertex array object to draw them with, and a debug view
+// of their normals.
+package mesh
+
+import (
+	"github.com/pebbe/gl/glm"
+
+	"math"
+)
+
+type Vertex struct {
+	Position glm.Vec3
+	Normal   glm.Vec3
+	UV       glm.Vec2
+	Tangent  glm.Vec3 // set by ComputeTangents
+}
+
+// Mesh is a list of triangles, three indices into Vertices for each
+// triangle, in counter-clockwise order when seen from the front.
+type Mesh struct {
+	Vertices []Vertex
+	Indices  []uint32
+}
+
+// Cube returns a cube from -1 to 1 on every axis, with four vertices per
+// face, so each face has its own normal and texture coordinates.
+func Cube() *Mesh {
+	// normal, and vectors to the right and up, seen from outside
+	faces := [6][3]glm.Vec3{
+		{{1, 0, 0}, {0, 0, -1}, {0, 1, 0}},
+		{{-1, 0, 0}, {0, 0, 1}, {0, 1, 0}},
+		{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}},
+		{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}},
+		{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, -1}, {-1, 0, 0}, {0, 1, 0}},
+	}
+	corners := [4]glm.Vec2{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+
+	m := &Mesh{}
+	for _, f := range faces {
+		n := uint32(len(m.Vertices))
+		for _, c := range corners {
+			m.Vertices = append(m.Vertices, Vertex{
+				Position: f[0].Add(f[1].Mul(c[0])).Add(f[2].Mul(c[1])),
+				Normal:   f[0],
+				UV:       glm.Vec2{(c[0] + 1) / 2, (1 - c[1]) / 2},
+			})
+		}
+		m.Indices = append(m.Indices, n, n+1, n+2, n, n+2, n+3)
+	}
+	return m
+}
+
+// Sphere returns a sphere with radius 1, divided into slices around the
+// y axis, and stacks from pole to pole.
+func Sphere(slices, stacks int) *Mesh {
+	m := &Mesh{}
+	for i := 0; i <= stacks; i++ {
+		v := float64(i) / float64(stacks)
+		phi := math.Pi * v
+		for j := 0; j <= slices; j++ {
+			u := float64(j) / float64(slices)
+			theta := 2 * math.Pi * u
+			p := glm.Vec3{
+				float32(math.Sin(phi) * math.Sin(theta)),
+				float32(math.Cos(phi)),
+				float32(math.Sin(phi) * math.Cos(theta)),
+			}
+			m.Vertices = append(m.Vertices, Vertex{
+				Position: p,
+				Normal:   p,
+				UV:       glm.Vec2{float32(u), float32(v)},
+			})
+		}
+	}
+	row := uint32(slices + 1)
+	for i := uint32(0); i < uint32(stacks); i++ {
+		for j := uint32(0); j < uint32(slices); j++ {
+			a := i*row + j
+			b := a + row
+			m.Indices = append(m.Indices, a, b, a+1, a+1, b, b+1)
+		}
+	}
+	return m
+}
+
+// Quad returns a square from -1 to 1 in the xy plane, facing +z.
+func Quad() *Mesh {
+	return &Mesh{
+		Vertices: []Vertex{
+			{Position: glm.Vec3{-1, -1, 0}, Normal: glm.Vec3{0, 0, 1}, UV: glm.Vec2{0, 1}},
+			{Position: glm.Vec3{1, -1, 0}, Normal: glm.Vec3{0, 0, 1}, UV: glm.Vec2{1, 1}},
+			{Position: glm.Vec3{1, 1, 0}, Normal: glm.Vec3{0, 0, 1}, UV: glm.Vec2{1, 0}},
+			{Position: glm.Vec3{-1, 1, 0}, Normal: glm.Vec3{0, 0, 1}, UV: glm.Vec2{0, 0}},
+		},
+		Indices: []uint32{0, 1, 2, 0, 2, 3},
+	}
+}
+
+// Disc returns a circle with radius in the xy plane, facing +z, as a fan of
+// triangles around a vertex in the center. The vertices on the edge, 1 to
+// segments, start on the x axis and go counter-clockwise, so they can also
+// be drawn as a line loop.
+func Disc(segments int, radius float32) *Mesh {
+	m := &Mesh{
+		Vertices: []Vertex{
+			{Normal: glm.Vec3{0, 0, 1}, UV: glm.Vec2{.5, .5}},
+		},
+	}
+	for i := 0; i < segments; i++ {
+		a := 2 * math.Pi * float64(i) / float64(segments)
+		c, s := float32(math.Cos(a)), float32(math.Sin(a))
+		m.Vertices = append(m.Vertices, Vertex{
+			Position: glm.Vec3{radius * c, radius * s, 0},
+			Normal:   glm.Vec3{0, 0, 1},
+			UV:       glm.Vec2{(1 + c) / 2, (1 - s) / 2},
+		})
+		m.Indices = append(m.Indices, 0, uint32(i+1), uint32((i+1)%segments+1))
+	}
+	return m
+}
+
+// ComputeNormals sets the normal of each vertex to the average of the
+// normals of the triangles it is part of.
+func (m *Mesh) ComputeNormals() {
+	all := make([]bool, len(m.Vertices))
+	for i := range all {
+		all[i] = true
+		m.Vertices[i].Normal = glm.Vec3{}
+	}
+	computeNormals(m, all)
+}
+
+// ComputeTangents sets the tangent of each vertex to the direction in which
+// the texture coordinate u increases, averaged over the triangles that share
+// the vertex, and made perpendicular to the normal.
+//
+// Texture coordinate v increases downwards in the image, so the bitangent
+// for a normal map with green pointing up is cross(normal, tangent).
+func (m *Mesh) ComputeTangents() {
+	tangents := make([]glm.Vec3, len(m.Vertices))
+	for i := 0; i+2 < len(m.Indices); i += 3 {
+		i0, i1, i2 := m.Indices[i], m.Indices[i+1], m.Indices[i+2]
+		v0, v1, v2 := m.Vertices[i0], m.Vertices[i1], m.Vertices[i2]
+
+		e1 := v1.Position.Sub(v0.Position)
+		e2 := v2.Position.Sub(v0.Position)
+		du1, dv1 := v1.UV[0]-v0.UV[0], v1.UV[1]-v0.UV[1]
+		du2, dv2 := v2.UV[0]-v0.UV[0], v2.UV[1]-v0.UV[1]
+
+		det := du1*dv2 - du2*dv1
+		if det == 0 {
+			continue // degenerate texture mapping
+		}
+		t := e1.Mul(dv2).Sub(e2.Mul(dv1)).Mul(1 / det)
+		tangents[i0] = tangents[i0].Add(t)
+		tangents[i1] = tangents[i1].Add(t)
+		tangents[i2] = tangents[i2].Add(t)
+	}
+	for i, t := range tangents {
+		n := m.Vertices[i].Normal
+		m.Vertices[i].Tangent = t.Sub(n.Mul(n.Dot(t))).Normalize()
+	}
+}
+
+// Bounds returns the axis-aligned box around all vertices.
+func (m *Mesh) Bounds() glm.AABB {
+	if len(m.Vertices) == 0 {
+		return glm.AABB{}
+	}
+	b := glm.AABB{Min: m.Vertices[0].Position, Max: m.Vertices[0].Position}
+	for _, v := range m.Vertices {
+		for i := 0; i < 3; i++ {
+			b.Min[i] = float32(math.Min(float64(b.Min[i]), float64(v.Position[i])))
+			b.Max[i] = float32(math.Max(float64(b.Max[i]), float64(v.Position[i])))
+		}
+	}
+	return b
+}
+
+// BoundingSphere returns a sphere around all vertices, centered on the
+// center of Bounds. It is not the smallest possible sphere, but close.
+func (m *Mesh) BoundingSphere() glm.Sphere {
+	s := glm.Sphere{Center: m.Bounds().Center()}
+	for _, v := range m.Vertices {
+		if d := v.Position.Sub(s.Center).Len(); d > s.Radius {
+			s.Radius = d
+		}
+	}
+	return s
+}
@@ -0,0 +1,121 @@
+package mesh
+
+import (
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+)
+
+var (
+	normals_vertex_glsl = `
+#version 330 core
+
+uniform mat4 modelView;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 viewNormal;
+
+void main()
+{
+    gl_Position = modelView * vec4(position, 1.0);
+    viewNormal = normalize(normalMatrix * normal);
+}
+`
+
+	// For each triangle, a line along the normal of each vertex, and
+	// optionally one along the face normal from the center.
+	normals_geometry_glsl = `
+#version 330 core
+
+layout(triangles) in;
+layout(line_strip, max_vertices = 8) out;
+
+uniform mat4 projection;
+uniform float len;
+uniform bool faceNormals;
+
+in vec3 viewNormal[];
+
+out vec3 color;
+
+void line(vec4 p, vec3 n, vec3 c)
+{
+    color = c;
+    gl_Position = projection * p;
+    EmitVertex();
+    gl_Position = projection * (p + vec4(len * n, 0.0));
+    EmitVertex();
+    EndPrimitive();
+}
+
+void main()
+{
+    for (int i = 0; i < 3; i++) {
+        line(gl_in[i].gl_Position, viewNormal[i], vec3(1.0, 1.0, 0.0));
+    }
+    if (faceNormals) {
+        vec3 a = gl_in[0].gl_Position.xyz;
+        vec3 b = gl_in[1].gl_Position.xyz;
+        vec3 c = gl_in[2].gl_Position.xyz;
+        line(vec4((a + b + c) / 3.0, 1.0), normalize(cross(b - a, c - a)), vec3(0.0, 1.0, 1.0));
+    }
+}
+`
+
+	normals_fragment_glsl = `
+#version 330 core
+
+in vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color, 1.0);
+}
+`
+)
+
+// NormalLines draws the normals of a mesh as lines, for debugging: yellow
+// lines for the vertex normals, and cyan lines for the face normals. The
+// lines are made by a geometry shader, from the triangles of the VAO.
+type NormalLines struct {
+	Length      float32 // in world units, if the view matrix doesn't scale
+	FaceNormals bool
+
+	program *glutil.Program
+}
+
+// NewNormalLines returns a NormalLines that draws lines of length 1, without
+// face normals.
+func NewNormalLines() (*NormalLines, error) {
+	p, err := glutil.NewGeometryProgram(normals_vertex_glsl, normals_geometry_glsl, normals_fragment_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &NormalLines{
+		Length:  1,
+		program: p,
+	}, nil
+}
+
+// Draw draws the normals of the triangles of v. The program in use is
+// changed.
+func (n *NormalLines) Draw(v *VAO, model, view, projection glm.Mat4) {
+	modelView := view.Mul(model)
+	p := n.program
+	p.Use()
+	p.SetMat4("modelView", modelView)
+	p.SetMat3("normalMatrix", modelView.NormalMatrix())
+	p.SetMat4("projection", projection)
+	p.SetFloat("len", n.Length)
+	p.SetBool("faceNormals", n.FaceNormals)
+	v.Draw()
+}
+
+// Delete deletes the shader program.
+func (n *NormalLines) Delete() {
+	n.program.Delete()
+}
@@ -0,0 +1,325 @@
+package mesh
+
+import (
+	"github.com/pebbe/gl/glm"
+
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Model is a mesh read from a Wavefront OBJ file, with its materials.
+type Model struct {
+	Mesh      *Mesh
+	Groups    []Group              // ranges of Mesh.Indices, each with one material
+	Materials map[string]*Material // by name
+	Libraries []string             // material files named in the OBJ file
+}
+
+// Group is a range of indices that are drawn with the same material.
+type Group struct {
+	Material     string // empty if no material was set
+	First, Count int32
+}
+
+// Material is a material from a Wavefront MTL file.
+type Material struct {
+	Name       string
+	Ambient    glm.Vec3 // Ka
+	Diffuse    glm.Vec3 // Kd
+	Specular   glm.Vec3 // Ks
+	Shininess  float32  // Ns
+	Opacity    float32  // d
+	DiffuseMap string   // map_Kd, a file name
+}
+
+// LoadOBJ reads an OBJ file, and the material files it refers to. These,
+// and the textures they refer to, are looked for relative to the directory
+// of the OBJ file.
+func LoadOBJ(filename string) (*Model, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	m, err := ReadOBJ(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	dir := filepath.Dir(filename)
+	for _, lib := range m.Libraries {
+		name := filepath.Join(dir, lib)
+		fp, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		materials, err := ReadMTL(fp)
+		fp.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for k, v := range materials {
+			if v.DiffuseMap != "" {
+				v.DiffuseMap = filepath.Join(dir, filepath.Dir(lib), v.DiffuseMap)
+			}
+			m.Materials[k] = v
+		}
+	}
+	return m, nil
+}
+
+// ReadOBJ reads a model in OBJ format. Material libraries are not read,
+// their names are stored in Libraries.
+//
+// Faces with more than three vertices are split into triangles. Vertices
+// without a normal get the average normal of the faces they are part of.
+// Texture coordinates are flipped vertically, so v = 0 is the top of the
+// image, as for the other meshes.
+func ReadOBJ(r io.Reader) (*Model, error) {
+	m := &Model{
+		Mesh:      &Mesh{},
+		Materials: make(map[string]*Material),
+	}
+
+	var (
+		positions []glm.Vec3
+		normals   []glm.Vec3
+		uvs       []glm.Vec2
+		noNormal  []bool // for each vertex in the mesh
+	)
+
+	// vertices already in the mesh, by their position, uv and normal indices
+	seen := make(map[[3]int]uint32)
+
+	// index into a list, from a 1-based or negative index in the file
+	index := func(s string, n int) (int, error) {
+		if s == "" {
+			return -1, nil
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			i += n
+		} else {
+			i--
+		}
+		if i < 0 || i >= n {
+			return 0, fmt.Errorf("index out of range: %s", s)
+		}
+		return i, nil
+	}
+
+	vertex := func(s string) (uint32, error) {
+		parts := strings.Split(s, "/")
+		var key [3]int
+		var err error
+		if key[0], err = index(parts[0], len(positions)); err != nil {
+			return 0, err
+		}
+		if key[0] < 0 {
+			return 0, fmt.Errorf("missing position: %s", s)
+		}
+		key[1], key[2] = -1, -1
+		if len(parts) > 1 {
+			if key[1], err = index(parts[1], len(uvs)); err != nil {
+				return 0, err
+			}
+		}
+		if len(parts) > 2 {
+			if key[2], err = index(parts[2], len(normals)); err != nil {
+				return 0, err
+			}
+		}
+		if i, ok := seen[key]; ok {
+			return i, nil
+		}
+
+		v := Vertex{Position: positions[key[0]]}
+		if key[1] >= 0 {
+			v.UV = glm.Vec2{uvs[key[1]][0], 1 - uvs[key[1]][1]}
+		}
+		if key[2] >= 0 {
+			v.Normal = normals[key[2]]
+		}
+		i := uint32(len(m.Mesh.Vertices))
+		m.Mesh.Vertices = append(m.Mesh.Vertices, v)
+		noNormal = append(noNormal, key[2] < 0)
+		seen[key] = i
+		return i, nil
+	}
+
+	// start a new group if the material changes
+	material := ""
+	newGroup := func() {
+		n := int32(len(m.Mesh.Indices))
+		if len(m.Groups) > 0 {
+			g := &m.Groups[len(m.Groups)-1]
+			g.Count = n - g.First
+			if g.Count == 0 {
+				m.Groups = m.Groups[:len(m.Groups)-1]
+			}
+		}
+		m.Groups = append(m.Groups, Group{Material: material, First: n})
+	}
+	newGroup()
+
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "v":
+			var v glm.Vec3
+			err = parseFloats(fields[1:], v[:])
+			positions = append(positions, v)
+		case "vn":
+			var v glm.Vec3
+			err = parseFloats(fields[1:], v[:])
+			normals = append(normals, v.Normalize())
+		case "vt":
+			var v glm.Vec2
+			err = parseFloats(fields[1:], v[:])
+			uvs = append(uvs, v)
+		case "f":
+			if len(fields) < 4 {
+				err = fmt.Errorf("face with less than three vertices")
+				break
+			}
+			face := make([]uint32, len(fields)-1)
+			for i, f := range fields[1:] {
+				if face[i], err = vertex(f); err != nil {
+					break
+				}
+			}
+			for i := 2; err == nil && i < len(face); i++ {
+				m.Mesh.Indices = append(m.Mesh.Indices, face[0], face[i-1], face[i])
+			}
+		case "usemtl":
+			if len(fields) > 1 {
+				material = fields[1]
+			}
+			newGroup()
+		case "mtllib":
+			m.Libraries = append(m.Libraries, fields[1:]...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	newGroup()
+	m.Groups = m.Groups[:len(m.Groups)-1]
+
+	computeNormals(m.Mesh, noNormal)
+	return m, nil
+}
+
+// computeNormals sets the normal of each vertex i for which missing[i] is
+// true, to the average of the normals of the faces it is part of.
+func computeNormals(m *Mesh, missing []bool) {
+	for i := 0; i+2 < len(m.Indices); i += 3 {
+		i0, i1, i2 := m.Indices[i], m.Indices[i+1], m.Indices[i+2]
+		p0 := m.Vertices[i0].Position
+		// not normalized, so larger faces count more
+		n := m.Vertices[i1].Position.Sub(p0).Cross(m.Vertices[i2].Position.Sub(p0))
+		for _, j := range []uint32{i0, i1, i2} {
+			if missing[j] {
+				m.Vertices[j].Normal = m.Vertices[j].Normal.Add(n)
+			}
+		}
+	}
+	for i := range m.Vertices {
+		if missing[i] {
+			m.Vertices[i].Normal = m.Vertices[i].Normal.Normalize()
+		}
+	}
+}
+
+// ReadMTL reads materials in MTL format. File names of textures are
+// returned as they are in the file.
+func ReadMTL(r io.Reader) (map[string]*Material, error) {
+	materials := make(map[string]*Material)
+	var current *Material
+
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "newmtl" {
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: material without a name", lineno)
+			}
+			current = &Material{
+				Name:      fields[1],
+				Diffuse:   glm.Vec3{.8, .8, .8},
+				Shininess: 32,
+				Opacity:   1,
+			}
+			materials[current.Name] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "Ka":
+			err = parseFloats(fields[1:], current.Ambient[:])
+		case "Kd":
+			err = parseFloats(fields[1:], current.Diffuse[:])
+		case "Ks":
+			err = parseFloats(fields[1:], current.Specular[:])
+		case "Ns":
+			f := []float32{current.Shininess}
+			err = parseFloats(fields[1:], f)
+			current.Shininess = f[0]
+		case "d":
+			f := []float32{current.Opacity}
+			err = parseFloats(fields[1:], f)
+			current.Opacity = f[0]
+		case "map_Kd":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: map_Kd without a file name", lineno)
+			}
+			// options are not supported, the file name is the last field
+			current.DiffuseMap = fields[len(fields)-1]
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+// parseFloats parses len(dst) numbers from fields into dst. Missing numbers
+// are left unchanged, extra fields are ignored.
+func parseFloats(fields []string, dst []float32) error {
+	for i := range dst {
+		if i >= len(fields) {
+			break
+		}
+		f, err := strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			return err
+		}
+		dst[i] = float32(f)
+	}
+	return nil
+}
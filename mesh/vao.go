@@ -0,0 +1,216 @@
+package mesh
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"math"
+)
+
+// Attribute locations used by VAO. Shaders should declare their inputs
+// with these locations, e.g. layout(location = 1) in vec3 normal.
+const (
+	PositionLocation = 0
+	NormalLocation   = 1
+	UVLocation       = 2
+	TangentLocation  = 3
+	JointsLocation   = 4 // uvec4, only after AddSkin
+	WeightsLocation  = 5 // vec4, only after AddSkin
+
+	// Per instance, only after SetInstances. The model matrix is a mat4,
+	// which takes four locations: 6 to 9.
+	InstanceModelLocation = 6
+	InstanceDataLocation  = 10 // vec4
+)
+
+// Size of a vertex in the buffer: position, normal, uv, tangent.
+const stride = 4 * (3 + 3 + 2 + 3)
+
+// VAO is a mesh uploaded to the GPU: a vertex array object with an
+// interleaved vertex buffer and an element buffer.
+type VAO struct {
+	VertexArray   uint32
+	VertexBuffer  uint32
+	ElementBuffer uint32
+	SkinBuffer    uint32 // joints and weights, 0 without AddSkin
+	Count         int32  // number of indices
+	IndexType     uint32 // gl.UNSIGNED_SHORT or gl.UNSIGNED_INT
+
+	InstanceBuffer uint32 // 0 without SetInstances
+	Instances      int32  // number of instances
+}
+
+// Instance is the data of one instance for DrawInstanced: its model matrix,
+// and four floats for any other use.
+type Instance struct {
+	Model glm.Mat4
+	Data  glm.Vec4
+}
+
+// Upload creates a vertex array object for the mesh. The indices are
+// stored with 16 bits when they fit, which takes half the memory, and is
+// the only size some older hardware draws at full speed.
+func (m *Mesh) Upload() *VAO {
+	data := make([]float32, 0, len(m.Vertices)*stride/4)
+	for _, v := range m.Vertices {
+		data = append(data, v.Position[:]...)
+		data = append(data, v.Normal[:]...)
+		data = append(data, v.UV[:]...)
+		data = append(data, v.Tangent[:]...)
+	}
+
+	v := &VAO{Count: int32(len(m.Indices)), IndexType: gl.UNSIGNED_INT}
+	gl.GenVertexArrays(1, &v.VertexArray)
+	gl.BindVertexArray(v.VertexArray)
+	if len(m.Indices) == 0 {
+		// nothing to draw, such as an isosurface at a level none of the
+		// values reach
+		v.VertexBuffer = glutil.MakeEmptyBuffer(gl.ARRAY_BUFFER, 0, gl.STATIC_DRAW)
+		v.ElementBuffer = glutil.MakeEmptyBuffer(gl.ELEMENT_ARRAY_BUFFER, 0, gl.STATIC_DRAW)
+	} else {
+		v.VertexBuffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
+		if short, ok := shortIndices(m.Indices); ok {
+			v.IndexType = gl.UNSIGNED_SHORT
+			v.ElementBuffer = glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, short, gl.STATIC_DRAW)
+		} else {
+			v.ElementBuffer = glutil.MakeBuffer(gl.ELEMENT_ARRAY_BUFFER, m.Indices, gl.STATIC_DRAW)
+		}
+	}
+
+	gl.VertexAttribPointer(PositionLocation, 3, gl.FLOAT, false, stride, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(PositionLocation)
+	gl.VertexAttribPointer(NormalLocation, 3, gl.FLOAT, false, stride, gl.PtrOffset(12))
+	gl.EnableVertexAttribArray(NormalLocation)
+	gl.VertexAttribPointer(UVLocation, 2, gl.FLOAT, false, stride, gl.PtrOffset(24))
+	gl.EnableVertexAttribArray(UVLocation)
+	gl.VertexAttribPointer(TangentLocation, 3, gl.FLOAT, false, stride, gl.PtrOffset(32))
+	gl.EnableVertexAttribArray(TangentLocation)
+
+	gl.BindVertexArray(0)
+	return v
+}
+
+// shortIndices returns the indices as 16-bit values, if they all fit. The
+// largest value is left out, as it is often used as the primitive restart
+// index, which would then change.
+func shortIndices(indices []uint32) ([]uint16, bool) {
+	short := make([]uint16, len(indices))
+	for i, index := range indices {
+		if index >= math.MaxUint16 {
+			return nil, false
+		}
+		short[i] = uint16(index)
+	}
+	return short, true
+}
+
+// indexSize is the number of bytes of an index.
+func (v *VAO) indexSize() int {
+	if v.IndexType == gl.UNSIGNED_SHORT {
+		return 2
+	}
+	return 4
+}
+
+// AddSkin adds a buffer with, for each vertex, the indices of four joints
+// and their weights, for skinning in the vertex shader.
+func (v *VAO) AddSkin(joints [][4]uint16, weights []glm.Vec4) {
+	// four shorts and four floats per vertex
+	const skinStride = 2*4 + 4*4
+	data := make([]byte, 0, len(joints)*skinStride)
+	for i := range joints {
+		for _, j := range joints[i] {
+			data = append(data, byte(j), byte(j>>8))
+		}
+		for _, w := range weights[i] {
+			b := math.Float32bits(w)
+			data = append(data, byte(b), byte(b>>8), byte(b>>16), byte(b>>24))
+		}
+	}
+
+	gl.BindVertexArray(v.VertexArray)
+	if v.SkinBuffer != 0 {
+		gl.DeleteBuffers(1, &v.SkinBuffer)
+	}
+	v.SkinBuffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
+	gl.VertexAttribIPointer(JointsLocation, 4, gl.UNSIGNED_SHORT, skinStride, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(JointsLocation)
+	gl.VertexAttribPointer(WeightsLocation, 4, gl.FLOAT, false, skinStride, gl.PtrOffset(8))
+	gl.EnableVertexAttribArray(WeightsLocation)
+	gl.BindVertexArray(0)
+}
+
+// SetInstances replaces the instances drawn by DrawInstanced. It can be
+// called every frame.
+func (v *VAO) SetInstances(instances []Instance) {
+	const instanceStride = 4 * (16 + 4)
+
+	v.Instances = int32(len(instances))
+	if v.InstanceBuffer == 0 {
+		gl.GenBuffers(1, &v.InstanceBuffer)
+		gl.BindVertexArray(v.VertexArray)
+		gl.BindBuffer(gl.ARRAY_BUFFER, v.InstanceBuffer)
+		for i := uint32(0); i < 4; i++ {
+			gl.VertexAttribPointer(InstanceModelLocation+i, 4, gl.FLOAT, false, instanceStride, gl.PtrOffset(16*int(i)))
+			gl.EnableVertexAttribArray(InstanceModelLocation + i)
+			gl.VertexAttribDivisor(InstanceModelLocation+i, 1)
+		}
+		gl.VertexAttribPointer(InstanceDataLocation, 4, gl.FLOAT, false, instanceStride, gl.PtrOffset(64))
+		gl.EnableVertexAttribArray(InstanceDataLocation)
+		gl.VertexAttribDivisor(InstanceDataLocation, 1)
+		gl.BindVertexArray(0)
+	}
+	if len(instances) == 0 {
+		return
+	}
+	// a new buffer each time, so the driver doesn't have to wait until the
+	// previous frame is drawn
+	gl.BindBuffer(gl.ARRAY_BUFFER, v.InstanceBuffer)
+	gl.BufferData(gl.ARRAY_BUFFER, instanceStride*len(instances), nil, gl.STREAM_DRAW)
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, instanceStride*len(instances), gl.Ptr(&instances[0]))
+	app.CountUpload(instanceStride * len(instances))
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// Draw draws all triangles.
+func (v *VAO) Draw() {
+	gl.BindVertexArray(v.VertexArray)
+	gl.DrawElements(gl.TRIANGLES, v.Count, v.IndexType, gl.PtrOffset(0))
+	app.CountDrawCalls(1)
+	gl.BindVertexArray(0)
+}
+
+// DrawRange draws count indices, starting at index first, as triangles.
+func (v *VAO) DrawRange(first, count int32) {
+	gl.BindVertexArray(v.VertexArray)
+	gl.DrawElements(gl.TRIANGLES, count, v.IndexType, gl.PtrOffset(v.indexSize()*int(first)))
+	app.CountDrawCalls(1)
+	gl.BindVertexArray(0)
+}
+
+// DrawInstanced draws all triangles once for each instance set with
+// SetInstances.
+func (v *VAO) DrawInstanced() {
+	if v.Instances == 0 {
+		return
+	}
+	gl.BindVertexArray(v.VertexArray)
+	gl.DrawElementsInstanced(gl.TRIANGLES, v.Count, v.IndexType, gl.PtrOffset(0), v.Instances)
+	app.CountDrawCalls(1)
+	gl.BindVertexArray(0)
+}
+
+// Delete deletes the vertex array object and its buffers.
+func (v *VAO) Delete() {
+	gl.DeleteVertexArrays(1, &v.VertexArray)
+	gl.DeleteBuffers(1, &v.VertexBuffer)
+	gl.DeleteBuffers(1, &v.ElementBuffer)
+	if v.SkinBuffer != 0 {
+		gl.DeleteBuffers(1, &v.SkinBuffer)
+	}
+	if v.InstanceBuffer != 0 {
+		gl.DeleteBuffers(1, &v.InstanceBuffer)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+uniform vec3 lightDirection;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    float diffuse = max(dot(normalize(worldNormal), lightDirection), 0.0);
+    fragColor = vec4((0.3 + 0.7 * diffuse) * color, 1.0);
+}
+` + "\x00"
+)
+
+// Extra actions for this demo.
+const (
+	actionMap     = "map"
+	actionZoomIn  = "zoomin"
+	actionZoomOut = "zoomout"
+)
+
+// Size of the minimap as a fraction of the window height, and the width of
+// its border in pixels.
+const (
+	mapSize   = .3
+	mapBorder = 3
+)
+
+//
+// The scene: a maze-like field of walls
+//
+
+type tWall struct {
+	position glm.Vec3
+	size     glm.Vec3 // half the size on each axis
+	color    glm.Vec3
+}
+
+var walls []tWall
+
+func makeWalls() {
+	walls = append(walls, tWall{glm.Vec3{0, -.1, 0}, glm.Vec3{30, .1, 30}, glm.Vec3{.45, .5, .4}})
+	for i := -5; i <= 5; i++ {
+		for j := -5; j <= 5; j++ {
+			if (i*7+j*3)%4 == 0 || i == 0 && j == 0 {
+				continue
+			}
+			// walls along x or z, pseudo-randomly
+			size := glm.Vec3{2.5, 1, .25}
+			if (i*i+j)%3 == 0 {
+				size = glm.Vec3{.25, 1, 2.5}
+			}
+			c := .5 + .3*float32(math.Sin(float64(i+2*j)))
+			walls = append(walls, tWall{
+				position: glm.Vec3{float32(i) * 5, 1, float32(j) * 5},
+				size:     size,
+				color:    glm.Vec3{c, .5, 1 - c},
+			})
+		}
+	}
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	cube    *mesh.VAO
+	sphere  *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		cube:   mesh.Cube().Upload(),
+		sphere: mesh.Sphere(24, 12).Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam     *camera.FreeFly
+	showMap = true
+	mapArea = float32(20) // half the width of the world shown on the minimap
+)
+
+var stateScene = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func draw(r *gResources, v *mesh.VAO, model glm.Mat4, color glm.Vec3) {
+	p := r.program
+	p.SetMat4("model", model)
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	p.SetVec3("color", color)
+	v.Draw()
+}
+
+func drawScene(r *gResources) {
+	for _, wl := range walls {
+		draw(r, r.cube, glm.Translate(wl.position[0], wl.position[1], wl.position[2]).Mul(glm.Scale(wl.size[0], wl.size[1], wl.size[2])), wl.color)
+	}
+}
+
+// drawPlayer draws the position of the camera, with a pointer in the
+// direction it looks at. It is only drawn on the minimap.
+func drawPlayer(r *gResources) {
+	pos := cam.Position
+	draw(r, r.sphere, glm.Translate(pos[0], 3, pos[2]).Mul(glm.Scale(.8, .8, .8)), glm.Vec3{1, .2, .2})
+	model := glm.Translate(pos[0], 3, pos[2]).
+		Mul(glm.Rotate(-cam.Yaw, glm.Vec3{0, 1, 0})).
+		Mul(glm.Translate(0, 0, -1.2)).
+		Mul(glm.Scale(.25, .25, 1))
+	draw(r, r.cube, model, glm.Vec3{1, .2, .2})
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+
+	p := r.program
+	p.Use()
+	p.SetVec3("lightDirection", glm.Vec3{.4, 1, .6}.Normalize())
+
+	// the view of the camera, in the whole window
+	gl.Viewport(0, 0, int32(width), int32(height))
+	stateScene.Apply()
+	gl.ClearColor(app.Background(.6, .7, .85, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 200))
+	drawScene(r)
+
+	if !showMap {
+		return
+	}
+
+	// The minimap, in the top right corner. Clearing only clears the
+	// scissor rectangle, so first a larger rectangle for the border, then
+	// the map itself.
+	size := int32(mapSize * float32(height))
+	x0 := int32(width) - size - 10
+	y0 := int32(height) - size - 10
+
+	state := glutil.State{
+		Scissor: &glutil.Scissor{X: x0 - mapBorder, Y: y0 - mapBorder, Width: size + 2*mapBorder, Height: size + 2*mapBorder},
+	}
+	state.Apply()
+	gl.ClearColor(1, 1, 1, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	state = stateScene
+	state.Scissor = &glutil.Scissor{X: x0, Y: y0, Width: size, Height: size}
+	state.Apply()
+	gl.ClearColor(.2, .2, .2, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// looking straight down on the camera, with north up
+	gl.Viewport(x0, y0, size, size)
+	pos := cam.Position
+	p.SetMat4("view", glm.LookAt(glm.Vec3{pos[0], 50, pos[2]}, glm.Vec3{pos[0], 0, pos[2]}, glm.Vec3{0, 0, -1}))
+	p.SetMat4("projection", glm.Ortho(-mapArea, mapArea, -mapArea, mapArea, 1, 100))
+	drawScene(r)
+	drawPlayer(r)
+
+	stateScene.Apply()
+}
+
+func main() {
+	app.Keys[actionMap] = []string{"m"}
+	app.Keys[actionZoomIn] = []string{"]"}
+	app.Keys[actionZoomOut] = []string{"["}
+	app.Flags(800, 600, "Minimap")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewFreeFly(glm.Vec3{0, 1.5, 0})
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	makeWalls()
+	r := makeResources()
+
+	app.Info("Drag with the mouse to look around, W, A, S and D to move")
+	app.Info("Press 'm' to toggle the minimap, '[' and ']' to zoom the minimap")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		cam.Update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionMap:
+		showMap = !showMap
+	case actionZoomIn:
+		if mapArea > 5 {
+			mapArea /= 1.25
+		}
+	case actionZoomOut:
+		if mapArea < 60 {
+			mapArea *= 1.25
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec4 color;
+uniform vec3 lightDirection;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    float diffuse = max(dot(normalize(worldNormal), lightDirection), 0.0);
+    fragColor = vec4((0.25 + 0.75 * diffuse) * color.rgb, color.a);
+}
+` + "\x00"
+)
+
+// Extra action for this demo.
+const actionStencil = "stencil"
+
+// Half the size of the mirror on the floor.
+const mirrorSize = 2
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	cube    *mesh.VAO
+	sphere  *mesh.VAO
+	quad    *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		cube:   mesh.Cube().Upload(),
+		sphere: mesh.Sphere(32, 16).Upload(),
+		quad:   mesh.Quad().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock   = app.NewClock()
+	cam     *camera.Orbit
+	stencil = true
+)
+
+var (
+	stateScene = glutil.State{
+		DepthTest: true,
+		CullFace:  true,
+	}
+
+	// The mirror is drawn into the stencil buffer only.
+	stateMirrorMask = glutil.State{
+		DepthTest:    true,
+		NoDepthWrite: true,
+		NoColorWrite: true,
+		Stencil: &glutil.Stencil{
+			Func:      gl.ALWAYS,
+			Ref:       1,
+			Mask:      0xff,
+			WriteMask: 0xff,
+			Pass:      gl.REPLACE,
+		},
+	}
+
+	// The reflected scene is drawn only where the mirror is. Mirroring
+	// turns the triangles inside out, so the front faces are culled.
+	stateReflection = glutil.State{
+		DepthTest: true,
+		CullFace:  true,
+		CullMode:  gl.FRONT,
+		Stencil: &glutil.Stencil{
+			Func: gl.EQUAL,
+			Ref:  1,
+			Mask: 0xff,
+		},
+	}
+
+	// Without the stencil test, the reflection shows outside the mirror.
+	stateReflectionNoStencil = glutil.State{
+		DepthTest: true,
+		CullFace:  true,
+		CullMode:  gl.FRONT,
+	}
+
+	// The mirror itself, a tinted glass over the reflection.
+	stateMirror = glutil.State{
+		DepthTest: true,
+		Blend:     true,
+	}
+)
+
+// drawScene draws the objects above the floor, with transform applied
+// after their own model matrix.
+func drawScene(r *gResources, transform glm.Mat4) {
+	t := float32(clock.Seconds())
+	p := r.program
+
+	draw := func(v *mesh.VAO, model glm.Mat4, color glm.Vec4) {
+		model = transform.Mul(model)
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetVec4("color", color)
+		v.Draw()
+	}
+
+	draw(r.cube,
+		glm.Translate(-.8, .8, 0).Mul(glm.Rotate(t/2, glm.Vec3{1, 1, 0}.Normalize())).Mul(glm.Scale(.4, .4, .4)),
+		glm.Vec4{.9, .4, .2, 1})
+	draw(r.sphere,
+		glm.Translate(.8, .7+.2*float32(math.Sin(2*float64(t))), 0).Mul(glm.Scale(.45, .45, .45)),
+		glm.Vec4{.2, .5, .9, 1})
+	draw(r.cube,
+		glm.Rotate(t/3, glm.Vec3{0, 1, 0}).Mul(glm.Translate(0, .3, 1.2)).Mul(glm.Scale(.3, .3, .3)),
+		glm.Vec4{.3, .8, .3, 1})
+}
+
+func drawMirror(r *gResources, color glm.Vec4) {
+	model := glm.Rotate(-glm.Radians(90), glm.Vec3{1, 0, 0}).Mul(glm.Scale(mirrorSize, mirrorSize, 1))
+	p := r.program
+	p.SetMat4("model", model)
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	p.SetVec4("color", color)
+	r.quad.Draw()
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.2, .2, .25, 0))
+	gl.ClearStencil(0)
+	stateScene.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+
+	// keep the camera above the mirror
+	if cam.Pitch < .05 {
+		cam.Pitch = .05
+	}
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100))
+	p.SetVec3("lightDirection", glm.Vec3{.4, 1, .6}.Normalize())
+
+	// pass 1: mark the mirror in the stencil buffer
+	stateMirrorMask.Apply()
+	drawMirror(r, glm.Vec4{})
+
+	// pass 2: the scene mirrored in the floor, inside the mark
+	if stencil {
+		stateReflection.Apply()
+	} else {
+		stateReflectionNoStencil.Apply()
+	}
+	drawScene(r, glm.Scale(1, -1, 1))
+
+	// pass 3: the mirror, blended over the reflection
+	stateMirror.Apply()
+	drawMirror(r, glm.Vec4{.6, .6, .7, .4})
+
+	// pass 4: the scene itself
+	stateScene.Apply()
+	drawScene(r, glm.Identity())
+}
+
+func main() {
+	app.Keys[actionStencil] = []string{"s"}
+	app.Flags(640, 480, "Stencil mirror")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	glfw.WindowHint(glfw.StencilBits, 8)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewOrbit(glm.Vec3{0, .5, 0}, 7)
+	cam.Pitch = .5
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 's' to toggle the stencil test")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionStencil:
+		stencil = !stencil
+		if stencil {
+			app.Info("Stencil test on")
+		} else {
+			app.Info("Stencil test off")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
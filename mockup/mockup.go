@@ -0,0 +1,375 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/shapes"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+// Extra actions for this demo.
+const (
+	actionZoomIn  = "zoomin"
+	actionZoomOut = "zoomout"
+)
+
+// The size of the window of the mock-up, in design units.
+const cardWidth, cardHeight = 560, 420
+
+var (
+	accent    = glm.Vec4{.25, .5, .95, 1}
+	white     = glm.Vec4{1, 1, 1, 1}
+	lightGray = glm.Vec4{.88, .89, .91, 1}
+	midGray   = glm.Vec4{.7, .72, .75, 1}
+	darkGray  = glm.Vec4{.3, .32, .35, 1}
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	batch *shapes.Batch
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.batch, err = shapes.NewBatch(256)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	drag  = input.Drag{}
+
+	// The widgets.
+	switchOn  bool
+	switchPos float32 // of the knob, from 0 to 1, following switchOn
+	checked   = [2]bool{true, false}
+	radio     = 0
+	slider    = float32(.4)
+	sliding   bool
+	pressed   = -1 // the button that is held down
+
+	// The mock-up is drawn this many pixels per design unit.
+	zoom = float32(1)
+)
+
+// Positions of widgets, in design units.
+var (
+	switchMin, switchMax = glm.Vec2{428, 158}, glm.Vec2{492, 182}
+	checkX               = [2]float32{40, 240}
+	radioX               = [3]float32{40, 180, 320}
+	sliderMin, sliderMax = float32(40), float32(520)
+	buttonMin            = [2]glm.Vec2{{300, 352}, {420, 352}}
+	buttonMax            = [2]glm.Vec2{{400, 388}, {520, 388}}
+	buttonNames          = [2]string{"Cancel", "OK"}
+)
+
+// toDesign converts a cursor position in the window to design units.
+func toDesign(w *glfw.Window, cx, cy float64) glm.Vec2 {
+	ww, wh := w.GetSize()
+	ox := (float32(ww) - cardWidth*zoom) / 2
+	oy := (float32(wh) - cardHeight*zoom) / 2
+	return glm.Vec2{(float32(cx) - ox) / zoom, (float32(cy) - oy) / zoom}
+}
+
+func inside(p, min, max glm.Vec2) bool {
+	return p[0] >= min[0] && p[0] < max[0] && p[1] >= min[1] && p[1] < max[1]
+}
+
+func onPress(p glm.Vec2) {
+	switch {
+	case inside(p, switchMin, switchMax):
+		switchOn = !switchOn
+	case p[1] >= 300 && p[1] < 340 && p[0] >= sliderMin-10 && p[0] < sliderMax+10:
+		sliding = true
+		setSlider(p)
+	}
+	for i, cx := range checkX {
+		if inside(p, glm.Vec2{cx, 208}, glm.Vec2{cx + 150, 232}) {
+			checked[i] = !checked[i]
+		}
+	}
+	for i, cx := range radioX {
+		if inside(p, glm.Vec2{cx, 258}, glm.Vec2{cx + 120, 282}) {
+			radio = i
+		}
+	}
+	for i := range buttonMin {
+		if inside(p, buttonMin[i], buttonMax[i]) {
+			pressed = i
+		}
+	}
+}
+
+func onRelease(p glm.Vec2) {
+	if pressed >= 0 && inside(p, buttonMin[pressed], buttonMax[pressed]) {
+		app.Info("Clicked", buttonNames[pressed])
+	}
+	pressed = -1
+	sliding = false
+}
+
+func setSlider(p glm.Vec2) {
+	slider = (p[0] - sliderMin) / (sliderMax - sliderMin)
+	if slider < 0 {
+		slider = 0
+	} else if slider > 1 {
+		slider = 1
+	}
+}
+
+func update() {
+	// the knob of the switch slides over in a fifth of a second
+	step := float32(5 * clock.Delta())
+	if switchOn {
+		switchPos += step
+	} else {
+		switchPos -= step
+	}
+	if switchPos < 0 {
+		switchPos = 0
+	} else if switchPos > 1 {
+		switchPos = 1
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.55, .6, .68, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// design units, centered, with y down, in screen coordinates so the
+	// cursor maps directly
+	ww, wh := w.GetSize()
+	ox := (float32(ww) - cardWidth*zoom) / 2
+	oy := (float32(wh) - cardHeight*zoom) / 2
+	projection := glm.Ortho(0, float32(ww), float32(wh), 0, -1, 1).
+		Mul(glm.Translate(ox, oy, 0)).
+		Mul(glm.Scale(zoom, zoom, 1))
+
+	cx, cy := w.GetCursorPos()
+	hover := toDesign(w, cx, cy)
+
+	b := r.batch
+	b.Begin(projection)
+
+	// the window, with a shadow and a title bar
+	b.Rect(glm.Vec2{4, 8}, glm.Vec2{cardWidth + 4, cardHeight + 8}, 16, shapes.Style{Fill: glm.Vec4{0, 0, 0, .2}})
+	b.Rect(glm.Vec2{0, 0}, glm.Vec2{cardWidth, cardHeight}, 16, shapes.Style{Fill: white})
+	b.Rect(glm.Vec2{0, 0}, glm.Vec2{cardWidth, 44}, 16, shapes.Style{Fill: lightGray})
+	b.Rect(glm.Vec2{0, 28}, glm.Vec2{cardWidth, 44}, 0, shapes.Style{Fill: lightGray})
+	for i, c := range []glm.Vec4{{1, .37, .34, 1}, {1, .74, .18, 1}, {.16, .78, .25, 1}} {
+		b.Circle(glm.Vec2{24 + 22*float32(i), 22}, 7, shapes.Style{Fill: c, Border: darker(c), BorderWidth: 1})
+	}
+	label(b, 240, 22, 80, midGray)
+
+	// a user with a busy indicator
+	b.Ring(glm.Vec2{60, 100}, 33, 3, shapes.Style{Fill: accent})
+	b.Circle(glm.Vec2{60, 100}, 28, shapes.Style{Fill: glm.Vec4{.95, .75, .55, 1}})
+	b.Circle(glm.Vec2{60, 92}, 10, shapes.Style{Fill: white})
+	b.Capsule(glm.Vec2{50, 114}, glm.Vec2{70, 114}, 8, shapes.Style{Fill: white})
+	b.Capsule(glm.Vec2{110, 88}, glm.Vec2{260, 88}, 7, shapes.Style{Fill: darkGray})
+	label(b, 110, 112, 100, midGray)
+	t := float32(clock.Seconds())
+	for i := 0; i < 8; i++ {
+		a := float64(i) * math.Pi / 4
+		// the dots fade out behind the one in front, which goes round
+		// once a second
+		fade := float32(math.Mod(float64(8*t)-float64(i), 8)) / 8
+		b.Circle(glm.Vec2{500 + 18*float32(math.Cos(a)), 100 + 18*float32(math.Sin(a))}, 4,
+			shapes.Style{Fill: accent.Vec3().Vec4(1 - fade)})
+	}
+
+	b.Capsule(glm.Vec2{20, 140}, glm.Vec2{cardWidth - 20, 140}, .5, shapes.Style{Fill: lightGray})
+
+	// a switch
+	label(b, 40, 170, 140, midGray)
+	track := lerp(midGray, accent, switchPos)
+	b.Capsule(glm.Vec2{440, 170}, glm.Vec2{480, 170}, 12, shapes.Style{Fill: track})
+	b.Circle(glm.Vec2{440 + 40*switchPos, 170}, 10, shapes.Style{Fill: white, Border: darker(track), BorderWidth: 1})
+
+	// check boxes
+	for i, cx := range checkX {
+		min, max := glm.Vec2{cx, 208}, glm.Vec2{cx + 24, 232}
+		if checked[i] {
+			b.Rect(min, max, 5, shapes.Style{Fill: accent})
+			b.Capsule(glm.Vec2{cx + 6, 220}, glm.Vec2{cx + 10, 225}, 1.5, shapes.Style{Fill: white})
+			b.Capsule(glm.Vec2{cx + 10, 225}, glm.Vec2{cx + 18, 214}, 1.5, shapes.Style{Fill: white})
+		} else {
+			border := midGray
+			if inside(hover, min, glm.Vec2{cx + 150, 232}) {
+				border = accent
+			}
+			b.Rect(min, max, 5, shapes.Style{Fill: white, Border: border, BorderWidth: 2})
+		}
+		label(b, cx+36, 220, 90, darkGray)
+	}
+
+	// radio buttons
+	for i, cx := range radioX {
+		center := glm.Vec2{cx + 12, 270}
+		if i == radio {
+			b.Ring(center, 10, 2, shapes.Style{Fill: accent})
+			b.Circle(center, 5, shapes.Style{Fill: accent})
+		} else {
+			b.Ring(center, 10, 2, shapes.Style{Fill: midGray})
+		}
+		label(b, cx+34, 270, 70, darkGray)
+	}
+
+	// a slider
+	knob := sliderMin + slider*(sliderMax-sliderMin)
+	b.Capsule(glm.Vec2{sliderMin, 320}, glm.Vec2{sliderMax, 320}, 3, shapes.Style{Fill: lightGray})
+	b.Capsule(glm.Vec2{sliderMin, 320}, glm.Vec2{knob, 320}, 3, shapes.Style{Fill: accent})
+	knobRadius := float32(9)
+	if sliding {
+		knobRadius = 11
+	}
+	b.Circle(glm.Vec2{knob, 320}, knobRadius, shapes.Style{Fill: white, Border: accent, BorderWidth: 2})
+
+	// buttons, lighter under the cursor, darker when pressed
+	for i := range buttonMin {
+		fill, border, text := white, midGray, darkGray
+		if i == 1 {
+			fill, border, text = accent, darker(accent), white
+		}
+		switch {
+		case i == pressed:
+			fill = darker(fill)
+		case inside(hover, buttonMin[i], buttonMax[i]):
+			fill = lerp(fill, white, .2)
+		}
+		b.Rect(buttonMin[i], buttonMax[i], 8, shapes.Style{Fill: fill, Border: border, BorderWidth: 1})
+		center := buttonMin[i].Add(buttonMax[i]).Mul(.5)
+		label(b, center[0]-20, center[1], 40, text)
+	}
+
+	b.End()
+}
+
+// label adds a bar where text would be, from x, centered vertically on y.
+func label(b *shapes.Batch, x0, y, length float32, c glm.Vec4) {
+	b.Capsule(glm.Vec2{x0 + 5, y}, glm.Vec2{x0 + length - 5, y}, 5, shapes.Style{Fill: c})
+}
+
+func darker(c glm.Vec4) glm.Vec4 {
+	return c.Vec3().Mul(.8).Vec4(c[3])
+}
+
+func lerp(a, b glm.Vec4, t float32) glm.Vec4 {
+	return glm.Vec4{
+		a[0] + (b[0]-a[0])*t,
+		a[1] + (b[1]-a[1])*t,
+		a[2] + (b[2]-a[2])*t,
+		a[3] + (b[3]-a[3])*t,
+	}
+}
+
+func main() {
+	app.Keys[actionZoomIn] = []string{"]"}
+	app.Keys[actionZoomOut] = []string{"["}
+	app.Flags(800, 600, "UI mock-up")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+	drag.OnStart = func(cx, cy float64) { onPress(toDesign(w, cx, cy)) }
+	drag.OnMove = func(cx, cy, dx, dy float64) {
+		if sliding {
+			setSlider(toDesign(w, cx, cy))
+		}
+	}
+	drag.OnEnd = func(cx, cy float64) { onRelease(toDesign(w, cx, cy)) }
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+
+	app.Info("All shapes are signed distance functions, with smooth edges at any size")
+	app.Info("Click on the switch, the check boxes, the radio buttons and the buttons, drag the slider")
+	app.Info("Press '[' and ']' to zoom")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionZoomIn:
+		if zoom < 8 {
+			zoom *= 1.25
+		}
+	case actionZoomOut:
+		if zoom > .25 {
+			zoom /= 1.25
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package noise contains GLSL implementations of procedural noise, to be
+// included in shader source after the #version line:
+//
+//	float perlin(vec3 p)    gradient noise, from -1 to 1
+//	float simplex(vec3 p)   simplex noise, from -1 to 1
+//	float worley(vec3 p)    distance to the nearest feature point, from 0 to about 1
+//
+// and fractal sums of octaves of these:
+//
+//	float fractalNoise(vec3 p, int type, int octaves, float lacunarity, float gain)
+//
+// where type is one of the constants Perlin, Simplex and Worley. Each octave
+// has lacunarity times the frequency, and gain times the amplitude, of the
+// previous octave. The result is from -1 to 1.
+//
+// The noise is 3D, so 2D noise can be animated by using time as the third
+// coordinate.
+package noise
+
+import (
+	"fmt"
+)
+
+// Noise types for fractalNoise.
+const (
+	Perlin = iota
+	Simplex
+	Worley
+)
+
+// Names of the noise types.
+var Names = []string{"Perlin", "simplex", "Worley"}
+
+// GLSL has the functions described in the package documentation.
+var GLSL = fmt.Sprintf(`
+// a pseudo-random vector in [0, 1) for each point, without sin, so it is
+// the same on all GPUs
+vec3 hash33(vec3 p)
+{
+    p = fract(p * vec3(0.1031, 0.1030, 0.0973));
+    p += dot(p, p.yxz + 33.33);
+    return fract((p.xxy + p.yxx) * p.zyx);
+}
+
+// the contribution of the gradient at corner o of the cell at i, for the
+// offset f from i
+float gradientAt(vec3 i, vec3 f, vec3 o)
+{
+    return dot(2.0 * hash33(i + o) - 1.0, f - o);
+}
+
+float perlin(vec3 p)
+{
+    vec3 i = floor(p);
+    vec3 f = fract(p);
+    // quintic interpolation, with continuous second derivatives
+    vec3 u = f * f * f * (f * (f * 6.0 - 15.0) + 10.0);
+    return mix(
+        mix(mix(gradientAt(i, f, vec3(0, 0, 0)), gradientAt(i, f, vec3(1, 0, 0)), u.x),
+            mix(gradientAt(i, f, vec3(0, 1, 0)), gradientAt(i, f, vec3(1, 1, 0)), u.x), u.y),
+        mix(mix(gradientAt(i, f, vec3(0, 0, 1)), gradientAt(i, f, vec3(1, 0, 1)), u.x),
+            mix(gradientAt(i, f, vec3(0, 1, 1)), gradientAt(i, f, vec3(1, 1, 1)), u.x), u.y),
+        u.z) * 1.4;
+}
+
+float simplex(vec3 p)
+{
+    const float F3 = 1.0 / 3.0;
+    const float G3 = 1.0 / 6.0;
+
+    // the corner of the simplex cell, and the offset from it
+    vec3 s = floor(p + dot(p, vec3(F3)));
+    vec3 x0 = p - s + dot(s, vec3(G3));
+
+    // which of the six tetrahedra of the cell, by the order of the
+    // components of x0
+    vec3 e = step(vec3(0.0), x0 - x0.yzx);
+    vec3 i1 = e * (1.0 - e.zxy);
+    vec3 i2 = 1.0 - e.zxy * (1.0 - e);
+
+    vec3 x1 = x0 - i1 + G3;
+    vec3 x2 = x0 - i2 + 2.0 * G3;
+    vec3 x3 = x0 - 1.0 + 3.0 * G3;
+
+    vec4 w = max(0.6 - vec4(dot(x0, x0), dot(x1, x1), dot(x2, x2), dot(x3, x3)), 0.0);
+    vec4 d = vec4(
+        dot(2.0 * hash33(s) - 1.0, x0),
+        dot(2.0 * hash33(s + i1) - 1.0, x1),
+        dot(2.0 * hash33(s + i2) - 1.0, x2),
+        dot(2.0 * hash33(s + 1.0) - 1.0, x3));
+    w *= w;
+    w *= w;
+    return 26.0 * dot(d, w);
+}
+
+float worley(vec3 p)
+{
+    vec3 i = floor(p);
+    vec3 f = fract(p);
+    float nearest = 1.0;
+    for (int z = -1; z <= 1; z++) {
+        for (int y = -1; y <= 1; y++) {
+            for (int x = -1; x <= 1; x++) {
+                // one feature point in each cell
+                vec3 o = vec3(x, y, z);
+                vec3 d = o + hash33(i + o) - f;
+                nearest = min(nearest, dot(d, d));
+            }
+        }
+    }
+    return sqrt(nearest);
+}
+
+float fractalNoise(vec3 p, int type, int octaves, float lacunarity, float gain)
+{
+    float sum = 0.0;
+    float amplitude = 1.0;
+    float total = 0.0;
+    for (int i = 0; i < octaves; i++) {
+        float n;
+        if (type == %d) {
+            n = perlin(p);
+        } else if (type == %d) {
+            n = simplex(p);
+        } else {
+            n = 2.0 * worley(p) - 1.0;
+        }
+        sum += amplitude * n;
+        total += amplitude;
+        amplitude *= gain;
+        // shifted, so the octaves don't line up at the origin
+        p = p * lacunarity + vec3(1.7, 9.2, 3.1);
+    }
+    return sum / total;
+}
+`, Perlin, Simplex)
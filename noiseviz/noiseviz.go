@@ -0,0 +1,259 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/noise"
+	"github.com/pebbe/gl/palette"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var fragment_glsl = `
+#version 330 core
+` + noise.GLSL + palette.GLSL + `
+uniform vec2 offset;     // position of the lower left corner
+uniform float scale;     // units per pixel
+uniform float time;
+uniform int type;
+uniform int octaves;
+uniform float lacunarity;
+uniform float gain;
+uniform int coloring;    // 0: gray, 1: palette, 2: shaded relief
+uniform Palette colors;
+
+out vec4 fragColor;
+
+float height(vec2 p)
+{
+    return fractalNoise(vec3(p, 0.2 * time), type, octaves, lacunarity, gain);
+}
+
+void main()
+{
+    vec2 p = offset + gl_FragCoord.xy * scale;
+    float n = height(p);
+    vec3 c;
+    if (coloring == 0) {
+        c = vec3(0.5 + 0.5 * n);
+    } else if (coloring == 1) {
+        c = palette(colors, 0.5 + 0.5 * n);
+    } else {
+        // the noise as a landscape, lit from the upper left
+        float h = scale;
+        vec3 normal = normalize(vec3(height(p - vec2(h, 0.0)) - height(p + vec2(h, 0.0)), height(p - vec2(0.0, h)) - height(p + vec2(0.0, h)), 40.0 * h));
+        float light = max(dot(normal, normalize(vec3(-1.0, 1.0, 1.0))), 0.0);
+        vec3 ground = n < 0.0 ? vec3(0.1, 0.3, 0.6) : mix(vec3(0.3, 0.6, 0.2), vec3(0.9), smoothstep(0.3, 0.6, n));
+        c = ground * (0.2 + 0.8 * light);
+    }
+    fragColor = vec4(c, 1.0);
+}
+`
+
+// Extra actions for this demo.
+const (
+	actionType           = "type"
+	actionColoring       = "coloring"
+	actionMoreOctaves    = "moreoctaves"
+	actionFewerOctaves   = "feweroctaves"
+	actionMoreLacunarity = "morelacunarity"
+	actionLessLacunarity = "lesslacunarity"
+	actionMoreGain       = "moregain"
+	actionLessGain       = "lessgain"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	drag  input.Drag
+
+	// the point at the center of the window, and the zoom
+	centerX, centerY float32
+	scale            float32 = .01
+
+	noiseType  = noise.Perlin
+	coloring   int
+	octaves    int32   = 5
+	lacunarity float32 = 2
+	gain       float32 = .5
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	// scale is per screen pixel, the framebuffer may have more pixels
+	ww, _ := w.GetSize()
+	s := scale * float32(ww) / float32(width)
+
+	p := r.program
+	p.Use()
+	p.SetVec2("offset", glm.Vec2{centerX - s*float32(width)/2, centerY - s*float32(height)/2})
+	p.SetFloat("scale", s)
+	p.SetFloat("time", float32(clock.Seconds()))
+	p.SetInt("type", int32(noiseType))
+	p.SetInt("octaves", octaves)
+	p.SetFloat("lacunarity", lacunarity)
+	p.SetFloat("gain", gain)
+	p.SetInt("coloring", int32(coloring))
+	palette.Sunset.Set(p, "colors")
+	glutil.DrawFullscreen()
+}
+
+// scroll zooms in or out around the center.
+func scroll(w *glfw.Window, xoff, yoff float64) {
+	scale *= float32(math.Pow(.9, yoff))
+}
+
+func printSettings() {
+	app.Infof("%s noise, %d octaves, lacunarity %.2f, gain %.2f", noise.Names[noiseType], octaves, lacunarity, gain)
+}
+
+func main() {
+	app.Keys[actionType] = []string{"n"}
+	app.Keys[actionColoring] = []string{"c"}
+	app.Keys[actionMoreOctaves] = []string{"O"}
+	app.Keys[actionFewerOctaves] = []string{"o"}
+	app.Keys[actionMoreLacunarity] = []string{"L"}
+	app.Keys[actionLessLacunarity] = []string{"l"}
+	app.Keys[actionMoreGain] = []string{"G"}
+	app.Keys[actionLessGain] = []string{"g"}
+	app.Flags(800, 600, "Noise")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	drag.OnMove = func(x, y, dx, dy float64) {
+		centerX -= float32(dx) * scale
+		centerY += float32(dy) * scale
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+	w.SetScrollCallback(scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to move, scroll to zoom")
+	app.Info("Press 'n' to change the type of noise, 'c' to change the coloring")
+	app.Info("Press 'o' and 'O' to change the number of octaves, 'l' and 'L' the lacunarity, 'g' and 'G' the gain")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	printSettings()
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionType:
+		noiseType = (noiseType + 1) % len(noise.Names)
+		printSettings()
+	case actionColoring:
+		coloring = (coloring + 1) % 3
+	case actionMoreOctaves:
+		if octaves < 10 {
+			octaves++
+		}
+		printSettings()
+	case actionFewerOctaves:
+		if octaves > 1 {
+			octaves--
+		}
+		printSettings()
+	case actionMoreLacunarity:
+		lacunarity += .1
+		printSettings()
+	case actionLessLacunarity:
+		if lacunarity > 1.15 {
+			lacunarity -= .1
+		}
+		printSettings()
+	case actionMoreGain:
+		if gain < .95 {
+			gain += .05
+		}
+		printSettings()
+	case actionLessGain:
+		if gain > .15 {
+			gain -= .05
+		}
+		printSettings()
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
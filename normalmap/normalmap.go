@@ -0,0 +1,322 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 texcoord;
+layout(location = 3) in vec3 tangent;
+
+out vec3 worldPosition;
+out vec2 uv;
+out mat3 tbn;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    uv = texcoord;
+
+    vec3 n = normalize(normalMatrix * normal);
+    vec3 t = normalize(normalMatrix * tangent);
+    tbn = mat3(t, cross(n, t), n);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D diffuseMap;
+uniform sampler2D normalMap;
+uniform bool useNormalMap;
+
+uniform vec3 lightPosition;
+uniform vec3 viewPosition;
+
+in vec3 worldPosition;
+in vec2 uv;
+in mat3 tbn;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = tbn[2];
+    if (useNormalMap) {
+        n = tbn * (texture(normalMap, uv).rgb * 2.0 - 1.0);
+    }
+    n = normalize(n);
+
+    vec3 l = normalize(lightPosition - worldPosition);
+    vec3 v = normalize(viewPosition - worldPosition);
+    vec3 h = normalize(l + v);
+
+    vec3 base = texture(diffuseMap, uv).rgb;
+    vec3 result = 0.1 * base + max(dot(n, l), 0.0) * base + 0.3 * pow(max(dot(n, h), 0.0), 64.0);
+    fragColor = vec4(result, 1.0);
+}
+` + "\x00"
+)
+
+// Extra action for this demo.
+const actionNormalMap = "normalmap"
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program    *glutil.Program
+	quad       *mesh.VAO
+	diffuseMap uint32
+	normalMap  uint32
+}
+
+//
+// The brick texture, and its normal map, derived from a height map
+//
+
+const (
+	textureSize = 256
+	brickWidth  = 64
+	brickHeight = 32
+	mortar      = 4
+	bevel       = 4
+)
+
+// brickHeightAt returns the height of the wall at pixel x, y: 0 in the
+// mortar, rising to 1 over the bevel at the edges of a brick.
+func brickHeightAt(x, y int) float64 {
+	x = (x + textureSize) % textureSize
+	y = (y + textureSize) % textureSize
+	row := y / brickHeight
+	if row%2 == 1 {
+		x = (x + brickWidth/2) % textureSize
+	}
+	bx := x % brickWidth
+	by := y % brickHeight
+	d := min4(bx-mortar/2, brickWidth-mortar/2-1-bx, by-mortar/2, brickHeight-mortar/2-1-by)
+	if d < 0 {
+		return 0
+	}
+	if d < bevel {
+		return float64(d+1) / float64(bevel+1)
+	}
+	return 1
+}
+
+func min4(a, b, c, d int) int {
+	m := a
+	for _, v := range []int{b, c, d} {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func makeBrickTextures() (diffuse, normal *image.RGBA) {
+	diffuse = image.NewRGBA(image.Rect(0, 0, textureSize, textureSize))
+	normal = image.NewRGBA(image.Rect(0, 0, textureSize, textureSize))
+	const strength = 2.0
+	for y := 0; y < textureSize; y++ {
+		for x := 0; x < textureSize; x++ {
+			h := brickHeightAt(x, y)
+			if h > 0 {
+				// vary the color a bit per brick
+				row := y / brickHeight
+				col := (x + row%2*brickWidth/2) / brickWidth
+				v := uint8(20 * ((row*7 + col*13) % 5))
+				diffuse.SetRGBA(x, y, color.RGBA{150 + v, 60 + v/2, 40, 255})
+			} else {
+				diffuse.SetRGBA(x, y, color.RGBA{170, 170, 160, 255})
+			}
+
+			// y goes down in the image, the green channel points up
+			dx := (brickHeightAt(x+1, y) - brickHeightAt(x-1, y)) / 2
+			dy := (brickHeightAt(x, y+1) - brickHeightAt(x, y-1)) / 2
+			n := glm.Vec3{float32(-strength * dx), float32(strength * dy), 1}.Normalize()
+			normal.SetRGBA(x, y, color.RGBA{
+				uint8(127.5 + 127.5*n[0]),
+				uint8(127.5 + 127.5*n[1]),
+				uint8(127.5 + 127.5*n[2]),
+				255,
+			})
+		}
+	}
+	return
+}
+
+func makeResources() *gResources {
+	quad := mesh.Quad()
+	quad.ComputeTangents()
+	r := &gResources{
+		quad: quad.Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	diffuse, normal := makeBrickTextures()
+	r.diffuseMap = glutil.MakeTextureFromImage(diffuse)
+	r.normalMap = glutil.MakeTextureFromImage(normal)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock        = app.NewClock()
+	zoom         = input.NewZoom(.5, 3)
+	useNormalMap = true
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, 0, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	t := clock.Seconds()
+
+	eye := glm.Vec3{0, 0, 3.5 / float32(zoom.Scale())}
+	view := glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0})
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100)
+
+	// the wall turns a little from side to side, the light circles in
+	// front of it
+	model := glm.Rotate(.4*float32(math.Sin(t/3)), glm.Vec3{0, 1, 0})
+	lightPosition := glm.Vec3{
+		float32(math.Cos(t)),
+		float32(math.Sin(t)),
+		.6,
+	}
+
+	p := r.program
+	p.Use()
+	p.SetMat4("model", model)
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	p.SetVec3("lightPosition", lightPosition)
+	p.SetVec3("viewPosition", eye)
+	p.SetBool("useNormalMap", useNormalMap)
+
+	p.SetInt("diffuseMap", 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, r.diffuseMap)
+	p.SetInt("normalMap", 1)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, r.normalMap)
+
+	r.quad.Draw()
+
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func main() {
+	app.Keys[actionNormalMap] = []string{"n"}
+	app.Flags(640, 480, "Normal mapping")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Press 'n' to toggle the normal map")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionNormalMap:
+		useNormalMap = !useNormalMap
+		if useNormalMap {
+			app.Info("Normal map: on")
+		} else {
+			app.Info("Normal map: off")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
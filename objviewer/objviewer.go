@@ -0,0 +1,333 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/stereo"
+
+	"flag"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 uv;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+out vec2 texcoord;
+
+void main()
+{
+    gl_Position = projection * view * vec4(position, 1.0);
+    worldPosition = position;
+    worldNormal = normal;
+    texcoord = uv;
+}
+`
+
+	// Blinn-Phong, with the light at the camera.
+	fragment_glsl = `
+#version 330 core
+
+struct Material {
+    vec3 ambient;
+    vec3 diffuse;
+    vec3 specular;
+    float shininess;
+    bool hasTexture;
+};
+
+uniform Material material;
+uniform sampler2D diffuseMap;
+uniform vec3 eye;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+in vec2 texcoord;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 albedo = material.diffuse;
+    if (material.hasTexture) {
+        albedo *= texture(diffuseMap, texcoord).rgb;
+    }
+    vec3 n = normalize(worldNormal);
+    vec3 l = normalize(eye - worldPosition);
+    if (!gl_FrontFacing) {
+        n = -n;
+    }
+    float diffuse = max(dot(n, l), 0.0);
+    // the light is at the eye, so the half vector is l
+    float specular = pow(diffuse, max(material.shininess, 1.0));
+    vec3 c = (material.ambient + 0.15) * albedo + diffuse * albedo + specular * material.specular;
+    fragColor = vec4(c, 1.0);
+}
+`
+)
+
+var model = flag.String("model", "", "Wavefront OBJ file (default: a built-in model)")
+
+// Extra action for this demo.
+const actionNormals = "normals"
+
+// The model shown without -model: a small house.
+const (
+	house_obj = `
+v -1 0 -1
+v 1 0 -1
+v 1 0 1
+v -1 0 1
+v -1 1.2 -1
+v 1 1.2 -1
+v 1 1.2 1
+v -1 1.2 1
+v 0 2 -1
+v 0 2 1
+usemtl walls
+f 5 6 2 1
+f 6 7 3 2
+f 7 8 4 3
+f 8 5 1 4
+f 7 10 8
+f 5 9 6
+usemtl roof
+f 6 9 10 7
+f 8 10 9 5
+usemtl floor
+f 1 2 3 4
+`
+	house_mtl = `
+newmtl walls
+Kd 0.9 0.85 0.7
+newmtl roof
+Kd 0.7 0.2 0.15
+Ks 0.3 0.3 0.3
+Ns 20
+newmtl floor
+Kd 0.4 0.4 0.4
+`
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program  *glutil.Program
+	model    *mesh.Model
+	vao      *mesh.VAO
+	textures map[string]uint32 // by file name
+	normals  *mesh.NormalLines
+	stereo   *stereo.Renderer
+}
+
+func loadModel() *mesh.Model {
+	if *model != "" {
+		m, err := mesh.LoadOBJ(*model)
+		x(err)
+		return m
+	}
+	m, err := mesh.ReadOBJ(strings.NewReader(house_obj))
+	x(err)
+	m.Materials, err = mesh.ReadMTL(strings.NewReader(house_mtl))
+	x(err)
+	return m
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		model:    loadModel(),
+		textures: make(map[string]uint32),
+	}
+	r.vao = r.model.Mesh.Upload()
+
+	for _, m := range r.model.Materials {
+		if m.DiffuseMap == "" || r.textures[m.DiffuseMap] != 0 {
+			continue
+		}
+		texture, err := glutil.MakeTexture(m.DiffuseMap)
+		if err != nil {
+			app.Error(err)
+			continue
+		}
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+		r.textures[m.DiffuseMap] = texture
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.normals, err = mesh.NewNormalLines()
+	x(err)
+
+	mode, err := stereo.FlagMode()
+	x(err)
+	r.stereo, err = stereo.New(mode)
+	x(err)
+
+	return r
+}
+
+// bounds returns the center of the mesh, and the radius of a sphere around
+// it that contains all vertices.
+func bounds(m *mesh.Mesh) (center glm.Vec3, radius float32) {
+	s := m.BoundingSphere()
+	if s.Radius == 0 {
+		s.Radius = 1
+	}
+	return s.Center, s.Radius
+}
+
+//
+// Update and render
+//
+
+var (
+	cam *camera.Orbit
+
+	showNormals int // 0: off, 1: vertex normals, 2: vertex and face normals
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+}
+
+// Used for groups without a known material.
+var defaultMaterial = &mesh.Material{
+	Diffuse:   glm.Vec3{.8, .8, .8},
+	Shininess: 32,
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	near := cam.Distance / 100
+	r.stereo.Convergence = cam.Distance
+	err := r.stereo.Render(int32(width), int32(height), cam.View(), glm.Radians(45), near, near*10000, func(view, projection glm.Mat4) {
+		drawScene(r, view, projection)
+	})
+	x(err)
+}
+
+func drawScene(r *gResources, view, projection glm.Mat4) {
+	state3D.Apply()
+	gl.ClearColor(app.Background(.2, .2, .25, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("eye", cam.Eye())
+	p.SetInt("diffuseMap", 0)
+
+	for _, g := range r.model.Groups {
+		m, ok := r.model.Materials[g.Material]
+		if !ok {
+			m = defaultMaterial
+		}
+		p.SetVec3("material.ambient", m.Ambient)
+		p.SetVec3("material.diffuse", m.Diffuse)
+		p.SetVec3("material.specular", m.Specular)
+		p.SetFloat("material.shininess", m.Shininess)
+		texture := r.textures[m.DiffuseMap]
+		p.SetBool("material.hasTexture", texture != 0)
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+		r.vao.DrawRange(g.First, g.Count)
+	}
+
+	if showNormals > 0 {
+		r.normals.FaceNormals = showNormals == 2
+		r.normals.Draw(r.vao, glm.Identity(), view, projection)
+	}
+}
+
+func main() {
+	app.Keys[actionNormals] = []string{"n"}
+	app.Flags(800, 600, "OBJ viewer")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	center, radius := bounds(r.model.Mesh)
+	cam = camera.NewOrbit(center, 2.5*radius)
+	cam.Pitch = .3
+	r.normals.Length = radius / 20
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	app.Infof("%d vertices, %d triangles, %d materials", len(r.model.Mesh.Vertices), len(r.model.Mesh.Indices)/3, len(r.model.Materials))
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 'n' to show vertex normals, then face normals too, then none")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionNormals:
+		showNormals = (showNormals + 1) % 3
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
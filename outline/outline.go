@@ -0,0 +1,366 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/easing"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform vec2 offset;
+uniform vec2 aspect;
+uniform float angle;
+uniform float scale;
+
+in vec2 position;
+
+void main()
+{
+    float s = sin(angle);
+    float c = cos(angle);
+    vec2 p = scale * vec2(c*position.x - s*position.y, s*position.x + c*position.y);
+    gl_Position = vec4(aspect * (p + offset), 0.0, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec4 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = color;
+}
+` + "\x00"
+)
+
+//
+// Global data used by render
+//
+
+type tUniforms struct {
+	offset int32
+	aspect int32
+	angle  int32
+	scale  int32
+	color  int32
+}
+
+type tShape struct {
+	first  int32 // first vertex in the buffer
+	count  int32 // number of vertices
+	offset [2]float32
+	color  [3]float32
+}
+
+type gResources struct {
+	vertexArray  uint32
+	vertexBuffer uint32
+	program      uint32
+	uniforms     tUniforms
+	position     uint32
+
+	shapes []tShape
+}
+
+//
+// Pipeline states for the three kinds of drawing
+//
+
+var (
+	// Shapes that are not selected leave the stencil buffer unchanged.
+	stateNormal = glutil.State{}
+
+	// The selected shape writes 1 into the stencil buffer.
+	stateMask = glutil.State{
+		Stencil: &glutil.Stencil{
+			Func:      gl.ALWAYS,
+			Ref:       1,
+			Mask:      0xff,
+			WriteMask: 0xff,
+			Pass:      gl.REPLACE,
+		},
+	}
+
+	// The outline is drawn only where the selected shape was not drawn.
+	stateOutline = glutil.State{
+		Stencil: &glutil.Stencil{
+			Func:      gl.NOTEQUAL,
+			Ref:       1,
+			Mask:      0xff,
+			WriteMask: 0,
+		},
+	}
+)
+
+//
+// Shapes: polygons and stars, as triangle fans around the center
+//
+
+// Number of corners, and inner radius for stars (or 0 for polygons).
+var shapeDefs = []struct {
+	corners int
+	inner   float64
+}{
+	{3, 0},
+	{4, 0},
+	{6, 0},
+	{5, .45},
+	{6, .6},
+	{12, .75},
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.MakeProgramFromSource(vertex_glsl, fragment_glsl)
+	x(err)
+
+	r.uniforms.offset = gl.GetUniformLocation(r.program, gl.Str("offset\x00"))
+	r.uniforms.aspect = gl.GetUniformLocation(r.program, gl.Str("aspect\x00"))
+	r.uniforms.angle = gl.GetUniformLocation(r.program, gl.Str("angle\x00"))
+	r.uniforms.scale = gl.GetUniformLocation(r.program, gl.Str("scale\x00"))
+	r.uniforms.color = gl.GetUniformLocation(r.program, gl.Str("color\x00"))
+	r.position = uint32(gl.GetAttribLocation(r.program, gl.Str("position\x00")))
+
+	vertices := make([]float32, 0)
+	for i, def := range shapeDefs {
+		points := def.corners
+		if def.inner > 0 {
+			points *= 2
+		}
+		shape := tShape{
+			first: int32(len(vertices) / 2),
+			count: int32(points + 2),
+			offset: [2]float32{
+				float32(i%3)*.6 - .6,
+				.3 - float32(i/3)*.6,
+			},
+		}
+		shape.color[0], shape.color[1], shape.color[2] = hsb2rgb(float32(i)/float32(len(shapeDefs)), .7, .8)
+
+		vertices = append(vertices, 0, 0)
+		for j := 0; j <= points; j++ {
+			a := 2 * math.Pi * float64(j) / float64(points)
+			rd := 1.0
+			if def.inner > 0 && j%2 == 1 {
+				rd = def.inner
+			}
+			vertices = append(vertices, float32(rd*math.Sin(a)), float32(rd*math.Cos(a)))
+		}
+		r.shapes = append(r.shapes, shape)
+	}
+
+	gl.GenVertexArrays(1, &r.vertexArray)
+	gl.BindVertexArray(r.vertexArray)
+	r.vertexBuffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, vertices, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(
+		r.position,      // attribute
+		2,               // size
+		gl.FLOAT,        // type
+		false,           // normalized?
+		8,               // stride
+		gl.PtrOffset(0)) // array buffer offset
+	gl.EnableVertexAttribArray(r.position)
+
+	return r
+}
+
+//
+// Rendering
+//
+
+const shapeScale = .25
+
+var (
+	clock    = app.NewClock()
+	zoom     = input.NewZoom(.5, 4)
+	selected = 0
+	ax, ay   float32 // aspect correction, including zoom
+)
+
+func drawShape(r *gResources, s tShape, scale float32, red, green, blue float32) {
+	gl.Uniform2f(r.uniforms.offset, s.offset[0], s.offset[1])
+	gl.Uniform1f(r.uniforms.scale, scale)
+	gl.Uniform4f(r.uniforms.color, red, green, blue, 1)
+	gl.DrawArrays(gl.TRIANGLE_FAN, s.first, s.count)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	// the shapes grow into view at the start
+	z := float32(zoom.Scale() * easing.OutElastic(clock.Seconds()/1.5))
+	ax, ay = z, z
+	if width > height {
+		ax *= float32(height) / float32(width)
+	} else {
+		ay *= float32(width) / float32(height)
+	}
+
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.5, .5, .5, 0))
+	gl.ClearStencil(0)
+	stateNormal.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+
+	gl.UseProgram(r.program)
+	gl.BindVertexArray(r.vertexArray)
+	gl.Uniform2f(r.uniforms.aspect, ax, ay)
+	gl.Uniform1f(r.uniforms.angle, float32(clock.Seconds()/2))
+
+	// pass 1: all shapes, the selected one also into the stencil buffer
+	for i, s := range r.shapes {
+		if i == selected {
+			stateMask.Apply()
+		} else {
+			stateNormal.Apply()
+		}
+		drawShape(r, s, shapeScale, s.color[0], s.color[1], s.color[2])
+	}
+
+	// pass 2: the selected shape, scaled up, outside the stencil mask
+	if selected >= 0 {
+		stateOutline.Apply()
+		drawShape(r, r.shapes[selected], shapeScale*1.12, 1, 1, 0)
+	}
+
+	stateNormal.Apply()
+}
+
+// selectAt selects the shape whose bounding circle contains the window
+// position cx, cy, or no shape if there is none.
+func selectAt(w *glfw.Window, r *gResources, cx, cy float64) {
+	width, height := w.GetSize()
+	// window coordinates to normalized device coordinates, to scene coordinates
+	px := (2*cx/float64(width) - 1) / float64(ax)
+	py := (1 - 2*cy/float64(height)) / float64(ay)
+
+	selected = -1
+	for i, s := range r.shapes {
+		dx := px - float64(s.offset[0])
+		dy := py - float64(s.offset[1])
+		if dx*dx+dy*dy < shapeScale*shapeScale {
+			selected = i
+		}
+	}
+}
+
+func main() {
+	app.Flags(640, 480, "Stencil outline")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.StencilBits, 8)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+	clicked := false
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button == glfw.MouseButtonLeft && action == glfw.Press {
+			clicked = true
+		}
+	})
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Click on a shape to outline it")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		if clicked {
+			clicked = false
+			cx, cy := w.GetCursorPos()
+			selectAt(w, r, cx, cy)
+		}
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	m := b - c
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c + m, x + m, m
+	}
+	if h < 2 {
+		return x + m, c + m, m
+	}
+	if h < 3 {
+		return m, c + m, x + m
+	}
+	if h < 4 {
+		return m, x + m, c + m
+	}
+	if h < 5 {
+		return x + m, m, c + m
+	}
+	return c + m, m, x + m
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+
+	"math"
+	"runtime"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+var (
+	// A square around each stamp of the brush, from gl_VertexID.
+	stamp_vertex_glsl = `
+#version 330 core
+
+uniform mat4 projection;
+
+layout(location = 0) in vec3 stamp; // center, radius
+layout(location = 1) in vec4 stampColor;
+
+out vec2 local;
+flat out float radius;
+flat out vec4 color;
+
+void main()
+{
+    // a triangle strip: (-1, -1), (1, -1), (-1, 1), (1, 1)
+    local = vec2(gl_VertexID & 1, gl_VertexID >> 1) * 2.0 - 1.0;
+    radius = stamp.z;
+    color = stampColor;
+    gl_Position = projection * vec4(stamp.xy + (stamp.z + 1.0) * local, 0.0, 1.0);
+}
+`
+
+	// A round brush that fades out from hardness to its edge, with at
+	// least a pixel of anti-aliasing. As an outline, only a ring at the
+	// edge is drawn.
+	stamp_fragment_glsl = `
+#version 330 core
+
+uniform float hardness;
+uniform bool outline;
+
+in vec2 local;
+flat in float radius;
+flat in vec4 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    // distance from the center in pixels, the square is a pixel larger
+    float d = length(local) * (radius + 1.0);
+    float alpha;
+    if (outline) {
+        alpha = 1.0 - smoothstep(0.0, 1.0, abs(d - radius));
+    } else {
+        alpha = 1.0 - smoothstep(min(hardness * radius, radius - 1.0), radius, d);
+    }
+    if (alpha <= 0.0) {
+        discard;
+    }
+    fragColor = vec4(color.rgb, color.a * alpha);
+}
+`
+
+	// Shows the canvas in the window.
+	show_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D canvas;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(texture(canvas, uv).rgb, 1.0);
+}
+`
+)
+
+// Extra actions for this demo. The colors are selected with actions
+// "color1" to "color9".
+const (
+	actionLarger   = "larger"
+	actionSmaller  = "smaller"
+	actionHarder   = "harder"
+	actionSofter   = "softer"
+	actionOpaque   = "opaque"
+	actionClear    = "clear"
+	actionColorKey = "color"
+)
+
+var (
+	background = glm.Vec4{.95, .93, .88, 1}
+
+	// nine colors around the color wheel, and black
+	palette = func() []glm.Vec3 {
+		p := []glm.Vec3{{.1, .1, .1}}
+		for i := 0; i < 8; i++ {
+			p = append(p, color.HSV(float32(i)/8, .8, .9))
+		}
+		return p
+	}()
+)
+
+// The brush.
+var (
+	brushColor    = palette[0]
+	brushSize     = float32(12) // radius in canvas pixels
+	brushHardness = float32(.5) // fraction of the radius that is fully opaque
+	brushOpacity  = float32(.5)
+)
+
+// Stamps are this fraction of the radius apart along a stroke.
+const spacing = .25
+
+//
+// Global data used by render
+//
+
+// Data of a stamp for the shader.
+type tStamp struct {
+	Center glm.Vec2
+	Radius float32
+	Color  glm.Vec4
+}
+
+const stampSize = int(unsafe.Sizeof(tStamp{}))
+
+// Room in the stamp buffer, for the stamps of a single frame.
+const maxStamps = 10000
+
+type gResources struct {
+	stamp *glutil.Program
+	show  *glutil.Program
+
+	// The painting, which persists between frames.
+	canvas *glutil.Framebuffer
+
+	vao    uint32
+	stamps *glutil.StreamBuffer
+}
+
+func makeResources(width, height int32) *gResources {
+	r := &gResources{}
+
+	var err error
+	r.stamp, err = glutil.NewProgram(stamp_vertex_glsl, stamp_fragment_glsl)
+	x(err)
+	r.show, err = glutil.NewProgram(glutil.FullscreenVertexShader, show_fragment_glsl)
+	x(err)
+
+	r.canvas, err = glutil.NewFramebuffer(width, height)
+	x(err)
+	r.canvas.SetFilter(gl.LINEAR)
+	clearCanvas(r)
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.BindVertexArray(r.vao)
+	r.stamps = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, maxStamps*stampSize)
+	gl.EnableVertexAttribArray(0)
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribDivisor(0, 1)
+	gl.VertexAttribDivisor(1, 1)
+	gl.BindVertexArray(0)
+
+	return r
+}
+
+func clearCanvas(r *gResources) {
+	r.canvas.Bind()
+	gl.ClearColor(background[0], background[1], background[2], background[3])
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	r.canvas.Unbind()
+}
+
+//
+// Update and render
+//
+
+var (
+	paintDrag = input.Drag{Button: glfw.MouseButtonLeft}
+	eraseDrag = input.Drag{Button: glfw.MouseButtonRight}
+
+	// Stamps not drawn into the canvas yet, and the color, the last stamp
+	// and the distance since that stamp of the current stroke.
+	pending     []tStamp
+	strokeColor glm.Vec4
+	last        glm.Vec2
+	travelled   float32
+)
+
+var stateStamps = glutil.State{
+	Blend: true,
+}
+
+// toCanvas converts a cursor position in the window to canvas pixels.
+func toCanvas(w *glfw.Window, r *gResources, x, y float64) glm.Vec2 {
+	sw, sh := w.GetSize()
+	return glm.Vec2{
+		float32(x / float64(sw) * float64(r.canvas.Width)),
+		float32((1 - y/float64(sh)) * float64(r.canvas.Height)),
+	}
+}
+
+// startStroke begins a stroke with a single stamp. Erasing is painting
+// with the color of the background.
+func startStroke(p glm.Vec2, erase bool) {
+	if erase {
+		strokeColor = background.Vec3().Vec4(brushOpacity)
+	} else {
+		strokeColor = brushColor.Vec4(brushOpacity)
+	}
+	last = p
+	travelled = 0
+	if len(pending) < maxStamps {
+		pending = append(pending, tStamp{p, brushSize, strokeColor})
+	}
+}
+
+// continueStroke adds stamps at even distances along the line from the
+// last stamp to p.
+func continueStroke(p glm.Vec2) {
+	step := brushSize * spacing
+	if step < 1 {
+		step = 1
+	}
+	d := p.Sub(last)
+	length := float32(math.Hypot(float64(d[0]), float64(d[1])))
+	if length == 0 {
+		return
+	}
+	dir := d.Mul(1 / length)
+	for travelled+length >= step && len(pending) < maxStamps {
+		last = last.Add(dir.Mul(step - travelled))
+		length -= step - travelled
+		travelled = 0
+		pending = append(pending, tStamp{last, brushSize, strokeColor})
+	}
+	// the rest of the way counts towards the next stamp
+	travelled += length
+	last = p
+}
+
+// drawStamps draws the stamps that are pending into the canvas.
+func drawStamps(r *gResources) {
+	if len(pending) == 0 {
+		return
+	}
+	r.canvas.Bind()
+	drawBrush(r, pending, false, float32(r.canvas.Width), float32(r.canvas.Height))
+	r.canvas.Unbind()
+	pending = pending[:0]
+}
+
+// drawBrush draws stamps in a target of width by height pixels.
+func drawBrush(r *gResources, stamps []tStamp, outline bool, width, height float32) {
+	stateStamps.Apply()
+	p := r.stamp
+	p.Use()
+	p.SetMat4("projection", glm.Ortho(0, width, 0, height, -1, 1))
+	p.SetFloat("hardness", brushHardness)
+	p.SetBool("outline", outline)
+
+	gl.BindVertexArray(r.vao)
+	offset := r.stamps.Write(unsafe.Pointer(&stamps[0]), len(stamps)*stampSize)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.stamps.Buffer)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, int32(stampSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 4, gl.FLOAT, false, int32(stampSize), gl.PtrOffset(offset+int(unsafe.Offsetof(tStamp{}.Color))))
+	gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(len(stamps)))
+	r.stamps.Done()
+	gl.BindVertexArray(0)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	drawStamps(r)
+
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+	r.show.Use()
+	r.show.SetInt("canvas", 0)
+	gl.BindTexture(gl.TEXTURE_2D, r.canvas.Texture)
+	glutil.DrawFullscreen()
+
+	// the size of the brush at the cursor, in canvas coordinates, which
+	// are stretched over the window
+	cx, cy := w.GetCursorPos()
+	p := toCanvas(w, r, cx, cy)
+	drawBrush(r, []tStamp{{p, brushSize, glm.Vec4{.5, .5, .5, 1}}}, true, float32(r.canvas.Width), float32(r.canvas.Height))
+}
+
+func main() {
+	app.Keys[actionLarger] = []string{"]"}
+	app.Keys[actionSmaller] = []string{"["}
+	app.Keys[actionHarder] = []string{"h"}
+	app.Keys[actionSofter] = []string{"s"}
+	app.Keys[actionOpaque] = []string{"o"}
+	app.Keys[actionClear] = []string{"c"}
+	for i := range palette {
+		app.Keys[actionColorKey+strconv.Itoa(i+1)] = []string{strconv.Itoa(i + 1)}
+	}
+	app.Flags(1024, 768, "Paint")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	// the canvas has the size of the window as it is at the start
+	width, height := w.GetFramebufferSize()
+	r := makeResources(int32(width), int32(height))
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+	paintDrag.OnStart = func(x, y float64) { startStroke(toCanvas(w, r, x, y), false) }
+	paintDrag.OnMove = func(x, y, dx, dy float64) { continueStroke(toCanvas(w, r, x, y)) }
+	eraseDrag.OnStart = func(x, y float64) { startStroke(toCanvas(w, r, x, y), true) }
+	eraseDrag.OnMove = paintDrag.OnMove
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		paintDrag.MouseButton(w, button, action, mod)
+		eraseDrag.MouseButton(w, button, action, mod)
+	})
+	w.SetCursorPosCallback(func(w *glfw.Window, x, y float64) {
+		paintDrag.CursorPos(w, x, y)
+		eraseDrag.CursorPos(w, x, y)
+	})
+	w.SetScrollCallback(func(w *glfw.Window, xoff, yoff float64) {
+		resize(float32(math.Pow(1.1, yoff)))
+	})
+
+	app.Infof("Canvas of %d x %d pixels", r.canvas.Width, r.canvas.Height)
+	app.Info("Paint with the left mouse button, erase with the right button")
+	app.Infof("Press '1' to '%d' to select a color, '[' and ']' or scroll to change the size of the brush", len(palette))
+	app.Info("Press 'h' and 's' to make the brush harder or softer, 'o' to change the opacity, 'c' to clear")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+// resize changes the size of the brush by factor f.
+func resize(f float32) {
+	brushSize *= f
+	if brushSize < 1 {
+		brushSize = 1
+	} else if brushSize > 200 {
+		brushSize = 200
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionLarger:
+		resize(1.25)
+	case actionSmaller:
+		resize(1 / 1.25)
+	case actionHarder, actionSofter:
+		if action == actionHarder {
+			brushHardness += .1
+		} else {
+			brushHardness -= .1
+		}
+		if brushHardness < 0 {
+			brushHardness = 0
+		} else if brushHardness > 1 {
+			brushHardness = 1
+		}
+		app.Infof("Hardness: %.1f", brushHardness)
+	case actionOpaque:
+		// cycle through a few steps
+		brushOpacity += .25
+		if brushOpacity > 1 {
+			brushOpacity = .25
+		}
+		app.Infof("Opacity: %.2f", brushOpacity)
+	case actionClear:
+		pending = pending[:0]
+		clearCanvas(r)
+	default:
+		for i := range palette {
+			if action == actionColorKey+strconv.Itoa(i+1) {
+				brushColor = palette[i]
+			}
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package palette contains color gradients defined by cosine waves, as
+// described by Inigo Quilez, that can be evaluated in Go and in shaders:
+//
+//	color(t) = a + b * cos(2π(c * t + d))
+//
+// The gradients repeat with period 1 if the components of c are whole
+// numbers.
+//
+// To use a palette in a shader, include GLSL in the source, declare a
+// uniform Palette, set it with Palette.Set, and call palette(p, t).
+package palette
+
+import (
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"math"
+)
+
+// GLSL declares the struct Palette and the function palette, for use in
+// shader source after the #version line.
+const GLSL = `
+struct Palette {
+    vec3 a;
+    vec3 b;
+    vec3 c;
+    vec3 d;
+};
+
+vec3 palette(Palette p, float t)
+{
+    return p.a + p.b * cos(6.28318530718 * (p.c * t + p.d));
+}
+`
+
+// Palette is a gradient of colors, see the package documentation.
+type Palette struct {
+	Name       string
+	A, B, C, D glm.Vec3
+}
+
+// Some palettes.
+var (
+	Rainbow = Palette{"rainbow", glm.Vec3{.5, .5, .5}, glm.Vec3{.5, .5, .5}, glm.Vec3{1, 1, 1}, glm.Vec3{0, .33, .67}}
+	Fire    = Palette{"fire", glm.Vec3{.5, .5, .5}, glm.Vec3{.5, .5, .5}, glm.Vec3{1, .7, .4}, glm.Vec3{0, .15, .2}}
+	Ocean   = Palette{"ocean", glm.Vec3{.2, .4, .6}, glm.Vec3{.2, .3, .4}, glm.Vec3{1, 1, 1}, glm.Vec3{0, .1, .2}}
+	Sunset  = Palette{"sunset", glm.Vec3{.8, .5, .4}, glm.Vec3{.2, .4, .2}, glm.Vec3{2, 1, 1}, glm.Vec3{0, .25, .25}}
+	Gray    = Palette{"gray", glm.Vec3{.5, .5, .5}, glm.Vec3{.5, .5, .5}, glm.Vec3{1, 1, 1}, glm.Vec3{0, 0, 0}}
+)
+
+// All is a list of the palettes above.
+var All = []Palette{Rainbow, Fire, Ocean, Sunset, Gray}
+
+// At returns the color at t.
+func (p Palette) At(t float32) glm.Vec3 {
+	var c glm.Vec3
+	for i := range c {
+		c[i] = p.A[i] + p.B[i]*float32(math.Cos(2*math.Pi*float64(p.C[i]*t+p.D[i])))
+	}
+	return c
+}
+
+// Set sets the uniform with the given name, declared as Palette in the
+// shader, of the current program.
+func (p Palette) Set(prog *glutil.Program, name string) {
+	prog.SetVec3(name+".a", p.A)
+	prog.SetVec3(name+".b", p.B)
+	prog.SetVec3(name+".c", p.C)
+	prog.SetVec3(name+".d", p.D)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+
+	"flag"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+var (
+	// Each pixel casts a ray from the center of the sphere, and looks up
+	// where it hits in the equirectangular image: longitude from left to
+	// right, latitude from top to bottom.
+	fragment_glsl = `
+#version 330 core
+
+const float PI = 3.14159265358979;
+
+uniform sampler2D panorama;
+uniform vec3 right;
+uniform vec3 up;
+uniform vec3 forward;
+uniform vec2 scale; // tangent of half the field of view, horizontal and vertical
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec2 p = (2.0 * uv - 1.0) * scale;
+    vec3 d = normalize(p.x * right + p.y * up + forward);
+    float longitude = atan(d.x, -d.z);
+    float latitude = asin(clamp(d.y, -1.0, 1.0));
+    vec2 st = vec2(0.5 + longitude / (2.0 * PI), 0.5 - latitude / PI);
+
+    // Where longitude wraps around, st.x jumps from 1 to 0 between
+    // neighbouring pixels, which would select the smallest mipmap level.
+    vec2 dx = dFdx(st);
+    vec2 dy = dFdy(st);
+    dx.x -= round(dx.x);
+    dy.x -= round(dy.x);
+    fragColor = textureGrad(panorama, st, dx, dy);
+}
+` + "\x00"
+)
+
+var imageFile = flag.String("image", "", "equirectangular panorama image, instead of a generated one")
+
+// Field of view when not zoomed in or out, in degrees.
+const fieldOfView = 75
+
+// makePanorama returns an equirectangular image of a sky above a ground,
+// with lines of longitude and latitude every 15 degrees, and a red line for
+// the front.
+func makePanorama(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for j := 0; j < height; j++ {
+		latitude := 90 - 180*(float64(j)+.5)/float64(height)
+		for i := 0; i < width; i++ {
+			longitude := 360*(float64(i)+.5)/float64(width) - 180
+			var c color.RGBA
+			if latitude > 0 {
+				f := latitude / 90
+				c = color.RGBA{uint8(200 - 150*f), uint8(220 - 120*f), 255, 255}
+			} else {
+				f := -latitude / 90
+				c = color.RGBA{uint8(90 - 50*f), uint8(130 - 60*f), uint8(60 - 30*f), 255}
+			}
+			// width of a line in degrees, wider near the poles in longitude
+			lw := 180 / float64(height)
+			lwLon := lw / math.Max(math.Cos(latitude*math.Pi/180), .01)
+			switch {
+			case math.Abs(longitude) < lwLon:
+				c = color.RGBA{220, 40, 40, 255}
+			case math.Abs(math.Remainder(latitude, 15)) < lw, math.Abs(math.Remainder(longitude, 15)) < lwLon:
+				c = color.RGBA{c.R / 2, c.G / 2, c.B / 2, 255}
+			}
+			img.SetRGBA(i, j, c)
+		}
+	}
+	return img
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	texture uint32
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	if *imageFile != "" {
+		r.texture, err = glutil.MakeTexture(*imageFile)
+		x(err)
+	} else {
+		r.texture = glutil.MakeTextureFromImage(makePanorama(2048, 1024))
+	}
+	setupTexture(r.texture)
+
+	return r
+}
+
+// setupTexture adds mipmaps to the texture, and makes it wrap around in
+// longitude.
+func setupTexture(texture uint32) {
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+}
+
+// dropFiles replaces the panorama by the first image that can be loaded.
+func dropFiles(w *glfw.Window, r *gResources, names []string) {
+	for _, name := range names {
+		fp, err := os.Open(name)
+		if err != nil {
+			app.Error(err)
+			continue
+		}
+		texture, err := glutil.MakeTextureFromReader(fp)
+		fp.Close()
+		if err != nil {
+			app.Errorf("%s: %v", name, err)
+			continue
+		}
+		setupTexture(texture)
+		gl.DeleteTextures(1, &r.texture)
+		r.texture = texture
+		w.SetTitle(app.Title + " - " + filepath.Base(name))
+		return
+	}
+}
+
+//
+// Update and render
+//
+
+var (
+	// Direction of view, in radians. With yaw and pitch 0, the view is at
+	// the center of the image.
+	yaw, pitch float64
+
+	zoom = input.NewZoom(.7, 6)
+	drag input.Drag
+)
+
+// look turns the view, so that the image moves with the cursor.
+func look(w *glfw.Window, dx, dy float64) {
+	_, height := w.GetSize()
+	// radians per screen pixel, at the center of the window
+	f := 2 * math.Tan(fov()/2) / float64(height)
+	yaw -= dx * f
+	pitch += dy * f
+	limit := math.Pi/2 - .01
+	pitch = math.Max(-limit, math.Min(limit, pitch))
+}
+
+// fov returns the vertical field of view in radians.
+func fov() float64 {
+	return 2 * math.Atan(math.Tan(fieldOfView*math.Pi/360)/zoom.Scale())
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+
+	forward := glm.Vec3{
+		float32(math.Sin(yaw) * math.Cos(pitch)),
+		float32(math.Sin(pitch)),
+		float32(-math.Cos(yaw) * math.Cos(pitch)),
+	}
+	right := glm.Vec3{float32(math.Cos(yaw)), 0, float32(math.Sin(yaw))}
+	up := right.Cross(forward)
+	t := float32(math.Tan(fov() / 2))
+
+	p := r.program
+	p.Use()
+	p.SetVec3("forward", forward)
+	p.SetVec3("right", right)
+	p.SetVec3("up", up)
+	p.SetVec2("scale", glm.Vec2{t * float32(width) / float32(height), t})
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, r.texture)
+	p.SetInt("panorama", 0)
+	glutil.DrawFullscreen()
+}
+
+func main() {
+	app.Flags(960, 540, "Panorama")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+	drag.OnMove = func(x, y, dx, dy float64) { look(w, dx, dy) }
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+	w.SetScrollCallback(zoom.Scroll)
+	w.SetDropCallback(func(w *glfw.Window, names []string) {
+		dropFiles(w, r, names)
+	})
+
+	app.Info("Drag with the mouse to look around, scroll to zoom")
+	app.Info("Drop an equirectangular image on the window to view it")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
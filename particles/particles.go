@@ -0,0 +1,328 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/ecs"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// The particles are entities, with the components of package ecs, and a
+// lifetime. Each frame, the systems run in order: emit, age, physics and
+// render. This is the structure to use for demos that grow into games.
+
+var (
+	// A quad per particle, drawn with instancing by ecs.Renderer.
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 6) in mat4 model;
+layout(location = 10) in vec4 instanceColor;
+
+out vec2 local;
+out vec4 color;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    local = position.xy;
+    color = instanceColor;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+in vec2 local;
+in vec4 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    // a round, soft sprite
+    float d = length(local);
+    if (d > 1.0) {
+        discard;
+    }
+    fragColor = vec4(color.rgb, color.a * (1.0 - d * d));
+}
+` + "\x00"
+)
+
+var maxParticles = flag.Int("particles", 5000, "maximum number of particles")
+
+// Extra action for this demo.
+const actionMode = "mode"
+
+// Size of a particle with size 1: a pixel in a window 480 pixels high.
+const pixel = 1. / 480
+
+//
+// Particles
+//
+
+// The component for the time a particle has left.
+type tLife struct {
+	left float32 // seconds
+	span float32 // total lifetime
+}
+
+type tSystem struct {
+	world *ecs.World
+	lives *ecs.Store[tLife]
+
+	mesh    ecs.Mesh
+	program *glutil.Program
+
+	emit      float64 // particles waiting to be emitted, for the fountain
+	nextBurst float64 // seconds until the next firework
+}
+
+func newSystem(r *gResources) *tSystem {
+	s := &tSystem{
+		world:   ecs.NewWorld(),
+		mesh:    ecs.Mesh{VAO: r.quad},
+		program: r.program,
+	}
+	s.lives = ecs.NewStore[tLife](s.world)
+	return s
+}
+
+// add makes a particle at x, y, with a velocity, a color, a lifetime in
+// seconds, and a size in pixels.
+func (s *tSystem) add(x, y, vx, vy float32, r, g, b float32, life, size float32) {
+	if s.lives.Len() >= *maxParticles {
+		return
+	}
+	w := s.world
+	e := w.Spawn()
+	w.Transforms.Add(e, ecs.NewTransform(glm.Vec3{x, y, 0}, size*pixel))
+	w.Velocities.Add(e, ecs.Velocity{Linear: glm.Vec3{vx, vy, 0}})
+	w.Meshes.Add(e, s.mesh)
+	w.Materials.Add(e, ecs.Material{Program: s.program, Color: glm.Vec4{r, g, b, 1}})
+	s.lives.Add(e, tLife{left: life, span: life})
+}
+
+// fountain emits a steady stream of particles from the bottom.
+func (s *tSystem) fountain(dt float64) {
+	s.emit += dt * float64(*maxParticles) / 3
+	for ; s.emit >= 1; s.emit-- {
+		a := math.Pi/2 + .25*(rand.Float64()-.5)
+		v := 1.6 + .3*rand.Float64()
+		r, g, b := hsb2rgb(float32(.5+.15*rand.Float64()), .7, 1)
+		s.add(0, -1, float32(v*math.Cos(a)), float32(v*math.Sin(a)), r, g, b, 3, 4+4*rand.Float32())
+	}
+}
+
+// fireworks launches a burst of particles at a random position now and then.
+func (s *tSystem) fireworks(dt float64) {
+	s.nextBurst -= dt
+	if s.nextBurst > 0 {
+		return
+	}
+	s.nextBurst = .3 + .7*rand.Float64()
+
+	x := float32(1.4*rand.Float64() - .7)
+	y := float32(.8*rand.Float64() - .1)
+	r, g, b := hsb2rgb(rand.Float32(), .6, 1)
+	n := *maxParticles / 8
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * rand.Float64()
+		v := .6 * math.Sqrt(rand.Float64())
+		s.add(x, y, float32(v*math.Cos(a)), float32(v*math.Sin(a)), r, g, b, 1.5+rand.Float32(), 3+3*rand.Float32())
+	}
+}
+
+// age is the system that destroys the particles whose time is up, and
+// fades the others.
+func (s *tSystem) age(dt float32) {
+	s.lives.Each(func(e ecs.Entity, l *tLife) {
+		l.left -= dt
+		if l.left <= 0 {
+			s.world.Destroy(e)
+			return
+		}
+		s.world.Materials.Get(e).Color[3] = l.left / l.span
+	})
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program  *glutil.Program
+	quad     *mesh.VAO
+	renderer ecs.Renderer
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		quad: mesh.Quad().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock     = app.NewClock()
+	system    *tSystem
+	fireworks = false
+)
+
+// Particles add up their light.
+var stateAdditive = glutil.State{
+	Blend:    true,
+	BlendSrc: gl.SRC_ALPHA,
+	BlendDst: gl.ONE,
+}
+
+func update() {
+	dt := clock.Delta()
+	if fireworks {
+		system.fireworks(dt)
+		system.age(float32(dt))
+		ecs.Physics(system.world, float32(dt), glm.Vec3{0, -.3, 0}, 1.5)
+	} else {
+		system.fountain(dt)
+		system.age(float32(dt))
+		ecs.Physics(system.world, float32(dt), glm.Vec3{0, -1.2, 0}, .2)
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, 0, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	var ax, ay float32 = 1, 1
+	if width > height {
+		ax = float32(height) / float32(width)
+	} else {
+		ay = float32(width) / float32(height)
+	}
+
+	stateAdditive.Apply()
+	r.renderer.Draw(system.world, glm.Identity(), glm.Scale(ax, ay, 1))
+}
+
+func main() {
+	app.Keys[actionMode] = []string{"m"}
+	app.Flags(640, 480, "Particles")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	system = newSystem(r)
+
+	app.Info("Press 'm' to switch between fountain and fireworks")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionMode:
+		fireworks = !fireworks
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	m := b - c
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c + m, x + m, m
+	}
+	if h < 2 {
+		return x + m, c + m, m
+	}
+	if h < 3 {
+		return m, c + m, x + m
+	}
+	if h < 4 {
+		return m, x + m, c + m
+	}
+	if h < 5 {
+		return x + m, m, c + m
+	}
+	return c + m, m, x + m
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/postfx"
+	"github.com/pebbe/gl/sprite"
+	"github.com/pebbe/gl/text"
+
+	"flag"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	// Path tracing: from each pixel a random path, bouncing off the walls
+	// in random directions, picking up light along the way. One path per
+	// pixel is noisy, but the average of many converges to the image.
+	//
+	// The result is the running average of all samples so far, read from
+	// the previous frame, and written to the other framebuffer.
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D previous;
+uniform int samples; // in previous
+uniform int depth;   // maximum number of bounces
+uniform mat4 cameraToWorld;
+uniform vec2 resolution;
+uniform float tanHalfFov;
+
+out vec4 fragColor;
+
+const float PI = 3.14159265;
+const float INFINITY = 1e20;
+const float EPSILON = 0.0005;
+
+// The light in the ceiling, facing down.
+const vec3 lightMin = vec3(-0.25, 1.98, -0.25);
+const vec3 lightMax = vec3(0.25, 1.98, 0.25);
+const vec3 lightEmission = vec3(17.0, 12.0, 4.0);
+
+//
+// Random numbers
+//
+
+uint seed;
+
+// PCG hash, by Mark Jarzynski and Marc Olano
+uint pcg(uint v)
+{
+    uint state = v * 747796405u + 2891336453u;
+    uint word = ((state >> ((state >> 28u) + 4u)) ^ state) * 277803737u;
+    return (word >> 22u) ^ word;
+}
+
+// random returns a number from 0 to 1.
+float random()
+{
+    seed = pcg(seed);
+    return float(seed) / 4294967296.0;
+}
+
+// cosineSample returns a random direction around the normal, more often
+// near the normal, in proportion to the cosine of the angle.
+vec3 cosineSample(vec3 n)
+{
+    float phi = 2.0 * PI * random();
+    float r2 = random();
+    float r = sqrt(r2);
+    vec3 t = normalize(cross(n, abs(n.x) > 0.5 ? vec3(0.0, 1.0, 0.0) : vec3(1.0, 0.0, 0.0)));
+    vec3 b = cross(n, t);
+    return normalize(r * cos(phi) * t + r * sin(phi) * b + sqrt(1.0 - r2) * n);
+}
+
+//
+// The scene: a box open at the front, with two blocks inside
+//
+
+struct Hit {
+    float t; // distance along the ray, INFINITY for nothing
+    vec3 normal;
+    vec3 albedo;
+    vec3 emission;
+};
+
+// rect returns the distance along the ray to a rectangle perpendicular to
+// an axis, from corner lo to corner hi, or INFINITY. Both corners have the
+// same value for the axis.
+float rect(vec3 o, vec3 d, int axis, vec3 lo, vec3 hi)
+{
+    float t = (lo[axis] - o[axis]) / d[axis];
+    if (t < EPSILON) {
+        return INFINITY;
+    }
+    vec3 p = o + t * d;
+    p[axis] = lo[axis];
+    return all(greaterThanEqual(p, lo)) && all(lessThanEqual(p, hi)) ? t : INFINITY;
+}
+
+// block returns the distance along the ray to a box with the given half
+// size, at center, rotated around the y axis, or INFINITY. The normal is
+// set to the normal of the side that is hit.
+float block(vec3 o, vec3 d, vec3 center, vec3 halfSize, float angle, out vec3 normal)
+{
+    // into the space of the box
+    mat3 rot = mat3(cos(angle), 0.0, -sin(angle), 0.0, 1.0, 0.0, sin(angle), 0.0, cos(angle));
+    vec3 lo = transpose(rot) * (o - center);
+    vec3 ld = transpose(rot) * d;
+
+    // slabs: the ray is between each pair of sides from t1 to t2
+    vec3 t1 = (-halfSize - lo) / ld;
+    vec3 t2 = (halfSize - lo) / ld;
+    vec3 tNear = min(t1, t2);
+    vec3 tFar = max(t1, t2);
+    float near = max(max(tNear.x, tNear.y), tNear.z);
+    float far = min(min(tFar.x, tFar.y), tFar.z);
+    if (near > far || near < EPSILON) {
+        return INFINITY;
+    }
+    // the side that is entered last
+    normal = rot * (-sign(ld) * step(tNear.yzx, tNear) * step(tNear.zxy, tNear));
+    return near;
+}
+
+Hit trace(vec3 o, vec3 d)
+{
+    Hit h = Hit(INFINITY, vec3(0.0), vec3(0.0), vec3(0.0));
+    const vec3 white = vec3(0.73);
+    const vec3 red = vec3(0.65, 0.05, 0.05);
+    const vec3 green = vec3(0.12, 0.45, 0.15);
+
+    float t;
+    // the light, with all surfaces facing the ray
+    t = rect(o, d, 1, lightMin, lightMax);
+    if (t < h.t) {
+        h = Hit(t, vec3(0.0, -1.0, 0.0), vec3(0.0), d.y > 0.0 ? lightEmission : vec3(0.0));
+    }
+    // floor, ceiling, back
+    t = rect(o, d, 1, vec3(-1.0, 0.0, -1.0), vec3(1.0, 0.0, 1.0));
+    if (t < h.t) {
+        h = Hit(t, vec3(0.0, -sign(d.y), 0.0), white, vec3(0.0));
+    }
+    t = rect(o, d, 1, vec3(-1.0, 2.0, -1.0), vec3(1.0, 2.0, 1.0));
+    if (t < h.t) {
+        h = Hit(t, vec3(0.0, -sign(d.y), 0.0), white, vec3(0.0));
+    }
+    t = rect(o, d, 2, vec3(-1.0, 0.0, -1.0), vec3(1.0, 2.0, -1.0));
+    if (t < h.t) {
+        h = Hit(t, vec3(0.0, 0.0, -sign(d.z)), white, vec3(0.0));
+    }
+    // left and right
+    t = rect(o, d, 0, vec3(-1.0, 0.0, -1.0), vec3(-1.0, 2.0, 1.0));
+    if (t < h.t) {
+        h = Hit(t, vec3(-sign(d.x), 0.0, 0.0), red, vec3(0.0));
+    }
+    t = rect(o, d, 0, vec3(1.0, 0.0, -1.0), vec3(1.0, 2.0, 1.0));
+    if (t < h.t) {
+        h = Hit(t, vec3(-sign(d.x), 0.0, 0.0), green, vec3(0.0));
+    }
+
+    vec3 n;
+    t = block(o, d, vec3(-0.33, 0.6, -0.3), vec3(0.3, 0.6, 0.3), 0.3, n);
+    if (t < h.t) {
+        h = Hit(t, n, white, vec3(0.0));
+    }
+    t = block(o, d, vec3(0.35, 0.3, 0.35), vec3(0.3, 0.3, 0.3), -0.3, n);
+    if (t < h.t) {
+        h = Hit(t, n, white, vec3(0.0));
+    }
+    return h;
+}
+
+//
+// Light transport
+//
+
+// visible returns 1 if nothing is in the way from p in direction l, up to
+// the distance, 0 otherwise.
+float visible(vec3 p, vec3 l, float distance)
+{
+    return trace(p, l).t < distance - 2.0 * EPSILON ? 0.0 : 1.0;
+}
+
+// directLight returns the light reaching p from a random point on the
+// light, divided by pi, as needed for a diffuse surface.
+vec3 directLight(vec3 p, vec3 n)
+{
+    vec3 q = mix(lightMin, lightMax, vec3(random(), 0.0, random()));
+    vec3 l = q - p;
+    float distance = length(l);
+    l /= distance;
+    float cosSurface = dot(n, l);
+    float cosLight = l.y; // the light faces down
+    if (cosSurface <= 0.0 || cosLight <= 0.0) {
+        return vec3(0.0);
+    }
+    vec2 size = (lightMax - lightMin).xz;
+    float area = size.x * size.y;
+    return lightEmission * visible(p, l, distance) * cosSurface * cosLight * area / (PI * distance * distance);
+}
+
+vec3 radiance(vec3 o, vec3 d)
+{
+    vec3 c = vec3(0.0);
+    vec3 throughput = vec3(1.0);
+    for (int i = 0; i < depth; i++) {
+        Hit h = trace(o, d);
+        if (h.t == INFINITY) {
+            break;
+        }
+        if (h.emission != vec3(0.0)) {
+            // the light is only counted when seen directly, after a bounce
+            // it is already counted by directLight
+            if (i == 0) {
+                c += h.emission;
+            }
+            break;
+        }
+
+        vec3 p = o + h.t * d;
+        c += throughput * h.albedo * directLight(p, h.normal);
+
+        // With directions picked in proportion to the cosine, the cosine
+        // and the diffuse reflection of albedo / pi cancel out, but for
+        // the albedo.
+        throughput *= h.albedo;
+
+        // Russian roulette: stop dark paths early, and make up for it in
+        // the ones that go on
+        if (i >= 2) {
+            float q = max(throughput.r, max(throughput.g, throughput.b));
+            if (random() > q) {
+                break;
+            }
+            throughput /= q;
+        }
+
+        o = p + EPSILON * h.normal;
+        d = cosineSample(h.normal);
+    }
+    return c;
+}
+
+void main()
+{
+    seed = pcg(uint(gl_FragCoord.x) + pcg(uint(gl_FragCoord.y) + pcg(uint(samples))));
+
+    // a random point in the pixel, for antialiasing
+    vec2 pixel = gl_FragCoord.xy + vec2(random(), random()) - 0.5;
+    vec2 ndc = (2.0 * pixel - resolution) / resolution.y;
+    vec3 origin = cameraToWorld[3].xyz;
+    vec3 direction = normalize(mat3(cameraToWorld) * vec3(ndc * tanHalfFov, -1.0));
+    vec3 c = radiance(origin, direction);
+
+    if (samples > 0) {
+        vec3 average = texelFetch(previous, ivec2(gl_FragCoord.xy), 0).rgb;
+        c = mix(average, c, 1.0 / float(samples + 1));
+    }
+    fragColor = vec4(c, 1.0);
+}
+`
+)
+
+var (
+	maxSamples = flag.Int("samples", 10000, "stop after this number of samples per pixel")
+	depth      = flag.Int("depth", 8, "maximum number of bounces of a path")
+)
+
+// Extra actions for this demo.
+const (
+	actionBrighter = "brighter"
+	actionDarker   = "darker"
+	actionHUD      = "hud"
+)
+
+// Number of lines of the log shown on the HUD.
+const hudLogLines = 5
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	tonemap *postfx.ToneMap
+	hud     *sprite.Batch
+	font    *text.Font
+	graph   *text.FrameGraph
+
+	// The average so far is read from one, and the new average is written
+	// to the other. Then they are swapped.
+	accumulate [2]*glutil.Framebuffer
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		font: text.NewFont(2),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+	r.tonemap, err = postfx.NewToneMap()
+	x(err)
+	r.hud, err = sprite.NewBatch(256)
+	x(err)
+	r.graph, err = text.NewFrameGraph()
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam = camera.NewOrbit(glm.Vec3{0, 1, 0}, 3.9)
+
+	samples  = 0
+	lastView glm.Mat4
+	showHUD  = true
+
+	// for samples per second
+	lastTime    = time.Now()
+	lastSamples = 0
+	rate        = 0.0
+)
+
+const fov = 40 // degrees, vertical
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	if r.accumulate[0] == nil || r.accumulate[0].Width != int32(width) || r.accumulate[0].Height != int32(height) {
+		for i, f := range r.accumulate {
+			if f != nil {
+				f.Delete()
+			}
+			var err error
+			r.accumulate[i], err = glutil.NewMultiFramebuffer(int32(width), int32(height), glutil.FormatRGBA32F)
+			x(err)
+		}
+		samples = 0
+	}
+
+	// start over when the camera moves
+	view := cam.View()
+	if view != lastView {
+		lastView = view
+		samples = 0
+	}
+
+	if samples < *maxSamples {
+		src, dst := r.accumulate[0], r.accumulate[1]
+		dst.Bind()
+		glutil.State{}.Apply()
+		p := r.program
+		p.Use()
+		gl.ActiveTexture(gl.TEXTURE0)
+		gl.BindTexture(gl.TEXTURE_2D, src.Texture)
+		p.SetInt("previous", 0)
+		p.SetInt("samples", int32(samples))
+		p.SetInt("depth", int32(*depth))
+		p.SetMat4("cameraToWorld", view.Inverse())
+		p.SetVec2("resolution", glm.Vec2{float32(width), float32(height)})
+		p.SetFloat("tanHalfFov", float32(math.Tan(fov*math.Pi/360)))
+		glutil.DrawFullscreen()
+		dst.Unbind()
+
+		r.accumulate[0], r.accumulate[1] = dst, src
+		samples++
+	}
+
+	x(r.tonemap.Apply(r.accumulate[0].Texture, int32(width), int32(height), nil))
+
+	if now := time.Now(); now.Sub(lastTime) >= time.Second {
+		rate = float64(samples-lastSamples) / now.Sub(lastTime).Seconds()
+		if rate < 0 {
+			rate = 0
+		}
+		lastTime, lastSamples = now, samples
+	}
+
+	if showHUD {
+		ww, wh := w.GetSize()
+		s := fmt.Sprintf("Samples:  %d\nPer sec:  %.0f\nExposure: %.2f", samples, rate, r.tonemap.Exposure)
+		glutil.State{Blend: true}.Apply()
+		r.hud.Begin(glm.Ortho(0, float32(ww), float32(wh), 0, -1, 1))
+		// a shadow, to make it readable on a light background
+		r.font.Draw(r.hud, s, glm.Vec2{12, 12}, glm.Vec4{0, 0, 0, .8})
+		r.font.Draw(r.hud, s, glm.Vec2{10, 10}, glm.Vec4{1, 1, 1, 1})
+		// the last messages of the log at the bottom
+		r.font.DrawLog(r.hud, glm.Vec2{10, float32(wh) - hudLogLines*text.CellHeight*r.font.Scale - 10}, hudLogLines)
+		r.hud.End()
+		r.graph.Draw(width, height)
+	}
+}
+
+func main() {
+	app.Keys[actionBrighter] = []string{"up"}
+	app.Keys[actionDarker] = []string{"down"}
+	app.Keys[actionHUD] = []string{"F1"}
+	app.Flags(600, 600, "Path tracing")
+	if *maxSamples < 1 || *depth < 1 {
+		app.Fatal("-samples and -depth must be positive")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	// as many samples as possible, not limited by the refresh rate
+	glfw.SwapInterval(0)
+
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+	cam.MinDistance = 1
+	cam.MaxDistance = 10
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom, the image starts over")
+	app.Info("Press up and down to change the exposure, F1 to toggle the display of statistics, frame times and the log")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		if samples >= *maxSamples {
+			// nothing more to do, until the camera moves
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionHUD:
+		showHUD = !showHUD
+	case actionBrighter:
+		r.tonemap.Exposure *= 1.25
+	case actionDarker:
+		r.tonemap.Exposure /= 1.25
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,771 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+// Functions shared by the shaders below.
+const brdf_glsl = `
+const float PI = 3.14159265359;
+
+// Trowbridge-Reitz GGX normal distribution
+float distributionGGX(float nh, float roughness)
+{
+    float a = roughness * roughness;
+    float a2 = a * a;
+    float d = nh * nh * (a2 - 1.0) + 1.0;
+    return a2 / (PI * d * d);
+}
+
+// Smith's geometry function with Schlick-GGX, with k depending on the use
+float geometrySmith(float nv, float nl, float k)
+{
+    return nv / (nv * (1.0 - k) + k) * nl / (nl * (1.0 - k) + k);
+}
+
+// A low-discrepancy sequence for importance sampling
+vec2 hammersley(uint i, uint n)
+{
+    uint bits = i;
+    bits = (bits << 16u) | (bits >> 16u);
+    bits = ((bits & 0x55555555u) << 1u) | ((bits & 0xAAAAAAAAu) >> 1u);
+    bits = ((bits & 0x33333333u) << 2u) | ((bits & 0xCCCCCCCCu) >> 2u);
+    bits = ((bits & 0x0F0F0F0Fu) << 4u) | ((bits & 0xF0F0F0F0u) >> 4u);
+    bits = ((bits & 0x00FF00FFu) << 8u) | ((bits & 0xFF00FF00u) >> 8u);
+    return vec2(float(i) / float(n), float(bits) * 2.3283064365386963e-10);
+}
+
+// A half vector around n, distributed like the GGX distribution
+vec3 importanceSampleGGX(vec2 xi, vec3 n, float roughness)
+{
+    float a = roughness * roughness;
+    float phi = 2.0 * PI * xi.x;
+    float cosTheta = sqrt((1.0 - xi.y) / (1.0 + (a * a - 1.0) * xi.y));
+    float sinTheta = sqrt(1.0 - cosTheta * cosTheta);
+    vec3 up = abs(n.z) < 0.999 ? vec3(0.0, 0.0, 1.0) : vec3(1.0, 0.0, 0.0);
+    vec3 tx = normalize(cross(up, n));
+    vec3 ty = cross(n, tx);
+    return normalize(tx * cos(phi) * sinTheta + ty * sin(phi) * sinTheta + n * cosTheta);
+}
+`
+
+var (
+	//
+	// generating the maps for image-based lighting
+	//
+
+	// for rendering into the faces of a cube map
+	cube_vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+
+out vec3 direction;
+
+void main()
+{
+    direction = position;
+    gl_Position = projection * view * vec4(position, 1.0);
+}
+`
+
+	// A generated environment with high dynamic range: sky, sun and a
+	// checkered floor.
+	sky_fragment_glsl = `
+#version 330 core
+
+in vec3 direction;
+
+out vec4 fragColor;
+
+const vec3 sun = normalize(vec3(0.5, 0.4, -0.7));
+const vec3 horizon = vec3(0.85, 0.85, 0.9);
+
+void main()
+{
+    vec3 d = normalize(direction);
+    vec3 c;
+    if (d.y >= 0.0) {
+        c = mix(horizon, vec3(0.2, 0.4, 0.8), sqrt(d.y));
+        float s = dot(d, sun);
+        c += vec3(1.0, 0.9, 0.7) * (50.0 * smoothstep(0.997, 0.999, s) + 0.5 * pow(max(s, 0.0), 64.0));
+    } else {
+        // the floor at y = -1
+        vec2 p = d.xz / -d.y;
+        float checker = mod(floor(p.x) + floor(p.y), 2.0);
+        c = mix(horizon, vec3(0.15 + 0.15 * checker), min(1.0, -4.0 * d.y));
+    }
+    fragColor = vec4(c, 1.0);
+}
+`
+
+	// The diffuse light from all directions in the hemisphere around the
+	// normal, weighted by the cosine of the angle.
+	irradiance_fragment_glsl = `
+#version 330 core
+
+uniform samplerCube environment;
+
+in vec3 direction;
+
+out vec4 fragColor;
+
+const float PI = 3.14159265359;
+const float delta = 0.05;
+
+void main()
+{
+    vec3 n = normalize(direction);
+    vec3 up = abs(n.y) < 0.999 ? vec3(0.0, 1.0, 0.0) : vec3(0.0, 0.0, 1.0);
+    vec3 right = normalize(cross(up, n));
+    up = cross(n, right);
+
+    vec3 sum = vec3(0.0);
+    float count = 0.0;
+    for (float phi = 0.0; phi < 2.0 * PI; phi += delta) {
+        for (float theta = 0.0; theta < 0.5 * PI; theta += delta) {
+            vec3 d = sin(theta) * (cos(phi) * right + sin(phi) * up) + cos(theta) * n;
+            // a blurred level, against aliasing
+            sum += textureLod(environment, d, 3.0).rgb * cos(theta) * sin(theta);
+            count++;
+        }
+    }
+    fragColor = vec4(PI * sum / count, 1.0);
+}
+`
+
+	// The specular light, for the roughness of this mipmap level, with
+	// the assumption that the view direction equals the normal.
+	prefilter_fragment_glsl = `
+#version 330 core
+
+uniform samplerCube environment;
+uniform float roughness;
+uniform float resolution; // of a face of environment
+
+in vec3 direction;
+
+out vec4 fragColor;
+` + brdf_glsl + `
+const uint SAMPLES = 512u;
+
+void main()
+{
+    vec3 n = normalize(direction);
+    vec3 v = n;
+
+    vec3 sum = vec3(0.0);
+    float weight = 0.0;
+    for (uint i = 0u; i < SAMPLES; i++) {
+        vec3 h = importanceSampleGGX(hammersley(i, SAMPLES), n, roughness);
+        vec3 l = normalize(2.0 * dot(v, h) * h - v);
+        float nl = dot(n, l);
+        if (nl > 0.0) {
+            // Unlikely directions stand for a larger solid angle, so they
+            // are read from a blurred level, against aliasing.
+            float nh = max(dot(n, h), 0.0);
+            float pdf = distributionGGX(nh, roughness) / 4.0 + 0.0001;
+            float texel = 4.0 * PI / (6.0 * resolution * resolution);
+            float solidAngle = 1.0 / (float(SAMPLES) * pdf + 0.0001);
+            float lod = roughness == 0.0 ? 0.0 : 0.5 * log2(solidAngle / texel);
+            sum += textureLod(environment, l, lod).rgb * nl;
+            weight += nl;
+        }
+    }
+    fragColor = vec4(sum / weight, 1.0);
+}
+`
+
+	// The lookup table with the scale and bias of F0 for the specular
+	// light, for the cosine of the view angle (u) and roughness (v).
+	lut_fragment_glsl = `
+#version 330 core
+
+in vec2 uv;
+
+out vec2 fragColor;
+` + brdf_glsl + `
+const uint SAMPLES = 1024u;
+
+void main()
+{
+    float nv = max(uv.x, 0.001);
+    float roughness = uv.y;
+    vec3 v = vec3(sqrt(1.0 - nv * nv), 0.0, nv);
+    vec3 n = vec3(0.0, 0.0, 1.0);
+    float k = roughness * roughness / 2.0;
+
+    float scale = 0.0;
+    float bias = 0.0;
+    for (uint i = 0u; i < SAMPLES; i++) {
+        vec3 h = importanceSampleGGX(hammersley(i, SAMPLES), n, roughness);
+        vec3 l = normalize(2.0 * dot(v, h) * h - v);
+        float nl = max(l.z, 0.0);
+        if (nl > 0.0) {
+            float nh = max(h.z, 0.0);
+            float vh = max(dot(v, h), 0.0);
+            float g = geometrySmith(nv, nl, k) * vh / (nh * nv);
+            float fc = pow(1.0 - vh, 5.0);
+            scale += (1.0 - fc) * g;
+            bias += fc * g;
+        }
+    }
+    fragColor = vec2(scale, bias) / float(SAMPLES);
+}
+`
+
+	//
+	// the spheres
+	//
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 uv;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+out vec2 texcoord;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+    texcoord = uv;
+}
+`
+
+	// Cook-Torrance, with one directional light, and the environment as
+	// ambient light.
+	fragment_glsl = `
+#version 330 core
+
+struct Material {
+    vec3 albedo;
+    float metallic;
+    float roughness;
+    float ao;
+    bool textured;
+};
+
+uniform Material material;
+uniform sampler2D albedoMap;
+uniform sampler2D metallicRoughnessMap; // roughness in green, metallic in blue, like glTF
+uniform sampler2D aoMap;
+
+uniform samplerCube irradianceMap;
+uniform samplerCube prefilterMap;
+uniform sampler2D brdfLUT;
+uniform float maxLod;
+
+uniform vec3 eye;
+uniform vec3 lightDirection;
+uniform vec3 lightColor;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+in vec2 texcoord;
+
+out vec4 fragColor;
+` + brdf_glsl + `
+vec3 fresnelSchlick(float cosTheta, vec3 f0, float roughness)
+{
+    return f0 + (max(vec3(1.0 - roughness), f0) - f0) * pow(1.0 - cosTheta, 5.0);
+}
+
+void main()
+{
+    vec3 albedo = material.albedo;
+    float metallic = material.metallic;
+    float roughness = material.roughness;
+    float ao = material.ao;
+    if (material.textured) {
+        albedo = pow(texture(albedoMap, texcoord).rgb, vec3(2.2));
+        vec3 mr = texture(metallicRoughnessMap, texcoord).rgb;
+        roughness = mr.g;
+        metallic = mr.b;
+        ao = texture(aoMap, texcoord).r;
+    }
+
+    vec3 n = normalize(worldNormal);
+    vec3 v = normalize(eye - worldPosition);
+    float nv = max(dot(n, v), 0.0);
+    vec3 f0 = mix(vec3(0.04), albedo, metallic);
+
+    // the light
+    vec3 l = normalize(lightDirection);
+    vec3 h = normalize(v + l);
+    float nl = max(dot(n, l), 0.0);
+    float k = (roughness + 1.0) * (roughness + 1.0) / 8.0;
+    vec3 f = fresnelSchlick(max(dot(h, v), 0.0), f0, 0.0);
+    vec3 specular = distributionGGX(max(dot(n, h), 0.0), roughness) * geometrySmith(nv, nl, k) * f / (4.0 * nv * nl + 0.0001);
+    vec3 diffuse = (1.0 - f) * (1.0 - metallic) * albedo / PI;
+    vec3 direct = (diffuse + specular) * lightColor * nl;
+
+    // the environment
+    f = fresnelSchlick(nv, f0, roughness);
+    diffuse = (1.0 - f) * (1.0 - metallic) * albedo * texture(irradianceMap, n).rgb;
+    vec3 prefiltered = textureLod(prefilterMap, reflect(-v, n), roughness * maxLod).rgb;
+    vec2 brdf = texture(brdfLUT, vec2(nv, roughness)).rg;
+    specular = prefiltered * (f * brdf.x + brdf.y);
+    vec3 ambient = (diffuse + specular) * ao;
+
+    vec3 c = ambient + direct;
+    // tone mapping and gamma correction
+    c = c / (c + 1.0);
+    fragColor = vec4(pow(c, vec3(1.0 / 2.2)), 1.0);
+}
+`
+
+	//
+	// the background
+	//
+	background_vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+
+out vec3 direction;
+
+void main()
+{
+    // only the rotation of the view, the background is infinitely far away
+    vec4 p = projection * mat4(mat3(view)) * vec4(position, 1.0);
+    // depth 1: the far plane
+    gl_Position = p.xyww;
+    direction = position;
+}
+`
+	background_fragment_glsl = `
+#version 330 core
+
+uniform samplerCube background;
+uniform float lod;
+
+in vec3 direction;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 c = textureLod(background, direction, lod).rgb;
+    c = c / (c + 1.0);
+    fragColor = vec4(pow(c, vec3(1.0 / 2.2)), 1.0);
+}
+`
+)
+
+var skyboxFiles = flag.String("skybox", "", "environment images, as a pattern with %s for posx, negx, posy, negy, posz and negz, e.g. sky/%s.jpg")
+
+// Sizes of the maps for image-based lighting.
+const (
+	environmentSize = 512
+	irradianceSize  = 32
+	prefilterSize   = 128
+	prefilterLevels = 5 // from roughness 0 to 1
+	lutSize         = 512
+)
+
+// Extra actions for this demo.
+const (
+	actionTextured   = "textured"
+	actionBackground = "background"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program    *glutil.Program
+	background *glutil.Program
+	sphere     *mesh.VAO
+	cube       *mesh.VAO
+
+	environment uint32
+	irradiance  uint32
+	prefilter   uint32
+	lut         *glutil.Framebuffer
+
+	albedoMap            uint32
+	metallicRoughnessMap uint32
+	aoMap                uint32
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		sphere: mesh.Sphere(64, 32).Upload(),
+		cube:   mesh.Cube().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.background, err = glutil.NewProgram(background_vertex_glsl, background_fragment_glsl)
+	x(err)
+
+	makeMaps(r)
+
+	albedo, metallicRoughness, ao := makeMaterial(256)
+	r.albedoMap = makeMaterialTexture(albedo)
+	r.metallicRoughnessMap = makeMaterialTexture(metallicRoughness)
+	r.aoMap = makeMaterialTexture(ao)
+
+	return r
+}
+
+// makeMaps makes the environment cube map, and from it the maps for
+// image-based lighting: the irradiance and prefiltered cube maps, and the
+// lookup table for the BRDF.
+func makeMaps(r *gResources) {
+	glutil.State{}.Apply()
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	// renderCube draws the cube with program p into each face of texture
+	renderCube := func(p *glutil.Program, texture uint32, size, level int32) {
+		p.Use()
+		p.SetMat4("projection", glutil.CubemapProjection)
+		x(glutil.RenderToCubemap(texture, size, level, func(face int) {
+			p.SetMat4("view", glutil.CubemapViews[face])
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			r.cube.Draw()
+		}))
+	}
+
+	var err error
+	var size int32 = environmentSize
+	if *skyboxFiles != "" {
+		var filenames [6]string
+		for i, name := range []string{"posx", "negx", "posy", "negy", "posz", "negz"} {
+			filenames[i] = fmt.Sprintf(*skyboxFiles, name)
+		}
+		r.environment, err = glutil.MakeCubemapFromFiles(filenames)
+		x(err)
+		gl.GetTexLevelParameteriv(gl.TEXTURE_CUBE_MAP_POSITIVE_X, 0, gl.TEXTURE_WIDTH, &size)
+	} else {
+		sky, err := glutil.NewProgram(cube_vertex_glsl, sky_fragment_glsl)
+		x(err)
+		r.environment = glutil.MakeEmptyCubemap(size, glutil.FormatRGB16F, true)
+		renderCube(sky, r.environment, size, 0)
+		sky.Delete()
+	}
+	// the blurred levels are used against aliasing when sampling
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.environment)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.GenerateMipmap(gl.TEXTURE_CUBE_MAP)
+
+	r.irradiance = glutil.MakeEmptyCubemap(irradianceSize, glutil.FormatRGB16F, false)
+	r.prefilter = glutil.MakeEmptyCubemap(prefilterSize, glutil.FormatRGB16F, true)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAX_LEVEL, prefilterLevels-1)
+
+	// the environment is the input for the rest
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.environment)
+
+	p, err := glutil.NewProgram(cube_vertex_glsl, irradiance_fragment_glsl)
+	x(err)
+	p.Use()
+	p.SetInt("environment", 0)
+	renderCube(p, r.irradiance, irradianceSize, 0)
+	p.Delete()
+
+	p, err = glutil.NewProgram(cube_vertex_glsl, prefilter_fragment_glsl)
+	x(err)
+	p.Use()
+	p.SetInt("environment", 0)
+	p.SetFloat("resolution", float32(size))
+	for level := int32(0); level < prefilterLevels; level++ {
+		p.Use()
+		p.SetFloat("roughness", float32(level)/(prefilterLevels-1))
+		renderCube(p, r.prefilter, prefilterSize, level)
+	}
+	p.Delete()
+
+	p, err = glutil.NewProgram(glutil.FullscreenVertexShader, lut_fragment_glsl)
+	x(err)
+	r.lut, err = glutil.NewMultiFramebuffer(lutSize, lutSize, glutil.FormatRG16F)
+	x(err)
+	r.lut.SetFilter(gl.LINEAR)
+	r.lut.Bind()
+	p.Use()
+	glutil.DrawFullscreen()
+	r.lut.Unbind()
+	p.Delete()
+}
+
+// makeMaterial returns the maps for the textured sphere: metal tiles, with
+// rough paint in the gaps between them.
+func makeMaterial(size int) (albedo, metallicRoughness, ao *image.RGBA) {
+	const (
+		tiles = 4
+		gap   = .06 // width of half a gap, relative to a tile
+	)
+	metals := []color.RGBA{
+		{255, 195, 86, 255},  // gold
+		{242, 163, 130, 255}, // copper
+		{196, 199, 199, 255}, // steel
+		{250, 249, 245, 255}, // silver
+	}
+	rect := image.Rect(0, 0, size, size)
+	albedo = image.NewRGBA(rect)
+	metallicRoughness = image.NewRGBA(rect)
+	ao = image.NewRGBA(rect)
+	for py := 0; py < size; py++ {
+		for px := 0; px < size; px++ {
+			tx, u := math.Modf(float64(px*tiles) / float64(size))
+			ty, v := math.Modf(float64(py*tiles) / float64(size))
+			d := math.Min(math.Min(u, 1-u), math.Min(v, 1-v))
+			if d < gap {
+				albedo.SetRGBA(px, py, color.RGBA{60, 60, 60, 255})
+				metallicRoughness.SetRGBA(px, py, color.RGBA{0, 230, 0, 255})
+				ao.SetRGBA(px, py, color.RGBA{100, 100, 100, 255})
+				continue
+			}
+			i := int(tx) + 2*int(ty)
+			albedo.SetRGBA(px, py, metals[i%len(metals)])
+			roughness := .1 + .25*float64((i*7)%4)
+			metallicRoughness.SetRGBA(px, py, color.RGBA{0, uint8(255 * roughness), 255, 255})
+			// darker near the gaps
+			a := uint8(255 * math.Min(1, .4+(d-gap)/.15))
+			ao.SetRGBA(px, py, color.RGBA{a, a, a, 255})
+		}
+	}
+	return
+}
+
+func makeMaterialTexture(img image.Image) uint32 {
+	texture := glutil.MakeTextureFromImage(img)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	return texture
+}
+
+//
+// Update and render
+//
+
+// The spheres in the grid: metallic increases from bottom to top, roughness
+// from left to right.
+const grid = 7
+
+var (
+	clock = app.NewClock()
+	cam   = camera.NewOrbit(glm.Vec3{}, 20)
+
+	textured   bool
+	background int // 0: environment, 1: irradiance, 2: prefiltered
+)
+
+var (
+	stateObject = glutil.State{
+		DepthTest: true,
+		CullFace:  true,
+	}
+
+	// The background is drawn last, at depth 1, only where nothing else
+	// was drawn.
+	stateBackground = glutil.State{
+		DepthTest:    true,
+		DepthFunc:    gl.LEQUAL,
+		NoDepthWrite: true,
+	}
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	stateObject.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	view := cam.View()
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100)
+
+	// the light circles around
+	t := clock.Seconds() / 4
+	light := glm.Vec3{float32(math.Cos(t)), .7, float32(math.Sin(t))}
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.irradiance)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.prefilter)
+	gl.ActiveTexture(gl.TEXTURE2)
+	gl.BindTexture(gl.TEXTURE_2D, r.lut.Texture)
+	gl.ActiveTexture(gl.TEXTURE3)
+	gl.BindTexture(gl.TEXTURE_2D, r.albedoMap)
+	gl.ActiveTexture(gl.TEXTURE4)
+	gl.BindTexture(gl.TEXTURE_2D, r.metallicRoughnessMap)
+	gl.ActiveTexture(gl.TEXTURE5)
+	gl.BindTexture(gl.TEXTURE_2D, r.aoMap)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("eye", cam.Eye())
+	p.SetVec3("lightDirection", light)
+	p.SetVec3("lightColor", glm.Vec3{3, 3, 3})
+	p.SetInt("irradianceMap", 0)
+	p.SetInt("prefilterMap", 1)
+	p.SetInt("brdfLUT", 2)
+	p.SetInt("albedoMap", 3)
+	p.SetInt("metallicRoughnessMap", 4)
+	p.SetInt("aoMap", 5)
+	p.SetFloat("maxLod", prefilterLevels-1)
+
+	if textured {
+		model := glm.Scale(5, 5, 5)
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetBool("material.textured", true)
+		r.sphere.Draw()
+	} else {
+		p.SetBool("material.textured", false)
+		p.SetVec3("material.albedo", glm.Vec3{.9, .1, .1})
+		p.SetFloat("material.ao", 1)
+		for row := 0; row < grid; row++ {
+			for col := 0; col < grid; col++ {
+				model := glm.Translate(2.5*float32(col-grid/2), 2.5*float32(row-grid/2), 0)
+				p.SetMat4("model", model)
+				p.SetMat3("normalMatrix", model.NormalMatrix())
+				p.SetFloat("material.metallic", float32(row)/(grid-1))
+				// completely smooth looks wrong with a single light
+				p.SetFloat("material.roughness", float32(math.Max(.05, float64(col)/(grid-1))))
+				r.sphere.Draw()
+			}
+		}
+	}
+
+	stateBackground.Apply()
+	p = r.background
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetInt("background", 6)
+	gl.ActiveTexture(gl.TEXTURE6)
+	switch background {
+	case 0:
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.environment)
+		p.SetFloat("lod", 0)
+	case 1:
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.irradiance)
+		p.SetFloat("lod", 0)
+	case 2:
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.prefilter)
+		p.SetFloat("lod", 2)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	r.cube.Draw()
+}
+
+func main() {
+	app.Keys[actionTextured] = []string{"t"}
+	app.Keys[actionBackground] = []string{"b"}
+	app.Flags(800, 600, "PBR")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Metallic increases from bottom to top, roughness from left to right")
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 't' to switch between the grid and a textured sphere, 'b' to change the background")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionTextured:
+		textured = !textured
+	case actionBackground:
+		background = (background + 1) % 3
+		app.Info([]string{"Environment", "Irradiance", "Prefiltered"}[background])
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+struct Light {
+    vec3 position;
+    vec3 color;
+    float ambient;
+    float specular;
+    float shininess;
+};
+
+uniform Light light;
+uniform vec3 viewPosition;
+uniform vec3 objectColor;
+
+uniform bool useAmbient;
+uniform bool useDiffuse;
+uniform bool useSpecular;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    vec3 l = normalize(light.position - worldPosition);
+    vec3 v = normalize(viewPosition - worldPosition);
+    vec3 r = reflect(-l, n);
+
+    vec3 result = vec3(0.0);
+    if (useAmbient) {
+        result += light.ambient * light.color * objectColor;
+    }
+    if (useDiffuse) {
+        result += max(dot(n, l), 0.0) * light.color * objectColor;
+    }
+    if (useSpecular) {
+        result += light.specular * pow(max(dot(v, r), 0.0), light.shininess) * light.color;
+    }
+    fragColor = vec4(result, 1.0);
+}
+` + "\x00"
+
+	// the lamp: an unlit sphere in the color of the light
+	lamp_fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color, 1.0);
+}
+` + "\x00"
+)
+
+// Extra actions for this demo, to toggle each of the lighting terms.
+const (
+	actionAmbient  = "ambient"
+	actionDiffuse  = "diffuse"
+	actionSpecular = "specular"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	phong  *glutil.Program
+	lamp   *glutil.Program
+	sphere *mesh.VAO
+	cube   *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		sphere: mesh.Sphere(48, 24).Upload(),
+		cube:   mesh.Cube().Upload(),
+	}
+
+	var err error
+	r.phong, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.lamp, err = glutil.NewProgram(vertex_glsl, lamp_fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	zoom  = input.NewZoom(.3, 3)
+
+	useAmbient  = true
+	useDiffuse  = true
+	useSpecular = true
+
+	lightColor = glm.Vec3{1, 1, 1}
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.1, .1, .1, 0))
+	state3D.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	t := float32(clock.Seconds())
+
+	eye := glm.Vec3{0, 2, 7 / float32(zoom.Scale())}
+	view := glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0})
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100)
+
+	// the light circles around both objects
+	lightPosition := glm.Vec3{
+		3 * float32(math.Sin(float64(t))),
+		1 + float32(math.Sin(float64(t/2))),
+		3 * float32(math.Cos(float64(t))),
+	}
+
+	p := r.phong
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("light.position", lightPosition)
+	p.SetVec3("light.color", lightColor)
+	p.SetFloat("light.ambient", .15)
+	p.SetFloat("light.specular", .6)
+	p.SetFloat("light.shininess", 32)
+	p.SetVec3("viewPosition", eye)
+	p.SetBool("useAmbient", useAmbient)
+	p.SetBool("useDiffuse", useDiffuse)
+	p.SetBool("useSpecular", useSpecular)
+
+	// sphere on the left
+	model := glm.Translate(-1.4, 0, 0)
+	p.SetMat4("model", model)
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	p.SetVec3("objectColor", glm.Vec3{.9, .4, .2})
+	r.sphere.Draw()
+
+	// rotating cube on the right
+	model = glm.Translate(1.4, 0, 0).Mul(glm.Rotate(t/3, glm.Vec3{1, 1, 0}.Normalize())).Mul(glm.Scale(.7, .7, .7))
+	p.SetMat4("model", model)
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	p.SetVec3("objectColor", glm.Vec3{.2, .5, .9})
+	r.cube.Draw()
+
+	// the light itself
+	model = glm.Translate(lightPosition[0], lightPosition[1], lightPosition[2]).Mul(glm.Scale(.1, .1, .1))
+	r.lamp.Use()
+	r.lamp.SetMat4("model", model)
+	r.lamp.SetMat4("view", view)
+	r.lamp.SetMat4("projection", projection)
+	r.lamp.SetVec3("color", lightColor)
+	r.sphere.Draw()
+}
+
+func main() {
+	app.Keys[actionAmbient] = []string{"a"}
+	app.Keys[actionDiffuse] = []string{"d"}
+	app.Keys[actionSpecular] = []string{"s"}
+	app.Flags(640, 480, "Phong lighting")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Press 'a', 'd' and 's' to toggle the ambient, diffuse and specular terms")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionAmbient:
+		useAmbient = !useAmbient
+		app.Info("Ambient:", onOff(useAmbient))
+	case actionDiffuse:
+		useDiffuse = !useDiffuse
+		app.Info("Diffuse:", onOff(useDiffuse))
+	case actionSpecular:
+		useSpecular = !useSpecular
+		app.Info("Specular:", onOff(useSpecular))
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
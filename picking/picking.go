@@ -0,0 +1,339 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/easing"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform vec2 offset;
+uniform vec2 aspect;
+uniform float angle;
+uniform float scale;
+
+in vec2 position;
+
+void main()
+{
+    float s = sin(angle);
+    float c = cos(angle);
+    vec2 p = scale * vec2(c*position.x - s*position.y, s*position.x + c*position.y);
+    gl_Position = vec4(aspect * (p + offset), 0.0, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec4 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = color;
+}
+` + "\x00"
+)
+
+//
+// Global data used by render
+//
+
+type tUniforms struct {
+	offset int32
+	aspect int32
+	angle  int32
+	scale  int32
+	color  int32
+}
+
+type tObject struct {
+	first  int32 // first vertex in the buffer
+	count  int32 // number of vertices
+	offset [2]float32
+	speed  float64
+	color  [3]float32
+}
+
+type gResources struct {
+	vertexArray  uint32
+	vertexBuffer uint32
+	program      uint32
+	uniforms     tUniforms
+	position     uint32
+
+	objects []tObject
+
+	// for picking
+	fbo *glutil.Framebuffer
+}
+
+//
+// The objects are regular polygons, with 3 to 14 sides, on a grid of 4 by 3.
+//
+
+const (
+	columns = 4
+	rows    = 3
+)
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.MakeProgramFromSource(vertex_glsl, fragment_glsl)
+	x(err)
+
+	r.uniforms.offset = gl.GetUniformLocation(r.program, gl.Str("offset\x00"))
+	r.uniforms.aspect = gl.GetUniformLocation(r.program, gl.Str("aspect\x00"))
+	r.uniforms.angle = gl.GetUniformLocation(r.program, gl.Str("angle\x00"))
+	r.uniforms.scale = gl.GetUniformLocation(r.program, gl.Str("scale\x00"))
+	r.uniforms.color = gl.GetUniformLocation(r.program, gl.Str("color\x00"))
+	r.position = uint32(gl.GetAttribLocation(r.program, gl.Str("position\x00")))
+
+	vertices := make([]float32, 0)
+	for i := 0; i < columns*rows; i++ {
+		sides := i + 3
+		obj := tObject{
+			first: int32(len(vertices) / 2),
+			count: int32(sides + 2),
+			offset: [2]float32{
+				float32(i%columns)*2/columns - 1 + 1.0/columns,
+				1 - float32(i/columns)*2/rows - 1.0/rows,
+			},
+			speed: float64(i%5+1) / 5,
+		}
+		if i%2 == 1 {
+			obj.speed = -obj.speed
+		}
+		obj.color[0], obj.color[1], obj.color[2] = hsb2rgb(float32(i)/float32(columns*rows), .7, .8)
+
+		// triangle fan: center, then the corners, closing the loop
+		vertices = append(vertices, 0, 0)
+		for j := 0; j <= sides; j++ {
+			a := 2 * math.Pi * float64(j) / float64(sides)
+			vertices = append(vertices, float32(math.Sin(a)), float32(math.Cos(a)))
+		}
+		r.objects = append(r.objects, obj)
+	}
+
+	gl.GenVertexArrays(1, &r.vertexArray)
+	gl.BindVertexArray(r.vertexArray)
+	r.vertexBuffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, vertices, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(
+		r.position,      // attribute
+		2,               // size
+		gl.FLOAT,        // type
+		false,           // normalized?
+		8,               // stride
+		gl.PtrOffset(0)) // array buffer offset
+	gl.EnableVertexAttribArray(r.position)
+
+	return r
+}
+
+//
+// Rendering
+//
+
+var (
+	clock    = app.NewClock()
+	zoom     = input.NewZoom(.5, 4)
+	selected = -1
+)
+
+// drawObjects draws all objects. With ids set, each object is drawn in a
+// flat color that encodes its index, with 0 for the background.
+func drawObjects(r *gResources, width, height int, ids bool) {
+	var ax, ay float32 = 1, 1
+	if width > height {
+		ax = float32(height) / float32(width)
+	} else {
+		ay = float32(width) / float32(height)
+	}
+	// the objects grow into view at the start
+	z := float32(zoom.Scale() * easing.OutElastic(clock.Seconds()/1.5))
+
+	gl.UseProgram(r.program)
+	gl.BindVertexArray(r.vertexArray)
+	gl.Uniform2f(r.uniforms.aspect, ax*z, ay*z)
+
+	for i, obj := range r.objects {
+		gl.Uniform2f(r.uniforms.offset, obj.offset[0], obj.offset[1])
+		gl.Uniform1f(r.uniforms.angle, float32(obj.speed*clock.Seconds()))
+
+		scale := float32(.8 / columns)
+		if ids {
+			gl.Uniform4f(r.uniforms.color, float32(i+1)/255, 0, 0, 1)
+		} else if i == selected {
+			// pulsate, and draw a white border first
+			scale *= 1 + .05*float32(math.Sin(8*clock.Seconds()))
+			gl.Uniform1f(r.uniforms.scale, scale*1.1)
+			gl.Uniform4f(r.uniforms.color, 1, 1, 1, 1)
+			gl.DrawArrays(gl.TRIANGLE_FAN, obj.first, obj.count)
+			gl.Uniform4f(r.uniforms.color, obj.color[0], obj.color[1], obj.color[2], 1)
+		} else {
+			gl.Uniform4f(r.uniforms.color, obj.color[0], obj.color[1], obj.color[2], 1)
+		}
+		gl.Uniform1f(r.uniforms.scale, scale)
+		gl.DrawArrays(gl.TRIANGLE_FAN, obj.first, obj.count)
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.5, .5, .5, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	drawObjects(r, width, height, false)
+}
+
+// pick renders the object ids into the offscreen framebuffer, and returns
+// the index of the object at window position cx, cy, or -1 if there is none.
+func pick(w *glfw.Window, r *gResources, cx, cy float64) int {
+	width, height := w.GetFramebufferSize()
+	if r.fbo == nil || r.fbo.Width != int32(width) || r.fbo.Height != int32(height) {
+		if r.fbo != nil {
+			r.fbo.Delete()
+		}
+		var err error
+		r.fbo, err = glutil.NewFramebuffer(int32(width), int32(height))
+		x(err)
+	}
+
+	r.fbo.Bind()
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	drawObjects(r, width, height, true)
+
+	// from window coordinates to framebuffer pixels, with y going up
+	ww, wh := w.GetSize()
+	px := int32(cx * float64(width) / float64(ww))
+	py := int32(height) - 1 - int32(cy*float64(height)/float64(wh))
+
+	var pixel [4]uint8
+	gl.ReadPixels(px, py, 1, 1, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&pixel[0]))
+	r.fbo.Unbind()
+
+	return int(pixel[0]) - 1
+}
+
+func main() {
+	app.Flags(640, 480, "Picking")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	// Picking is done in the main loop, not in the callback.
+	clicked := false
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button == glfw.MouseButtonLeft && action == glfw.Press {
+			clicked = true
+		}
+	})
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Click on an object to select it")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		if clicked {
+			clicked = false
+			cx, cy := w.GetCursorPos()
+			selected = pick(w, r, cx, cy)
+		}
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
+
+func hsb2rgb(h, s, b float32) (float32, float32, float32) {
+	c := b * s
+	m := b - c
+	h *= 6
+	x := c * float32(1-math.Abs(math.Mod(float64(h), 2)-1))
+	if h < 1 {
+		return c + m, x + m, m
+	}
+	if h < 2 {
+		return x + m, c + m, m
+	}
+	if h < 3 {
+		return m, c + m, x + m
+	}
+	if h < 4 {
+		return m, x + m, c + m
+	}
+	if h < 5 {
+		return x + m, m, c + m
+	}
+	return c + m, m, x + m
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/color"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/polyline"
+	"github.com/pebbe/gl/shapes"
+	"github.com/pebbe/gl/sprite"
+	"github.com/pebbe/gl/text"
+
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	dataFile = flag.String("file", "", "CSV file to plot: x in the first column, a series in each other column, optionally a header row; without it, generated data is plotted")
+	styles   = flag.String("styles", "", "comma-separated style for each series: line, scatter or both (default line)")
+)
+
+// Extra actions for this demo.
+const (
+	actionGrid = "grid"
+)
+
+//
+// The data
+//
+
+// dataSet holds the columns of a CSV file that is read bit by bit as it
+// grows. Missing or invalid values are NaN.
+type dataSet struct {
+	names   []string
+	x       []float64
+	y       [][]float64 // for each series
+	partial string      // the start of a line that isn't complete yet
+	changed bool
+}
+
+// add parses text appended to the file.
+func (d *dataSet) add(s string) {
+	s = d.partial + s
+	lines := strings.Split(s, "\n")
+	d.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		d.addLine(strings.TrimSpace(line))
+	}
+}
+
+func (d *dataSet) addLine(line string) {
+	if line == "" || line[0] == '#' {
+		return
+	}
+	fields := strings.Split(line, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if len(fields) < 2 {
+		return
+	}
+	xv, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		if d.names == nil && len(d.x) == 0 {
+			// a header
+			d.names = fields[1:]
+		}
+		return
+	}
+	if d.y == nil {
+		// the first row sets the number of series
+		d.y = make([][]float64, len(fields)-1)
+		for len(d.names) < len(d.y) {
+			d.names = append(d.names, fmt.Sprint("series ", len(d.names)+1))
+		}
+	}
+	d.x = append(d.x, xv)
+	for i := range d.y {
+		v := math.NaN()
+		if i+1 < len(fields) {
+			if f, err := strconv.ParseFloat(fields[i+1], 64); err == nil {
+				v = f
+			}
+		}
+		d.y[i] = append(d.y[i], v)
+	}
+	d.changed = true
+}
+
+// bounds returns the range of the data, with some room around it.
+func (d *dataSet) bounds() (x0, x1, y0, y1 float64) {
+	x0, y0 = math.Inf(1), math.Inf(1)
+	x1, y1 = math.Inf(-1), math.Inf(-1)
+	for i, xv := range d.x {
+		for _, ys := range d.y {
+			if v := ys[i]; !math.IsNaN(v) {
+				x0, x1 = math.Min(x0, xv), math.Max(x1, xv)
+				y0, y1 = math.Min(y0, v), math.Max(y1, v)
+			}
+		}
+	}
+	if math.IsInf(x0, 1) {
+		return 0, 1, 0, 1
+	}
+	if x1 == x0 {
+		x0, x1 = x0-1, x1+1
+	}
+	if y1 == y0 {
+		y0, y1 = y0-1, y1+1
+	}
+	dy := (y1 - y0) * .05
+	return x0, x1, y0 - dy, y1 + dy
+}
+
+// watcher reads what is appended to a file.
+type watcher struct {
+	filename string
+	offset   int64
+}
+
+// poll adds the new part of the file to the data. If the file got shorter,
+// it is read again from the start.
+func (w *watcher) poll(d *dataSet) {
+	fi, err := os.Stat(w.filename)
+	if err != nil {
+		return
+	}
+	if fi.Size() < w.offset {
+		*d = dataSet{changed: true}
+		w.offset = 0
+	}
+	if fi.Size() == w.offset {
+		return
+	}
+	fp, err := os.Open(w.filename)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	buf := make([]byte, fi.Size()-w.offset)
+	n, _ := fp.ReadAt(buf, w.offset)
+	w.offset += int64(n)
+	d.add(string(buf[:n]))
+}
+
+// generator adds rows of made-up measurements, for when there is no file.
+type generator struct {
+	t    float64
+	walk float64
+}
+
+func (g *generator) next(d *dataSet) {
+	if g.t == 0 {
+		d.add("time,sine,random walk,noise\n")
+	}
+	g.walk += rand.NormFloat64() * .1
+	d.add(fmt.Sprintf("%g,%g,%g,%g\n", g.t, math.Sin(g.t), g.walk, rand.Float64()-.5))
+	g.t += .1
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	shapes *shapes.Batch
+	lines  *polyline.Renderer
+	labels *sprite.Batch
+	font   *text.Font
+
+	// The series, with x relative to that of the first row, for precision
+	// with values such as times.
+	series  []*polyline.Line
+	originX float64
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.shapes, err = shapes.NewBatch(4096)
+	x(err)
+	r.lines, err = polyline.NewRenderer()
+	x(err)
+	r.lines.Width = 2
+	r.labels, err = sprite.NewBatch(1024)
+	x(err)
+	r.font = text.NewFont(2)
+
+	return r
+}
+
+// makeLines uploads the series as lines. Missing values are skipped.
+func makeLines(r *gResources, d *dataSet) {
+	for _, l := range r.series {
+		l.Delete()
+	}
+	r.series = r.series[:0]
+	if len(d.x) > 0 {
+		r.originX = d.x[0]
+	}
+	for i, ys := range d.y {
+		c := seriesColor(i).Vec4(1)
+		points := make([]polyline.Point, 0, len(ys))
+		for j, v := range ys {
+			if !math.IsNaN(v) {
+				points = append(points, polyline.Point{
+					Position: glm.Vec3{float32(d.x[j] - r.originX), float32(v), 0},
+					Color:    c,
+				})
+			}
+		}
+		r.series = append(r.series, polyline.NewLine(points, false))
+	}
+}
+
+func seriesColor(i int) glm.Vec3 {
+	// hues far apart, for any number of series
+	return color.HSV(float32(math.Mod(float64(i)*.618034+.6, 1)), .75, .8)
+}
+
+//
+// Update and render
+//
+
+var (
+	data     dataSet
+	showGrid = true
+)
+
+// Margins around the plot, in screen coordinates.
+const (
+	marginLeft   = 90
+	marginRight  = 30
+	marginTop    = 50
+	marginBottom = 50
+)
+
+// ticks returns values at a round interval between v0 and v1, about n of
+// them, and the interval.
+func ticks(v0, v1 float64, n int) ([]float64, float64) {
+	raw := (v1 - v0) / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag
+	for _, s := range []float64{2, 5, 10} {
+		if step >= raw {
+			break
+		}
+		step = s * mag
+	}
+	var t []float64
+	for v := math.Ceil(v0/step) * step; v <= v1; v += step {
+		// no -0 or 0.30000000000000004
+		t = append(t, math.Round(v/step)*step+0)
+	}
+	return t, step
+}
+
+// label formats a tick, with as many decimals as the interval between
+// ticks needs.
+func label(v, step float64) string {
+	decimals := int(-math.Floor(math.Log10(step) + 1e-9))
+	if decimals < 0 {
+		decimals = 0
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	if data.changed {
+		makeLines(r, &data)
+		data.changed = false
+	}
+
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.97, .97, .96, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// screen coordinates, y down
+	ww, wh := w.GetSize()
+	sw, sh := float32(ww), float32(wh)
+	projection := glm.Ortho(0, sw, sh, 0, -1, 1)
+
+	// the plot area, and the data shown in it
+	left, top := float32(marginLeft), float32(marginTop)
+	right, bottom := sw-marginRight, sh-marginBottom
+	if right <= left || bottom <= top {
+		return
+	}
+	x0, x1, y0, y1 := data.bounds()
+	toScreen := func(xv, yv float64) glm.Vec2 {
+		return glm.Vec2{
+			left + float32((xv-x0)/(x1-x0))*(right-left),
+			bottom - float32((yv-y0)/(y1-y0))*(bottom-top),
+		}
+	}
+	xTicks, xStep := ticks(x0, x1, int((right-left)/100)+1)
+	yTicks, yStep := ticks(y0, y1, int((bottom-top)/60)+1)
+
+	dark := glm.Vec4{.2, .2, .2, 1}
+	gridColor := glm.Vec4{.85, .85, .85, 1}
+
+	b := r.shapes
+	b.Begin(projection)
+	b.Rect(glm.Vec2{left, top}, glm.Vec2{right, bottom}, 0, shapes.Style{Fill: glm.Vec4{1, 1, 1, 1}})
+	for _, t := range xTicks {
+		p := toScreen(t, y0)
+		if showGrid {
+			b.Capsule(glm.Vec2{p[0], top}, glm.Vec2{p[0], bottom}, .5, shapes.Style{Fill: gridColor})
+		}
+		b.Capsule(glm.Vec2{p[0], bottom}, glm.Vec2{p[0], bottom + 6}, .75, shapes.Style{Fill: dark})
+	}
+	for _, t := range yTicks {
+		p := toScreen(x0, t)
+		if showGrid {
+			b.Capsule(glm.Vec2{left, p[1]}, glm.Vec2{right, p[1]}, .5, shapes.Style{Fill: gridColor})
+		}
+		b.Capsule(glm.Vec2{left - 6, p[1]}, glm.Vec2{left, p[1]}, .75, shapes.Style{Fill: dark})
+	}
+	b.Capsule(glm.Vec2{left, bottom}, glm.Vec2{right, bottom}, .75, shapes.Style{Fill: dark})
+	b.Capsule(glm.Vec2{left, top}, glm.Vec2{left, bottom}, .75, shapes.Style{Fill: dark})
+	b.End()
+
+	// the series, from data relative to originX to clip space
+	sx := float64(right-left) / (x1 - x0)
+	sy := -float64(bottom-top) / (y1 - y0)
+	transform := projection.
+		Mul(glm.Translate(left+float32((r.originX-x0)*sx), bottom-float32(y0*sy), 0)).
+		Mul(glm.Scale(float32(sx), float32(sy), 1))
+	for i, l := range r.series {
+		if style(i) != "scatter" {
+			r.lines.Draw(l, transform, int32(width), int32(height))
+		}
+	}
+
+	b.Begin(projection)
+	for i, ys := range data.y {
+		if style(i) == "line" {
+			continue
+		}
+		c := seriesColor(i).Vec4(.8)
+		for j, v := range ys {
+			if !math.IsNaN(v) {
+				b.Circle(toScreen(data.x[j], v), 3, shapes.Style{Fill: c})
+			}
+		}
+	}
+	// a legend, with a swatch for each series
+	for i := range data.y {
+		y := top + 18 + 20*float32(i)
+		b.Capsule(glm.Vec2{right - 190, y}, glm.Vec2{right - 170, y}, 2, shapes.Style{Fill: seriesColor(i).Vec4(1)})
+	}
+	// a crosshair at the cursor
+	cx, cy := w.GetCursorPos()
+	cursor := glm.Vec2{float32(cx), float32(cy)}
+	inPlot := cursor[0] >= left && cursor[0] < right && cursor[1] >= top && cursor[1] < bottom
+	if inPlot {
+		b.Capsule(glm.Vec2{cursor[0], top}, glm.Vec2{cursor[0], bottom}, .5, shapes.Style{Fill: glm.Vec4{0, 0, 0, .4}})
+		b.Capsule(glm.Vec2{left, cursor[1]}, glm.Vec2{right, cursor[1]}, .5, shapes.Style{Fill: glm.Vec4{0, 0, 0, .4}})
+	}
+	b.End()
+
+	f := r.font
+	l := r.labels
+	l.Begin(projection)
+	for _, t := range xTicks {
+		s := label(t, xStep)
+		p := toScreen(t, y0)
+		f.Draw(l, s, glm.Vec2{p[0] - f.Size(s)[0]/2, bottom + 12}, dark)
+	}
+	for _, t := range yTicks {
+		s := label(t, yStep)
+		p := toScreen(x0, t)
+		size := f.Size(s)
+		f.Draw(l, s, glm.Vec2{left - 12 - size[0], p[1] - size[1]/2}, dark)
+	}
+	for i, name := range data.names {
+		if i < len(data.y) {
+			f.Draw(l, name, glm.Vec2{right - 160, top + 10 + 20*float32(i)}, dark)
+		}
+	}
+	title := "Generated data"
+	if *dataFile != "" {
+		title = *dataFile
+	}
+	title += fmt.Sprintf(", %d rows", len(data.x))
+	f.Draw(l, title, glm.Vec2{left, 16}, dark)
+	if inPlot {
+		xv := x0 + float64((cursor[0]-left)/(right-left))*(x1-x0)
+		yv := y0 + float64((bottom-cursor[1])/(bottom-top))*(y1-y0)
+		s := fmt.Sprintf("%.4g, %.4g", xv, yv)
+		f.Draw(l, s, glm.Vec2{right - f.Size(s)[0], 16}, dark)
+	}
+	l.End()
+}
+
+// style returns the style of series i.
+func style(i int) string {
+	s := strings.Split(*styles, ",")
+	if i < len(s) && s[i] != "" {
+		return strings.TrimSpace(s[i])
+	}
+	return "line"
+}
+
+func main() {
+	app.Keys[actionGrid] = []string{"g"}
+	app.Flags(1024, 640, "Plot")
+	for _, s := range strings.Split(*styles, ",") {
+		if s = strings.TrimSpace(s); s != "" && s != "line" && s != "scatter" && s != "both" {
+			app.Fatalf("invalid style %q, should be line, scatter or both", s)
+		}
+	}
+
+	var watch *watcher
+	if *dataFile != "" {
+		if _, err := os.Stat(*dataFile); err != nil {
+			app.Fatal(err)
+		}
+		watch = &watcher{filename: *dataFile}
+		watch.poll(&data)
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.OnAction(w, onAction)
+
+	if watch != nil {
+		app.Info("Rows appended to the file are added to the plot")
+	} else {
+		app.Info("No -file given, plotting generated data")
+	}
+	app.Info("Press 'g' to toggle the grid")
+	app.Info("Press 'q' to quit")
+	var gen generator
+	last := time.Now()
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		// new data ten times a second
+		if time.Since(last) > 100*time.Millisecond {
+			last = time.Now()
+			if watch != nil {
+				watch.poll(&data)
+			} else {
+				gen.next(&data)
+			}
+		}
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionGrid:
+		showGrid = !showGrid
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
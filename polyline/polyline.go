@@ -0,0 +1,296 @@
+// Package polyline draws thick lines through a list of points, with round
+// joins and caps, and antialiased edges.
+//
+// Wide lines with gl.LineWidth are deprecated, and core profile contexts
+// often support no widths other than 1. Here a geometry shader turns each
+// segment into a rectangle in screen space, and the fragment shader draws
+// a capsule in it: all pixels within half the width of the segment. The
+// capsules of consecutive segments overlap, which gives the round joins.
+//
+// Lines can be dashed, a replacement for glLineStipple, which is gone from
+// the core profile. Each point has its distance along the line, and the
+// fragment shader looks up this distance in the dash pattern.
+package polyline
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+)
+
+const (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 transform;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec4 color;
+layout(location = 2) in float distance;
+
+out vec4 vertexColor;
+out float vertexDistance;
+
+void main()
+{
+    gl_Position = transform * vec4(position, 1.0);
+    vertexColor = color;
+    vertexDistance = distance;
+}
+`
+
+	// Segments with an end behind the camera are skipped.
+	geometry_glsl = `
+#version 330 core
+
+layout(lines) in;
+layout(triangle_strip, max_vertices = 4) out;
+
+uniform vec2 viewport;
+uniform float width;
+
+in vec4 vertexColor[];
+in float vertexDistance[];
+
+out vec2 pixel;
+flat out vec2 a;
+flat out vec2 b;
+flat out vec4 colorA;
+flat out vec4 colorB;
+flat out vec2 distances;
+
+void corner(vec2 p, float z)
+{
+    pixel = p;
+    gl_Position = vec4(p / viewport * 2.0 - 1.0, z, 1.0);
+    EmitVertex();
+}
+
+void main()
+{
+    vec4 p0 = gl_in[0].gl_Position;
+    vec4 p1 = gl_in[1].gl_Position;
+    if (p0.w <= 0.0 || p1.w <= 0.0) {
+        return;
+    }
+
+    // from clip space to pixels
+    a = (p0.xy / p0.w * 0.5 + 0.5) * viewport;
+    b = (p1.xy / p1.w * 0.5 + 0.5) * viewport;
+    colorA = vertexColor[0];
+    colorB = vertexColor[1];
+    distances = vec2(vertexDistance[0], vertexDistance[1]);
+
+    // half the width, and a pixel for the antialiasing
+    float r = 0.5 * width + 1.0;
+    vec2 d = b - a;
+    d = length(d) > 0.0 ? normalize(d) * r : vec2(r, 0.0);
+    vec2 n = vec2(-d.y, d.x);
+
+    corner(a - d - n, p0.z / p0.w);
+    corner(a - d + n, p0.z / p0.w);
+    corner(b + d - n, p1.z / p1.w);
+    corner(b + d + n, p1.z / p1.w);
+    EndPrimitive();
+}
+`
+
+	fragment_glsl = `
+#version 330 core
+
+uniform float width;
+uniform float dashes[8]; // lengths of dashes and gaps, in turn
+uniform int dashCount;   // 0 for a solid line
+uniform float dashPeriod;
+uniform float dashOffset;
+
+in vec2 pixel;
+flat in vec2 a;
+flat in vec2 b;
+flat in vec4 colorA;
+flat in vec4 colorB;
+flat in vec2 distances;
+
+out vec4 fragColor;
+
+// inDash reports whether distance d along the line is in a dash, rather
+// than a gap.
+bool inDash(float d)
+{
+    float t = mod(d + dashOffset, dashPeriod);
+    for (int i = 0; i < dashCount; i++) {
+        if (t < dashes[i]) {
+            return i % 2 == 0;
+        }
+        t -= dashes[i];
+    }
+    return false;
+}
+
+void main()
+{
+    // the nearest point on the segment
+    vec2 ba = b - a;
+    float h = clamp(dot(pixel - a, ba) / max(dot(ba, ba), 1e-6), 0.0, 1.0);
+    float d = length(pixel - a - h * ba);
+
+    float coverage = clamp(0.5 * width + 0.5 - d, 0.0, 1.0);
+    if (coverage <= 0.0 || dashCount > 0 && !inDash(mix(distances.x, distances.y, h))) {
+        discard;
+    }
+    vec4 c = mix(colorA, colorB, h);
+    fragColor = vec4(c.rgb, c.a * coverage);
+}
+`
+)
+
+// Attribute locations, as set in the vertex shader.
+const (
+	PositionLocation = 0
+	ColorLocation    = 1
+	DistanceLocation = 2
+)
+
+// Point is a point of a line. The color changes gradually from point to
+// point.
+type Point struct {
+	Position glm.Vec3
+	Color    glm.Vec4
+}
+
+// Size of a point in the buffer: position, color, and distance along the
+// line.
+const stride = 8 * 4
+
+// Line is a line through a list of points, uploaded to a buffer.
+type Line struct {
+	Length float32 // in the coordinates of the points
+
+	vertexArray uint32
+	buffer      uint32
+	count       int32
+}
+
+// NewLine uploads the points of a line. A closed line goes from the last
+// point back to the first.
+func NewLine(points []Point, closed bool) *Line {
+	if closed && len(points) > 0 {
+		// the first point again, at the end of the line, for its distance
+		points = append(points[:len(points):len(points)], points[0])
+	}
+	l := &Line{
+		count: int32(len(points)),
+	}
+	data := make([]float32, 0, len(points)*stride/4)
+	for i, p := range points {
+		if i > 0 {
+			l.Length += p.Position.Sub(points[i-1].Position).Len()
+		}
+		data = append(data, p.Position[:]...)
+		data = append(data, p.Color[:]...)
+		data = append(data, l.Length)
+	}
+
+	gl.GenVertexArrays(1, &l.vertexArray)
+	gl.BindVertexArray(l.vertexArray)
+	l.buffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(PositionLocation, 3, gl.FLOAT, false, stride, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(PositionLocation)
+	gl.VertexAttribPointer(ColorLocation, 4, gl.FLOAT, false, stride, gl.PtrOffset(12))
+	gl.EnableVertexAttribArray(ColorLocation)
+	gl.VertexAttribPointer(DistanceLocation, 1, gl.FLOAT, false, stride, gl.PtrOffset(28))
+	gl.EnableVertexAttribArray(DistanceLocation)
+	gl.BindVertexArray(0)
+	return l
+}
+
+// Delete frees the buffer and the vertex array object.
+func (l *Line) Delete() {
+	gl.DeleteBuffers(1, &l.buffer)
+	gl.DeleteVertexArrays(1, &l.vertexArray)
+}
+
+// Renderer draws lines.
+type Renderer struct {
+	Width float32 // in pixels
+
+	// Lengths of dashes and gaps in turn, in the coordinates of the
+	// points, at most 8, nil for a solid line. As with the SVG
+	// stroke-dasharray, an odd number of lengths is repeated. The ends of
+	// the dashes are square.
+	Dashes []float32
+
+	// Distance along the line where the pattern starts. Changing it over
+	// time makes the dashes move.
+	DashOffset float32
+
+	// Test against the depth buffer, for lines in a 3D scene. The lines
+	// don't write to the depth buffer.
+	DepthTest bool
+
+	program *glutil.Program
+}
+
+// NewRenderer returns a renderer that draws lines 1 pixel wide.
+func NewRenderer() (*Renderer, error) {
+	p, err := glutil.NewGeometryProgram(vertex_glsl, geometry_glsl, fragment_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{
+		Width:   1,
+		program: p,
+	}, nil
+}
+
+// Draw draws a line, with transform from the coordinates of its points to
+// clip space, in a viewport of width by height pixels. The program in use
+// and the blend state are changed.
+func (r *Renderer) Draw(l *Line, transform glm.Mat4, width, height int32) {
+	if l.count < 2 {
+		return
+	}
+	glutil.State{
+		Blend:        true,
+		DepthTest:    r.DepthTest,
+		NoDepthWrite: true,
+	}.Apply()
+	p := r.program
+	p.Use()
+	p.SetMat4("transform", transform)
+	p.SetVec2("viewport", glm.Vec2{float32(width), float32(height)})
+	p.SetFloat("width", r.Width)
+	r.setDashes()
+
+	gl.BindVertexArray(l.vertexArray)
+	gl.DrawArrays(gl.LINE_STRIP, 0, l.count)
+	app.CountDrawCalls(1)
+	gl.BindVertexArray(0)
+}
+
+func (r *Renderer) setDashes() {
+	dashes := r.Dashes
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes[:len(dashes):len(dashes)], dashes...)
+	}
+	var period float32
+	for _, d := range dashes {
+		period += d
+	}
+	if len(dashes) > 8 || period <= 0 {
+		dashes = nil
+	}
+	p := r.program
+	p.SetInt("dashCount", int32(len(dashes)))
+	if len(dashes) > 0 {
+		gl.Uniform1fv(p.Uniform("dashes"), int32(len(dashes)), &dashes[0])
+		p.SetFloat("dashPeriod", period)
+		p.SetFloat("dashOffset", r.DashOffset)
+	}
+}
+
+// Delete deletes the shader program.
+func (r *Renderer) Delete() {
+	r.program.Delete()
+}
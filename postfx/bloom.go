@@ -0,0 +1,217 @@
+package postfx
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+)
+
+const (
+	bright_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform float threshold;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 c = texture(source, uv).rgb;
+    float brightness = max(c.r, max(c.g, c.b));
+    fragColor = vec4(c * max(brightness - threshold, 0.0) / max(brightness, 0.0001), 1.0);
+}
+`
+
+	// a 9-tap Gaussian blur in one direction
+	blur_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform vec2 direction; // one texel, horizontal or vertical
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+const float weights[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+
+void main()
+{
+    vec3 sum = weights[0] * texture(source, uv).rgb;
+    for (int i = 1; i < 5; i++) {
+        sum += weights[i] * texture(source, uv + float(i) * direction).rgb;
+        sum += weights[i] * texture(source, uv - float(i) * direction).rgb;
+    }
+    fragColor = vec4(sum, 1.0);
+}
+`
+
+	composite_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform sampler2D bloom;
+uniform float intensity;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 c = texture(source, uv);
+    fragColor = vec4(c.rgb + intensity * texture(bloom, uv).rgb, c.a);
+}
+`
+)
+
+// Bloom makes bright parts of an image glow. The bright parts are
+// extracted into a framebuffer of half the size, which is repeatedly
+// halved and blurred. The levels are added up, and added to the image.
+type Bloom struct {
+	Threshold float32 // brightness above which pixels glow
+	Intensity float32 // strength of the glow
+
+	// for each level, the result and a framebuffer for the first half of
+	// the blur
+	levels        [][2]*glutil.Framebuffer
+	width, height int32
+
+	bright, blur, copy, composite *glutil.Program
+}
+
+// NewBloom creates a bloom effect with the given number of levels. More
+// levels give a wider glow.
+func NewBloom(levels int) (*Bloom, error) {
+	b := &Bloom{
+		Threshold: 1,
+		Intensity: 1,
+		levels:    make([][2]*glutil.Framebuffer, levels),
+	}
+	var err error
+	for _, p := range []struct {
+		program **glutil.Program
+		source  string
+	}{
+		{&b.bright, bright_glsl},
+		{&b.blur, blur_glsl},
+		{&b.copy, copy_glsl},
+		{&b.composite, composite_glsl},
+	} {
+		*p.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, p.source)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// allocate creates the framebuffers for a source image of width by height.
+func (b *Bloom) allocate(width, height int32) error {
+	if width == b.width && height == b.height {
+		return nil
+	}
+	b.deleteLevels()
+	w, h := width, height
+	for i := range b.levels {
+		w, h = max1(w/2), max1(h/2)
+		for j := range b.levels[i] {
+			f, err := glutil.NewMultiFramebuffer(w, h, glutil.FormatRGBA16F)
+			if err != nil {
+				return err
+			}
+			f.SetFilter(gl.LINEAR)
+			b.levels[i][j] = f
+		}
+	}
+	b.width, b.height = width, height
+	return nil
+}
+
+func max1(i int32) int32 {
+	if i < 1 {
+		return 1
+	}
+	return i
+}
+
+// Apply adds bloom to the image in texture src, of width by height pixels,
+// and draws the result into dst, or into the window if dst is nil.
+func (b *Bloom) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	if err := b.allocate(width, height); err != nil {
+		return err
+	}
+	glutil.State{}.Apply()
+
+	// the bright parts, into the first level
+	bindTarget(b.levels[0][0], 0, 0)
+	b.bright.Use()
+	bindTexture(b.bright, "source", 0, src)
+	b.bright.SetFloat("threshold", b.Threshold)
+	glutil.DrawFullscreen()
+
+	for i, level := range b.levels {
+		// downsample from the previous level
+		if i > 0 {
+			bindTarget(level[0], 0, 0)
+			b.copy.Use()
+			bindTexture(b.copy, "source", 0, b.levels[i-1][0].Texture)
+			glutil.DrawFullscreen()
+		}
+
+		// blur horizontally into the second framebuffer, and vertically back
+		b.blur.Use()
+		texel := glm.Vec2{1 / float32(level[0].Width), 1 / float32(level[0].Height)}
+		bindTarget(level[1], 0, 0)
+		bindTexture(b.blur, "source", 0, level[0].Texture)
+		b.blur.SetVec2("direction", glm.Vec2{texel[0], 0})
+		glutil.DrawFullscreen()
+		bindTarget(level[0], 0, 0)
+		bindTexture(b.blur, "source", 0, level[1].Texture)
+		b.blur.SetVec2("direction", glm.Vec2{0, texel[1]})
+		glutil.DrawFullscreen()
+	}
+
+	// add each level to the one above it
+	glutil.State{Blend: true, BlendSrc: gl.ONE, BlendDst: gl.ONE}.Apply()
+	b.copy.Use()
+	for i := len(b.levels) - 1; i > 0; i-- {
+		bindTarget(b.levels[i-1][0], 0, 0)
+		bindTexture(b.copy, "source", 0, b.levels[i][0].Texture)
+		glutil.DrawFullscreen()
+	}
+	glutil.State{}.Apply()
+
+	bindTarget(dst, width, height)
+	b.composite.Use()
+	bindTexture(b.composite, "source", 0, src)
+	bindTexture(b.composite, "bloom", 1, b.levels[0][0].Texture)
+	b.composite.SetFloat("intensity", b.Intensity)
+	glutil.DrawFullscreen()
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	return nil
+}
+
+func (b *Bloom) deleteLevels() {
+	for i := range b.levels {
+		for j, f := range b.levels[i] {
+			if f != nil {
+				f.Delete()
+				b.levels[i][j] = nil
+			}
+		}
+	}
+}
+
+// Delete frees the framebuffers and programs.
+func (b *Bloom) Delete() {
+	b.deleteLevels()
+	b.bright.Delete()
+	b.blur.Delete()
+	b.copy.Delete()
+	b.composite.Delete()
+}
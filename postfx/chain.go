@@ -0,0 +1,211 @@
+package postfx
+
+import (
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glutil"
+)
+
+// Effect is a post-processing step. Bloom, ToneMap, Blur, Vignette,
+// Grayscale, ChromaticAberration, ColorGrade and CRT are effects.
+type Effect interface {
+	// Apply draws the image in texture src, of width by height pixels,
+	// with the effect into dst, or into the window if dst is nil.
+	Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error
+	// Delete frees the resources of the effect.
+	Delete()
+}
+
+// Node is an effect in a chain.
+type Node struct {
+	Name    string
+	Effect  Effect
+	Enabled bool
+}
+
+// Chain applies a sequence of effects, each working on the result of the
+// previous one. Intermediate results are kept in two RGBA16F framebuffers,
+// used in turn. Effects can be enabled, disabled and moved while the
+// chain is in use.
+type Chain struct {
+	Nodes []*Node
+
+	targets [2]*glutil.Framebuffer
+	copy    *glutil.Program
+
+	settings int // generation of the config settings last applied
+}
+
+// NewChain creates an empty chain.
+func NewChain() (*Chain, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, copy_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &Chain{copy: p}, nil
+}
+
+// Add appends an enabled effect to the end of the chain.
+func (c *Chain) Add(name string, e Effect) *Node {
+	n := &Node{Name: name, Effect: e, Enabled: true}
+	c.Nodes = append(c.Nodes, n)
+	return n
+}
+
+// Node returns the node with the given name, or nil.
+func (c *Chain) Node(name string) *Node {
+	for _, n := range c.Nodes {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// Toggle enables or disables the node with the given name, and returns
+// whether it is enabled now.
+func (c *Chain) Toggle(name string) bool {
+	n := c.Node(name)
+	if n == nil {
+		return false
+	}
+	n.Enabled = !n.Enabled
+	return n.Enabled
+}
+
+// Move moves the node with the given name to position i in the chain.
+func (c *Chain) Move(name string, i int) {
+	for j, n := range c.Nodes {
+		if n.Name == name {
+			c.Nodes = append(c.Nodes[:j], c.Nodes[j+1:]...)
+			if i < 0 {
+				i = 0
+			}
+			if i > len(c.Nodes) {
+				i = len(c.Nodes)
+			}
+			c.Nodes = append(c.Nodes[:i], append([]*Node{n}, c.Nodes[i:]...)...)
+			return
+		}
+	}
+}
+
+// allocate creates the framebuffers for images of width by height.
+func (c *Chain) allocate(width, height int32) error {
+	if c.targets[0] != nil && c.targets[0].Width == width && c.targets[0].Height == height {
+		return nil
+	}
+	c.deleteTargets()
+	for i := range c.targets {
+		f, err := glutil.NewMultiFramebuffer(width, height, glutil.FormatRGBA16F)
+		if err != nil {
+			return err
+		}
+		c.targets[i] = f
+	}
+	return nil
+}
+
+// Begin binds a framebuffer of width by height to render the scene into,
+// to be processed by End. On a nil chain, it does nothing, and the scene
+// is drawn where it would be without.
+func (c *Chain) Begin(width, height int32) error {
+	if c == nil {
+		return nil
+	}
+	if err := c.allocate(width, height); err != nil {
+		return err
+	}
+	c.targets[0].Bind()
+	return nil
+}
+
+// End applies the chain to the scene rendered after Begin, and draws the
+// result into dst, or into the window if dst is nil. On a nil chain, it
+// does nothing.
+func (c *Chain) End(dst *glutil.Framebuffer) error {
+	if c == nil {
+		return nil
+	}
+	return c.apply(0, c.targets[0].Texture, c.targets[0].Width, c.targets[0].Height, dst)
+}
+
+// Apply applies the chain to the image in texture src, of width by height
+// pixels, and draws the result into dst, or into the window if dst is nil.
+func (c *Chain) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	if err := c.allocate(width, height); err != nil {
+		return err
+	}
+	return c.apply(-1, src, width, height, dst)
+}
+
+// applySettings enables and disables effects as set in the config file,
+// once after each change of the file, so they can be toggled with keys in
+// between.
+func (c *Chain) applySettings() {
+	s, gen := app.CurrentSettings()
+	if gen == c.settings {
+		return
+	}
+	c.settings = gen
+	for name, enabled := range s.Effects {
+		if n := c.Node(name); n != nil {
+			n.Enabled = enabled
+		}
+	}
+}
+
+// apply runs the enabled effects. current is the index of the framebuffer
+// that holds src, or -1 if src is not one of them.
+func (c *Chain) apply(current int, src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	c.applySettings()
+	nodes := make([]*Node, 0, len(c.Nodes))
+	for _, n := range c.Nodes {
+		if n.Enabled {
+			nodes = append(nodes, n)
+		}
+	}
+
+	if len(nodes) == 0 {
+		glutil.State{}.Apply()
+		bindTarget(dst, width, height)
+		c.copy.Use()
+		bindTexture(c.copy, "source", 0, src)
+		glutil.DrawFullscreen()
+		return nil
+	}
+
+	for i, n := range nodes {
+		target := dst
+		next := -1
+		if i < len(nodes)-1 {
+			next = (current + 1) % 2
+			target = c.targets[next]
+		}
+		if err := n.Effect.Apply(src, width, height, target); err != nil {
+			return err
+		}
+		if target != nil {
+			src = target.Texture
+		}
+		current = next
+	}
+	return nil
+}
+
+func (c *Chain) deleteTargets() {
+	for i, f := range c.targets {
+		if f != nil {
+			f.Delete()
+			c.targets[i] = nil
+		}
+	}
+}
+
+// Delete frees the framebuffers, and all effects in the chain.
+func (c *Chain) Delete() {
+	c.deleteTargets()
+	c.copy.Delete()
+	for _, n := range c.Nodes {
+		n.Effect.Delete()
+	}
+}
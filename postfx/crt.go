@@ -0,0 +1,131 @@
+package postfx
+
+import (
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+)
+
+var crt = flag.Bool("crt", false, "draw as on an old CRT monitor")
+
+// OptionalCRT returns a chain with only a CRT effect, named "crt", if the
+// demo was started with -crt, and nil otherwise. Begin and End do nothing
+// on a nil chain, so a demo can call them either way:
+//
+//	x(effects.Begin(width, height))
+//	// draw the scene
+//	x(effects.End(nil))
+func OptionalCRT() (*Chain, error) {
+	if !*crt {
+		return nil, nil
+	}
+	c, err := NewChain()
+	if err != nil {
+		return nil, err
+	}
+	effect, err := NewCRT()
+	if err != nil {
+		c.Delete()
+		return nil, err
+	}
+	c.Add("crt", effect)
+	return c, nil
+}
+
+const crt_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform float curvature;
+uniform float scanlines;
+uniform float lineHeight;
+uniform float mask;
+uniform float height;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+// bulges the image out, more towards the corners
+vec2 curve(vec2 p)
+{
+    p = 2.0 * p - 1.0;
+    vec2 offset = abs(p.yx) * curvature;
+    p += p * offset * offset;
+    return 0.5 * p + 0.5;
+}
+
+void main()
+{
+    vec2 p = curve(uv);
+    if (p.x < 0.0 || p.x > 1.0 || p.y < 0.0 || p.y > 1.0) {
+        fragColor = vec4(0.0, 0.0, 0.0, 1.0);
+        return;
+    }
+    vec3 c = texture(source, p).rgb;
+
+    // dark gaps between the lines, brighter lines to make up for it
+    float line = 0.5 + 0.5 * cos(6.2831853 * p.y * height / lineHeight);
+    c *= mix(1.0, line, scanlines) * (1.0 + 0.5 * scanlines);
+
+    // an aperture grille: stripes of red, green and blue phosphor
+    int stripe = int(gl_FragCoord.x) % 3;
+    vec3 m = vec3(1.0 - mask);
+    m[stripe] = 1.0;
+    c *= m * (1.0 + 0.5 * mask);
+
+    // darker towards the rounded edges of the tube
+    float edge = 16.0 * p.x * p.y * (1.0 - p.x) * (1.0 - p.y);
+    c *= pow(edge, 0.25);
+
+    fragColor = vec4(c, 1.0);
+}
+`
+
+// CRT makes the image look like it is shown on an old cathode ray tube
+// monitor: curved, with scanlines, and a phosphor mask.
+type CRT struct {
+	Curvature  float32 // 0 for a flat screen
+	Scanlines  float32 // darkness of the gaps between scanlines, from 0 to 1
+	LineHeight float32 // distance between scanlines, in pixels
+	Mask       float32 // strength of the phosphor mask, from 0 to 1
+
+	program *glutil.Program
+}
+
+// NewCRT creates a CRT effect with a slightly curved screen, scanlines
+// every three pixels, and a faint phosphor mask.
+func NewCRT() (*CRT, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, crt_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &CRT{
+		Curvature:  .2,
+		Scanlines:  .6,
+		LineHeight: 3,
+		Mask:       .3,
+		program:    p,
+	}, nil
+}
+
+// Apply draws the image in texture src, of width by height pixels, as on
+// a CRT into dst, or into the window if dst is nil.
+func (c *CRT) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	glutil.State{}.Apply()
+	bindTarget(dst, width, height)
+	c.program.Use()
+	bindTexture(c.program, "source", 0, src)
+	c.program.SetFloat("curvature", c.Curvature)
+	c.program.SetFloat("scanlines", c.Scanlines)
+	c.program.SetFloat("lineHeight", c.LineHeight)
+	c.program.SetFloat("mask", c.Mask)
+	c.program.SetFloat("height", float32(height))
+	glutil.DrawFullscreen()
+	return nil
+}
+
+// Delete frees the program.
+func (c *CRT) Delete() {
+	c.program.Delete()
+}
@@ -0,0 +1,233 @@
+package postfx
+
+import (
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+)
+
+const (
+	vignette_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform float radius;
+uniform float softness;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 c = texture(source, uv);
+    float d = length(uv - 0.5) * 1.414;
+    fragColor = vec4(c.rgb * (1.0 - smoothstep(radius - softness, radius, d)), c.a);
+}
+`
+
+	grayscale_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform float amount;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 c = texture(source, uv);
+    float y = dot(c.rgb, vec3(0.2126, 0.7152, 0.0722));
+    fragColor = vec4(mix(c.rgb, vec3(y), amount), c.a);
+}
+`
+
+	// red and blue are shifted away from and towards the center
+	chromatic_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform vec2 offset; // largest shift, in texture coordinates
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec2 d = offset * (uv - 0.5) * 2.0;
+    vec4 c = texture(source, uv);
+    fragColor = vec4(texture(source, uv + d).r, c.g, texture(source, uv - d).b, c.a);
+}
+`
+)
+
+// Blur is a Gaussian blur, done in two passes, horizontally and vertically.
+type Blur struct {
+	Size float32 // distance between the samples, in pixels
+
+	program *glutil.Program
+	temp    *glutil.Framebuffer
+}
+
+// NewBlur creates a blur with samples one pixel apart.
+func NewBlur() (*Blur, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, blur_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &Blur{
+		Size:    1,
+		program: p,
+	}, nil
+}
+
+// Apply blurs the image in texture src, of width by height pixels, and
+// draws the result into dst, or into the window if dst is nil.
+func (b *Blur) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	if b.temp == nil || b.temp.Width != width || b.temp.Height != height {
+		if b.temp != nil {
+			b.temp.Delete()
+		}
+		var err error
+		b.temp, err = glutil.NewMultiFramebuffer(width, height, glutil.FormatRGBA16F)
+		if err != nil {
+			return err
+		}
+	}
+	glutil.State{}.Apply()
+	b.program.Use()
+
+	bindTarget(b.temp, 0, 0)
+	bindTexture(b.program, "source", 0, src)
+	b.program.SetVec2("direction", glm.Vec2{b.Size / float32(width), 0})
+	glutil.DrawFullscreen()
+
+	bindTarget(dst, width, height)
+	bindTexture(b.program, "source", 0, b.temp.Texture)
+	b.program.SetVec2("direction", glm.Vec2{0, b.Size / float32(height)})
+	glutil.DrawFullscreen()
+	return nil
+}
+
+// Delete frees the framebuffer and the program.
+func (b *Blur) Delete() {
+	if b.temp != nil {
+		b.temp.Delete()
+	}
+	b.program.Delete()
+}
+
+// Vignette darkens the image towards the corners.
+type Vignette struct {
+	Radius   float32 // distance from the center where darkening is complete, 1 is a corner
+	Softness float32 // width of the transition
+
+	program *glutil.Program
+}
+
+// NewVignette creates a vignette that is black in the corners.
+func NewVignette() (*Vignette, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, vignette_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &Vignette{
+		Radius:   1,
+		Softness: .6,
+		program:  p,
+	}, nil
+}
+
+// Apply darkens the image in texture src, of width by height pixels, and
+// draws the result into dst, or into the window if dst is nil.
+func (v *Vignette) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	glutil.State{}.Apply()
+	bindTarget(dst, width, height)
+	v.program.Use()
+	bindTexture(v.program, "source", 0, src)
+	v.program.SetFloat("radius", v.Radius)
+	v.program.SetFloat("softness", v.Softness)
+	glutil.DrawFullscreen()
+	return nil
+}
+
+// Delete frees the program.
+func (v *Vignette) Delete() {
+	v.program.Delete()
+}
+
+// Grayscale removes the colors from the image.
+type Grayscale struct {
+	Amount float32 // from 0, unchanged, to 1, completely gray
+
+	program *glutil.Program
+}
+
+// NewGrayscale creates a grayscale effect that removes all color.
+func NewGrayscale() (*Grayscale, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, grayscale_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &Grayscale{
+		Amount:  1,
+		program: p,
+	}, nil
+}
+
+// Apply removes the colors from the image in texture src, of width by
+// height pixels, and draws the result into dst, or into the window if dst
+// is nil.
+func (g *Grayscale) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	glutil.State{}.Apply()
+	bindTarget(dst, width, height)
+	g.program.Use()
+	bindTexture(g.program, "source", 0, src)
+	g.program.SetFloat("amount", g.Amount)
+	glutil.DrawFullscreen()
+	return nil
+}
+
+// Delete frees the program.
+func (g *Grayscale) Delete() {
+	g.program.Delete()
+}
+
+// ChromaticAberration splits the colors towards the edges of the image,
+// like a cheap lens does.
+type ChromaticAberration struct {
+	Offset float32 // shift of red and blue at the edges, in pixels
+
+	program *glutil.Program
+}
+
+// NewChromaticAberration creates a chromatic aberration of 4 pixels.
+func NewChromaticAberration() (*ChromaticAberration, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, chromatic_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &ChromaticAberration{
+		Offset:  4,
+		program: p,
+	}, nil
+}
+
+// Apply splits the colors of the image in texture src, of width by height
+// pixels, and draws the result into dst, or into the window if dst is nil.
+func (c *ChromaticAberration) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	glutil.State{}.Apply()
+	bindTarget(dst, width, height)
+	c.program.Use()
+	bindTexture(c.program, "source", 0, src)
+	c.program.SetVec2("offset", glm.Vec2{c.Offset / float32(width), c.Offset / float32(height)})
+	glutil.DrawFullscreen()
+	return nil
+}
+
+// Delete frees the program.
+func (c *ChromaticAberration) Delete() {
+	c.program.Delete()
+}
@@ -0,0 +1,146 @@
+package postfx
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"fmt"
+	"math"
+)
+
+// The lookup table is sampled at the centers of its first and last
+// texels for the ends of the domain, so that linear filtering between
+// entries gives the interpolation the table was made for.
+const grade_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform sampler3D lut;
+uniform vec3 domainMin;
+uniform vec3 domainMax;
+uniform float size;
+uniform float amount;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 c = texture(source, uv);
+    vec3 p = clamp((c.rgb - domainMin) / (domainMax - domainMin), 0.0, 1.0);
+    vec3 graded = texture(lut, p * (size - 1.0) / size + 0.5 / size).rgb;
+    fragColor = vec4(mix(c.rgb, graded, amount), c.a);
+}
+`
+
+// ColorGrade maps the colors of an image through a 3D lookup table, such
+// as one made in a color grading application and saved as a .cube file.
+// The input should be in the range the table was made for, usually after
+// tone mapping and gamma correction.
+type ColorGrade struct {
+	Amount float32 // 0 for the original colors, 1 for the graded ones
+
+	program   *glutil.Program
+	texture   uint32
+	size      int
+	domainMin glm.Vec3
+	domainMax glm.Vec3
+}
+
+// NewColorGrade creates a color grading effect with the lookup table,
+// fully applied.
+func NewColorGrade(lut *asset.LUT) (*ColorGrade, error) {
+	if lut.Size < 2 || len(lut.Data) != 3*lut.Size*lut.Size*lut.Size {
+		return nil, fmt.Errorf("invalid lookup table")
+	}
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, grade_glsl)
+	if err != nil {
+		return nil, err
+	}
+	s := int32(lut.Size)
+	// red changes fastest, as the rows of a 3D texture
+	texture := glutil.MakeTexture3D(s, s, s, glutil.Format{Internal: gl.RGB16F, Format: gl.RGB, Type: gl.FLOAT}, gl.Ptr(lut.Data))
+	return &ColorGrade{
+		Amount:    1,
+		program:   p,
+		texture:   texture,
+		size:      lut.Size,
+		domainMin: lut.DomainMin,
+		domainMax: lut.DomainMax,
+	}, nil
+}
+
+// LoadColorGrade creates a color grading effect with the lookup table in
+// a .cube file.
+func LoadColorGrade(filename string) (*ColorGrade, error) {
+	lut, err := asset.LoadCube(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewColorGrade(lut)
+}
+
+// Apply grades the image in texture src, of width by height pixels, and
+// draws the result into dst, or into the window if dst is nil.
+func (g *ColorGrade) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	glutil.State{}.Apply()
+	bindTarget(dst, width, height)
+	g.program.Use()
+	bindTexture(g.program, "source", 0, src)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_3D, g.texture)
+	g.program.SetInt("lut", 1)
+	g.program.SetVec3("domainMin", g.domainMin)
+	g.program.SetVec3("domainMax", g.domainMax)
+	g.program.SetFloat("size", float32(g.size))
+	g.program.SetFloat("amount", g.Amount)
+	glutil.DrawFullscreen()
+	gl.ActiveTexture(gl.TEXTURE0)
+	return nil
+}
+
+// Delete frees the texture and the program.
+func (g *ColorGrade) Delete() {
+	gl.DeleteTextures(1, &g.texture)
+	g.program.Delete()
+}
+
+// FilmLook returns a lookup table of size entries in each direction with
+// a common film look: more contrast, shadows towards teal, highlights
+// towards orange, and a little less saturation.
+func FilmLook(size int) *asset.LUT {
+	lut := asset.IdentityLUT(size)
+	lut.Title = "Film look"
+	for i := 0; i < len(lut.Data); i += 3 {
+		r, g, b := float64(lut.Data[i]), float64(lut.Data[i+1]), float64(lut.Data[i+2])
+
+		y := .2126*r + .7152*g + .0722*b
+		r, g, b = y+.85*(r-y), y+.85*(g-y), y+.85*(b-y)
+
+		// split toning, by brightness
+		shadow := 1 - smoothstep(0, .5, y)
+		highlight := smoothstep(.5, 1, y)
+		r += .08*highlight - .05*shadow
+		g += .02*highlight + .02*shadow
+		b += -.08*highlight + .05*shadow
+
+		lut.Data[i] = float32(sCurve(r))
+		lut.Data[i+1] = float32(sCurve(g))
+		lut.Data[i+2] = float32(sCurve(b))
+	}
+	return lut
+}
+
+func smoothstep(edge0, edge1, v float64) float64 {
+	t := math.Max(0, math.Min(1, (v-edge0)/(edge1-edge0)))
+	return t * t * (3 - 2*t)
+}
+
+// sCurve adds contrast, keeping black, white and middle gray.
+func sCurve(v float64) float64 {
+	v = math.Max(0, math.Min(1, v))
+	return .6*v + .4*smoothstep(0, 1, v)
+}
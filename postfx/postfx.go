@@ -0,0 +1,46 @@
+// Package postfx contains post-processing effects, that work on an image of
+// the scene that was rendered into a texture.
+//
+// All effects draw with glutil.DrawFullscreen, and leave blending and depth
+// testing disabled.
+package postfx
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/glutil"
+)
+
+// Fragment shader that copies a texture.
+const copy_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = texture(source, uv);
+}
+`
+
+// bindTarget makes dst the target for rendering, or the window if dst is
+// nil, with a viewport of width by height.
+func bindTarget(dst *glutil.Framebuffer, width, height int32) {
+	if dst != nil {
+		dst.Bind()
+	} else {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		gl.Viewport(0, 0, width, height)
+	}
+}
+
+// bindTexture binds a texture to a texture unit, and sets the sampler
+// uniform of program p, which must be in use.
+func bindTexture(p *glutil.Program, name string, unit int32, texture uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	p.SetInt(name, unit)
+}
@@ -0,0 +1,100 @@
+package postfx
+
+import (
+	"github.com/pebbe/gl/glutil"
+)
+
+const tonemap_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+uniform float exposure;
+uniform int operator;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+// fit by Krzysztof Narkowicz
+vec3 aces(vec3 x)
+{
+    return clamp((x * (2.51 * x + 0.03)) / (x * (2.43 * x + 0.59) + 0.14), 0.0, 1.0);
+}
+
+void main()
+{
+    vec4 c = texture(source, uv);
+    vec3 hdr = exposure * c.rgb;
+    vec3 ldr;
+    if (operator == 1) {
+        ldr = hdr / (hdr + vec3(1.0));
+    } else if (operator == 2) {
+        ldr = aces(hdr);
+    } else {
+        ldr = clamp(hdr, 0.0, 1.0);
+    }
+    // gamma correction
+    fragColor = vec4(pow(ldr, vec3(1.0 / 2.2)), c.a);
+}
+`
+
+// Operator is a function that maps high dynamic range colors to the range
+// from 0 to 1.
+type Operator int
+
+const (
+	Clamp    Operator = iota // no tone mapping, values above 1 are clipped
+	Reinhard                 // c / (c + 1)
+	ACES                     // approximation of the ACES filmic curve
+)
+
+var operatorNames = []string{"clamp", "Reinhard", "ACES"}
+
+func (o Operator) String() string {
+	if o >= 0 && int(o) < len(operatorNames) {
+		return operatorNames[o]
+	}
+	return "unknown"
+}
+
+// ToneMap maps a high dynamic range image, such as one rendered into a
+// framebuffer with glutil.FormatRGBA16F, to the range of the screen, and
+// applies gamma correction.
+type ToneMap struct {
+	Exposure float32 // colors are multiplied by this before mapping
+	Operator Operator
+
+	program *glutil.Program
+}
+
+// NewToneMap creates a tone mapping effect with an exposure of 1, using
+// the ACES curve.
+func NewToneMap() (*ToneMap, error) {
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, tonemap_glsl)
+	if err != nil {
+		return nil, err
+	}
+	return &ToneMap{
+		Exposure: 1,
+		Operator: ACES,
+		program:  p,
+	}, nil
+}
+
+// Apply maps the image in texture src, of width by height pixels, and draws
+// the result into dst, or into the window if dst is nil.
+func (t *ToneMap) Apply(src uint32, width, height int32, dst *glutil.Framebuffer) error {
+	glutil.State{}.Apply()
+	bindTarget(dst, width, height)
+	t.program.Use()
+	bindTexture(t.program, "source", 0, src)
+	t.program.SetFloat("exposure", t.Exposure)
+	t.program.SetInt("operator", int32(t.Operator))
+	glutil.DrawFullscreen()
+	return nil
+}
+
+// Delete frees the program.
+func (t *ToneMap) Delete() {
+	t.program.Delete()
+}
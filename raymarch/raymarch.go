@@ -0,0 +1,410 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/text"
+
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"time"
+)
+
+var (
+	// Signed distance functions for use in scenes. Each returns the
+	// distance from p to the surface, negative inside.
+	library_glsl = `
+float sdSphere(vec3 p, float r)
+{
+    return length(p) - r;
+}
+
+// b: half the size along each axis
+float sdBox(vec3 p, vec3 b)
+{
+    vec3 q = abs(p) - b;
+    return length(max(q, 0.0)) + min(max(q.x, max(q.y, q.z)), 0.0);
+}
+
+float sdRoundBox(vec3 p, vec3 b, float r)
+{
+    return sdBox(p, b - r) - r;
+}
+
+// a torus around the y axis
+float sdTorus(vec3 p, float major, float minor)
+{
+    return length(vec2(length(p.xz) - major, p.y)) - minor;
+}
+
+float sdCylinder(vec3 p, float r, float halfHeight)
+{
+    vec2 d = abs(vec2(length(p.xz), p.y)) - vec2(r, halfHeight);
+    return min(max(d.x, d.y), 0.0) + length(max(d, 0.0));
+}
+
+// the plane y = h
+float sdPlane(vec3 p, float h)
+{
+    return p.y - h;
+}
+
+// union that blends the shapes within distance k
+float opSmoothUnion(float a, float b, float k)
+{
+    float h = clamp(0.5 + 0.5 * (b - a) / k, 0.0, 1.0);
+    return mix(b, a, h) - k * h * (1.0 - h);
+}
+
+float opSubtract(float a, float b)
+{
+    return max(a, -b);
+}
+`
+
+	// The default scene. A scene defines sceneSDF, the distance to the
+	// nearest surface, and sceneColor, the color of the surface at a point.
+	// It can use the functions above, and the uniform time, in seconds.
+	scene_glsl = `
+float sceneSDF(vec3 p)
+{
+    float d = sdPlane(p, 0.0);
+    // a sphere bouncing into a box
+    float s = sdSphere(p - vec3(0.0, 1.2 + 0.4 * sin(time), 0.0), 0.8);
+    float b = sdRoundBox(p - vec3(0.0, 0.5, 0.0), vec3(1.2, 0.5, 1.2), 0.1);
+    d = min(d, opSmoothUnion(s, b, 0.4));
+    d = min(d, sdTorus(p - vec3(3.0, 0.3, 0.0), 0.8, 0.3));
+    d = min(d, opSubtract(sdBox(p - vec3(-3.0, 0.8, 0.0), vec3(0.8)), sdSphere(p - vec3(-3.0, 0.8, 0.0), 1.0)));
+    d = min(d, sdCylinder(p - vec3(0.0, 1.0, -3.0), 0.4, 1.0));
+    return d;
+}
+
+vec3 sceneColor(vec3 p)
+{
+    if (p.y < 0.01) {
+        // checkered floor
+        float c = mod(floor(p.x) + floor(p.z), 2.0);
+        return mix(vec3(0.3), vec3(0.6), c);
+    }
+    if (p.x > 2.0) {
+        return vec3(0.9, 0.5, 0.2);
+    }
+    if (p.x < -2.0) {
+        return vec3(0.3, 0.6, 0.9);
+    }
+    if (p.z < -2.0) {
+        return vec3(0.8, 0.8, 0.3);
+    }
+    return vec3(0.8, 0.3, 0.3);
+}
+`
+
+	// Sphere tracing: step along the ray by the distance to the nearest
+	// surface, which can't overshoot, until that distance is tiny.
+	header_glsl = `
+#version 330 core
+
+uniform mat4 cameraToWorld;
+uniform vec2 resolution;
+uniform float tanHalfFov;
+uniform float time;
+uniform bool shadows;
+uniform bool occlusion;
+
+out vec4 fragColor;
+`
+
+	trace_glsl = `
+const int MAX_STEPS = 256;
+const float MAX_DISTANCE = 100.0;
+const float EPSILON = 0.001;
+const vec3 lightDirection = normalize(vec3(0.6, 0.8, 0.4));
+const vec3 skyColor = vec3(0.6, 0.7, 0.9);
+
+// trace returns the distance along the ray to the surface, or -1
+float trace(vec3 origin, vec3 direction)
+{
+    float t = 0.0;
+    for (int i = 0; i < MAX_STEPS && t < MAX_DISTANCE; i++) {
+        float d = sceneSDF(origin + t * direction);
+        if (d < EPSILON * t) {
+            return t;
+        }
+        t += d;
+    }
+    return -1.0;
+}
+
+// the gradient of the distance, with four samples on a tetrahedron
+vec3 normal(vec3 p)
+{
+    const vec2 k = vec2(1.0, -1.0);
+    const float h = 0.0005;
+    return normalize(
+        k.xyy * sceneSDF(p + h * k.xyy) +
+        k.yyx * sceneSDF(p + h * k.yyx) +
+        k.yxy * sceneSDF(p + h * k.yxy) +
+        k.xxx * sceneSDF(p + h * k.xxx));
+}
+
+// Soft shadows: how close the ray towards the light passes by other
+// surfaces, relative to the distance travelled.
+float softShadow(vec3 p, vec3 l)
+{
+    float result = 1.0;
+    float t = 0.02;
+    for (int i = 0; i < 64 && t < 20.0; i++) {
+        float d = sceneSDF(p + t * l);
+        if (d < 0.0001) {
+            return 0.0;
+        }
+        result = min(result, 8.0 * d / t);
+        t += clamp(d, 0.02, 0.5);
+    }
+    return clamp(result, 0.0, 1.0);
+}
+
+// Ambient occlusion: at a few points along the normal, the distance to
+// the nearest surface is less than the distance to p if something is near.
+float ambientOcclusion(vec3 p, vec3 n)
+{
+    float occ = 0.0;
+    float weight = 1.0;
+    for (int i = 1; i <= 5; i++) {
+        float h = 0.03 * float(i * i);
+        occ += weight * (h - sceneSDF(p + h * n));
+        weight *= 0.7;
+    }
+    return clamp(1.0 - 1.5 * occ, 0.0, 1.0);
+}
+
+void main()
+{
+    vec2 ndc = (2.0 * gl_FragCoord.xy - resolution) / resolution.y;
+    vec3 origin = cameraToWorld[3].xyz;
+    vec3 direction = normalize(mat3(cameraToWorld) * vec3(ndc * tanHalfFov, -1.0));
+
+    vec3 c = skyColor - 0.3 * direction.y;
+    float t = trace(origin, direction);
+    if (t > 0.0) {
+        vec3 p = origin + t * direction;
+        vec3 n = normal(p);
+        vec3 albedo = sceneColor(p);
+        float diffuse = max(dot(n, lightDirection), 0.0);
+        if (shadows && diffuse > 0.0) {
+            diffuse *= softShadow(p + 0.01 * n, lightDirection);
+        }
+        float ao = occlusion ? ambientOcclusion(p, n) : 1.0;
+        float sky = 0.5 + 0.5 * n.y;
+        c = albedo * (diffuse * vec3(1.0, 0.95, 0.85) + 0.3 * sky * ao * skyColor);
+        // fog
+        c = mix(c, skyColor, 1.0 - exp(-0.0005 * t * t));
+    }
+    fragColor = vec4(pow(c, vec3(1.0 / 2.2)), 1.0);
+}
+`
+)
+
+var shaderFile = flag.String("shader", "", "GLSL file with a scene, that defines float sceneSDF(vec3 p) and vec3 sceneColor(vec3 p), reloaded when it changes (default: a built-in scene)")
+
+// Extra actions for this demo.
+const (
+	actionShadows   = "shadows"
+	actionOcclusion = "occlusion"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program // nil if the shader file never compiled
+	banner  *text.Banner
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.banner, err = text.NewBanner()
+	x(err)
+
+	if *shaderFile == "" {
+		r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, header_glsl+library_glsl+scene_glsl+trace_glsl)
+		x(err)
+	} else {
+		loadScene(r)
+	}
+
+	return r
+}
+
+// For reloading the shader file when it changes.
+var (
+	modTime   time.Time
+	lastCheck time.Time
+)
+
+// loadScene compiles the shader file. On an error, the error is shown in
+// the window, and the old program is kept, so the file can be fixed while
+// the demo runs.
+func loadScene(r *gResources) {
+	if fi, err := os.Stat(*shaderFile); err == nil {
+		modTime = fi.ModTime()
+	}
+	data, err := os.ReadFile(*shaderFile)
+	if err != nil {
+		r.banner.Set(err)
+		return
+	}
+	p, err := glutil.NewProgram(glutil.FullscreenVertexShader, header_glsl+library_glsl+string(data)+trace_glsl)
+	if err != nil {
+		app.Errorf("%s: %v", *shaderFile, err)
+		r.banner.Set(fmt.Errorf("%s: %v", *shaderFile, err))
+		return
+	}
+	if r.program != nil {
+		r.program.Delete()
+	}
+	r.program = p
+	r.banner.Set(nil)
+}
+
+// reload loads the shader file again when it has changed.
+func reload(r *gResources) {
+	if *shaderFile == "" || time.Since(lastCheck) < time.Second/2 {
+		return
+	}
+	lastCheck = time.Now()
+	fi, err := os.Stat(*shaderFile)
+	if err != nil || !fi.ModTime().After(modTime) {
+		return
+	}
+	loadScene(r)
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   = camera.NewOrbit(glm.Vec3{0, .5, 0}, 9)
+
+	shadows   = true
+	occlusion = true
+)
+
+const fov = 60 // degrees, vertical
+
+func render(w *glfw.Window, r *gResources) {
+	reload(r)
+
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	p := r.program
+	if p == nil {
+		gl.ClearColor(0, 0, 0, 0)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		return
+	}
+	p.Use()
+	p.SetMat4("cameraToWorld", cam.View().Inverse())
+	p.SetVec2("resolution", glm.Vec2{float32(width), float32(height)})
+	p.SetFloat("tanHalfFov", float32(math.Tan(fov*math.Pi/360)))
+	p.SetFloat("time", float32(clock.Seconds()))
+	p.SetBool("shadows", shadows)
+	p.SetBool("occlusion", occlusion)
+	glutil.DrawFullscreen()
+}
+
+func main() {
+	app.Keys[actionShadows] = []string{"s"}
+	app.Keys[actionOcclusion] = []string{"o"}
+	app.Flags(800, 600, "Raymarching")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+	cam.Pitch = .35
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 's' to toggle soft shadows, 'o' to toggle ambient occlusion")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+		r.banner.Draw(w.GetFramebufferSize())
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionShadows:
+		shadows = !shadows
+	case actionOcclusion:
+		occlusion = !occlusion
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
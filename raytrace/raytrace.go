@@ -0,0 +1,368 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	// Ray tracing: for each pixel a ray from the camera, intersected with
+	// every object in the scene, to find the nearest one. From there, a ray
+	// towards the light tells if the point is in shadow, and a reflected ray
+	// is traced the same way, for a number of bounces.
+	fragment_glsl = `
+#version 330 core
+
+const int MAX_SPHERES = 8;
+
+uniform mat4 cameraToWorld;
+uniform vec2 resolution;
+uniform float tanHalfFov;
+uniform int sphereCount;
+uniform vec4 spheres[MAX_SPHERES];   // center, radius
+uniform vec4 materials[MAX_SPHERES]; // color, reflectivity
+uniform vec3 lightPosition;
+uniform bool shadows;
+uniform int bounces;
+uniform int samples; // per pixel, along each axis
+
+out vec4 fragColor;
+
+const float INFINITY = 1e20;
+const float EPSILON = 0.001;
+const vec3 lightColor = vec3(1.0, 0.95, 0.85);
+const vec3 ambient = vec3(0.08, 0.1, 0.14);
+
+struct Hit {
+    float t; // distance along the ray, INFINITY for nothing
+    vec3 normal;
+    vec3 color;
+    float reflectivity;
+};
+
+// intersectSphere returns the distance along the ray to the sphere, or
+// INFINITY. The direction d must have unit length.
+float intersectSphere(vec3 o, vec3 d, vec4 s)
+{
+    // solve |o + t d - center|^2 = radius^2 for t
+    vec3 oc = o - s.xyz;
+    float b = dot(oc, d);
+    float c = dot(oc, oc) - s.w * s.w;
+    float h = b * b - c;
+    if (h < 0.0) {
+        return INFINITY;
+    }
+    h = sqrt(h);
+    float t = -b - h;
+    if (t < EPSILON) {
+        // the ray starts inside the sphere
+        t = -b + h;
+    }
+    return t < EPSILON ? INFINITY : t;
+}
+
+// intersectPlane returns the distance along the ray to the plane y = 0,
+// or INFINITY.
+float intersectPlane(vec3 o, vec3 d)
+{
+    float t = -o.y / d.y;
+    return t > EPSILON ? t : INFINITY;
+}
+
+Hit trace(vec3 o, vec3 d)
+{
+    Hit h = Hit(INFINITY, vec3(0.0), vec3(0.0), 0.0);
+
+    float t = intersectPlane(o, d);
+    if (t < h.t) {
+        vec3 p = o + t * d;
+        // a checkered floor, a bit of a mirror
+        float c = mod(floor(p.x) + floor(p.z), 2.0);
+        h = Hit(t, vec3(0.0, 1.0, 0.0), mix(vec3(0.2), vec3(0.8), c), 0.2);
+    }
+
+    for (int i = 0; i < sphereCount; i++) {
+        t = intersectSphere(o, d, spheres[i]);
+        if (t < h.t) {
+            vec3 p = o + t * d;
+            h = Hit(t, (p - spheres[i].xyz) / spheres[i].w, materials[i].rgb, materials[i].a);
+        }
+    }
+    return h;
+}
+
+vec3 sky(vec3 d)
+{
+    return mix(vec3(0.8, 0.85, 0.9), vec3(0.3, 0.5, 0.9), clamp(d.y, 0.0, 1.0));
+}
+
+// shade returns the light at p, coming straight from the light source,
+// without reflections.
+vec3 shade(vec3 p, vec3 d, Hit h)
+{
+    vec3 l = lightPosition - p;
+    float distance = length(l);
+    l /= distance;
+
+    float diffuse = max(dot(h.normal, l), 0.0);
+    float specular = pow(max(dot(reflect(-l, h.normal), -d), 0.0), 60.0);
+    if (shadows && diffuse > 0.0) {
+        // anything between p and the light
+        if (trace(p + EPSILON * h.normal, l).t < distance) {
+            diffuse = 0.0;
+            specular = 0.0;
+        }
+    }
+    return h.color * (ambient + diffuse * lightColor) + specular * lightColor;
+}
+
+// radiance follows a ray and its reflections.
+vec3 radiance(vec3 o, vec3 d)
+{
+    vec3 c = vec3(0.0);
+    float weight = 1.0;
+    for (int i = 0; i <= bounces; i++) {
+        Hit h = trace(o, d);
+        if (h.t == INFINITY) {
+            c += weight * sky(d);
+            break;
+        }
+        vec3 p = o + h.t * d;
+
+        // after the last bounce, everything is seen as if it doesn't reflect
+        float r = i < bounces ? h.reflectivity : 0.0;
+        vec3 local = shade(p, d, h);
+        // fade into the sky in the distance
+        float fog = 1.0 - exp(-0.0005 * h.t * h.t);
+        c += weight * mix((1.0 - r) * local, sky(d), fog);
+        weight *= r * (1.0 - fog);
+        if (weight < 0.01) {
+            break;
+        }
+
+        o = p + EPSILON * h.normal;
+        d = reflect(d, h.normal);
+    }
+    return c;
+}
+
+void main()
+{
+    vec3 origin = cameraToWorld[3].xyz;
+    vec3 c = vec3(0.0);
+    // supersampling, evenly spread over the pixel
+    for (int i = 0; i < samples; i++) {
+        for (int j = 0; j < samples; j++) {
+            vec2 offset = (vec2(i, j) + 0.5) / float(samples) - 0.5;
+            vec2 ndc = (2.0 * (gl_FragCoord.xy + offset) - resolution) / resolution.y;
+            vec3 direction = normalize(mat3(cameraToWorld) * vec3(ndc * tanHalfFov, -1.0));
+            c += radiance(origin, direction);
+        }
+    }
+    c /= float(samples * samples);
+    fragColor = vec4(pow(c, vec3(1.0 / 2.2)), 1.0);
+}
+`
+)
+
+// Extra actions for this demo.
+const (
+	actionShadows   = "shadows"
+	actionBounces   = "bounces"
+	actionAntialias = "antialias"
+)
+
+// Must match MAX_SPHERES in the shader.
+const maxSpheres = 8
+
+type sphere struct {
+	center       glm.Vec3
+	radius       float32
+	color        glm.Vec3
+	reflectivity float32 // 0 for none, 1 for a perfect mirror
+
+	// for spheres that circle around the big one, in radians per second
+	speed float64
+}
+
+var scene = []sphere{
+	{center: glm.Vec3{0, 1.5, 0}, radius: 1.5, color: glm.Vec3{.9, .9, .9}, reflectivity: .8},
+	{center: glm.Vec3{3, .6, 0}, radius: .6, color: glm.Vec3{.9, .2, .2}, reflectivity: .1, speed: .5},
+	{center: glm.Vec3{-2.5, .8, 2}, radius: .8, color: glm.Vec3{.2, .8, .3}, reflectivity: .3, speed: .3},
+	{center: glm.Vec3{0, .4, -3.5}, radius: .4, color: glm.Vec3{.2, .4, .9}, reflectivity: 0, speed: .7},
+	{center: glm.Vec3{-3.5, 1, -2.5}, radius: 1, color: glm.Vec3{.9, .7, .2}, reflectivity: .5, speed: .2},
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   = camera.NewOrbit(glm.Vec3{0, 1, 0}, 10)
+
+	shadows   = true
+	bounces   = 3
+	antialias = false
+)
+
+const (
+	fov        = 60 // degrees, vertical
+	maxBounces = 6
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	// the small spheres circle around the y axis
+	spheres := make([]glm.Vec4, len(scene))
+	materials := make([]glm.Vec4, len(scene))
+	for i, s := range scene {
+		a := s.speed * clock.Seconds()
+		sin, cos := float32(math.Sin(a)), float32(math.Cos(a))
+		spheres[i] = glm.Vec4{
+			cos*s.center[0] + sin*s.center[2],
+			s.center[1],
+			-sin*s.center[0] + cos*s.center[2],
+			s.radius,
+		}
+		materials[i] = glm.Vec4{s.color[0], s.color[1], s.color[2], s.reflectivity}
+	}
+
+	samples := int32(1)
+	if antialias {
+		samples = 2
+	}
+
+	p := r.program
+	p.Use()
+	p.SetMat4("cameraToWorld", cam.View().Inverse())
+	p.SetVec2("resolution", glm.Vec2{float32(width), float32(height)})
+	p.SetFloat("tanHalfFov", float32(math.Tan(fov*math.Pi/360)))
+	p.SetInt("sphereCount", int32(len(spheres)))
+	gl.Uniform4fv(p.Uniform("spheres"), int32(len(spheres)), &spheres[0][0])
+	gl.Uniform4fv(p.Uniform("materials"), int32(len(materials)), &materials[0][0])
+	p.SetVec3("lightPosition", glm.Vec3{6, 8, 4})
+	p.SetBool("shadows", shadows)
+	p.SetInt("bounces", int32(bounces))
+	p.SetInt("samples", samples)
+	glutil.DrawFullscreen()
+}
+
+func main() {
+	app.Keys[actionShadows] = []string{"s"}
+	app.Keys[actionBounces] = []string{"r"}
+	app.Keys[actionAntialias] = []string{"a"}
+	app.Flags(800, 600, "Ray tracing")
+	if len(scene) > maxSpheres {
+		app.Fatalf("At most %d spheres", maxSpheres)
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+	cam.Pitch = .3
+	cam.MinDistance = 2
+	cam.MaxDistance = 50
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 's' to toggle shadows, 'r' to change the number of reflections, 'a' to toggle antialiasing")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionShadows:
+		shadows = !shadows
+	case actionBounces:
+		bounces = (bounces + 1) % (maxBounces + 1)
+		app.Info("Reflections:", bounces)
+	case actionAntialias:
+		antialias = !antialias
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
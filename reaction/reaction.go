@@ -0,0 +1,380 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	// One step of the Gray-Scott model, rendering into a framebuffer with
+	// the next state. Two chemicals, a in the red and b in the green
+	// component, diffuse at different rates, and react: a + 2b -> 3b. A
+	// is fed in, b is killed off. The grid wraps around at the edges.
+	step_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D current;
+uniform float feed;
+uniform float kill;
+
+out vec4 fragColor;
+
+const float diffusionA = 1.0;
+const float diffusionB = 0.5;
+
+void main()
+{
+    ivec2 size = textureSize(current, 0);
+    ivec2 p = ivec2(gl_FragCoord.xy);
+
+    // the Laplacian, with weights for the neighbours that add up to one
+    vec2 c = texelFetch(current, p, 0).rg;
+    vec2 laplacian = -c;
+    for (int dy = -1; dy <= 1; dy++) {
+        for (int dx = -1; dx <= 1; dx++) {
+            if (dx != 0 || dy != 0) {
+                float w = dx == 0 || dy == 0 ? 0.2 : 0.05;
+                laplacian += w * texelFetch(current, (p + ivec2(dx, dy) + size) % size, 0).rg;
+            }
+        }
+    }
+
+    float a = c.r;
+    float b = c.g;
+    float reaction = a * b * b;
+    a += diffusionA * laplacian.r - reaction + feed * (1.0 - a);
+    b += diffusionB * laplacian.g + reaction - (kill + feed) * b;
+    fragColor = vec4(clamp(vec2(a, b), 0.0, 1.0), 0.0, 1.0);
+}
+`
+
+	// Shows the concentration of b.
+	show_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D cells;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    float b = texture(cells, uv).g;
+    vec3 c = mix(vec3(0.02, 0.03, 0.08), vec3(0.1, 0.5, 0.6), smoothstep(0.0, 0.2, b));
+    c = mix(c, vec3(1.0, 0.95, 0.8), smoothstep(0.2, 0.4, b));
+    fragColor = vec4(c, 1.0);
+}
+`
+)
+
+var (
+	cellSize = flag.Int("cell", 2, "size of a cell in pixels")
+	preset   = flag.Int("preset", 0, "preset to start with")
+)
+
+// Extra actions for this demo.
+const (
+	actionPreset   = "preset"
+	actionFeedUp   = "feed+"
+	actionFeedDown = "feed-"
+	actionKillUp   = "kill+"
+	actionKillDown = "kill-"
+	actionClear    = "clear"
+)
+
+// Steps per second at normal speed.
+const stepsPerSecond = 1200
+
+// Parameters that give well-known patterns.
+var presets = []struct {
+	name       string
+	feed, kill float32
+}{
+	{"coral", .0545, .062},
+	{"mitosis", .0367, .0649},
+	{"maze", .029, .057},
+	{"solitons", .03, .062},
+	{"worms", .046, .063},
+	{"waves", .014, .045},
+}
+
+var feed, kill float32
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	step *glutil.Program
+	show *glutil.Program
+
+	// The current state, and the next.
+	cells      [2]*glutil.Framebuffer
+	cols, rows int32
+}
+
+func makeResources(cols, rows int32) *gResources {
+	r := &gResources{
+		cols: cols,
+		rows: rows,
+	}
+
+	var err error
+	r.step, err = glutil.NewProgram(glutil.FullscreenVertexShader, step_fragment_glsl)
+	x(err)
+	r.show, err = glutil.NewProgram(glutil.FullscreenVertexShader, show_fragment_glsl)
+	x(err)
+
+	// the changes per step are small, too small for half floats
+	for i := range r.cells {
+		r.cells[i], err = glutil.NewMultiFramebuffer(cols, rows, glutil.FormatRG32F)
+		x(err)
+	}
+	// smooth when shown larger than the grid
+	r.cells[0].SetFilter(gl.LINEAR)
+	r.cells[1].SetFilter(gl.LINEAR)
+
+	seed(r)
+
+	return r
+}
+
+// seed fills the grid with a, and drops a few squares of b.
+func seed(r *gResources) {
+	data := make([]float32, 2*r.cols*r.rows)
+	for i := 0; i < len(data); i += 2 {
+		data[i] = 1
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, r.cols, r.rows, gl.RG, gl.FLOAT, gl.Ptr(data))
+	for i := 0; i < 20; i++ {
+		paint(r, rand.Int31n(r.cols), rand.Int31n(r.rows), 5)
+	}
+}
+
+// paint adds b in a square of 2 * size + 1 cells around col, row.
+func paint(r *gResources, col, row, size int32) {
+	x0, y0, x1, y1 := col-size, row-size, col+size+1, row+size+1
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > r.cols {
+		x1 = r.cols
+	}
+	if y1 > r.rows {
+		y1 = r.rows
+	}
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+	data := make([]float32, 2*(x1-x0)*(y1-y0))
+	for i := 0; i < len(data); i += 2 {
+		data[i] = .5
+		data[i+1] = .25 + .05*rand.Float32()
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, x0, y0, x1-x0, y1-y0, gl.RG, gl.FLOAT, gl.Ptr(data))
+}
+
+func setPreset(i int) {
+	*preset = i
+	feed, kill = presets[i].feed, presets[i].kill
+	app.Infof("Preset %q: feed %.4f, kill %.4f", presets[i].name, feed, kill)
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+
+	pending  float64 // steps to run, with a fraction left from the last frame
+	painting bool
+)
+
+// nextStep computes the next state into cells[1], and swaps the buffers.
+func nextStep(r *gResources) {
+	r.cells[1].Bind()
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	glutil.DrawFullscreen()
+	r.cells[1].Unbind()
+	r.cells[0], r.cells[1] = r.cells[1], r.cells[0]
+}
+
+func update(r *gResources) {
+	pending += clock.Delta() * stepsPerSecond
+	if pending < 1 {
+		return
+	}
+
+	glutil.State{}.Apply()
+	p := r.step
+	p.Use()
+	p.SetInt("current", 0)
+	p.SetFloat("feed", feed)
+	p.SetFloat("kill", kill)
+
+	// don't fall behind ever further if the GPU can't keep up
+	for n := 0; pending >= 1 && n < 40; n++ {
+		nextStep(r)
+		pending--
+	}
+	if pending > 1 {
+		pending = 0
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	r.show.Use()
+	r.show.SetInt("cells", 0)
+	gl.BindTexture(gl.TEXTURE_2D, r.cells[0].Texture)
+	glutil.DrawFullscreen()
+}
+
+func cursorPos(w *glfw.Window, r *gResources, x, y float64) {
+	if !painting {
+		return
+	}
+	// window coordinates have y down, the grid has row 0 at the bottom
+	width, height := w.GetSize()
+	col := int32(x / float64(width) * float64(r.cols))
+	row := int32((1 - y/float64(height)) * float64(r.rows))
+	paint(r, col, row, 3)
+}
+
+func main() {
+	app.Keys[actionPreset] = []string{"p"}
+	app.Keys[actionFeedUp] = []string{"up"}
+	app.Keys[actionFeedDown] = []string{"down"}
+	app.Keys[actionKillUp] = []string{"right"}
+	app.Keys[actionKillDown] = []string{"left"}
+	app.Keys[actionClear] = []string{"c"}
+	app.Flags(1024, 768, "Reaction-diffusion")
+
+	if *preset < 0 || *preset >= len(presets) {
+		app.Fatalf("preset must be from 0 to %d", len(presets)-1)
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	// the grid fills the window as it is at the start
+	width, height := w.GetFramebufferSize()
+	r := makeResources(int32(width / *cellSize), int32(height / *cellSize))
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button == glfw.MouseButtonLeft {
+			painting = action == glfw.Press
+			x, y := w.GetCursorPos()
+			cursorPos(w, r, x, y)
+		}
+	})
+	w.SetCursorPosCallback(func(w *glfw.Window, x, y float64) {
+		cursorPos(w, r, x, y)
+	})
+
+	app.Infof("%d x %d cells", r.cols, r.rows)
+	setPreset(*preset)
+	app.Info("Drag with the mouse to add chemical b")
+	app.Info("Press 'p' for the next preset, up and down to change the feed rate, left and right to change the kill rate, 'c' to start over")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionPreset:
+		setPreset((*preset + 1) % len(presets))
+		seed(r)
+	case actionFeedUp, actionFeedDown, actionKillUp, actionKillDown:
+		switch action {
+		case actionFeedUp:
+			feed += .001
+		case actionFeedDown:
+			feed -= .001
+		case actionKillUp:
+			kill += .001
+		case actionKillDown:
+			kill -= .001
+		}
+		if feed < 0 {
+			feed = 0
+		}
+		if kill < 0 {
+			kill = 0
+		}
+		app.Infof("Feed %.4f, kill %.4f", feed, kill)
+	case actionClear:
+		seed(r)
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
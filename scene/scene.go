@@ -0,0 +1,203 @@
+// Package scene is a scene graph: a tree of nodes, each with a
+// transformation relative to its parent, and optionally a mesh to draw with
+// a material.
+//
+// The transformation of a node relative to the root, its world matrix, is
+// only computed when it is asked for, and then kept until the node or one
+// of its ancestors moves. Moving a node marks its subtree as changed, so
+// nodes that don't move cost nothing.
+package scene
+
+import (
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/mesh"
+)
+
+// Material is how a mesh is drawn. The meaning of the values is up to the
+// shader.
+type Material struct {
+	Color     glm.Vec3
+	Emission  glm.Vec3 // light given off, e.g. by a lamp
+	Specular  float32
+	Shininess float32
+}
+
+// Node is a node in a scene graph. Nodes without a mesh group their
+// children, or are only a transformation, such as a pivot to rotate around.
+// Make nodes with NewNode.
+type Node struct {
+	Name     string
+	Mesh     *mesh.VAO // nil if there is nothing to draw
+	Material *Material
+
+	parent   *Node
+	children []*Node
+
+	translation glm.Vec3
+	rotation    glm.Quat
+	scale       glm.Vec3
+
+	local glm.Mat4
+	world glm.Mat4
+	dirty bool // local and world need to be computed
+}
+
+// NewNode returns a node without a transformation.
+func NewNode(name string) *Node {
+	return &Node{
+		Name:     name,
+		rotation: glm.Quat{0, 0, 0, 1},
+		scale:    glm.Vec3{1, 1, 1},
+		local:    glm.Identity(),
+		world:    glm.Identity(),
+	}
+}
+
+// NewMeshNode returns a node with a mesh and a material.
+func NewMeshNode(name string, vao *mesh.VAO, material *Material) *Node {
+	n := NewNode(name)
+	n.Mesh = vao
+	n.Material = material
+	return n
+}
+
+// Add makes the nodes children of n, and returns n. A node that already has
+// a parent is moved.
+func (n *Node) Add(children ...*Node) *Node {
+	for _, c := range children {
+		if c.parent != nil {
+			c.parent.Remove(c)
+		}
+		c.parent = n
+		n.children = append(n.children, c)
+		c.markDirty()
+	}
+	return n
+}
+
+// Remove removes a child from n. It does nothing if child is not a child
+// of n.
+func (n *Node) Remove(child *Node) {
+	for i, c := range n.children {
+		if c == child {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			child.parent = nil
+			child.markDirty()
+			return
+		}
+	}
+}
+
+// Parent returns the parent of n, or nil for a root.
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the children of n. The slice must not be changed.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// Find returns the first node in the subtree of n, n included, with the
+// name, or nil.
+func (n *Node) Find(name string) *Node {
+	if n.Name == name {
+		return n
+	}
+	for _, c := range n.children {
+		if found := c.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Translation returns the position of n relative to its parent.
+func (n *Node) Translation() glm.Vec3 {
+	return n.translation
+}
+
+// Rotation returns the rotation of n relative to its parent.
+func (n *Node) Rotation() glm.Quat {
+	return n.rotation
+}
+
+// Scale returns the scale of n relative to its parent.
+func (n *Node) Scale() glm.Vec3 {
+	return n.scale
+}
+
+// SetTranslation sets the position of n relative to its parent.
+func (n *Node) SetTranslation(t glm.Vec3) {
+	n.translation = t
+	n.markDirty()
+}
+
+// SetRotation sets the rotation of n relative to its parent.
+func (n *Node) SetRotation(q glm.Quat) {
+	n.rotation = q
+	n.markDirty()
+}
+
+// SetScale sets the scale of n relative to its parent.
+func (n *Node) SetScale(s glm.Vec3) {
+	n.scale = s
+	n.markDirty()
+}
+
+// markDirty marks n and its subtree as changed. A dirty node has a dirty
+// subtree, so the marking can stop there.
+func (n *Node) markDirty() {
+	if n.dirty {
+		return
+	}
+	n.dirty = true
+	for _, c := range n.children {
+		c.markDirty()
+	}
+}
+
+// Local returns the transformation of n relative to its parent: first
+// scale, then rotate, then translate.
+func (n *Node) Local() glm.Mat4 {
+	n.update()
+	return n.local
+}
+
+// World returns the transformation of n relative to the root.
+func (n *Node) World() glm.Mat4 {
+	n.update()
+	return n.world
+}
+
+// Position returns the origin of n relative to the root.
+func (n *Node) Position() glm.Vec3 {
+	return n.World().Transform(glm.Vec3{})
+}
+
+// update computes the matrices of n, and of its ancestors as needed.
+func (n *Node) update() {
+	if !n.dirty {
+		return
+	}
+	n.local = glm.Translate(n.translation[0], n.translation[1], n.translation[2]).
+		Mul(n.rotation.Mat4()).
+		Mul(glm.Scale(n.scale[0], n.scale[1], n.scale[2]))
+	if n.parent != nil {
+		n.world = n.parent.World().Mul(n.local)
+	} else {
+		n.world = n.local
+	}
+	n.dirty = false
+}
+
+// Walk calls f for each node in the subtree of n, n included, that has a
+// mesh, parents before their children, with the world matrix of the node.
+func (n *Node) Walk(f func(node *Node, world glm.Mat4)) {
+	if n.Mesh != nil {
+		f(n, n.World())
+	}
+	for _, c := range n.children {
+		c.Walk(f)
+	}
+}
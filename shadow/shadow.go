@@ -0,0 +1,308 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	//
+	// pass 1: depth only, seen from the light
+	//
+	depth_vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 lightSpace;
+
+layout(location = 0) in vec3 position;
+
+void main()
+{
+    gl_Position = lightSpace * model * vec4(position, 1.0);
+}
+` + "\x00"
+	depth_fragment_glsl = `
+#version 330 core
+
+void main()
+{
+}
+` + "\x00"
+
+	//
+	// pass 2: lit, with shadows from the depth texture
+	//
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+uniform mat4 lightSpace;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+out vec4 lightSpacePosition;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldNormal = normalMatrix * normal;
+    lightSpacePosition = lightSpace * p;
+}
+` + "\x00"
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2DShadow shadowMap;
+uniform vec3 lightDirection;
+uniform vec3 objectColor;
+uniform bool usePCF;
+
+in vec3 worldNormal;
+in vec4 lightSpacePosition;
+
+out vec4 fragColor;
+
+float lit(vec3 n, vec3 l)
+{
+    // from clip space to texture coordinates and depth
+    vec3 p = lightSpacePosition.xyz / lightSpacePosition.w * 0.5 + 0.5;
+    if (p.z > 1.0) {
+        return 1.0;
+    }
+
+    // the bias avoids shadow acne, more so on surfaces at a steep angle
+    float bias = max(0.005 * (1.0 - dot(n, l)), 0.0005);
+    if (!usePCF) {
+        return texture(shadowMap, vec3(p.xy, p.z - bias));
+    }
+
+    // percentage closer filtering: average the comparisons over 3x3 texels
+    vec2 texel = 1.0 / textureSize(shadowMap, 0);
+    float sum = 0.0;
+    for (int x = -1; x <= 1; x++) {
+        for (int y = -1; y <= 1; y++) {
+            sum += texture(shadowMap, vec3(p.xy + vec2(x, y) * texel, p.z - bias));
+        }
+    }
+    return sum / 9.0;
+}
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    vec3 l = normalize(lightDirection);
+    float diffuse = max(dot(n, l), 0.0);
+    fragColor = vec4((0.2 + 0.8 * diffuse * lit(n, l)) * objectColor, 1.0);
+}
+` + "\x00"
+)
+
+var shadowSize = flag.Int("shadowsize", 2048, "size of the shadow map")
+
+// Extra action for this demo.
+const actionPCF = "pcf"
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	depth  *glutil.Program
+	lit    *glutil.Program
+	shadow *glutil.Framebuffer
+
+	ground *mesh.VAO
+	cube   *mesh.VAO
+	sphere *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		ground: mesh.Quad().Upload(),
+		cube:   mesh.Cube().Upload(),
+		sphere: mesh.Sphere(48, 24).Upload(),
+	}
+
+	var err error
+	r.depth, err = glutil.NewProgram(depth_vertex_glsl, depth_fragment_glsl)
+	x(err)
+	r.lit, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.shadow, err = glutil.NewDepthFramebuffer(int32(*shadowSize), int32(*shadowSize))
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock  = app.NewClock()
+	zoom   = input.NewZoom(.3, 3)
+	usePCF = true
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+// drawScene draws all objects with program p, which must be in use.
+func drawScene(r *gResources, p *glutil.Program) {
+	t := float32(clock.Seconds())
+
+	draw := func(vao *mesh.VAO, model glm.Mat4, color glm.Vec3) {
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetVec3("objectColor", color)
+		vao.Draw()
+	}
+
+	// the ground, turned to face up
+	draw(r.ground,
+		glm.Translate(0, -1, 0).Mul(glm.Scale(5, 5, 5)).Mul(glm.Rotate(-math.Pi/2, glm.Vec3{1, 0, 0})),
+		glm.Vec3{.8, .8, .8})
+	draw(r.cube,
+		glm.Translate(-1.2, -.3, 0).Mul(glm.Rotate(t/2, glm.Vec3{0, 1, 0})).Mul(glm.Scale(.7, .7, .7)),
+		glm.Vec3{.2, .5, .9})
+	draw(r.sphere,
+		glm.Translate(1.2, float32(math.Abs(math.Sin(float64(2*t)))), .5).Mul(glm.Scale(.6, .6, .6)),
+		glm.Vec3{.9, .4, .2})
+}
+
+func render(w *glfw.Window, r *gResources) {
+	t := clock.Seconds()
+
+	// a directional light, turning slowly around the scene
+	lightDirection := glm.Vec3{float32(math.Cos(t / 4)), 2, float32(math.Sin(t / 4))}.Normalize()
+	lightView := glm.LookAt(lightDirection.Mul(10), glm.Vec3{}, glm.Vec3{0, 1, 0})
+	lightSpace := glm.Ortho(-6, 6, -6, 6, 1, 20).Mul(lightView)
+
+	state3D.Apply()
+
+	// pass 1: depth from the light
+	r.shadow.Bind()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	r.depth.Use()
+	r.depth.SetMat4("lightSpace", lightSpace)
+	drawScene(r, r.depth)
+	r.shadow.Unbind()
+
+	// pass 2: the scene, seen from the camera
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.5, .6, .7, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	eye := glm.Vec3{0, 3, 7 / float32(zoom.Scale())}
+	p := r.lit
+	p.Use()
+	p.SetMat4("view", glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0}))
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100))
+	p.SetMat4("lightSpace", lightSpace)
+	p.SetVec3("lightDirection", lightDirection)
+	p.SetBool("usePCF", usePCF)
+	p.SetInt("shadowMap", 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, r.shadow.DepthTexture)
+	drawScene(r, p)
+}
+
+func main() {
+	app.Keys[actionPCF] = []string{"p"}
+	app.Flags(640, 480, "Shadow mapping")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Press 'p' to toggle percentage closer filtering")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionPCF:
+		usePCF = !usePCF
+		if usePCF {
+			app.Info("PCF: on")
+		} else {
+			app.Info("PCF: off")
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,322 @@
+// Package shapes draws 2D shapes, rounded rectangles, circles, rings and
+// capsules, in batches. The shapes are signed distance functions evaluated
+// in the fragment shader, so their edges are antialiased at any size,
+// unlike lines and polygons drawn by the rasterizer.
+package shapes
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"unsafe"
+)
+
+const (
+	// Each shape is drawn as a rectangle around it, with the corners
+	// generated from gl_VertexID. All other data is per instance.
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 projection;
+
+layout(location = 0) in vec4 bounds; // x0, y0, x1, y1
+layout(location = 1) in vec4 points; // depends on the kind of shape
+layout(location = 2) in vec4 params; // kind, radius, width of a ring, width of the border
+layout(location = 3) in vec4 fill;
+layout(location = 4) in vec4 border;
+
+out vec2 position;
+flat out vec4 shapePoints;
+flat out vec4 shapeParams;
+flat out vec4 fillColor;
+flat out vec4 borderColor;
+
+void main()
+{
+    // a triangle strip: (0, 0), (1, 0), (0, 1), (1, 1)
+    vec2 corner = vec2(gl_VertexID & 1, gl_VertexID >> 1);
+    position = mix(bounds.xy, bounds.zw, corner);
+    shapePoints = points;
+    shapeParams = params;
+    fillColor = fill;
+    borderColor = border;
+    gl_Position = projection * vec4(position, 0.0, 1.0);
+}
+`
+
+	fragment_glsl = `
+#version 330 core
+
+in vec2 position;
+flat in vec4 shapePoints;
+flat in vec4 shapeParams;
+flat in vec4 fillColor;
+flat in vec4 borderColor;
+
+out vec4 fragColor;
+
+// Signed distances: negative inside, positive outside.
+
+float roundedRect(vec2 p, vec2 center, vec2 halfSize, float radius)
+{
+    vec2 q = abs(p - center) - halfSize + radius;
+    return length(max(q, 0.0)) + min(max(q.x, q.y), 0.0) - radius;
+}
+
+float circle(vec2 p, vec2 center, float radius)
+{
+    return length(p - center) - radius;
+}
+
+float ring(vec2 p, vec2 center, float radius, float width)
+{
+    return abs(length(p - center) - radius) - 0.5 * width;
+}
+
+float capsule(vec2 p, vec2 a, vec2 b, float radius)
+{
+    vec2 pa = p - a;
+    vec2 ba = b - a;
+    float h = clamp(dot(pa, ba) / max(dot(ba, ba), 1e-6), 0.0, 1.0);
+    return length(pa - h * ba) - radius;
+}
+
+void main()
+{
+    int kind = int(shapeParams.x);
+    float radius = shapeParams.y;
+    float d;
+    if (kind == 0) {
+        d = roundedRect(position, shapePoints.xy, shapePoints.zw, radius);
+    } else if (kind == 1) {
+        d = circle(position, shapePoints.xy, radius);
+    } else if (kind == 2) {
+        d = ring(position, shapePoints.xy, radius, shapeParams.z);
+    } else {
+        d = capsule(position, shapePoints.xy, shapePoints.zw, radius);
+    }
+
+    // the size of a pixel in units of distance, for smooth edges
+    float aa = max(fwidth(d), 1e-4);
+
+    vec4 c = fillColor;
+    float borderWidth = shapeParams.w;
+    if (borderWidth > 0.0) {
+        c = mix(c, borderColor, clamp(0.5 + (d + borderWidth) / aa, 0.0, 1.0));
+    }
+    float coverage = clamp(0.5 - d / aa, 0.0, 1.0);
+    if (coverage <= 0.0) {
+        discard;
+    }
+    fragColor = vec4(c.rgb, c.a * coverage);
+}
+`
+)
+
+// Style is how a shape is filled and outlined. The border lies inside the
+// edge of the shape, so it doesn't change its size.
+type Style struct {
+	Fill        glm.Vec4
+	Border      glm.Vec4
+	BorderWidth float32 // 0 for no border
+}
+
+// Kinds of shapes, as numbered in the fragment shader.
+const (
+	kindRect = iota
+	kindCircle
+	kindRing
+	kindCapsule
+)
+
+// A shape in the buffer. All fields are float32, so the Go struct has the
+// same layout.
+type tShape struct {
+	Bounds glm.Vec4
+	Points glm.Vec4
+	Params glm.Vec4
+	Fill   glm.Vec4
+	Border glm.Vec4
+}
+
+const shapeSize = int(unsafe.Sizeof(tShape{}))
+
+// Room around each shape for the antialiased edge, in units of the shape
+// coordinates. This is enough when the coordinates are pixels, or larger.
+const margin = 2
+
+// Batch collects shapes, and draws them with a single draw call.
+//
+// Use it as:
+//
+//	b.Begin(projection)
+//	b.Rect(min, max, radius, style)
+//	b.Circle(center, radius, style)
+//	...
+//	b.End()
+//
+// Shapes are drawn in the order they were added. More shapes than fit in
+// the batch start a new draw call.
+type Batch struct {
+	program *glutil.Program
+	vao     uint32
+	stream  *glutil.StreamBuffer
+
+	shapes []tShape
+
+	// Number of draw calls since Begin, for statistics.
+	DrawCalls int
+}
+
+// Blending for colors with straight, not premultiplied, alpha.
+var stateShapes = glutil.State{
+	Blend: true,
+}
+
+// NewBatch creates a batch for at most size shapes per draw call.
+func NewBatch(size int) (*Batch, error) {
+	p, err := glutil.NewProgram(vertex_glsl, fragment_glsl)
+	if err != nil {
+		return nil, err
+	}
+	b := &Batch{
+		program: p,
+		shapes:  make([]tShape, 0, size),
+	}
+	gl.GenVertexArrays(1, &b.vao)
+	gl.BindVertexArray(b.vao)
+	b.stream = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, size*shapeSize)
+	for i := uint32(0); i < 5; i++ {
+		gl.EnableVertexAttribArray(i)
+		gl.VertexAttribDivisor(i, 1)
+	}
+	gl.BindVertexArray(0)
+	return b, nil
+}
+
+// Begin starts a batch, with a projection from the coordinates of the
+// shapes to clip space, e.g. glm.Ortho(0, width, height, 0, -1, 1) for
+// pixels from the top left corner.
+func (b *Batch) Begin(projection glm.Mat4) {
+	b.shapes = b.shapes[:0]
+	b.DrawCalls = 0
+	b.program.Use()
+	b.program.SetMat4("projection", projection)
+}
+
+// Rect adds a rectangle from corner min to corner max, with corners
+// rounded with radius, 0 for square corners.
+func (b *Batch) Rect(min, max glm.Vec2, radius float32, s Style) {
+	half := max.Sub(min).Mul(.5)
+	if half[0] < 0 {
+		half[0] = -half[0]
+	}
+	if half[1] < 0 {
+		half[1] = -half[1]
+	}
+	if radius > half[0] {
+		radius = half[0]
+	}
+	if radius > half[1] {
+		radius = half[1]
+	}
+	center := min.Add(max).Mul(.5)
+	b.add(tShape{
+		Bounds: bounds(center, half[0], half[1]),
+		Points: glm.Vec4{center[0], center[1], half[0], half[1]},
+		Params: glm.Vec4{kindRect, radius, 0, s.BorderWidth},
+	}, s)
+}
+
+// Circle adds a disc.
+func (b *Batch) Circle(center glm.Vec2, radius float32, s Style) {
+	b.add(tShape{
+		Bounds: bounds(center, radius, radius),
+		Points: glm.Vec4{center[0], center[1], 0, 0},
+		Params: glm.Vec4{kindCircle, radius, 0, s.BorderWidth},
+	}, s)
+}
+
+// Ring adds a circle with a line of the given width, centered on radius.
+// The fill color is the color of the line.
+func (b *Batch) Ring(center glm.Vec2, radius, width float32, s Style) {
+	outer := radius + width/2
+	b.add(tShape{
+		Bounds: bounds(center, outer, outer),
+		Points: glm.Vec4{center[0], center[1], 0, 0},
+		Params: glm.Vec4{kindRing, radius, width, s.BorderWidth},
+	}, s)
+}
+
+// Capsule adds a line from p0 to p1 with round ends, all points within
+// radius of the line.
+func (b *Batch) Capsule(p0, p1 glm.Vec2, radius float32, s Style) {
+	bb := glm.Vec4{p0[0], p0[1], p1[0], p1[1]}
+	if bb[0] > bb[2] {
+		bb[0], bb[2] = bb[2], bb[0]
+	}
+	if bb[1] > bb[3] {
+		bb[1], bb[3] = bb[3], bb[1]
+	}
+	e := radius + margin
+	b.add(tShape{
+		Bounds: glm.Vec4{bb[0] - e, bb[1] - e, bb[2] + e, bb[3] + e},
+		Points: glm.Vec4{p0[0], p0[1], p1[0], p1[1]},
+		Params: glm.Vec4{kindCapsule, radius, 0, s.BorderWidth},
+	}, s)
+}
+
+// bounds returns the rectangle around center, half width by half height,
+// with a margin.
+func bounds(center glm.Vec2, hw, hh float32) glm.Vec4 {
+	hw += margin
+	hh += margin
+	return glm.Vec4{center[0] - hw, center[1] - hh, center[0] + hw, center[1] + hh}
+}
+
+func (b *Batch) add(sh tShape, s Style) {
+	if len(b.shapes) == cap(b.shapes) {
+		b.flush()
+	}
+	sh.Fill = s.Fill
+	sh.Border = s.Border
+	b.shapes = append(b.shapes, sh)
+}
+
+// End draws the shapes that haven't been drawn yet.
+func (b *Batch) End() {
+	b.flush()
+}
+
+func (b *Batch) flush() {
+	n := len(b.shapes)
+	if n == 0 {
+		return
+	}
+	stateShapes.Apply()
+	b.program.Use()
+
+	gl.BindVertexArray(b.vao)
+	offset := b.stream.Write(unsafe.Pointer(&b.shapes[0]), n*shapeSize)
+	// the offset changes with each write to a persistent buffer
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.stream.Buffer)
+	for i := 0; i < 5; i++ {
+		gl.VertexAttribPointer(uint32(i), 4, gl.FLOAT, false, int32(shapeSize), gl.PtrOffset(offset+16*i))
+	}
+	gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(n))
+	app.CountDrawCalls(1)
+	b.stream.Done()
+	gl.BindVertexArray(0)
+
+	b.shapes = b.shapes[:0]
+	b.DrawCalls++
+}
+
+// Delete frees the resources of the batch.
+func (b *Batch) Delete() {
+	b.stream.Delete()
+	gl.DeleteVertexArrays(1, &b.vao)
+	b.program.Delete()
+}
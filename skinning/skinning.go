@@ -0,0 +1,467 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/gltf"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+)
+
+// Maximum number of joints in a skin, the size of the uniform block.
+const maxJoints = 64
+
+var (
+	vertex_glsl = fmt.Sprintf(`
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform bool skinned;
+
+layout(std140) uniform Joints {
+    mat4 joints[%d];
+};
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 4) in uvec4 joint;
+layout(location = 5) in vec4 weight;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    mat4 skin = mat4(1.0);
+    if (skinned) {
+        skin = weight.x * joints[joint.x] +
+               weight.y * joints[joint.y] +
+               weight.z * joints[joint.z] +
+               weight.w * joints[joint.w];
+    }
+    mat4 m = model * skin;
+    vec4 p = m * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    // good enough without non-uniform scaling
+    worldNormal = mat3(m) * normal;
+}
+`, maxJoints)
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec4 baseColor;
+uniform vec3 eye;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+const vec3 lightDirection = normalize(vec3(0.4, 1.0, 0.6));
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    if (!gl_FrontFacing) {
+        n = -n;
+    }
+    vec3 v = normalize(eye - worldPosition);
+    vec3 h = normalize(lightDirection + v);
+    float diffuse = max(dot(n, lightDirection), 0.0);
+    float specular = diffuse > 0.0 ? 0.3 * pow(max(dot(n, h), 0.0), 32.0) : 0.0;
+    fragColor = vec4((0.2 + diffuse) * baseColor.rgb + specular, 1.0);
+}
+`
+)
+
+var (
+	model = flag.String("model", "", "glTF or GLB file with a skin (default: a built-in model)")
+	blend = flag.Float64("blend", .5, "seconds to crossfade from one animation to the next")
+)
+
+// Extra actions for this demo: select animation 1 to 9, or 0 for the rest
+// pose.
+const actionAnimation = "animation"
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	model   *gltf.Model
+	vaos    [][]*mesh.VAO // for each mesh, for each primitive
+	joints  *glutil.UniformBuffer
+}
+
+// The number of segments of the built-in model, each with its own joint.
+const segments = 4
+
+// defaultModel returns a column of segments, standing on the origin, with a
+// joint at the bottom of each segment, and two animations.
+func defaultModel() *gltf.Model {
+	const (
+		sides = 16
+		rings = 8 * segments
+		width = .3
+	)
+
+	// The mesh, with each vertex bound to the joints of the two nearest
+	// segment centers.
+	m := &mesh.Mesh{}
+	var prim gltf.Primitive
+	for i := 0; i <= rings; i++ {
+		y := float32(i) / rings * segments
+		t := y - .5
+		j0 := int(math.Max(0, math.Min(segments-1, math.Floor(float64(t)))))
+		j1 := j0 + 1
+		if j1 == segments {
+			j1 = j0
+		}
+		f := float32(math.Max(0, math.Min(1, float64(t)-float64(j0))))
+		for s := 0; s <= sides; s++ {
+			a := 2 * math.Pi * float64(s) / sides
+			n := glm.Vec3{float32(math.Cos(a)), 0, float32(-math.Sin(a))}
+			m.Vertices = append(m.Vertices, mesh.Vertex{
+				Position: glm.Vec3{width * n[0], y, width * n[2]},
+				Normal:   n,
+			})
+			prim.Joints = append(prim.Joints, [4]uint16{uint16(j0), uint16(j1), 0, 0})
+			prim.Weights = append(prim.Weights, glm.Vec4{1 - f, f, 0, 0})
+		}
+	}
+	for i := 0; i < rings; i++ {
+		for s := 0; s < sides; s++ {
+			a := uint32(i*(sides+1) + s)
+			b := a + sides + 1
+			m.Indices = append(m.Indices, a, a+1, b+1, a, b+1, b)
+		}
+	}
+	prim.Mesh = m
+	prim.Material = 0
+
+	rest := gltf.Transform{Rotation: glm.Quat{0, 0, 0, 1}, Scale: glm.Vec3{1, 1, 1}}
+
+	// Node 0 has the mesh, nodes 1 to segments are the chain of joints.
+	mdl := &gltf.Model{
+		Roots:     []int{0, 1},
+		Meshes:    []gltf.Mesh{{Name: "column", Primitives: []gltf.Primitive{prim}}},
+		Materials: []gltf.Material{gltf.DefaultMaterial},
+	}
+	mdl.Materials[0].BaseColor = glm.Vec4{.3, .6, .9, 1}
+	mdl.Nodes = append(mdl.Nodes, gltf.Node{Name: "column", Mesh: 0, Skin: 0, Local: glm.Identity(), Transform: rest})
+	skin := gltf.Skin{Name: "spine"}
+	for i := 0; i < segments; i++ {
+		t := rest
+		if i > 0 {
+			t.Translation = glm.Vec3{0, 1, 0}
+		}
+		n := gltf.Node{Name: fmt.Sprint("joint", i), Mesh: -1, Skin: -1, Local: t.Mat4(), Transform: t}
+		if i < segments-1 {
+			n.Children = []int{i + 2}
+		}
+		mdl.Nodes = append(mdl.Nodes, n)
+		skin.Joints = append(skin.Joints, i+1)
+		skin.InverseBind = append(skin.InverseBind, glm.Translate(0, -float32(i), 0))
+	}
+	mdl.Skins = []gltf.Skin{skin}
+
+	// Both animations take two seconds, with a key frame every 1/8 second.
+	const keys = 16
+	wave := gltf.Animation{Name: "wave", Duration: 2}
+	twist := gltf.Animation{Name: "twist", Duration: 2}
+	for i := 0; i < segments; i++ {
+		cw := gltf.Channel{Node: i + 1, Path: "rotation", Interpolation: "LINEAR"}
+		ct := cw
+		for k := 0; k <= keys; k++ {
+			t := float32(k) / keys * 2
+			phase := 2*math.Pi*float64(k)/keys - float64(i)*.6
+			q := glm.AxisAngle(glm.Vec3{0, 0, 1}, .4*float32(math.Sin(phase)))
+			cw.Times = append(cw.Times, t)
+			cw.Values = append(cw.Values, q[:]...)
+			q = glm.AxisAngle(glm.Vec3{0, 1, 0}, .5*float32(math.Sin(2*math.Pi*float64(k)/keys)))
+			ct.Times = append(ct.Times, t)
+			ct.Values = append(ct.Values, q[:]...)
+		}
+		wave.Channels = append(wave.Channels, cw)
+		twist.Channels = append(twist.Channels, ct)
+	}
+	mdl.Animations = []gltf.Animation{wave, twist}
+
+	return mdl
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	if *model != "" {
+		r.model, err = gltf.Load(*model)
+		x(err)
+	} else {
+		r.model = defaultModel()
+	}
+	for _, s := range r.model.Skins {
+		if len(s.Joints) > maxJoints {
+			app.Fatalf("skin %q has %d joints, the maximum is %d", s.Name, len(s.Joints), maxJoints)
+		}
+	}
+
+	for _, m := range r.model.Meshes {
+		vaos := make([]*mesh.VAO, len(m.Primitives))
+		for i, p := range m.Primitives {
+			vaos[i] = p.Mesh.Upload()
+			if p.Joints != nil {
+				vaos[i].AddSkin(p.Joints, p.Weights)
+			}
+		}
+		r.vaos = append(r.vaos, vaos)
+	}
+
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+	r.joints = glutil.NewUniformBuffer(0, maxJoints*64)
+	r.program.BindUniformBlock("Joints", 0)
+
+	return r
+}
+
+// bounds returns the center of the model in its rest pose, and the radius
+// of a sphere around it that contains all vertices.
+func bounds(m *gltf.Model) (center glm.Vec3, radius float32) {
+	inf := float32(math.Inf(1))
+	lo, hi := glm.Vec3{inf, inf, inf}, glm.Vec3{-inf, -inf, -inf}
+	m.Walk(func(n *gltf.Node, world glm.Mat4) {
+		if n.Skin >= 0 {
+			// the vertices of a skinned mesh are in model space
+			world = glm.Identity()
+		}
+		for _, p := range m.Meshes[n.Mesh].Primitives {
+			for _, v := range p.Mesh.Vertices {
+				q := world.Transform(v.Position)
+				for i := 0; i < 3; i++ {
+					lo[i] = float32(math.Min(float64(lo[i]), float64(q[i])))
+					hi[i] = float32(math.Max(float64(hi[i]), float64(q[i])))
+				}
+			}
+		}
+	})
+	if lo[0] > hi[0] {
+		return glm.Vec3{}, 1
+	}
+	center = lo.Add(hi).Mul(.5)
+	radius = hi.Sub(center).Len()
+	if radius == 0 {
+		radius = 1
+	}
+	return
+}
+
+//
+// Update and render
+//
+
+var (
+	cam   *camera.Orbit
+	clock = app.NewClock()
+
+	// The animation that is playing, and the one it is fading from, as
+	// indices into Model.Animations, or -1 for the rest pose.
+	current  = -1
+	previous = -1
+	// Seconds since each animation started, and since the fade started.
+	currentTime, previousTime, fadeTime float64
+
+	pose gltf.Pose
+)
+
+// animate returns the pose of the model for animation a at time t, looping
+// the animation.
+func animate(m *gltf.Model, a int, t float64) gltf.Pose {
+	p := m.RestPose()
+	if a >= 0 {
+		anim := &m.Animations[a]
+		if anim.Duration > 0 {
+			t = math.Mod(t, float64(anim.Duration))
+		}
+		anim.Apply(p, float32(t))
+	}
+	return p
+}
+
+func update(r *gResources) {
+	dt := clock.Delta()
+	currentTime += dt
+	previousTime += dt
+	fadeTime += dt
+
+	pose = animate(r.model, current, currentTime)
+	if fadeTime < *blend {
+		from := animate(r.model, previous, previousTime)
+		pose = gltf.Blend(from, pose, float32(fadeTime / *blend))
+	}
+}
+
+// play starts animation a, fading from the one that is playing.
+func play(m *gltf.Model, a int) {
+	if a >= len(m.Animations) || a == current {
+		return
+	}
+	previous, previousTime = current, currentTime
+	current, currentTime = a, 0
+	fadeTime = 0
+	if a < 0 {
+		app.Info("Rest pose")
+	} else {
+		app.Infof("Animation %d: %s", a+1, m.Animations[a].Name)
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+
+	glutil.State{DepthTest: true}.Apply()
+	gl.ClearColor(app.Background(.2, .2, .25, 1))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	near := cam.Distance / 100
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(45), float32(width)/float32(height), near, near*10000))
+	p.SetVec3("eye", cam.Eye())
+
+	world := r.model.WorldMatrices(pose)
+	for i, n := range r.model.Nodes {
+		if n.Mesh < 0 {
+			continue
+		}
+		p.SetMat4("model", world[i])
+		p.SetBool("skinned", n.Skin >= 0)
+		if n.Skin >= 0 {
+			joints := r.model.JointMatrices(&r.model.Skins[n.Skin], world, i)
+			r.joints.Update(gl.Ptr(joints), 64*len(joints))
+		}
+		for j, prim := range r.model.Meshes[n.Mesh].Primitives {
+			m := gltf.DefaultMaterial
+			if prim.Material >= 0 {
+				m = r.model.Materials[prim.Material]
+			}
+			p.SetVec4("baseColor", m.BaseColor)
+			r.vaos[n.Mesh][j].Draw()
+		}
+	}
+}
+
+func main() {
+	for i := 0; i <= 9; i++ {
+		app.Keys[fmt.Sprint(actionAnimation, i)] = []string{fmt.Sprint(i)}
+	}
+	app.Flags(800, 600, "Skinning")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	pose = r.model.RestPose()
+
+	center, radius := bounds(r.model)
+	cam = camera.NewOrbit(center, 2.5*radius)
+	cam.Pitch = .3
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, r, action)
+	})
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	app.Infof("%d nodes, %d skins, %d animations", len(r.model.Nodes), len(r.model.Skins), len(r.model.Animations))
+	for i, a := range r.model.Animations {
+		if i < 9 {
+			app.Infof("  %d: %s (%.2fs)", i+1, a.Name, a.Duration)
+		}
+	}
+	play(r.model, 0)
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press '1' to '9' to select an animation, '0' for the rest pose")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	default:
+		var i int
+		if _, err := fmt.Sscanf(action, actionAnimation+"%d", &i); err == nil {
+			play(r.model, i-1)
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
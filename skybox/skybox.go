@@ -0,0 +1,347 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	//
+	// the skybox
+	//
+	sky_vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+
+out vec3 direction;
+
+void main()
+{
+    // only the rotation of the view, the sky is infinitely far away
+    vec4 p = projection * mat4(mat3(view)) * vec4(position, 1.0);
+    // depth 1: the far plane
+    gl_Position = p.xyww;
+    direction = position;
+}
+` + "\x00"
+	sky_fragment_glsl = `
+#version 330 core
+
+uniform samplerCube sky;
+
+in vec3 direction;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = texture(sky, direction);
+}
+` + "\x00"
+
+	//
+	// the reflective object
+	//
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+	fragment_glsl = `
+#version 330 core
+
+uniform samplerCube sky;
+uniform vec3 viewPosition;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 i = normalize(worldPosition - viewPosition);
+    vec3 r = reflect(i, normalize(worldNormal));
+    fragColor = vec4(0.9 * texture(sky, r).rgb, 1.0);
+}
+` + "\x00"
+)
+
+var skyboxFiles = flag.String("skybox", "", "skybox images, as a pattern with %s for posx, negx, posy, negy, posz and negz, e.g. sky/%s.jpg")
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	skyProgram *glutil.Program
+	program    *glutil.Program
+	sky        uint32
+	cube       *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		cube: mesh.Cube().Upload(),
+	}
+
+	var err error
+	r.skyProgram, err = glutil.NewProgram(sky_vertex_glsl, sky_fragment_glsl)
+	x(err)
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	if *skyboxFiles != "" {
+		var filenames [6]string
+		for i, name := range []string{"posx", "negx", "posy", "negy", "posz", "negz"} {
+			filenames[i] = fmt.Sprintf(*skyboxFiles, name)
+		}
+		r.sky, err = glutil.MakeCubemapFromFiles(filenames)
+	} else {
+		r.sky, err = glutil.MakeCubemap(makeSky(256))
+	}
+	x(err)
+
+	return r
+}
+
+//
+// A generated sky, for when no images are given: a gradient from the
+// horizon up, a sun, and a checkered floor
+//
+
+// skyDirection returns the direction for pixel i, j of cube map face,
+// following the layout of cube map textures.
+func skyDirection(face, i, j, size int) glm.Vec3 {
+	s := 2*(float32(i)+.5)/float32(size) - 1
+	t := 2*(float32(j)+.5)/float32(size) - 1
+	switch face {
+	case 0:
+		return glm.Vec3{1, -t, -s}
+	case 1:
+		return glm.Vec3{-1, -t, s}
+	case 2:
+		return glm.Vec3{s, 1, t}
+	case 3:
+		return glm.Vec3{s, -1, -t}
+	case 4:
+		return glm.Vec3{s, -t, 1}
+	}
+	return glm.Vec3{-s, -t, -1}
+}
+
+func skyColor(d glm.Vec3) glm.Vec3 {
+	horizon := glm.Vec3{.85, .85, .9}
+	if d[1] >= 0 {
+		c := horizon.Lerp(glm.Vec3{.2, .4, .8}, float32(math.Sqrt(float64(d[1]))))
+		sun := glm.Vec3{.5, .4, -.7}.Normalize()
+		if d.Dot(sun) > .995 {
+			c = glm.Vec3{1, 1, .8}
+		}
+		return c
+	}
+	// the floor at y = -1
+	p := d.Mul(-1 / d[1])
+	c := glm.Vec3{.3, .3, .3}
+	if (int(math.Floor(float64(p[0])))+int(math.Floor(float64(p[2]))))%2 == 0 {
+		c = glm.Vec3{.6, .6, .6}
+	}
+	// fade into the horizon with distance
+	return horizon.Lerp(c, float32(math.Min(1, float64(-d[1])*4)))
+}
+
+func makeSky(size int) [6]image.Image {
+	var faces [6]image.Image
+	for face := range faces {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for j := 0; j < size; j++ {
+			for i := 0; i < size; i++ {
+				c := skyColor(skyDirection(face, i, j, size).Normalize())
+				img.SetRGBA(i, j, color.RGBA{uint8(255 * c[0]), uint8(255 * c[1]), uint8(255 * c[2]), 255})
+			}
+		}
+		faces[face] = img
+	}
+	return faces
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	zoom  = input.NewZoom(.3, 3)
+	drag  input.Drag
+
+	yaw, pitch float32 = 0, .2
+)
+
+var (
+	stateObject = glutil.State{
+		DepthTest: true,
+		CullFace:  true,
+	}
+
+	// The sky is drawn last, at depth 1, only where nothing else was drawn.
+	// It is seen from the inside, so there is no culling.
+	stateSky = glutil.State{
+		DepthTest:    true,
+		DepthFunc:    gl.LEQUAL,
+		NoDepthWrite: true,
+	}
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	stateObject.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	t := float32(clock.Seconds())
+
+	// the camera orbits the object
+	camera := glm.Rotate(yaw, glm.Vec3{0, 1, 0}).Mul(glm.Rotate(-pitch, glm.Vec3{1, 0, 0}))
+	eye := camera.Transform(glm.Vec3{0, 0, 5 / float32(zoom.Scale())})
+	view := glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0})
+	projection := glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 100)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, r.sky)
+
+	model := glm.Rotate(t/2, glm.Vec3{1, 1, 0}.Normalize())
+	p := r.program
+	p.Use()
+	p.SetMat4("model", model)
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetMat3("normalMatrix", model.NormalMatrix())
+	p.SetVec3("viewPosition", eye)
+	p.SetInt("sky", 0)
+	r.cube.Draw()
+
+	stateSky.Apply()
+	p = r.skyProgram
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetInt("sky", 0)
+	r.cube.Draw()
+}
+
+func main() {
+	app.Flags(640, 480, "Skybox")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+	drag.OnMove = func(x, y, dx, dy float64) {
+		yaw -= float32(dx) * .01
+		pitch += float32(dy) * .01
+		if pitch > 1.5 {
+			pitch = 1.5
+		} else if pitch < -1.5 {
+			pitch = -1.5
+		}
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to look around, scroll to zoom")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+// A small solar system, as a scene graph: the moon goes around the earth
+// because it is a child of a node that moves with the earth, and the tilt
+// of the earth doesn't tilt the orbit of the moon, because the tilt is on
+// a node of its own.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/scene"
+
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+
+	// lit by the sun, at the origin
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 viewPosition;
+
+uniform vec3 color;
+uniform vec3 emission;
+uniform float specular;
+uniform float shininess;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    vec3 l = normalize(-worldPosition);
+    vec3 v = normalize(viewPosition - worldPosition);
+    vec3 r = reflect(-l, n);
+
+    vec3 result = emission + 0.05 * color;
+    result += max(dot(n, l), 0.0) * color;
+    result += specular * pow(max(dot(v, r), 0.0), shininess) * vec3(1.0);
+    fragColor = vec4(result, 1.0);
+}
+` + "\x00"
+)
+
+// Extra action for this demo.
+const actionCamera = "camera"
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	sphere  *mesh.VAO
+	cube    *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		sphere: mesh.Sphere(48, 24).Upload(),
+		cube:   mesh.Cube().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// The scene
+//
+
+// The nodes that move.
+type tSystem struct {
+	root *scene.Node
+
+	sun        *scene.Node
+	earthOrbit *scene.Node // rotates around the sun
+	earth      *scene.Node // spins around its axis
+	moonOrbit  *scene.Node // rotates around the earth
+	marsOrbit  *scene.Node
+	mars       *scene.Node
+	satellite  *scene.Node // tumbles around mars
+}
+
+func makeSystem(r *gResources) *tSystem {
+	s := &tSystem{root: scene.NewNode("system")}
+
+	s.sun = scene.NewMeshNode("sun", r.sphere, &scene.Material{
+		Color:    glm.Vec3{1, .8, .3},
+		Emission: glm.Vec3{1, .7, .2},
+	})
+
+	// orbit, position on the orbit, tilt, spin: each its own node
+	s.earthOrbit = scene.NewNode("earth orbit")
+	earthPosition := scene.NewNode("earth position")
+	earthPosition.SetTranslation(glm.Vec3{4, 0, 0})
+	earthTilt := scene.NewNode("earth tilt")
+	earthTilt.SetRotation(glm.AxisAngle(glm.Vec3{0, 0, 1}, glm.Radians(23.4)))
+	s.earth = scene.NewMeshNode("earth", r.sphere, &scene.Material{
+		Color:     glm.Vec3{.2, .4, .9},
+		Specular:  .5,
+		Shininess: 32,
+	})
+	s.earth.SetScale(glm.Vec3{.4, .4, .4})
+
+	// the moon is a child of the position of the earth, not of the earth
+	// itself, so it is neither tilted nor spun with it
+	s.moonOrbit = scene.NewNode("moon orbit")
+	moon := scene.NewMeshNode("moon", r.sphere, &scene.Material{
+		Color: glm.Vec3{.6, .6, .6},
+	})
+	moon.SetTranslation(glm.Vec3{.9, 0, 0})
+	moon.SetScale(glm.Vec3{.12, .12, .12})
+
+	s.marsOrbit = scene.NewNode("mars orbit")
+	marsPosition := scene.NewNode("mars position")
+	marsPosition.SetTranslation(glm.Vec3{6.5, 0, 0})
+	s.mars = scene.NewMeshNode("mars", r.sphere, &scene.Material{
+		Color:     glm.Vec3{.8, .35, .2},
+		Specular:  .2,
+		Shininess: 8,
+	})
+	s.mars.SetScale(glm.Vec3{.3, .3, .3})
+
+	// a child of mars, so it goes around with the spin of mars
+	s.satellite = scene.NewMeshNode("satellite", r.cube, &scene.Material{
+		Color:     glm.Vec3{.8, .8, .85},
+		Specular:  .8,
+		Shininess: 64,
+	})
+	s.satellite.SetTranslation(glm.Vec3{0, 0, 1.8})
+	s.satellite.SetScale(glm.Vec3{.15, .15, .15})
+
+	s.root.Add(
+		s.sun,
+		s.earthOrbit.Add(
+			earthPosition.Add(
+				earthTilt.Add(s.earth),
+				s.moonOrbit.Add(moon),
+			),
+		),
+		s.marsOrbit.Add(
+			marsPosition.Add(
+				s.mars.Add(s.satellite),
+			),
+		),
+	)
+
+	return s
+}
+
+// update sets the rotations for time t, in seconds.
+func (s *tSystem) update(t float32) {
+	up := glm.Vec3{0, 1, 0}
+	s.sun.SetRotation(glm.AxisAngle(up, t*.1))
+	s.earthOrbit.SetRotation(glm.AxisAngle(up, t*.3))
+	s.earth.SetRotation(glm.AxisAngle(up, t*2))
+	s.moonOrbit.SetRotation(glm.AxisAngle(up, t*1.2))
+	s.marsOrbit.SetRotation(glm.AxisAngle(up, t*.16))
+	s.mars.SetRotation(glm.AxisAngle(up, t*1.9))
+	s.satellite.SetRotation(glm.AxisAngle(glm.Vec3{1, 1, 0}, t*3))
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	zoom  = input.NewZoom(.3, 3)
+
+	followEarth = false
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func render(w *glfw.Window, r *gResources, s *tSystem) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, .02, 0))
+	state3D.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	s.update(float32(clock.Seconds()))
+
+	var eye, center glm.Vec3
+	if followEarth {
+		// from outside the orbit, at the height of the plane of the
+		// orbits, looking at the earth
+		center = s.earth.Position()
+		eye = center.Add(center.Normalize().Mul(2.5 / float32(zoom.Scale()))).Add(glm.Vec3{0, .4, 0})
+	} else {
+		eye = glm.Vec3{0, 6, 12}.Mul(1 / float32(zoom.Scale()))
+	}
+	view := glm.LookAt(eye, center, glm.Vec3{0, 1, 0})
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100)
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("viewPosition", eye)
+
+	s.root.Walk(func(node *scene.Node, world glm.Mat4) {
+		m := node.Material
+		p.SetMat4("model", world)
+		p.SetMat3("normalMatrix", world.NormalMatrix())
+		p.SetVec3("color", m.Color)
+		p.SetVec3("emission", m.Emission)
+		p.SetFloat("specular", m.Specular)
+		p.SetFloat("shininess", m.Shininess)
+		node.Mesh.Draw()
+	})
+}
+
+func main() {
+	app.Keys[actionCamera] = []string{"c"}
+	app.Flags(800, 600, "Solar system")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	s := makeSystem(r)
+
+	app.Info("Press 'c' to switch between the view of the system and the view of the earth")
+	app.Info("Scroll to zoom")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r, s)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionCamera:
+		followEarth = !followEarth
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
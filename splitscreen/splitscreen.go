@@ -0,0 +1,275 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+uniform vec3 lightDirection;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    float diffuse = max(dot(normalize(worldNormal), lightDirection), 0.0);
+    fragColor = vec4((0.3 + 0.7 * diffuse) * color, 1.0);
+}
+` + "\x00"
+)
+
+//
+// The players, each with a camera and a half of the window
+//
+
+type tPlayer struct {
+	cam        *camera.FreeFly
+	color      glm.Vec3 // of the player as seen by the other
+	background glm.Vec3
+}
+
+var players [2]*tPlayer
+
+// active is the player whose camera is controlled with the mouse, the one
+// whose half of the window the mouse button was pressed in.
+var active *tPlayer
+
+func mouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+	if action == glfw.Press {
+		width, _ := w.GetSize()
+		cx, _ := w.GetCursorPos()
+		active = players[0]
+		if cx >= float64(width)/2 {
+			active = players[1]
+		}
+	}
+	if active != nil {
+		active.cam.MouseButton(w, button, action, mod)
+	}
+}
+
+func cursorPos(w *glfw.Window, cx, cy float64) {
+	if active != nil {
+		active.cam.CursorPos(w, cx, cy)
+	}
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	cube    *mesh.VAO
+	sphere  *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		cube:   mesh.Cube().Upload(),
+		sphere: mesh.Sphere(24, 12).Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var clock = app.NewClock()
+
+var stateScene = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func drawScene(r *gResources, viewer *tPlayer) {
+	p := r.program
+	draw := func(v *mesh.VAO, model glm.Mat4, color glm.Vec3) {
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetVec3("color", color)
+		v.Draw()
+	}
+
+	// the floor, and a grid of pillars
+	draw(r.cube, glm.Translate(0, -.1, 0).Mul(glm.Scale(20, .1, 20)), glm.Vec3{.4, .45, .4})
+	t := float32(clock.Seconds())
+	for i := -3; i <= 3; i++ {
+		for j := -3; j <= 3; j++ {
+			h := 1 + .5*float32(math.Sin(float64(i*3+j)))
+			model := glm.Translate(float32(i)*5, h, float32(j)*5).
+				Mul(glm.Rotate(t/4, glm.Vec3{0, 1, 0})).
+				Mul(glm.Scale(.6, h, .6))
+			draw(r.cube, model, glm.Vec3{.6 + .05*float32(i), .6, .6 - .05*float32(j)})
+		}
+	}
+
+	// the other players
+	for _, pl := range players {
+		if pl != viewer {
+			pos := pl.cam.Position
+			draw(r.sphere, glm.Translate(pos[0], pos[1], pos[2]).Mul(glm.Scale(.5, .5, .5)), pl.color)
+		}
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	half := int32(width / 2)
+
+	p := r.program
+	p.Use()
+	p.SetVec3("lightDirection", glm.Vec3{.4, 1, .6}.Normalize())
+
+	for i, pl := range players {
+		// The viewport only maps coordinates to the window, it doesn't
+		// limit drawing or clearing. The scissor test does.
+		x0 := int32(i) * half
+		gl.Viewport(x0, 0, half, int32(height))
+		state := stateScene
+		state.Scissor = &glutil.Scissor{X: x0, Y: 0, Width: half, Height: int32(height)}
+		state.Apply()
+		gl.ClearColor(pl.background[0], pl.background[1], pl.background[2], 0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+		p.SetMat4("view", pl.cam.View())
+		p.SetMat4("projection", glm.Perspective(glm.Radians(60), float32(half)/float32(height), .1, 100))
+		drawScene(r, pl)
+	}
+	stateScene.Apply()
+}
+
+func main() {
+	app.Flags(1000, 400, "Split screen")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+
+	players[0] = &tPlayer{
+		cam:        camera.NewFreeFly(glm.Vec3{-8, 1.5, 12}),
+		color:      glm.Vec3{.9, .2, .2},
+		background: glm.Vec3{.55, .65, .8},
+	}
+	players[0].cam.Yaw = .5
+	players[0].cam.Keys.Up = glfw.KeyR
+	players[0].cam.Keys.Down = glfw.KeyF
+	players[1] = &tPlayer{
+		cam:        camera.NewFreeFly(glm.Vec3{8, 1.5, 12}),
+		color:      glm.Vec3{.2, .3, .9},
+		background: glm.Vec3{.75, .65, .55},
+	}
+	players[1].cam.Yaw = -.5
+	players[1].cam.Keys = camera.Arrows
+	w.SetMouseButtonCallback(mouseButton)
+	w.SetCursorPosCallback(cursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Left player: W, A, S and D to move, R and F to go up and down")
+	app.Info("Right player: arrow keys to move, home and end to go up and down")
+	app.Info("Drag with the mouse in either half to look around, hold shift to move faster")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		for _, pl := range players {
+			pl.cam.Update(w)
+		}
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
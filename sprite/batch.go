@@ -0,0 +1,190 @@
+// Package sprite draws 2D sprites, textured rectangles, in batches, and
+// plays frame animations from sprite sheets.
+package sprite
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"unsafe"
+)
+
+const (
+	// The corners of each sprite are generated from gl_VertexID, all other
+	// data is per instance.
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 projection;
+
+layout(location = 0) in vec4 rect; // center, size
+layout(location = 1) in vec4 region; // u0, v0, u1, v1
+layout(location = 2) in vec4 color;
+layout(location = 3) in float rotation;
+
+out vec2 texcoord;
+out vec4 tint;
+
+void main()
+{
+    // a triangle strip: (0, 0), (1, 0), (0, 1), (1, 1)
+    vec2 corner = vec2(gl_VertexID & 1, gl_VertexID >> 1);
+    texcoord = mix(region.xy, region.zw, corner);
+    tint = color;
+
+    vec2 p = (corner - 0.5) * rect.zw;
+    float c = cos(rotation);
+    float s = sin(rotation);
+    p = vec2(c * p.x - s * p.y, s * p.x + c * p.y);
+    gl_Position = projection * vec4(rect.xy + p, 0.0, 1.0);
+}
+`
+
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D sprite;
+
+in vec2 texcoord;
+in vec4 tint;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = tint * texture(sprite, texcoord);
+}
+`
+)
+
+// Sprite is a rectangle drawn with a region of a texture.
+type Sprite struct {
+	Position glm.Vec2 // center
+	Size     glm.Vec2
+
+	// The region of the texture, u0, v0, u1, v1, with u0, v0 at the corner
+	// where x and y are smallest. Texture coordinates of images loaded
+	// with glutil have v 0 at the top, so a projection with y pointing
+	// down shows them the right way up.
+	Region glm.Vec4
+
+	Color    glm.Vec4 // multiplied with the texture
+	Rotation float32  // around the center, in radians
+}
+
+// Size of a sprite in the buffer. All fields are float32, so the Go struct
+// has the same layout.
+const spriteSize = int(unsafe.Sizeof(Sprite{}))
+
+// Batch collects sprites that use the same texture, and draws them with a
+// single draw call.
+//
+// Use it as:
+//
+//	b.Begin(projection)
+//	b.Draw(texture, s1)
+//	b.Draw(texture, s2)
+//	...
+//	b.End()
+//
+// A change of texture, or more sprites than fit in the batch, starts a new
+// draw call.
+type Batch struct {
+	program *glutil.Program
+	vao     uint32
+	stream  *glutil.StreamBuffer
+
+	sprites []Sprite
+	texture uint32
+
+	// Number of draw calls since Begin, for statistics.
+	DrawCalls int
+}
+
+// Blending for sprites with straight, not premultiplied, alpha.
+var stateSprites = glutil.State{
+	Blend: true,
+}
+
+// NewBatch creates a batch for at most size sprites per draw call.
+func NewBatch(size int) (*Batch, error) {
+	p, err := glutil.NewProgram(vertex_glsl, fragment_glsl)
+	if err != nil {
+		return nil, err
+	}
+	b := &Batch{
+		program: p,
+		sprites: make([]Sprite, 0, size),
+	}
+	gl.GenVertexArrays(1, &b.vao)
+	gl.BindVertexArray(b.vao)
+	b.stream = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, size*spriteSize)
+	for i := uint32(0); i < 4; i++ {
+		gl.EnableVertexAttribArray(i)
+		gl.VertexAttribDivisor(i, 1)
+	}
+	gl.BindVertexArray(0)
+	return b, nil
+}
+
+// Begin starts a batch, with a projection from the coordinates of the
+// sprites to clip space, e.g. glm.Ortho(0, width, height, 0, -1, 1) for
+// pixels from the top left corner.
+func (b *Batch) Begin(projection glm.Mat4) {
+	b.sprites = b.sprites[:0]
+	b.texture = 0
+	b.DrawCalls = 0
+	b.program.Use()
+	b.program.SetMat4("projection", projection)
+	b.program.SetInt("sprite", 0)
+}
+
+// Draw adds a sprite with a texture to the batch.
+func (b *Batch) Draw(texture uint32, s Sprite) {
+	if texture != b.texture || len(b.sprites) == cap(b.sprites) {
+		b.flush()
+		b.texture = texture
+	}
+	b.sprites = append(b.sprites, s)
+}
+
+// End draws the sprites that haven't been drawn yet.
+func (b *Batch) End() {
+	b.flush()
+}
+
+func (b *Batch) flush() {
+	n := len(b.sprites)
+	if n == 0 {
+		return
+	}
+	stateSprites.Apply()
+	b.program.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, b.texture)
+
+	gl.BindVertexArray(b.vao)
+	offset := b.stream.Write(unsafe.Pointer(&b.sprites[0]), n*spriteSize)
+	// the offset changes with each write to a persistent buffer
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.stream.Buffer)
+	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, int32(spriteSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 4, gl.FLOAT, false, int32(spriteSize), gl.PtrOffset(offset+16))
+	gl.VertexAttribPointer(2, 4, gl.FLOAT, false, int32(spriteSize), gl.PtrOffset(offset+32))
+	gl.VertexAttribPointer(3, 1, gl.FLOAT, false, int32(spriteSize), gl.PtrOffset(offset+48))
+	gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(n))
+	app.CountDrawCalls(1)
+	b.stream.Done()
+	gl.BindVertexArray(0)
+
+	b.sprites = b.sprites[:0]
+	b.DrawCalls++
+}
+
+// Delete frees the resources of the batch.
+func (b *Batch) Delete() {
+	b.stream.Delete()
+	gl.DeleteVertexArrays(1, &b.vao)
+	b.program.Delete()
+}
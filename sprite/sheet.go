@@ -0,0 +1,190 @@
+package sprite
+
+import (
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Sheet is a sprite sheet: an image with frames of equal size, numbered
+// from 0, row by row from the top left corner.
+//
+// The frames and animations are described in JSON, for example:
+//
+//	{
+//	    "image": "hero.png",
+//	    "frameWidth": 32,
+//	    "frameHeight": 48,
+//	    "animations": {
+//	        "walk": { "frames": [0, 1, 2, 3], "duration": 100, "loop": true },
+//	        "jump": { "frames": [4, 5, 6], "durations": [50, 200, 50] }
+//	    }
+//	}
+//
+// Durations are in milliseconds. An animation has either one duration for
+// all frames, or a duration for each frame. The image file is relative to
+// the description.
+type Sheet struct {
+	Image       string `json:"image"`
+	FrameWidth  int    `json:"frameWidth"`
+	FrameHeight int    `json:"frameHeight"`
+
+	Animations map[string]*Animation `json:"animations"`
+
+	Texture uint32 `json:"-"`
+	Width   int    `json:"-"` // of the image, in pixels
+	Height  int    `json:"-"`
+}
+
+// Animation is a sequence of frames of a sheet.
+type Animation struct {
+	Frames    []int     `json:"frames"`
+	Duration  float64   `json:"duration"`  // for each frame, in milliseconds
+	Durations []float64 `json:"durations"` // for the frames in turn, instead of Duration
+	Loop      bool      `json:"loop"`
+}
+
+// LoadSheet reads the description of a sprite sheet, and its image.
+func LoadSheet(filename string) (*Sheet, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	s, err := parseSheet(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if s.Image == "" {
+		return nil, fmt.Errorf("%s: no image", filename)
+	}
+	img, err := asset.LoadImageOrPlaceholder(filepath.Join(filepath.Dir(filename), s.Image))
+	if err != nil {
+		return nil, err
+	}
+	s.setImage(img)
+	return s, nil
+}
+
+// NewSheet creates a sprite sheet from an image, and a description in
+// JSON. The image field of the description is ignored.
+func NewSheet(img image.Image, description io.Reader) (*Sheet, error) {
+	s, err := parseSheet(description)
+	if err != nil {
+		return nil, err
+	}
+	s.setImage(img)
+	return s, nil
+}
+
+func parseSheet(r io.Reader) (*Sheet, error) {
+	s := &Sheet{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	if s.FrameWidth <= 0 || s.FrameHeight <= 0 {
+		return nil, fmt.Errorf("invalid frame size %dx%d", s.FrameWidth, s.FrameHeight)
+	}
+	for name, a := range s.Animations {
+		if len(a.Frames) == 0 {
+			return nil, fmt.Errorf("animation %q: no frames", name)
+		}
+		if a.Durations != nil && len(a.Durations) != len(a.Frames) {
+			return nil, fmt.Errorf("animation %q: %d frames, but %d durations", name, len(a.Frames), len(a.Durations))
+		}
+		if a.Durations == nil && a.Duration <= 0 {
+			return nil, fmt.Errorf("animation %q: no duration", name)
+		}
+	}
+	return s, nil
+}
+
+func (s *Sheet) setImage(img image.Image) {
+	rgba := asset.ToRGBA(img)
+	s.Width, s.Height = rgba.Rect.Dx(), rgba.Rect.Dy()
+	s.Texture = glutil.MakeTextureFromImage(rgba)
+}
+
+// Frames returns the number of frames in the sheet.
+func (s *Sheet) Frames() int {
+	return (s.Width / s.FrameWidth) * (s.Height / s.FrameHeight)
+}
+
+// Region returns the region of the texture with frame i, for
+// Sprite.Region.
+func (s *Sheet) Region(i int) glm.Vec4 {
+	columns := s.Width / s.FrameWidth
+	col, row := i%columns, i/columns
+	w, h := float32(s.Width), float32(s.Height)
+	return glm.Vec4{
+		float32(col*s.FrameWidth) / w,
+		float32(row*s.FrameHeight) / h,
+		float32((col+1)*s.FrameWidth) / w,
+		float32((row+1)*s.FrameHeight) / h,
+	}
+}
+
+// duration returns the duration of the i-th frame of the animation, in
+// seconds.
+func (a *Animation) duration(i int) float64 {
+	if a.Durations != nil {
+		return a.Durations[i] / 1000
+	}
+	return a.Duration / 1000
+}
+
+// Length returns the duration of one run through the animation, in seconds.
+func (a *Animation) Length() float64 {
+	var t float64
+	for i := range a.Frames {
+		t += a.duration(i)
+	}
+	return t
+}
+
+// Player plays an animation.
+type Player struct {
+	Animation *Animation
+	Time      float64 // seconds since the start
+}
+
+// Play starts an animation from the beginning.
+func (p *Player) Play(a *Animation) {
+	p.Animation = a
+	p.Time = 0
+}
+
+// Update advances the animation by dt seconds.
+func (p *Player) Update(dt float64) {
+	p.Time += dt
+}
+
+// Done reports whether an animation that doesn't loop has ended.
+func (p *Player) Done() bool {
+	return !p.Animation.Loop && p.Time >= p.Animation.Length()
+}
+
+// Frame returns the current frame of the sheet. An animation that doesn't
+// loop stays at its last frame.
+func (p *Player) Frame() int {
+	a := p.Animation
+	t := p.Time
+	if a.Loop {
+		t = math.Mod(t, a.Length())
+	}
+	for i, f := range a.Frames {
+		t -= a.duration(i)
+		if t < 0 {
+			return f
+		}
+	}
+	return a.Frames[len(a.Frames)-1]
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	//
+	// Point sprites: one vertex per tuft, the rasterizer makes a square of
+	// gl_PointSize pixels around it, always aligned with the screen.
+	//
+	point_vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+uniform float viewportHeight;
+
+// position of the base, and size
+layout(location = 0) in vec4 tuft;
+
+out vec2 texcoord;
+out float depth;
+
+void main()
+{
+    // the center of the square is half its size above the ground
+    vec4 p = view * vec4(tuft.xyz + vec3(0.0, 0.5 * tuft.w, 0.0), 1.0);
+    gl_Position = projection * p;
+    // the size in pixels of an object of size tuft.w at this distance
+    gl_PointSize = 0.5 * viewportHeight * projection[1][1] * tuft.w / -p.z;
+    texcoord = vec2(0.0); // not used, gl_PointCoord is
+    depth = -p.z;
+}
+`
+
+	//
+	// Billboards: four vertices per tuft, generated from gl_VertexID, and
+	// turned to face the camera in the vertex shader. Cylindrical
+	// billboards only turn around the vertical axis, so the grass stays
+	// upright when seen from above.
+	//
+	billboard_vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+uniform bool cylindrical;
+uniform float time;
+
+// per instance: position of the base, and size
+layout(location = 0) in vec4 tuft;
+
+out vec2 texcoord;
+out float depth;
+
+void main()
+{
+    // a triangle strip: (0, 0), (1, 0), (0, 1), (1, 1)
+    vec2 corner = vec2(gl_VertexID & 1, gl_VertexID >> 1);
+    texcoord = vec2(corner.x, 1.0 - corner.y);
+
+    // the axes of the camera in world space, the rows of the view matrix
+    vec3 right = vec3(view[0][0], view[1][0], view[2][0]);
+    vec3 up = vec3(view[0][1], view[1][1], view[2][1]);
+    if (cylindrical) {
+        right = normalize(vec3(right.x, 0.0, right.z));
+        up = vec3(0.0, 1.0, 0.0);
+    }
+    vec3 p = tuft.xyz + tuft.w * ((corner.x - 0.5) * right + corner.y * up);
+
+    // the wind moves the top, something point sprites can't do
+    p.x += corner.y * tuft.w * 0.15 * sin(2.0 * time + 0.5 * tuft.x + 0.3 * tuft.z);
+
+    vec4 v = view * vec4(p, 1.0);
+    gl_Position = projection * v;
+    depth = -v.z;
+}
+`
+
+	sprite_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D sprite;
+uniform bool pointSprite;
+uniform vec3 fogColor;
+uniform float fogDistance;
+
+in vec2 texcoord;
+in float depth;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 c = texture(sprite, pointSprite ? gl_PointCoord : texcoord);
+    // alpha test, so the tufts don't need to be sorted
+    if (c.a < 0.5) {
+        discard;
+    }
+    float fog = clamp(depth / fogDistance, 0.0, 1.0);
+    fragColor = vec4(mix(c.rgb, fogColor, fog * fog), 1.0);
+}
+`
+
+	//
+	// the ground
+	//
+	ground_vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+
+out float depth;
+
+void main()
+{
+    vec4 p = view * model * vec4(position, 1.0);
+    gl_Position = projection * p;
+    depth = -p.z;
+}
+`
+	ground_fragment_glsl = `
+#version 330 core
+
+uniform vec3 fogColor;
+uniform float fogDistance;
+
+in float depth;
+
+out vec4 fragColor;
+
+void main()
+{
+    float fog = clamp(depth / fogDistance, 0.0, 1.0);
+    fragColor = vec4(mix(vec3(0.2, 0.3, 0.1), fogColor, fog * fog), 1.0);
+}
+`
+)
+
+var (
+	count  = flag.Int("count", 20000, "number of grass tufts")
+	radius = flag.Float64("radius", 40, "radius of the field")
+)
+
+// Extra actions for this demo.
+const (
+	actionMode        = "mode"
+	actionCylindrical = "cylindrical"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	pointProgram     *glutil.Program
+	billboardProgram *glutil.Program
+	groundProgram    *glutil.Program
+	tufts            uint32 // buffer with a vec4 per tuft
+	points           uint32 // vertex array object for point sprites
+	billboards       uint32 // vertex array object for instanced billboards
+	ground           *mesh.VAO
+	sprite           uint32
+}
+
+// makeTufts returns the positions and sizes of the tufts, spread uniformly
+// over a disc.
+func makeTufts(n int, radius float64) []glm.Vec4 {
+	tufts := make([]glm.Vec4, n)
+	for i := range tufts {
+		r := radius * math.Sqrt(rand.Float64())
+		a := 2 * math.Pi * rand.Float64()
+		tufts[i] = glm.Vec4{
+			float32(r * math.Cos(a)),
+			0,
+			float32(r * math.Sin(a)),
+			float32(.6 + .6*rand.Float64()),
+		}
+	}
+	return tufts
+}
+
+// makeSprite returns a square image of a grass tuft, with the blades
+// growing from the bottom, transparent around them.
+func makeSprite(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	rnd := rand.New(rand.NewSource(1))
+	s := float64(size)
+	for blade := 0; blade < 12; blade++ {
+		base := s * (.3 + .4*rnd.Float64())
+		lean := s * (rnd.Float64() - .5) * .6
+		height := s * (.5 + .48*rnd.Float64())
+		width := s * .04
+		c := color.RGBA{uint8(40 + rnd.Intn(40)), uint8(110 + rnd.Intn(80)), uint8(20 + rnd.Intn(30)), 255}
+		steps := 4 * size
+		for i := 0; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			// curving further to the side near the tip
+			x := base + lean*t*t
+			y := s - 1 - height*t
+			w := width * (1 - t)
+			for px := int(x - w); px <= int(x+w); px++ {
+				img.SetRGBA(px, int(y), color.RGBA{
+					uint8(float64(c.R) * (.6 + .4*t)),
+					uint8(float64(c.G) * (.6 + .4*t)),
+					uint8(float64(c.B) * (.6 + .4*t)),
+					255,
+				})
+			}
+		}
+	}
+	return img
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		ground: mesh.Quad().Upload(),
+	}
+
+	var err error
+	r.pointProgram, err = glutil.NewProgram(point_vertex_glsl, sprite_fragment_glsl)
+	x(err)
+	r.billboardProgram, err = glutil.NewProgram(billboard_vertex_glsl, sprite_fragment_glsl)
+	x(err)
+	r.groundProgram, err = glutil.NewProgram(ground_vertex_glsl, ground_fragment_glsl)
+	x(err)
+
+	tufts := makeTufts(*count, *radius)
+	r.tufts = glutil.MakeBuffer(gl.ARRAY_BUFFER, tufts, gl.STATIC_DRAW)
+
+	// The same buffer, once with a vertex per tuft, once with an instance
+	// per tuft.
+	gl.GenVertexArrays(1, &r.points)
+	gl.BindVertexArray(r.points)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.tufts)
+	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, 16, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+
+	gl.GenVertexArrays(1, &r.billboards)
+	gl.BindVertexArray(r.billboards)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.tufts)
+	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, 16, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribDivisor(0, 1)
+	gl.BindVertexArray(0)
+
+	r.sprite = glutil.MakeTextureFromImage(makeSprite(64))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   = camera.NewFreeFly(glm.Vec3{0, 1.7, 0})
+
+	pointSprites bool
+	cylindrical  = true
+)
+
+var (
+	fogColor = glm.Vec3{.7, .8, .9}
+
+	state3D = glutil.State{
+		DepthTest: true,
+	}
+)
+
+func update(w *glfw.Window) {
+	cam.Update(w)
+	// walk, don't fly
+	if cam.Position[1] < .3 {
+		cam.Position[1] = .3
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	state3D.Apply()
+	gl.ClearColor(fogColor[0], fogColor[1], fogColor[2], 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	fogDistance := float32(*radius)
+	view := cam.View()
+	projection := glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 2*fogDistance)
+
+	p := r.groundProgram
+	p.Use()
+	s := float32(*radius) + 1
+	p.SetMat4("model", glm.Rotate(-math.Pi/2, glm.Vec3{1, 0, 0}).Mul(glm.Scale(s, s, 1)))
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("fogColor", fogColor)
+	p.SetFloat("fogDistance", fogDistance)
+	r.ground.Draw()
+
+	gl.BindTexture(gl.TEXTURE_2D, r.sprite)
+	if pointSprites {
+		p = r.pointProgram
+		p.Use()
+		p.SetFloat("viewportHeight", float32(height))
+	} else {
+		p = r.billboardProgram
+		p.Use()
+		p.SetBool("cylindrical", cylindrical)
+		p.SetFloat("time", float32(clock.Seconds()))
+	}
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetInt("sprite", 0)
+	p.SetBool("pointSprite", pointSprites)
+	p.SetVec3("fogColor", fogColor)
+	p.SetFloat("fogDistance", fogDistance)
+
+	if pointSprites {
+		gl.BindVertexArray(r.points)
+		gl.DrawArrays(gl.POINTS, 0, int32(*count))
+	} else {
+		gl.BindVertexArray(r.billboards)
+		gl.DrawArraysInstanced(gl.TRIANGLE_STRIP, 0, 4, int32(*count))
+	}
+	gl.BindVertexArray(0)
+}
+
+// printMode prints the technique in use.
+func printMode() {
+	switch {
+	case pointSprites:
+		app.Info("Point sprites")
+	case cylindrical:
+		app.Info("Billboards, cylindrical")
+	default:
+		app.Info("Billboards, spherical")
+	}
+}
+
+func main() {
+	app.Keys[actionMode] = []string{"m"}
+	app.Keys[actionCylindrical] = []string{"c"}
+	app.Flags(800, 600, "Sprites")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	gl.Enable(gl.PROGRAM_POINT_SIZE)
+
+	var sizes [2]float32
+	gl.GetFloatv(gl.POINT_SIZE_RANGE, &sizes[0])
+	app.Debugf("Point sizes from %g to %g pixels, larger sprites are clamped", sizes[0], sizes[1])
+	app.Info("Point sprites are clipped as soon as their center leaves the view, and can't sway in the wind")
+	app.Info("Drag with the mouse to look around, use W, A, S, D to move, shift to move faster")
+	app.Info("Press 'm' to switch between point sprites and billboards, 'c' for cylindrical or spherical billboards")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	printMode()
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionMode:
+		pointSprites = !pointSprites
+		printMode()
+	case actionCylindrical:
+		cylindrical = !cylindrical
+		printMode()
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/postfx"
+	"github.com/pebbe/gl/sprite"
+
+	"flag"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	sheetFile   = flag.String("sheet", "", "JSON description of a sprite sheet, instead of a generated one")
+	spriteCount = flag.Int("sprites", 300, "number of moving sprites")
+)
+
+// Description of the generated sheet: eight frames of 64x64 pixels in each
+// row.
+const defaultSheet = `{
+    "frameWidth": 64,
+    "frameHeight": 64,
+    "animations": {
+        "spin": { "frames": [0, 1, 2, 3, 4, 5, 6, 7], "duration": 80, "loop": true },
+        "pulse": {
+            "frames": [8, 9, 10, 11, 12, 13, 14, 15],
+            "durations": [400, 60, 60, 60, 60, 60, 60, 60],
+            "loop": true
+        },
+        "explode": { "frames": [16, 17, 18, 19, 20, 21, 22, 23], "duration": 50 }
+    }
+}`
+
+//
+// The generated sprite sheet
+//
+
+const frameSize = 64
+
+// makeSheet returns an image with three rows of eight frames: a spinning
+// coin, a pulsing gem, and an explosion.
+func makeSheet() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 8*frameSize, 3*frameSize))
+	for i := 0; i < 8; i++ {
+		f := float64(i) / 8
+
+		// a coin, seen from the side as it turns
+		w := math.Abs(math.Cos(math.Pi * f))
+		drawFrame(img, i, 0, func(x, y float64) (color.RGBA, float64) {
+			d := math.Hypot(x/math.Max(w, .08), y) - .8
+			c := color.RGBA{240, 190, 40, 255}
+			if d > -.15 {
+				c = color.RGBA{200, 140, 20, 255}
+			}
+			return c, d
+		})
+
+		// a gem that grows, and shrinks back
+		s := .6 + .3*math.Sin(math.Pi*f)
+		drawFrame(img, i, 1, func(x, y float64) (color.RGBA, float64) {
+			d := (math.Abs(x) + math.Abs(y)) - s
+			b := uint8(255 * (.6 + .4*(1-math.Abs(x+y)/s)))
+			return color.RGBA{60, b, 220, 255}, d
+		})
+
+		// a ring that expands and fades
+		r := .2 + .75*f
+		drawFrame(img, i, 2, func(x, y float64) (color.RGBA, float64) {
+			d := math.Abs(math.Hypot(x, y)-r) - .15*(1-f) - .02
+			a := uint8(255 * (1 - f*f))
+			return color.RGBA{255, uint8(220 - 180*f), 40, a}, d
+		})
+	}
+	return img
+}
+
+// drawFrame draws a shape in the frame at column col and row row. The
+// function shape gets coordinates from -1 to 1, and returns the color and
+// the signed distance to the edge, negative inside.
+func drawFrame(img *image.RGBA, col, row int, shape func(x, y float64) (color.RGBA, float64)) {
+	for j := 0; j < frameSize; j++ {
+		for i := 0; i < frameSize; i++ {
+			x := 2*(float64(i)+.5)/frameSize - 1
+			y := 2*(float64(j)+.5)/frameSize - 1
+			c, d := shape(x, y)
+			// about one pixel of anti-aliasing
+			coverage := math.Max(0, math.Min(1, .5-d*frameSize/2))
+			c.A = uint8(float64(c.A) * coverage)
+			img.SetRGBA(col*frameSize+i, row*frameSize+j, c)
+		}
+	}
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	batch *sprite.Batch
+	sheet *sprite.Sheet
+
+	effects *postfx.Chain // nil without -crt
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.batch, err = sprite.NewBatch(1000)
+	x(err)
+
+	if *sheetFile != "" {
+		r.sheet, err = sprite.LoadSheet(*sheetFile)
+	} else {
+		r.sheet, err = sprite.NewSheet(makeSheet(), strings.NewReader(defaultSheet))
+	}
+	x(err)
+
+	r.effects, err = postfx.OptionalCRT()
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+type tActor struct {
+	player   sprite.Player
+	position glm.Vec2
+	velocity glm.Vec2 // pixels per second
+	size     float32
+}
+
+var (
+	clock = app.NewClock()
+
+	actors  []*tActor
+	effects []*tActor // animations that don't loop, removed when done
+
+	// names of the animations, sorted, for a reproducible choice
+	loops, once []string
+)
+
+func makeActors(r *gResources, width, height int) {
+	for name, a := range r.sheet.Animations {
+		if a.Loop {
+			loops = append(loops, name)
+		} else {
+			once = append(once, name)
+		}
+	}
+	sort.Strings(loops)
+	sort.Strings(once)
+	if len(loops) == 0 {
+		return
+	}
+
+	actors = make([]*tActor, *spriteCount)
+	for i := range actors {
+		a := &tActor{
+			position: glm.Vec2{rand.Float32() * float32(width), rand.Float32() * float32(height)},
+			velocity: glm.Vec2{rand.Float32()*200 - 100, rand.Float32()*200 - 100},
+			size:     float32(r.sheet.FrameHeight) * (.5 + rand.Float32()/2),
+		}
+		a.player.Play(r.sheet.Animations[loops[rand.Intn(len(loops))]])
+		// not all in step
+		a.player.Update(rand.Float64() * a.player.Animation.Length())
+		actors[i] = a
+	}
+}
+
+// addEffect plays a random animation that doesn't loop at a position.
+func addEffect(r *gResources, x, y float64) {
+	if len(once) == 0 {
+		return
+	}
+	e := &tActor{
+		position: glm.Vec2{float32(x), float32(y)},
+		size:     2 * float32(r.sheet.FrameHeight),
+	}
+	e.player.Play(r.sheet.Animations[once[rand.Intn(len(once))]])
+	effects = append(effects, e)
+}
+
+func update(w *glfw.Window) {
+	dt := clock.Delta()
+	width, height := w.GetSize()
+	for _, a := range actors {
+		a.player.Update(dt)
+		p := a.position.Add(a.velocity.Mul(float32(dt)))
+		// bounce off the edges of the window
+		if p[0] < 0 && a.velocity[0] < 0 || p[0] > float32(width) && a.velocity[0] > 0 {
+			a.velocity[0] = -a.velocity[0]
+		}
+		if p[1] < 0 && a.velocity[1] < 0 || p[1] > float32(height) && a.velocity[1] > 0 {
+			a.velocity[1] = -a.velocity[1]
+		}
+		a.position = p
+	}
+
+	n := 0
+	for _, e := range effects {
+		e.player.Update(dt)
+		if !e.player.Done() {
+			effects[n] = e
+			n++
+		}
+	}
+	effects = effects[:n]
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	x(r.effects.Begin(int32(width), int32(height)))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.15, .15, .2, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// in screen coordinates, like the cursor, from the top left corner
+	sw, sh := w.GetSize()
+	b := r.batch
+	b.Begin(glm.Ortho(0, float32(sw), float32(sh), 0, -1, 1))
+	for _, list := range [][]*tActor{actors, effects} {
+		for _, a := range list {
+			b.Draw(r.sheet.Texture, sprite.Sprite{
+				Position: a.position,
+				Size:     glm.Vec2{a.size * float32(r.sheet.FrameWidth) / float32(r.sheet.FrameHeight), a.size},
+				Region:   r.sheet.Region(a.player.Frame()),
+				Color:    glm.Vec4{1, 1, 1, 1},
+			})
+		}
+	}
+	b.End()
+
+	x(r.effects.End(nil))
+}
+
+func main() {
+	app.Flags(800, 600, "Sprite sheet")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	makeActors(r, app.Width, app.Height)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button == glfw.MouseButtonLeft && action == glfw.Press {
+			cx, cy := w.GetCursorPos()
+			addEffect(r, cx, cy)
+		}
+	})
+
+	app.Infof("%d frames, animations that loop: %s, once: %s",
+		r.sheet.Frames(), strings.Join(loops, " "), strings.Join(once, " "))
+	app.Info("Click to play an animation that doesn't loop")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
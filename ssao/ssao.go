@@ -0,0 +1,475 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/mesh"
+
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+const (
+	kernelSize = 32
+	noiseSize  = 4
+)
+
+var (
+	//
+	// geometry pass: positions and normals in view space
+	//
+	geometry_vertex_glsl = `
+#version 330 core
+
+uniform mat4 modelView;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 viewPosition;
+out vec3 viewNormal;
+
+void main()
+{
+    vec4 p = modelView * vec4(position, 1.0);
+    gl_Position = projection * p;
+    viewPosition = p.xyz;
+    viewNormal = normalMatrix * normal;
+}
+`
+	geometry_fragment_glsl = `
+#version 330 core
+
+uniform vec3 albedo;
+
+in vec3 viewPosition;
+in vec3 viewNormal;
+
+layout(location = 0) out vec4 gPosition;
+layout(location = 1) out vec4 gNormal;
+layout(location = 2) out vec4 gAlbedo;
+
+void main()
+{
+    gPosition = vec4(viewPosition, 1.0);
+    gNormal = vec4(normalize(viewNormal), 0.0);
+    gAlbedo = vec4(albedo, 1.0);
+}
+`
+
+	//
+	// ambient occlusion: how many points in a hemisphere around each pixel
+	// are behind the geometry
+	//
+	ssao_fragment_glsl = fmt.Sprintf(`
+#version 330 core
+
+uniform sampler2D gPosition;
+uniform sampler2D gNormal;
+uniform sampler2D noise;
+
+uniform vec3 samples[%d];
+uniform mat4 projection;
+uniform vec2 noiseScale;
+uniform float radius;
+
+in vec2 uv;
+
+out float occlusion;
+
+const float bias = 0.025;
+
+void main()
+{
+    vec4 p = texture(gPosition, uv);
+    if (p.w == 0.0) {
+        occlusion = 1.0;
+        return;
+    }
+    vec3 n = normalize(texture(gNormal, uv).xyz);
+
+    // a basis around the normal, randomly rotated with the tiled noise
+    vec3 random = normalize(texture(noise, uv * noiseScale).xyz);
+    vec3 t = normalize(random - n * dot(random, n));
+    mat3 tbn = mat3(t, cross(n, t), n);
+
+    float occluded = 0.0;
+    for (int i = 0; i < %d; i++) {
+        vec3 s = p.xyz + radius * (tbn * samples[i]);
+        vec4 offset = projection * vec4(s, 1.0);
+        vec2 suv = offset.xy / offset.w * 0.5 + 0.5;
+        float depth = texture(gPosition, suv).z;
+        // ignore geometry far in front of this point
+        float range = smoothstep(0.0, 1.0, radius / abs(p.z - depth));
+        occluded += (depth >= s.z + bias ? 1.0 : 0.0) * range;
+    }
+    occlusion = 1.0 - occluded / %d.0;
+}
+`, kernelSize, kernelSize, kernelSize)
+
+	// a box blur over the size of the noise texture, to remove the noise
+	blur_fragment_glsl = fmt.Sprintf(`
+#version 330 core
+
+uniform sampler2D source;
+
+in vec2 uv;
+
+out float result;
+
+void main()
+{
+    vec2 texel = 1.0 / vec2(textureSize(source, 0));
+    float sum = 0.0;
+    for (int x = -%d; x < %d; x++) {
+        for (int y = -%d; y < %d; y++) {
+            sum += texture(source, uv + vec2(x, y) * texel).r;
+        }
+    }
+    result = sum / %d.0;
+}
+`, noiseSize/2, noiseSize/2, noiseSize/2, noiseSize/2, noiseSize*noiseSize)
+
+	//
+	// lighting, with the ambient light reduced by the occlusion
+	//
+	lighting_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D gPosition;
+uniform sampler2D gNormal;
+uniform sampler2D gAlbedo;
+uniform sampler2D ao;
+
+uniform vec3 lightDirection; // in view space
+uniform bool useAO;
+uniform bool showAO;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    float occlusion = useAO ? texture(ao, uv).r : 1.0;
+    if (showAO) {
+        fragColor = vec4(vec3(occlusion), 1.0);
+        return;
+    }
+    if (texture(gPosition, uv).w == 0.0) {
+        fragColor = vec4(0.5, 0.6, 0.7, 1.0);
+        return;
+    }
+    vec3 n = texture(gNormal, uv).xyz;
+    vec3 albedo = texture(gAlbedo, uv).rgb;
+    float diffuse = max(dot(n, lightDirection), 0.0);
+    fragColor = vec4((0.6 * occlusion + 0.4 * diffuse) * albedo, 1.0);
+}
+`
+)
+
+// Extra actions for this demo.
+const (
+	actionAO     = "ao"
+	actionShowAO = "showao"
+)
+
+//
+// Global data used by render
+//
+
+type tObject struct {
+	vao    *mesh.VAO
+	model  glm.Mat4
+	albedo glm.Vec3
+}
+
+type gResources struct {
+	geometry *glutil.Program
+	ssao     *glutil.Program
+	blur     *glutil.Program
+	lighting *glutil.Program
+
+	gbuffer *glutil.Framebuffer
+	ao      *glutil.Framebuffer // raw occlusion
+	aoBlur  *glutil.Framebuffer // blurred occlusion
+
+	noise   uint32
+	kernel  []glm.Vec3
+	objects []tObject
+}
+
+// makeKernel returns points in a unit hemisphere around the z axis, more of
+// them close to the center.
+func makeKernel() []glm.Vec3 {
+	kernel := make([]glm.Vec3, kernelSize)
+	for i := range kernel {
+		v := glm.Vec3{2*rand.Float32() - 1, 2*rand.Float32() - 1, rand.Float32()}.Normalize()
+		scale := float32(i) / kernelSize
+		scale = .1 + .9*scale*scale
+		kernel[i] = v.Mul(rand.Float32() * scale)
+	}
+	return kernel
+}
+
+// makeNoise returns a small texture of random rotations around the z axis,
+// to be tiled over the screen.
+func makeNoise() uint32 {
+	data := make([]float32, 0, noiseSize*noiseSize*3)
+	for i := 0; i < noiseSize*noiseSize; i++ {
+		data = append(data, 2*rand.Float32()-1, 2*rand.Float32()-1, 0)
+	}
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGB16F, noiseSize, noiseSize, 0, gl.RGB, gl.FLOAT, gl.Ptr(data))
+	return texture
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		kernel: makeKernel(),
+		noise:  makeNoise(),
+	}
+
+	var err error
+	r.geometry, err = glutil.NewProgram(geometry_vertex_glsl, geometry_fragment_glsl)
+	x(err)
+	r.ssao, err = glutil.NewProgram(glutil.FullscreenVertexShader, ssao_fragment_glsl)
+	x(err)
+	r.blur, err = glutil.NewProgram(glutil.FullscreenVertexShader, blur_fragment_glsl)
+	x(err)
+	r.lighting, err = glutil.NewProgram(glutil.FullscreenVertexShader, lighting_fragment_glsl)
+	x(err)
+
+	// a floor, with boxes and balls close together, to have lots of corners
+	cube := mesh.Cube().Upload()
+	sphere := mesh.Sphere(32, 16).Upload()
+	r.objects = append(r.objects, tObject{
+		vao:    cube,
+		model:  glm.Translate(0, -1.5, 0).Mul(glm.Scale(6, .5, 6)),
+		albedo: glm.Vec3{.9, .9, .9},
+	})
+	for i := 0; i < 20; i++ {
+		obj := tObject{
+			vao:    cube,
+			albedo: glm.Vec3{.8, .7, .6},
+		}
+		px := 6*rand.Float32() - 3
+		pz := 6*rand.Float32() - 3
+		s := .2 + .4*rand.Float32()
+		if i%2 == 1 {
+			obj.vao = sphere
+			obj.albedo = glm.Vec3{.6, .7, .8}
+		}
+		obj.model = glm.Translate(px, -1+s, pz).Mul(glm.Rotate(2*math.Pi*rand.Float32(), glm.Vec3{0, 1, 0})).Mul(glm.Scale(s, s, s))
+		r.objects = append(r.objects, obj)
+	}
+
+	return r
+}
+
+// resize (re)creates the framebuffers if the size of the window changed.
+func (r *gResources) resize(width, height int32) {
+	if r.gbuffer != nil && r.gbuffer.Width == width && r.gbuffer.Height == height {
+		return
+	}
+	for _, f := range []*glutil.Framebuffer{r.gbuffer, r.ao, r.aoBlur} {
+		if f != nil {
+			f.Delete()
+		}
+	}
+	var err error
+	r.gbuffer, err = glutil.NewMultiFramebuffer(width, height,
+		glutil.FormatRGBA16F, // position
+		glutil.FormatRGBA16F, // normal
+		glutil.FormatRGBA8)   // albedo
+	x(err)
+	r.ao, err = glutil.NewMultiFramebuffer(width, height, glutil.FormatR8)
+	x(err)
+	r.aoBlur, err = glutil.NewMultiFramebuffer(width, height, glutil.FormatR8)
+	x(err)
+}
+
+//
+// Update and render
+//
+
+var (
+	clock  = app.NewClock()
+	zoom   = input.NewZoom(.5, 3)
+	useAO  = true
+	showAO = false
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func bindTextures(p *glutil.Program, textures map[string]uint32) {
+	unit := int32(0)
+	for name, texture := range textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+		p.SetInt(name, unit)
+		unit++
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	r.resize(int32(width), int32(height))
+
+	a := clock.Seconds() / 8
+	d := 8 / float32(zoom.Scale())
+	eye := glm.Vec3{d * float32(math.Sin(a)), d / 2, d * float32(math.Cos(a))}
+	view := glm.LookAt(eye, glm.Vec3{}, glm.Vec3{0, 1, 0})
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 50)
+
+	// geometry pass
+	r.gbuffer.Bind()
+	state3D.Apply()
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	p := r.geometry
+	p.Use()
+	p.SetMat4("projection", projection)
+	for _, obj := range r.objects {
+		modelView := view.Mul(obj.model)
+		p.SetMat4("modelView", modelView)
+		p.SetMat3("normalMatrix", modelView.NormalMatrix())
+		p.SetVec3("albedo", obj.albedo)
+		obj.vao.Draw()
+	}
+
+	glutil.State{}.Apply()
+	gPosition, gNormal, gAlbedo := r.gbuffer.Textures[0], r.gbuffer.Textures[1], r.gbuffer.Textures[2]
+
+	// ambient occlusion
+	r.ao.Bind()
+	p = r.ssao
+	p.Use()
+	bindTextures(p, map[string]uint32{"gPosition": gPosition, "gNormal": gNormal, "noise": r.noise})
+	for i, s := range r.kernel {
+		p.SetVec3(fmt.Sprintf("samples[%d]", i), s)
+	}
+	p.SetMat4("projection", projection)
+	p.SetVec2("noiseScale", glm.Vec2{float32(width) / noiseSize, float32(height) / noiseSize})
+	p.SetFloat("radius", .5)
+	glutil.DrawFullscreen()
+
+	r.aoBlur.Bind()
+	p = r.blur
+	p.Use()
+	bindTextures(p, map[string]uint32{"source": r.ao.Texture})
+	glutil.DrawFullscreen()
+	r.aoBlur.Unbind()
+
+	// lighting
+	gl.Viewport(0, 0, int32(width), int32(height))
+	p = r.lighting
+	p.Use()
+	bindTextures(p, map[string]uint32{"gPosition": gPosition, "gNormal": gNormal, "gAlbedo": gAlbedo, "ao": r.aoBlur.Texture})
+	light := view.MulVec(glm.Vec3{.3, 1, .5}.Vec4(0)).Vec3()
+	p.SetVec3("lightDirection", light.Normalize())
+	p.SetBool("useAO", useAO)
+	p.SetBool("showAO", showAO)
+	glutil.DrawFullscreen()
+}
+
+func main() {
+	app.Keys[actionAO] = []string{"o"}
+	app.Keys[actionShowAO] = []string{"v"}
+	app.Flags(800, 600, "SSAO")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetScrollCallback(zoom.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Press 'o' to toggle ambient occlusion, 'v' to show only the occlusion")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionAO:
+		useAO = !useAO
+		if useAO {
+			app.Info("Ambient occlusion: on")
+		} else {
+			app.Info("Ambient occlusion: off")
+		}
+	case actionShowAO:
+		showAO = !showAO
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
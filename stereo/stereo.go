@@ -0,0 +1,244 @@
+// Package stereo renders a scene twice, once for each eye, and combines the
+// images as a red/cyan anaglyph, or side by side.
+//
+// Demos that use it accept the flag -stereo, with the value anaglyph or
+// sbs.
+package stereo
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"fmt"
+)
+
+const (
+	// red from the left eye, green and blue from the right eye
+	anaglyph_glsl = `
+#version 330 core
+
+uniform sampler2D left;
+uniform sampler2D right;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(texture(left, uv).r, texture(right, uv).gb, 1.0);
+}
+`
+
+	copy_glsl = `
+#version 330 core
+
+uniform sampler2D source;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = texture(source, uv);
+}
+`
+)
+
+// Mode is the way the images for the eyes are combined.
+type Mode int
+
+const (
+	Off        Mode = iota // no stereo, the scene is drawn once
+	Anaglyph               // red for the left eye, cyan for the right eye
+	SideBySide             // left eye in the left half of the window
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Anaglyph:
+		return "anaglyph"
+	case SideBySide:
+		return "sbs"
+	}
+	return "off"
+}
+
+var modeFlag = flag.String("stereo", "", "stereo mode: anaglyph or sbs")
+
+// FlagMode returns the mode given with the flag -stereo. Call it after
+// flag.Parse or app.Flags.
+func FlagMode() (Mode, error) {
+	switch *modeFlag {
+	case "", "off":
+		return Off, nil
+	case "anaglyph":
+		return Anaglyph, nil
+	case "sbs":
+		return SideBySide, nil
+	}
+	return Off, fmt.Errorf("unknown stereo mode %q", *modeFlag)
+}
+
+// Renderer draws a scene for both eyes, each into a framebuffer of its
+// own, and combines the results in the window.
+type Renderer struct {
+	Mode Mode
+
+	// Distance to the plane that appears at the depth of the screen, in
+	// units of the scene. Objects closer than this appear in front of
+	// the screen.
+	Convergence float32
+
+	// Distance between the eyes, in units of the scene. If zero, it is a
+	// thirtieth of Convergence.
+	Separation float32
+
+	// Exchange the images of the eyes, for viewing side by side
+	// cross-eyed.
+	SwapEyes bool
+
+	eyes     [2]*glutil.Framebuffer
+	anaglyph *glutil.Program
+	copy     *glutil.Program
+}
+
+// New returns a renderer with Convergence 5.
+func New(mode Mode) (*Renderer, error) {
+	s := &Renderer{
+		Mode:        mode,
+		Convergence: 5,
+	}
+	var err error
+	s.anaglyph, err = glutil.NewProgram(glutil.FullscreenVertexShader, anaglyph_glsl)
+	if err != nil {
+		return nil, err
+	}
+	s.copy, err = glutil.NewProgram(glutil.FullscreenVertexShader, copy_glsl)
+	if err != nil {
+		s.anaglyph.Delete()
+		return nil, err
+	}
+	return s, nil
+}
+
+// allocate creates the framebuffers for images of width by height.
+func (s *Renderer) allocate(width, height int32) error {
+	if s.eyes[0] != nil && s.eyes[0].Width == width && s.eyes[0].Height == height {
+		return nil
+	}
+	s.deleteEyes()
+	for i := range s.eyes {
+		f, err := glutil.NewFramebuffer(width, height)
+		if err != nil {
+			return err
+		}
+		s.eyes[i] = f
+	}
+	return nil
+}
+
+// Render draws the scene into the window of width by height pixels. The
+// camera is given by view, and by the vertical field of view fovy in
+// radians, and the near and far planes.
+//
+// The function draw is called once for each eye, or once if Mode is Off,
+// with the matrices to use. The framebuffer and viewport are set before
+// the call, so draw should clear, and set its pipeline state, but it
+// should not change the viewport.
+func (s *Renderer) Render(width, height int32, view glm.Mat4, fovy, near, far float32, draw func(view, projection glm.Mat4)) error {
+	if s.Mode == Off {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		gl.Viewport(0, 0, width, height)
+		draw(view, glm.Perspective(fovy, float32(width)/float32(height), near, far))
+		return nil
+	}
+
+	eyeWidth := width
+	if s.Mode == SideBySide {
+		eyeWidth = width / 2
+	}
+	if err := s.allocate(eyeWidth, height); err != nil {
+		return err
+	}
+
+	separation := s.Separation
+	if separation == 0 {
+		separation = s.Convergence / 30
+	}
+	projection := glm.Perspective(fovy, float32(eyeWidth)/float32(height), near, far)
+	for i, f := range s.eyes {
+		// left eye to the left, right eye to the right
+		offset := separation / 2
+		if i == 0 {
+			offset = -offset
+		}
+		f.Bind()
+		draw(glm.Translate(-offset, 0, 0).Mul(view), offCenter(projection, offset, s.Convergence))
+	}
+
+	left, right := s.eyes[0].Texture, s.eyes[1].Texture
+	if s.SwapEyes {
+		left, right = right, left
+	}
+
+	glutil.State{}.Apply()
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, width, height)
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	if s.Mode == Anaglyph {
+		p := s.anaglyph
+		p.Use()
+		bindTexture(p, "left", 0, left)
+		bindTexture(p, "right", 1, right)
+		glutil.DrawFullscreen()
+	} else {
+		p := s.copy
+		p.Use()
+		gl.Viewport(0, 0, eyeWidth, height)
+		bindTexture(p, "source", 0, left)
+		glutil.DrawFullscreen()
+		gl.Viewport(eyeWidth, 0, eyeWidth, height)
+		bindTexture(p, "source", 0, right)
+		glutil.DrawFullscreen()
+		gl.Viewport(0, 0, width, height)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	return nil
+}
+
+// offCenter shifts a perspective projection for an eye that is offset to
+// the right of the camera, so that the views of both eyes coincide at
+// distance convergence, without turning the eyes inwards.
+func offCenter(projection glm.Mat4, offset, convergence float32) glm.Mat4 {
+	projection[8] -= projection[0] * offset / convergence
+	return projection
+}
+
+// bindTexture binds a texture to a texture unit, and sets the sampler
+// uniform of program p, which must be in use.
+func bindTexture(p *glutil.Program, name string, unit int32, texture uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	p.SetInt(name, unit)
+}
+
+func (s *Renderer) deleteEyes() {
+	for i, f := range s.eyes {
+		if f != nil {
+			f.Delete()
+			s.eyes[i] = nil
+		}
+	}
+}
+
+// Delete frees the resources of the renderer.
+func (s *Renderer) Delete() {
+	s.deleteEyes()
+	s.anaglyph.Delete()
+	s.copy.Delete()
+}
@@ -0,0 +1,426 @@
+package main
+
+// A swarm of particles, simulated in a goroutine of its own, while the main
+// goroutine draws it. This is how to structure a concurrent program around
+// OpenGL, which can only be used from the thread that owns the context:
+//
+//   - The main goroutine, locked to the main thread, owns the window and
+//     the context, and is the only one to call glfw and gl.
+//
+//   - The simulation goroutine owns the state of the simulation. No other
+//     goroutine reads or writes it, so it needs no locks.
+//
+//   - After each step, the simulation sends a snapshot of the state to the
+//     main goroutine over a channel. A snapshot is never changed after it is
+//     sent, so the main goroutine can draw it while the next step runs.
+//
+//   - Input goes the other way, as functions that the simulation goroutine
+//     runs on its state, between two steps.
+//
+// The channel of snapshots holds only the latest one. If drawing is slower
+// than the simulation, old snapshots are dropped, and if the simulation is
+// slower, the same snapshot is drawn again: neither waits for the other.
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform vec2 scale;
+
+layout(location = 0) in vec2 position;
+layout(location = 1) in float speed;
+
+out vec3 color;
+
+void main()
+{
+    color = mix(vec3(0.2, 0.4, 1.0), vec3(1.0, 0.6, 0.2), clamp(speed, 0.0, 1.0));
+    gl_PointSize = 2.0;
+    gl_Position = vec4(position * scale, 0.0, 1.0);
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+in vec3 color;
+
+out vec4 fragColor;
+
+void main()
+{
+    fragColor = vec4(color * 0.5, 1.0);
+}
+` + "\x00"
+)
+
+var (
+	particleCount = flag.Int("particles", 20000, "number of particles")
+	rate          = flag.Float64("rate", 120, "simulation steps per second")
+)
+
+// Extra action for this demo.
+const actionLoad = "load"
+
+//
+// The simulation, run by its own goroutine
+//
+
+// Data of a particle, both for the simulation and for the shader.
+type tParticle struct {
+	Position glm.Vec2
+	Speed    float32
+}
+
+const particleSize = int(unsafe.Sizeof(tParticle{}))
+
+// A snapshot is the state of the simulation after a step. It is never
+// changed after it is sent.
+type tSnapshot struct {
+	Particles []tParticle
+}
+
+// The state of the simulation. Only the simulation goroutine uses it.
+type tSimulation struct {
+	time       float64
+	particles  []tParticle
+	velocities []glm.Vec2
+
+	attractor glm.Vec2
+	follow    bool // attract to the mouse, rather than a point on a curve
+
+	paused   bool
+	stepOnce bool // do one step while paused
+	speed    float64
+	slow     bool // add work to each step, to show that drawing goes on
+}
+
+// A command is run by the simulation goroutine on its state, between steps.
+type tCommand func(s *tSimulation)
+
+func newSimulation(n int) *tSimulation {
+	s := &tSimulation{
+		particles:  make([]tParticle, n),
+		velocities: make([]glm.Vec2, n),
+		speed:      1,
+	}
+	for i := range s.particles {
+		a := 2 * math.Pi * rand.Float64()
+		d := .2 + .6*rand.Float64()
+		p := glm.Vec2{float32(d * math.Cos(a)), float32(d * math.Sin(a))}
+		s.particles[i].Position = p
+		// around the center, counterclockwise
+		s.velocities[i] = glm.Vec2{-p[1], p[0]}.Mul(.8)
+	}
+	return s
+}
+
+// advance moves the particles by dt seconds.
+func (s *tSimulation) advance(dt float32) {
+	s.time += float64(dt)
+	if !s.follow {
+		s.attractor = glm.Vec2{float32(.5 * math.Cos(.7*s.time)), float32(.5 * math.Sin(1.1*s.time))}
+	}
+	for i := range s.particles {
+		p := &s.particles[i]
+		v := s.velocities[i]
+		d := s.attractor.Sub(p.Position)
+		r2 := d[0]*d[0] + d[1]*d[1] + .01
+		v = v.Add(d.Mul(.3 * dt / r2))
+		v = v.Mul(1 - .2*dt)
+		s.velocities[i] = v
+		p.Position = p.Position.Add(v.Mul(dt))
+		p.Speed = float32(math.Hypot(float64(v[0]), float64(v[1])))
+	}
+	if s.slow {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// snapshot copies the state for drawing.
+func (s *tSimulation) snapshot() *tSnapshot {
+	// a new slice each time, so the one that is being drawn is never
+	// overwritten
+	particles := make([]tParticle, len(s.particles))
+	copy(particles, s.particles)
+	return &tSnapshot{Particles: particles}
+}
+
+// run steps the simulation at the rate, and sends a snapshot after each
+// step, until done is closed.
+func (s *tSimulation) run(commands <-chan tCommand, snapshots chan *tSnapshot, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case cmd := <-commands:
+			cmd(s)
+		case <-ticker.C:
+			if s.paused && !s.stepOnce {
+				continue
+			}
+			s.stepOnce = false
+			s.advance(float32(s.speed / *rate))
+			send(snapshots, s.snapshot())
+		}
+	}
+}
+
+// send puts a snapshot in the channel, replacing the one that is there if
+// it wasn't taken yet. This needs a buffer of one, and a single sender.
+func send(snapshots chan *tSnapshot, snapshot *tSnapshot) {
+	select {
+	case snapshots <- snapshot:
+		return
+	default:
+	}
+	select {
+	case <-snapshots:
+	default:
+	}
+	snapshots <- snapshot
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program   *glutil.Program
+	vao       uint32
+	particles *glutil.StreamBuffer
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.BindVertexArray(r.vao)
+	r.particles = glutil.NewStreamBuffer(gl.ARRAY_BUFFER, *particleCount*particleSize)
+	gl.EnableVertexAttribArray(0)
+	gl.EnableVertexAttribArray(1)
+	gl.BindVertexArray(0)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var stateSwarm = glutil.State{
+	Blend:    true,
+	BlendSrc: gl.ONE,
+	BlendDst: gl.ONE,
+}
+
+func render(w *glfw.Window, r *gResources, snapshot *tSnapshot) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(0, 0, 0, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	if snapshot == nil {
+		return
+	}
+
+	stateSwarm.Apply()
+	p := r.program
+	p.Use()
+	p.SetVec2("scale", aspectScale(w))
+
+	gl.BindVertexArray(r.vao)
+	offset := r.particles.Write(unsafe.Pointer(&snapshot.Particles[0]), len(snapshot.Particles)*particleSize)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.particles.Buffer)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, int32(particleSize), gl.PtrOffset(offset))
+	gl.VertexAttribPointer(1, 1, gl.FLOAT, false, int32(particleSize), gl.PtrOffset(offset+8))
+	gl.DrawArrays(gl.POINTS, 0, int32(len(snapshot.Particles)))
+	r.particles.Done()
+	gl.BindVertexArray(0)
+}
+
+// aspectScale maps the square from -1 to 1 of the simulation into the
+// window, keeping it square.
+func aspectScale(w *glfw.Window) glm.Vec2 {
+	width, height := w.GetSize()
+	if width > height {
+		return glm.Vec2{float32(height) / float32(width), 1}
+	}
+	return glm.Vec2{1, float32(width) / float32(height)}
+}
+
+// cursorPosition is the position of the mouse in the simulation.
+func cursorPosition(w *glfw.Window) glm.Vec2 {
+	xpos, ypos := w.GetCursorPos()
+	width, height := w.GetSize()
+	scale := aspectScale(w)
+	return glm.Vec2{
+		(float32(xpos)/float32(width)*2 - 1) / scale[0],
+		(1 - float32(ypos)/float32(height)*2) / scale[1],
+	}
+}
+
+func main() {
+	app.Keys[actionLoad] = []string{"l"}
+	app.Flags(800, 800, "Simulation in a goroutine")
+
+	if *particleCount < 1 {
+		app.Fatal("-particles must be at least 1")
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	gl.Enable(gl.PROGRAM_POINT_SIZE)
+
+	// Start the simulation. Nothing but the channels is shared with it.
+	commands := make(chan tCommand, 16)
+	snapshots := make(chan *tSnapshot, 1)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		newSimulation(*particleCount).run(commands, snapshots, done)
+	}()
+
+	app.OnAction(w, func(w *glfw.Window, action string) {
+		onAction(w, commands, action)
+	})
+	pressed := false
+	w.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
+		if button != glfw.MouseButtonLeft {
+			return
+		}
+		pressed = action != glfw.Release
+		// the values for the command are computed here, on the main
+		// thread, as the window may only be used there
+		follow, p := pressed, cursorPosition(w)
+		commands <- func(s *tSimulation) {
+			s.follow = follow
+			s.attractor = p
+		}
+	})
+	w.SetCursorPosCallback(func(w *glfw.Window, xpos, ypos float64) {
+		if !pressed {
+			return
+		}
+		p := cursorPosition(w)
+		// if the simulation is behind, skip this position, as the next one
+		// replaces it anyway
+		select {
+		case commands <- func(s *tSimulation) { s.attractor = p }:
+		default:
+		}
+	})
+
+	app.Info("Press the mouse button to attract the particles to the mouse")
+	app.Info("Press 'l' to toggle a heavy load on the simulation, which doesn't slow down drawing")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	var snapshot *tSnapshot
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		// take the latest snapshot, if there is a new one
+		select {
+		case snapshot = <-snapshots:
+		default:
+		}
+		render(w, r, snapshot)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+
+	// stop the simulation, and wait until it has
+	close(done)
+	wg.Wait()
+}
+
+func onAction(w *glfw.Window, commands chan<- tCommand, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		commands <- func(s *tSimulation) {
+			s.paused = !s.paused
+		}
+	case app.Step:
+		commands <- func(s *tSimulation) {
+			s.paused = true
+			s.stepOnce = true
+		}
+	case app.Faster:
+		commands <- func(s *tSimulation) {
+			s.speed *= 2
+			app.Infof("Speed: %.2fx", s.speed)
+		}
+	case app.Slower:
+		commands <- func(s *tSimulation) {
+			s.speed /= 2
+			app.Infof("Speed: %.2fx", s.speed)
+		}
+	case actionLoad:
+		commands <- func(s *tSimulation) {
+			s.slow = !s.slow
+			if s.slow {
+				app.Info("Simulation slowed down to at most 10 steps per second")
+			} else {
+				app.Info("Simulation at full speed")
+			}
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"flag"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+layout(location = 2) in vec2 uv;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+out vec2 texcoord;
+
+void main()
+{
+    gl_Position = projection * view * vec4(position, 1.0);
+    worldPosition = position;
+    worldNormal = normal;
+    texcoord = uv;
+}
+`
+
+	// Grass on flat ground, rock on steep slopes, snow on high flat ground.
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D grass;
+uniform sampler2D rock;
+uniform float maxHeight;
+uniform vec3 eye;
+uniform vec3 fogColor;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+in vec2 texcoord;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    float slope = 1.0 - n.y;
+    float height = worldPosition.y / maxHeight;
+
+    vec3 albedo = mix(texture(grass, texcoord).rgb, texture(rock, texcoord).rgb, smoothstep(0.15, 0.35, slope));
+    float snow = smoothstep(0.65, 0.8, height) * (1.0 - smoothstep(0.3, 0.5, slope));
+    albedo = mix(albedo, vec3(0.95), snow);
+
+    vec3 l = normalize(vec3(0.5, 0.8, 0.3));
+    vec3 c = (0.3 + 0.7 * max(dot(n, l), 0.0)) * albedo;
+
+    float fog = 1.0 - exp(-0.004 * distance(eye, worldPosition));
+    fragColor = vec4(mix(c, fogColor, fog), 1.0);
+}
+`
+)
+
+var (
+	heightmap = flag.String("heightmap", "", "grayscale heightmap image (default: generated)")
+	size      = flag.Float64("size", 200, "width and depth of the terrain")
+	scale     = flag.Float64("scale", 30, "height of the terrain for white pixels")
+)
+
+// Extra action for this demo.
+const actionWireframe = "wireframe"
+
+// Marks the end of a strip in the element buffer.
+const restartIndex = math.MaxUint32
+
+var fogColor = glm.Vec3{.6, .7, .8}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	terrain *mesh.VAO
+	grass   uint32
+	rock    uint32
+	heights tHeights
+}
+
+// tHeights is a grid of heights, from 0 to 1.
+type tHeights struct {
+	h             []float32
+	width, height int
+}
+
+func (h tHeights) at(x, y int) float32 {
+	if x < 0 {
+		x = 0
+	} else if x >= h.width {
+		x = h.width - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= h.height {
+		y = h.height - 1
+	}
+	return h.h[y*h.width+x]
+}
+
+// loadHeights reads a heightmap from an image, using the brightness of
+// each pixel.
+func loadHeights(filename string) (tHeights, error) {
+	img, err := asset.LoadImageOrPlaceholder(filename)
+	if err != nil {
+		return tHeights{}, err
+	}
+	b := img.Bounds()
+	h := tHeights{
+		h:      make([]float32, 0, b.Dx()*b.Dy()),
+		width:  b.Dx(),
+		height: b.Dy(),
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			h.h = append(h.h, (float32(c.R)+float32(c.G)+float32(c.B))/(3*255))
+		}
+	}
+	return h, nil
+}
+
+// generateHeights makes hills with several octaves of value noise.
+func generateHeights(n int) tHeights {
+	h := tHeights{
+		h:      make([]float32, n*n),
+		width:  n,
+		height: n,
+	}
+	amplitude := float32(.5)
+	for cells := 4; cells <= n/2; cells *= 2 {
+		grid := make([]float32, (cells+1)*(cells+1))
+		for i := range grid {
+			grid[i] = rand.Float32()
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				fx := float32(x) * float32(cells) / float32(n)
+				fy := float32(y) * float32(cells) / float32(n)
+				ix, iy := int(fx), int(fy)
+				tx, ty := smooth(fx-float32(ix)), smooth(fy-float32(iy))
+				g := func(i, j int) float32 { return grid[(iy+j)*(cells+1)+ix+i] }
+				v := lerp(lerp(g(0, 0), g(1, 0), tx), lerp(g(0, 1), g(1, 1), tx), ty)
+				h.h[y*n+x] += amplitude * v
+			}
+		}
+		amplitude /= 2
+	}
+	// sharper peaks, flatter valleys
+	for i, v := range h.h {
+		h.h[i] = v * v
+	}
+	return h
+}
+
+func smooth(t float32) float32 {
+	return t * t * (3 - 2*t)
+}
+
+func lerp(a, b, t float32) float32 {
+	return a + (b-a)*t
+}
+
+// makeTerrain creates the terrain mesh: a vertex for each sample in the
+// heightmap, with normals from the differences between neighbours, and
+// the indices as one triangle strip per row, separated by restartIndex.
+func makeTerrain(h tHeights) *mesh.Mesh {
+	m := &mesh.Mesh{
+		Vertices: make([]mesh.Vertex, 0, h.width*h.height),
+		Indices:  make([]uint32, 0, (h.height-1)*(2*h.width+1)),
+	}
+	dx := float32(*size) / float32(h.width-1)
+	dz := float32(*size) / float32(h.height-1)
+	hs := float32(*scale)
+	for y := 0; y < h.height; y++ {
+		for x := 0; x < h.width; x++ {
+			normal := glm.Vec3{
+				(h.at(x-1, y) - h.at(x+1, y)) * hs / (2 * dx),
+				1,
+				(h.at(x, y-1) - h.at(x, y+1)) * hs / (2 * dz),
+			}.Normalize()
+			m.Vertices = append(m.Vertices, mesh.Vertex{
+				Position: glm.Vec3{float32(x)*dx - float32(*size)/2, h.at(x, y) * hs, float32(y)*dz - float32(*size)/2},
+				Normal:   normal,
+				UV:       glm.Vec2{float32(x) / 8, float32(y) / 8}, // texture repeats every 8 samples
+			})
+		}
+	}
+	for y := 0; y < h.height-1; y++ {
+		for x := 0; x < h.width; x++ {
+			m.Indices = append(m.Indices, uint32(y*h.width+x), uint32((y+1)*h.width+x))
+		}
+		m.Indices = append(m.Indices, restartIndex)
+	}
+	return m
+}
+
+// makeNoiseTexture creates a repeating texture with random variations of
+// a color.
+func makeNoiseTexture(c color.RGBA, variation float64) uint32 {
+	const n = 128
+	img := image.NewRGBA(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			f := 1 + variation*(2*rand.Float64()-1)
+			img.SetRGBA(x, y, color.RGBA{
+				uint8(math.Min(255, float64(c.R)*f)),
+				uint8(math.Min(255, float64(c.G)*f)),
+				uint8(math.Min(255, float64(c.B)*f)),
+				255,
+			})
+		}
+	}
+	texture := glutil.MakeTextureFromImage(img)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	return texture
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		grass: makeNoiseTexture(color.RGBA{70, 110, 40, 255}, .25),
+		rock:  makeNoiseTexture(color.RGBA{110, 100, 90, 255}, .35),
+	}
+
+	var err error
+	if *heightmap != "" {
+		r.heights, err = loadHeights(*heightmap)
+		x(err)
+	} else {
+		r.heights = generateHeights(256)
+	}
+	r.terrain = makeTerrain(r.heights).Upload()
+
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock     = app.NewClock()
+	cam       = camera.NewFreeFly(glm.Vec3{0, 40, 100})
+	wireframe = false
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+// update moves the camera, and keeps it above the ground.
+func update(w *glfw.Window, r *gResources) {
+	cam.Update(w)
+
+	h := r.heights
+	col := int((cam.Position[0]/float32(*size) + .5) * float32(h.width-1))
+	row := int((cam.Position[2]/float32(*size) + .5) * float32(h.height-1))
+	ground := h.at(col, row)*float32(*scale) + 1
+	if cam.Position[1] < ground {
+		cam.Position[1] = ground
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+
+	state3D.Apply()
+	gl.ClearColor(fogColor[0], fogColor[1], fogColor[2], 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	if wireframe {
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+	} else {
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+	}
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(60), float32(width)/float32(height), .5, 1000))
+	p.SetVec3("eye", cam.Position)
+	p.SetVec3("fogColor", fogColor)
+	p.SetFloat("maxHeight", float32(*scale))
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, r.grass)
+	p.SetInt("grass", 0)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, r.rock)
+	p.SetInt("rock", 1)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	gl.Enable(gl.PRIMITIVE_RESTART)
+	gl.PrimitiveRestartIndex(restartIndex)
+	gl.BindVertexArray(r.terrain.VertexArray)
+	gl.DrawElements(gl.TRIANGLE_STRIP, r.terrain.Count, r.terrain.IndexType, gl.PtrOffset(0))
+	gl.BindVertexArray(0)
+	gl.Disable(gl.PRIMITIVE_RESTART)
+
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+}
+
+func main() {
+	app.Keys[actionWireframe] = []string{"f"}
+	app.Flags(800, 600, "Terrain")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to look around, use W, A, S, D, page up and page down to move, shift to move faster")
+	app.Info("Press 'f' to toggle wireframe")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		update(w, r)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionWireframe:
+		wireframe = !wireframe
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"image"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	// The corners of the patches, on the ground.
+	vertex_glsl = `
+#version 400 core
+
+layout(location = 0) in vec2 position;
+
+void main()
+{
+    gl_Position = vec4(position.x, 0.0, position.y, 1.0);
+}
+`
+
+	// Sets how finely each patch is divided. The level of an edge only
+	// depends on the edge itself, so neighbouring patches agree, and there
+	// are no cracks between them.
+	control_glsl = `
+#version 400 core
+
+layout(vertices = 4) out;
+
+uniform vec3 eye;
+uniform bool adaptive;
+uniform float level;
+uniform float maxLevel;
+uniform float detailDistance; // where adaptive tessellation uses level
+
+float edgeLevel(vec4 a, vec4 b)
+{
+    if (!adaptive) {
+        return level;
+    }
+    float d = distance(eye, 0.5 * (a.xyz + b.xyz));
+    return clamp(level * detailDistance / d, 1.0, maxLevel);
+}
+
+void main()
+{
+    gl_out[gl_InvocationID].gl_Position = gl_in[gl_InvocationID].gl_Position;
+    if (gl_InvocationID == 0) {
+        vec4 p0 = gl_in[0].gl_Position;
+        vec4 p1 = gl_in[1].gl_Position;
+        vec4 p2 = gl_in[2].gl_Position;
+        vec4 p3 = gl_in[3].gl_Position;
+        gl_TessLevelOuter[0] = edgeLevel(p0, p3);
+        gl_TessLevelOuter[1] = edgeLevel(p0, p1);
+        gl_TessLevelOuter[2] = edgeLevel(p1, p2);
+        gl_TessLevelOuter[3] = edgeLevel(p3, p2);
+        gl_TessLevelInner[0] = max(gl_TessLevelOuter[1], gl_TessLevelOuter[3]);
+        gl_TessLevelInner[1] = max(gl_TessLevelOuter[0], gl_TessLevelOuter[2]);
+    }
+}
+`
+
+	// Places each generated vertex in its patch, and lifts it by the
+	// heightmap.
+	evaluation_glsl = `
+#version 400 core
+
+layout(quads, fractional_even_spacing, ccw) in;
+
+uniform sampler2D heightmap;
+uniform mat4 view;
+uniform mat4 projection;
+uniform float size;
+uniform float heightScale;
+
+out vec2 texcoord;
+out vec3 worldPosition;
+
+void main()
+{
+    vec2 t = gl_TessCoord.xy;
+    vec4 p = mix(
+        mix(gl_in[0].gl_Position, gl_in[1].gl_Position, t.x),
+        mix(gl_in[3].gl_Position, gl_in[2].gl_Position, t.x),
+        t.y);
+    texcoord = p.xz / size + 0.5;
+    // no derivatives outside the fragment shader, so no mipmap selection
+    p.y = heightScale * textureLod(heightmap, texcoord, 0.0).r;
+    worldPosition = p.xyz;
+    gl_Position = projection * view * p;
+}
+`
+
+	fragment_glsl = `
+#version 400 core
+
+uniform sampler2D heightmap;
+uniform float size;
+uniform float heightScale;
+uniform vec3 eye;
+uniform vec3 fogColor;
+uniform float fogDistance;
+
+in vec2 texcoord;
+in vec3 worldPosition;
+
+out vec4 fragColor;
+
+const vec3 lightDirection = normalize(vec3(0.5, 1.0, 0.3));
+
+void main()
+{
+    // the normal from the heightmap, so it doesn't depend on the
+    // tessellation level
+    vec2 texel = 1.0 / vec2(textureSize(heightmap, 0));
+    float left = texture(heightmap, texcoord - vec2(texel.x, 0.0)).r;
+    float right = texture(heightmap, texcoord + vec2(texel.x, 0.0)).r;
+    float down = texture(heightmap, texcoord - vec2(0.0, texel.y)).r;
+    float up = texture(heightmap, texcoord + vec2(0.0, texel.y)).r;
+    vec2 slope = heightScale * vec2(left - right, down - up) / (2.0 * texel * size);
+    vec3 n = normalize(vec3(slope.x, 1.0, slope.y));
+
+    float h = worldPosition.y / heightScale;
+    vec3 c = mix(vec3(0.3, 0.5, 0.2), vec3(0.5, 0.45, 0.4), smoothstep(0.3, 0.5, h));
+    c = mix(c, vec3(0.95), smoothstep(0.7, 0.8, h) * smoothstep(0.6, 0.8, n.y));
+    c *= 0.3 + 0.7 * max(dot(n, lightDirection), 0.0);
+
+    float fog = clamp(distance(eye, worldPosition) / fogDistance, 0.0, 1.0);
+    fragColor = vec4(mix(c, fogColor, fog * fog), 1.0);
+}
+`
+)
+
+var (
+	heightmap   = flag.String("heightmap", "", "heightmap image (default: generated)")
+	size        = flag.Float64("size", 128, "width and depth of the terrain")
+	heightScale = flag.Float64("scale", 20, "height of the terrain")
+	patches     = flag.Int("patches", 16, "number of patches along each side")
+)
+
+// Extra actions for this demo.
+const (
+	actionWireframe = "wireframe"
+	actionAdaptive  = "adaptive"
+	actionFiner     = "finer"
+	actionCoarser   = "coarser"
+)
+
+var fogColor = glm.Vec3{.6, .7, .8}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program     *glutil.Program
+	vertexArray uint32
+	buffer      uint32
+	count       int32 // number of control points
+	heightmap   uint32
+}
+
+// generateHeightmap makes hills with several octaves of value noise.
+func generateHeightmap(n int) image.Image {
+	h := make([]float32, n*n)
+	amplitude := float32(.5)
+	for cells := 4; cells <= n/4; cells *= 2 {
+		grid := make([]float32, (cells+1)*(cells+1))
+		for i := range grid {
+			grid[i] = rand.Float32()
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				fx := float32(x) * float32(cells) / float32(n)
+				fy := float32(y) * float32(cells) / float32(n)
+				ix, iy := int(fx), int(fy)
+				tx, ty := smooth(fx-float32(ix)), smooth(fy-float32(iy))
+				g := func(i, j int) float32 { return grid[(iy+j)*(cells+1)+ix+i] }
+				v := lerp(lerp(g(0, 0), g(1, 0), tx), lerp(g(0, 1), g(1, 1), tx), ty)
+				h[y*n+x] += amplitude * v
+			}
+		}
+		amplitude /= 2
+	}
+	img := image.NewGray(image.Rect(0, 0, n, n))
+	for i, v := range h {
+		// sharper peaks, flatter valleys
+		img.Pix[i] = uint8(math.Min(255, float64(255*v*v)))
+	}
+	return img
+}
+
+func smooth(t float32) float32 {
+	return t * t * (3 - 2*t)
+}
+
+func lerp(a, b, t float32) float32 {
+	return a + (b-a)*t
+}
+
+// makePatches returns the corners of n x n square patches covering the
+// terrain, four for each patch.
+func makePatches(n int, size float32) []glm.Vec2 {
+	corners := make([]glm.Vec2, 0, 4*n*n)
+	step := size / float32(n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			x0, z0 := float32(i)*step-size/2, float32(j)*step-size/2
+			x1, z1 := x0+step, z0+step
+			corners = append(corners, glm.Vec2{x0, z0}, glm.Vec2{x1, z0}, glm.Vec2{x1, z1}, glm.Vec2{x0, z1})
+		}
+	}
+	return corners
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewTessellationProgram(vertex_glsl, control_glsl, evaluation_glsl, fragment_glsl)
+	x(err)
+
+	if *heightmap != "" {
+		r.heightmap, err = glutil.MakeTexture(*heightmap)
+		x(err)
+	} else {
+		r.heightmap = glutil.MakeTextureFromImage(generateHeightmap(512))
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+
+	corners := makePatches(*patches, float32(*size))
+	r.count = int32(len(corners))
+	gl.GenVertexArrays(1, &r.vertexArray)
+	gl.BindVertexArray(r.vertexArray)
+	r.buffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, corners, gl.STATIC_DRAW)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 0, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+	gl.BindVertexArray(0)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam = camera.NewFreeFly(glm.Vec3{0, 30, 70})
+
+	wireframe bool
+	adaptive  = true
+	level     = float32(16)
+	maxLevel  float32
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	state3D.Apply()
+	gl.ClearColor(fogColor[0], fogColor[1], fogColor[2], 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	if wireframe {
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+	}
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", cam.View())
+	p.SetMat4("projection", glm.Perspective(glm.Radians(60), float32(width)/float32(height), .5, float32(2**size)))
+	p.SetVec3("eye", cam.Position)
+	p.SetBool("adaptive", adaptive)
+	p.SetFloat("level", level)
+	p.SetFloat("maxLevel", maxLevel)
+	p.SetFloat("detailDistance", float32(*size)/float32(*patches))
+	p.SetFloat("size", float32(*size))
+	p.SetFloat("heightScale", float32(*heightScale))
+	p.SetVec3("fogColor", fogColor)
+	p.SetFloat("fogDistance", float32(*size))
+	p.SetInt("heightmap", 0)
+	gl.BindTexture(gl.TEXTURE_2D, r.heightmap)
+
+	gl.PatchParameteri(gl.PATCH_VERTICES, 4)
+	gl.BindVertexArray(r.vertexArray)
+	gl.DrawArrays(gl.PATCHES, 0, r.count)
+	gl.BindVertexArray(0)
+
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+}
+
+func printLevel() {
+	if adaptive {
+		app.Infof("Tessellation level %g nearby, less with distance", level)
+	} else {
+		app.Infof("Tessellation level %g everywhere", level)
+	}
+}
+
+func main() {
+	app.Keys[actionWireframe] = []string{"f"}
+	app.Keys[actionAdaptive] = []string{"t"}
+	app.Keys[actionFiner] = []string{"]"}
+	app.Keys[actionCoarser] = []string{"["}
+	app.Flags(800, 600, "Tessellation")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	// tessellation shaders need OpenGL 4.0
+	app.CoreProfile(4, 0)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	cam.Pitch = -.4
+	cam.Speed = 20
+
+	var m int32
+	gl.GetIntegerv(gl.MAX_TESS_GEN_LEVEL, &m)
+	maxLevel = float32(m)
+
+	app.Info("Drag with the mouse to look around, use W, A, S, D, page up and page down to move, shift to move faster")
+	app.Info("Press '[' and ']' to change the tessellation level, 't' to toggle distance-based tessellation, 'f' to toggle wireframe")
+	app.Info("Press 'q' to quit")
+	printLevel()
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		cam.Update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionWireframe:
+		wireframe = !wireframe
+	case actionAdaptive:
+		adaptive = !adaptive
+		printLevel()
+	case actionFiner:
+		if level*2 <= maxLevel {
+			level *= 2
+		}
+		printLevel()
+	case actionCoarser:
+		if level > 1 {
+			level /= 2
+		}
+		printLevel()
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+package text
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/sprite"
+
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Banner shows an error in the window, on a red banner at the top, so a
+// demo can go on after a mistake that can be fixed while it runs, such as
+// in a shader file that is loaded again when it changes.
+//
+//	p, err := glutil.NewProgram(vertex, fragment)
+//	banner.Set(err)
+//	if err == nil {
+//		program = p
+//	}
+//	...
+//	// at the end of each frame
+//	banner.Draw(width, height)
+type Banner struct {
+	font  *Font
+	batch *sprite.Batch
+	white uint32
+	err   error
+}
+
+// Margin around the text of a banner, in pixels.
+const bannerMargin = 8
+
+// NewBanner returns a banner that shows nothing yet.
+func NewBanner() (*Banner, error) {
+	batch, err := sprite.NewBatch(1024)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{255, 255, 255, 255})
+	return &Banner{
+		font:  NewFont(2),
+		batch: batch,
+		white: glutil.MakeTextureFromImage(img),
+	}, nil
+}
+
+// Set sets the error to show, or nil to hide the banner.
+func (b *Banner) Set(err error) {
+	b.err = err
+}
+
+// Err returns the error that is shown, or nil.
+func (b *Banner) Err() error {
+	return b.err
+}
+
+// Draw draws the banner over the top of the window, or the default
+// framebuffer, of the given size, if there is an error. Long lines are
+// wrapped, and what doesn't fit in the top half of the window is left out.
+func (b *Banner) Draw(width, height int) {
+	if b.err == nil {
+		return
+	}
+
+	cell := glm.Vec2{CellWidth * b.font.Scale, CellHeight * b.font.Scale}
+	perLine := int((float32(width) - 2*bannerMargin) / cell[0])
+	maxLines := int((float32(height)/2 - 2*bannerMargin) / cell[1])
+	if perLine < 1 || maxLines < 1 {
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(b.err.Error()), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		for len(line) > perLine {
+			lines = append(lines, line[:perLine])
+			line = line[perLine:]
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines-1], "...")
+	}
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, int32(width), int32(height))
+	bannerHeight := float32(len(lines))*cell[1] + 2*bannerMargin
+	b.batch.Begin(glm.Ortho(0, float32(width), float32(height), 0, -1, 1))
+	b.batch.Draw(b.white, sprite.Sprite{
+		Position: glm.Vec2{float32(width) / 2, bannerHeight / 2},
+		Size:     glm.Vec2{float32(width), bannerHeight},
+		Region:   glm.Vec4{0, 0, 1, 1},
+		Color:    glm.Vec4{.7, 0, 0, .9},
+	})
+	b.font.Draw(b.batch, strings.Join(lines, "\n"), glm.Vec2{bannerMargin, bannerMargin}, glm.Vec4{1, 1, 1, 1})
+	b.batch.End()
+}
+
+// Delete frees the OpenGL objects of the banner.
+func (b *Banner) Delete() {
+	b.font.Delete()
+	b.batch.Delete()
+	gl.DeleteTextures(1, &b.white)
+}
@@ -0,0 +1,122 @@
+package text
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/shapes"
+	"github.com/pebbe/gl/sprite"
+
+	"fmt"
+	"time"
+)
+
+// FrameGraph draws the times of the last frames, as swapped by
+// app.SwapBuffers, as a graph that scrolls to the left, so stutters can
+// be seen at a glance. Frames that fit in 16.6 ms, 60 frames per second,
+// are green, those that fit in 33.3 ms yellow, and slower ones red, with
+// white lines at those times:
+//
+//	graph, err := text.NewFrameGraph()
+//	...
+//	// at the end of each frame
+//	graph.Draw(width, height)
+type FrameGraph struct {
+	shapes  *shapes.Batch
+	sprites *sprite.Batch
+	font    *Font
+}
+
+// Size of the graph in pixels, with a bar of one pixel for each frame, and
+// the time at the top. Slower frames are clipped.
+const (
+	graphFrames = 240
+	graphHeight = 80
+	graphMax    = 50 * time.Millisecond
+	graphMargin = 10
+)
+
+// Frame times of 60 and 30 frames per second.
+const (
+	frame60 = 16667 * time.Microsecond
+	frame30 = 33333 * time.Microsecond
+)
+
+// Colors of the bars of the graph.
+var (
+	graphFast   = glm.Vec4{.2, .8, .2, .9}
+	graphSlow   = glm.Vec4{1, .8, .2, .9}
+	graphSlower = glm.Vec4{1, .3, .3, .9}
+)
+
+// NewFrameGraph returns a frame graph.
+func NewFrameGraph() (*FrameGraph, error) {
+	sh, err := shapes.NewBatch(graphFrames + 8)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := sprite.NewBatch(256)
+	if err != nil {
+		sh.Delete()
+		return nil, err
+	}
+	return &FrameGraph{
+		shapes:  sh,
+		sprites: sp,
+		font:    NewFont(1),
+	}, nil
+}
+
+// Draw draws the graph in the top right corner of the window, or the
+// default framebuffer, of the given size.
+func (g *FrameGraph) Draw(width, height int) {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, int32(width), int32(height))
+	projection := glm.Ortho(0, float32(width), float32(height), 0, -1, 1)
+
+	left := float32(width) - graphFrames - graphMargin
+	top := float32(graphMargin)
+	bottom := top + graphHeight
+	y := func(d time.Duration) float32 {
+		if d > graphMax {
+			d = graphMax
+		}
+		return bottom - graphHeight*float32(d)/float32(graphMax)
+	}
+
+	times := app.RecentFrameTimes(graphFrames)
+	g.shapes.Begin(projection)
+	g.shapes.Rect(glm.Vec2{left, top}, glm.Vec2{left + graphFrames, bottom}, 0, shapes.Style{Fill: glm.Vec4{0, 0, 0, .6}})
+	// the newest at the right
+	x0 := left + float32(graphFrames-len(times))
+	for i, d := range times {
+		c := graphFast
+		if d > frame30 {
+			c = graphSlower
+		} else if d > frame60 {
+			c = graphSlow
+		}
+		bar := x0 + float32(i)
+		g.shapes.Rect(glm.Vec2{bar, y(d)}, glm.Vec2{bar + 1, bottom}, 0, shapes.Style{Fill: c})
+	}
+	for _, d := range []time.Duration{frame60, frame30} {
+		g.shapes.Rect(glm.Vec2{left, y(d)}, glm.Vec2{left + graphFrames, y(d) + 1}, 0, shapes.Style{Fill: glm.Vec4{1, 1, 1, .5}})
+	}
+	g.shapes.End()
+
+	g.sprites.Begin(projection)
+	g.font.Draw(g.sprites, "16.6", glm.Vec2{left + 2, y(frame60) - CellHeight - 1}, glm.Vec4{1, 1, 1, .8})
+	g.font.Draw(g.sprites, "33.3", glm.Vec2{left + 2, y(frame30) - CellHeight - 1}, glm.Vec4{1, 1, 1, .8})
+	if len(times) > 0 {
+		s := fmt.Sprintf("%.1f ms", times[len(times)-1].Seconds()*1000)
+		g.font.Draw(g.sprites, s, glm.Vec2{left + graphFrames - g.font.Size(s)[0] - 2, top + 2}, glm.Vec4{1, 1, 1, 1})
+	}
+	g.sprites.End()
+}
+
+// Delete frees the OpenGL objects of the graph.
+func (g *FrameGraph) Delete() {
+	g.shapes.Delete()
+	g.sprites.Delete()
+	g.font.Delete()
+}
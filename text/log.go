@@ -0,0 +1,51 @@
+package text
+
+import (
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/sprite"
+
+	"strings"
+)
+
+// Colors of the messages drawn by DrawLog, by level.
+var logColors = map[app.Level]glm.Vec4{
+	app.LevelDebug: {.6, .6, .6, 1},
+	app.LevelInfo:  {1, 1, 1, 1},
+	app.LevelWarn:  {1, .8, .2, 1},
+	app.LevelError: {1, .3, .3, 1},
+}
+
+// DrawLog draws the last n messages of the log of package app at the level
+// set with -v and -quiet or higher, from pos down, one line for each, in
+// the color of its level, with a shadow to make them readable on any
+// background. Only the first line of a message is drawn. It returns the
+// number of lines drawn. Call it between b.Begin and b.End:
+//
+//	b.Begin(glm.Ortho(0, float32(width), float32(height), 0, -1, 1))
+//	f.DrawLog(b, glm.Vec2{10, float32(height) - 5*f.Scale*text.CellHeight - 10}, 5)
+//	b.End()
+func (f *Font) DrawLog(b *sprite.Batch, pos glm.Vec2, n int) int {
+	var lines []app.LogEntry
+	level := app.LogLevel()
+	for _, e := range app.RecentLog(100) {
+		if e.Level >= level {
+			lines = append(lines, e)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	shadow := glm.Vec2{2, 2}
+	for _, e := range lines {
+		s := e.Message
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[:i]
+		}
+		f.Draw(b, s, pos.Add(shadow), glm.Vec4{0, 0, 0, .8})
+		f.Draw(b, s, pos, logColors[e.Level])
+		pos[1] += CellHeight * f.Scale
+	}
+	return len(lines)
+}
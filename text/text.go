@@ -0,0 +1,118 @@
+// Package text draws text with the small built-in bitmap font of package
+// font, for labels and statistics in the demos. The characters are sprites,
+// drawn with a sprite.Batch, so text can be mixed with other sprites.
+//
+// Only printable ASCII is supported, other characters are drawn as '?'.
+package text
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/font"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/sprite"
+
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Size of a character cell in pixels of the font, the same as in package
+// font.
+const (
+	CellWidth  = font.CellWidth
+	CellHeight = font.CellHeight
+)
+
+// The atlas has the glyphs in rows of 16.
+const (
+	atlasColumns = 16
+	atlasRows    = (len(font.Glyphs) + atlasColumns - 1) / atlasColumns
+	atlasWidth   = atlasColumns * CellWidth
+	atlasHeight  = atlasRows * CellHeight
+)
+
+// Font is the built-in font, uploaded to a texture.
+type Font struct {
+	Texture uint32
+	Scale   float32 // screen pixels per pixel of the font, whole numbers stay sharp
+}
+
+// NewFont uploads the font, to be drawn at the given scale.
+func NewFont(scale float32) *Font {
+	f := &Font{
+		Texture: glutil.MakeTextureFromImage(atlas()),
+		Scale:   scale,
+	}
+	// sharp pixels
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	return f
+}
+
+// atlas returns an image with all glyphs, white on transparent.
+func atlas() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, atlasWidth, atlasHeight))
+	for i, g := range font.Glyphs {
+		x0 := (i % atlasColumns) * CellWidth
+		y0 := (i / atlasColumns) * CellHeight
+		for col, bits := range g {
+			for row := 0; row < 7; row++ {
+				if bits&(1<<uint(row)) != 0 {
+					img.SetRGBA(x0+col, y0+row, color.RGBA{255, 255, 255, 255})
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Draw adds sprites for the characters of s to the batch, with the top
+// left corner at pos, in color c. Lines are separated by '\n'. The batch
+// should have a projection with y pointing down, such as
+// glm.Ortho(0, width, height, 0, -1, 1).
+func (f *Font) Draw(b *sprite.Batch, s string, pos glm.Vec2, c glm.Vec4) {
+	w, h := CellWidth*f.Scale, CellHeight*f.Scale
+	// whole pixels, so the glyphs stay sharp
+	x0 := float32(math.Round(float64(pos[0])))
+	y := float32(math.Round(float64(pos[1])))
+	for _, line := range strings.Split(s, "\n") {
+		px := x0
+		for _, r := range line {
+			i := int(r) - ' '
+			if i < 0 || i >= len(font.Glyphs) {
+				i = '?' - ' '
+			}
+			if i != 0 {
+				u := float32(i%atlasColumns*CellWidth) / float32(atlasWidth)
+				v := float32(i/atlasColumns*CellHeight) / float32(atlasHeight)
+				b.Draw(f.Texture, sprite.Sprite{
+					Position: glm.Vec2{px + w/2, y + h/2},
+					Size:     glm.Vec2{w, h},
+					Region:   glm.Vec4{u, v, u + CellWidth/float32(atlasWidth), v + CellHeight/float32(atlasHeight)},
+					Color:    c,
+				})
+			}
+			px += w
+		}
+		y += h
+	}
+}
+
+// Size returns the width and height of s when drawn, in screen pixels.
+func (f *Font) Size(s string) glm.Vec2 {
+	lines := strings.Split(s, "\n")
+	n := 0
+	for _, line := range lines {
+		if l := len([]rune(line)); l > n {
+			n = l
+		}
+	}
+	return glm.Vec2{float32(n) * CellWidth * f.Scale, float32(len(lines)) * CellHeight * f.Scale}
+}
+
+// Delete frees the texture.
+func (f *Font) Delete() {
+	gl.DeleteTextures(1, &f.Texture)
+}
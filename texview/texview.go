@@ -0,0 +1,343 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D tex;
+uniform bool useLod;
+uniform float lod;
+
+in vec2 uv;
+
+out vec4 fragColor;
+
+void main()
+{
+    // the first row of the image is at the top
+    vec2 st = vec2(uv.x, 1.0 - uv.y);
+    if (useLod) {
+        fragColor = textureLod(tex, st, lod);
+    } else {
+        fragColor = texture(tex, st);
+    }
+}
+` + "\x00"
+)
+
+var (
+	imageFile = flag.String("image", "", "image to use, converted to a single channel, instead of a generated pattern")
+	noViews   = flag.Bool("noviews", false, "don't use texture views, even if available")
+)
+
+// Extra actions for this demo.
+const (
+	actionSwizzle = "swizzle"
+	actionFilter  = "filter"
+	actionUp      = "up"
+	actionDown    = "down"
+)
+
+//
+// A single channel image
+//
+
+// makePattern returns a size x size image of concentric rings that get
+// narrower to the edges, which turn into a flat gray in the smaller
+// mipmap levels.
+func makePattern(size int) []byte {
+	pix := make([]byte, size*size)
+	for j := 0; j < size; j++ {
+		for i := 0; i < size; i++ {
+			dx := float64(i-size/2) / float64(size/2)
+			dy := float64(j-size/2) / float64(size/2)
+			v := .5 + .5*math.Cos(40*(dx*dx+dy*dy))
+			pix[j*size+i] = byte(255 * v)
+		}
+	}
+	return pix
+}
+
+// loadGray returns the luminance of the image.
+func loadGray(filename string) (pix []byte, width, height int, err error) {
+	img, err := asset.LoadImageOrPlaceholder(filename)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	width, height = img.Rect.Dx(), img.Rect.Dy()
+	pix = make([]byte, width*height)
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			c := img.RGBAAt(img.Rect.Min.X+i, img.Rect.Min.Y+j)
+			pix[j*width+i] = byte(.299*float64(c.R) + .587*float64(c.G) + .114*float64(c.B))
+		}
+	}
+	return
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+
+	texture       uint32
+	width, height int32
+	levels        int32
+
+	// a view of each mipmap level, or nil without texture views
+	views []uint32
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	var pix []byte
+	var width, height int
+	if *imageFile != "" {
+		pix, width, height, err = loadGray(*imageFile)
+		x(err)
+	} else {
+		width, height = 256, 256
+		pix = makePattern(width)
+	}
+	r.width, r.height = int32(width), int32(height)
+	// down to 1x1, only the highest bit of the larger size counts
+	for s := r.width | r.height; s > 0; s /= 2 {
+		r.levels++
+	}
+
+	useViews := !*noViews && (glutil.VersionAtLeast(4, 3) || glutil.Has("GL_ARB_texture_view"))
+	if useViews {
+		// views need immutable storage
+		r.texture = glutil.MakeTextureStorage(r.width, r.height, r.levels, glutil.FormatR8)
+	} else {
+		gl.GenTextures(1, &r.texture)
+		gl.BindTexture(gl.TEXTURE_2D, r.texture)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R8, r.width, r.height, 0, gl.RED, gl.UNSIGNED_BYTE, nil)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, r.texture)
+	// rows of single bytes aren't aligned to four bytes
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, r.width, r.height, gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(pix))
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+
+	if useViews {
+		for level := uint32(0); level < uint32(r.levels); level++ {
+			view, err := glutil.MakeTextureView(r.texture, glutil.FormatR8, level, 1)
+			x(err)
+			r.views = append(r.views, view)
+		}
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	swizzle = true
+	nearest = true
+	level   int32
+)
+
+// setTexture binds the texture for a mipmap level, with the current
+// settings. Without views, the level is selected in the shader.
+func setTexture(r *gResources, lvl int32) {
+	p := r.program
+	texture := r.texture
+	if r.views != nil {
+		texture = r.views[lvl]
+		p.SetBool("useLod", false)
+	} else {
+		p.SetBool("useLod", true)
+		p.SetFloat("lod", float32(lvl))
+	}
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	// a view has its own swizzle, independent of the original texture
+	if swizzle {
+		glutil.SetSwizzle(texture, glutil.SwizzleGray)
+	} else {
+		glutil.SetSwizzle(texture, glutil.SwizzleRGBA)
+	}
+	filter := int32(gl.LINEAR)
+	if nearest {
+		filter = gl.NEAREST
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+	if r.views != nil {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	}
+	p.SetInt("tex", 0)
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.2, .2, .25, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	r.program.Use()
+
+	// the selected level, magnified, on the left
+	size := int32(height) - 20
+	aspect := float32(r.width) / float32(r.height)
+	pw, ph := size, size
+	if aspect > 1 {
+		ph = int32(float32(size) / aspect)
+	} else {
+		pw = int32(float32(size) * aspect)
+	}
+	setTexture(r, level)
+	gl.Viewport(10, 10, pw, ph)
+	glutil.DrawFullscreen()
+
+	// all levels on the right, at half their size, up to 128 pixels for
+	// level 0
+	biggest := r.width
+	if r.height > biggest {
+		biggest = r.height
+	}
+	scale := float32(128) / float32(biggest)
+	if scale > .5 {
+		scale = .5
+	}
+	px := size + 30
+	for i := int32(0); i < r.levels; i++ {
+		lw := int32(math.Max(1, float64(scale*float32(r.width>>uint(i)))))
+		lh := int32(math.Max(1, float64(scale*float32(r.height>>uint(i)))))
+		setTexture(r, i)
+		gl.Viewport(px, 10, lw, lh)
+		glutil.DrawFullscreen()
+		px += lw + 6
+	}
+}
+
+func printLevel(r *gResources) {
+	lw, lh := r.width>>uint(level), r.height>>uint(level)
+	if lw < 1 {
+		lw = 1
+	}
+	if lh < 1 {
+		lh = 1
+	}
+	app.Infof("Level %d: %d x %d", level, lw, lh)
+}
+
+func main() {
+	app.Keys[actionSwizzle] = []string{"s"}
+	app.Keys[actionFilter] = []string{"f"}
+	app.Keys[actionUp] = []string{"]"}
+	app.Keys[actionDown] = []string{"["}
+	app.Flags(820, 500, "Texture swizzle and views")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(4, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		app.Info("No OpenGL 4.3, trying 3.3")
+		app.CoreProfile(3, 3)
+		w, err = app.CreateWindow()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	if r.views != nil {
+		app.Infof("A texture view for each of %d mipmap levels", r.levels)
+	} else {
+		app.Infof("%d mipmap levels, selected in the shader", r.levels)
+	}
+	printLevel(r)
+
+	app.OnAction(w, func(w *glfw.Window, action string) { onAction(w, r, action) })
+
+	app.Info("Press 's' to toggle the gray swizzle, 'f' to switch between nearest and linear filtering")
+	app.Info("Press '[' and ']' to select a mipmap level")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionSwizzle:
+		swizzle = !swizzle
+		if swizzle {
+			app.Info("Swizzle: red as gray")
+		} else {
+			app.Info("Swizzle: none, the single channel is red")
+		}
+	case actionFilter:
+		nearest = !nearest
+	case actionUp:
+		if level+1 < r.levels {
+			level++
+			printLevel(r)
+		}
+	case actionDown:
+		if level > 0 {
+			level--
+			printLevel(r)
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,303 @@
+package tilemap
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"path/filepath"
+)
+
+const (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 projection;
+uniform vec2 offset;
+
+layout(location = 0) in vec2 position;
+layout(location = 1) in vec2 uv;
+
+out vec2 texcoord;
+
+void main()
+{
+    texcoord = uv;
+    gl_Position = projection * vec4(position + offset, 0.0, 1.0);
+}
+`
+
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler2D tiles;
+uniform float opacity;
+
+in vec2 texcoord;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec4 c = texture(tiles, texcoord);
+    fragColor = vec4(c.rgb, c.a * opacity);
+}
+`
+)
+
+// ChunkSize is the width and height of a chunk, in tiles.
+const ChunkSize = 16
+
+// Floats per vertex: position and texture coordinates.
+const vertexSize = 4
+
+// Renderer draws a map. The tiles of each layer are stored in a static
+// vertex buffer, grouped by chunk, and within a chunk by tileset, so that
+// each chunk takes a draw call per tileset it uses.
+type Renderer struct {
+	Map *Map
+
+	// Statistics of the last call to Draw.
+	ChunksDrawn int
+	ChunksTotal int
+
+	program *glutil.Program
+	layers  []*layerBuffer
+	chunksX int
+	chunksY int
+}
+
+type layerBuffer struct {
+	layer       *Layer
+	vao, buffer uint32
+	chunks      [][]tRange // chunk at column x and row y at y*chunksX+x
+}
+
+// A range of vertices in a layer buffer, with tiles of one tileset.
+type tRange struct {
+	tileset      *Tileset
+	first, count int32
+}
+
+// Layers are blended over the layers below them.
+var stateTiles = glutil.State{
+	Blend: true,
+}
+
+// NewRenderer creates the buffers for all tile layers of the map, and
+// loads the images of the tilesets that don't have a texture yet.
+func NewRenderer(m *Map) (*Renderer, error) {
+	p, err := glutil.NewProgram(vertex_glsl, fragment_glsl)
+	if err != nil {
+		return nil, err
+	}
+	r := &Renderer{
+		Map:     m,
+		program: p,
+		chunksX: (m.Width + ChunkSize - 1) / ChunkSize,
+		chunksY: (m.Height + ChunkSize - 1) / ChunkSize,
+	}
+
+	for _, ts := range m.Tilesets {
+		if ts.Texture != 0 {
+			continue
+		}
+		filename := ts.Image
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(m.Dir, filename)
+		}
+		ts.Texture, err = glutil.MakeTexture(filename)
+		if err != nil {
+			r.Delete()
+			return nil, err
+		}
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	}
+
+	for _, l := range m.Layers {
+		r.layers = append(r.layers, r.makeLayer(l))
+	}
+
+	return r, nil
+}
+
+// makeLayer creates the vertex buffer for a layer.
+func (r *Renderer) makeLayer(l *Layer) *layerBuffer {
+	m := r.Map
+	lb := &layerBuffer{
+		layer:  l,
+		chunks: make([][]tRange, r.chunksX*r.chunksY),
+	}
+	var data []float32
+	for cy := 0; cy < r.chunksY; cy++ {
+		for cx := 0; cx < r.chunksX; cx++ {
+			for _, ts := range m.Tilesets {
+				first := int32(len(data) / vertexSize)
+				for y := cy * ChunkSize; y < (cy+1)*ChunkSize && y < l.Height; y++ {
+					for x := cx * ChunkSize; x < (cx+1)*ChunkSize && x < l.Width; x++ {
+						gid, flags := l.Tile(x, y)
+						if gid == 0 || m.Tileset(gid) != ts {
+							continue
+						}
+						data = appendTile(data, m, ts, x, y, gid, flags)
+					}
+				}
+				if count := int32(len(data)/vertexSize) - first; count > 0 {
+					i := cy*r.chunksX + cx
+					lb.chunks[i] = append(lb.chunks[i], tRange{ts, first, count})
+				}
+			}
+		}
+	}
+
+	gl.GenVertexArrays(1, &lb.vao)
+	gl.BindVertexArray(lb.vao)
+	if len(data) > 0 {
+		lb.buffer = glutil.MakeBuffer(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
+	}
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 4*vertexSize, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(1, 2, gl.FLOAT, false, 4*vertexSize, gl.PtrOffset(8))
+	gl.EnableVertexAttribArray(1)
+	gl.BindVertexArray(0)
+	return lb
+}
+
+// appendTile adds the two triangles of a tile. Tiles larger than the grid
+// of the map are aligned with the bottom left corner of their cell, as in
+// Tiled.
+func appendTile(data []float32, m *Map, ts *Tileset, x, y int, gid, flags uint32) []float32 {
+	id := int(gid) - ts.FirstGID
+	columns := ts.Columns
+	if columns == 0 {
+		columns = 1
+	}
+	tx := ts.Margin + (id%columns)*(ts.TileWidth+ts.Spacing)
+	ty := ts.Margin + (id/columns)*(ts.TileHeight+ts.Spacing)
+	u0, v0 := float32(tx)/float32(ts.ImageWidth), float32(ty)/float32(ts.ImageHeight)
+	u1 := float32(tx+ts.TileWidth) / float32(ts.ImageWidth)
+	v1 := float32(ty+ts.TileHeight) / float32(ts.ImageHeight)
+
+	x0 := float32(x * m.TileWidth)
+	y1 := float32((y + 1) * m.TileHeight)
+	x1 := x0 + float32(ts.TileWidth)
+	y0 := y1 - float32(ts.TileHeight)
+
+	corner := func(cx, cy int) []float32 {
+		p := []float32{x0, y0}
+		if cx == 1 {
+			p[0] = x1
+		}
+		if cy == 1 {
+			p[1] = y1
+		}
+		// the flips of the image, undone on the texture coordinates: the
+		// diagonal flip first, then horizontal and vertical
+		if flags&FlipHorizontal != 0 {
+			cx = 1 - cx
+		}
+		if flags&FlipVertical != 0 {
+			cy = 1 - cy
+		}
+		if flags&FlipDiagonal != 0 {
+			cx, cy = cy, cx
+		}
+		u, v := u0, v0
+		if cx == 1 {
+			u = u1
+		}
+		if cy == 1 {
+			v = v1
+		}
+		return append(p, u, v)
+	}
+	for _, c := range [6][2]int{{0, 0}, {1, 0}, {0, 1}, {0, 1}, {1, 0}, {1, 1}} {
+		data = append(data, corner(c[0], c[1])...)
+	}
+	return data
+}
+
+// Draw draws the visible layers. Map coordinates are in pixels, with y
+// pointing down: tile x, y has its top left corner at x*TileWidth,
+// y*TileHeight. The projection maps these to clip space. Only the chunks
+// that overlap the rectangle from x0, y0 to x1, y1, in map coordinates,
+// are drawn.
+func (r *Renderer) Draw(projection glm.Mat4, x0, y0, x1, y1 float32) {
+	m := r.Map
+	p := r.program
+	stateTiles.Apply()
+	p.Use()
+	p.SetMat4("projection", projection)
+	p.SetInt("tiles", 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	r.ChunksDrawn, r.ChunksTotal = 0, 0
+	chunkWidth := float32(ChunkSize * m.TileWidth)
+	chunkHeight := float32(ChunkSize * m.TileHeight)
+	for _, lb := range r.layers {
+		l := lb.layer
+		if !l.Visible || lb.buffer == 0 {
+			continue
+		}
+		p.SetVec2("offset", glm.Vec2{l.OffsetX, l.OffsetY})
+		p.SetFloat("opacity", l.Opacity)
+		gl.BindVertexArray(lb.vao)
+
+		// one extra chunk to the left and below, for tiles larger than
+		// the grid, that reach into the chunk to the right or above
+		cx0 := clamp(int((x0-l.OffsetX)/chunkWidth)-1, r.chunksX)
+		cy0 := clamp(int((y0-l.OffsetY)/chunkHeight), r.chunksY)
+		cx1 := clamp(int((x1-l.OffsetX)/chunkWidth)+1, r.chunksX)
+		cy1 := clamp(int((y1-l.OffsetY)/chunkHeight)+2, r.chunksY)
+		for cy := cy0; cy < cy1; cy++ {
+			for cx := cx0; cx < cx1; cx++ {
+				ranges := lb.chunks[cy*r.chunksX+cx]
+				for _, rg := range ranges {
+					gl.BindTexture(gl.TEXTURE_2D, rg.tileset.Texture)
+					gl.DrawArrays(gl.TRIANGLES, rg.first, rg.count)
+					app.CountDrawCalls(1)
+				}
+				if len(ranges) > 0 {
+					r.ChunksDrawn++
+				}
+			}
+		}
+		for _, ranges := range lb.chunks {
+			if len(ranges) > 0 {
+				r.ChunksTotal++
+			}
+		}
+	}
+	gl.BindVertexArray(0)
+}
+
+// clamp limits a chunk index to 0 to n.
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// Delete frees the buffers of the renderer, and the textures of the
+// tilesets.
+func (r *Renderer) Delete() {
+	for _, lb := range r.layers {
+		gl.DeleteVertexArrays(1, &lb.vao)
+		if lb.buffer != 0 {
+			gl.DeleteBuffers(1, &lb.buffer)
+		}
+	}
+	for _, ts := range r.Map.Tilesets {
+		if ts.Texture != 0 {
+			gl.DeleteTextures(1, &ts.Texture)
+			ts.Texture = 0
+		}
+	}
+	r.program.Delete()
+}
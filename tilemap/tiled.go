@@ -0,0 +1,238 @@
+// Package tilemap loads tile maps exported from the Tiled map editor in its
+// JSON format, and draws them in chunks, skipping those that are off
+// screen.
+package tilemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Flags in the high bits of a global tile ID.
+const (
+	FlipHorizontal = 0x80000000
+	FlipVertical   = 0x40000000
+	FlipDiagonal   = 0x20000000
+
+	flipMask = FlipHorizontal | FlipVertical | FlipDiagonal
+)
+
+// Map is an orthogonal tile map. Tiles are numbered with global IDs: 0 is
+// no tile, and each tileset has the IDs from its FirstGID up.
+type Map struct {
+	Width       int        `json:"width"`  // in tiles
+	Height      int        `json:"height"` // in tiles
+	TileWidth   int        `json:"tilewidth"`
+	TileHeight  int        `json:"tileheight"`
+	Orientation string     `json:"orientation"`
+	Infinite    bool       `json:"infinite"`
+	Layers      []*Layer   `json:"layers"`
+	Tilesets    []*Tileset `json:"tilesets"`
+
+	// Directory of the map file, that the images of the tilesets are
+	// relative to.
+	Dir string `json:"-"`
+}
+
+// Layer is a layer of a map. Only tile layers are drawn. Group layers are
+// replaced by the layers in them when the map is loaded.
+type Layer struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"` // "tilelayer", "objectgroup", "imagelayer" or "group"
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Visible bool    `json:"visible"`
+	Opacity float32 `json:"opacity"`
+	OffsetX float32 `json:"offsetx"`
+	OffsetY float32 `json:"offsety"`
+
+	// Global tile IDs, row by row from the top left, after decoding.
+	Data []uint32 `json:"-"`
+
+	RawData     json.RawMessage `json:"data"` // an array, or a base64 string
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+
+	Layers []*Layer `json:"layers"` // for a group
+}
+
+// Tileset is an image with tiles of equal size.
+type Tileset struct {
+	FirstGID    int    `json:"firstgid"`
+	Source      string `json:"source"` // external tileset file
+	Name        string `json:"name"`
+	Image       string `json:"image"`
+	ImageWidth  int    `json:"imagewidth"`
+	ImageHeight int    `json:"imageheight"`
+	TileWidth   int    `json:"tilewidth"`
+	TileHeight  int    `json:"tileheight"`
+	TileCount   int    `json:"tilecount"`
+	Columns     int    `json:"columns"`
+	Margin      int    `json:"margin"`
+	Spacing     int    `json:"spacing"`
+
+	// The texture with the image. If it is set before creating a
+	// Renderer, the image isn't loaded.
+	Texture uint32 `json:"-"`
+}
+
+// Load reads a map in the JSON format of Tiled, with external tilesets in
+// JSON too.
+func Load(filename string) (*Map, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m := &Map{Dir: filepath.Dir(filename)}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if err := m.init(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return m, nil
+}
+
+func (m *Map) init() error {
+	if m.Orientation != "" && m.Orientation != "orthogonal" {
+		return fmt.Errorf("%s maps are not supported", m.Orientation)
+	}
+	if m.Infinite {
+		return fmt.Errorf("infinite maps are not supported")
+	}
+
+	for i, ts := range m.Tilesets {
+		if ts.Source == "" {
+			continue
+		}
+		ext, err := loadTileset(filepath.Join(m.Dir, ts.Source))
+		if err != nil {
+			return err
+		}
+		ext.FirstGID = ts.FirstGID
+		// images in an external tileset are relative to that file
+		if ext.Image != "" && !filepath.IsAbs(ext.Image) {
+			ext.Image = filepath.Join(filepath.Dir(ts.Source), ext.Image)
+		}
+		m.Tilesets[i] = ext
+	}
+
+	layers, err := flatten(m.Layers)
+	if err != nil {
+		return err
+	}
+	m.Layers = layers
+	return nil
+}
+
+func loadTileset(filename string) (*Tileset, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	ts := &Tileset{}
+	if err := json.Unmarshal(data, ts); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return ts, nil
+}
+
+// flatten returns the tile layers, with the layers in groups in their
+// place, and decodes their data. The offset and opacity of a group apply
+// to its layers.
+func flatten(layers []*Layer) ([]*Layer, error) {
+	var result []*Layer
+	for _, l := range layers {
+		switch l.Type {
+		case "group":
+			sub, err := flatten(l.Layers)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range sub {
+				s.OffsetX += l.OffsetX
+				s.OffsetY += l.OffsetY
+				s.Opacity *= l.Opacity
+				s.Visible = s.Visible && l.Visible
+			}
+			result = append(result, sub...)
+		case "tilelayer":
+			if err := l.decode(); err != nil {
+				return nil, fmt.Errorf("layer %q: %v", l.Name, err)
+			}
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+// decode sets Data from RawData.
+func (l *Layer) decode() error {
+	n := l.Width * l.Height
+	if l.Encoding == "" || l.Encoding == "csv" {
+		if err := json.Unmarshal(l.RawData, &l.Data); err != nil {
+			return err
+		}
+	} else if l.Encoding == "base64" {
+		var s string
+		if err := json.Unmarshal(l.RawData, &s); err != nil {
+			return err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		var r io.Reader = bytes.NewReader(b)
+		switch l.Compression {
+		case "":
+		case "zlib":
+			r, err = zlib.NewReader(r)
+		case "gzip":
+			r, err = gzip.NewReader(r)
+		default:
+			err = fmt.Errorf("unsupported compression %q", l.Compression)
+		}
+		if err != nil {
+			return err
+		}
+		l.Data = make([]uint32, n)
+		// little-endian unsigned 32-bit integers
+		if err := binary.Read(r, binary.LittleEndian, l.Data); err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("unsupported encoding %q", l.Encoding)
+	}
+	if len(l.Data) != n {
+		return fmt.Errorf("%d tiles, expected %d", len(l.Data), n)
+	}
+	l.RawData = nil
+	return nil
+}
+
+// Tile returns the global tile ID at column x and row y of the layer,
+// without the flip flags, and the flags separately.
+func (l *Layer) Tile(x, y int) (gid, flags uint32) {
+	v := l.Data[y*l.Width+x]
+	return v &^ flipMask, v & flipMask
+}
+
+// Tileset returns the tileset that a global tile ID belongs to, or nil
+// for no tile.
+func (m *Map) Tileset(gid uint32) *Tileset {
+	var found *Tileset
+	for _, ts := range m.Tilesets {
+		if gid >= uint32(ts.FirstGID) && (found == nil || ts.FirstGID > found.FirstGID) {
+			found = ts
+		}
+	}
+	return found
+}
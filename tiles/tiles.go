@@ -0,0 +1,407 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/input"
+	"github.com/pebbe/gl/postfx"
+	"github.com/pebbe/gl/tilemap"
+
+	"flag"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+var (
+	mapFile = flag.String("map", "", "map exported from Tiled as JSON, instead of a generated one")
+	mapSize = flag.Int("size", 256, "width and height in tiles of the generated map")
+)
+
+// Extra actions for this demo.
+const (
+	actionCulling = "culling"
+	actionLayer   = "layer"
+)
+
+//
+// The generated map
+//
+
+const tileSize = 32
+
+// Tiles of the generated tileset, by local ID.
+const (
+	tileWater = iota
+	tileSand
+	tileGrass
+	tileForest
+	tileRock
+	tileSnow
+	tileTree
+	tileFlower
+)
+
+// makeTileset returns an image with eight tiles in two rows: the ground
+// types, and two decorations with a transparent background.
+func makeTileset() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4*tileSize, 2*tileSize))
+	ground := []color.RGBA{
+		{40, 90, 170, 255},
+		{220, 200, 140, 255},
+		{90, 160, 70, 255},
+		{50, 110, 50, 255},
+		{120, 115, 110, 255},
+		{240, 240, 250, 255},
+	}
+	for t := 0; t < 8; t++ {
+		ox, oy := (t%4)*tileSize, (t/4)*tileSize
+		for j := 0; j < tileSize; j++ {
+			for i := 0; i < tileSize; i++ {
+				x := 2*(float64(i)+.5)/tileSize - 1
+				y := 2*(float64(j)+.5)/tileSize - 1
+				var c color.RGBA
+				switch t {
+				case tileTree:
+					// a crown above a trunk, lit from the left
+					if math.Hypot(x, y+.2) < .7 {
+						c = shade(color.RGBA{30, 120, 40, 255}, 1-.3*(x+y+.2))
+					} else if math.Abs(x) < .12 && y > 0 {
+						c = color.RGBA{100, 70, 40, 255}
+					}
+				case tileFlower:
+					a := math.Atan2(y, x)
+					r := math.Hypot(x, y)
+					if r < .15 {
+						c = color.RGBA{250, 220, 50, 255}
+					} else if r < .3+.15*math.Cos(5*a) {
+						c = color.RGBA{230, 80, 120, 255}
+					}
+				default:
+					// a little variation, so the grid of tiles shows
+					c = shade(ground[t], .9+.2*rand.Float64())
+				}
+				img.SetRGBA(ox+i, oy+j, c)
+			}
+		}
+	}
+	return img
+}
+
+func shade(c color.RGBA, f float64) color.RGBA {
+	s := func(v uint8) uint8 { return uint8(math.Min(255, float64(v)*f)) }
+	return color.RGBA{s(c.R), s(c.G), s(c.B), c.A}
+}
+
+// makeMap returns a map with an island of n by n tiles, with a ground
+// layer and a layer with trees and flowers.
+func makeMap(n int) *tilemap.Map {
+	heights := valueNoise(n)
+
+	newLayer := func(name string) *tilemap.Layer {
+		return &tilemap.Layer{
+			Name:    name,
+			Type:    "tilelayer",
+			Width:   n,
+			Height:  n,
+			Visible: true,
+			Opacity: 1,
+			Data:    make([]uint32, n*n),
+		}
+	}
+	ground := newLayer("ground")
+	decoration := newLayer("decoration")
+
+	// global IDs start at 1
+	const first = 1
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			// lower towards the edges, so there is sea around
+			dx, dy := 2*float64(x)/float64(n)-1, 2*float64(y)/float64(n)-1
+			h := heights[y*n+x] - .5*(dx*dx+dy*dy)
+			var t uint32
+			switch {
+			case h < .15:
+				t = tileWater
+			case h < .2:
+				t = tileSand
+			case h < .35:
+				t = tileGrass
+			case h < .5:
+				t = tileForest
+			case h < .6:
+				t = tileRock
+			default:
+				t = tileSnow
+			}
+			ground.Data[y*n+x] = first + t
+
+			r := rand.Float64()
+			switch {
+			case t == tileForest && r < .5, t == tileGrass && r < .05:
+				d := uint32(first + tileTree)
+				if rand.Intn(2) == 0 {
+					d |= tilemap.FlipHorizontal
+				}
+				decoration.Data[y*n+x] = d
+			case t == tileGrass && r < .1:
+				decoration.Data[y*n+x] = first + tileFlower
+			}
+		}
+	}
+
+	img := makeTileset()
+	texture := glutil.MakeTextureFromImage(img)
+	// no blending with neighbouring tiles
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+
+	return &tilemap.Map{
+		Width:      n,
+		Height:     n,
+		TileWidth:  tileSize,
+		TileHeight: tileSize,
+		Layers:     []*tilemap.Layer{ground, decoration},
+		Tilesets: []*tilemap.Tileset{{
+			FirstGID:    first,
+			Name:        "generated",
+			ImageWidth:  img.Rect.Dx(),
+			ImageHeight: img.Rect.Dy(),
+			TileWidth:   tileSize,
+			TileHeight:  tileSize,
+			TileCount:   8,
+			Columns:     4,
+			Texture:     texture,
+		}},
+	}
+}
+
+// valueNoise returns n by n values from 0 to 1, from several octaves of
+// value noise.
+func valueNoise(n int) []float64 {
+	h := make([]float64, n*n)
+	amplitude := .5
+	for cells := 4; cells <= n/2; cells *= 2 {
+		grid := make([]float64, (cells+1)*(cells+1))
+		for i := range grid {
+			grid[i] = rand.Float64()
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				fx := float64(x) * float64(cells) / float64(n)
+				fy := float64(y) * float64(cells) / float64(n)
+				ix, iy := int(fx), int(fy)
+				tx, ty := smooth(fx-float64(ix)), smooth(fy-float64(iy))
+				g := func(i, j int) float64 { return grid[(iy+j)*(cells+1)+ix+i] }
+				v := lerp(lerp(g(0, 0), g(1, 0), tx), lerp(g(0, 1), g(1, 1), tx), ty)
+				h[y*n+x] += amplitude * v
+			}
+		}
+		amplitude /= 2
+	}
+	return h
+}
+
+func smooth(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	tiles *tilemap.Renderer
+
+	effects *postfx.Chain // nil without -crt
+}
+
+func makeResources() *gResources {
+	r := &gResources{}
+
+	var m *tilemap.Map
+	var err error
+	if *mapFile != "" {
+		m, err = tilemap.Load(*mapFile)
+		x(err)
+	} else {
+		m = makeMap(*mapSize)
+	}
+
+	r.tiles, err = tilemap.NewRenderer(m)
+	x(err)
+
+	r.effects, err = postfx.OptionalCRT()
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	// the point of the map at the center of the window, in map pixels
+	center glm.Vec2
+
+	zoom    = input.NewZoom(.1, 4)
+	drag    input.Drag
+	culling = true
+
+	lastUpdate = time.Now()
+	lastPrint  = time.Now()
+)
+
+// Scroll speed in window pixels per second.
+const scrollSpeed = 600
+
+// update scrolls with the arrow keys or W, A, S and D.
+func update(w *glfw.Window) {
+	now := time.Now()
+	dt := float32(now.Sub(lastUpdate).Seconds())
+	lastUpdate = now
+
+	step := scrollSpeed * dt / float32(zoom.Scale())
+	for _, k := range []struct {
+		keys   []glfw.Key
+		dx, dy float32
+	}{
+		{[]glfw.Key{glfw.KeyLeft, glfw.KeyA}, -1, 0},
+		{[]glfw.Key{glfw.KeyRight, glfw.KeyD}, 1, 0},
+		{[]glfw.Key{glfw.KeyUp, glfw.KeyW}, 0, -1},
+		{[]glfw.Key{glfw.KeyDown, glfw.KeyS}, 0, 1},
+	} {
+		for _, key := range k.keys {
+			if w.GetKey(key) == glfw.Press {
+				center = center.Add(glm.Vec2{k.dx * step, k.dy * step})
+				break
+			}
+		}
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	x(r.effects.Begin(int32(width), int32(height)))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.1, .1, .1, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// one map pixel is zoom screen pixels
+	sw, sh := w.GetSize()
+	s := float32(zoom.Scale())
+	x0, x1 := center[0]-float32(sw)/2/s, center[0]+float32(sw)/2/s
+	y0, y1 := center[1]-float32(sh)/2/s, center[1]+float32(sh)/2/s
+	projection := glm.Ortho(x0, x1, y1, y0, -1, 1)
+
+	m := r.tiles.Map
+	if culling {
+		r.tiles.Draw(projection, x0, y0, x1, y1)
+	} else {
+		r.tiles.Draw(projection, 0, 0, float32(m.Width*m.TileWidth), float32(m.Height*m.TileHeight))
+	}
+
+	x(r.effects.End(nil))
+
+	if time.Since(lastPrint) >= time.Second {
+		app.Infof("Chunks drawn: %d of %d", r.tiles.ChunksDrawn, r.tiles.ChunksTotal)
+		lastPrint = time.Now()
+	}
+}
+
+func main() {
+	app.Keys[actionCulling] = []string{"c"}
+	app.Keys[actionLayer] = []string{"l"}
+	app.Flags(800, 600, "Tile map")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	m := r.tiles.Map
+	center = glm.Vec2{float32(m.Width*m.TileWidth) / 2, float32(m.Height*m.TileHeight) / 2}
+
+	app.OnAction(w, func(w *glfw.Window, action string) { onAction(w, r, action) })
+	drag.OnMove = func(x, y, dx, dy float64) {
+		s := float32(zoom.Scale())
+		center = center.Sub(glm.Vec2{float32(dx) / s, float32(dy) / s})
+	}
+	w.SetMouseButtonCallback(drag.MouseButton)
+	w.SetCursorPosCallback(drag.CursorPos)
+	w.SetScrollCallback(zoom.Scroll)
+
+	app.Infof("%d x %d tiles, %d layers, %d tilesets", m.Width, m.Height, len(m.Layers), len(m.Tilesets))
+	app.Info("Drag with the mouse or use the arrow keys to scroll, scroll to zoom")
+	app.Info("Press 'c' to toggle culling of chunks, 'l' to toggle the top layer")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, r *gResources, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionCulling:
+		culling = !culling
+		if culling {
+			app.Info("Drawing visible chunks")
+		} else {
+			app.Info("Drawing all chunks")
+		}
+	case actionLayer:
+		layers := r.tiles.Map.Layers
+		if len(layers) > 1 {
+			l := layers[len(layers)-1]
+			l.Visible = !l.Visible
+		}
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"runtime"
+	"sort"
+	"time"
+)
+
+var (
+	vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    gl_Position = projection * view * model * vec4(position, 1.0);
+    worldNormal = mat3(model) * normal;
+}
+` + "\x00"
+
+	fragment_glsl = `
+#version 330 core
+
+uniform vec4 color;
+uniform bool lit;
+
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    float light = 1.0;
+    if (lit) {
+        light = 0.4 + 0.6 * max(dot(normalize(worldNormal), normalize(vec3(0.5, 1.0, 0.7))), 0.0);
+    }
+    fragColor = vec4(light * color.rgb, color.a);
+}
+` + "\x00"
+)
+
+// Extra actions for this demo.
+const (
+	actionDepthWrite = "depthwrite"
+	actionSort       = "sort"
+	actionCoverage   = "coverage"
+)
+
+//
+// The scene: an opaque cube between translucent quads
+//
+
+type tQuad struct {
+	position glm.Vec3
+	color    glm.Vec4
+}
+
+var quads = []tQuad{
+	{glm.Vec3{-.9, 0, -1.6}, glm.Vec4{1, .2, .2, .5}},
+	{glm.Vec3{-.3, .3, -.8}, glm.Vec4{.2, 1, .2, .5}},
+	{glm.Vec3{.3, -.2, 0}, glm.Vec4{.2, .4, 1, .5}},
+	{glm.Vec3{.9, .2, .8}, glm.Vec4{1, 1, .2, .5}},
+	{glm.Vec3{0, -.4, 1.6}, glm.Vec4{1, .2, 1, .5}},
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program *glutil.Program
+	quad    *mesh.VAO
+	cube    *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		quad: mesh.Quad().Upload(),
+		cube: mesh.Cube().Upload(),
+	}
+
+	var err error
+	r.program, err = glutil.NewProgram(vertex_glsl, fragment_glsl)
+	x(err)
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   *camera.Orbit
+
+	depthWrite = false
+	sorted     = true
+	coverage   = false
+)
+
+var stateOpaque = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+// The state for the quads, depending on the settings.
+func stateTranslucent() glutil.State {
+	if coverage {
+		// No blending: each quad covers some of the samples of a pixel,
+		// and the depth test picks the nearest for each sample.
+		return glutil.State{
+			DepthTest:       true,
+			AlphaToCoverage: true,
+		}
+	}
+	return glutil.State{
+		DepthTest:    true,
+		NoDepthWrite: !depthWrite,
+		Blend:        true,
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.ClearColor(app.Background(.15, .15, .15, 0))
+	stateOpaque.Apply()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	view := cam.View()
+	projection := glm.Perspective(glm.Radians(45), float32(width)/float32(height), .1, 100)
+	spin := glm.Rotate(float32(clock.Seconds())/3, glm.Vec3{0, 1, 0})
+
+	p := r.program
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+
+	// opaque objects first
+	p.SetMat4("model", spin.Mul(glm.Scale(.3, .3, .3)))
+	p.SetVec4("color", glm.Vec4{.8, .8, .8, 1})
+	p.SetBool("lit", true)
+	r.cube.Draw()
+
+	// then the translucent ones, furthest away first if sorted
+	models := make([]glm.Mat4, len(quads))
+	order := make([]int, len(quads))
+	for i, q := range quads {
+		models[i] = spin.Mul(glm.Translate(q.position[0], q.position[1], q.position[2])).Mul(glm.Scale(.6, .6, .6))
+		order[i] = i
+	}
+	if sorted {
+		// distance along the view direction: more negative is further
+		z := func(i int) float32 { return view.Mul(models[i]).Transform(glm.Vec3{})[2] }
+		sort.Slice(order, func(i, j int) bool { return z(order[i]) < z(order[j]) })
+	}
+
+	stateTranslucent().Apply()
+	p.SetBool("lit", false)
+	for _, i := range order {
+		p.SetMat4("model", models[i])
+		p.SetVec4("color", quads[i].color)
+		r.quad.Draw()
+	}
+}
+
+func main() {
+	app.Keys[actionDepthWrite] = []string{"d"}
+	app.Keys[actionSort] = []string{"s"}
+	app.Keys[actionCoverage] = []string{"a"}
+	app.Flags(640, 480, "Transparency")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	glfw.WindowHint(glfw.Samples, 4)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewOrbit(glm.Vec3{}, 6)
+	cam.Pitch = .3
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+	var samples int32
+	gl.GetIntegerv(gl.SAMPLES, &samples)
+	app.Debugf("Samples per pixel: %d", samples)
+	printSettings()
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 'd' to toggle depth write, 's' to toggle sorting, 'a' to toggle alpha to coverage")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func printSettings() {
+	if coverage {
+		app.Info("Alpha to coverage, no blending, no sorting needed")
+		return
+	}
+	app.Infof("Blending, depth write: %v, sorted: %v", depthWrite, sorted)
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case actionDepthWrite:
+		depthWrite = !depthWrite
+		printSettings()
+	case actionSort:
+		sorted = !sorted
+		printSettings()
+	case actionCoverage:
+		coverage = !coverage
+		printSettings()
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
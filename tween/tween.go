@@ -0,0 +1,273 @@
+// Package tween animates values over time, declaratively: an animation is
+// built once from tweens, each of which moves a variable from one value to
+// another with an easing function, and combined in sequences, in parallel
+// and in loops. Each frame, Seek sets all variables for the time:
+//
+//	blink := tween.Loop(tween.Sequence(
+//		tween.Float32(&alpha, 0, 1, .5, easing.OutQuad),
+//		tween.Wait(1),
+//		tween.Float32(&alpha, 1, 0, .5, easing.InQuad),
+//	), 0)
+//	...
+//	blink.Seek(clock.Seconds())
+//
+// As Seek takes the time since the start, rather than the time since the
+// previous frame, animations can be paused, stepped, and run faster or
+// slower with an app.Clock, and even backwards.
+package tween
+
+import (
+	"github.com/pebbe/gl/easing"
+	"github.com/pebbe/gl/glm"
+
+	"math"
+)
+
+// Animation is a tween, or a combination of animations.
+type Animation interface {
+	// Duration returns the length in seconds, which is infinite for an
+	// endless loop.
+	Duration() float64
+
+	// Seek sets the animated variables for t seconds after the start.
+	// Before the start, they have their first values, and after the end,
+	// their last.
+	Seek(t float64)
+}
+
+// Tween moves a variable from one value to another.
+type Tween[T any] struct {
+	target   *T
+	from, to T
+	duration float64
+	ease     easing.Func
+	lerp     func(a, b T, t float64) T
+}
+
+// New returns a tween of target from one value to the other in a duration
+// of seconds, with the easing function, or easing.Linear if nil. The values
+// in between are computed with lerp, for t from 0 to 1, or beyond 1 for
+// easing functions that overshoot.
+func New[T any](target *T, from, to T, seconds float64, ease easing.Func, lerp func(a, b T, t float64) T) *Tween[T] {
+	if ease == nil {
+		ease = easing.Linear
+	}
+	return &Tween[T]{
+		target:   target,
+		from:     from,
+		to:       to,
+		duration: math.Max(seconds, 0),
+		ease:     ease,
+		lerp:     lerp,
+	}
+}
+
+// Duration implements Animation.
+func (tw *Tween[T]) Duration() float64 {
+	return tw.duration
+}
+
+// Seek implements Animation.
+func (tw *Tween[T]) Seek(t float64) {
+	p := 1.0
+	if tw.duration > 0 {
+		p = math.Max(0, math.Min(1, t/tw.duration))
+	}
+	*tw.target = tw.lerp(tw.from, tw.to, tw.ease(p))
+}
+
+// Float returns a tween of a float64.
+func Float(target *float64, from, to float64, seconds float64, ease easing.Func) *Tween[float64] {
+	return New(target, from, to, seconds, ease, func(a, b float64, t float64) float64 {
+		return a + (b-a)*t
+	})
+}
+
+// Float32 returns a tween of a float32.
+func Float32(target *float32, from, to float32, seconds float64, ease easing.Func) *Tween[float32] {
+	return New(target, from, to, seconds, ease, func(a, b float32, t float64) float32 {
+		return a + (b-a)*float32(t)
+	})
+}
+
+// Vec2 returns a tween of a glm.Vec2.
+func Vec2(target *glm.Vec2, from, to glm.Vec2, seconds float64, ease easing.Func) *Tween[glm.Vec2] {
+	return New(target, from, to, seconds, ease, func(a, b glm.Vec2, t float64) glm.Vec2 {
+		return a.Add(b.Sub(a).Mul(float32(t)))
+	})
+}
+
+// Vec3 returns a tween of a glm.Vec3.
+func Vec3(target *glm.Vec3, from, to glm.Vec3, seconds float64, ease easing.Func) *Tween[glm.Vec3] {
+	return New(target, from, to, seconds, ease, func(a, b glm.Vec3, t float64) glm.Vec3 {
+		return a.Lerp(b, float32(t))
+	})
+}
+
+// Vec4 returns a tween of a glm.Vec4.
+func Vec4(target *glm.Vec4, from, to glm.Vec4, seconds float64, ease easing.Func) *Tween[glm.Vec4] {
+	return New(target, from, to, seconds, ease, func(a, b glm.Vec4, t float64) glm.Vec4 {
+		var v glm.Vec4
+		for i := range v {
+			v[i] = a[i] + (b[i]-a[i])*float32(t)
+		}
+		return v
+	})
+}
+
+// Color returns a tween of an sRGB color. It is interpolated in linear
+// light, so that halfway between two colors is as bright as it looks, with
+// no dark band when fading from red to green.
+func Color(target *glm.Vec3, from, to glm.Vec3, seconds float64, ease easing.Func) *Tween[glm.Vec3] {
+	return New(target, from, to, seconds, ease, func(a, b glm.Vec3, t float64) glm.Vec3 {
+		var v glm.Vec3
+		for i := range v {
+			la := math.Pow(float64(a[i]), 2.2)
+			lb := math.Pow(float64(b[i]), 2.2)
+			v[i] = float32(math.Pow(math.Max(la+(lb-la)*t, 0), 1/2.2))
+		}
+		return v
+	})
+}
+
+// wait is an animation that does nothing.
+type wait float64
+
+// Wait returns an animation that does nothing for a number of seconds, for
+// a pause in a sequence.
+func Wait(seconds float64) Animation {
+	return wait(math.Max(seconds, 0))
+}
+
+func (w wait) Duration() float64 {
+	return float64(w)
+}
+
+func (w wait) Seek(t float64) {}
+
+// sequence plays animations one after another.
+type sequence struct {
+	animations []Animation
+	starts     []float64
+	duration   float64
+}
+
+// Sequence returns an animation that plays the animations one after
+// another. Several of them may animate the same variable.
+func Sequence(animations ...Animation) Animation {
+	s := &sequence{
+		animations: animations,
+		starts:     make([]float64, len(animations)),
+	}
+	for i, a := range animations {
+		s.starts[i] = s.duration
+		s.duration += a.Duration()
+	}
+	return s
+}
+
+func (s *sequence) Duration() float64 {
+	return s.duration
+}
+
+// Seek sets the animations that haven't started yet to their start, last
+// to first, and then those that have, first to last, so the variables end
+// up with the values of the latest animation that animates them.
+func (s *sequence) Seek(t float64) {
+	for i := len(s.animations) - 1; i >= 0; i-- {
+		if t < s.starts[i] {
+			s.animations[i].Seek(0)
+		}
+	}
+	for i, a := range s.animations {
+		if t >= s.starts[i] {
+			a.Seek(t - s.starts[i])
+		}
+	}
+}
+
+// parallel plays animations at the same time.
+type parallel []Animation
+
+// Parallel returns an animation that plays the animations at the same
+// time. It lasts as long as the longest of them.
+func Parallel(animations ...Animation) Animation {
+	return parallel(animations)
+}
+
+func (p parallel) Duration() float64 {
+	d := 0.0
+	for _, a := range p {
+		d = math.Max(d, a.Duration())
+	}
+	return d
+}
+
+func (p parallel) Seek(t float64) {
+	for _, a := range p {
+		a.Seek(t)
+	}
+}
+
+// yoyo plays an animation forward and then backward.
+type yoyo struct {
+	Animation
+}
+
+// Yoyo returns an animation that plays the animation forward, and then
+// backward, which takes twice as long.
+func Yoyo(a Animation) Animation {
+	return yoyo{a}
+}
+
+func (y yoyo) Duration() float64 {
+	return 2 * y.Animation.Duration()
+}
+
+func (y yoyo) Seek(t float64) {
+	d := y.Animation.Duration()
+	if t > d {
+		t = 2*d - t
+	}
+	y.Animation.Seek(t)
+}
+
+// Looping is an animation that is played a number of times.
+type Looping struct {
+	animation Animation
+	times     int
+
+	// The number of the current iteration, from 0, as set by Seek.
+	Iteration int
+}
+
+// Loop returns an animation that plays the animation a number of times, or
+// endlessly if times is 0. An endless loop can also be played backward,
+// before time 0.
+func Loop(a Animation, times int) *Looping {
+	return &Looping{animation: a, times: times}
+}
+
+// Duration implements Animation.
+func (l *Looping) Duration() float64 {
+	if l.times <= 0 {
+		return math.Inf(1)
+	}
+	return float64(l.times) * l.animation.Duration()
+}
+
+// Seek implements Animation.
+func (l *Looping) Seek(t float64) {
+	d := l.animation.Duration()
+	if d <= 0 || math.IsInf(d, 1) {
+		l.Iteration = 0
+		l.animation.Seek(t)
+		return
+	}
+	n := math.Floor(t / d)
+	if l.times > 0 {
+		n = math.Max(0, math.Min(n, float64(l.times-1)))
+	}
+	l.Iteration = int(n)
+	l.animation.Seek(t - n*d)
+}
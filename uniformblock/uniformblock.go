@@ -0,0 +1,334 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+	"github.com/pebbe/gl/stereo"
+
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// The uniform block shared by all programs. It is updated once per frame,
+// instead of setting the same uniforms in each program.
+const frame_glsl = `
+layout(std140) uniform Frame {
+    mat4 view;
+    mat4 projection;
+    vec3 eye;
+    float time;
+};
+`
+
+// The same block in Go. With std140, the vec3 takes 12 bytes, and the
+// float after it fills the last 4 bytes of its 16.
+type tFrame struct {
+	View       glm.Mat4
+	Projection glm.Mat4
+	Eye        glm.Vec3
+	Time       float32
+}
+
+// The binding point of the Frame block.
+const frameBinding = 0
+
+var (
+	//
+	// lit objects
+	//
+	lit_vertex_glsl = `
+#version 330 core
+` + frame_glsl + `
+uniform mat4 model;
+uniform mat3 normalMatrix;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+}
+` + "\x00"
+	lit_fragment_glsl = `
+#version 330 core
+` + frame_glsl + `
+uniform vec3 color;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    vec3 l = normalize(vec3(cos(time), 1.0, sin(time)));
+    vec3 v = normalize(eye - worldPosition);
+    float diffuse = max(dot(n, l), 0.0);
+    float specular = pow(max(dot(n, normalize(l + v)), 0.0), 40.0);
+    fragColor = vec4((0.2 + 0.8 * diffuse) * color + 0.5 * specular, 1.0);
+}
+` + "\x00"
+
+	//
+	// a sphere that wobbles over time
+	//
+	wobble_vertex_glsl = `
+#version 330 core
+` + frame_glsl + `
+uniform mat4 model;
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldNormal;
+
+void main()
+{
+    float w = 1.0 + 0.15 * sin(4.0 * position.y + 3.0 * time) * sin(4.0 * position.x + 2.0 * time);
+    gl_Position = projection * view * model * vec4(w * position, 1.0);
+    worldNormal = mat3(model) * normal;
+}
+` + "\x00"
+	wobble_fragment_glsl = `
+#version 330 core
+` + frame_glsl + `
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 n = normalize(worldNormal);
+    fragColor = vec4(0.5 + 0.5 * n * vec3(cos(time), 1.0, sin(time)), 1.0);
+}
+` + "\x00"
+
+	//
+	// the floor, a grid that fades with the distance to the eye
+	//
+	floor_vertex_glsl = `
+#version 330 core
+` + frame_glsl + `
+layout(location = 0) in vec3 position;
+
+out vec3 worldPosition;
+
+void main()
+{
+    // the quad is in the xy plane, the floor in the xz plane
+    worldPosition = vec3(20.0 * position.x, -1.0, -20.0 * position.y);
+    gl_Position = projection * view * vec4(worldPosition, 1.0);
+}
+` + "\x00"
+	floor_fragment_glsl = `
+#version 330 core
+` + frame_glsl + `
+in vec3 worldPosition;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec2 p = worldPosition.xz;
+    vec2 g = abs(fract(p - 0.5) - 0.5) / fwidth(p);
+    float line = 1.0 - min(min(g.x, g.y), 1.0);
+    float fade = exp(-0.05 * length(worldPosition - eye));
+    fragColor = vec4(mix(vec3(0.15), vec3(0.8), line * fade), 1.0);
+}
+` + "\x00"
+)
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	lit    *glutil.Program
+	wobble *glutil.Program
+	floor  *glutil.Program
+
+	frame *glutil.UniformBuffer
+
+	stereo *stereo.Renderer
+
+	cube   *mesh.VAO
+	sphere *mesh.VAO
+	quad   *mesh.VAO
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		cube:   mesh.Cube().Upload(),
+		sphere: mesh.Sphere(48, 24).Upload(),
+		quad:   mesh.Quad().Upload(),
+	}
+
+	var err error
+	r.lit, err = glutil.NewProgram(lit_vertex_glsl, lit_fragment_glsl)
+	x(err)
+	r.wobble, err = glutil.NewProgram(wobble_vertex_glsl, wobble_fragment_glsl)
+	x(err)
+	r.floor, err = glutil.NewProgram(floor_vertex_glsl, floor_fragment_glsl)
+	x(err)
+
+	mode, err := stereo.FlagMode()
+	x(err)
+	r.stereo, err = stereo.New(mode)
+	x(err)
+
+	// one buffer, at one binding point, for all programs
+	r.frame = glutil.NewUniformBuffer(frameBinding, int(unsafe.Sizeof(tFrame{})))
+	for _, p := range []*glutil.Program{r.lit, r.wobble, r.floor} {
+		p.BindUniformBlock("Frame", frameBinding)
+	}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   *camera.Orbit
+)
+
+var stateScene = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	r.stereo.Convergence = cam.Distance
+	err := r.stereo.Render(int32(width), int32(height), cam.View(), glm.Radians(45), .1, 100, func(view, projection glm.Mat4) {
+		drawScene(r, view, projection)
+	})
+	x(err)
+}
+
+func drawScene(r *gResources, view, projection glm.Mat4) {
+	stateScene.Apply()
+	gl.ClearColor(app.Background(.1, .1, .15, 0))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// the only place where the camera and the time are passed to the shaders
+	frame := tFrame{
+		View:       view,
+		Projection: projection,
+		Eye:        cam.Eye(),
+		Time:       float32(clock.Seconds()),
+	}
+	r.frame.Update(unsafe.Pointer(&frame), int(unsafe.Sizeof(frame)))
+
+	r.floor.Use()
+	r.quad.Draw()
+
+	p := r.lit
+	p.Use()
+	for i, c := range []glm.Vec3{{.9, .3, .2}, {.2, .7, .3}, {.2, .4, .9}} {
+		model := glm.Translate(float32(i-1)*2.5, 0, -2).
+			Mul(glm.Rotate(frame.Time/2+float32(i), glm.Vec3{1, 1, 0}.Normalize())).
+			Mul(glm.Scale(.6, .6, .6))
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetVec3("color", c)
+		r.cube.Draw()
+	}
+
+	p = r.wobble
+	p.Use()
+	p.SetMat4("model", glm.Translate(0, 0, 1.5))
+	r.sphere.Draw()
+}
+
+func main() {
+	app.Flags(640, 480, "Uniform block")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	cam = camera.NewOrbit(glm.Vec3{}, 8)
+	cam.Pitch = .4
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+// A raw volume file has no header, so the size must be given, for example
+// for the bonsai of the Open SciVis Datasets:
+//
+//	volume -file bonsai_256x256x256_uint8.raw -size 256x256x256
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/asset"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	// Ray casting: along the ray through each pixel, the volume is sampled
+	// at even steps, from front to back. The transfer function gives the
+	// color and opacity of each value, and these are blended until the
+	// ray is opaque.
+	//
+	// The slices of the volume are stacked upwards, as in most scans, so
+	// x, y and z of the volume are x, -z and y in the world.
+	fragment_glsl = `
+#version 330 core
+
+uniform sampler3D volume;
+uniform sampler2D transfer;
+uniform float transferRow; // texture coordinate of the transfer function
+uniform mat4 cameraToWorld;
+uniform vec2 resolution;
+uniform float tanHalfFov;
+uniform vec3 halfSize; // of the box with the volume, along the axes of the volume
+uniform vec3 voxel;    // size of a voxel in texture coordinates
+uniform int steps;     // along the diagonal of the box
+uniform int mode;      // 0 for blending, 1 for maximum intensity projection
+uniform bool lighting;
+
+out vec4 fragColor;
+
+const vec3 background = vec3(0.1, 0.1, 0.12);
+
+// the opacity in the transfer function is for steps of this length
+const float REFERENCE_STEP = 1.0 / 256.0;
+
+vec3 toTexture(vec3 p)
+{
+    return vec3(p.x, -p.z, p.y) / (2.0 * halfSize) + 0.5;
+}
+
+// the gradient of the values, in world coordinates
+vec3 gradient(vec3 uvw)
+{
+    vec3 g = vec3(
+        texture(volume, uvw + vec3(voxel.x, 0.0, 0.0)).r - texture(volume, uvw - vec3(voxel.x, 0.0, 0.0)).r,
+        texture(volume, uvw + vec3(0.0, voxel.y, 0.0)).r - texture(volume, uvw - vec3(0.0, voxel.y, 0.0)).r,
+        texture(volume, uvw + vec3(0.0, 0.0, voxel.z)).r - texture(volume, uvw - vec3(0.0, 0.0, voxel.z)).r);
+    // the samples are two voxels apart, which is 4 * voxel * halfSize in
+    // the world
+    g /= 4.0 * voxel * halfSize;
+    return vec3(g.x, g.z, -g.y);
+}
+
+void main()
+{
+    vec2 ndc = (2.0 * gl_FragCoord.xy - resolution) / resolution.y;
+    vec3 origin = cameraToWorld[3].xyz;
+    vec3 direction = normalize(mat3(cameraToWorld) * vec3(ndc * tanHalfFov, -1.0));
+
+    // where the ray enters and leaves the box
+    vec3 b = halfSize.xzy;
+    vec3 t1 = (-b - origin) / direction;
+    vec3 t2 = (b - origin) / direction;
+    vec3 tNear = min(t1, t2);
+    vec3 tFar = max(t1, t2);
+    float near = max(max(max(tNear.x, tNear.y), tNear.z), 0.0);
+    float far = min(min(tFar.x, tFar.y), tFar.z);
+    if (near >= far) {
+        fragColor = vec4(background, 1.0);
+        return;
+    }
+
+    float stepSize = 2.0 * length(halfSize) / float(steps);
+    // a random start within the first step, to hide the layers of samples
+    float t = near + stepSize * fract(sin(dot(gl_FragCoord.xy, vec2(12.9898, 78.233))) * 43758.5453);
+
+    vec4 sum = vec4(0.0);
+    float maxValue = 0.0;
+    for (int i = 0; i < 4 * steps && t < far; i++) {
+        vec3 uvw = toTexture(origin + t * direction);
+        t += stepSize;
+        float v = texture(volume, uvw).r;
+        if (mode == 1) {
+            maxValue = max(maxValue, v);
+            continue;
+        }
+
+        vec4 c = texture(transfer, vec2(v, transferRow));
+        if (c.a < 0.001) {
+            continue;
+        }
+        // the same opacity for the same distance, whatever the step size
+        c.a = 1.0 - pow(1.0 - c.a, stepSize / REFERENCE_STEP);
+        if (lighting) {
+            // a light at the camera, on surfaces where the value changes
+            vec3 g = gradient(uvw);
+            float len = length(g);
+            if (len > 0.01) {
+                c.rgb *= 0.3 + 0.7 * abs(dot(g / len, direction));
+            }
+        }
+        sum.rgb += (1.0 - sum.a) * c.a * c.rgb;
+        sum.a += (1.0 - sum.a) * c.a;
+        if (sum.a > 0.99) {
+            // nothing behind this can be seen
+            break;
+        }
+    }
+
+    if (mode == 1) {
+        fragColor = vec4(mix(background, vec3(1.0), maxValue), 1.0);
+    } else {
+        fragColor = vec4(sum.rgb + (1.0 - sum.a) * background, 1.0);
+    }
+}
+`
+)
+
+var (
+	file       = flag.String("file", "", "raw volume file, without it a generated volume is shown")
+	sizeFlag   = flag.String("size", "", "size of the raw volume, as WIDTHxHEIGHTxDEPTH")
+	bits       = flag.Int("bits", 8, "bits per value of the raw volume, 8 or 16 (little-endian)")
+	resolution = flag.Int("resolution", 128, "size of the generated volume in each direction")
+)
+
+// Extra actions for this demo.
+const (
+	actionMoreSteps  = "more"
+	actionFewerSteps = "fewer"
+	actionTransfer   = "transfer"
+	actionMode       = "mode"
+	actionLighting   = "lighting"
+)
+
+//
+// Transfer functions
+//
+
+// A transfer function is the color and opacity at some values, with
+// linear interpolation in between. The opacity is for a step of 1/256 of
+// the size of the volume.
+type transferFunction struct {
+	name   string
+	points []transferPoint
+}
+
+type transferPoint struct {
+	value float64
+	color glm.Vec4
+}
+
+var transferFunctions = []transferFunction{
+	{"gray", []transferPoint{
+		{0, glm.Vec4{0, 0, 0, 0}},
+		{.1, glm.Vec4{.2, .2, .2, 0}},
+		{1, glm.Vec4{1, 1, 1, .15}},
+	}},
+	{"tissue and bone", []transferPoint{
+		{0, glm.Vec4{0, 0, 0, 0}},
+		{.08, glm.Vec4{.8, .4, .3, 0}},
+		{.15, glm.Vec4{.9, .5, .4, .01}},
+		{.35, glm.Vec4{.9, .5, .4, .01}},
+		{.45, glm.Vec4{1, .95, .85, .3}},
+		{1, glm.Vec4{1, 1, 1, .8}},
+	}},
+	{"fire", []transferPoint{
+		{0, glm.Vec4{0, 0, 0, 0}},
+		{.1, glm.Vec4{.5, 0, 0, .005}},
+		{.4, glm.Vec4{1, .3, 0, .05}},
+		{.7, glm.Vec4{1, .8, .1, .2}},
+		{1, glm.Vec4{1, 1, .9, .5}},
+	}},
+	{"surfaces", []transferPoint{
+		{0, glm.Vec4{0, 0, 0, 0}},
+		{.45, glm.Vec4{.2, .5, 1, 0}},
+		{.5, glm.Vec4{.2, .5, 1, .6}},
+		{.55, glm.Vec4{.2, .5, 1, 0}},
+		{.85, glm.Vec4{1, .6, .2, 0}},
+		{.9, glm.Vec4{1, .6, .2, 1}},
+		{1, glm.Vec4{1, .6, .2, 1}},
+	}},
+}
+
+// transferImage returns an image with a row of 256 pixels for each
+// transfer function.
+func transferImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 256, len(transferFunctions)))
+	for row, tf := range transferFunctions {
+		for i := 0; i < 256; i++ {
+			v := float64(i) / 255
+			c := tf.points[len(tf.points)-1].color
+			for j := 1; j < len(tf.points); j++ {
+				p0, p1 := tf.points[j-1], tf.points[j]
+				if v <= p1.value {
+					f := float32((v - p0.value) / (p1.value - p0.value))
+					for k := range c {
+						c[k] = p0.color[k] + f*(p1.color[k]-p0.color[k])
+					}
+					break
+				}
+			}
+			img.SetRGBA(i, row, color.RGBA{
+				uint8(255*c[0] + .5),
+				uint8(255*c[1] + .5),
+				uint8(255*c[2] + .5),
+				uint8(255*c[3] + .5),
+			})
+		}
+	}
+	return img
+}
+
+//
+// The generated volume
+//
+
+func smoothstep(e0, e1, v float64) float64 {
+	t := math.Max(0, math.Min(1, (v-e0)/(e1-e0)))
+	return t * t * (3 - 2*t)
+}
+
+// generate returns a volume of n values in each direction: a shell, with a
+// torus and three balls inside, in a rippled haze.
+func generate(n int) *asset.Volume {
+	v := &asset.Volume{
+		Width:  n,
+		Height: n,
+		Depth:  n,
+		Data:   make([]float32, n*n*n),
+	}
+	balls := []glm.Vec3{{0, 0, .45}, {.3, -.2, -.3}, {-.35, .25, -.2}}
+	i := 0
+	for z := 0; z < n; z++ {
+		pz := 2*float64(z)/float64(n-1) - 1
+		for y := 0; y < n; y++ {
+			py := 2*float64(y)/float64(n-1) - 1
+			for xx := 0; xx < n; xx++ {
+				px := 2*float64(xx)/float64(n-1) - 1
+				r := math.Sqrt(px*px + py*py + pz*pz)
+
+				haze := .25 * smoothstep(.95, .7, r) * (.6 + .4*math.Sin(9*px)*math.Sin(9*py)*math.Sin(9*pz))
+				shell := .55 * smoothstep(.06, .02, math.Abs(r-.75))
+				ring := math.Hypot(math.Hypot(px, py)-.4, pz) - .1
+				value := math.Max(haze, math.Max(shell, smoothstep(.02, -.02, ring)))
+				for _, b := range balls {
+					d := math.Sqrt(sq(px-float64(b[0]))+sq(py-float64(b[1]))+sq(pz-float64(b[2]))) - .12
+					value = math.Max(value, .95*smoothstep(.03, -.03, d))
+				}
+
+				v.Data[i] = float32(value)
+				i++
+			}
+		}
+	}
+	return v
+}
+
+func sq(f float64) float64 {
+	return f * f
+}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	program  *glutil.Program
+	volume   uint32
+	transfer uint32
+
+	// along the axes of the volume
+	halfSize glm.Vec3
+	voxel    glm.Vec3
+}
+
+func makeResources(v *asset.Volume) *gResources {
+	r := &gResources{}
+
+	var err error
+	r.program, err = glutil.NewProgram(glutil.FullscreenVertexShader, fragment_glsl)
+	x(err)
+
+	r.volume = glutil.MakeTexture3D(int32(v.Width), int32(v.Height), int32(v.Depth),
+		glutil.Format{Internal: gl.R16F, Format: gl.RED, Type: gl.FLOAT}, gl.Ptr(v.Data))
+	r.transfer = glutil.MakeTextureFromImage(transferImage())
+
+	// the longest side of the box is 1
+	size := float32(v.Width)
+	if h := float32(v.Height); h > size {
+		size = h
+	}
+	if d := float32(v.Depth); d > size {
+		size = d
+	}
+	r.halfSize = glm.Vec3{float32(v.Width) / size / 2, float32(v.Height) / size / 2, float32(v.Depth) / size / 2}
+	r.voxel = glm.Vec3{1 / float32(v.Width), 1 / float32(v.Height), 1 / float32(v.Depth)}
+
+	return r
+}
+
+//
+// Update and render
+//
+
+var (
+	cam = camera.NewOrbit(glm.Vec3{0, 0, 0}, 2)
+
+	steps    = 256
+	transfer = 1
+	mode     = 0
+	lighting = true
+)
+
+const (
+	fov      = 45 // degrees, vertical
+	minSteps = 16
+	maxSteps = 2048
+)
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+	glutil.State{}.Apply()
+
+	p := r.program
+	p.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_3D, r.volume)
+	p.SetInt("volume", 0)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, r.transfer)
+	p.SetInt("transfer", 1)
+	p.SetFloat("transferRow", (float32(transfer)+.5)/float32(len(transferFunctions)))
+	p.SetMat4("cameraToWorld", cam.View().Inverse())
+	p.SetVec2("resolution", glm.Vec2{float32(width), float32(height)})
+	p.SetFloat("tanHalfFov", float32(math.Tan(fov*math.Pi/360)))
+	p.SetVec3("halfSize", r.halfSize)
+	p.SetVec3("voxel", r.voxel)
+	p.SetInt("steps", int32(steps))
+	p.SetInt("mode", int32(mode))
+	p.SetBool("lighting", lighting)
+	glutil.DrawFullscreen()
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func main() {
+	app.Keys[actionMoreSteps] = []string{"up"}
+	app.Keys[actionFewerSteps] = []string{"down"}
+	app.Keys[actionTransfer] = []string{"t"}
+	app.Keys[actionMode] = []string{"m"}
+	app.Keys[actionLighting] = []string{"l"}
+	app.Flags(800, 600, "Volume rendering")
+
+	var v *asset.Volume
+	if *file != "" {
+		var w, h, d int
+		if _, err := fmt.Sscanf(*sizeFlag, "%dx%dx%d", &w, &h, &d); err != nil {
+			app.Fatal("-size must be given as WIDTHxHEIGHTxDEPTH")
+		}
+		var err error
+		v, err = asset.LoadRawVolume(*file, w, h, d, *bits)
+		x(err)
+	} else {
+		if *resolution < 2 {
+			app.Fatal("-resolution must be at least 2")
+		}
+		v = generate(*resolution)
+	}
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+	w.SetScrollCallback(cam.Scroll)
+	cam.Pitch = .3
+	cam.MinDistance = .5
+	cam.MaxDistance = 10
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources(v)
+
+	app.Infof("Volume of %dx%dx%d values", v.Width, v.Height, v.Depth)
+	app.Info("Drag with the mouse to rotate, scroll to zoom")
+	app.Info("Press up and down to change the number of steps, 't' to change the transfer function")
+	app.Info("Press 'm' to switch to maximum intensity projection and back, 'l' to toggle lighting")
+	app.Info("Press 'q' to quit")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case actionMoreSteps:
+		if steps < maxSteps {
+			steps *= 2
+		}
+		app.Info("Steps:", steps)
+	case actionFewerSteps:
+		if steps > minSteps {
+			steps /= 2
+		}
+		app.Info("Steps:", steps)
+	case actionTransfer:
+		transfer = (transfer + 1) % len(transferFunctions)
+		app.Info("Transfer function:", transferFunctions[transfer].name)
+	case actionMode:
+		mode = 1 - mode
+		if mode == 1 {
+			app.Info("Maximum intensity projection")
+		} else {
+			app.Info("Blending")
+		}
+	case actionLighting:
+		lighting = !lighting
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pebbe/gl/app"
+	"github.com/pebbe/gl/camera"
+	"github.com/pebbe/gl/glinfo"
+	"github.com/pebbe/gl/glm"
+	"github.com/pebbe/gl/glutil"
+	"github.com/pebbe/gl/mesh"
+
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+)
+
+var (
+	scene_vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat3 normalMatrix;
+uniform vec4 plane; // the clip plane
+
+layout(location = 0) in vec3 position;
+layout(location = 1) in vec3 normal;
+
+out vec3 worldPosition;
+out vec3 worldNormal;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    gl_Position = projection * view * p;
+    gl_ClipDistance[0] = dot(p, plane);
+    worldPosition = p.xyz;
+    worldNormal = normalMatrix * normal;
+}
+`
+
+	scene_fragment_glsl = `
+#version 330 core
+
+uniform vec3 color;
+uniform bool checkered;
+
+in vec3 worldPosition;
+in vec3 worldNormal;
+
+out vec4 fragColor;
+
+void main()
+{
+    vec3 albedo = color;
+    if (checkered) {
+        vec2 cell = floor(worldPosition.xz);
+        albedo *= mod(cell.x + cell.y, 2.0) == 0.0 ? 1.0 : 0.8;
+    }
+    vec3 l = normalize(vec3(0.4, 1.0, 0.3));
+    float diffuse = max(dot(normalize(worldNormal), l), 0.0);
+    fragColor = vec4((0.3 + 0.7 * diffuse) * albedo, 1.0);
+}
+`
+
+	water_vertex_glsl = `
+#version 330 core
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+
+layout(location = 0) in vec3 position;
+layout(location = 2) in vec2 uv;
+
+out vec4 clipPosition;
+out vec3 worldPosition;
+out vec2 texcoord;
+
+void main()
+{
+    vec4 p = model * vec4(position, 1.0);
+    clipPosition = projection * view * p;
+    gl_Position = clipPosition;
+    worldPosition = p.xyz;
+    texcoord = uv * 6.0;
+}
+`
+
+	// The reflection and refraction are looked up at the position of the
+	// fragment on the screen, shifted by the DuDv map.
+	water_fragment_glsl = `
+#version 330 core
+
+uniform sampler2D reflection;
+uniform sampler2D refraction;
+uniform sampler2D dudv;
+uniform float time;
+uniform vec3 eye;
+
+in vec4 clipPosition;
+in vec3 worldPosition;
+in vec2 texcoord;
+
+out vec4 fragColor;
+
+const float strength = 0.02;
+
+void main()
+{
+    vec2 ndc = clipPosition.xy / clipPosition.w * 0.5 + 0.5;
+
+    // two layers of ripples, moving in different directions
+    vec2 d1 = texture(dudv, texcoord + vec2(0.03 * time, 0.0)).rg * 2.0 - 1.0;
+    vec2 d2 = texture(dudv, texcoord * 1.3 + vec2(-0.02 * time, 0.025 * time)).rg * 2.0 - 1.0;
+    vec2 distortion = (d1 + d2) * strength;
+
+    // the reflection was rendered upside down
+    vec2 reflectUV = clamp(vec2(ndc.x, 1.0 - ndc.y) + distortion, 0.001, 0.999);
+    vec2 refractUV = clamp(ndc + distortion, 0.001, 0.999);
+
+    // Fresnel: more reflection when looking along the surface
+    vec3 view = normalize(eye - worldPosition);
+    float fresnel = pow(1.0 - max(view.y, 0.0), 2.0);
+
+    vec3 c = mix(texture(refraction, refractUV).rgb, texture(reflection, reflectUV).rgb, fresnel);
+    fragColor = vec4(mix(c, vec3(0.0, 0.3, 0.4), 0.2), 1.0);
+}
+`
+)
+
+// Height of the water surface.
+const waterHeight = 0
+
+var skyColor = glm.Vec3{.6, .75, .9}
+
+//
+// Global data used by render
+//
+
+type gResources struct {
+	scene *glutil.Program
+	water *glutil.Program
+
+	cube   *mesh.VAO
+	sphere *mesh.VAO
+	quad   *mesh.VAO
+
+	dudv       uint32
+	reflection *glutil.Framebuffer
+	refraction *glutil.Framebuffer
+}
+
+// makeDuDv creates a repeating texture of distortions, with the x and y
+// displacement in the red and green components, from sums of waves that
+// fit the texture a whole number of times.
+func makeDuDv() uint32 {
+	const n = 128
+	img := image.NewRGBA(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			u := 2 * math.Pi * float64(x) / n
+			v := 2 * math.Pi * float64(y) / n
+			du := .5*math.Sin(2*u+v) + .3*math.Sin(5*u-3*v) + .2*math.Cos(7*v)
+			dv := .5*math.Cos(u-2*v) + .3*math.Sin(4*u+5*v) + .2*math.Sin(9*u)
+			img.SetRGBA(x, y, color.RGBA{
+				uint8(127.5 + 127*du),
+				uint8(127.5 + 127*dv),
+				0,
+				255,
+			})
+		}
+	}
+	texture := glutil.MakeTextureFromImage(img)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	return texture
+}
+
+func makeResources() *gResources {
+	r := &gResources{
+		cube:   mesh.Cube().Upload(),
+		sphere: mesh.Sphere(32, 16).Upload(),
+		quad:   mesh.Quad().Upload(),
+		dudv:   makeDuDv(),
+	}
+
+	var err error
+	r.scene, err = glutil.NewProgram(scene_vertex_glsl, scene_fragment_glsl)
+	x(err)
+	r.water, err = glutil.NewProgram(water_vertex_glsl, water_fragment_glsl)
+	x(err)
+
+	return r
+}
+
+// resize (re)creates the framebuffers, at half the size of the window.
+func (r *gResources) resize(width, height int32) {
+	width, height = width/2, height/2
+	if r.reflection != nil && r.reflection.Width == width && r.reflection.Height == height {
+		return
+	}
+	var err error
+	for _, f := range []**glutil.Framebuffer{&r.reflection, &r.refraction} {
+		if *f != nil {
+			(*f).Delete()
+		}
+		*f, err = glutil.NewFramebuffer(width, height)
+		x(err)
+		(*f).SetFilter(gl.LINEAR)
+	}
+}
+
+//
+// Update and render
+//
+
+var (
+	clock = app.NewClock()
+	cam   = camera.NewFreeFly(glm.Vec3{0, 4, 16})
+)
+
+var state3D = glutil.State{
+	DepthTest: true,
+	CullFace:  true,
+}
+
+// drawScene draws everything except the water, clipped by plane, which is
+// given as a, b, c, d in a*x + b*y + c*z + d >= 0.
+func drawScene(r *gResources, view, projection glm.Mat4, plane glm.Vec4) {
+	t := clock.Seconds()
+
+	p := r.scene
+	p.Use()
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec4("plane", plane)
+
+	draw := func(vao *mesh.VAO, model glm.Mat4, color glm.Vec3, checkered bool) {
+		p.SetMat4("model", model)
+		p.SetMat3("normalMatrix", model.NormalMatrix())
+		p.SetVec3("color", color)
+		p.SetBool("checkered", checkered)
+		vao.Draw()
+	}
+
+	// the bottom of the pool
+	draw(r.quad,
+		glm.Translate(0, -3, 0).Mul(glm.Scale(20, 20, 20)).Mul(glm.Rotate(-math.Pi/2, glm.Vec3{1, 0, 0})),
+		glm.Vec3{.8, .7, .5}, true)
+
+	// pillars standing in the water
+	for i := 0; i < 6; i++ {
+		a := float32(i) * 2 * math.Pi / 6
+		h := 2 + float32(i%3)
+		draw(r.cube,
+			glm.Rotate(a, glm.Vec3{0, 1, 0}).Mul(glm.Translate(0, h/2-2, 7)).Mul(glm.Scale(.6, h/2+1, .6)),
+			glm.Vec3{.7, .3, .2}, false)
+	}
+
+	// floating balls
+	for i := 0; i < 4; i++ {
+		a := float64(i)*math.Pi/2 + t/10
+		y := .3 * math.Sin(1.5*t+float64(i))
+		draw(r.sphere,
+			glm.Translate(3*float32(math.Cos(a)), float32(y), 3*float32(math.Sin(a))),
+			glm.Vec3{float32(i % 2), .8, float32(1 - i%2)}, false)
+	}
+}
+
+func render(w *glfw.Window, r *gResources) {
+	width, height := w.GetFramebufferSize()
+	r.resize(int32(width), int32(height))
+
+	projection := glm.Perspective(glm.Radians(60), float32(width)/float32(height), .1, 200)
+	state3D.Apply()
+	gl.ClearColor(skyColor[0], skyColor[1], skyColor[2], 1)
+	gl.Enable(gl.CLIP_DISTANCE0)
+
+	// reflection: what is above the water, seen from a camera below the
+	// water, looking up as much as the real camera looks down
+	mirror := *cam
+	mirror.Position[1] = 2*waterHeight - cam.Position[1]
+	mirror.Pitch = -cam.Pitch
+	r.reflection.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawScene(r, mirror.View(), projection, glm.Vec4{0, 1, 0, -waterHeight})
+
+	// refraction: what is below the water
+	r.refraction.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawScene(r, cam.View(), projection, glm.Vec4{0, -1, 0, waterHeight})
+	r.refraction.Unbind()
+
+	gl.Disable(gl.CLIP_DISTANCE0)
+
+	// the scene, and the water on top of it
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	view := cam.View()
+	drawScene(r, view, projection, glm.Vec4{})
+
+	p := r.water
+	p.Use()
+	p.SetMat4("model", glm.Translate(0, waterHeight, 0).Mul(glm.Scale(20, 20, 20)).Mul(glm.Rotate(-math.Pi/2, glm.Vec3{1, 0, 0})))
+	p.SetMat4("view", view)
+	p.SetMat4("projection", projection)
+	p.SetVec3("eye", cam.Position)
+	p.SetFloat("time", float32(clock.Seconds()))
+	for i, t := range []struct {
+		name    string
+		texture uint32
+	}{
+		{"reflection", r.reflection.Texture},
+		{"refraction", r.refraction.Texture},
+		{"dudv", r.dudv},
+	} {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, t.texture)
+		p.SetInt(t.name, int32(i))
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	r.quad.Draw()
+}
+
+func main() {
+	app.Flags(800, 600, "Water")
+
+	err := glfw.Init()
+	if err != nil {
+		panic(err)
+	}
+	defer glfw.Terminate()
+
+	app.CoreProfile(3, 3)
+	glfw.WindowHint(glfw.DepthBits, 24)
+	w, err := app.CreateWindow()
+	if err != nil {
+		panic(err)
+	}
+
+	w.MakeContextCurrent()
+	glfw.SwapInterval(1)
+
+	app.OnAction(w, onAction)
+	w.SetMouseButtonCallback(cam.MouseButton)
+	w.SetCursorPosCallback(cam.CursorPos)
+
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+	glinfo.Report()
+
+	r := makeResources()
+
+	cam.Pitch = -.2
+	app.Info("Drag with the mouse to look around, use W, A, S, D, page up and page down to move")
+	app.Info("Press 'q' to quit, space to pause, '.' to step, '+' and '-' to change speed")
+	for !w.ShouldClose() {
+		time.Sleep(10 * time.Millisecond)
+
+		clock.Tick()
+		cam.Update(w)
+		render(w, r)
+
+		app.SwapBuffers(w)
+		glfw.PollEvents()
+	}
+}
+
+func onAction(w *glfw.Window, action string) {
+	switch action {
+	case app.Quit:
+		w.SetShouldClose(true)
+	case app.Pause:
+		clock.Pause()
+	case app.Step:
+		clock.Step()
+	case app.Faster:
+		clock.Faster()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	case app.Slower:
+		clock.Slower()
+		app.Infof("Speed: %.2fx", clock.Speed())
+	}
+}
+
+func init() {
+	// This is needed to arrange that main() runs on main thread.
+	// See documentation for functions that are only allowed to be called from the main thread.
+	runtime.LockOSThread()
+}
+
+func x(err error) {
+	if err != nil {
+		app.Fatal(err)
+	}
+}